@@ -2,65 +2,188 @@ package bot
 
 import (
 	"context"
-	_ "komainu/interactions" // To make all the interactions init()
+	"fmt"
 	"komainu/interactions/autocomplete"
 	"komainu/interactions/command"
 	"komainu/interactions/component"
 	"komainu/interactions/delete"
 	"komainu/interactions/edit"
+	"komainu/interactions/guildcreate"
 	"komainu/interactions/join"
 	"komainu/interactions/leave"
+	"komainu/interactions/memberupdate"
 	"komainu/interactions/message"
 	"komainu/interactions/modal"
+	"komainu/interactions/pins"
+	"komainu/interactions/presence"
+	"komainu/interactions/reaction"
+	"komainu/interactions/thread"
+	"komainu/interactions/timer"
+	"komainu/interactions/voice"
+	"komainu/logging"
+	"komainu/membercache"
+	"komainu/metrics"
+	"komainu/replay"
+	"komainu/status"
 	"komainu/storage"
 	"log"
-	"os"
+	"strconv"
+	"strings"
 
 	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session/shard"
 	"github.com/diamondburned/arikawa/v3/state"
 )
 
-// Connect connects to Discord
-func Connect(cfg *storage.Configuration, kvs storage.KeyValueStore) *state.State {
-	var token = os.Getenv("BOT_TOKEN")
-	if token == "" {
-		log.Fatalln("No BOT_TOKEN found in environment variables.")
+// Connect connects to Discord, with as many shards as Discord recommends unless the configuration says otherwise.
+// Every shard gets its own state, handlers and background tasks, looking after just the guilds on that shard.
+func Connect(cfg *storage.Configuration, kvs storage.KeyValueStore) *shard.Manager {
+	token, err := cfg.Token()
+	if err != nil {
+		log.Fatalln("Could not get the bot token:", err)
 	}
 
-	state := state.New("Bot " + token)
-
-	addBoatloadOfIntents(state)
+	newShard := func(m *shard.Manager, id *gateway.Identifier) (shard.Shard, error) {
+		return state.NewShardFunc(func(_ *shard.Manager, state *state.State) {
+			setupShard(state, id.Shard.ShardID(), cfg, kvs)
+		})(m, id)
+	}
 
-	command.AddHandler(state, kvs)
-	autocomplete.AddHandler(state, kvs)
-	modal.AddHandler(state, kvs)
-	component.AddHandler(state, kvs)
-	message.AddHandler(state, kvs)
-	delete.AddHandler(state, kvs)
-	edit.AddHandler(state, kvs)
-	join.AddHandler(state, kvs)
-	leave.AddHandler(state, kvs)
+	manager, err := newShardManager("Bot "+token, cfg.ShardCount, newShard)
+	if err != nil {
+		log.Fatalln("Failed to set up the shards:", err)
+	}
+	logging.SetShards(manager.NumShards())
+	log.Printf("Connecting %d shard(s) to Discord", manager.NumShards())
 
-	if err := state.Open(context.Background()); err != nil {
+	if err := manager.Open(context.Background()); err != nil {
 		log.Fatalln("Failed to connect to Discord:", err)
 	}
 
-	user, err := state.Me()
+	first := manager.Shard(0).(*state.State)
+	user, err := first.Me()
 	if err != nil {
 		log.Fatalln("Failed to get myself:", err)
 	}
 	log.Printf("Connected to Discord as %s#%s\n", user.Username, user.Discriminator)
 
-	if err := command.RegisterCommands(state); err != nil {
+	command.SetOwners(cfg.OwnerIDs)
+	storage.SetFeatureDefaults(cfg.Snapshot().Features)
+
+	// Commands are global, so registering them once is plenty.
+	if err := command.RegisterCommands(first); err != nil {
 		log.Fatalf("Error during command registration: %s", err)
 	}
 
+	storage.SubscribeWebhooks(kvs)
+	storage.SubscribeAuditLog(kvs)
+
 	// I was wondering if this should be init() in those specific files.
 	// This is a bad idea, however, as they only really work after connecting.
-	go storage.StartClosingExpiredVotes(state, kvs)
-	go storage.StartRevokingActiveRole(state, kvs)
+	manager.ForEach(func(s shard.Shard) {
+		state := s.(*state.State)
+		go storage.StartClosingExpiredVotes(state, kvs)
+		go storage.StartReapingClosedVotes(state, kvs, cfg)
+		go storage.StartRevokingActiveRole(state, kvs)
+		go storage.StartRemindingEvents(state, kvs)
+		go storage.StartArchivingStaleThreads(state, kvs)
+		go storage.StartCheckingQuotas(state, kvs)
+		go storage.StartCheckingFeeds(state, kvs)
+		go storage.StartCheckingStreams(state, kvs, cfg)
+		go storage.StartCheckingGitHub(state, kvs, cfg)
+		go storage.StartCheckpointingVoice(state, kvs)
+		go storage.StartCleaningVoiceLobbies(state, kvs)
+		go storage.StartPruningAnalytics(state, kvs)
+		go storage.StartPruningTrash(state, kvs)
+		go timer.Start(state, kvs)
+	})
+	go status.Start(manager, kvs, cfg)
+	if cfg.MetricsAddress != "" {
+		addActiveVotesGauge(manager, kvs)
+	}
+	if cfg.HealthAddress != "" {
+		go serveHealth(manager, cfg.HealthAddress)
+	}
+
+	return manager
+}
+
+// newShardManager makes a shard manager with the given number of shards, or as many as Discord recommends if that is zero.
+func newShardManager(token string, count int, newShard shard.NewShardFunc) (*shard.Manager, error) {
+	if count <= 0 {
+		return shard.NewManager(token, newShard)
+	}
+	identify := gateway.DefaultIdentifier(token).IdentifyCommand
+	identify.SetShard(0, count)
+	return shard.NewIdentifiedManager(identify, newShard)
+}
+
+// setupShard adds the intents and handlers to the state of a single shard.
+func setupShard(state *state.State, shardID int, cfg *storage.Configuration, kvs storage.KeyValueStore) {
+	addBoatloadOfIntents(state)
+	if cfg.ContentIntent {
+		state.AddIntents(message.IntentMessageContent)
+	}
+	if cfg.PresenceIntent {
+		// Privileged, and a lot of events in big guilds, so only if asked for.
+		state.AddIntents(gateway.IntentGuildPresences)
+	}
+
+	if cfg.MetricsAddress != "" {
+		addMetrics(state, shardID)
+	}
+
+	command.AddHandler(state, kvs)
+	autocomplete.AddHandler(state, kvs)
+	modal.AddHandler(state, kvs)
+	component.AddHandler(state, kvs)
+	message.AddHandler(state, kvs)
+	delete.AddHandler(state, kvs)
+	edit.AddHandler(state, kvs)
+	guildcreate.AddHandler(state, kvs)
+	join.AddHandler(state, kvs)
+	leave.AddHandler(state, kvs)
+	memberupdate.AddHandler(state, kvs)
+	pins.AddHandler(state, kvs)
+	reaction.AddHandler(state, kvs)
+	thread.AddHandler(state, kvs)
+	voice.AddHandler(state, kvs)
+	presence.AddHandler(state, kvs)
+	membercache.AddHandler(state)
+	if cfg.RecordInteractions != "" {
+		replay.Record(state, cfg.RecordInteractions)
+	}
+}
+
+// addMetrics counts the gateway events received by the shard.
+func addMetrics(state *state.State, shardID int) {
+	shardLabel := strconv.Itoa(shardID)
+	state.AddHandler(func(e interface{}) {
+		metrics.GatewayEvents.Inc(strings.TrimPrefix(fmt.Sprintf("%T", e), "*gateway."), shardLabel)
+	})
+}
 
-	return state
+// addActiveVotesGauge keeps an eye on how many votes are open, on all shards.
+func addActiveVotesGauge(manager *shard.Manager, kvs storage.KeyValueStore) {
+	metrics.NewGaugeFunc("komainu_active_votes", "Votes that are still open, in all guilds.", func() float64 {
+		open := 0
+		manager.ForEach(func(s shard.Shard) {
+			guilds, err := s.(*state.State).Guilds()
+			if err != nil {
+				metrics.Errors.Inc("metrics")
+				return
+			}
+			for _, guild := range guilds {
+				votes, err := storage.GetOpenVotes(kvs, guild.ID)
+				if err != nil {
+					metrics.Errors.Inc("metrics")
+					continue
+				}
+				open += len(votes)
+			}
+		})
+		return float64(open)
+	})
 }
 
 func addBoatloadOfIntents(state *state.State) {
@@ -72,6 +195,7 @@ func addBoatloadOfIntents(state *state.State) {
 		gateway.IntentGuildInvites |
 		gateway.IntentGuildMessages |
 		gateway.IntentGuildMessageReactions |
+		gateway.IntentGuildVoiceStates |
 		gateway.IntentGuildMessageTyping |
 		gateway.IntentDirectMessages |
 		gateway.IntentDirectMessageReactions |
@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/session/shard"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// healthBeatLimit is how long since Discord last answered a heartbeat before the bot counts as unhealthy.
+// Heartbeats are usually about 40 seconds apart, so this allows for a couple of slow ones.
+const healthBeatLimit = 2 * time.Minute
+
+// serveHealth answers on /healthz at the given address, with 200 if the gateway connection of every shard is fine,
+// and 503 if any of them isn't. Intended to be called as a goroutine.
+func serveHealth(manager *shard.Manager, address string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		problems := []string{}
+		for i := 0; i < manager.NumShards(); i++ {
+			if problem := shardHealth(manager.Shard(i).(*state.State)); problem != "" {
+				problems = append(problems, fmt.Sprintf("shard %d: %s", i, problem))
+			}
+		}
+		if len(problems) > 0 {
+			http.Error(w, strings.Join(problems, "\n"), http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok\n"))
+	})
+	log.Printf("Serving health checks on %s", address)
+	if err := http.ListenAndServe(address, mux); err != nil {
+		log.Printf("Health check server stopped: %s", err)
+	}
+}
+
+// shardHealth returns what is wrong with the gateway connection of the shard, or a blank string if nothing is.
+func shardHealth(state *state.State) string {
+	gw := state.Gateway()
+	if gw == nil || !state.GatewayIsAlive() {
+		return "gateway is not connected"
+	}
+	if since := time.Since(gw.EchoBeat()); since > healthBeatLimit {
+		return "no heartbeat for " + since.Round(time.Second).String()
+	}
+	return ""
+}
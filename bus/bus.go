@@ -0,0 +1,69 @@
+// Package bus lets parts of the bot tell each other about things happening, without knowing about each other.
+// Whatever makes something happen publishes an event, and whatever cares subscribes to it.
+package bus
+
+import (
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// The events that are published. Names are "thing.what_happened", and some of them are sent on to webhooks as they are.
+const (
+	VoteClosed     = "vote.closed"
+	WarningIssued  = "warning.issued"
+	MemberVerified = "member.verified"
+	TicketOpened   = "ticket.opened"
+)
+
+// Everything is what to subscribe to for every event, whatever it's called.
+const Everything = "*"
+
+// Event is something that happened.
+type Event struct {
+	Name    string
+	GuildID discord.GuildID
+	UserID  discord.UserID // Who it's about, if anyone.
+	Time    time.Time
+	Summary string // Says what happened, for people to read.
+	Data    any    // The details, which depend on the event. Always something that can be made into JSON.
+}
+
+// Handler deals with an event. It's called right away, so anything slow should go in a goroutine.
+type Handler func(event Event)
+
+var subscribers = map[string][]Handler{}
+var subscribersLock sync.RWMutex
+
+// Subscribe makes the handler get every event with the name, or every event at all if the name is Everything.
+func Subscribe(name string, handler Handler) {
+	subscribersLock.Lock()
+	defer subscribersLock.Unlock()
+	subscribers[name] = append(subscribers[name], handler)
+}
+
+// Publish tells every subscriber about the event, one after the other, before returning. A subscriber that panics is
+// logged, and doesn't keep the others from hearing about it.
+func Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	subscribersLock.RLock()
+	handlers := append(append([]Handler{}, subscribers[event.Name]...), subscribers[Everything]...)
+	subscribersLock.RUnlock()
+	for _, handler := range handlers {
+		deliver(handler, event)
+	}
+}
+
+func deliver(handler Handler, event Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[%s] A subscriber to %s panicked: %v\n%s", event.GuildID, event.Name, r, debug.Stack())
+		}
+	}()
+	handler(event)
+}
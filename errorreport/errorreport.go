@@ -0,0 +1,255 @@
+// Package errorreport sends errors somewhere they get noticed, rather than just logged. Anything logged at the error
+// level is reported, with the fields it was logged with, which for interactions are the guild, user, interaction and
+// handler. Panics in handlers are logged with a stack, so they are reported with it.
+//
+// Reports go to Sentry, to a webhook as JSON, or both. Either way, they're sent in the background, and dropped rather
+// than piling up if they can't be sent fast enough.
+package errorreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	mathrand "math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// queueLength is how many reports can wait to be sent, before more are dropped.
+const queueLength = 100
+
+// Settings say where reports go, and which.
+type Settings struct {
+	SentryDSN  string  // Like "https://key@sentry.example.com/42". Blank means no Sentry.
+	WebhookURL string  // Gets every report POSTed as JSON. Blank means no webhook.
+	SampleRate float64 // The share of errors to report, from 0 to 1. Zero means all of them.
+	Scrub      bool    // Leave out who it was, and what they typed.
+}
+
+// Report is an error, as reported.
+type Report struct {
+	Time    time.Time         `json:"time"`
+	Kind    string            `json:"kind"` // "panic" or "error".
+	Message string            `json:"message"`
+	Stack   string            `json:"stack,omitempty"`
+	Fields  map[string]string `json:"fields"` // What it was logged with, like guild, user and command.
+}
+
+var settings atomic.Pointer[Settings]
+var queue = make(chan Report, queueLength)
+var sending sync.Once
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// Setup sets where reports go. It can be called again, when the configuration changes.
+func Setup(s Settings) {
+	if s.SentryDSN != "" {
+		if _, _, err := sentryStore(s.SentryDSN); err != nil {
+			slog.Warn("Not reporting errors to Sentry, as the DSN is no good", "error", err)
+			s.SentryDSN = ""
+		}
+	}
+	settings.Store(&s)
+	if s.SentryDSN != "" || s.WebhookURL != "" {
+		sending.Do(func() { go send() })
+	}
+}
+
+// Capture reports the error, unless it's not sampled, or there's nowhere to report it.
+func Capture(report Report) {
+	s := settings.Load()
+	if s == nil || (s.SentryDSN == "" && s.WebhookURL == "") {
+		return
+	}
+	if s.SampleRate > 0 && s.SampleRate < 1 && mathrand.Float64() >= s.SampleRate {
+		return
+	}
+	if s.Scrub {
+		scrub(&report)
+	}
+	select {
+	case queue <- report:
+	default:
+		// Too many to keep up with. Logging it would only report another.
+	}
+}
+
+// mention matches mentions of users, which say who someone is.
+var mention = regexp.MustCompile(`<@!?[0-9]+>`)
+
+// quoted matches what's quoted in a message, which is usually something someone typed.
+var quoted = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+
+// scrubbedFields are the fields that say who someone is, or what they typed.
+var scrubbedFields = []string{"user", "content", "value", "input"}
+
+// scrub takes out who it was, and what they typed, as well as can be done.
+func scrub(report *Report) {
+	report.Message = quoted.ReplaceAllString(mention.ReplaceAllString(report.Message, "<@user>"), `"..."`)
+	for _, field := range scrubbedFields {
+		delete(report.Fields, field)
+	}
+	for key, value := range report.Fields {
+		report.Fields[key] = quoted.ReplaceAllString(mention.ReplaceAllString(value, "<@user>"), `"..."`)
+	}
+}
+
+func send() {
+	for report := range queue {
+		s := settings.Load()
+		if s.SentryDSN != "" {
+			if err := sendSentry(s.SentryDSN, report); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to report an error to Sentry: %s\n", err)
+			}
+		}
+		if s.WebhookURL != "" {
+			if err := post(s.WebhookURL, nil, report); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to report an error to the webhook: %s\n", err)
+			}
+		}
+	}
+}
+
+// post sends the payload as JSON, with the headers.
+func post(address string, headers map[string]string, payload interface{}) error {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, address, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "Komainu")
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("got %s", response.Status)
+	}
+	return nil
+}
+
+// sentryStore works out where to send events for the DSN, and the key to send them with.
+func sentryStore(dsn string) (store string, key string, err error) {
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", err
+	}
+	if parsed.User == nil || parsed.User.Username() == "" {
+		return "", "", errors.New("there is no key in the DSN")
+	}
+	path := strings.Trim(parsed.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	project, prefix := path[slash+1:], path[:slash+1]
+	if project == "" {
+		return "", "", errors.New("there is no project in the DSN")
+	}
+	store = fmt.Sprintf("%s://%s/%sapi/%s/store/", parsed.Scheme, parsed.Host, prefix, project)
+	return store, parsed.User.Username(), nil
+}
+
+// sentryEvent is a report, as Sentry wants it.
+type sentryEvent struct {
+	EventID   string            `json:"event_id"`
+	Timestamp string            `json:"timestamp"`
+	Level     string            `json:"level"`
+	Platform  string            `json:"platform"`
+	Logger    string            `json:"logger"`
+	Message   string            `json:"message"`
+	Tags      map[string]string `json:"tags"`
+	Extra     map[string]string `json:"extra,omitempty"`
+	User      map[string]string `json:"user,omitempty"`
+}
+
+func sendSentry(dsn string, report Report) error {
+	store, key, err := sentryStore(dsn)
+	if err != nil {
+		return err
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	event := sentryEvent{
+		EventID:   hex.EncodeToString(id),
+		Timestamp: report.Time.UTC().Format(time.RFC3339),
+		Level:     "error",
+		Platform:  "go",
+		Logger:    "komainu",
+		Message:   report.Message,
+		Tags:      map[string]string{"kind": report.Kind},
+	}
+	if report.Kind == "panic" {
+		event.Level = "fatal"
+	}
+	for field, value := range report.Fields {
+		if field == "user" {
+			event.User = map[string]string{"id": value}
+			continue
+		}
+		event.Tags[field] = value
+	}
+	if report.Stack != "" {
+		event.Extra = map[string]string{"stack": report.Stack}
+	}
+	auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=komainu/1.0, sentry_key=%s", key)
+	return post(store, map[string]string{"X-Sentry-Auth": auth}, event)
+}
+
+// Handler reports everything logged at the error level, and hands everything on to next either way.
+func Handler(next slog.Handler) slog.Handler {
+	return reportingHandler{Handler: next}
+}
+
+type reportingHandler struct {
+	slog.Handler
+	attrs []slog.Attr // What the logger was made with, like the guild and user.
+}
+
+func (h reportingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelError {
+		Capture(fromRecord(h.attrs, r))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h reportingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return reportingHandler{h.Handler.WithAttrs(attrs), append(append([]slog.Attr{}, h.attrs...), attrs...)}
+}
+
+func (h reportingHandler) WithGroup(name string) slog.Handler {
+	return reportingHandler{h.Handler.WithGroup(name), h.attrs}
+}
+
+// fromRecord makes a report of the record. If it was logged with a stack, it's a panic.
+func fromRecord(attrs []slog.Attr, r slog.Record) Report {
+	report := Report{Time: r.Time, Kind: "error", Message: r.Message, Fields: map[string]string{}}
+	add := func(attr slog.Attr) bool {
+		if attr.Key == "stack" {
+			report.Kind, report.Stack = "panic", attr.Value.String()
+		} else {
+			report.Fields[attr.Key] = attr.Value.String()
+		}
+		return true
+	}
+	for _, attr := range attrs {
+		add(attr)
+	}
+	r.Attrs(add)
+	return report
+}
@@ -0,0 +1,54 @@
+package errorreport
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	received := make(chan Report, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := Report{}
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			t.Errorf("Could not decode the report: %s", err)
+		}
+		received <- report
+	}))
+	defer server.Close()
+	Setup(Settings{WebhookURL: server.URL, Scrub: true})
+	defer Setup(Settings{})
+
+	logger := slog.New(Handler(slog.NewTextHandler(&strings.Builder{}, nil))).With("guild", "1234", "user", "5678", "command", "vote")
+	logger.Info("Nothing to see here")
+	logger.Error(`Failed to find the topic "my secret" for <@5678>`, "stack", "goroutine 1")
+
+	select {
+	case report := <-received:
+		if report.Kind != "panic" || report.Stack != "goroutine 1" {
+			t.Errorf("Expected a panic with the stack, Got %+v", report)
+		}
+		if report.Fields["guild"] != "1234" || report.Fields["command"] != "vote" {
+			t.Errorf("Expected the interaction fields, Got %v", report.Fields)
+		}
+		if _, ok := report.Fields["user"]; ok || strings.Contains(report.Message, "secret") || strings.Contains(report.Message, "5678") {
+			t.Errorf("Expected the user and what they typed to be scrubbed, Got %+v", report)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("The error was never reported")
+	}
+}
+
+func TestSentryStore(t *testing.T) {
+	store, key, err := sentryStore("https://abc123@sentry.example.com/prefix/42")
+	if err != nil || key != "abc123" || store != "https://sentry.example.com/prefix/api/42/store/" {
+		t.Errorf("Expected the store endpoint and key, Got %q and %q (%v)", store, key, err)
+	}
+	if _, _, err := sentryStore("https://sentry.example.com/42"); err == nil {
+		t.Error("Expected a DSN without a key to be refused")
+	}
+}
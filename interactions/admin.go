@@ -0,0 +1,376 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/interactions/timer"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"github.com/google/uuid"
+)
+
+// selftestTimerWait is how long the self-test waits for the scheduler. It ticks every 15 seconds, so this allows for a missed tick.
+const selftestTimerWait = 40 * time.Second
+
+// adminAuditShown is how many entries /admin audit shows.
+const adminAuditShown = 20
+
+var adminModule = module.Basic{
+	ModuleName: "admin",
+	CommandHandlers: map[string]command.Handler{
+		"admin": commandAdminObject,
+	},
+	Handlers: []module.EventHandler{
+		module.Timer("selftest", timer.Handler{Code: TimerSelftest}),
+	},
+}
+
+var commandAdminObject = command.Handler{
+	Description: "Things for checking on the bot itself",
+	Code:        CommandAdmin,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "selftest",
+			Description: "Check that everything the bot needs works here",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:  "channel",
+					Description: "Where to test sending, editing and deleting messages. Defaults to this channel.",
+					Required:    false,
+				},
+				&discord.RoleOption{
+					OptionName:  "role",
+					Description: "A role to briefly give the bot and take back. Skipped if left blank.",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "quota",
+			Description: "Show how much storage each module uses here, or set a soft quota for one",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "module",
+					Description: "The module to set a quota for",
+					Required:    false,
+					Choices:     quotaModuleChoices(),
+				},
+				&discord.IntegerOption{
+					OptionName:  "kilobytes",
+					Description: "The soft quota, in kilobytes. 0 removes it.",
+					Required:    false,
+					Min:         option.NewInt(0),
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "report",
+			Description: "Write up every setting here as a document, for audits and new admins",
+			Options:     []discord.CommandOptionValue{},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "audit",
+			Description: "Show the latest things to happen here, like votes closing and warnings",
+			Options:     []discord.CommandOptionValue{},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "restore",
+			Description: "Bring back something deleted recently, or list what can be brought back",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "collection",
+					Description: "What kind of thing it is",
+					Required:    false,
+					Choices:     trashCollectionChoices(),
+				},
+				&discord.StringOption{
+					OptionName:  "key",
+					Description: "Which one, as listed. Leave blank to list what can be brought back.",
+					Required:    false,
+				},
+			},
+		},
+	},
+}
+
+func quotaModuleChoices() []discord.StringChoice {
+	modules := storage.StorageModules()
+	choices := make([]discord.StringChoice, len(modules))
+	for i, module := range modules {
+		choices[i] = discord.StringChoice{Name: module, Value: module}
+	}
+	return choices
+}
+
+// CommandAdmin processes the /admin command, dispatching to the right subcommand.
+func CommandAdmin(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /admin command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "selftest":
+		return SubCommandAdminSelftest(state, kvs, event, cmd.Options[0].Options)
+	case "quota":
+		return SubCommandAdminQuota(kvs, event, cmd.Options[0].Options)
+	case "report":
+		return SubCommandAdminReport(state, kvs, event)
+	case "audit":
+		return SubCommandAdminAudit(kvs, event)
+	case "restore":
+		return SubCommandAdminRestore(kvs, event, cmd.Options[0].Options)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandAdminAudit shows the latest entries in the audit log.
+func SubCommandAdminAudit(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	entries, err := storage.GetAuditLog(kvs, event.GuildID, adminAuditShown)
+	if err != nil {
+		log.Printf("[%s] /admin audit failed to get the audit log: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(entries) == 0 {
+		return command.Response{Response: response.Ephemeral("Nothing has happened here yet. Not that I've noted, anyway.")}
+	}
+	var sb strings.Builder
+	sb.WriteString("The latest things to happen here, newest first:")
+	for _, entry := range entries {
+		fmt.Fprintf(&sb, "\n- <t:%d:f> %s", entry.Time, entry.Summary)
+	}
+	return command.Response{Response: response.Ephemeral(utility.Substring(sb.String(), 0, 2000))}
+}
+
+// selftestCheck is a single thing the self-test does. A nil error means it passed.
+type selftestCheck struct {
+	Name string
+	Test func() error
+}
+
+// SubCommandAdminSelftest runs through every subsystem and reports what works and what doesn't.
+func SubCommandAdminSelftest(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	channelID := event.ChannelID
+	if options.Find("channel").Name != "" {
+		snowflake, err := options.Find("channel").SnowflakeValue()
+		if err != nil {
+			log.Printf("[%s] /admin selftest failed to get channel snowflake: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+		}
+		channelID = discord.ChannelID(snowflake)
+	}
+	roleID := discord.NullRoleID
+	if options.Find("role").Name != "" {
+		snowflake, err := options.Find("role").SnowflakeValue()
+		if err != nil {
+			log.Printf("[%s] /admin selftest failed to get role snowflake: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("There was an issue figuring out the role. It has been logged.")}
+		}
+		roleID = discord.RoleID(snowflake)
+	}
+
+	checks := []selftestCheck{
+		{"Storage read/write", func() error { return selftestStorage(kvs, event.GuildID) }},
+		{"Messages in " + channelID.Mention(), func() error { return selftestMessages(state, channelID) }},
+	}
+	if roleID.IsValid() {
+		checks = append(checks, selftestCheck{"Role " + roleID.Mention() + " on myself", func() error { return selftestRole(state, event.GuildID, roleID) }})
+	}
+	checks = append(checks, selftestCheck{"Scheduler", func() error { return selftestScheduler(kvs, event.GuildID) }})
+
+	return command.Deferred(state, event, func() string {
+		lines := make([]string, 0, len(checks)+1)
+		failed := 0
+		for _, check := range checks {
+			if err := check.Test(); err != nil {
+				failed++
+				log.Printf("[%s] Self-test %q failed: %s", event.GuildID, check.Name, err)
+				lines = append(lines, fmt.Sprintf("❌ %s: %s", check.Name, err))
+			} else {
+				lines = append(lines, fmt.Sprintf("✅ %s", check.Name))
+			}
+		}
+		if !roleID.IsValid() {
+			lines = append(lines, "➖ Roles: skipped, as no `role` was given")
+		}
+		log.Printf("[%s] <@%s> ran the self-test, and %d of %d checks failed", event.GuildID, event.SenderID(), failed, len(checks))
+		return strings.Join(lines, "\n")
+	})
+}
+
+func selftestStorage(kvs storage.KeyValueStore, guildID discord.GuildID) error {
+	written := uuid.New().String()
+	if err := kvs.Set(guildID, "selftest", "check", written); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	read := ""
+	exist, err := kvs.Get(guildID, "selftest", "check", &read)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+	if !exist || read != written {
+		return fmt.Errorf("read back %q, but wrote %q", read, written)
+	}
+	if err := kvs.Delete(guildID, "selftest", "check"); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	return nil
+}
+
+func selftestMessages(state *state.State, channelID discord.ChannelID) error {
+	msg, err := state.SendMessage(channelID, "🔧 Self-test message. It will be gone in a moment.")
+	if err != nil {
+		return fmt.Errorf("send: %w", err)
+	}
+	_, err = state.EditMessage(channelID, msg.ID, "🔧 Self-test message, edited. It will be gone in a moment.")
+	if err != nil {
+		state.DeleteMessage(channelID, msg.ID, "Self-test")
+		return fmt.Errorf("edit: %w", err)
+	}
+	if err := state.DeleteMessage(channelID, msg.ID, "Self-test"); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	return nil
+}
+
+func selftestRole(state *state.State, guildID discord.GuildID, roleID discord.RoleID) error {
+	me, err := state.Me()
+	if err != nil {
+		return fmt.Errorf("finding myself: %w", err)
+	}
+	member, err := state.Member(guildID, me.ID)
+	if err != nil {
+		return fmt.Errorf("finding myself in the guild: %w", err)
+	}
+	for _, has := range member.RoleIDs {
+		if has == roleID {
+			return fmt.Errorf("I already have the role, so I won't risk taking it away")
+		}
+	}
+	if err := state.AddRole(guildID, me.ID, roleID, api.AddRoleData{AuditLogReason: "Self-test"}); err != nil {
+		return fmt.Errorf("grant: %w", err)
+	}
+	if err := state.RemoveRole(guildID, me.ID, roleID, "Self-test"); err != nil {
+		return fmt.Errorf("revoke: %w", err)
+	}
+	return nil
+}
+
+// selftestWaiting holds a channel for each self-test timer that someone is waiting on.
+var selftestWaiting = struct {
+	sync.Mutex
+	timers map[string]chan struct{}
+}{timers: map[string]chan struct{}{}}
+
+func selftestScheduler(kvs storage.KeyValueStore, guildID discord.GuildID) error {
+	// Waiting before storing the timer, in case the scheduler ticks right away.
+	fired := make(chan struct{}, 1)
+	t := storage.NewTimer(guildID, "selftest", time.Now(), nil)
+	selftestWaiting.Lock()
+	selftestWaiting.timers[t.ID] = fired
+	selftestWaiting.Unlock()
+	defer func() {
+		selftestWaiting.Lock()
+		delete(selftestWaiting.timers, t.ID)
+		selftestWaiting.Unlock()
+	}()
+	if err := t.Store(kvs); err != nil {
+		return fmt.Errorf("schedule: %w", err)
+	}
+
+	select {
+	case <-fired:
+		return nil
+	case <-time.After(selftestTimerWait):
+		storage.CancelTimer(kvs, guildID, t.ID)
+		return fmt.Errorf("the timer didn't fire within %s", selftestTimerWait)
+	}
+}
+
+// TimerSelftest lets the self-test know the scheduler works.
+func TimerSelftest(state *state.State, kvs storage.KeyValueStore, t storage.Timer) {
+	selftestWaiting.Lock()
+	defer selftestWaiting.Unlock()
+	if fired, ok := selftestWaiting.timers[t.ID]; ok {
+		fired <- struct{}{}
+	}
+}
+
+// SubCommandAdminQuota sets a soft quota if asked to, then shows the storage used by each module.
+func SubCommandAdminQuota(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	module := options.Find("module").String()
+	if (module == "") != (options.Find("kilobytes").Name == "") {
+		return command.Response{Response: response.Ephemeral("To set a quota, give both `module` and `kilobytes`. To just look, give neither.")}
+	}
+	if module != "" {
+		kilobytes, err := options.Find("kilobytes").IntValue()
+		if err != nil {
+			log.Printf("[%s] /admin quota failed to get kilobytes: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("There was an issue figuring out the quota. It has been logged.")}
+		}
+		if err := storage.SetQuota(kvs, event.GuildID, module, int(kilobytes)); err != nil {
+			log.Printf("[%s] Failed to store quota: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		log.Printf("[%s] <@%s> set the storage quota of %s to %d KB", event.GuildID, event.SenderID(), module, kilobytes)
+	}
+
+	usage, err := storage.ModuleUsage(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /admin quota failed to get storage usage: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	quotas, err := storage.GetQuotas(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /admin quota failed to get quotas: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	return command.Response{Response: response.Ephemeral(describeQuotas(usage, quotas))}
+}
+
+// describeQuotas lists the modules by how much they use, biggest first, marking the ones over quota.
+func describeQuotas(usage map[string]storage.CollectionUsage, quotas map[string]int) string {
+	modules := storage.StorageModules()
+	sort.SliceStable(modules, func(i, j int) bool {
+		return usage[modules[i]].Bytes > usage[modules[j]].Bytes
+	})
+	over := map[string]bool{}
+	for _, module := range storage.OverQuota(usage, quotas) {
+		over[module] = true
+	}
+	total := 0
+	lines := []string{}
+	for _, module := range modules {
+		used := usage[module]
+		total += used.Bytes
+		if used.Keys == 0 && quotas[module] == 0 {
+			continue
+		}
+		line := fmt.Sprintf("`%s`: %.1f KB in %d entries", module, float64(used.Bytes)/1024, used.Keys)
+		if quotas[module] > 0 {
+			line += fmt.Sprintf(", quota %d KB", quotas[module])
+		}
+		if over[module] {
+			line = "⚠️ " + line + " **over quota!**"
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return "Nothing is stored for this guild yet."
+	}
+	return fmt.Sprintf("Storage used here: %.1f KB\n%s", float64(total)/1024, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,381 @@
+package interactions
+
+import (
+	"bytes"
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/storage"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"github.com/diamondburned/arikawa/v3/utils/sendpart"
+)
+
+// settingsReport writes a Markdown description of how the bot is set up in a guild. Mentions don't work outside of
+// Discord, so channels, roles and users are written out by name.
+type settingsReport struct {
+	state   *state.State
+	kvs     storage.KeyValueStore
+	guildID discord.GuildID
+	buf     bytes.Buffer
+	failed  int
+}
+
+// reportSection is a part of the settings report. A section that fails says so in the report, and the rest carry on.
+type reportSection struct {
+	Title string
+	Write func(report *settingsReport) error
+}
+
+var reportSections = []reportSection{
+	{"Access", (*settingsReport).access},
+	{"Automod and antispam", (*settingsReport).moderation},
+	{"FAQ", (*settingsReport).faq},
+	{"Open votes", (*settingsReport).votes},
+	{"Schedules", (*settingsReport).schedules},
+}
+
+// SubCommandAdminReport writes up every setting of the guild, and attaches it as a Markdown file.
+func SubCommandAdminReport(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	log.Printf("[%s] <@%s> asked for a settings report", event.GuildID, event.SenderID())
+	return command.DeferredData(state, event, true, func() api.EditInteractionResponseData {
+		report := &settingsReport{state: state, kvs: kvs, guildID: event.GuildID}
+		report.write(event.Sender())
+		content := "Here's how everything is set up here."
+		if report.failed > 0 {
+			content += fmt.Sprintf(" %d parts of it couldn't be written up, and say so. The errors have been logged.", report.failed)
+		}
+		return api.EditInteractionResponseData{
+			Content: option.NewNullableString(content),
+			Files:   []sendpart.File{{Name: "settings.md", Reader: &report.buf}},
+		}
+	})
+}
+
+// write writes the whole report.
+func (report *settingsReport) write(by *discord.User) {
+	guildName := report.guildID.String()
+	if guild, err := report.state.Guild(report.guildID); err == nil {
+		guildName = guild.Name
+	}
+	fmt.Fprintf(&report.buf, "# Settings for %s\n\n", guildName)
+	fmt.Fprintf(&report.buf, "Written up %s by %s.\n", time.Now().UTC().Format("2006-01-02 15:04 MST"), by.Tag())
+	for _, section := range reportSections {
+		fmt.Fprintf(&report.buf, "\n## %s\n\n", section.Title)
+		if err := section.Write(report); err != nil {
+			report.failed++
+			log.Printf("[%s] Settings report failed to write the %q section: %s", report.guildID, section.Title, err)
+			report.line("*This part couldn't be written up. The error has been logged.*")
+		}
+	}
+}
+
+func (report *settingsReport) line(format string, args ...interface{}) {
+	fmt.Fprintf(&report.buf, format+"\n", args...)
+}
+
+func (report *settingsReport) channel(channelID discord.ChannelID) string {
+	if channel, err := report.state.Channel(channelID); err == nil {
+		return "#" + channel.Name
+	}
+	return fmt.Sprintf("unknown channel %s", channelID)
+}
+
+func (report *settingsReport) role(roleID discord.RoleID) string {
+	if role, err := report.state.Role(report.guildID, roleID); err == nil {
+		return "@" + role.Name
+	}
+	return fmt.Sprintf("unknown role %s", roleID)
+}
+
+func (report *settingsReport) user(userID discord.UserID) string {
+	if !userID.IsValid() {
+		return "the REST API"
+	}
+	if member, err := report.state.Member(report.guildID, userID); err == nil {
+		return member.User.Tag()
+	}
+	return fmt.Sprintf("user %s", userID)
+}
+
+func (report *settingsReport) channels(channels map[discord.ChannelID]bool) string {
+	names := []string{}
+	for channelID := range channels {
+		names = append(names, report.channel(channelID))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func reportTime(unix int64) string {
+	return time.Unix(unix, 0).UTC().Format("2006-01-02 15:04 MST")
+}
+
+// access is who and what gets to do things with the bot.
+func (report *settingsReport) access() error {
+	report.line("### Features\n")
+	for _, flag := range storage.FeatureFlags {
+		text, err := describeFeature(report.kvs, report.guildID, flag)
+		if err != nil {
+			return err
+		}
+		report.line("- %s", text)
+	}
+
+	report.line("\n### REST API\n")
+	exist, token, err := storage.GetAPIToken(report.kvs, report.guildID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !exist:
+		report.line("There is no API token, so the REST API is off.")
+	case token.Write:
+		report.line("There is an API token that can read and change things, made by %s on %s.", report.user(token.CreatedBy), reportTime(token.Created))
+	default:
+		report.line("There is an API token that can only read things, made by %s on %s.", report.user(token.CreatedBy), reportTime(token.Created))
+	}
+
+	report.line("\n### Who can use /say\n")
+	sayRoles, err := storage.GetSayRoles(report.kvs, report.guildID)
+	if err != nil {
+		return err
+	}
+	if len(sayRoles) == 0 {
+		report.line("Only those who can use admin commands.")
+	}
+	for _, roleID := range sayRoles {
+		report.line("- %s", report.role(roleID))
+	}
+
+	report.line("\n### Custom commands\n")
+	customs, err := storage.GetCustomCommands(report.kvs, report.guildID)
+	if err != nil {
+		return err
+	}
+	if len(customs) == 0 {
+		report.line("None.")
+	}
+	for _, custom := range customs {
+		report.line("- `/%s`, by %s: %s", custom.Name, report.user(custom.Creator), custom.Description)
+	}
+
+	report.line("\n### Macros\n")
+	macros, err := storage.GetMacros(report.kvs, report.guildID)
+	if err != nil {
+		return err
+	}
+	if len(macros) == 0 {
+		report.line("None.")
+	}
+	for _, macro := range macros {
+		report.line("- `%s`, by %s, with %d steps", macro.Name, report.user(macro.CreatedBy), len(macro.Steps))
+	}
+
+	report.line("\n### Command defaults\n")
+	presets, err := storage.AllPresets(report.kvs, report.guildID)
+	if err != nil {
+		return err
+	}
+	if len(presets) == 0 {
+		report.line("None.")
+	}
+	paths := make([]string, 0, len(presets))
+	for path := range presets {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		report.line("- `/%s`: `%s`", path, presets[path])
+	}
+	return nil
+}
+
+// moderation is what automod and antispam look for, and what they do about it.
+func (report *settingsReport) moderation() error {
+	config, err := storage.GetAutomodConfig(report.kvs, report.guildID)
+	if err != nil {
+		return err
+	}
+	report.line("### Automod\n")
+	if config.LogChannelID.IsValid() {
+		report.line("- Logs to %s", report.channel(config.LogChannelID))
+	} else {
+		report.line("- Doesn't log anywhere")
+	}
+	report.line("- Times people out for %s", automodTimeout(config))
+	if len(config.Exempt) > 0 {
+		report.line("- Leaves %s alone", report.channels(config.Exempt))
+	}
+
+	filters, err := storage.GetAutomodFilters(report.kvs, report.guildID)
+	if err != nil {
+		return err
+	}
+	report.line("\nFilters:\n")
+	if len(filters) == 0 {
+		report.line("None.")
+	}
+	for _, filter := range filters {
+		report.line("- %d: %s, %s", filter.ID, filter.Describe(), filter.Action)
+	}
+
+	rules, err := storage.GetAutomodListRules(report.kvs, report.guildID)
+	if err != nil {
+		return err
+	}
+	report.line("\nLinks and attachments:\n")
+	if len(rules) == 0 {
+		report.line("Anything goes.")
+	}
+	for _, rule := range rules {
+		where := "Everywhere"
+		if rule.ChannelID.IsValid() {
+			where = "In " + report.channel(rule.ChannelID)
+		}
+		how := "blocking"
+		if rule.Mode == storage.AutomodAllowOnly {
+			how = "only allowing"
+		}
+		report.line("- %s, %s %s `%s`, %s", where, how, rule.Kind, strings.Join(rule.Entries, "`, `"), rule.Action)
+	}
+
+	antispam, err := storage.GetAntispamConfig(report.kvs, report.guildID)
+	if err != nil {
+		return err
+	}
+	report.line("\n### Antispam\n")
+	if !antispam.Enabled {
+		report.line("Off.")
+		return nil
+	}
+	report.line("- %d messages in %d seconds, or %d identical ones, is spam, and gets a %s", antispam.MessageLimit, antispam.MessageWindow, antispam.DuplicateLimit, antispam.SpamAction)
+	report.line("- %d joins in %d seconds is a raid, and gets a %s", antispam.JoinLimit, antispam.JoinWindow, antispam.RaidAction)
+	if antispam.LogChannelID.IsValid() {
+		report.line("- Logs to %s", report.channel(antispam.LogChannelID))
+	}
+
+	lockdown, err := storage.GetLockdownConfig(report.kvs, report.guildID)
+	if err != nil {
+		return err
+	}
+	if len(lockdown.Channels) == 0 {
+		report.line("- A lockdown covers every text channel")
+	} else {
+		report.line("- A lockdown covers %s", report.channels(lockdown.Channels))
+	}
+	return nil
+}
+
+// faq is the topics by category, and the auto-responder.
+func (report *settingsReport) faq() error {
+	categories, err := storage.GetFAQCategories(report.kvs, report.guildID)
+	if err != nil {
+		return err
+	}
+	if len(categories) == 0 {
+		report.line("There are no topics.")
+	}
+	names := make([]string, 0, len(categories))
+	for name := range categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		title := name
+		if name == "" {
+			title = faqBrowseOther
+		}
+		report.line("- %s: %s", title, strings.Join(categories[name], ", "))
+	}
+
+	auto, err := storage.GetFAQAutoConfig(report.kvs, report.guildID)
+	if err != nil {
+		return err
+	}
+	report.line("\n### Auto-responder\n")
+	if len(auto.Channels) == 0 {
+		report.line("Off.")
+		return nil
+	}
+	report.line("On in %s, answering with style %q at most every %d minutes.\n", report.channels(auto.Channels), auto.Style, auto.Cooldown())
+	phrases := make([]string, 0, len(auto.Triggers))
+	for phrase := range auto.Triggers {
+		phrases = append(phrases, phrase)
+	}
+	sort.Strings(phrases)
+	for _, phrase := range phrases {
+		report.line("- %q answers with %s", phrase, auto.Triggers[phrase])
+	}
+	return nil
+}
+
+// votes is the votes that are still going.
+func (report *settingsReport) votes() error {
+	votes, err := storage.GetOpenVotes(report.kvs, report.guildID)
+	if err != nil {
+		return err
+	}
+	if len(votes) == 0 {
+		report.line("None.")
+	}
+	for _, vote := range votes {
+		report.line("- %q in %s, by %s, ends %s", vote.Question, report.channel(vote.ChannelID), report.user(vote.Creator), reportTime(vote.EndTime))
+	}
+	return nil
+}
+
+// schedules is everything that happens by itself later, or when something happens.
+func (report *settingsReport) schedules() error {
+	rules, err := storage.GetSlowmodeRules(report.kvs, report.guildID)
+	if err != nil {
+		return err
+	}
+	report.line("### Slowmode\n")
+	if len(rules) == 0 {
+		report.line("None.")
+	}
+	for _, rule := range rules {
+		change := "turn slowmode off"
+		if rule.Seconds > 0 {
+			change = fmt.Sprintf("set slowmode to %s", time.Duration(rule.Seconds)*time.Second)
+		}
+		if rule.Kind == storage.SlowmodeDaily {
+			report.line("- Every day at %02d:%02d, %s in %s", rule.Minute/60, rule.Minute%60, change, report.channel(rule.ChannelID))
+		} else {
+			report.line("- At %d messages a minute, %s in %s for %s", rule.Messages, change, report.channel(rule.ChannelID), time.Duration(rule.Duration)*time.Second)
+		}
+	}
+
+	timers, err := storage.GetTimers(report.kvs, report.guildID)
+	if err != nil {
+		return err
+	}
+	report.line("\n### Pending\n")
+	if len(timers) == 0 {
+		report.line("Nothing.")
+		return nil
+	}
+	kinds := map[string][]int64{}
+	for _, t := range timers {
+		kinds[t.Kind] = append(kinds[t.Kind], t.When)
+	}
+	names := make([]string, 0, len(kinds))
+	for kind := range kinds {
+		names = append(names, kind)
+	}
+	sort.Strings(names)
+	for _, kind := range names {
+		when := kinds[kind]
+		sort.Slice(when, func(i, j int) bool { return when[i] < when[j] })
+		report.line("- %d %s, the next one %s", len(when), kind, reportTime(when[0]))
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package interactions
+
+import (
+	"errors"
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+func trashCollectionChoices() []discord.StringChoice {
+	collections := storage.TrashedCollections()
+	choices := make([]discord.StringChoice, len(collections))
+	for i, collection := range collections {
+		choices[i] = discord.StringChoice{Name: collection, Value: collection}
+	}
+	return choices
+}
+
+// SubCommandAdminRestore puts something back from the trash, or lists what's in it when no key is given.
+func SubCommandAdminRestore(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	collection := options.Find("collection").String()
+	key := strings.TrimSpace(options.Find("key").String())
+	if key == "" {
+		entries, err := storage.GetTrash(kvs, event.GuildID, collection)
+		if err != nil {
+			log.Printf("[%s] /admin restore failed to get the trash: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		return command.Response{Response: response.Ephemeral(describeTrash(entries))}
+	}
+	if collection == "" {
+		return command.Response{Response: response.Ephemeral("To bring something back, I need to know the `collection` too.")}
+	}
+
+	restored, err := kvs.Restore(event.GuildID, collection, key)
+	if errors.Is(err, storage.ErrTrashOccupied) {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("Something new has been made as `%s` in `%s` since, so I can't put the old one back without losing it. Delete the new one first.", key, collection))}
+	}
+	if err != nil {
+		log.Printf("[%s] /admin restore failed to restore %s/%s: %s", event.GuildID, collection, key, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !restored {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There's no `%s` in the trash for `%s`. Things are only kept for %d days.", key, collection, int(storage.TrashRetention.Hours()/24)))}
+	}
+	log.Printf("[%s] <@%s> restored %s/%s from the trash", event.GuildID, event.SenderID(), collection, key)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("`%s` is back in `%s`.", key, collection))}
+}
+
+// describeTrash lists what's in the trash, newest first, and when each is gone for good.
+func describeTrash(entries []storage.TrashEntry) string {
+	if len(entries) == 0 {
+		return "The trash is empty. Nothing has been deleted recently."
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "These were deleted in the last %d days, and can be brought back:", int(storage.TrashRetention.Hours()/24))
+	for _, entry := range entries {
+		fmt.Fprintf(&sb, "\n- `%s` `%s`, deleted <t:%d:R>, gone for good <t:%d:R>", entry.Collection, entry.Key, entry.Deleted, entry.Expires().Unix())
+	}
+	return utility.Substring(sb.String(), 0, 2000)
+}
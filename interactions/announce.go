@@ -0,0 +1,115 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/interactions/timer"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+var announceModule = module.Basic{
+	ModuleName: "announce",
+	CommandHandlers: map[string]command.Handler{
+		"announce": commandAnnounceObject,
+	},
+	Handlers: []module.EventHandler{
+		module.Timer("deletemessage", timer.Handler{Code: TimerDeleteMessage}),
+	},
+}
+
+var commandAnnounceObject = command.Handler{
+	Description: "Make the bot post a message in a channel",
+	Code:        CommandAnnounce,
+	Options: []discord.CommandOption{
+		&discord.ChannelOption{
+			OptionName:  "channel",
+			Description: "Where to post the announcement",
+			Required:    true,
+		},
+		&discord.StringOption{
+			OptionName:  "message",
+			Description: "What to announce",
+			Required:    true,
+		},
+		&discord.StringOption{
+			OptionName:  "expires",
+			Description: "Delete the announcement again after this long, like 2h or 3d12h. Blank to keep it.",
+			Required:    false,
+		},
+	},
+}
+
+// CommandAnnounce processes a command to post an announcement, and maybe schedule it for deletion.
+func CommandAnnounce(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	channelSnowflake, err := cmd.Options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /announce failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	channelID := discord.ChannelID(channelSnowflake)
+	content := cmd.Options.Find("message").String()
+	if len([]rune(content)) > 2000 {
+		return command.Response{Response: response.Ephemeral("That's too long for a single message, sorry. Discord won't take more than 2000 characters.")}
+	}
+
+	var expires time.Duration
+	if expiresText := cmd.Options.Find("expires").String(); expiresText != "" {
+		expires, err = utility.ParseDuration(expiresText)
+		if err != nil || expires <= 0 {
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("I don't understand %q as an amount of time. Try something like 2h or 3d12h.", expiresText))}
+		}
+	}
+
+	message, err := state.SendMessageComplex(channelID, api.SendMessageData{Content: content})
+	if err != nil {
+		log.Printf("[%s] /announce failed to post in <#%s>: %s", event.GuildID, channelID, err)
+		return command.Response{Response: response.Ephemeral("I couldn't post in that channel. Do I have access to it?")}
+	}
+
+	if expires == 0 {
+		return command.Response{Response: response.Ephemeral("Announced in", channelID.Mention())}
+	}
+
+	deleteAt := time.Now().Add(expires)
+	if err := scheduleMessageDeletion(kvs, event.GuildID, message, deleteAt); err != nil {
+		log.Printf("[%s] /announce posted, but failed to schedule the deletion: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("Announced in", channelID.Mention(), "but I couldn't schedule it for deletion. The error has been logged.")}
+	}
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Announced in %s, and it will be deleted <t:%d:R>.", channelID.Mention(), deleteAt.Unix()))}
+}
+
+// scheduleMessageDeletion sets a timer to delete the given message at the given time.
+func scheduleMessageDeletion(kvs storage.KeyValueStore, guildID discord.GuildID, message *discord.Message, when time.Time) error {
+	_, err := timer.Schedule(kvs, guildID, "deletemessage", when, map[string]string{
+		"channel": message.ChannelID.String(),
+		"message": message.ID.String(),
+	})
+	return err
+}
+
+// TimerDeleteMessage deletes the message referenced by the timer.
+func TimerDeleteMessage(state *state.State, kvs storage.KeyValueStore, t storage.Timer) {
+	channelID, err := discord.ParseSnowflake(t.Data["channel"])
+	if err != nil {
+		log.Printf("[%s] Message deletion timer has a weird channel: %s", t.GuildID, err)
+		return
+	}
+	messageID, err := discord.ParseSnowflake(t.Data["message"])
+	if err != nil {
+		log.Printf("[%s] Message deletion timer has a weird message: %s", t.GuildID, err)
+		return
+	}
+	if err := state.DeleteMessage(discord.ChannelID(channelID), discord.MessageID(messageID), "The message expired"); err != nil {
+		log.Printf("[%s] Failed to delete expired message %s in <#%s>: %s", t.GuildID, messageID, channelID, err)
+	}
+}
@@ -0,0 +1,424 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/join"
+	"komainu/interactions/message"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/interactions/timer"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+// antispamSlowmodeLength is how long automatic slowmode lasts before it's put back the way it was.
+const antispamSlowmodeLength = 10 * time.Minute
+
+// antispamDuplicateWindow is how far back identical messages are counted. Longer than the rate window, as copy-paste floods can be slow.
+const antispamDuplicateWindow = 60
+
+var antispamModule = module.Basic{
+	ModuleName: "antispam",
+	CommandHandlers: map[string]command.Handler{
+		"antispam": commandAntispamObject,
+	},
+	Handlers: []module.EventHandler{
+		module.Message(message.Handler{Code: MessageAntispam}),
+		module.Join(join.Handler{Code: JoinAntispam}),
+		module.Timer("slowmodeoff", timer.Handler{Code: TimerSlowmodeOff}),
+	},
+}
+
+var commandAntispamObject = command.Handler{
+	Description: "Automatically deal with spam and raids",
+	Code:        CommandAntispam,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "config",
+			Description: "Change the antispam settings. Anything left blank stays as it is.",
+			Options: []discord.CommandOptionValue{
+				&discord.BooleanOption{
+					OptionName:  "enabled",
+					Description: "Turn antispam on or off",
+					Required:    false,
+				},
+				&discord.IntegerOption{
+					OptionName:  "messages",
+					Description: "How many messages in a row is spam",
+					Required:    false,
+					Min:         option.NewInt(2),
+					Max:         option.NewInt(100),
+				},
+				&discord.IntegerOption{
+					OptionName:  "seconds",
+					Description: "How many seconds those messages have to be sent within",
+					Required:    false,
+					Min:         option.NewInt(1),
+					Max:         option.NewInt(600),
+				},
+				&discord.IntegerOption{
+					OptionName:  "duplicates",
+					Description: "How many identical messages within a minute is spam",
+					Required:    false,
+					Min:         option.NewInt(2),
+					Max:         option.NewInt(100),
+				},
+				&discord.StringOption{
+					OptionName:  "action",
+					Description: "What to do about spam",
+					Required:    false,
+					Choices: []discord.StringChoice{
+						{Name: "Time out the spammer", Value: storage.AntispamTimeout},
+						{Name: "Slow down the channel", Value: storage.AntispamSlowmode},
+					},
+				},
+				&discord.IntegerOption{
+					OptionName:  "joins",
+					Description: "How many people joining is a raid",
+					Required:    false,
+					Min:         option.NewInt(2),
+					Max:         option.NewInt(1000),
+				},
+				&discord.IntegerOption{
+					OptionName:  "join_seconds",
+					Description: "How many seconds they have to join within",
+					Required:    false,
+					Min:         option.NewInt(1),
+					Max:         option.NewInt(3600),
+				},
+				&discord.StringOption{
+					OptionName:  "raid_action",
+					Description: "What to do about a raid",
+					Required:    false,
+					Choices: []discord.StringChoice{
+						{Name: "Just report it", Value: storage.RaidReport},
+						{Name: "Lock down every channel", Value: storage.RaidLockdown},
+					},
+				},
+				&discord.ChannelOption{
+					OptionName:  "log",
+					Description: "Where to report what antispam does",
+					Required:    false,
+				},
+			},
+		},
+	},
+}
+
+// CommandAntispam processes the /antispam command.
+func CommandAntispam(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 || cmd.Options[0].Name != "config" {
+		log.Printf("[%s] /antispam command structure is somehow not a single config subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	options := cmd.Options[0].Options
+	config, err := storage.GetAntispamConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to get antispam config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+
+	for _, opt := range options {
+		var err error
+		var number int64
+		if opt.Type == discord.IntegerOptionType {
+			number, err = opt.IntValue()
+		}
+		switch opt.Name {
+		case "enabled":
+			config.Enabled, err = opt.BoolValue()
+		case "messages":
+			config.MessageLimit = int(number)
+		case "seconds":
+			config.MessageWindow = number
+		case "duplicates":
+			config.DuplicateLimit = int(number)
+		case "action":
+			config.SpamAction = opt.String()
+		case "joins":
+			config.JoinLimit = int(number)
+		case "join_seconds":
+			config.JoinWindow = number
+		case "raid_action":
+			config.RaidAction = opt.String()
+		case "log":
+			var snowflake discord.Snowflake
+			snowflake, err = opt.SnowflakeValue()
+			config.LogChannelID = discord.ChannelID(snowflake)
+		}
+		if err != nil {
+			log.Printf("[%s] /antispam config failed to get the %s option: %s", event.GuildID, opt.Name, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+	}
+
+	if err := storage.SetAntispamConfig(kvs, event.GuildID, config); err != nil {
+		log.Printf("[%s] Failed to store antispam config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> changed the antispam config", event.GuildID, event.SenderID())
+	return command.Response{Response: response.Ephemeral(describeAntispamConfig(config))}
+}
+
+func describeAntispamConfig(config storage.AntispamConfig) string {
+	var sb strings.Builder
+	if config.Enabled {
+		sb.WriteString("Antispam is **on**.\n")
+	} else {
+		sb.WriteString("Antispam is **off**.\n")
+	}
+	fmt.Fprintf(&sb, "Spam is %d messages in %d seconds, or %d identical messages within a minute. ", config.MessageLimit, config.MessageWindow, config.DuplicateLimit)
+	if config.SpamAction == storage.AntispamSlowmode {
+		sb.WriteString("The channel is slowed down for a while.\n")
+	} else {
+		sb.WriteString("The spammer is timed out.\n")
+	}
+	fmt.Fprintf(&sb, "A raid is %d people joining in %d seconds. ", config.JoinLimit, config.JoinWindow)
+	if config.RaidAction == storage.RaidLockdown {
+		sb.WriteString("Every channel is locked down.\n")
+	} else {
+		sb.WriteString("It is only reported.\n")
+	}
+	if config.LogChannelID.IsValid() {
+		fmt.Fprintf(&sb, "Reports go to %s.", config.LogChannelID.Mention())
+	} else {
+		sb.WriteString("Reports go nowhere. Set a `log` channel!")
+	}
+	return sb.String()
+}
+
+// spamMessage is a message recently sent by someone, as far as the spam tracker cares.
+type spamMessage struct {
+	When    int64
+	Channel discord.ChannelID
+	Content string
+}
+
+// spamTracker keeps recent messages and joins in memory, to look for patterns.
+// Nothing here is stored, as it's only interesting for a minute or two anyway.
+type spamTracker struct {
+	lock     sync.Mutex
+	messages map[discord.GuildID]map[discord.UserID][]spamMessage
+	joins    map[discord.GuildID][]int64
+}
+
+var antispam = spamTracker{
+	messages: map[discord.GuildID]map[discord.UserID][]spamMessage{},
+	joins:    map[discord.GuildID][]int64{},
+}
+
+// message records the message, and returns how many messages the user sent within the window, and how many of them were identical to this one.
+func (tracker *spamTracker) message(guildID discord.GuildID, userID discord.UserID, msg spamMessage, window int64) (recent int, duplicates int) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	if tracker.messages[guildID] == nil {
+		tracker.messages[guildID] = map[discord.UserID][]spamMessage{}
+	}
+	keepFrom := msg.When - window
+	if antispamDuplicateWindow > window {
+		keepFrom = msg.When - antispamDuplicateWindow
+	}
+	kept := []spamMessage{}
+	for _, previous := range tracker.messages[guildID][userID] {
+		if previous.When < keepFrom {
+			continue
+		}
+		kept = append(kept, previous)
+		if previous.When >= msg.When-window {
+			recent++
+		}
+		if previous.Content == msg.Content && msg.Content != "" {
+			duplicates++
+		}
+	}
+	tracker.messages[guildID][userID] = append(kept, msg)
+	return recent + 1, duplicates + 1
+}
+
+// forget clears the user's message history, so they aren't punished again for the same spam.
+func (tracker *spamTracker) forget(guildID discord.GuildID, userID discord.UserID) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	if tracker.messages[guildID] != nil {
+		delete(tracker.messages[guildID], userID)
+	}
+}
+
+// join records someone joining, and returns how many have joined within the window.
+func (tracker *spamTracker) join(guildID discord.GuildID, when int64, window int64) int {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	kept := []int64{}
+	for _, previous := range tracker.joins[guildID] {
+		if previous >= when-window {
+			kept = append(kept, previous)
+		}
+	}
+	tracker.joins[guildID] = append(kept, when)
+	return len(tracker.joins[guildID])
+}
+
+// MessageAntispam looks for someone sending too many messages, or the same message over and over.
+func MessageAntispam(state *state.State, kvs storage.KeyValueStore, event *gateway.MessageCreateEvent) {
+	if event.GuildID == discord.NullGuildID || event.Author.Bot {
+		return
+	}
+	config, err := storage.GetAntispamConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Antispam failed to get config: %s", event.GuildID, err)
+		return
+	}
+	if !config.Enabled {
+		return
+	}
+	recent, duplicates := antispam.message(event.GuildID, event.Author.ID, spamMessage{
+		When:    time.Now().Unix(),
+		Channel: event.ChannelID,
+		Content: event.Content,
+	}, config.MessageWindow)
+
+	reason := ""
+	if recent >= config.MessageLimit {
+		reason = fmt.Sprintf("%d messages in %d seconds", recent, config.MessageWindow)
+	} else if duplicates >= config.DuplicateLimit {
+		reason = fmt.Sprintf("the same message %d times", duplicates)
+	} else {
+		return
+	}
+	antispam.forget(event.GuildID, event.Author.ID)
+
+	action := ""
+	if config.SpamAction == storage.AntispamSlowmode {
+		action = antispamSlowmode(state, kvs, event.GuildID, event.ChannelID, config)
+	} else {
+		action = antispamTimeout(state, event.GuildID, event.Author.ID, config, reason)
+	}
+	log.Printf("[%s] Antispam caught <@%s> in <#%s> sending %s, and %s", event.GuildID, event.Author.ID, event.ChannelID, reason, action)
+	antispamReport(state, config, fmt.Sprintf("%s sent %s in %s, so I %s.", event.Author.ID.Mention(), reason, event.ChannelID.Mention(), action))
+}
+
+func antispamTimeout(state *state.State, guildID discord.GuildID, userID discord.UserID, config storage.AntispamConfig, reason string) string {
+	if err := utility.CanModerate(state, guildID, nil, userID); utility.OutOfReach(err) {
+		log.Printf("[%s] Antispam can't time out <@%s>: %s", guildID, userID, err)
+		return "couldn't time them out, as they're out of my reach"
+	}
+	until := discord.NewTimestamp(time.Now().Add(time.Duration(config.TimeoutSeconds) * time.Second))
+	err := state.ModifyMember(guildID, userID, api.ModifyMemberData{
+		CommunicationDisabledUntil: &until,
+		AuditLogReason:             api.AuditLogReason("Antispam: " + reason),
+	})
+	if err != nil {
+		log.Printf("[%s] Antispam failed to time out <@%s>: %s", guildID, userID, err)
+		return "tried to time them out, but failed"
+	}
+	return fmt.Sprintf("timed them out until <t:%d:t>", until.Time().Unix())
+}
+
+func antispamSlowmode(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID, channelID discord.ChannelID, config storage.AntispamConfig) string {
+	channel, err := state.Channel(channelID)
+	if err != nil {
+		log.Printf("[%s] Antispam failed to get <#%s> for slowmode: %s", guildID, channelID, err)
+		return "tried to slow the channel down, but failed"
+	}
+	if int64(channel.UserRateLimit) >= config.SlowmodeSeconds {
+		return "left the slowmode that was already there alone"
+	}
+	err = state.ModifyChannel(channelID, api.ModifyChannelData{
+		UserRateLimit:  option.NewNullableUint(uint(config.SlowmodeSeconds)),
+		AuditLogReason: api.AuditLogReason("Antispam"),
+	})
+	if err != nil {
+		log.Printf("[%s] Antispam failed to set slowmode in <#%s>: %s", guildID, channelID, err)
+		return "tried to slow the channel down, but failed"
+	}
+	_, err = timer.Schedule(kvs, guildID, "slowmodeoff", time.Now().Add(antispamSlowmodeLength), map[string]string{
+		"channel":  channelID.String(),
+		"previous": strconv.FormatInt(int64(channel.UserRateLimit), 10),
+	})
+	if err != nil {
+		log.Printf("[%s] Antispam failed to schedule the end of slowmode in <#%s>: %s", guildID, channelID, err)
+		return "slowed the channel down, but couldn't schedule speeding it back up"
+	}
+	return fmt.Sprintf("slowed the channel down for %s", antispamSlowmodeLength)
+}
+
+// TimerSlowmodeOff puts the slowmode in a channel back to what it was.
+func TimerSlowmodeOff(state *state.State, kvs storage.KeyValueStore, t storage.Timer) {
+	channelID, err := discord.ParseSnowflake(t.Data["channel"])
+	if err != nil {
+		log.Printf("[%s] Slowmode timer has a weird channel: %s", t.GuildID, err)
+		return
+	}
+	previous, err := strconv.ParseUint(t.Data["previous"], 10, 64)
+	if err != nil {
+		log.Printf("[%s] Slowmode timer has a weird previous value: %s", t.GuildID, err)
+		return
+	}
+	reason := t.Data["reason"]
+	if reason == "" {
+		reason = "Antispam slowmode over"
+	}
+	err = state.ModifyChannel(discord.ChannelID(channelID), api.ModifyChannelData{
+		UserRateLimit:  option.NewNullableUint(uint(previous)),
+		AuditLogReason: api.AuditLogReason(reason),
+	})
+	if err != nil {
+		log.Printf("[%s] Failed to put slowmode in <#%s> back: %s", t.GuildID, channelID, err)
+	}
+}
+
+// JoinAntispam looks for lots of people joining at once.
+func JoinAntispam(state *state.State, kvs storage.KeyValueStore, event *gateway.GuildMemberAddEvent) {
+	config, err := storage.GetAntispamConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Antispam failed to get config: %s", event.GuildID, err)
+		return
+	}
+	if !config.Enabled {
+		return
+	}
+	joined := antispam.join(event.GuildID, time.Now().Unix(), config.JoinWindow)
+	if joined != config.JoinLimit {
+		return // Only acting when the limit is reached, not on every join after.
+	}
+
+	report := fmt.Sprintf("%d people joined in %d seconds. This might be a raid!", joined, config.JoinWindow)
+	if config.RaidAction == storage.RaidLockdown {
+		locked, err := storage.Lockdown(state, kvs, event.GuildID, discord.NullChannelID, "Possible raid", false)
+		if err != nil {
+			log.Printf("[%s] Antispam lockdown failed after %d channels: %s", event.GuildID, len(locked), err)
+			report += fmt.Sprintf(" I tried to lock everything down, but only managed %d channels.", len(locked))
+		} else {
+			report += fmt.Sprintf(" I locked down %d channels. Use `/lockdown end` once it's over.", len(locked))
+		}
+	}
+	log.Printf("[%s] Antispam: %s", event.GuildID, report)
+	antispamReport(state, config, report)
+}
+
+// antispamReport posts what antispam did in the log channel, if there is one.
+func antispamReport(state *state.State, config storage.AntispamConfig, report string) {
+	if !config.LogChannelID.IsValid() {
+		return
+	}
+	_, err := state.SendMessageComplex(config.LogChannelID, api.SendMessageData{
+		Content:         "🚨 " + report,
+		AllowedMentions: &api.AllowedMentions{Parse: []api.AllowedMentionType{}},
+	})
+	if err != nil {
+		log.Printf("Antispam failed to report in <#%s>: %s", config.LogChannelID, err)
+	}
+}
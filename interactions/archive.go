@@ -0,0 +1,267 @@
+package interactions
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"github.com/diamondburned/arikawa/v3/utils/sendpart"
+)
+
+// archiveMaxMessages is the most messages a single transcript can have, so the file stays small enough to upload.
+const archiveMaxMessages = 10000
+
+var archiveModule = module.Basic{
+	ModuleName: "archive",
+	CommandHandlers: map[string]command.Handler{
+		"archive": commandArchiveObject,
+	},
+}
+
+var commandArchiveObject = command.Handler{
+	Description: "Save what's been said in a channel to a file",
+	Code:        CommandArchive,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "channel",
+			Description: "Make a transcript of a channel",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:  "channel",
+					Description: "The channel to archive",
+					Required:    true,
+				},
+				&discord.StringOption{
+					OptionName:  "since",
+					Description: "A date, like 2024-07-01, or how far back, like 7d. Default is from the very start.",
+					Required:    false,
+				},
+				&discord.StringOption{
+					OptionName:  "format",
+					Description: "What kind of file to make. Default is a web page.",
+					Required:    false,
+					Choices: []discord.StringChoice{
+						{Name: "Web page (HTML)", Value: "html"},
+						{Name: "JSON", Value: "json"},
+					},
+				},
+			},
+		},
+	},
+}
+
+// archiveRunning keeps track of the guilds with an archive being made, so they don't pile up.
+var archiveRunning = map[discord.GuildID]bool{}
+var archiveLock sync.Mutex
+
+// archiveMessage is a single message in a transcript.
+type archiveMessage struct {
+	ID          discord.MessageID `json:"id"`
+	AuthorID    discord.UserID    `json:"author_id"`
+	Author      string            `json:"author"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Edited      *time.Time        `json:"edited,omitempty"`
+	Content     string            `json:"content"`
+	Attachments []string          `json:"attachments,omitempty"`
+}
+
+// archiveTranscript is everything that goes in the file.
+type archiveTranscript struct {
+	Guild     string            `json:"guild"`
+	Channel   string            `json:"channel"`
+	ChannelID discord.ChannelID `json:"channel_id"`
+	Since     time.Time         `json:"since"`
+	Made      time.Time         `json:"made"`
+	Truncated bool              `json:"truncated"` // There were more than archiveMaxMessages, and only the oldest ones are here.
+	Messages  []archiveMessage  `json:"messages"`
+}
+
+// CommandArchive processes the /archive command, dispatching to the right subcommand.
+func CommandArchive(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /archive command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "channel":
+		return SubCommandArchiveChannel(state, kvs, event, cmd.Options[0].Options)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// archiveSince figures out when the transcript starts, from either a date or how long ago.
+func archiveSince(kvs storage.KeyValueStore, guildID discord.GuildID, input string) (time.Time, error) {
+	if duration, err := utility.ParseDuration(input); err == nil {
+		return time.Now().Add(-duration), nil
+	}
+	location, err := storage.GetTimezone(kvs, guildID)
+	if err != nil {
+		log.Printf("[%s] /archive failed to get the time zone, going with UTC: %s", guildID, err)
+	}
+	since, err := time.ParseInLocation("2006-01-02", input, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("I don't understand %q. Write a date like 2024-07-01, or how far back, like 7d.", input)
+	}
+	return since, nil
+}
+
+// SubCommandArchiveChannel makes a transcript of the channel in the background, saying how far it's got as it goes.
+func SubCommandArchiveChannel(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /archive channel failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	channelID := discord.ChannelID(channelSnowflake)
+	channel, err := state.Channel(channelID)
+	if err != nil || channel.GuildID != event.GuildID {
+		return command.Response{Response: response.Ephemeral("I can't see that channel.")}
+	}
+	since := channelID.Time()
+	if input := strings.TrimSpace(options.Find("since").String()); input != "" {
+		since, err = archiveSince(kvs, event.GuildID, input)
+		if err != nil {
+			return command.Response{Response: response.Ephemeral(err.Error())}
+		}
+	}
+	format := options.Find("format").String()
+	if format == "" {
+		format = "html"
+	}
+
+	archiveLock.Lock()
+	if archiveRunning[event.GuildID] {
+		archiveLock.Unlock()
+		return command.Response{Response: response.Ephemeral("There's already an archive being made here. Wait for that one to finish first.")}
+	}
+	archiveRunning[event.GuildID] = true
+	archiveLock.Unlock()
+	log.Printf("[%s] <@%s> started archiving <#%s> since %s", event.GuildID, event.SenderID(), channelID, since.Format(time.RFC3339))
+
+	return command.DeferredDataProgress(state, event, true, func(progress func(string)) api.EditInteractionResponseData {
+		defer func() {
+			archiveLock.Lock()
+			delete(archiveRunning, event.GuildID)
+			archiveLock.Unlock()
+		}()
+		transcript := archiveTranscript{
+			Channel:   channel.Name,
+			ChannelID: channelID,
+			Since:     since.UTC(),
+			Made:      time.Now().UTC(),
+			Messages:  []archiveMessage{},
+		}
+		if guild, err := state.Guild(event.GuildID); err == nil {
+			transcript.Guild = guild.Name
+		}
+
+		after := discord.MessageID(discord.NewSnowflake(since))
+		for {
+			progress(fmt.Sprintf("Archiving %s... %d messages so far.", channelID.Mention(), len(transcript.Messages)))
+			messages, err := state.Client.MessagesAfter(channelID, after, 100)
+			if err != nil {
+				log.Printf("[%s] /archive failed to get messages in <#%s>: %s", event.GuildID, channelID, err)
+				return api.EditInteractionResponseData{Content: option.NewNullableString("I couldn't read the channel, so there's no archive. Do I have access to its history? The error has been logged.")}
+			}
+			// They come newest first.
+			for i := len(messages) - 1; i >= 0; i-- {
+				transcript.Messages = append(transcript.Messages, newArchiveMessage(messages[i]))
+			}
+			if len(messages) < 100 {
+				break
+			}
+			if len(transcript.Messages) >= archiveMaxMessages {
+				transcript.Truncated = true
+				transcript.Messages = transcript.Messages[:archiveMaxMessages]
+				break
+			}
+			after = messages[0].ID
+		}
+
+		var file bytes.Buffer
+		if err := writeArchive(&file, format, transcript); err != nil {
+			log.Printf("[%s] /archive failed to write the transcript of <#%s>: %s", event.GuildID, channelID, err)
+			return api.EditInteractionResponseData{Content: option.NewNullableString("I couldn't write the archive. The error has been logged.")}
+		}
+		content := fmt.Sprintf("Here's the archive of %s, with %d messages.", channelID.Mention(), len(transcript.Messages))
+		if transcript.Truncated {
+			content += fmt.Sprintf(" That's as many as I'll put in one file, so it stops <t:%d:f>. Archive again from there for the rest.", transcript.Messages[len(transcript.Messages)-1].Timestamp.Unix())
+		}
+		log.Printf("[%s] Archived %d messages in <#%s> for <@%s>", event.GuildID, len(transcript.Messages), channelID, event.SenderID())
+		return api.EditInteractionResponseData{
+			Content: option.NewNullableString(content),
+			Files:   []sendpart.File{{Name: fmt.Sprintf("archive_%s_%s.%s", channel.Name, time.Now().Format("2006-01-02"), format), Reader: &file}},
+		}
+	})
+}
+
+func newArchiveMessage(message discord.Message) archiveMessage {
+	archived := archiveMessage{
+		ID:        message.ID,
+		AuthorID:  message.Author.ID,
+		Author:    message.Author.Tag(),
+		Timestamp: message.Timestamp.Time().UTC(),
+		Content:   message.Content,
+	}
+	if message.EditedTimestamp.IsValid() {
+		edited := message.EditedTimestamp.Time().UTC()
+		archived.Edited = &edited
+	}
+	for _, attachment := range message.Attachments {
+		archived.Attachments = append(archived.Attachments, attachment.URL)
+	}
+	return archived
+}
+
+// writeArchive writes the transcript in the given format.
+func writeArchive(file *bytes.Buffer, format string, transcript archiveTranscript) error {
+	if format == "json" {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(transcript)
+	}
+	return archiveHTML.Execute(file, transcript)
+}
+
+var archiveHTML = template.Must(template.New("archive").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>#{{.Channel}} - {{.Guild}}</title>
+<style>
+body { font-family: sans-serif; background: #313338; color: #dbdee1; margin: 2em; }
+.message { margin: 0.5em 0; }
+.author { font-weight: bold; color: #f2f3f5; }
+.time, .edited { color: #949ba4; font-size: 0.8em; }
+.content { white-space: pre-wrap; }
+a { color: #00a8fc; }
+</style>
+</head>
+<body>
+<h1>#{{.Channel}}</h1>
+<p>{{.Guild}}, since {{.Since.Format "2006-01-02 15:04 MST"}}. Archived {{.Made.Format "2006-01-02 15:04 MST"}}, with {{len .Messages}} messages.{{if .Truncated}} There were more, but this is as many as fit in one file.{{end}}</p>
+{{range .Messages}}<div class="message" id="{{.ID}}">
+<span class="author">{{.Author}}</span> <span class="time">{{.Timestamp.Format "2006-01-02 15:04:05"}}</span>{{if .Edited}} <span class="edited">(edited)</span>{{end}}
+<div class="content">{{.Content}}</div>
+{{range .Attachments}}<div class="attachment"><a href="{{.}}">{{.}}</a></div>
+{{end}}</div>
+{{end}}</body>
+</html>
+`))
@@ -3,19 +3,20 @@ package interactions
 import (
 	"fmt"
 	"komainu/interactions/command"
+	"komainu/interactions/module"
 	"komainu/interactions/response"
-	"komainu/storage"
 	"log"
 	"math/rand"
 	"time"
 
 	"github.com/diamondburned/arikawa/v3/discord"
-	"github.com/diamondburned/arikawa/v3/gateway"
-	"github.com/diamondburned/arikawa/v3/state"
 )
 
-func init() {
-	command.Register("ateball", commandAteHandler)
+var ateBallModule = module.Basic{
+	ModuleName: "ateball",
+	CommandHandlers: map[string]command.Handler{
+		"ateball": commandAteHandler,
+	},
 }
 
 var commandAteHandler = command.Handler{
@@ -66,7 +67,8 @@ var foodEmoji = []rune{
 	'🥢', '🍽', '🍴', '🥄', '🔪', '🏺',
 }
 
-func CommandAte(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, cmd *discord.CommandInteraction) command.Response {
+func CommandAte(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	event := ctx.Event
 
 	if cmd.Options == nil || len(cmd.Options) != 1 || cmd.Options[0].String() == "" {
 		log.Printf("[%s] /ateball command structure somehow did not include the question portion. Wat.\n", event.GuildID)
@@ -28,11 +28,19 @@ func Register(name string, handler Handler) {
 	autocompleters[name] = handler
 }
 
+// GetAutocompleteValue finds what has been typed so far in the option being filled in, looking inside subcommands too.
 func GetAutocompleteValue(interaction *discord.AutocompleteInteraction) (bool, json.Raw) {
-	for _, option := range interaction.Options {
+	return focusedValue(interaction.Options)
+}
+
+func focusedValue(options []discord.AutocompleteOption) (bool, json.Raw) {
+	for _, option := range options {
 		if option.Focused {
 			return true, option.Value
 		}
+		if found, value := focusedValue(option.Options); found {
+			return true, value
+		}
 	}
 	return false, json.Raw{}
 }
@@ -0,0 +1,675 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/message"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+const automodDefaultTimeout = 10 * time.Minute
+
+var automodInvitePattern = regexp.MustCompile(`(?i)(discord\.gg|discord(app)?\.com/invite)/\S+`)
+var automodLinkPattern = regexp.MustCompile(`(?i)https?://([^/\s:?#<>]+)`)
+
+// automodPatterns caches compiled filter patterns, as compiling them for every single message is a waste.
+var automodPatterns = map[string]*regexp.Regexp{}
+var automodPatternsLock sync.Mutex
+
+var automodModule = module.Basic{
+	ModuleName: "automod",
+	CommandHandlers: map[string]command.Handler{
+		"automod": commandAutomodObject,
+	},
+	Handlers: []module.EventHandler{
+		module.Message(message.Handler{Code: MessageAutomod, NeedsContent: "Automod"}),
+		module.Hook("timeline source", func() { registerTimelineSource(timelineWarnings) }),
+	},
+}
+
+var commandAutomodObject = command.Handler{
+	Description: "Automatically deal with messages that break the rules",
+	Code:        CommandAutomod,
+	Feature:     "automod",
+	Options: []discord.CommandOption{
+		&discord.SubcommandGroupOption{
+			OptionName:  "filter",
+			Description: "What automod looks for",
+			Subcommands: []*discord.SubcommandOption{
+				{
+					OptionName:  "add",
+					Description: "Add something for automod to look for",
+					Options: []discord.CommandOptionValue{
+						&discord.StringOption{
+							OptionName:  "kind",
+							Description: "What kind of filter this is",
+							Required:    true,
+							Choices: []discord.StringChoice{
+								{Name: "Banned word", Value: storage.AutomodWord},
+								{Name: "Regular expression", Value: storage.AutomodRegex},
+								{Name: "Invite links", Value: storage.AutomodInvite},
+							},
+						},
+						&discord.StringOption{
+							OptionName:  "action",
+							Description: "What to do about it. Each action also does the ones before it.",
+							Required:    true,
+							Choices: []discord.StringChoice{
+								{Name: "Delete the message", Value: storage.AutomodDelete},
+								{Name: "Warn the author", Value: storage.AutomodWarn},
+								{Name: "Time the author out", Value: storage.AutomodTimeout},
+							},
+						},
+						&discord.StringOption{
+							OptionName:  "pattern",
+							Description: "The word or regular expression. Not needed for invite links.",
+							Required:    false,
+						},
+					},
+				},
+				{
+					OptionName:  "remove",
+					Description: "Remove a filter",
+					Options: []discord.CommandOptionValue{
+						&discord.IntegerOption{
+							OptionName:  "id",
+							Description: "The number of the filter, as shown by /automod filter list",
+							Required:    true,
+						},
+					},
+				},
+				{
+					OptionName:  "list",
+					Description: "List the filters",
+					Options:     []discord.CommandOptionValue{},
+				},
+			},
+		},
+		&discord.SubcommandGroupOption{
+			OptionName:  "links",
+			Description: "What links and attachments are allowed",
+			Subcommands: []*discord.SubcommandOption{
+				{
+					OptionName:  "set",
+					Description: "Restrict links or attachments, replacing any earlier rule for the same channel",
+					Options: []discord.CommandOptionValue{
+						&discord.StringOption{
+							OptionName:  "kind",
+							Description: "What to restrict",
+							Required:    true,
+							Choices: []discord.StringChoice{
+								{Name: "Links, by domain", Value: storage.AutomodLinks},
+								{Name: "Attachments, by file type", Value: storage.AutomodAttachments},
+							},
+						},
+						&discord.StringOption{
+							OptionName:  "mode",
+							Description: "If the list is what's allowed, or what's blocked",
+							Required:    true,
+							Choices: []discord.StringChoice{
+								{Name: "Only allow these", Value: storage.AutomodAllowOnly},
+								{Name: "Block these", Value: storage.AutomodBlock},
+							},
+						},
+						&discord.StringOption{
+							OptionName:  "list",
+							Description: "Domains like \"youtube.com\" or file types like \"png\", separated by spaces or commas",
+							Required:    true,
+						},
+						&discord.StringOption{
+							OptionName:  "action",
+							Description: "What to do about it. Each action also does the ones before it.",
+							Required:    true,
+							Choices: []discord.StringChoice{
+								{Name: "Delete the message", Value: storage.AutomodDelete},
+								{Name: "Warn the author", Value: storage.AutomodWarn},
+								{Name: "Time the author out", Value: storage.AutomodTimeout},
+							},
+						},
+						&discord.ChannelOption{
+							OptionName:  "channel",
+							Description: "The channel this applies in. Blank for every channel without a rule of its own.",
+							Required:    false,
+						},
+					},
+				},
+				{
+					OptionName:  "clear",
+					Description: "Remove a link or attachment rule",
+					Options: []discord.CommandOptionValue{
+						&discord.StringOption{
+							OptionName:  "kind",
+							Description: "What kind of rule to remove",
+							Required:    true,
+							Choices: []discord.StringChoice{
+								{Name: "Links, by domain", Value: storage.AutomodLinks},
+								{Name: "Attachments, by file type", Value: storage.AutomodAttachments},
+							},
+						},
+						&discord.ChannelOption{
+							OptionName:  "channel",
+							Description: "The channel the rule is for. Blank for the rule for every channel.",
+							Required:    false,
+						},
+					},
+				},
+				{
+					OptionName:  "list",
+					Description: "List the link and attachment rules",
+					Options:     []discord.CommandOptionValue{},
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "config",
+			Description: "Set where automod reports, and how long timeouts are",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:  "log",
+					Description: "Where to report what automod does",
+					Required:    false,
+				},
+				&discord.IntegerOption{
+					OptionName:  "timeout",
+					Description: "How many minutes a timeout lasts. Default is 10.",
+					Required:    false,
+					Min:         option.NewInt(1),
+					Max:         option.NewInt(40320), // Discord won't go beyond 28 days.
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "exempt",
+			Description: "Make automod ignore a channel, or stop ignoring it",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:  "channel",
+					Description: "The channel to toggle",
+					Required:    true,
+				},
+			},
+		},
+	},
+}
+
+// CommandAutomod processes the /automod command, dispatching to the right subcommand.
+func CommandAutomod(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /automod command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	if cmd.Options[0].Type == discord.SubcommandOptionType {
+		switch cmd.Options[0].Name {
+		case "config":
+			return SubCommandAutomodConfig(kvs, event, cmd.Options[0].Options)
+		case "exempt":
+			return SubCommandAutomodExempt(kvs, event, cmd.Options[0].Options)
+		default:
+			return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+		}
+	}
+	group := cmd.Options[0]
+	if len(group.Options) != 1 {
+		log.Printf("[%s] /automod %s command structure is somehow not a single subcommand. Wat.\n", event.GuildID, group.Name)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	sub := group.Options[0]
+	switch group.Name + " " + sub.Name {
+	case "filter add":
+		return SubCommandAutomodFilterAdd(kvs, event, sub.Options)
+	case "filter remove":
+		return SubCommandAutomodFilterRemove(kvs, event, sub.Options)
+	case "filter list":
+		return SubCommandAutomodFilterList(kvs, event)
+	case "links set":
+		return SubCommandAutomodLinksSet(kvs, event, sub.Options)
+	case "links clear":
+		return SubCommandAutomodLinksClear(kvs, event, sub.Options)
+	case "links list":
+		return SubCommandAutomodLinksList(kvs, event)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandAutomodFilterAdd validates and stores a new filter.
+func SubCommandAutomodFilterAdd(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	filter := storage.AutomodFilter{
+		GuildID: event.GuildID,
+		Kind:    options.Find("kind").String(),
+		Action:  options.Find("action").String(),
+		Pattern: strings.TrimSpace(options.Find("pattern").String()),
+	}
+	if filter.Kind != storage.AutomodInvite {
+		if filter.Pattern == "" {
+			return command.Response{Response: response.Ephemeral("You have to give a pattern for that kind of filter.")}
+		}
+		if _, err := automodPattern(&filter); err != nil {
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("That pattern doesn't work: %s", err))}
+		}
+	}
+	if err := storage.AddAutomodFilter(kvs, &filter); err != nil {
+		log.Printf("[%s] Failed to add automod filter: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> added automod filter #%d: %s, %s", event.GuildID, event.SenderID(), filter.ID, filter.Describe(), filter.Action)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Added filter #%d: %s, action %s.", filter.ID, filter.Describe(), filter.Action))}
+}
+
+// SubCommandAutomodFilterRemove removes a filter.
+func SubCommandAutomodFilterRemove(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	id, err := options.Find("id").IntValue()
+	if err != nil {
+		log.Printf("[%s] /automod filter remove failed to get the ID: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	exist, err := storage.RemoveAutomodFilter(kvs, event.GuildID, id)
+	if err != nil {
+		log.Printf("[%s] Failed to remove automod filter #%d: %s", event.GuildID, id, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no filter #%d.", id))}
+	}
+	log.Printf("[%s] <@%s> removed automod filter #%d", event.GuildID, event.SenderID(), id)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Filter #%d removed.", id))}
+}
+
+// SubCommandAutomodFilterList lists the filters, along with the rest of the automod setup.
+func SubCommandAutomodFilterList(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	filters, err := storage.GetAutomodFilters(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to list automod filters: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	config, err := storage.GetAutomodConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to get automod config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	var sb strings.Builder
+	if len(filters) == 0 {
+		sb.WriteString("There are no filters.\n")
+	}
+	for _, filter := range filters {
+		fmt.Fprintf(&sb, "#%d: %s, action %s\n", filter.ID, filter.Describe(), filter.Action)
+	}
+	if config.LogChannelID.IsValid() {
+		fmt.Fprintf(&sb, "\nReporting to %s.", config.LogChannelID.Mention())
+	} else {
+		sb.WriteString("\nNot reporting anywhere.")
+	}
+	if len(config.Exempt) > 0 {
+		sb.WriteString("\nIgnoring:")
+		for channelID := range config.Exempt {
+			sb.WriteString(" " + channelID.Mention())
+		}
+	}
+	return command.Response{Response: response.Ephemeral(sb.String())}
+}
+
+// automodRuleChannel gets the channel option, or the null channel if it was left blank.
+func automodRuleChannel(event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) (discord.ChannelID, error) {
+	if options.Find("channel").Name == "" {
+		return discord.NullChannelID, nil
+	}
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	return discord.ChannelID(channelSnowflake), err
+}
+
+// SubCommandAutomodLinksSet validates and stores a link or attachment rule.
+func SubCommandAutomodLinksSet(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	channelID, err := automodRuleChannel(event, options)
+	if err != nil {
+		log.Printf("[%s] /automod links set failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	rule := storage.AutomodListRule{
+		ChannelID: channelID,
+		Kind:      options.Find("kind").String(),
+		Mode:      options.Find("mode").String(),
+		Action:    options.Find("action").String(),
+	}
+	for _, entry := range strings.FieldsFunc(strings.ToLower(options.Find("list").String()), func(r rune) bool {
+		return r == ',' || r == ' '
+	}) {
+		if rule.Kind == storage.AutomodLinks {
+			entry = strings.TrimPrefix(strings.TrimPrefix(entry, "https://"), "http://")
+			entry = strings.TrimSuffix(entry, "/")
+		} else {
+			entry = strings.TrimPrefix(entry, ".")
+		}
+		if entry != "" {
+			rule.Entries = append(rule.Entries, entry)
+		}
+	}
+	if len(rule.Entries) == 0 {
+		return command.Response{Response: response.Ephemeral("The list can't be empty. To remove a rule, use `/automod links clear`.")}
+	}
+	if err := storage.SetAutomodListRule(kvs, event.GuildID, rule); err != nil {
+		log.Printf("[%s] Failed to store automod %s rule: %s", event.GuildID, rule.Kind, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> set an automod rule: %s, action %s", event.GuildID, event.SenderID(), rule.Describe(), rule.Action)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Automod is now %s, action %s.", rule.Describe(), rule.Action))}
+}
+
+// SubCommandAutomodLinksClear removes a link or attachment rule.
+func SubCommandAutomodLinksClear(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	channelID, err := automodRuleChannel(event, options)
+	if err != nil {
+		log.Printf("[%s] /automod links clear failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	kind := options.Find("kind").String()
+	exist, err := storage.RemoveAutomodListRule(kvs, event.GuildID, channelID, kind)
+	if err != nil {
+		log.Printf("[%s] Failed to remove automod %s rule: %s", event.GuildID, kind, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	where := "for every channel"
+	if channelID.IsValid() {
+		where = "for " + channelID.Mention()
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no %s rule %s.", kind, where))}
+	}
+	log.Printf("[%s] <@%s> removed the automod %s rule %s", event.GuildID, event.SenderID(), kind, where)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("The %s rule %s is removed.", kind, where))}
+}
+
+// SubCommandAutomodLinksList lists the link and attachment rules.
+func SubCommandAutomodLinksList(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	rules, err := storage.GetAutomodListRules(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to list automod link rules: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(rules) == 0 {
+		return command.Response{Response: response.Ephemeral("There are no link or attachment rules.")}
+	}
+	lines := make([]string, len(rules))
+	for i, rule := range rules {
+		lines[i] = fmt.Sprintf("%s, action %s", rule.Describe(), rule.Action)
+	}
+	return command.Response{Response: response.Ephemeral(strings.Join(lines, "\n"))}
+}
+
+// SubCommandAutomodConfig sets the log channel and timeout length.
+func SubCommandAutomodConfig(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	config, err := storage.GetAutomodConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to get automod config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	config.LogChannelID = discord.NullChannelID
+	if options.Find("log").Name != "" {
+		logSnowflake, err := options.Find("log").SnowflakeValue()
+		if err != nil {
+			log.Printf("[%s] /automod config failed to get log channel snowflake: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+		}
+		config.LogChannelID = discord.ChannelID(logSnowflake)
+	}
+	if options.Find("timeout").Name != "" {
+		minutes, err := options.Find("timeout").IntValue()
+		if err != nil {
+			log.Printf("[%s] /automod config failed to get the timeout: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		config.TimeoutSeconds = minutes * 60
+	}
+	if err := storage.SetAutomodConfig(kvs, event.GuildID, config); err != nil {
+		log.Printf("[%s] Failed to store automod config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	reply := "Automod will not report anywhere"
+	if config.LogChannelID.IsValid() {
+		reply = "Automod will report to " + config.LogChannelID.Mention()
+	}
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("%s, and timeouts last %s.", reply, automodTimeout(config)))}
+}
+
+// SubCommandAutomodExempt toggles if automod ignores a channel.
+func SubCommandAutomodExempt(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /automod exempt failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	channelID := discord.ChannelID(channelSnowflake)
+	config, err := storage.GetAutomodConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to get automod config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	reply := "Automod will now ignore"
+	if config.Exempt[channelID] {
+		delete(config.Exempt, channelID)
+		reply = "Automod will no longer ignore"
+	} else {
+		config.Exempt[channelID] = true
+	}
+	if err := storage.SetAutomodConfig(kvs, event.GuildID, config); err != nil {
+		log.Printf("[%s] Failed to store automod config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	return command.Response{Response: response.Ephemeral(reply, channelID.Mention())}
+}
+
+func automodTimeout(config storage.AutomodConfig) time.Duration {
+	if config.TimeoutSeconds <= 0 {
+		return automodDefaultTimeout
+	}
+	return time.Duration(config.TimeoutSeconds) * time.Second
+}
+
+// automodPattern compiles the filter into a regular expression, or gets it from the cache if that's already done.
+func automodPattern(filter *storage.AutomodFilter) (*regexp.Regexp, error) {
+	if filter.Kind == storage.AutomodInvite {
+		return automodInvitePattern, nil
+	}
+	source := filter.Pattern
+	if filter.Kind == storage.AutomodWord {
+		source = `(?i)\b` + regexp.QuoteMeta(filter.Pattern) + `\b`
+	}
+	automodPatternsLock.Lock()
+	defer automodPatternsLock.Unlock()
+	if pattern, ok := automodPatterns[source]; ok {
+		return pattern, nil
+	}
+	pattern, err := regexp.Compile(source)
+	if err != nil {
+		return nil, err
+	}
+	automodPatterns[source] = pattern
+	return pattern, nil
+}
+
+// MessageAutomod checks every message against the filters, and acts on the first one that matches.
+func MessageAutomod(state *state.State, kvs storage.KeyValueStore, event *gateway.MessageCreateEvent) {
+	if event.GuildID == discord.NullGuildID || event.Author.Bot {
+		return
+	}
+	if enabled, err := storage.FeatureEnabled(kvs, event.GuildID, "automod"); err != nil || !enabled {
+		return
+	}
+	config, err := storage.GetAutomodConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Automod failed to get config: %s", event.GuildID, err)
+		return
+	}
+	if config.Exempt[event.ChannelID] {
+		return
+	}
+	filters, err := storage.GetAutomodFilters(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Automod failed to get filters: %s", event.GuildID, err)
+		return
+	}
+	for _, filter := range filters {
+		pattern, err := automodPattern(&filter)
+		if err != nil {
+			log.Printf("[%s] Automod filter #%d is broken: %s", event.GuildID, filter.ID, err)
+			continue
+		}
+		if pattern.MatchString(event.Content) {
+			automodAct(state, kvs, event, config, filter.Action, fmt.Sprintf("Filter #%d, %s", filter.ID, filter.Describe()))
+			return
+		}
+	}
+
+	rules, err := storage.GetAutomodListRules(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Automod failed to get link rules: %s", event.GuildID, err)
+		return
+	}
+	for _, rule := range storage.AutomodListRulesFor(rules, event.ChannelID) {
+		if rejected := automodListRejects(&rule, &event.Message); rejected != "" {
+			automodAct(state, kvs, event, config, rule.Action, fmt.Sprintf("`%s` is not allowed %s", rejected, rule.Where()))
+			return
+		}
+	}
+}
+
+// automodListRejects returns the first domain or file type in the message that the rule doesn't allow, or an empty string if it's all fine.
+func automodListRejects(rule *storage.AutomodListRule, msg *discord.Message) string {
+	if rule.Kind == storage.AutomodAttachments {
+		for _, attachment := range msg.Attachments {
+			extension := strings.ToLower(strings.TrimPrefix(path.Ext(attachment.Filename), "."))
+			if !rule.Allows(extension) {
+				return "." + extension
+			}
+		}
+		return ""
+	}
+	for _, match := range automodLinkPattern.FindAllStringSubmatch(msg.Content, -1) {
+		if !rule.Allows(match[1]) {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// automodAct does what the action says to the message and it's author, and reports it.
+func automodAct(state *state.State, kvs storage.KeyValueStore, event *gateway.MessageCreateEvent, config storage.AutomodConfig, action string, reason string) {
+	taken := []string{}
+	if err := state.DeleteMessage(event.ChannelID, event.ID, api.AuditLogReason("Automod: "+reason)); err != nil {
+		log.Printf("[%s] Automod failed to delete message %s: %s", event.GuildID, event.ID, err)
+	} else {
+		taken = append(taken, "deleted the message")
+	}
+
+	if action == storage.AutomodWarn || action == storage.AutomodTimeout {
+		me, err := state.Me()
+		if err != nil {
+			log.Printf("[%s] Automod failed to look up itself: %s", event.GuildID, err)
+		} else if _, err := storage.AddWarning(kvs, event.GuildID, event.Author.ID, me.ID, "Automod: "+reason); err != nil {
+			log.Printf("[%s] Automod failed to warn <@%s>: %s", event.GuildID, event.Author.ID, err)
+		} else {
+			taken = append(taken, "warned them")
+		}
+		automodNotify(state, event, reason)
+	}
+
+	if action == storage.AutomodTimeout {
+		taken = append(taken, automodTimeOut(state, event, config, reason))
+	}
+
+	log.Printf("[%s] Automod caught <@%s> in <#%s> (%s) and %s", event.GuildID, event.Author.ID, event.ChannelID, reason, strings.Join(taken, ", "))
+	automodReport(state, event, config, reason, taken)
+}
+
+// automodTimeOut times the author out, if they are within reach, and says what it did.
+func automodTimeOut(state *state.State, event *gateway.MessageCreateEvent, config storage.AutomodConfig, reason string) string {
+	if err := utility.CanModerate(state, event.GuildID, nil, event.Author.ID); utility.OutOfReach(err) {
+		log.Printf("[%s] Automod can't time out <@%s>: %s", event.GuildID, event.Author.ID, err)
+		return "couldn't time them out, as they're out of my reach"
+	}
+	until := discord.NewTimestamp(time.Now().Add(automodTimeout(config)))
+	err := state.ModifyMember(event.GuildID, event.Author.ID, api.ModifyMemberData{
+		CommunicationDisabledUntil: &until,
+		AuditLogReason:             api.AuditLogReason("Automod: " + reason),
+	})
+	if err != nil {
+		log.Printf("[%s] Automod failed to time out <@%s>: %s", event.GuildID, event.Author.ID, err)
+		return "tried to time them out, but failed"
+	}
+	return "timed them out"
+}
+
+// automodNotify tells the author why their message went away.
+func automodNotify(state *state.State, event *gateway.MessageCreateEvent, reason string) {
+	dm, err := state.CreatePrivateChannel(event.Author.ID)
+	if err != nil {
+		log.Printf("[%s] Automod could not open a DM with <@%s>: %s", event.GuildID, event.Author.ID, err)
+		return
+	}
+	content := fmt.Sprintf("Your message in %s was removed by automod, and you have been given a warning.\n%s", event.ChannelID.Mention(), reason)
+	if _, err := state.SendMessage(dm.ID, content); err != nil {
+		log.Printf("[%s] Automod could not DM <@%s>: %s", event.GuildID, event.Author.ID, err)
+	}
+}
+
+// automodReport posts what automod did in the log channel, if there is one.
+func automodReport(state *state.State, event *gateway.MessageCreateEvent, config storage.AutomodConfig, reason string, taken []string) {
+	if !config.LogChannelID.IsValid() {
+		return
+	}
+	if len(taken) == 0 {
+		taken = []string{"failed to do anything about it"}
+	}
+	content := event.Content
+	if len([]rune(content)) > 1000 {
+		content = string([]rune(content)[:1000]) + "…"
+	}
+	_, err := state.SendMessageComplex(config.LogChannelID, api.SendMessageData{
+		Embeds: []discord.Embed{{
+			Type:        discord.NormalEmbed,
+			Title:       "Automod",
+			Description: content,
+			Color:       discord.Color(0xED4245),
+			Timestamp:   discord.NowTimestamp(),
+			Fields: []discord.EmbedField{
+				{Name: "Author", Value: event.Author.ID.Mention(), Inline: true},
+				{Name: "Channel", Value: event.ChannelID.Mention(), Inline: true},
+				{Name: "Reason", Value: reason},
+				{Name: "Action", Value: strings.Join(taken, ", ")},
+			},
+		}},
+	})
+	if err != nil {
+		log.Printf("[%s] Automod failed to report in <#%s>: %s", event.GuildID, config.LogChannelID, err)
+	}
+}
+
+func timelineWarnings(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID, userID discord.UserID) ([]timelineEntry, error) {
+	warnings, err := storage.GetWarnings(kvs, guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	entries := []timelineEntry{}
+	for _, warning := range warnings {
+		entries = append(entries, timelineEntry{
+			When: warning.Created,
+			Text: fmt.Sprintf("Warned by %s: %s", warning.Moderator.Mention(), warning.Reason),
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,94 @@
+package interactions
+
+import (
+	"bytes"
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+	"sort"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"github.com/diamondburned/arikawa/v3/utils/sendpart"
+)
+
+var broadcastModule = module.Basic{
+	ModuleName: "broadcast",
+	CommandHandlers: map[string]command.Handler{
+		"broadcast": commandBroadcastObject,
+	},
+}
+
+var commandBroadcastObject = command.Handler{
+	Description: "Post news about the bot in every guild that asked for it. Only for whoever runs the bot.",
+	Code:        CommandBroadcast,
+	DMs:         true,
+	Owner:       true,
+	Options: []discord.CommandOption{
+		&discord.StringOption{
+			OptionName:  "message",
+			Description: "The news, like maintenance coming up or what changed",
+			Required:    true,
+		},
+	},
+}
+
+// CommandBroadcast posts the message in the bot news channel of every guild that set one with /config botnews,
+// and reports how that went in each of them.
+func CommandBroadcast(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	content := cmd.Options.Find("message").String()
+	if len([]rune(content)) > 2000 {
+		return command.Response{Response: response.Ephemeral("That's too long for a single message, sorry. Discord won't take more than 2000 characters.")}
+	}
+	channels, err := storage.BotNewsChannels(kvs)
+	if err != nil {
+		log.Printf("[%s] /broadcast failed to get the bot news channels: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(channels) == 0 {
+		return command.Response{Response: response.Ephemeral("No guild has asked for news about me with `/config botnews`, so there's nowhere to post it.")}
+	}
+	guildIDs := make([]discord.GuildID, 0, len(channels))
+	for guildID := range channels {
+		guildIDs = append(guildIDs, guildID)
+	}
+	sort.Slice(guildIDs, func(i, j int) bool {
+		return guildIDs[i] < guildIDs[j]
+	})
+
+	log.Printf("[%s] <@%s> is broadcasting to %d guilds", event.GuildID, event.SenderID(), len(guildIDs))
+	return command.DeferredDataProgress(state, event, true, func(progress func(string)) api.EditInteractionResponseData {
+		var buf bytes.Buffer
+		failed := 0
+		for i, guildID := range guildIDs {
+			progress(fmt.Sprintf("Posting, %d of %d guilds...", i, len(guildIDs)))
+			channelID := channels[guildID]
+			// News about the bot is no reason to ping anyone, whatever the message says.
+			_, err := state.SendMessageComplex(channelID, api.SendMessageData{
+				Content:         content,
+				AllowedMentions: &api.AllowedMentions{Parse: []api.AllowedMentionType{}},
+			})
+			if err != nil {
+				log.Printf("[%s] /broadcast failed to post in <#%s>: %s", guildID, channelID, err)
+				fmt.Fprintf(&buf, "%s %s failed: %s\n", guildID, channelID, err)
+				failed++
+				continue
+			}
+			fmt.Fprintf(&buf, "%s %s posted\n", guildID, channelID)
+		}
+		message := fmt.Sprintf("Posted in all %d guilds.", len(guildIDs))
+		if failed > 0 {
+			message = fmt.Sprintf("Posted in %d of %d guilds. It failed in %d, probably because I can't get to the channel anymore.", len(guildIDs)-failed, len(guildIDs), failed)
+		}
+		log.Printf("[%s] /broadcast posted in %d of %d guilds", event.GuildID, len(guildIDs)-failed, len(guildIDs))
+		return api.EditInteractionResponseData{
+			Content: option.NewNullableString(message),
+			Files:   []sendpart.File{{Name: "broadcast.txt", Reader: &buf}},
+		}
+	})
+}
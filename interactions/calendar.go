@@ -0,0 +1,300 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/delete"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/interactions/timer"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+const (
+	calendarCollection     = "calendar"
+	calendarKey            = "message"
+	calendarMaxEntries     = 25
+	calendarUpdateInterval = 10 * time.Minute
+)
+
+var calendarModule = module.Basic{
+	ModuleName: "calendar",
+	CommandHandlers: map[string]command.Handler{
+		"calendar": commandCalendarObject,
+	},
+	Handlers: []module.EventHandler{
+		module.Timer("calendar", timer.Handler{Code: TimerCalendar}),
+		module.Delete(delete.Handler{Code: DeleteCalendar}),
+		module.Hook("calendar source", func() { registerCalendarSource(calendarScheduledEvents) }),
+		module.Hook("calendar source", func() { registerCalendarSource(calendarEvents) }),
+		module.Hook("calendar source", func() { registerCalendarSource(calendarVotes) }),
+	},
+}
+
+var commandCalendarObject = command.Handler{
+	Description: "Everything coming up, in one place",
+	Code:        CommandCalendar,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "show",
+			Description: "Show what's coming up",
+			Options:     []discord.CommandOptionValue{},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "pin",
+			Description: "Post a calendar that keeps itself up to date",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:  "channel",
+					Description: "Where to post the calendar",
+					Required:    true,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "unpin",
+			Description: "Stop updating the posted calendar",
+			Options:     []discord.CommandOptionValue{},
+		},
+	},
+}
+
+// calendarMessage is where the self-updating calendar is posted.
+type calendarMessage struct {
+	ChannelID discord.ChannelID
+	MessageID discord.MessageID
+}
+
+// calendarEntry is a single upcoming thing on the calendar.
+type calendarEntry struct {
+	When int64
+	Text string
+}
+
+// calendarSource looks up whatever one subsystem has coming up in the guild, and returns it as calendar entries.
+type calendarSource func(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID) ([]calendarEntry, error)
+
+var calendarSources = []calendarSource{}
+
+// registerCalendarSource adds a source of entries to the /calendar command.
+// Anything that has things happening at a set time should register one of these in it's init()
+func registerCalendarSource(source calendarSource) {
+	calendarSources = append(calendarSources, source)
+}
+
+// CommandCalendar processes the /calendar command, dispatching to the right subcommand.
+func CommandCalendar(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /calendar command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "show":
+		resp := response.EphemeralShareable()
+		resp.Data.Embeds = &[]discord.Embed{calendarEmbed(state, kvs, event.GuildID)}
+		return command.Response{Response: resp}
+	case "pin":
+		return SubCommandCalendarPin(state, kvs, event, cmd.Options[0].Options)
+	case "unpin":
+		if err := kvs.Delete(event.GuildID, calendarCollection, calendarKey); err != nil {
+			log.Printf("[%s] Failed to unpin the calendar: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		return command.Response{Response: response.Ephemeral("The calendar will no longer be updated.")}
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandCalendarPin posts a calendar, and schedules it to be kept up to date.
+func SubCommandCalendarPin(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /calendar pin failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	channelID := discord.ChannelID(channelSnowflake)
+	message, err := state.SendMessageComplex(channelID, api.SendMessageData{
+		Embeds: []discord.Embed{calendarEmbed(state, kvs, event.GuildID)},
+	})
+	if err != nil {
+		log.Printf("[%s] /calendar pin failed to post in <#%s>: %s", event.GuildID, channelID, err)
+		return command.Response{Response: response.Ephemeral("I couldn't post in that channel. Do I have access to it?")}
+	}
+	pinned := calendarMessage{ChannelID: channelID, MessageID: message.ID}
+	if err := kvs.Set(event.GuildID, calendarCollection, calendarKey, pinned); err != nil {
+		log.Printf("[%s] Failed to store the pinned calendar: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("The calendar is posted, but I couldn't remember where, so it won't update. The error has been logged.")}
+	}
+	if err := scheduleCalendarUpdate(kvs, event.GuildID, message.ID); err != nil {
+		log.Printf("[%s] Failed to schedule calendar update: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("The calendar is posted, but I couldn't schedule the updates. The error has been logged.")}
+	}
+	log.Printf("[%s] <@%s> pinned the calendar in <#%s>", event.GuildID, event.SenderID(), channelID)
+	return command.Response{Response: response.Ephemeral("The calendar is posted in", channelID.Mention(), "and will keep itself up to date.")}
+}
+
+func scheduleCalendarUpdate(kvs storage.KeyValueStore, guildID discord.GuildID, messageID discord.MessageID) error {
+	_, err := timer.Schedule(kvs, guildID, "calendar", time.Now().Add(calendarUpdateInterval), map[string]string{
+		"message": messageID.String(),
+	})
+	return err
+}
+
+// TimerCalendar updates the pinned calendar, and schedules the next update.
+func TimerCalendar(state *state.State, kvs storage.KeyValueStore, t storage.Timer) {
+	pinned := calendarMessage{}
+	exist, err := kvs.Get(t.GuildID, calendarCollection, calendarKey, &pinned)
+	if err != nil {
+		log.Printf("[%s] Failed to get the pinned calendar: %s", t.GuildID, err)
+		return
+	}
+	if !exist || pinned.MessageID.String() != t.Data["message"] {
+		return // Unpinned, or pinned somewhere else since this timer was set.
+	}
+	_, err = state.EditMessageComplex(pinned.ChannelID, pinned.MessageID, api.EditMessageData{
+		Embeds: &[]discord.Embed{calendarEmbed(state, kvs, t.GuildID)},
+	})
+	if err != nil {
+		log.Printf("[%s] Failed to update the pinned calendar: %s", t.GuildID, err)
+	}
+	if err := scheduleCalendarUpdate(kvs, t.GuildID, pinned.MessageID); err != nil {
+		log.Printf("[%s] Failed to schedule the next calendar update: %s", t.GuildID, err)
+	}
+}
+
+// DeleteCalendar unpins the calendar if the message it's in is deleted.
+func DeleteCalendar(state *state.State, kvs storage.KeyValueStore, event *gateway.MessageDeleteEvent) {
+	pinned := calendarMessage{}
+	exist, err := kvs.Get(event.GuildID, calendarCollection, calendarKey, &pinned)
+	if err != nil || !exist || pinned.MessageID != event.ID {
+		return
+	}
+	if err := kvs.Delete(event.GuildID, calendarCollection, calendarKey); err != nil {
+		log.Printf("[%s] Failed to unpin the deleted calendar: %s", event.GuildID, err)
+	}
+}
+
+// collectCalendar asks every registered source what's coming up, and returns the entries sorted soonest first.
+func collectCalendar(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID) []calendarEntry {
+	now := time.Now().Unix()
+	entries := []calendarEntry{}
+	for _, source := range calendarSources {
+		found, err := source(state, kvs, guildID)
+		if err != nil {
+			log.Printf("[%s] A calendar source failed: %s", guildID, err)
+			continue
+		}
+		for _, entry := range found {
+			if entry.When >= now {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].When < entries[j].When
+	})
+	return entries
+}
+
+// calendarEmbed renders the upcoming entries as an embed.
+func calendarEmbed(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID) discord.Embed {
+	entries := collectCalendar(state, kvs, guildID)
+	var description strings.Builder
+	if len(entries) == 0 {
+		description.WriteString("Nothing coming up. Go make some plans!")
+	}
+	if len(entries) > calendarMaxEntries {
+		entries = entries[:calendarMaxEntries]
+	}
+	for _, entry := range entries {
+		line := fmt.Sprintf("%s %s\n", utility.TimestampAndRelative(entry.When, utility.ShortDateTime), entry.Text)
+		if description.Len()+len(line) > 4000 {
+			break // Embed descriptions max out at 4096.
+		}
+		description.WriteString(line)
+	}
+	return discord.Embed{
+		Type:        discord.NormalEmbed,
+		Title:       "Coming up",
+		Description: description.String(),
+		Color:       discord.Color(0x5865F2),
+		Timestamp:   discord.NowTimestamp(),
+		Footer:      &discord.EmbedFooter{Text: "Last updated"},
+	}
+}
+
+func calendarScheduledEvents(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID) ([]calendarEntry, error) {
+	events, err := state.ListScheduledEvents(guildID, false)
+	if err != nil {
+		return nil, err
+	}
+	entries := []calendarEntry{}
+	for _, event := range events {
+		if event.Status != discord.ScheduledEvent {
+			continue
+		}
+		entries = append(entries, calendarEntry{
+			When: event.StartTime.Time().Unix(),
+			Text: fmt.Sprintf("**%s** https://discord.com/events/%s/%s", event.Name, guildID, event.ID),
+		})
+	}
+	return entries, nil
+}
+
+func calendarEvents(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID) ([]calendarEntry, error) {
+	keys, err := kvs.Keys(guildID, "events")
+	if err != nil {
+		return nil, err
+	}
+	entries := []calendarEntry{}
+	for _, key := range keys {
+		event := storage.Event{}
+		exist, err := kvs.Get(guildID, "events", key, &event)
+		if err != nil {
+			return nil, err
+		}
+		if !exist {
+			continue
+		}
+		entries = append(entries, calendarEntry{
+			When: event.StartTime,
+			Text: fmt.Sprintf("**%s**, %d going https://discord.com/channels/%s/%s/%s", event.Title, len(event.Attendees(storage.RSVPGoing)), guildID, event.ChannelID, event.MessageID),
+		})
+	}
+	return entries, nil
+}
+
+func calendarVotes(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID) ([]calendarEntry, error) {
+	keys, err := kvs.Keys(guildID, "votes")
+	if err != nil {
+		return nil, err
+	}
+	entries := []calendarEntry{}
+	for _, key := range keys {
+		vote := storage.Vote{}
+		exist, err := kvs.Get(guildID, "votes", key, &vote)
+		if err != nil {
+			return nil, err
+		}
+		if !exist {
+			continue
+		}
+		entries = append(entries, calendarEntry{
+			When: vote.EndTime,
+			Text: fmt.Sprintf("Vote closes https://discord.com/channels/%s/%s/%s", guildID, vote.ChannelID, vote.MessageID),
+		})
+	}
+	return entries, nil
+}
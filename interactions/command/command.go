@@ -1,10 +1,17 @@
 package command
 
 import (
+	"fmt"
 	"komainu/interactions/response"
+	"komainu/locale"
+	"komainu/logging"
+	"komainu/metrics"
 	"komainu/storage"
 	"komainu/utility"
 	"log"
+	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/diamondburned/arikawa/v3/api"
 	"github.com/diamondburned/arikawa/v3/discord"
@@ -14,9 +21,7 @@ import (
 )
 
 type Command func(
-	state *state.State,
-	kvs storage.KeyValueStore,
-	event *gateway.InteractionCreateEvent,
+	ctx *Context,
 	command *discord.CommandInteraction,
 ) Response
 
@@ -27,23 +32,139 @@ type Response struct {
 
 // IsEphemeral checks if the contained InteractionResponse is only shown to the user initiating the interaction.
 func (cr *Response) IsEphemeral() bool {
-	return cr.Response.Data.Flags&api.EphemeralResponse != 0
+	return cr.Response.Data != nil && cr.Response.Data.Flags&api.EphemeralResponse != 0
 }
 
 // Length returns the number of runes in the content string. This is not the same as the number of bytes!
 func (cr *Response) Length() int {
-	if cr.Response.Data.Content == nil {
+	if cr.Response.Data == nil || cr.Response.Data.Content == nil {
 		return 0
 	}
 	runes := []rune(cr.Response.Data.Content.Val)
 	return len(runes)
 }
 
+// Deferred acknowledges the command right away, and edits in whatever work returns once it's done.
+// For commands that might take longer than the three seconds Discord allows.
+func Deferred(state *state.State, event *gateway.InteractionCreateEvent, work func() string) Response {
+	return DeferredData(state, event, false, func() api.EditInteractionResponseData {
+		return api.EditInteractionResponseData{Content: option.NewNullableString(work())}
+	})
+}
+
+// DeferredData is like Deferred, but work can return anything a response can have, like files, and the response can be ephemeral.
+func DeferredData(state *state.State, event *gateway.InteractionCreateEvent, ephemeral bool, work func() api.EditInteractionResponseData) Response {
+	deferred := api.InteractionResponse{Type: api.DeferredMessageInteractionWithSource}
+	if ephemeral {
+		deferred.Data = &api.InteractionResponseData{Flags: api.EphemeralResponse}
+	}
+	return Response{
+		Response: deferred,
+		Callback: func(message *discord.Message) {
+			go finishDeferred(state, event, deferredLogger(event), work) // Not on the worker, which others are waiting for.
+		},
+	}
+}
+
+// deferredLogger logs for deferred work, which has no Context to get a logger from.
+func deferredLogger(event *gateway.InteractionCreateEvent) *slog.Logger {
+	switch interaction := event.Data.(type) {
+	case *discord.CommandInteraction:
+		return logging.Interaction(event, "command", interaction.Name)
+	case *discord.ModalInteraction:
+		return logging.Interaction(event, "modal", string(interaction.CustomID))
+	}
+	return logging.Interaction(event, "deferred", "")
+}
+
+// finishDeferred does the deferred work, and edits in what it returns. If the work panics, the panic is reported, and
+// the error response is edited in instead, so nobody is left looking at "thinking..." forever.
+func finishDeferred(state *state.State, event *gateway.InteractionCreateEvent, logger *slog.Logger, work func() api.EditInteractionResponseData) {
+	data := api.EditInteractionResponseData{Content: option.NewNullableString("An error occured, and has been logged.")}
+	Protect(logger, func() { data = work() })
+	if _, err := state.EditInteractionResponse(event.AppID, event.Token, data); err != nil {
+		logger.Error("Failed to edit in the deferred response", "error", err)
+	}
+}
+
+// progressInterval is how often DeferredProgress edits in progress, to stay well clear of rate limits.
+const progressInterval = 3 * time.Second
+
+// DeferredProgress is like DeferredData, for work that takes long enough that people want to know how it's going.
+// The work runs on its own goroutine, and can report progress as often as it likes. The response is only edited every few seconds,
+// and then once more with whatever work returns.
+func DeferredProgress(state *state.State, event *gateway.InteractionCreateEvent, ephemeral bool, work func(progress func(string)) string) Response {
+	return DeferredDataProgress(state, event, ephemeral, func(progress func(string)) api.EditInteractionResponseData {
+		return api.EditInteractionResponseData{Content: option.NewNullableString(work(progress))}
+	})
+}
+
+// DeferredDataProgress is like DeferredProgress, but work can return anything a response can have, like files.
+func DeferredDataProgress(state *state.State, event *gateway.InteractionCreateEvent, ephemeral bool, work func(progress func(string)) api.EditInteractionResponseData) Response {
+	deferred := api.InteractionResponse{Type: api.DeferredMessageInteractionWithSource}
+	if ephemeral {
+		deferred.Data = &api.InteractionResponseData{Flags: api.EphemeralResponse}
+	}
+	return Response{
+		Response: deferred,
+		Callback: func(message *discord.Message) {
+			go func() {
+				logger := deferredLogger(event)
+				lastEdit := time.Time{}
+				progress := func(content string) {
+					if time.Since(lastEdit) < progressInterval {
+						return
+					}
+					lastEdit = time.Now()
+					_, err := state.EditInteractionResponse(event.AppID, event.Token, api.EditInteractionResponseData{Content: option.NewNullableString(content)})
+					if err != nil {
+						logger.Warn("Failed to edit in progress", "error", err)
+					}
+				}
+				finishDeferred(state, event, logger, func() api.EditInteractionResponseData { return work(progress) })
+			}()
+		},
+	}
+}
+
+// Scope gives the guild to store things under for the interaction. In private, that's a scope of the user's own.
+func Scope(event *gateway.InteractionCreateEvent) discord.GuildID {
+	if event.GuildID == discord.NullGuildID {
+		return storage.PrivateScope(event.SenderID())
+	}
+	return event.GuildID
+}
+
+// owners is who runs the bot, and so may use the commands marked Owner.
+var owners = map[discord.UserID]bool{}
+var ownersLock sync.RWMutex
+
+// SetOwners sets who runs the bot, replacing whoever did before.
+func SetOwners(userIDs []discord.UserID) {
+	ownersLock.Lock()
+	defer ownersLock.Unlock()
+	owners = make(map[discord.UserID]bool, len(userIDs))
+	for _, userID := range userIDs {
+		owners[userID] = true
+	}
+}
+
+// IsOwner tells if the user runs the bot.
+func IsOwner(userID discord.UserID) bool {
+	ownersLock.RLock()
+	defer ownersLock.RUnlock()
+	return owners[userID]
+}
+
 type Handler struct {
 	Description string
 	Code        Command
 	Type        discord.CommandType
 	Options     []discord.CommandOption
+	Public      bool   // Usable by everyone, not just those with admin permissions.
+	DMs         bool   // Usable in private with the bot, too. Such commands must not assume there is a guild or a member.
+	Owner       bool   // Only usable by whoever runs the bot, as set with OwnerIDs in the configuration.
+	Feature     string // The feature flag the command belongs to, turned on or off with /config features. Blank if it's always there.
 }
 
 // commands holds the Commands to be registered with each joined guild.
@@ -53,56 +174,230 @@ var commands = map[string]Handler{}
 var userTokenBin = &utility.TokenBin{Max: 5, Interval: 10}
 var channelTokenBin = &utility.TokenBin{Max: 10, Interval: 10}
 
+// throttling is whether the Token Bins, and the cooldowns set with /config cooldown, are used at all.
+var throttling = true
+
+// SetThrottling turns the Token Bins and cooldowns on or off. Replaying recorded interactions turns them off, as that
+// goes a lot quicker than anyone can type.
+func SetThrottling(on bool) {
+	throttling = on
+}
+
 func Register(name string, command Handler) {
 	commands[name] = command
 }
 
+// GuildCommandSource gives the commands that only exist in a single guild, like the ones the guild made itself.
+type GuildCommandSource func(kvs storage.KeyValueStore, guildID discord.GuildID) ([]api.CreateCommandData, error)
+
+var guildCommandSources = []GuildCommandSource{}
+
+// fallback handles commands that aren't registered by name, which is to say the ones from a GuildCommandSource.
+var fallback Command
+
+// RegisterGuild adds a source of guild commands, and the Command that handles all of them.
+func RegisterGuild(source GuildCommandSource, code Command) {
+	guildCommandSources = append(guildCommandSources, source)
+	fallback = code
+}
+
 // AddHandler adds handler for commands. You might have guessed that, but here we are.
 func AddHandler(state *state.State, kvs storage.KeyValueStore) {
 	state.AddHandler(func(e *gateway.InteractionCreateEvent) {
 		if interaction, ok := e.Data.(*discord.CommandInteraction); ok {
-			if e.GuildID == discord.NullGuildID || e.Member == nil { // Command issued in private
-				state.RespondInteraction(e.ID, e.Token, response.Ephemeral("I'm sorry, I do not respond to commands in private."))
-				return
+			received := time.Now()
+			Enqueue(state, kvs, e, "command", func() {
+				handleCommand(state, kvs, e, interaction, received)
+			})
+		}
+	})
+	state.AddHandler(func(e *gateway.GuildCreateEvent) {
+		if err := RegisterGuildCommands(state, kvs, e.ID); err != nil {
+			log.Printf("[%s] Failed to register guild commands: %s", e.ID, err)
+		}
+	})
+}
+
+// handleCommand handles a command interaction, on one of the workers.
+func handleCommand(state *state.State, kvs storage.KeyValueStore, e *gateway.InteractionCreateEvent, interaction *discord.CommandInteraction, received time.Time) {
+	val, ok := commands[interaction.Name]
+	private := e.GuildID == discord.NullGuildID || e.Member == nil
+	if private && !(ok && val.DMs) {
+		if err := state.RespondInteraction(e.ID, e.Token, response.Ephemeral(fmt.Sprintf("Sorry, /%s only works in a server, not in private.", interaction.Name))); err != nil {
+			log.Printf("Failed to tell <@%s> /%s doesn't work in private: %s", e.SenderID(), interaction.Name, err)
+		}
+		return
+	}
+	if ok && val.Owner && !IsOwner(e.SenderID()) {
+		log.Printf("[%s] <@%s> tried to use /%s, but doesn't run the bot", e.GuildID, e.SenderID(), interaction.Name)
+		if err := state.RespondInteraction(e.ID, e.Token, response.Ephemeral(fmt.Sprintf("Sorry, /%s is only for whoever runs the bot.", interaction.Name))); err != nil {
+			log.Printf("Failed to tell <@%s> /%s is only for the owners: %s", e.SenderID(), interaction.Name, err)
+		}
+		return
+	}
+	language := locale.ForGuild(kvs, e.GuildID)
+	if ok && val.Feature != "" && !private {
+		enabled, err := storage.FeatureEnabled(kvs, e.GuildID, val.Feature)
+		if err != nil {
+			log.Printf("[%s] Failed to check if %s is turned on for /%s: %s", e.GuildID, val.Feature, interaction.Name, err)
+		}
+		if err == nil && !enabled {
+			if err := state.RespondInteraction(e.ID, e.Token, response.Ephemeral(locale.Translate(language, "Sorry, that's turned off here. An administrator can turn it on with /config features."))); err != nil {
+				log.Printf("[%s] Failed to tell <@%s> /%s is turned off: %s", e.GuildID, e.SenderID(), interaction.Name, err)
 			}
-			if !userTokenBin.Allocate(discord.Snowflake(e.GuildID), discord.Snowflake(e.Member.User.ID)) {
-				if err := state.RespondInteraction(e.ID, e.Token, response.Ephemeral("You are using too many commands too quickly. Calm down.")); err != nil {
-					log.Println("An error occured posting throttle warning emphemral response (user):", err)
-				}
-				return
+			return
+		}
+	}
+	if throttling && !userTokenBin.Allocate(discord.Snowflake(Scope(e)), discord.Snowflake(e.SenderID())) {
+		if err := state.RespondInteraction(e.ID, e.Token, response.Ephemeral(locale.Translate(language, "You are using too many commands too quickly. Calm down."))); err != nil {
+			log.Println("An error occured posting throttle warning emphemral response (user):", err)
+		}
+		return
+	}
+	if throttling && !channelTokenBin.Allocate(discord.Snowflake(e.GuildID), discord.Snowflake(e.ChannelID)) {
+		if err := state.RespondInteraction(e.ID, e.Token, response.Ephemeral(locale.Translate(language, "Too many commands being processed in this channel right now. Please wait."))); err != nil {
+			log.Println("An error occured posting throttle warning emphemral response (channel):", err)
+		}
+		return
+	}
+	if throttling && !private {
+		left, perChannel, err := cooldownLeft(kvs, e, interaction.Name)
+		if err != nil {
+			log.Printf("[%s] Failed to check the cooldown for /%s: %s", e.GuildID, interaction.Name, err)
+		}
+		if left > 0 {
+			if err := state.RespondInteraction(e.ID, e.Token, response.Ephemeral(cooldownMessage(language, interaction.Name, left, perChannel))); err != nil {
+				log.Printf("[%s] Failed to tell <@%s> /%s is cooling down: %s", e.GuildID, e.SenderID(), interaction.Name, err)
 			}
-			if !channelTokenBin.Allocate(discord.Snowflake(e.GuildID), discord.Snowflake(e.ChannelID)) {
-				if err := state.RespondInteraction(e.ID, e.Token, response.Ephemeral("Too many commands being processed in this channel right now. Please wait.")); err != nil {
-					log.Println("An error occured posting throttle warning emphemral response (channel):", err)
-				}
+			return
+		}
+	}
+
+	if !ok && fallback != nil {
+		val, ok = Handler{Code: fallback}, true
+	}
+	if ok {
+		ctx := NewContext(state, kvs, e, received, "command", interaction.Name)
+		logger := ctx.Logger
+		if !private {
+			applyPresets(kvs, e.GuildID, interaction)
+		}
+		start := time.Now()
+		resp, ok := Run(ctx, func() Response { return val.Code(ctx, interaction) }, Response{Response: response.Ephemeral("An error occured, and has been logged.")})
+		if !ok {
+			logger.Error("Command took too long to respond, and was given up on", "after", time.Since(start))
+			metrics.Errors.Inc("command")
+			recordUse(kvs, e, interaction.Name, received, true)
+			return
+		}
+		metrics.Interactions.Inc("command", interaction.Name)
+		metrics.HandlerSeconds.Since(start, "command", interaction.Name)
+		logger.Debug("Command handled", "duration", time.Since(start))
+		if response.IsError(resp.Response) {
+			logger.Error("Command responded with an error", "response", resp.Response.Data.Content.Val)
+			metrics.Errors.Inc("command")
+		}
+		if !private {
+			applyVisibility(kvs, e.GuildID, interaction.Name, &resp)
+		}
+
+		if resp.Length() > 1500 {
+			if resp.IsEphemeral() {
+				resp.Response.Data.Content = option.NewNullableString(resp.Response.Data.Content.Val)
+			}
+		}
+
+		locale.TranslateResponse(locale.ForGuild(kvs, e.GuildID), &resp.Response) // Looked up again, as /config locale changes it.
+		err := state.RespondInteraction(e.ID, e.Token, resp.Response)
+		if err != nil {
+			logger.Error("Failed to send command interaction response", "error", err)
+			metrics.Errors.Inc("command")
+		}
+		recordUse(kvs, e, interaction.Name, received, err != nil)
+		if resp.Callback != nil {
+			message, err := state.InteractionResponse(e.AppID, e.Token)
+			if err != nil {
+				logger.Error("Failed to get message reference for command callback", "error", err)
 				return
 			}
+			if message != nil && message.ID != discord.NullMessageID {
+				Protect(logger, func() { resp.Callback(message) })
+			}
+		}
+	}
+}
 
-			if val, ok := commands[interaction.Name]; ok {
-				resp := val.Code(state, kvs, e, interaction)
+// recordUse keeps the use of the command for /stats, with how long it took since it came in.
+func recordUse(kvs storage.KeyValueStore, e *gateway.InteractionCreateEvent, name string, received time.Time, failed bool) {
+	use := storage.CommandUse{
+		Command: name,
+		UserID:  e.SenderID(),
+		At:      received.Unix(),
+		Millis:  time.Since(received).Milliseconds(),
+		Failed:  failed,
+	}
+	if err := storage.RecordCommandUse(kvs, e.GuildID, e.ID, use); err != nil {
+		log.Printf("[%s] Failed to record the use of /%s: %s", e.GuildID, name, err)
+	}
+}
 
-				if resp.Length() > 1500 {
-					if resp.IsEphemeral() {
-						resp.Response.Data.Content = option.NewNullableString(resp.Response.Data.Content.Val)
-					}
-				}
+// registeredIDs holds the ID Discord gave each command, for making clickable mentions of them.
+var registeredIDs = map[string]discord.CommandID{}
+var registeredLock sync.RWMutex
 
-				if err := state.RespondInteraction(e.ID, e.Token, resp.Response); err != nil {
-					log.Printf("[%s] Failed to send command interaction response: %s", e.GuildID, err)
-				}
-				if resp.Callback != nil {
-					message, err := state.InteractionResponse(e.AppID, e.Token)
-					if err != nil {
-						log.Printf("Error %s getting message reference for %s command callback\n", err, interaction.Name)
-						return
-					}
-					if message != nil && message.ID != discord.NullMessageID {
-						resp.Callback(message)
-					}
-				}
+// Mention returns a clickable mention of the named command, or just the name if it isn't registered with Discord yet.
+func Mention(name string) string {
+	registeredLock.RLock()
+	defer registeredLock.RUnlock()
+	if id, ok := registeredIDs[name]; ok {
+		return fmt.Sprintf("</%s:%s>", name, id)
+	}
+	return "`/" + name + "`"
+}
+
+// localizeOptions fills in the localized descriptions of the options, all the way down, from the catalogs.
+func localizeOptions(options []discord.CommandOption) {
+	for _, opt := range options {
+		switch o := opt.(type) {
+		case *discord.SubcommandGroupOption:
+			o.DescriptionLocalizations = locale.Localizations(o.Description)
+			for _, sub := range o.Subcommands {
+				sub.DescriptionLocalizations = locale.Localizations(sub.Description)
+				localizeValues(sub.Options)
 			}
+		case *discord.SubcommandOption:
+			o.DescriptionLocalizations = locale.Localizations(o.Description)
+			localizeValues(o.Options)
+		case discord.CommandOptionValue:
+			localizeValues([]discord.CommandOptionValue{o})
 		}
-	})
+	}
+}
+
+// localizeValues fills in the localized descriptions of value options, from the catalogs.
+func localizeValues(options []discord.CommandOptionValue) {
+	for _, opt := range options {
+		switch o := opt.(type) {
+		case *discord.StringOption:
+			o.DescriptionLocalizations = locale.Localizations(o.Description)
+		case *discord.IntegerOption:
+			o.DescriptionLocalizations = locale.Localizations(o.Description)
+		case *discord.NumberOption:
+			o.DescriptionLocalizations = locale.Localizations(o.Description)
+		case *discord.BooleanOption:
+			o.DescriptionLocalizations = locale.Localizations(o.Description)
+		case *discord.UserOption:
+			o.DescriptionLocalizations = locale.Localizations(o.Description)
+		case *discord.ChannelOption:
+			o.DescriptionLocalizations = locale.Localizations(o.Description)
+		case *discord.RoleOption:
+			o.DescriptionLocalizations = locale.Localizations(o.Description)
+		case *discord.MentionableOption:
+			o.DescriptionLocalizations = locale.Localizations(o.Description)
+		case *discord.AttachmentOption:
+			o.DescriptionLocalizations = locale.Localizations(o.Description)
+		}
+	}
 }
 
 // RegisterCommands chews up the commands registered for the bot and actually registers them with Discord.
@@ -114,18 +409,47 @@ func RegisterCommands(state *state.State) error {
 	adminOnly := discord.NewPermissions(0)
 	bulkCommands := []api.CreateCommandData{}
 	for name, data := range commands {
+		permissions := adminOnly
+		if data.Public {
+			permissions = nil
+		}
+		localizeOptions(data.Options)
 		bulkCommands = append(bulkCommands, api.CreateCommandData{
 			Name:                     name,
 			Description:              data.Description,
+			DescriptionLocalizations: locale.Localizations(data.Description),
 			Options:                  data.Options,
 			Type:                     data.Type,
-			DefaultMemberPermissions: adminOnly,
+			DefaultMemberPermissions: permissions,
+			NoDMPermission:           !data.DMs,
 		})
 	}
 	registered, err := state.BulkOverwriteCommands(app.ID, bulkCommands)
 	if err != nil {
 		return err
 	}
+	registeredLock.Lock()
+	for _, cmd := range registered {
+		registeredIDs[cmd.Name] = cmd.ID
+	}
+	registeredLock.Unlock()
 	log.Printf("%d commands successfully registered", len(registered))
 	return nil
 }
+
+// RegisterGuildCommands registers the commands that only exist in the given guild, replacing whatever was there.
+func RegisterGuildCommands(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID) error {
+	if len(guildCommandSources) == 0 {
+		return nil
+	}
+	bulkCommands := []api.CreateCommandData{}
+	for _, source := range guildCommandSources {
+		sourced, err := source(kvs, guildID)
+		if err != nil {
+			return err
+		}
+		bulkCommands = append(bulkCommands, sourced...)
+	}
+	_, err := state.BulkOverwriteGuildCommands(state.Ready().Application.ID, guildID, bulkCommands)
+	return err
+}
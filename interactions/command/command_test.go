@@ -0,0 +1,190 @@
+package command
+
+import (
+	"komainu/interactions/response"
+	"komainu/komainutest"
+	"komainu/storage"
+	"strings"
+	"testing"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+func init() {
+	answer := func(ctx *Context, cmd *discord.CommandInteraction) Response {
+		return Response{Response: response.Ephemeral("Handled /" + cmd.Name)}
+	}
+	Register("testguild", Handler{Code: answer})
+	Register("testdms", Handler{Code: answer, DMs: true})
+	Register("testowner", Handler{Code: answer, Owner: true})
+	Register("testfeature", Handler{Code: answer, Feature: "xp"})
+	Register("testshare", Handler{Code: func(ctx *Context, cmd *discord.CommandInteraction) Response {
+		return Response{Response: response.EphemeralShareable("Handled /" + cmd.Name)}
+	}})
+	Register("testpublic", Handler{Code: func(ctx *Context, cmd *discord.CommandInteraction) Response {
+		return Response{Response: response.Message("Handled /" + cmd.Name)}
+	}})
+	Register("testpanic", Handler{Code: func(ctx *Context, cmd *discord.CommandInteraction) Response {
+		panic("the handler fell over")
+	}})
+	Register("testcallbackpanic", Handler{Code: func(ctx *Context, cmd *discord.CommandInteraction) Response {
+		return Response{
+			Response: response.Ephemeral("Handled /" + cmd.Name),
+			Callback: func(message *discord.Message) { panic("the callback fell over") },
+		}
+	}})
+	Register("testdeferredpanic", Handler{Code: func(ctx *Context, cmd *discord.CommandInteraction) Response {
+		return DeferredProgress(ctx.State, ctx.Event, false, func(progress func(string)) string {
+			panic("the deferred work fell over")
+		})
+	}})
+}
+
+// access dispatches the event, and gets what it was answered with. Every test is someone else, so none of them run
+// out of tokens.
+func access(t *testing.T, kvs storage.KeyValueStore, e *gateway.InteractionCreateEvent) string {
+	t.Helper()
+	d := komainutest.New()
+	AddHandler(d.State, kvs)
+	d.Dispatch(komainutest.As(e, discord.UserID(e.ID)))
+	resp, ok := d.Response(e)
+	if !ok {
+		t.Fatalf("There was no response to /%s", e.Data.(*discord.CommandInteraction).Name)
+	}
+	return resp.Data.Content.Val
+}
+
+func TestAccessGuildOnly(t *testing.T) {
+	kvs := storage.OpenMemory()
+	if got := access(t, kvs, komainutest.Command("testguild")); got != "Handled /testguild" {
+		t.Errorf("Expected it to be handled in a guild, Got %q", got)
+	}
+	if got := access(t, kvs, komainutest.Private(komainutest.Command("testguild"))); !strings.Contains(got, "only works in a server") {
+		t.Errorf("Expected it to be refused in private, Got %q", got)
+	}
+	if got := access(t, kvs, komainutest.Private(komainutest.Command("testdms"))); got != "Handled /testdms" {
+		t.Errorf("Expected it to be handled in private, Got %q", got)
+	}
+}
+
+func TestAccessOwner(t *testing.T) {
+	kvs := storage.OpenMemory()
+	t.Cleanup(func() { SetOwners(nil) })
+
+	e := komainutest.Command("testowner")
+	if got := access(t, kvs, e); !strings.Contains(got, "only for whoever runs the bot") {
+		t.Errorf("Expected it to be refused for someone else, Got %q", got)
+	}
+	e = komainutest.Command("testowner")
+	SetOwners([]discord.UserID{discord.UserID(e.ID)})
+	if got := access(t, kvs, e); got != "Handled /testowner" {
+		t.Errorf("Expected it to be handled for the owner, Got %q", got)
+	}
+}
+
+func TestAccessFeature(t *testing.T) {
+	kvs := storage.OpenMemory()
+	if got := access(t, kvs, komainutest.Command("testfeature")); got != "Handled /testfeature" {
+		t.Errorf("Expected it to be handled with the feature on, Got %q", got)
+	}
+	if err := storage.SetFeature(kvs, komainutest.GuildID, "xp", false); err != nil {
+		t.Fatalf("Could not turn the feature off: %s", err)
+	}
+	if got := access(t, kvs, komainutest.Command("testfeature")); !strings.Contains(got, "turned off here") {
+		t.Errorf("Expected it to be refused with the feature off, Got %q", got)
+	}
+}
+
+func TestPanicIsRecovered(t *testing.T) {
+	kvs := storage.OpenMemory()
+	if got := access(t, kvs, komainutest.Command("testpanic")); got != "An error occured, and has been logged." {
+		t.Errorf("Expected to be told there was an error, Got %q", got)
+	}
+	if got := access(t, kvs, komainutest.Command("testguild")); got != "Handled /testguild" {
+		t.Errorf("Expected the bot to keep going after the panic, Got %q", got)
+	}
+}
+
+func TestPanicAfterRespondingIsRecovered(t *testing.T) {
+	kvs := storage.OpenMemory()
+	d := komainutest.New()
+	AddHandler(d.State, kvs)
+	in := func(e *gateway.InteractionCreateEvent) *gateway.InteractionCreateEvent {
+		return komainutest.In(e, komainutest.GuildID, komainutest.ChannelID+2) // Out of the way of the other tests' channel tokens.
+	}
+
+	d.Dispatch(in(komainutest.Command("testdeferredpanic")))
+	call, ok := d.WaitFor("PATCH", "webhooks/*/*/messages/@original")
+	if !ok {
+		t.Fatalf("The deferred response was never edited")
+	}
+	edit := api.EditInteractionResponseData{}
+	if err := call.Decode(&edit); err != nil {
+		t.Fatalf("Could not decode the edit: %s", err)
+	}
+	if edit.Content == nil || edit.Content.Val != "An error occured, and has been logged." {
+		t.Errorf("Expected the error to be edited in, Got %v", edit.Content)
+	}
+
+	if got := access(t, kvs, in(komainutest.Command("testcallbackpanic"))); got != "Handled /testcallbackpanic" {
+		t.Errorf("Expected the response before the callback, Got %q", got)
+	}
+	if got := access(t, kvs, in(komainutest.Command("testguild"))); got != "Handled /testguild" {
+		t.Errorf("Expected the bot to keep going after the panics, Got %q", got)
+	}
+}
+
+func TestCooldown(t *testing.T) {
+	kvs := storage.OpenMemory()
+	if err := storage.SetCooldown(kvs, komainutest.GuildID, "testguild", storage.Cooldown{Seconds: 60, PerChannel: true}); err != nil {
+		t.Fatalf("Could not set the cooldown: %s", err)
+	}
+	if got := access(t, kvs, komainutest.Command("testguild")); got != "Handled /testguild" {
+		t.Errorf("Expected the first use to be handled, Got %q", got)
+	}
+	if got := access(t, kvs, komainutest.Command("testguild")); !strings.Contains(got, "Try again in 60s") {
+		t.Errorf("Expected someone else in the channel to be told to wait, Got %q", got)
+	}
+	if got := access(t, kvs, komainutest.In(komainutest.Command("testguild"), komainutest.GuildID, komainutest.ChannelID+1)); got != "Handled /testguild" {
+		t.Errorf("Expected it to be handled in another channel, Got %q", got)
+	}
+}
+
+// ephemeral dispatches the event, and tells whether it was answered ephemerally.
+func ephemeral(t *testing.T, kvs storage.KeyValueStore, e *gateway.InteractionCreateEvent) bool {
+	t.Helper()
+	d := komainutest.New()
+	AddHandler(d.State, kvs)
+	d.Dispatch(komainutest.As(e, discord.UserID(e.ID)))
+	resp, ok := d.Response(e)
+	if !ok {
+		t.Fatalf("There was no response to /%s", e.Data.(*discord.CommandInteraction).Name)
+	}
+	return resp.Data.Flags&api.EphemeralResponse != 0
+}
+
+func TestVisibility(t *testing.T) {
+	kvs := storage.OpenMemory()
+	if !ephemeral(t, kvs, komainutest.Command("testshare")) || ephemeral(t, kvs, komainutest.Command("testpublic")) {
+		t.Fatal("Expected the commands to answer as they usually do before anything is picked")
+	}
+	for _, name := range []string{"testshare", "testguild"} {
+		if err := storage.SetVisibility(kvs, komainutest.GuildID, name, storage.VisibilityPublic); err != nil {
+			t.Fatalf("Could not set the visibility: %s", err)
+		}
+	}
+	if ephemeral(t, kvs, komainutest.Command("testshare")) {
+		t.Error("Expected the shareable answer to be made public")
+	}
+	if !ephemeral(t, kvs, komainutest.Command("testguild")) {
+		t.Error("Expected an answer that isn't shareable to stay ephemeral")
+	}
+	if err := storage.SetVisibility(kvs, komainutest.GuildID, "testpublic", storage.VisibilityEphemeral); err != nil {
+		t.Fatalf("Could not set the visibility: %s", err)
+	}
+	if !ephemeral(t, kvs, komainutest.Command("testpublic")) {
+		t.Error("Expected the public answer to be made ephemeral")
+	}
+}
@@ -0,0 +1,123 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"komainu/locale"
+	"komainu/logging"
+	"komainu/metrics"
+	"komainu/storage"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// ResponseWindow is how long Discord waits for an interaction to be responded to, before it gives up on it.
+const ResponseWindow = 3 * time.Second
+
+// InteractionLifetime is how long the interaction token works, for following up on deferred responses.
+// After that there is no way to tell anyone how it went, so the context is done.
+const InteractionLifetime = 15 * time.Minute
+
+// Context is everything a command, component or modal handler gets to work with. Things every handler might want
+// go here, rather than in every handler signature.
+//
+// It is also a context.Context, done once the interaction token expires, or once the handler is given up on for not
+// responding in time. The State and KVS are tied to it, so calls to Discord and storage fail rather than pile up.
+type Context struct {
+	context.Context
+	State     *state.State
+	KVS       storage.KeyValueStore
+	Event     *gateway.InteractionCreateEvent
+	Language  discord.Language // The language the guild has set with /config locale.
+	Logger    *slog.Logger     // Logs with the guild, user, interaction and handler as fields.
+	RespondBy time.Time        // When Discord stops waiting for a response. Defer, if the work might take longer.
+
+	cancel   context.CancelFunc
+	timezone *time.Location
+}
+
+// NewContext sets up the Context for an interaction of the given kind, like "command", handled by the named handler.
+// Discord's wait for a response started when the interaction was received, which may have been a while ago if it was queued.
+func NewContext(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, received time.Time, kind string, name string) *Context {
+	ctx, cancel := context.WithTimeout(context.Background(), InteractionLifetime)
+	return &Context{
+		Context:   ctx,
+		State:     state.WithContext(ctx),
+		KVS:       storage.WithContext(ctx, kvs),
+		Event:     event,
+		Language:  locale.ForGuild(kvs, event.GuildID),
+		Logger:    logging.Interaction(event, kind, name),
+		RespondBy: received.Add(ResponseWindow),
+		cancel:    cancel,
+	}
+}
+
+// Cancel gives up on the interaction, making anything still using the context fail.
+func (ctx *Context) Cancel() {
+	ctx.cancel()
+}
+
+// Run calls handle, and waits for it until the response is due. If it takes longer than that, the context is
+// cancelled so whatever the handler is still doing fails rather than piles up, and ok is false. Either way, the
+// worker gets on with the next interaction. If handle panics, the panic is logged with the stack, and the result is
+// failed instead.
+func Run[T any](ctx *Context, handle func() T, failed T) (result T, ok bool) {
+	done := make(chan T, 1)
+	go func() {
+		result := failed
+		Protect(ctx.Logger, func() { result = handle() })
+		done <- result
+	}()
+	select {
+	case result = <-done:
+		return result, true
+	case <-time.After(ctx.Remaining()):
+		ctx.Cancel()
+		return result, false
+	}
+}
+
+// Protect calls work, and if it panics, logs the panic with the stack so it's reported, rather than taking the bot
+// down with it. For anything done outside Run, like callbacks and deferred work.
+func Protect(logger *slog.Logger, work func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Handler panicked", "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+			metrics.Errors.Inc("panic")
+		}
+	}()
+	work()
+}
+
+// T translates the format into the language of the guild, if there is a translation for it, and fills in the arguments.
+func (ctx *Context) T(format string, args ...interface{}) string {
+	return locale.T(ctx.Language, format, args...)
+}
+
+// Timezone gets the time zone the guild has set with /config timezone, looking it up the first time it's needed.
+// If it can't be looked up, it's UTC.
+func (ctx *Context) Timezone() *time.Location {
+	if ctx.timezone == nil {
+		location, err := storage.GetTimezone(ctx.KVS, ctx.Event.GuildID)
+		if err != nil {
+			ctx.Logger.Warn("Failed to get the time zone, going with UTC", "error", err)
+		}
+		ctx.timezone = location
+	}
+	return ctx.timezone
+}
+
+// Scope gives the guild to store things under. In private, that's a scope of the user's own.
+func (ctx *Context) Scope() discord.GuildID {
+	return Scope(ctx.Event)
+}
+
+// Remaining is how long is left before Discord stops waiting for a response.
+func (ctx *Context) Remaining() time.Duration {
+	return time.Until(ctx.RespondBy)
+}
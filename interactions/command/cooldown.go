@@ -0,0 +1,64 @@
+package command
+
+import (
+	"komainu/locale"
+	"komainu/storage"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+// cooldownKey is who, or which channel, used which command where.
+type cooldownKey struct {
+	guildID discord.GuildID
+	command string
+	who     discord.Snowflake // The user, or the channel for cooldowns per channel.
+}
+
+// cooldowns holds when each command can be used again. It's only in memory, so a restart lets everyone off.
+var cooldowns = map[cooldownKey]time.Time{}
+var cooldownsLock sync.Mutex
+
+// lastSweep is when cooldowns was last rid of the ones that are over.
+var lastSweep time.Time
+
+// cooldownLeft checks the guild's cooldown for the command, and if it's not cooling down, starts the cooldown.
+// It returns how long is left if it is, and whether that's for the channel rather than the user.
+func cooldownLeft(kvs storage.KeyValueStore, e *gateway.InteractionCreateEvent, name string) (time.Duration, bool, error) {
+	exist, cooldown, err := storage.GetCooldown(kvs, e.GuildID, name)
+	if err != nil || !exist {
+		return 0, false, err
+	}
+	key := cooldownKey{guildID: e.GuildID, command: name, who: discord.Snowflake(e.SenderID())}
+	if cooldown.PerChannel {
+		key.who = discord.Snowflake(e.ChannelID)
+	}
+
+	cooldownsLock.Lock()
+	defer cooldownsLock.Unlock()
+	now := time.Now()
+	if now.Sub(lastSweep) > time.Minute {
+		for k, until := range cooldowns {
+			if !until.After(now) {
+				delete(cooldowns, k)
+			}
+		}
+		lastSweep = now
+	}
+	if until, ok := cooldowns[key]; ok && until.After(now) {
+		return until.Sub(now), cooldown.PerChannel, nil
+	}
+	cooldowns[key] = now.Add(time.Duration(cooldown.Seconds) * time.Second)
+	return 0, false, nil
+}
+
+// cooldownMessage tells someone how long until they can use the command again, in the language.
+func cooldownMessage(language discord.Language, name string, left time.Duration, perChannel bool) string {
+	seconds := int64((left + time.Second - 1) / time.Second) // Rounded up, so it's never "try again in 0s".
+	if perChannel {
+		return locale.T(language, "/%s was used in this channel just now. Try again in %ds.", name, seconds)
+	}
+	return locale.T(language, "You used /%s just now. Try again in %ds.", name, seconds)
+}
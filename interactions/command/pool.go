@@ -0,0 +1,61 @@
+package command
+
+import (
+	"komainu/interactions/response"
+	"komainu/locale"
+	"komainu/metrics"
+	"komainu/storage"
+	"log"
+	"sync/atomic"
+
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// workers is how many interactions are handled at the same time. The rest wait in the queue for one to be free.
+const workers = 8
+
+// queueLength is how many interactions can wait for a worker. Any more than that are turned away.
+const queueLength = 100
+
+var queue = make(chan func(), queueLength)
+
+// working is how many workers are busy with an interaction right now.
+var working atomic.Int64
+
+func init() {
+	for i := 0; i < workers; i++ {
+		go work()
+	}
+	metrics.NewGaugeFunc("komainu_interaction_queue_depth", "Interactions waiting for a worker.", func() float64 {
+		return float64(len(queue))
+	})
+	metrics.NewGaugeFunc("komainu_interaction_workers_busy", "Workers busy handling an interaction.", func() float64 {
+		return float64(working.Load())
+	})
+}
+
+func work() {
+	for job := range queue {
+		working.Add(1)
+		job()
+		working.Add(-1)
+	}
+}
+
+// Enqueue hands an interaction to the workers, so only so many are handled at once, and a burst of heavy commands
+// can't swamp everything else. If too many are waiting already, it is turned away with a reply saying so, and false is returned.
+func Enqueue(state *state.State, kvs storage.KeyValueStore, e *gateway.InteractionCreateEvent, kind string, job func()) bool {
+	select {
+	case queue <- job:
+		return true
+	default:
+	}
+	metrics.Shed.Inc(kind)
+	log.Printf("[%s] Too busy to handle a %s interaction from <@%s>, so it was turned away", e.GuildID, kind, e.SenderID())
+	reply := response.Ephemeral(locale.Translate(locale.ForGuild(kvs, e.GuildID), "I'm very busy right now. Please try again in a moment."))
+	if err := state.RespondInteraction(e.ID, e.Token, reply); err != nil {
+		log.Printf("[%s] ...and there was an error telling them so: %s", e.GuildID, err)
+	}
+	return false
+}
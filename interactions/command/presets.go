@@ -0,0 +1,185 @@
+package command
+
+import (
+	"fmt"
+	"komainu/storage"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/json"
+)
+
+// Lookup returns the registered handler for the named command, if there is one.
+func Lookup(name string) (Handler, bool) {
+	handler, ok := commands[name]
+	return handler, ok
+}
+
+// Names returns the names of all the registered commands.
+func Names() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	return names
+}
+
+// FindDeclaredOption looks up the declaration of the named option on the given command path, like "vote start".
+func FindDeclaredOption(path string, name string) (discord.CommandOptionValue, error) {
+	words := strings.Fields(path)
+	if len(words) == 0 {
+		return nil, fmt.Errorf("no command given")
+	}
+	handler, ok := commands[words[0]]
+	if !ok {
+		return nil, fmt.Errorf("there is no /%s command", words[0])
+	}
+	declared, err := declaredOptions(handler.Options, words[1:])
+	if err != nil {
+		return nil, fmt.Errorf("/%s: %w", path, err)
+	}
+	for _, opt := range declared {
+		if opt.Name() == name {
+			return opt, nil
+		}
+	}
+	return nil, fmt.Errorf("/%s has no %q option", path, name)
+}
+
+// declaredOptions walks down the subcommand groups and subcommands in the path, and returns the value options at the end.
+func declaredOptions(options []discord.CommandOption, path []string) ([]discord.CommandOptionValue, error) {
+	if len(path) == 0 {
+		values := []discord.CommandOptionValue{}
+		for _, opt := range options {
+			if value, ok := opt.(discord.CommandOptionValue); ok {
+				values = append(values, value)
+			}
+		}
+		return values, nil
+	}
+	for _, opt := range options {
+		if opt.Name() != path[0] {
+			continue
+		}
+		switch sub := opt.(type) {
+		case *discord.SubcommandGroupOption:
+			subOptions := make([]discord.CommandOption, len(sub.Subcommands))
+			for i, subcommand := range sub.Subcommands {
+				subOptions[i] = subcommand
+			}
+			return declaredOptions(subOptions, path[1:])
+		case *discord.SubcommandOption:
+			if len(path) > 1 {
+				return nil, fmt.Errorf("%s has no subcommands", path[0])
+			}
+			return sub.Options, nil
+		}
+	}
+	return nil, fmt.Errorf("no subcommand %q", path[0])
+}
+
+// IsRequired checks if Discord will insist on the option being filled in.
+func IsRequired(opt discord.CommandOptionValue) bool {
+	switch o := opt.(type) {
+	case *discord.StringOption:
+		return o.Required
+	case *discord.IntegerOption:
+		return o.Required
+	case *discord.BooleanOption:
+		return o.Required
+	case *discord.UserOption:
+		return o.Required
+	case *discord.ChannelOption:
+		return o.Required
+	case *discord.RoleOption:
+		return o.Required
+	case *discord.MentionableOption:
+		return o.Required
+	case *discord.NumberOption:
+		return o.Required
+	case *discord.AttachmentOption:
+		return o.Required
+	}
+	return false
+}
+
+// PresetValue turns the text of a preset into the JSON Discord would have sent for the given option.
+// This doubles as validation when the preset is saved.
+func PresetValue(opt discord.CommandOptionValue, value string) (json.Raw, error) {
+	value = strings.TrimSpace(value)
+	switch opt.(type) {
+	case *discord.StringOption:
+		return json.Marshal(value)
+	case *discord.IntegerOption:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("%q is not a whole number", value)
+		}
+		return json.Raw(value), nil
+	case *discord.NumberOption:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return json.Raw(value), nil
+	case *discord.BooleanOption:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not true or false", value)
+		}
+		return json.Marshal(b)
+	case *discord.UserOption, *discord.ChannelOption, *discord.RoleOption, *discord.MentionableOption:
+		// Accepting mentions as well as bare IDs, because that's what people will paste.
+		value = strings.Trim(value, "<@&#!>")
+		if _, err := discord.ParseSnowflake(value); err != nil {
+			return nil, fmt.Errorf("%q is not a mention or an ID", value)
+		}
+		return json.Marshal(value)
+	}
+	return nil, fmt.Errorf("options of type %d can't have presets", opt.Type())
+}
+
+// interactionPath figures out which subcommand was used, and returns the command path along with the options given to it.
+func interactionPath(interaction *discord.CommandInteraction) (path string, options *discord.CommandInteractionOptions) {
+	words := []string{interaction.Name}
+	options = &interaction.Options
+	for len(*options) == 1 {
+		opt := &(*options)[0]
+		if opt.Type != discord.SubcommandOptionType && opt.Type != discord.SubcommandGroupOptionType {
+			break
+		}
+		words = append(words, opt.Name)
+		options = &opt.Options
+	}
+	return strings.Join(words, " "), options
+}
+
+// applyPresets fills in any options the user left out with the guild's presets for them.
+func applyPresets(kvs storage.KeyValueStore, guildID discord.GuildID, interaction *discord.CommandInteraction) {
+	path, options := interactionPath(interaction)
+	presets, err := storage.GetPresets(kvs, guildID, path)
+	if err != nil {
+		log.Printf("[%s] Failed to get presets for /%s: %s", guildID, path, err)
+		return
+	}
+	for name, value := range presets {
+		if options.Find(name).Name != "" {
+			continue // They filled it in themselves.
+		}
+		declared, err := FindDeclaredOption(path, name)
+		if err != nil {
+			log.Printf("[%s] Preset for an option that doesn't exist any more: %s", guildID, err)
+			continue
+		}
+		raw, err := PresetValue(declared, value)
+		if err != nil {
+			log.Printf("[%s] Invalid preset for %q in /%s: %s", guildID, name, path, err)
+			continue
+		}
+		*options = append(*options, discord.CommandInteractionOption{
+			Type:  declared.Type(),
+			Name:  name,
+			Value: raw,
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package command
+
+import (
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// applyVisibility makes the response public or ephemeral, if the guild picked that for the command with
+// /config visibility. Only answers that were made to be shared are made public, so errors stay private, and responses
+// the command goes on to use, like a poll it keeps updating, are left alone.
+func applyVisibility(kvs storage.KeyValueStore, guildID discord.GuildID, name string, resp *Response) {
+	visibility, err := storage.GetVisibility(kvs, guildID, name)
+	if err != nil {
+		log.Printf("[%s] Failed to get the visibility of /%s: %s", guildID, name, err)
+		return
+	}
+	switch visibility {
+	case storage.VisibilityPublic:
+		response.MakePublic(&resp.Response)
+	case storage.VisibilityEphemeral:
+		if resp.Callback != nil && resp.Response.Type != api.DeferredMessageInteractionWithSource {
+			return
+		}
+		response.MakeEphemeral(&resp.Response)
+	}
+}
@@ -1,10 +1,15 @@
 package component
 
 import (
+	"komainu/interactions/command"
 	"komainu/interactions/response"
+	"komainu/locale"
+	"komainu/metrics"
 	"komainu/storage"
 	"log"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/diamondburned/arikawa/v3/api"
 	"github.com/diamondburned/arikawa/v3/discord"
@@ -17,10 +22,9 @@ type Handler struct {
 }
 
 type HandlerFunction func(
-	state *state.State,
-	kvs storage.KeyValueStore,
-	event *gateway.InteractionCreateEvent,
+	ctx *command.Context,
 	interaction discord.ComponentInteraction,
+	params []string,
 ) api.InteractionResponse
 
 var registrations = map[string]Handler{}
@@ -30,23 +34,74 @@ func Register(identifier string, handler Handler) {
 	registrations[identifier] = handler
 }
 
+// ID builds a CustomID that routes to the named handler, which gets the params back as they were given.
+// Each param is URL encoded, so they can hold anything, slashes included.
+func ID(name string, params ...string) discord.ComponentID {
+	parts := make([]string, len(params)+1)
+	parts[0] = name
+	for i, param := range params {
+		parts[i+1] = url.PathEscape(param)
+	}
+	return discord.ComponentID(strings.Join(parts, "/"))
+}
+
+// Decode splits a CustomID made by ID back into the handler name and the params.
+// A param that won't decode is passed along as it is, as older buttons may not have encoded theirs.
+func Decode(id discord.ComponentID) (name string, params []string) {
+	parts := strings.Split(string(id), "/")
+	params = make([]string, len(parts)-1)
+	for i, part := range parts[1:] {
+		param, err := url.PathUnescape(part)
+		if err != nil {
+			param = part
+		}
+		params[i] = param
+	}
+	return parts[0], params
+}
+
 // AddHandler adds the component interaction handler to the given state
 func AddHandler(state *state.State, kvs storage.KeyValueStore) {
 	state.AddHandler(func(e *gateway.InteractionCreateEvent) {
 		if interaction, ok := e.Data.(discord.ComponentInteraction); ok {
-			target := strings.SplitN(string(interaction.ID()), "/", 2)[0]
-
-			if handler, ok := registrations[target]; ok {
-				resp := handler.Code(state, kvs, e, interaction)
-				if err := state.RespondInteraction(e.ID, e.Token, resp); err != nil {
-					log.Printf("[%s] Failed to send component interaction response: %s", e.GuildID, err)
-				}
-			} else {
-				log.Printf("[%s] Got a %q component interaction, but there is no registered handler!", e.GuildID, target)
-				if err := state.RespondInteraction(e.ID, e.Token, response.Ephemeral("Something odd happened. It has been logged.")); err != nil {
-					log.Printf("[%s] ...and there was an error informing the user: %s", e.GuildID, err)
-				}
-			}
+			received := time.Now()
+			command.Enqueue(state, kvs, e, "component", func() {
+				handleComponent(state, kvs, e, interaction, received)
+			})
 		}
 	})
 }
+
+// handleComponent handles a component interaction, on one of the workers.
+func handleComponent(state *state.State, kvs storage.KeyValueStore, e *gateway.InteractionCreateEvent, interaction discord.ComponentInteraction, received time.Time) {
+	target, params := Decode(interaction.ID())
+
+	if handler, ok := registrations[target]; ok {
+		ctx := command.NewContext(state, kvs, e, received, "component", target)
+		logger := ctx.Logger
+		start := time.Now()
+		resp, ok := command.Run(ctx, func() api.InteractionResponse { return handler.Code(ctx, interaction, params) }, response.Ephemeral("An error occured, and has been logged."))
+		if !ok {
+			logger.Error("Component took too long to respond, and was given up on", "after", time.Since(start))
+			metrics.Errors.Inc("component")
+			return
+		}
+		metrics.Interactions.Inc("component", target)
+		metrics.HandlerSeconds.Since(start, "component", target)
+		logger.Debug("Component handled", "duration", time.Since(start))
+		if response.IsError(resp) {
+			logger.Error("Component responded with an error", "response", resp.Data.Content.Val)
+			metrics.Errors.Inc("component")
+		}
+		locale.TranslateResponse(locale.ForGuild(kvs, e.GuildID), &resp)
+		if err := state.RespondInteraction(e.ID, e.Token, resp); err != nil {
+			logger.Error("Failed to send component interaction response", "error", err)
+			metrics.Errors.Inc("component")
+		}
+	} else {
+		log.Printf("[%s] Got a %q component interaction, but there is no registered handler!", e.GuildID, target)
+		if err := state.RespondInteraction(e.ID, e.Token, response.Ephemeral(locale.Translate(locale.ForGuild(kvs, e.GuildID), "Something odd happened. It has been logged."))); err != nil {
+			log.Printf("[%s] ...and there was an error informing the user: %s", e.GuildID, err)
+		}
+	}
+}
@@ -0,0 +1,807 @@
+package interactions
+
+import (
+	"errors"
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/locale"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+var configModule = module.Basic{
+	ModuleName: "config",
+	CommandHandlers: map[string]command.Handler{
+		"config": commandConfigObject,
+	},
+}
+
+var commandConfigObject = command.Handler{
+	Description: "Configure how the bot behaves in this guild",
+	Code:        CommandConfig,
+	Options: []discord.CommandOption{
+		&discord.SubcommandGroupOption{
+			OptionName:  "defaults",
+			Description: "Presets for command options people leave blank",
+			Subcommands: []*discord.SubcommandOption{
+				{
+					OptionName:  "set",
+					Description: "Set what an option should be when it's left blank",
+					Options: []discord.CommandOptionValue{
+						&discord.StringOption{
+							OptionName:  "command",
+							Description: "The command, with subcommand if any, like \"vote start\"",
+							Required:    true,
+						},
+						&discord.StringOption{
+							OptionName:  "option",
+							Description: "The name of the option, like \"length\"",
+							Required:    true,
+						},
+						&discord.StringOption{
+							OptionName:  "value",
+							Description: "What to use when the option is left blank",
+							Required:    true,
+						},
+					},
+				},
+				{
+					OptionName:  "clear",
+					Description: "Go back to the built-in behavior when an option is left blank",
+					Options: []discord.CommandOptionValue{
+						&discord.StringOption{
+							OptionName:  "command",
+							Description: "The command, with subcommand if any, like \"vote start\"",
+							Required:    true,
+						},
+						&discord.StringOption{
+							OptionName:  "option",
+							Description: "The name of the option, like \"length\"",
+							Required:    true,
+						},
+					},
+				},
+				{
+					OptionName:  "list",
+					Description: "List the presets in this guild",
+					Options:     []discord.CommandOptionValue{},
+				},
+			},
+		},
+		&discord.SubcommandGroupOption{
+			OptionName:  "api",
+			Description: "Access to this guild's data through the REST API",
+			Subcommands: []*discord.SubcommandOption{
+				{
+					OptionName:  "token",
+					Description: "Make a new token, replacing the old one if there is one",
+					Options: []discord.CommandOptionValue{
+						&discord.BooleanOption{
+							OptionName:  "write",
+							Description: "If the token can change things, and not just read them. Default is no.",
+							Required:    false,
+						},
+					},
+				},
+				{
+					OptionName:  "revoke",
+					Description: "Remove the token, so the REST API can't be used here",
+					Options:     []discord.CommandOptionValue{},
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "webhook",
+			Description: "Where to send word of votes closing, members piling up warnings and tickets opening",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "url",
+					Description: "An https:// address to post to. Blank to stop sending.",
+					Required:    false,
+				},
+				&discord.IntegerOption{
+					OptionName:  "warnings",
+					Description: "How many warnings someone has when it's sent. Default is 3.",
+					Required:    false,
+					Min:         option.NewInt(1),
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "botnews",
+			Description: "Where to get news about the bot itself, like maintenance and changes",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:  "channel",
+					Description: "The channel for bot news. Blank to stop getting it.",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "suggestions",
+			Description: "Where /suggest posts suggestions",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:  "channel",
+					Description: "The suggestion channel. Blank to disable suggestions.",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "mention",
+			Description: "What the bot says when someone mentions it without saying anything else",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "text",
+					Description: "A short orientation. Blank to go back to the default.",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "locale",
+			Description: "The language the bot speaks in this guild",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "language",
+					Description: "What language to speak",
+					Required:    true,
+					Choices:     localeChoices(),
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "features",
+			Description: "Turn parts of the bot on or off here, or see which are",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "feature",
+					Description: "What to turn on or off. Blank to list them all.",
+					Required:    false,
+					Choices:     featureChoices(),
+				},
+				&discord.StringOption{
+					OptionName:  "state",
+					Description: "On, off, or whatever the default is",
+					Required:    false,
+					Choices: []discord.StringChoice{
+						{Name: "On", Value: "on"},
+						{Name: "Off", Value: "off"},
+						{Name: "Default", Value: "default"},
+					},
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "cooldown",
+			Description: "Make people wait between uses of a command, or see which commands they have to",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "command",
+					Description: "The command, like \"faq\". Blank to list the cooldowns.",
+					Required:    false,
+				},
+				&discord.IntegerOption{
+					OptionName:  "seconds",
+					Description: "How long to wait between uses. Zero removes the cooldown.",
+					Required:    false,
+					Min:         option.NewInt(0),
+					Max:         option.NewInt(storage.MaxCooldown),
+				},
+				&discord.StringOption{
+					OptionName:  "per",
+					Description: "Whether each user waits, or the whole channel does. Each user, if left blank.",
+					Required:    false,
+					Choices: []discord.StringChoice{
+						{Name: "User", Value: "user"},
+						{Name: "Channel", Value: "channel"},
+					},
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "visibility",
+			Description: "Pick whether everyone sees what a command answers, or just whoever used it",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "command",
+					Description: "The command, like \"seen\". Blank to list what's been picked.",
+					Required:    false,
+				},
+				&discord.StringOption{
+					OptionName:  "visibility",
+					Description: "Who sees the answers",
+					Required:    false,
+					Choices: []discord.StringChoice{
+						{Name: "Everyone", Value: storage.VisibilityPublic},
+						{Name: "Just whoever used it", Value: storage.VisibilityEphemeral},
+						{Name: "Default", Value: "default"},
+					},
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "presence",
+			Description: "Note when people are online, not just when they say something, for /seen",
+			Options: []discord.CommandOptionValue{
+				&discord.BooleanOption{
+					OptionName:  "enabled",
+					Description: "Turning it off forgets when everyone was last online.",
+					Required:    true,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "timezone",
+			Description: "The time zone times are given in, like when a vote ends",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "name",
+					Description: "Like Europe/Oslo or America/New_York. Blank to go back to UTC.",
+					Required:    false,
+				},
+			},
+		},
+	},
+}
+
+// CommandConfig processes the /config command, dispatching to the right subcommand.
+func CommandConfig(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /config command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	if cmd.Options[0].Type == discord.SubcommandOptionType {
+		switch cmd.Options[0].Name {
+		case "botnews":
+			return SubCommandConfigBotNews(kvs, event, cmd.Options[0].Options)
+		case "suggestions":
+			return SubCommandConfigSuggestions(kvs, event, cmd.Options[0].Options)
+		case "mention":
+			return SubCommandConfigMention(kvs, event, cmd.Options[0].Options)
+		case "timezone":
+			return SubCommandConfigTimezone(kvs, event, cmd.Options[0].Options)
+		case "locale":
+			return SubCommandConfigLocale(kvs, event, cmd.Options[0].Options)
+		case "features":
+			return SubCommandConfigFeatures(kvs, event, cmd.Options[0].Options)
+		case "cooldown":
+			return SubCommandConfigCooldown(kvs, event, cmd.Options[0].Options)
+		case "visibility":
+			return SubCommandConfigVisibility(kvs, event, cmd.Options[0].Options)
+		case "presence":
+			return SubCommandConfigPresence(state, kvs, event, cmd.Options[0].Options)
+		case "webhook":
+			return SubCommandConfigWebhook(kvs, event, cmd.Options[0].Options)
+		default:
+			return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+		}
+	}
+	group := cmd.Options[0]
+	if len(group.Options) != 1 {
+		log.Printf("[%s] /config %s command structure is somehow not a single subcommand. Wat.\n", event.GuildID, group.Name)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	sub := group.Options[0]
+	switch group.Name + " " + sub.Name {
+	case "defaults set":
+		return SubCommandConfigDefaultsSet(kvs, event, sub.Options)
+	case "defaults clear":
+		return SubCommandConfigDefaultsClear(kvs, event, sub.Options)
+	case "defaults list":
+		return SubCommandConfigDefaultsList(kvs, event)
+	case "api token":
+		return SubCommandConfigAPIToken(kvs, event, sub.Options)
+	case "api revoke":
+		return SubCommandConfigAPIRevoke(kvs, event)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// presetPath tidies up a command path as typed by a human, so "/Vote  start" becomes "vote start".
+func presetPath(input string) string {
+	return strings.Join(strings.Fields(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(input), "/"))), " ")
+}
+
+// SubCommandConfigDefaultsSet validates and stores a preset for a command option.
+func SubCommandConfigDefaultsSet(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	path := presetPath(options.Find("command").String())
+	name := strings.ToLower(strings.TrimSpace(options.Find("option").String()))
+	value := options.Find("value").String()
+
+	declared, err := command.FindDeclaredOption(path, name)
+	if err != nil {
+		return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "I can't find that: %s", err))}
+	}
+	if command.IsRequired(declared) {
+		return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "`%s` can't be left blank in `/%s`, so a preset would never be used.", name, path))}
+	}
+	if _, err := command.PresetValue(declared, value); err != nil {
+		return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "That won't work for `%s`: %s", name, err))}
+	}
+
+	if err := storage.SetPreset(kvs, event.GuildID, path, name, value); err != nil {
+		log.Printf("[%s] Failed to store preset for %s in /%s: %s", event.GuildID, name, path, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> set the preset for %s in /%s to %q", event.GuildID, event.SenderID(), name, path, value)
+	return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "When `%s` is left blank in `/%s`, it will now be `%s`.", name, path, value))}
+}
+
+// SubCommandConfigDefaultsClear removes a preset for a command option.
+func SubCommandConfigDefaultsClear(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	path := presetPath(options.Find("command").String())
+	name := strings.ToLower(strings.TrimSpace(options.Find("option").String()))
+
+	presets, err := storage.GetPresets(kvs, event.GuildID, path)
+	if err != nil {
+		log.Printf("[%s] Failed to get presets for /%s: %s", event.GuildID, path, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if _, ok := presets[name]; !ok {
+		return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "There is no preset for `%s` in `/%s`.", name, path))}
+	}
+	if err := storage.ClearPreset(kvs, event.GuildID, path, name); err != nil {
+		log.Printf("[%s] Failed to clear preset for %s in /%s: %s", event.GuildID, name, path, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> cleared the preset for %s in /%s", event.GuildID, event.SenderID(), name, path)
+	return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "Preset for `%s` in `/%s` cleared.", name, path))}
+}
+
+// SubCommandConfigDefaultsList lists all the presets in the guild.
+func SubCommandConfigDefaultsList(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	presets, err := storage.AllPresets(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to list presets: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(presets) == 0 {
+		return command.Response{Response: response.Ephemeral("There are no presets. Everything left blank gets the built-in behavior.")}
+	}
+	keys := make([]string, 0, len(presets))
+	for key := range presets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lastSlash := strings.LastIndex(key, "/")
+		lines[i] = fmt.Sprintf("`/%s` `%s`: `%s`", key[:lastSlash], key[lastSlash+1:], presets[key])
+	}
+	return command.Response{Response: response.Ephemeral(strings.Join(lines, "\n"))}
+}
+
+// SubCommandConfigAPIToken makes a new REST API token for the guild, and shows it to the user this one time.
+func SubCommandConfigAPIToken(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	write := false
+	if opt := options.Find("write"); opt.Name != "" {
+		var err error
+		write, err = opt.BoolValue()
+		if err != nil {
+			log.Printf("[%s] /config api token failed to get the write option: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+	}
+	token, err := storage.NewAPIToken(kvs, event.GuildID, event.SenderID(), write)
+	if err != nil {
+		log.Printf("[%s] Failed to make a REST API token: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> made a new REST API token, write access: %t", event.GuildID, event.SenderID(), write)
+	access := "read"
+	if write {
+		access = "read and write"
+	}
+	return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "Here is the new token, with %s access. Any old token no longer works. This is the only time it is shown, so keep it somewhere safe:\n||`%s`||", access, token))}
+}
+
+// SubCommandConfigAPIRevoke removes the REST API token of the guild.
+func SubCommandConfigAPIRevoke(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	if err := storage.RevokeAPIToken(kvs, event.GuildID); err != nil {
+		log.Printf("[%s] Failed to revoke the REST API token: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> revoked the REST API token", event.GuildID, event.SenderID())
+	return command.Response{Response: response.Ephemeral("The token is revoked, and the REST API can't be used here until a new one is made.")}
+}
+
+// SubCommandConfigWebhook sets or clears where webhooks are sent, showing the signing secret when set.
+func SubCommandConfigWebhook(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	address := strings.TrimSpace(options.Find("url").String())
+	if address == "" {
+		if err := storage.ClearWebhookConfig(kvs, event.GuildID); err != nil {
+			log.Printf("[%s] Failed to remove the webhook: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		log.Printf("[%s] <@%s> removed the webhook", event.GuildID, event.SenderID())
+		return command.Response{Response: response.Ephemeral("Okay, no more webhooks.")}
+	}
+	parsed, err := url.Parse(address)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return command.Response{Response: response.Ephemeral("That doesn't look like an `https://` address.")}
+	}
+	if err := storage.CheckWebhookHost(parsed.Hostname()); errors.Is(err, storage.ErrWebhookAddress) {
+		log.Printf("[%s] <@%s> tried to set the webhook to %s: %s", event.GuildID, event.SenderID(), parsed.Host, err)
+		return command.Response{Response: response.Ephemeral("Webhooks can only go to addresses out on the internet, not to the machine I run on or a network it's on.")}
+	} else if err != nil {
+		return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "I couldn't look up %s: %s", parsed.Hostname(), err))}
+	}
+	warnings := 0
+	if opt := options.Find("warnings"); opt.Name != "" {
+		value, err := opt.IntValue()
+		if err != nil || value < 1 {
+			return command.Response{Response: response.Ephemeral("The number of warnings has to be a whole number, at least 1.")}
+		}
+		warnings = int(value)
+	}
+	secret, err := storage.SetWebhookConfig(kvs, event.GuildID, parsed.String(), warnings)
+	if err != nil {
+		log.Printf("[%s] Failed to store the webhook: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> set the webhook to %s", event.GuildID, event.SenderID(), parsed.Host)
+	return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "Webhooks now go to %s. Every one is signed with this secret, in the `X-Komainu-Signature` header. This is the only time it is shown:\n||`%s`||", parsed.Host, secret))}
+}
+
+// SubCommandConfigSuggestions sets or clears the channel /suggest posts to.
+func SubCommandConfigSuggestions(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	if options.Find("channel").Name == "" {
+		if err := kvs.Delete(event.GuildID, suggestionCollection, suggestionKey); err != nil {
+			log.Printf("[%s] Failed to remove the suggestion channel setting: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		log.Printf("[%s] <@%s> disabled suggestions", event.GuildID, event.SenderID())
+		return command.Response{Response: response.Ephemeral("Okay, no more suggestions.")}
+	}
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /config suggestions failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	channelID := discord.ChannelID(channelSnowflake)
+	if err := kvs.Set(event.GuildID, suggestionCollection, suggestionKey, channelID); err != nil {
+		log.Printf("[%s] Failed to store the suggestion channel setting: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> set the suggestion channel to <#%s>", event.GuildID, event.SenderID(), channelID)
+	return command.Response{Response: response.Ephemeral("Suggestions will now be posted in", channelID.Mention())}
+}
+
+// SubCommandConfigBotNews sets or clears where news about the bot is posted, for /broadcast.
+func SubCommandConfigBotNews(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	if options.Find("channel").Name == "" {
+		if err := storage.ClearBotNewsChannel(kvs, event.GuildID); err != nil {
+			log.Printf("[%s] Failed to remove the bot news channel setting: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		log.Printf("[%s] <@%s> stopped the bot news", event.GuildID, event.SenderID())
+		return command.Response{Response: response.Ephemeral("Okay, no more news about me.")}
+	}
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /config botnews failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	channelID := discord.ChannelID(channelSnowflake)
+	if err := storage.SetBotNewsChannel(kvs, event.GuildID, channelID); err != nil {
+		log.Printf("[%s] Failed to store the bot news channel setting: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> set the bot news channel to <#%s>", event.GuildID, event.SenderID(), channelID)
+	return command.Response{Response: response.Ephemeral("News about me, like maintenance and changes, will now be posted in", channelID.Mention())}
+}
+
+// SubCommandConfigMention sets or resets what the bot says when it's mentioned.
+func SubCommandConfigMention(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	text := strings.TrimSpace(options.Find("text").String())
+	if text == "" {
+		if err := kvs.Delete(event.GuildID, mentionHelpCollection, mentionHelpKey); err != nil {
+			log.Printf("[%s] Failed to remove the mention text: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		log.Printf("[%s] <@%s> reset the mention text", event.GuildID, event.SenderID())
+		return command.Response{Response: response.Ephemeral("Mentioning me will now get the default reply.")}
+	}
+	if err := utility.ValidateTemplate(text); err != nil {
+		return command.Response{Response: response.Ephemeral("That text won't work: " + err.Error())}
+	}
+	if err := kvs.Set(event.GuildID, mentionHelpCollection, mentionHelpKey, text); err != nil {
+		log.Printf("[%s] Failed to store the mention text: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> set the mention text", event.GuildID, event.SenderID())
+	return command.Response{Response: response.Ephemeral("Mentioning me will now get this reply, followed by the commands they can use:\n" + text)}
+}
+
+// SubCommandConfigTimezone sets the time zone of the guild.
+func SubCommandConfigTimezone(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	name := strings.TrimSpace(options.Find("name").String())
+	location := time.UTC
+	if name != "" {
+		var err error
+		location, err = time.LoadLocation(name)
+		if err != nil || name == "Local" {
+			return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "I don't know the time zone %q. Use a name like `Europe/Oslo` or `America/New_York`.", name))}
+		}
+	}
+	if err := storage.SetTimezone(kvs, event.GuildID, name); err != nil {
+		log.Printf("[%s] Failed to store the time zone: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> set the time zone to %q", event.GuildID, event.SenderID(), name)
+	return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "Times are now given in %s. Right now, that's %s.", location, time.Now().In(location).Format("2006-01-02 15:04")))}
+}
+
+// localeChoices lists the languages there are catalogs for, as choices for the locale option.
+func localeChoices() []discord.StringChoice {
+	choices := []discord.StringChoice{}
+	for _, language := range locale.Languages() {
+		choices = append(choices, discord.StringChoice{Name: locale.Name(language), Value: string(language)})
+	}
+	return choices
+}
+
+func featureChoices() []discord.StringChoice {
+	choices := []discord.StringChoice{}
+	for _, flag := range storage.FeatureFlags {
+		choices = append(choices, discord.StringChoice{Name: flag.Description, Value: flag.Name})
+	}
+	return choices
+}
+
+// describeFeature says whether the feature is on in the guild, and if that's the default.
+func describeFeature(kvs storage.KeyValueStore, guildID discord.GuildID, flag storage.FeatureFlag) (string, error) {
+	set, enabled, err := storage.GetFeature(kvs, guildID, flag.Name)
+	if err != nil {
+		return "", err
+	}
+	how := "turned"
+	if !set {
+		enabled = storage.FeatureDefault(flag.Name)
+		how = "by default"
+	}
+	state := "off"
+	if enabled {
+		state = "on"
+	}
+	return fmt.Sprintf("**%s** is %s %s: %s", flag.Name, how, state, flag.Description), nil
+}
+
+// SubCommandConfigFeatures turns a feature on or off in the guild, or lists them all.
+func SubCommandConfigFeatures(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	name := options.Find("feature").String()
+	state := options.Find("state").String()
+	if name == "" {
+		if state != "" {
+			return command.Response{Response: response.Ephemeral("Turn what on or off? Pick a `feature` as well.")}
+		}
+		lines := []string{}
+		for _, flag := range storage.FeatureFlags {
+			line, err := describeFeature(kvs, event.GuildID, flag)
+			if err != nil {
+				log.Printf("[%s] Failed to get feature %s: %s", event.GuildID, flag.Name, err)
+				return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+			}
+			lines = append(lines, line)
+		}
+		return command.Response{Response: response.Ephemeral(strings.Join(lines, "\n"))}
+	}
+	flag, ok := storage.FindFeatureFlag(name)
+	if !ok {
+		return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "There's no feature called %q.", name))}
+	}
+
+	var err error
+	switch state {
+	case "on":
+		err = storage.SetFeature(kvs, event.GuildID, flag.Name, true)
+	case "off":
+		err = storage.SetFeature(kvs, event.GuildID, flag.Name, false)
+	case "default":
+		err = storage.ResetFeature(kvs, event.GuildID, flag.Name)
+	}
+	if err != nil {
+		log.Printf("[%s] Failed to set feature %s %s: %s", event.GuildID, flag.Name, state, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if state != "" {
+		log.Printf("[%s] <@%s> set feature %s to %s", event.GuildID, event.SenderID(), flag.Name, state)
+	}
+	line, err := describeFeature(kvs, event.GuildID, flag)
+	if err != nil {
+		log.Printf("[%s] Failed to get feature %s: %s", event.GuildID, flag.Name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	return command.Response{Response: response.Ephemeral(line)}
+}
+
+// describeCooldown says how long people have to wait between uses of the command, and who does.
+func describeCooldown(name string, cooldown storage.Cooldown) string {
+	who := "each user"
+	if cooldown.PerChannel {
+		who = "each channel"
+	}
+	return fmt.Sprintf("`/%s`: %d seconds between uses, for %s", name, cooldown.Seconds, who)
+}
+
+// SubCommandConfigCooldown sets how long people have to wait between uses of a command, or lists the cooldowns.
+func SubCommandConfigCooldown(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	name := presetPath(options.Find("command").String())
+	if name == "" {
+		cooldowns, err := storage.AllCooldowns(kvs, event.GuildID)
+		if err != nil {
+			log.Printf("[%s] Failed to list cooldowns: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		if len(cooldowns) == 0 {
+			return command.Response{Response: response.Ephemeral("There are no cooldowns. Every command can be used as often as people like.")}
+		}
+		names := make([]string, 0, len(cooldowns))
+		for name := range cooldowns {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		lines := make([]string, len(names))
+		for i, name := range names {
+			lines[i] = describeCooldown(name, cooldowns[name])
+		}
+		return command.Response{Response: response.Ephemeral(strings.Join(lines, "\n"))}
+	}
+	if strings.Contains(name, " ") {
+		return command.Response{Response: response.Ephemeral("Cooldowns are for a whole command, like `vote`, not for its subcommands.")}
+	}
+	if _, ok := command.Lookup(name); !ok {
+		return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "There is no `/%s` command.", name))}
+	}
+	if name == "config" {
+		return command.Response{Response: response.Ephemeral("A cooldown on `/config` would only get in your own way.")}
+	}
+
+	opt := options.Find("seconds")
+	if opt.Name == "" {
+		exist, cooldown, err := storage.GetCooldown(kvs, event.GuildID, name)
+		if err != nil {
+			log.Printf("[%s] Failed to get the cooldown for /%s: %s", event.GuildID, name, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		if !exist {
+			return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "`/%s` has no cooldown.", name))}
+		}
+		return command.Response{Response: response.Ephemeral(describeCooldown(name, cooldown))}
+	}
+	seconds, err := opt.IntValue()
+	if err != nil {
+		log.Printf("[%s] /config cooldown failed to get the seconds: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	cooldown := storage.Cooldown{Seconds: seconds, PerChannel: options.Find("per").String() == "channel"}
+	if err := storage.SetCooldown(kvs, event.GuildID, name, cooldown); err != nil {
+		log.Printf("[%s] Failed to store the cooldown for /%s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if seconds <= 0 {
+		log.Printf("[%s] <@%s> removed the cooldown for /%s", event.GuildID, event.SenderID(), name)
+		return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "`/%s` can be used as often as people like again.", name))}
+	}
+	log.Printf("[%s] <@%s> set the cooldown for /%s to %d seconds, per channel %t", event.GuildID, event.SenderID(), name, seconds, cooldown.PerChannel)
+	return command.Response{Response: response.Ephemeral("Cooldown set. " + describeCooldown(name, cooldown) + ".")}
+}
+
+// describeVisibility says who sees what the command answers, in the language.
+func describeVisibility(language discord.Language, name string, visibility string) string {
+	switch visibility {
+	case storage.VisibilityPublic:
+		return locale.T(language, "`/%s` answers so everyone can see it.", name)
+	case storage.VisibilityEphemeral:
+		return locale.T(language, "`/%s` answers so only whoever used it can see it.", name)
+	}
+	return locale.T(language, "`/%s` answers however it usually does.", name)
+}
+
+// SubCommandConfigVisibility sets whether everyone sees what a command answers, or lists what's been set.
+func SubCommandConfigVisibility(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	name := presetPath(options.Find("command").String())
+	visibility := options.Find("visibility").String()
+	if name == "" {
+		if visibility != "" {
+			return command.Response{Response: response.Ephemeral("Which command? Pick a `command` as well.")}
+		}
+		visibilities, err := storage.AllVisibilities(kvs, event.GuildID)
+		if err != nil {
+			log.Printf("[%s] Failed to list visibilities: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		if len(visibilities) == 0 {
+			return command.Response{Response: response.Ephemeral("Every command answers however it usually does.")}
+		}
+		names := make([]string, 0, len(visibilities))
+		for name := range visibilities {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		language := locale.ForGuild(kvs, event.GuildID)
+		lines := make([]string, len(names))
+		for i, name := range names {
+			lines[i] = describeVisibility(language, name, visibilities[name])
+		}
+		return command.Response{Response: response.Ephemeral(strings.Join(lines, "\n"))}
+	}
+	if strings.Contains(name, " ") {
+		return command.Response{Response: response.Ephemeral("This is for a whole command, like `faq`, not for its subcommands.")}
+	}
+	if _, ok := command.Lookup(name); !ok {
+		return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "There is no `/%s` command.", name))}
+	}
+
+	if visibility != "" {
+		stored := visibility
+		if visibility == "default" {
+			stored = ""
+		}
+		if err := storage.SetVisibility(kvs, event.GuildID, name, stored); err != nil {
+			log.Printf("[%s] Failed to store the visibility of /%s: %s", event.GuildID, name, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		log.Printf("[%s] <@%s> set the visibility of /%s to %s", event.GuildID, event.SenderID(), name, visibility)
+	}
+	current, err := storage.GetVisibility(kvs, event.GuildID, name)
+	if err != nil {
+		log.Printf("[%s] Failed to get the visibility of /%s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	return command.Response{Response: response.Ephemeral(describeVisibility(locale.ForGuild(kvs, event.GuildID), name, current))}
+}
+
+// SubCommandConfigLocale sets the language the bot speaks in the guild.
+func SubCommandConfigLocale(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	language := discord.Language(options.Find("language").String())
+	stored := string(language)
+	if language == locale.English {
+		stored = ""
+	}
+	if err := storage.SetLocale(kvs, event.GuildID, stored); err != nil {
+		log.Printf("[%s] Failed to store the locale: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> set the locale to %q", event.GuildID, event.SenderID(), language)
+	return command.Response{Response: response.Ephemeral(locale.T(language, "The bot now speaks %s here.", locale.Name(language)))}
+}
+
+// SubCommandConfigPresence opts the guild in or out of noting when people are online.
+func SubCommandConfigPresence(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	enabled, err := options.Find("enabled").BoolValue()
+	if err != nil {
+		log.Printf("[%s] /config presence failed to get the enabled value: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	if err := storage.SetPresenceTracking(kvs, event.GuildID, enabled); err != nil {
+		log.Printf("[%s] Failed to set presence tracking: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	forgetOnline(event.GuildID)
+	log.Printf("[%s] <@%s> set presence tracking to %t", event.GuildID, event.SenderID(), enabled)
+	if !enabled {
+		return command.Response{Response: response.Ephemeral("Okay, I no longer note when people are online, and have forgotten when everyone was.")}
+	}
+	if !state.HasIntents(gateway.IntentGuildPresences) {
+		return command.Response{Response: response.Ephemeral("Okay, but whoever runs the bot hasn't asked Discord for presence updates, so nothing will be noted until they do.")}
+	}
+	return command.Response{Response: response.Ephemeral("Okay, /seen now tells when people were last online, too, not just when they last said something.")}
+}
@@ -0,0 +1,112 @@
+package confirm
+
+import (
+	"komainu/interactions/command"
+	"komainu/interactions/component"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+// TTL is how long someone has to make up their mind.
+const TTL = 5 * time.Minute
+
+type Handler struct {
+	Code HandlerFunction
+}
+
+// HandlerFunction carries out the confirmed action, and returns what to tell whoever confirmed it.
+type HandlerFunction func(
+	ctx *command.Context,
+	confirmation storage.Confirmation,
+) string
+
+var confirmHandlers = map[string]Handler{}
+
+func init() {
+	component.Register("confirm", component.Handler{Code: componentConfirm})
+}
+
+// Register sets what function carries out the given action once it's confirmed.
+func Register(action string, handler Handler) {
+	confirmHandlers[action] = handler
+}
+
+// Ask stores the action, and responds with the question and Yes and No buttons. Nothing happens unless Yes is clicked in time.
+func Ask(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, action string, question string, data map[string]string) api.InteractionResponse {
+	if err := storage.ForgetExpiredConfirmations(kvs, event.GuildID); err != nil {
+		log.Printf("[%s] Failed to forget expired confirmations: %s", event.GuildID, err)
+	}
+	confirmation := storage.NewConfirmation(event.GuildID, event.SenderID(), action, TTL, data)
+	if err := confirmation.Store(kvs); err != nil {
+		log.Printf("[%s] Failed to store %s confirmation: %s", event.GuildID, action, err)
+		return response.Ephemeral("An error occured, and has been logged.")
+	}
+	return api.InteractionResponse{
+		Type: api.MessageInteractionWithSource,
+		Data: &api.InteractionResponseData{
+			Content: option.NewNullableString(question),
+			Flags:   api.EphemeralResponse,
+			Components: &discord.ContainerComponents{
+				&discord.ActionRowComponent{
+					&discord.ButtonComponent{
+						Style:    discord.DangerButtonStyle(),
+						CustomID: component.ID("confirm", "yes", confirmation.ID),
+						Label:    "Yes, do it",
+					},
+					&discord.ButtonComponent{
+						Style:    discord.SecondaryButtonStyle(),
+						CustomID: component.ID("confirm", "no", confirmation.ID),
+						Label:    "No",
+					},
+				},
+			},
+		},
+	}
+}
+
+// componentConfirm carries out the action if Yes was clicked, and replaces the question with how it went.
+func componentConfirm(ctx *command.Context, interaction discord.ComponentInteraction, params []string) api.InteractionResponse {
+	kvs, e := ctx.KVS, ctx.Event
+	if len(params) != 2 {
+		log.Printf("[%s] Confirmation button has a weird ID: %s", e.GuildID, interaction.ID())
+		return response.Ephemeral("I'm sorry, what? Something very weird happened.")
+	}
+	exist, confirmation, err := storage.TakeConfirmation(kvs, e.GuildID, params[1], e.SenderID())
+	if err != nil {
+		log.Printf("[%s] Failed to take confirmation %s: %s", e.GuildID, params[1], err)
+		return response.Ephemeral("An error occured, and has been logged.")
+	}
+	if !exist {
+		return answered("That was a while ago, so nothing was done. Try again if you still want to.")
+	}
+	if confirmation.UserID != e.SenderID() {
+		return response.Ephemeral("Only the one that asked can confirm this.")
+	}
+	if params[0] != "yes" {
+		return answered("Okay, nothing was done.")
+	}
+	handler, ok := confirmHandlers[confirmation.Action]
+	if !ok {
+		log.Printf("[%s] Got a confirmed %q action, but there is no registered handler!", e.GuildID, confirmation.Action)
+		return answered("Something odd happened. It has been logged.")
+	}
+	return answered(handler.Code(ctx, confirmation))
+}
+
+// answered replaces the question with the answer, and takes away the buttons.
+func answered(content string) api.InteractionResponse {
+	return api.InteractionResponse{
+		Type: api.UpdateMessage,
+		Data: &api.InteractionResponseData{
+			Content:    option.NewNullableString(content),
+			Components: &discord.ContainerComponents{},
+		},
+	}
+}
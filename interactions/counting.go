@@ -0,0 +1,203 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/message"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+var countingModule = module.Basic{
+	ModuleName: "counting",
+	CommandHandlers: map[string]command.Handler{
+		"counting": commandCountingObject,
+	},
+	Handlers: []module.EventHandler{
+		module.Message(message.Handler{Code: MessageCounting, NeedsContent: "Counting"}),
+	},
+}
+
+var commandCountingObject = command.Handler{
+	Description: "Set up a channel where everyone counts up, one number at a time",
+	Code:        CommandCounting,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "config",
+			Description: "Set up the counting game",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:   "channel",
+					Description:  "Where to count",
+					Required:     true,
+					ChannelTypes: []discord.ChannelType{discord.GuildText},
+				},
+				&discord.IntegerOption{
+					OptionName:  "milestone",
+					Description: "Celebrate every this many. Default is 100.",
+					Required:    false,
+					Min:         option.NewInt(10),
+					Max:         option.NewInt(100000),
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "disable",
+			Description: "Stop the counting game",
+			Options:     []discord.CommandOptionValue{},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "stats",
+			Description: "Show how far the counting has come, and the high score",
+			Options:     []discord.CommandOptionValue{},
+		},
+	},
+}
+
+// CommandCounting processes the /counting command, dispatching to the right subcommand.
+func CommandCounting(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /counting command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "config":
+		return SubCommandCountingConfig(kvs, event, cmd.Options[0].Options)
+	case "disable":
+		if err := storage.DisableCounting(kvs, event.GuildID); err != nil {
+			log.Printf("[%s] Failed to disable counting: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		log.Printf("[%s] <@%s> disabled counting", event.GuildID, event.SenderID())
+		return command.Response{Response: response.Ephemeral("Okay, no more counting. The count and high score are kept, in case you change your mind.")}
+	case "stats":
+		return SubCommandCountingStats(kvs, event)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandCountingConfig sets up the counting game.
+func SubCommandCountingConfig(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /counting config failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	config := storage.CountingConfig{ChannelID: discord.ChannelID(channelSnowflake)}
+	if opt := options.Find("milestone"); opt.Name != "" {
+		milestone, err := opt.IntValue()
+		if err != nil || milestone < 10 {
+			return command.Response{Response: response.Ephemeral("The milestone has to be a whole number, at least 10.")}
+		}
+		config.Milestone = int(milestone)
+	}
+	if err := storage.SetCountingConfig(kvs, event.GuildID, config); err != nil {
+		log.Printf("[%s] Failed to store counting config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	countingState, err := storage.GetCountingState(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /counting config failed to get the count: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> set counting to <#%s>, milestones every %d", event.GuildID, event.SenderID(), config.ChannelID, config.Every())
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Counting happens in %s, with a celebration every %d. The next number is **%d**.", config.ChannelID.Mention(), config.Every(), countingState.Count+1))}
+}
+
+// SubCommandCountingStats shows the count, the high score and how many times it was messed up.
+func SubCommandCountingStats(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	countingState, err := storage.GetCountingState(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /counting stats failed to get the count: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	lines := []string{fmt.Sprintf("The count is at **%d**.", countingState.Count)}
+	if countingState.HighScore > 0 {
+		lines = append(lines, fmt.Sprintf("The high score is **%d**, reached by %s <t:%d:R>.", countingState.HighScore, countingState.HighScoreBy.Mention(), countingState.HighScoreAt))
+	}
+	lines = append(lines, fmt.Sprintf("The count has been ruined %d times.", countingState.Resets))
+	return command.Response{Response: response.EphemeralShareable(strings.Join(lines, "\n"))}
+}
+
+// countingNumber reads the number at the start of the message, if there is one. Anything after it is just chatter.
+func countingNumber(content string) (int, bool) {
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	number, err := strconv.Atoi(strings.ReplaceAll(fields[0], ",", ""))
+	return number, err == nil
+}
+
+// MessageCounting counts the numbers posted in the counting channel, resets the count on mistakes, and celebrates milestones.
+func MessageCounting(state *state.State, kvs storage.KeyValueStore, event *gateway.MessageCreateEvent) {
+	if event.GuildID == discord.NullGuildID || event.Author.Bot {
+		return
+	}
+	exist, config, err := storage.GetCountingConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Counting failed to get config: %s", event.GuildID, err)
+		return
+	}
+	if !exist || event.ChannelID != config.ChannelID {
+		return
+	}
+	number, ok := countingNumber(event.Content)
+	if !ok {
+		return // Not a number, so it doesn't count either way.
+	}
+	result, err := storage.Count(kvs, event.GuildID, config, event.Author.ID, number)
+	if err != nil {
+		log.Printf("[%s] Counting failed to count %d from <@%s>: %s", event.GuildID, number, event.Author.ID, err)
+		return
+	}
+
+	if result.Correct {
+		if err := state.React(event.ChannelID, event.ID, discord.APIEmoji("✅")); err != nil {
+			log.Printf("[%s] Counting failed to react to %d: %s", event.GuildID, number, err)
+		}
+		if result.Milestone {
+			countingSay(state, event.ChannelID, fmt.Sprintf("🎉 **%d!** Well counted, everyone!", result.After.Count))
+		} else if result.NewHigh {
+			countingSay(state, event.ChannelID, fmt.Sprintf("🏆 That's a new high score! The old one was %d.", result.Before.HighScore))
+		}
+		return
+	}
+
+	if err := state.React(event.ChannelID, event.ID, discord.APIEmoji("❌")); err != nil {
+		log.Printf("[%s] Counting failed to react to %d: %s", event.GuildID, number, err)
+	}
+	if result.Before.Count == 0 {
+		countingSay(state, event.ChannelID, "Counting starts at **1**.")
+		return
+	}
+	reason := fmt.Sprintf("The next number was %d", result.Before.Count+1)
+	if number == result.Before.Count+1 {
+		reason = "Nobody gets to count twice in a row"
+	}
+	log.Printf("[%s] <@%s> ruined counting at %d", event.GuildID, event.Author.ID, result.Before.Count)
+	countingSay(state, event.ChannelID, fmt.Sprintf("💥 %s ruined it at **%d**! %s. Start over from **1**. The high score is %d.", event.Author.ID.Mention(), result.Before.Count, reason, result.After.HighScore))
+}
+
+// countingSay posts in the counting channel, without pinging anyone.
+func countingSay(state *state.State, channelID discord.ChannelID, content string) {
+	_, err := state.SendMessageComplex(channelID, api.SendMessageData{
+		Content:         content,
+		AllowedMentions: &api.AllowedMentions{Parse: []api.AllowedMentionType{}},
+	})
+	if err != nil {
+		log.Printf("Counting failed to post in <#%s>: %s", channelID, err)
+	}
+}
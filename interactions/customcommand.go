@@ -0,0 +1,251 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// customCommandLimit is how many commands Discord lets a single guild have.
+const customCommandLimit = 100
+
+// customCommandName is what Discord accepts as a command name, minus the non-latin letters it also allows.
+var customCommandName = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+var customCommandModule = module.Basic{
+	ModuleName: "customcommand",
+	CommandHandlers: map[string]command.Handler{
+		"customcommand": commandCustomCommandObject,
+	},
+	Handlers: []module.EventHandler{
+		module.GuildCommands(customCommandSource, CommandCustom),
+	},
+}
+
+var commandCustomCommandObject = command.Handler{
+	Description: "Make slash commands of your own",
+	Code:        CommandCustomCommand,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "add",
+			Description: "Add a command that replies with some text, or change one",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "name",
+					Description: "What the command is called, in lowercase",
+					Required:    true,
+				},
+				&discord.StringOption{
+					OptionName:  "response",
+					Description: "What it replies. {user}, {channel}, {server}, {random:a|b} and {arg1} are filled in.",
+					Required:    true,
+				},
+				&discord.StringOption{
+					OptionName:  "description",
+					Description: "What it says in the command list",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "remove",
+			Description: "Remove a command",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "name",
+					Description: "The command to remove",
+					Required:    true,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "list",
+			Description: "List the commands made here",
+			Options:     []discord.CommandOptionValue{},
+		},
+	},
+}
+
+// customCommandSource gives Discord the custom commands of the guild.
+func customCommandSource(kvs storage.KeyValueStore, guildID discord.GuildID) ([]api.CreateCommandData, error) {
+	customs, err := storage.GetCustomCommands(kvs, guildID)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]api.CreateCommandData, len(customs))
+	for i, custom := range customs {
+		options := []discord.CommandOption{}
+		for n := 1; n <= utility.TemplateArgs(custom.Response); n++ {
+			options = append(options, &discord.StringOption{
+				OptionName:  fmt.Sprintf("arg%d", n),
+				Description: fmt.Sprintf("Filled in where it says {arg%d}", n),
+				Required:    true,
+			})
+		}
+		data[i] = api.CreateCommandData{
+			Name:        custom.Name,
+			Description: custom.Description,
+			Options:     options,
+		}
+	}
+	return data, nil
+}
+
+// templateValues gets what the placeholders of a template are filled in with where it's used.
+func templateValues(state *state.State, guildID discord.GuildID, channelID discord.ChannelID, userID discord.UserID, args []string) utility.TemplateValues {
+	serverName := guildID.String()
+	if guild, err := state.Guild(guildID); err == nil {
+		serverName = guild.Name
+	}
+	return utility.TemplateValues{
+		User:    userID.Mention(),
+		Channel: channelID.Mention(),
+		Server:  serverName,
+		Args:    args,
+	}
+}
+
+// CommandCustomCommand processes the /customcommand command, dispatching to the right subcommand.
+func CommandCustomCommand(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /customcommand command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "add":
+		return SubCommandCustomCommandAdd(state, kvs, event, cmd.Options[0].Options)
+	case "remove":
+		return SubCommandCustomCommandRemove(state, kvs, event, cmd.Options[0].Options)
+	case "list":
+		return SubCommandCustomCommandList(kvs, event)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandCustomCommandAdd stores the command, and registers it with Discord.
+func SubCommandCustomCommandAdd(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	name := strings.ToLower(strings.TrimSpace(options.Find("name").String()))
+	if !customCommandName.MatchString(name) {
+		return command.Response{Response: response.Ephemeral("Command names can only have letters, numbers, `-` and `_` in them, and no spaces.")}
+	}
+	if _, builtin := command.Lookup(name); builtin {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is already a built-in `/%s` command.", name))}
+	}
+	description := strings.TrimSpace(options.Find("description").String())
+	if description == "" {
+		description = "A command made just for this server"
+	}
+	if err := utility.ValidateTemplate(options.Find("response").String()); err != nil {
+		return command.Response{Response: response.Ephemeral("That response won't work: " + err.Error())}
+	}
+	if len([]rune(description)) > 100 {
+		return command.Response{Response: response.Ephemeral("The description can't be longer than 100 characters.")}
+	}
+
+	exist, _, err := storage.GetCustomCommand(kvs, event.GuildID, name)
+	if err != nil {
+		log.Printf("[%s] /customcommand add failed to look up %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		customs, err := storage.GetCustomCommands(kvs, event.GuildID)
+		if err != nil {
+			log.Printf("[%s] /customcommand add failed to count custom commands: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		if len(customs) >= customCommandLimit {
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("Discord only allows %d commands per server, so one has to go before another can be added.", customCommandLimit))}
+		}
+	}
+
+	custom := storage.CustomCommand{
+		Name:        name,
+		Description: description,
+		Response:    options.Find("response").String(),
+		Creator:     event.SenderID(),
+		Created:     time.Now().Unix(),
+	}
+	if err := storage.SetCustomCommand(kvs, event.GuildID, custom); err != nil {
+		log.Printf("[%s] Failed to store custom command %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if err := command.RegisterGuildCommands(state, kvs, event.GuildID); err != nil {
+		log.Printf("[%s] Failed to register custom command %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("`/%s` is saved, but Discord wouldn't take it. The error has been logged, and I'll try again next time I start.", name))}
+	}
+	log.Printf("[%s] <@%s> added the custom command /%s", event.GuildID, event.SenderID(), name)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("`/%s` is ready! It might take a moment to show up in the command list.", name))}
+}
+
+// SubCommandCustomCommandRemove forgets the command, and takes it away from Discord.
+func SubCommandCustomCommandRemove(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	name := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(options.Find("name").String()), "/"))
+	exist, _, err := storage.GetCustomCommand(kvs, event.GuildID, name)
+	if err != nil {
+		log.Printf("[%s] /customcommand remove failed to look up %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no custom `/%s` command.", name))}
+	}
+	if err := storage.DeleteCustomCommand(kvs, event.GuildID, name); err != nil {
+		log.Printf("[%s] Failed to delete custom command %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if err := command.RegisterGuildCommands(state, kvs, event.GuildID); err != nil {
+		log.Printf("[%s] Failed to unregister custom command %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("`/%s` is forgotten, but Discord still lists it. The error has been logged, and it'll be gone next time I start.", name))}
+	}
+	log.Printf("[%s] <@%s> removed the custom command /%s", event.GuildID, event.SenderID(), name)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("`/%s` is gone.", name))}
+}
+
+// SubCommandCustomCommandList lists the custom commands of the guild.
+func SubCommandCustomCommandList(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	customs, err := storage.GetCustomCommands(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /customcommand list failed to get custom commands: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(customs) == 0 {
+		return command.Response{Response: response.Ephemeral("No custom commands have been made here.")}
+	}
+	lines := make([]string, len(customs))
+	for i, custom := range customs {
+		lines[i] = fmt.Sprintf("`/%s` by %s: %s", custom.Name, custom.Creator.Mention(), custom.Description)
+	}
+	return command.Response{Response: response.Ephemeral(strings.Join(lines, "\n"))}
+}
+
+// CommandCustom replies to any of the custom commands, as they are all handled the same way.
+func CommandCustom(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	exist, custom, err := storage.GetCustomCommand(kvs, event.GuildID, cmd.Name)
+	if err != nil {
+		log.Printf("[%s] Failed to get custom command %s: %s", event.GuildID, cmd.Name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral("That command doesn't exist here anymore.")}
+	}
+	args := []string{}
+	for n := 1; n <= utility.TemplateArgs(custom.Response); n++ {
+		args = append(args, cmd.Options.Find(fmt.Sprintf("arg%d", n)).String())
+	}
+	text := utility.RenderTemplate(custom.Response, templateValues(state, event.GuildID, event.ChannelID, event.SenderID(), args))
+	return command.Response{Response: response.MessageNoMention(text)}
+}
@@ -0,0 +1,515 @@
+package interactions
+
+import (
+	"bytes"
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/status"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+var debugModule = module.Basic{
+	ModuleName: "debug",
+	CommandHandlers: map[string]command.Handler{
+		"debug": commandDebugObject,
+	},
+}
+
+// debugGuildOption is which guild to poke around in, as each /debug kvs subcommand has it.
+func debugGuildOption() *discord.StringOption {
+	return &discord.StringOption{
+		OptionName:  "guild",
+		Description: "The ID of the guild. Blank for this one, or your own in private.",
+		Required:    false,
+	}
+}
+
+func debugKeyOptions() []discord.CommandOptionValue {
+	return []discord.CommandOptionValue{
+		&discord.StringOption{
+			OptionName:  "collection",
+			Description: "The collection, like seen or votes",
+			Required:    true,
+		},
+		&discord.StringOption{
+			OptionName:  "key",
+			Description: "The key in the collection",
+			Required:    true,
+		},
+	}
+}
+
+var commandDebugObject = command.Handler{
+	Description: "Poke around in the bot's insides. Only for whoever runs the bot.",
+	Code:        CommandDebug,
+	DMs:         true,
+	Owner:       true,
+	Options: []discord.CommandOption{
+		&discord.SubcommandGroupOption{
+			OptionName:  "kvs",
+			Description: "Look at or change what's stored",
+			Subcommands: []*discord.SubcommandOption{
+				{
+					OptionName:  "get",
+					Description: "Show what's stored under a key",
+					Options:     append(debugKeyOptions(), debugGuildOption()),
+				},
+				{
+					OptionName:  "set",
+					Description: "Store something under a key, replacing whatever was there",
+					Options: append(debugKeyOptions(),
+						&discord.StringOption{
+							OptionName:  "value",
+							Description: "What to store",
+							Required:    true,
+						},
+						&discord.StringOption{
+							OptionName:  "type",
+							Description: "What kind of value it is. Default is text.",
+							Required:    false,
+							Choices: []discord.StringChoice{
+								{Name: "Text", Value: "string"},
+								{Name: "Whole number", Value: "int"},
+								{Name: "Number", Value: "float"},
+								{Name: "Yes or no", Value: "bool"},
+							},
+						},
+						debugGuildOption(),
+					),
+				},
+				{
+					OptionName:  "delete",
+					Description: "Forget what's stored under a key",
+					Options:     append(debugKeyOptions(), debugGuildOption()),
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "dump",
+			Description: "Get everything in a collection as a file",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "collection",
+					Description: "The collection, like seen or votes",
+					Required:    true,
+				},
+				debugGuildOption(),
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "guilds",
+			Description: "List the guilds the bot is in, on this shard",
+		},
+		&discord.SubcommandOption{
+			OptionName:  "status",
+			Description: "Show something else as the bot's status for a while, like maintenance coming up",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "text",
+					Description: "The status, like Watching the clock. Blank to go back to the usual ones.",
+					Required:    false,
+				},
+				&discord.StringOption{
+					OptionName:  "for",
+					Description: "How long to show it, like 2h or 1d. Blank for until it's cleared.",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "resync-commands",
+			Description: "Register all the commands with Discord again",
+		},
+		&discord.SubcommandOption{
+			OptionName:  "storage",
+			Description: "Show how much is stored for every guild, by collection",
+		},
+		&discord.SubcommandOption{
+			OptionName:  "compact",
+			Description: "Compact storage now, giving back the space freed by deleting things",
+		},
+		&discord.SubcommandOption{
+			OptionName:  "modules",
+			Description: "List the modules, whether they are on, and what they have",
+		},
+	},
+}
+
+// CommandDebug processes the /debug command, dispatching to the right subcommand.
+// Only the owners get this far, as the command is marked Owner.
+func CommandDebug(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /debug command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	if cmd.Options[0].Type == discord.SubcommandOptionType {
+		switch cmd.Options[0].Name {
+		case "dump":
+			return SubCommandDebugDump(kvs, event, cmd.Options[0].Options)
+		case "guilds":
+			return SubCommandDebugGuilds(state, event)
+		case "status":
+			return SubCommandDebugStatus(kvs, event, cmd.Options[0].Options)
+		case "resync-commands":
+			return SubCommandDebugResync(state, kvs, event)
+		case "storage":
+			return SubCommandDebugStorage(state, kvs, event)
+		case "compact":
+			return SubCommandDebugCompact(state, kvs, event)
+		case "modules":
+			return SubCommandDebugModules()
+		default:
+			return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+		}
+	}
+	group := cmd.Options[0]
+	if len(group.Options) != 1 {
+		log.Printf("[%s] /debug %s command structure is somehow not a single subcommand. Wat.\n", event.GuildID, group.Name)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	sub := group.Options[0]
+	switch group.Name + " " + sub.Name {
+	case "kvs get":
+		return SubCommandDebugKVSGet(kvs, event, sub.Options)
+	case "kvs set":
+		return SubCommandDebugKVSSet(kvs, event, sub.Options)
+	case "kvs delete":
+		return SubCommandDebugKVSDelete(kvs, event, sub.Options)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// debugGuild gets the guild from the guild option, or where the command was used if it's blank.
+func debugGuild(event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) (discord.GuildID, error) {
+	raw := strings.TrimSpace(options.Find("guild").String())
+	if raw == "" {
+		return command.Scope(event), nil
+	}
+	snowflake, err := discord.ParseSnowflake(raw)
+	if err != nil {
+		return discord.NullGuildID, fmt.Errorf("%q isn't an ID", raw)
+	}
+	return discord.GuildID(snowflake), nil
+}
+
+// debugValue gets what's stored under the key, as something that can be shown. Values are stored as gob, which
+// doesn't say what type it was, so this tries the usual suspects in turn. Anything else is just described.
+func debugValue(kvs storage.KeyValueStore, guildID discord.GuildID, collection string, key string) (bool, string, error) {
+	candidates := []any{new(string), new(int64), new(uint64), new(float64), new(bool), new([]string), new(map[string]string)}
+	var lastErr error
+	for _, out := range candidates {
+		exist, err := kvs.Get(guildID, collection, key, out)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if !exist {
+			return false, "", nil
+		}
+		switch value := out.(type) {
+		case *string:
+			return true, strconv.Quote(*value), nil
+		case *int64:
+			return true, strconv.FormatInt(*value, 10), nil
+		case *uint64:
+			return true, strconv.FormatUint(*value, 10), nil
+		case *float64:
+			return true, strconv.FormatFloat(*value, 'g', -1, 64), nil
+		case *bool:
+			return true, strconv.FormatBool(*value), nil
+		case *[]string:
+			return true, fmt.Sprintf("%q", *value), nil
+		case *map[string]string:
+			return true, fmt.Sprintf("%q", *value), nil
+		}
+	}
+	// Gob says what it got when it doesn't fit, which is better than nothing.
+	if _, remote, found := strings.Cut(lastErr.Error(), "received remote type "); found {
+		return true, fmt.Sprintf("(a %s, which I can't show)", remote), nil
+	}
+	return false, "", lastErr
+}
+
+// SubCommandDebugKVSGet shows what's stored under a key.
+func SubCommandDebugKVSGet(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	guildID, err := debugGuild(event, options)
+	if err != nil {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("That guild won't work: %s", err))}
+	}
+	collection, key := options.Find("collection").String(), options.Find("key").String()
+	exist, value, err := debugValue(kvs, guildID, collection, key)
+	if err != nil {
+		log.Printf("[%s] /debug kvs get failed to get %s/%s in %s: %s", event.GuildID, collection, key, guildID, err)
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("Storage said no: %s", err))}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There's nothing under `%s/%s` in %s.", collection, key, guildID))}
+	}
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("`%s/%s` in %s is:\n```\n%s\n```", collection, key, guildID, value))}
+}
+
+// SubCommandDebugKVSSet stores a value under a key, as whatever type it was said to be.
+func SubCommandDebugKVSSet(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	guildID, err := debugGuild(event, options)
+	if err != nil {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("That guild won't work: %s", err))}
+	}
+	collection, key := options.Find("collection").String(), options.Find("key").String()
+	raw := options.Find("value").String()
+	var value any = raw
+	switch options.Find("type").String() {
+	case "int":
+		value, err = strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	case "float":
+		value, err = strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	case "bool":
+		value, err = strconv.ParseBool(strings.TrimSpace(raw))
+	}
+	if err != nil {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("That's not the kind of value you said it was: %s", err))}
+	}
+	if err := kvs.Set(guildID, collection, key, value); err != nil {
+		log.Printf("[%s] /debug kvs set failed to set %s/%s in %s: %s", event.GuildID, collection, key, guildID, err)
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("Storage said no: %s", err))}
+	}
+	log.Printf("[%s] <@%s> used /debug to set %s/%s in %s to %v", event.GuildID, event.SenderID(), collection, key, guildID, value)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("`%s/%s` in %s is now `%v`.", collection, key, guildID, value))}
+}
+
+// SubCommandDebugKVSDelete forgets what's stored under a key.
+func SubCommandDebugKVSDelete(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	guildID, err := debugGuild(event, options)
+	if err != nil {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("That guild won't work: %s", err))}
+	}
+	collection, key := options.Find("collection").String(), options.Find("key").String()
+	if err := kvs.Delete(guildID, collection, key); err != nil {
+		log.Printf("[%s] /debug kvs delete failed to delete %s/%s in %s: %s", event.GuildID, collection, key, guildID, err)
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("Storage said no: %s", err))}
+	}
+	log.Printf("[%s] <@%s> used /debug to delete %s/%s in %s", event.GuildID, event.SenderID(), collection, key, guildID)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("`%s/%s` in %s is gone, if it was ever there.", collection, key, guildID))}
+}
+
+// SubCommandDebugDump attaches everything in a collection as a file, one key on each line.
+func SubCommandDebugDump(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	guildID, err := debugGuild(event, options)
+	if err != nil {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("That guild won't work: %s", err))}
+	}
+	collection := options.Find("collection").String()
+	keys, err := kvs.Keys(guildID, collection)
+	if err != nil {
+		log.Printf("[%s] /debug dump failed to list %s in %s: %s", event.GuildID, collection, guildID, err)
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("Storage said no: %s", err))}
+	}
+	if len(keys) == 0 {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There's nothing in `%s` in %s.", collection, guildID))}
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, key := range keys {
+		_, value, err := debugValue(kvs, guildID, collection, key)
+		if err != nil {
+			value = fmt.Sprintf("(failed: %s)", err)
+		}
+		fmt.Fprintf(&buf, "%s = %s\n", key, value)
+	}
+	log.Printf("[%s] <@%s> used /debug to dump %s in %s", event.GuildID, event.SenderID(), collection, guildID)
+	return command.Response{Response: response.EphemeralAttachFile(
+		fmt.Sprintf("`%s` in %s has %d keys.", collection, guildID, len(keys)),
+		fmt.Sprintf("dump-%s-%s.txt", collection, guildID),
+		&buf,
+	)}
+}
+
+// SubCommandDebugGuilds lists the guilds on the shard the command came in on.
+func SubCommandDebugGuilds(state *state.State, event *gateway.InteractionCreateEvent) command.Response {
+	guilds, err := state.Guilds()
+	if err != nil {
+		log.Printf("[%s] /debug guilds failed to get the guilds: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	sort.Slice(guilds, func(i, j int) bool {
+		return guilds[i].ID < guilds[j].ID
+	})
+	var buf bytes.Buffer
+	for _, guild := range guilds {
+		fmt.Fprintf(&buf, "%s %s\n", guild.ID, guild.Name)
+	}
+	message := fmt.Sprintf("In %d guilds on this shard.", len(guilds))
+	if buf.Len() > 1500 {
+		return command.Response{Response: response.EphemeralAttachFile(message, "guilds.txt", &buf)}
+	}
+	return command.Response{Response: response.Ephemeral(message + "\n```\n" + buf.String() + "```")}
+}
+
+// SubCommandDebugStatus shows something else as the status on every shard, for a while or until cleared.
+func SubCommandDebugStatus(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	text := strings.TrimSpace(options.Find("text").String())
+	if text == "" {
+		if err := storage.ClearStatusOverride(kvs); err != nil {
+			log.Printf("[%s] /debug status failed to clear the override: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		status.Refresh()
+		log.Printf("[%s] <@%s> used /debug to clear the status", event.GuildID, event.SenderID())
+		return command.Response{Response: response.Ephemeral("Back to the usual statuses.")}
+	}
+	if len([]rune(text)) > 128 {
+		return command.Response{Response: response.Ephemeral("That's too long for a status. Discord won't show more than 128 characters.")}
+	}
+
+	override := storage.StatusOverride{Text: text, SetBy: event.SenderID()}
+	until := "until it's cleared"
+	if forText := options.Find("for").String(); forText != "" {
+		duration, err := utility.ParseDuration(forText)
+		if err != nil || duration <= 0 {
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("I don't understand %q as an amount of time. Try something like 2h or 1d.", forText))}
+		}
+		override.Until = time.Now().Add(duration).Unix()
+		until = fmt.Sprintf("until <t:%d:t>, <t:%d:R>", override.Until, override.Until)
+	}
+	if err := storage.SetStatusOverride(kvs, override); err != nil {
+		log.Printf("[%s] /debug status failed to set the override: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	status.Refresh()
+	log.Printf("[%s] <@%s> used /debug to set the status to %q", event.GuildID, event.SenderID(), text)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("The status is now %q, %s.", text, until))}
+}
+
+// SubCommandDebugResync registers all the commands with Discord again, for when they've gotten out of step.
+func SubCommandDebugResync(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	log.Printf("[%s] <@%s> used /debug to register the commands again", event.GuildID, event.SenderID())
+	return command.DeferredProgress(state, event, true, func(progress func(string)) string {
+		if err := command.RegisterCommands(state); err != nil {
+			log.Printf("[%s] /debug resync-commands failed to register the commands: %s", event.GuildID, err)
+			return fmt.Sprintf("Discord wouldn't take the commands: %s", err)
+		}
+		guilds, err := state.Guilds()
+		if err != nil {
+			log.Printf("[%s] /debug resync-commands failed to get the guilds: %s", event.GuildID, err)
+			return "The commands are registered, but I couldn't get the guilds to register their own commands in. The error has been logged."
+		}
+		failed := 0
+		for i, guild := range guilds {
+			progress(fmt.Sprintf("The commands are registered. Now doing the guild commands, %d of %d guilds...", i, len(guilds)))
+			if err := command.RegisterGuildCommands(state, kvs, guild.ID); err != nil {
+				log.Printf("[%s] /debug resync-commands failed to register the guild commands: %s", guild.ID, err)
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Sprintf("The commands are registered, but the guild commands failed in %d of %d guilds. The errors have been logged.", failed, len(guilds))
+		}
+		return fmt.Sprintf("The commands are registered, and the guild commands in all %d guilds on this shard.", len(guilds))
+	})
+}
+
+// SubCommandDebugStorage attaches how much is stored for every guild, biggest first, with each collection in it.
+func SubCommandDebugStorage(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	guilds, err := kvs.Guilds()
+	if err != nil {
+		log.Printf("[%s] /debug storage failed to list the guilds in storage: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("Storage said no: %s", err))}
+	}
+	type guildUsage struct {
+		guildID     discord.GuildID
+		total       storage.CollectionUsage
+		collections map[string]storage.CollectionUsage
+	}
+	usages := make([]guildUsage, 0, len(guilds))
+	total := storage.CollectionUsage{}
+	for _, guildID := range guilds {
+		collections, err := kvs.Usage(guildID)
+		if err != nil {
+			log.Printf("[%s] /debug storage failed to get the usage of %s: %s", event.GuildID, guildID, err)
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("Storage said no: %s", err))}
+		}
+		usage := guildUsage{guildID: guildID, collections: collections}
+		for _, used := range collections {
+			usage.total.Keys += used.Keys
+			usage.total.Bytes += used.Bytes
+		}
+		total.Keys += usage.total.Keys
+		total.Bytes += usage.total.Bytes
+		usages = append(usages, usage)
+	}
+	sort.SliceStable(usages, func(i, j int) bool {
+		return usages[i].total.Bytes > usages[j].total.Bytes
+	})
+
+	var buf bytes.Buffer
+	for _, usage := range usages {
+		name := "(not on this shard, or private)"
+		if guild, err := state.Cabinet.Guild(usage.guildID); err == nil {
+			name = guild.Name
+		}
+		fmt.Fprintf(&buf, "%s %s: %.1f KB in %d entries\n", usage.guildID, name, float64(usage.total.Bytes)/1024, usage.total.Keys)
+		collections := make([]string, 0, len(usage.collections))
+		for collection := range usage.collections {
+			collections = append(collections, collection)
+		}
+		sort.SliceStable(collections, func(i, j int) bool {
+			return usage.collections[collections[i]].Bytes > usage.collections[collections[j]].Bytes
+		})
+		for _, collection := range collections {
+			used := usage.collections[collection]
+			fmt.Fprintf(&buf, "    %s: %.1f KB in %d entries\n", collection, float64(used.Bytes)/1024, used.Keys)
+		}
+	}
+	message := fmt.Sprintf("%.1f KB in %d entries, for %d guilds. That's what's stored, the file itself is bigger.", float64(total.Bytes)/1024, total.Keys, len(usages))
+	return command.Response{Response: response.EphemeralAttachFile(message, "storage.txt", &buf)}
+}
+
+// SubCommandDebugCompact compacts storage right away, instead of waiting for it to happen on its own.
+func SubCommandDebugCompact(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	log.Printf("[%s] <@%s> used /debug to compact storage", event.GuildID, event.SenderID())
+	return command.DeferredProgress(state, event, true, func(progress func(string)) string {
+		progress("Compacting storage. Everything else waits until it's done...")
+		start := time.Now()
+		before, after, err := kvs.Compact()
+		if err != nil {
+			log.Printf("[%s] /debug compact failed: %s", event.GuildID, err)
+			return fmt.Sprintf("Compacting didn't work out: %s", err)
+		}
+		log.Printf("Compacted storage from %d KB to %d KB", before/1024, after/1024)
+		return fmt.Sprintf("Compacted storage from %.1f MB to %.1f MB in %s.", float64(before)/1024/1024, float64(after)/1024/1024, time.Since(start).Round(time.Millisecond))
+	})
+}
+
+// SubCommandDebugModules lists the registered modules. Turning them on or off is done in the configuration file, and
+// takes a restart, as commands are only registered with Discord when starting up.
+func SubCommandDebugModules() command.Response {
+	var buf bytes.Buffer
+	on := 0
+	for _, m := range module.All() {
+		status := "off"
+		if module.Loaded(m.Name()) {
+			status = "on"
+			on++
+		}
+		fmt.Fprintf(&buf, "%-14s %-3s %s\n", m.Name(), status, module.Describe(m))
+	}
+	message := fmt.Sprintf("%d modules, %d of them on.", len(module.All()), on)
+	if buf.Len() > 1500 {
+		return command.Response{Response: response.EphemeralAttachFile(message, "modules.txt", &buf)}
+	}
+	return command.Response{Response: response.Ephemeral(message + "\n```\n" + buf.String() + "```")}
+}
@@ -0,0 +1,289 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/component"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/interactions/timer"
+	"komainu/storage"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+const (
+	dropLength          = 10 * time.Minute // How long a drop stays claimable.
+	dropMinimumReaction = 250              // Milliseconds. Anything quicker is a bot, or very lucky guessing.
+	dropMaximumJitter   = 5 * time.Second  // The button becomes clickable somewhere between one second and this long after it's posted.
+)
+
+// dropLock makes sure two people can't grab the last prize at the same time.
+var dropLock sync.Mutex
+
+var dropModule = module.Basic{
+	ModuleName: "drop",
+	CommandHandlers: map[string]command.Handler{
+		"drop": commandDropObject,
+	},
+	ComponentHandlers: map[string]component.Handler{
+		"drop": component.Handler{Code: ComponentDrop},
+	},
+	Handlers: []module.EventHandler{
+		module.Timer("drop", timer.Handler{Code: TimerDrop}),
+		module.Timer("dropend", timer.Handler{Code: TimerDropEnd}),
+	},
+}
+
+var commandDropObject = command.Handler{
+	Description: "Drop a prize at a random time, for the quickest to claim",
+	Code:        CommandDrop,
+	Options: []discord.CommandOption{
+		&discord.StringOption{
+			OptionName:  "prize",
+			Description: "What can be won",
+			Required:    true,
+		},
+		&discord.IntegerOption{
+			OptionName:  "within",
+			Description: "The drop happens some time within this many minutes. Default is 60.",
+			Required:    false,
+			Min:         option.NewInt(1),
+			Max:         option.NewInt(10080),
+		},
+		&discord.IntegerOption{
+			OptionName:  "winners",
+			Description: "How many can claim it. Default is 1.",
+			Required:    false,
+			Min:         option.NewInt(1),
+			Max:         option.NewInt(100),
+		},
+		&discord.ChannelOption{
+			OptionName:  "channel",
+			Description: "Where to drop it. Defaults to this channel.",
+			Required:    false,
+		},
+	},
+}
+
+// CommandDrop processes the /drop command.
+func CommandDrop(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	drop := storage.Drop{
+		GuildID:   event.GuildID,
+		ChannelID: event.ChannelID,
+		Host:      event.SenderID(),
+		Prize:     strings.TrimSpace(cmd.Options.Find("prize").String()),
+		Winners:   1,
+	}
+	within := int64(60)
+	var err error
+	if cmd.Options.Find("within").Name != "" {
+		if within, err = cmd.Options.Find("within").IntValue(); err != nil {
+			log.Printf("[%s] /drop failed to get within: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+	}
+	if cmd.Options.Find("winners").Name != "" {
+		winners, err := cmd.Options.Find("winners").IntValue()
+		if err != nil {
+			log.Printf("[%s] /drop failed to get winners: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		drop.Winners = int(winners)
+	}
+	if cmd.Options.Find("channel").Name != "" {
+		channelSnowflake, err := cmd.Options.Find("channel").SnowflakeValue()
+		if err != nil {
+			log.Printf("[%s] /drop failed to get channel snowflake: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+		}
+		drop.ChannelID = discord.ChannelID(channelSnowflake)
+	}
+	if drop.Prize == "" {
+		return command.Response{Response: response.Ephemeral("A drop with no prize? That's just mean.")}
+	}
+
+	if err := storage.NewDrop(kvs, &drop); err != nil {
+		log.Printf("[%s] /drop failed to store the drop: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	when := time.Now().Add(time.Duration(rand.Int63n(within*60)) * time.Second)
+	_, err = timer.Schedule(kvs, event.GuildID, "drop", when, map[string]string{"drop": strconv.FormatInt(drop.ID, 10)})
+	if err != nil {
+		log.Printf("[%s] /drop failed to schedule drop #%d: %s", event.GuildID, drop.ID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> set up drop #%d of %q in <#%s>, due <t:%d:f>", event.GuildID, event.SenderID(), drop.ID, drop.Prize, drop.ChannelID, when.Unix())
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Drop #%d will show up in %s some time within the next %d minutes. Even you don't get to know when!", drop.ID, drop.ChannelID.Mention(), within))}
+}
+
+func dropButton(drop *storage.Drop, label string, disabled bool) *discord.ContainerComponents {
+	return &discord.ContainerComponents{
+		&discord.ActionRowComponent{
+			&discord.ButtonComponent{
+				Style:    discord.SuccessButtonStyle(),
+				CustomID: component.ID("drop", "claim", strconv.FormatInt(drop.ID, 10)),
+				Label:    label,
+				Disabled: disabled,
+			},
+		},
+	}
+}
+
+func dropContent(drop *storage.Drop) string {
+	if drop.Winners == 1 {
+		return fmt.Sprintf("🎁 **Drop!** The first person to click the button wins **%s**!", drop.Prize)
+	}
+	return fmt.Sprintf("🎁 **Drop!** The first %d people to click the button win **%s**!", drop.Winners, drop.Prize)
+}
+
+// TimerDrop posts the drop, and makes the button clickable after a random little while, so bots can't be ready for it.
+func TimerDrop(state *state.State, kvs storage.KeyValueStore, t storage.Timer) {
+	id, err := strconv.ParseInt(t.Data["drop"], 10, 64)
+	if err != nil {
+		log.Printf("[%s] Drop timer has a weird drop number: %s", t.GuildID, err)
+		return
+	}
+	exist, drop, err := storage.GetDrop(kvs, t.GuildID, id)
+	if err != nil || !exist {
+		log.Printf("[%s] Drop timer could not get drop #%d (exists: %t): %v", t.GuildID, id, exist, err)
+		return
+	}
+	msg, err := state.SendMessageComplex(drop.ChannelID, api.SendMessageData{
+		Content:    dropContent(&drop),
+		Components: *dropButton(&drop, "Get ready…", true),
+	})
+	if err != nil {
+		log.Printf("[%s] Failed to post drop #%d in <#%s>: %s", t.GuildID, id, drop.ChannelID, err)
+		return
+	}
+	drop.MessageID = msg.ID
+	if err := drop.Store(kvs); err != nil {
+		log.Printf("[%s] Failed to store drop #%d after posting it: %s", t.GuildID, id, err)
+		return
+	}
+
+	jitter := time.Second + time.Duration(rand.Int63n(int64(dropMaximumJitter-time.Second)))
+	time.AfterFunc(jitter, func() {
+		dropLock.Lock()
+		defer dropLock.Unlock()
+		drop.Opened = time.Now().UnixMilli()
+		if err := drop.Store(kvs); err != nil {
+			log.Printf("[%s] Failed to open drop #%d: %s", drop.GuildID, drop.ID, err)
+			return
+		}
+		_, err := state.EditMessageComplex(drop.ChannelID, drop.MessageID, api.EditMessageData{
+			Components: dropButton(&drop, "Claim!", false),
+		})
+		if err != nil {
+			log.Printf("[%s] Failed to enable the button of drop #%d: %s", drop.GuildID, drop.ID, err)
+		}
+		_, err = timer.Schedule(kvs, drop.GuildID, "dropend", time.Now().Add(dropLength), map[string]string{"drop": strconv.FormatInt(drop.ID, 10)})
+		if err != nil {
+			log.Printf("[%s] Failed to schedule the end of drop #%d: %s", drop.GuildID, drop.ID, err)
+		}
+	})
+}
+
+// TimerDropEnd closes a drop that wasn't fully claimed in time.
+func TimerDropEnd(state *state.State, kvs storage.KeyValueStore, t storage.Timer) {
+	id, err := strconv.ParseInt(t.Data["drop"], 10, 64)
+	if err != nil {
+		log.Printf("[%s] Drop end timer has a weird drop number: %s", t.GuildID, err)
+		return
+	}
+	dropLock.Lock()
+	defer dropLock.Unlock()
+	exist, drop, err := storage.GetDrop(kvs, t.GuildID, id)
+	if err != nil || !exist {
+		log.Printf("[%s] Drop end timer could not get drop #%d (exists: %t): %v", t.GuildID, id, exist, err)
+		return
+	}
+	if !drop.Closed {
+		closeDrop(state, kvs, &drop)
+	}
+}
+
+// closeDrop stops any more claims, and shows who won. Call with dropLock held.
+func closeDrop(state *state.State, kvs storage.KeyValueStore, drop *storage.Drop) {
+	drop.Closed = true
+	if err := drop.Store(kvs); err != nil {
+		log.Printf("[%s] Failed to store drop #%d as closed: %s", drop.GuildID, drop.ID, err)
+	}
+	content := fmt.Sprintf("🎁 **Drop over!** Nobody claimed **%s** in time.", drop.Prize)
+	if len(drop.Claims) > 0 {
+		winners := make([]string, len(drop.Claims))
+		for i, claim := range drop.Claims {
+			winners[i] = fmt.Sprintf("%s (%.3fs)", claim.UserID.Mention(), float64(claim.ReactionTime)/1000)
+		}
+		content = fmt.Sprintf("🎁 **Drop over!** **%s** went to %s", drop.Prize, strings.Join(winners, ", "))
+	}
+	_, err := state.EditMessageComplex(drop.ChannelID, drop.MessageID, api.EditMessageData{
+		Content:         option.NewNullableString(content),
+		Components:      &discord.ContainerComponents{},
+		AllowedMentions: &api.AllowedMentions{Parse: []api.AllowedMentionType{}},
+	})
+	if err != nil {
+		log.Printf("[%s] Failed to show the result of drop #%d: %s", drop.GuildID, drop.ID, err)
+	}
+	log.Printf("[%s] Drop #%d of %q closed with %d of %d claimed: %v", drop.GuildID, drop.ID, drop.Prize, len(drop.Claims), drop.Winners, drop.Claims)
+}
+
+// ComponentDrop handles someone clicking to claim a drop.
+func ComponentDrop(ctx *command.Context, interaction discord.ComponentInteraction, params []string) api.InteractionResponse {
+	state, kvs, e := ctx.State, ctx.KVS, ctx.Event
+	clicked := time.Now().UnixMilli()
+	// drop/claim/id
+	if len(params) != 2 {
+		log.Printf("[%s] Malformed drop component ID: %s", e.GuildID, interaction.ID())
+		return response.Ephemeral("That button is broken somehow. It has been logged.")
+	}
+	id, err := strconv.ParseInt(params[1], 10, 64)
+	if err != nil {
+		log.Printf("[%s] Malformed drop number in component: %s", e.GuildID, err)
+		return response.Ephemeral("That button is broken somehow. It has been logged.")
+	}
+
+	dropLock.Lock()
+	defer dropLock.Unlock()
+	exist, drop, err := storage.GetDrop(kvs, e.GuildID, id)
+	if err != nil {
+		log.Printf("[%s] Error while trying to fetch drop #%d for a claim: %s", e.GuildID, id, err)
+		return response.Ephemeral("There was an issue processing your claim. It has been logged.")
+	}
+	userID := e.SenderID()
+	switch {
+	case !exist || drop.Closed || drop.Full():
+		return response.Ephemeral("Too late! It's all gone.")
+	case drop.Opened == 0:
+		return response.Ephemeral("Not yet!")
+	case drop.Claimed(userID):
+		return response.Ephemeral("You already got one!")
+	}
+	reactionTime := clicked - drop.Opened
+	if reactionTime < dropMinimumReaction {
+		log.Printf("[%s] <@%s> tried to claim drop #%d after only %dms", e.GuildID, userID, id, reactionTime)
+		return response.Ephemeral("Whoa, too quick! That looks like a bot, so it doesn't count.")
+	}
+
+	drop.Claims = append(drop.Claims, storage.DropClaim{UserID: userID, ReactionTime: reactionTime})
+	if err := drop.Store(kvs); err != nil {
+		log.Printf("[%s] Error storing claim on drop #%d: %s", e.GuildID, id, err)
+		return response.Ephemeral("There was an issue storing your claim. It has been logged.")
+	}
+	log.Printf("[%s] <@%s> claimed drop #%d in %dms", e.GuildID, userID, id, reactionTime)
+	if drop.Full() {
+		closeDrop(state, kvs, &drop)
+	}
+	return response.Ephemeral(fmt.Sprintf("🎉 You got **%s**, with a reaction time of %.3f seconds!", drop.Prize, float64(reactionTime)/1000))
+}
@@ -0,0 +1,221 @@
+package interactions
+
+import (
+	"komainu/interactions/command"
+	"komainu/interactions/component"
+	"komainu/interactions/delete"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+const eventTimeLayout = "2006-01-02 15:04"
+
+var eventModule = module.Basic{
+	ModuleName: "event",
+	CommandHandlers: map[string]command.Handler{
+		"event": commandEventObject,
+	},
+	ComponentHandlers: map[string]component.Handler{
+		"event": component.Handler{Code: ComponentEvent},
+	},
+	Handlers: []module.EventHandler{
+		module.Delete(delete.Handler{Code: DeleteEvent}),
+	},
+}
+
+var commandEventObject = command.Handler{
+	Description: "Manage events people can sign up for",
+	Code:        CommandEvent,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "create",
+			Description: "Post an event people can RSVP to",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "title",
+					Description: "What is happening?",
+					Required:    true,
+				},
+				&discord.StringOption{
+					OptionName:  "start",
+					Description: "When does it start? YYYY-MM-DD HH:MM, in UTC",
+					Required:    true,
+				},
+				&discord.StringOption{
+					OptionName:  "description",
+					Description: "Any details people should know about",
+					Required:    false,
+				},
+				&discord.IntegerOption{
+					OptionName:  "remind",
+					Description: "How many minutes before the start to ping everyone that's coming. Default is 60.",
+					Required:    false,
+					Min:         option.NewInt(0),
+					Max:         option.NewInt(10080),
+				},
+			},
+		},
+	},
+}
+
+// DeleteEvent will delete the appropriate event when the message it's in is deleted.
+func DeleteEvent(state *state.State, kvs storage.KeyValueStore, e *gateway.MessageDeleteEvent) {
+	if e.GuildID == discord.NullGuildID {
+		return
+	}
+	err := kvs.Delete(e.GuildID, "events", e.ID)
+	if err != nil {
+		log.Printf("[%s] Encountered an error removing event from KVS after message deletion: %s\n", e.GuildID, err)
+	}
+}
+
+// CommandEvent processes the /event command and it's subcommands
+func CommandEvent(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	if cmd.Options == nil || len(cmd.Options) != 1 {
+		log.Printf("[%s] /event command structure is somehow nil or not a single element. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("Invalid command structure.")}
+	}
+	switch cmd.Options[0].Name {
+	case "create":
+		return SubCommandEventCreate(kvs, event, cmd.Options[0].Options)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandEventCreate processes a subcommand to post a new event.
+func SubCommandEventCreate(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	start, err := time.ParseInLocation(eventTimeLayout, strings.TrimSpace(options.Find("start").String()), time.UTC)
+	if err != nil {
+		return command.Response{Response: response.Ephemeral("I couldn't make sense of that start time. Please use YYYY-MM-DD HH:MM, like 2022-12-24 18:00")}
+	}
+	if start.Before(time.Now()) {
+		return command.Response{Response: response.Ephemeral("That start time has already passed!")}
+	}
+
+	title := options.Find("title").String()
+	if runes := []rune(title); len(runes) > 256 {
+		title = string(runes[:256]) // Discord won't take a longer embed title.
+	}
+
+	remind := int64(60)
+	if opt := options.Find("remind"); opt.Name != "" {
+		remind, err = opt.IntValue()
+		if err != nil {
+			log.Printf("[%s] Failed to get int value for /event remind: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+	}
+
+	newEvent := storage.Event{
+		GuildID:      event.GuildID,
+		ChannelID:    discord.NullChannelID, // This is added in the callback later.
+		MessageID:    discord.NullMessageID, // This one, too!
+		Creator:      event.SenderID(),
+		Title:        title,
+		Description:  options.Find("description").String(),
+		StartTime:    start.Unix(),
+		RemindBefore: remind * 60,
+		RSVP:         map[discord.UserID]string{},
+	}
+
+	return command.Response{
+		Response: api.InteractionResponse{
+			Type: api.MessageInteractionWithSource,
+			Data: &api.InteractionResponseData{
+				Embeds:     &[]discord.Embed{newEvent.Embed()},
+				Components: makeEventButtons(),
+			},
+		},
+		Callback: func(message *discord.Message) {
+			newEvent.MessageID = message.ID
+			newEvent.ChannelID = message.ChannelID
+			if err := newEvent.Store(kvs); err != nil {
+				log.Printf("[%s] Failed to save event after adding MessageID (%s) and ChannelID (%s)", newEvent.GuildID, message.ID, message.ChannelID)
+			}
+		},
+	}
+}
+
+func makeEventButtons() *discord.ContainerComponents {
+	return &discord.ContainerComponents{
+		&discord.ActionRowComponent{
+			&discord.ButtonComponent{
+				Style:    discord.SuccessButtonStyle(),
+				CustomID: component.ID("event", storage.RSVPGoing),
+				Label:    "Going",
+			},
+			&discord.ButtonComponent{
+				Style:    discord.SecondaryButtonStyle(),
+				CustomID: component.ID("event", storage.RSVPMaybe),
+				Label:    "Maybe",
+			},
+			&discord.ButtonComponent{
+				Style:    discord.DangerButtonStyle(),
+				CustomID: component.ID("event", storage.RSVPNo),
+				Label:    "Can't",
+			},
+		},
+	}
+}
+
+// ComponentEvent handles the RSVP buttons on an event.
+func ComponentEvent(ctx *command.Context, interaction discord.ComponentInteraction, params []string) api.InteractionResponse {
+	kvs, e := ctx.KVS, ctx.Event
+	unlock, err := storage.LockEvent(kvs, e.GuildID, e.Message.ID)
+	if err != nil {
+		log.Printf("[%s] Failed to lock the event for an RSVP: %s", e.GuildID, err)
+		return response.Ephemeral("Lots of people are responding right now. Please try again in a moment.")
+	}
+	defer unlock()
+	exist, rsvpEvent, err := storage.GetEvent(kvs, e.GuildID, e.Message.ID)
+	if err != nil {
+		log.Printf("[%s] Error while trying to fetch the event for an RSVP: %s", e.GuildID, err)
+		return response.Ephemeral("There was an issue processing your RSVP. It has been logged.")
+	}
+	if !exist {
+		return response.Ephemeral("I'm sorry, but I can't find the event you're responding to. Maybe it already started?")
+	}
+	if rsvpEvent.StartTime <= time.Now().Unix() {
+		return response.Ephemeral("I'm sorry, that event has already started!")
+	}
+
+	if rsvpEvent.RSVP == nil {
+		rsvpEvent.RSVP = map[discord.UserID]string{} // gob doesn't bother storing empty maps.
+	}
+	rsvp := ""
+	if len(params) == 1 {
+		rsvp = params[0]
+	}
+	switch rsvp {
+	case storage.RSVPGoing, storage.RSVPMaybe, storage.RSVPNo:
+		rsvpEvent.RSVP[e.SenderID()] = rsvp
+	default:
+		log.Printf("[%s] Unknown RSVP %q submitted", e.GuildID, rsvp)
+		return response.Ephemeral("That was kind of an odd response. What happened?")
+	}
+
+	if err := rsvpEvent.Store(kvs); err != nil {
+		log.Printf("[%s] Failed to store RSVP: %s", e.GuildID, err)
+		return response.Ephemeral("There was an error storing your RSVP. It has been logged.")
+	}
+
+	return api.InteractionResponse{
+		Type: api.UpdateMessage,
+		Data: &api.InteractionResponseData{
+			Embeds:     &[]discord.Embed{rsvpEvent.Embed()},
+			Components: makeEventButtons(),
+		},
+	}
+}
@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"komainu/interactions/autocomplete"
 	"komainu/interactions/command"
+	"komainu/interactions/component"
+	"komainu/interactions/confirm"
+	"komainu/interactions/message"
 	"komainu/interactions/modal"
+	"komainu/interactions/module"
 	"komainu/interactions/response"
 	"komainu/storage"
 	"komainu/utility"
@@ -19,22 +23,52 @@ import (
 	"github.com/diamondburned/arikawa/v3/utils/json/option"
 )
 
-func init() {
-	command.Register("faq", commandFaqObject)
-	command.Register("faqset", commandFaqSetObject)
-	modal.Register("faqadd", modal.Handler{Code: FAQAddModalHandler})
-	autocomplete.Register("faq", autocomplete.Handler{Code: FaqAutocomplete})
+// faqSuggestions is how many similar topics are suggested when the one asked for doesn't exist.
+const faqSuggestions = 5
+
+var faqModule = module.Basic{
+	ModuleName: "faq",
+	CommandHandlers: map[string]command.Handler{
+		"faq":    commandFaqObject,
+		"faqset": commandFaqSetObject,
+	},
+	ComponentHandlers: map[string]component.Handler{
+		"faqshow":   component.Handler{Code: ComponentFaqShow},
+		"faqbrowse": component.Handler{Code: ComponentFaqBrowse},
+	},
+	Handlers: []module.EventHandler{
+		module.Modal("faqadd", modal.Handler{Code: FAQAddModalHandler}),
+		module.Autocomplete("faq", autocomplete.Handler{Code: FaqAutocomplete}),
+		module.Confirm("faqremove", confirm.Handler{Code: ConfirmFaqRemove}),
+		module.Message(message.Handler{Code: MessageFAQAuto, NeedsContent: "The FAQ auto-responder"}),
+	},
 }
 
 var commandFaqObject = command.Handler{
 	Description: "Look up a FAQ topic",
 	Code:        CommandFaq,
 	Options: []discord.CommandOption{
-		&discord.StringOption{
-			OptionName:   "topic",
-			Description:  "The name of the topic you wish to recall",
-			Required:     true,
-			Autocomplete: true,
+		&discord.SubcommandOption{
+			OptionName:  "show",
+			Description: "Show a topic, if you know what it's called",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:   "topic",
+					Description:  "The name of the topic you wish to recall",
+					Required:     true,
+					Autocomplete: true,
+				},
+				&discord.BooleanOption{
+					OptionName:  "private",
+					Description: "Only show it to you, with a button to share it if you like",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "browse",
+			Description: "Look through the topics by category",
+			Options:     []discord.CommandOptionValue{},
 		},
 	},
 }
@@ -70,48 +104,169 @@ var commandFaqSetObject = command.Handler{
 			Description: "List the known topics in the FAQ",
 			Options:     []discord.CommandOptionValue{},
 		},
+		&discord.SubcommandOption{
+			OptionName:  "undo",
+			Description: "Put a topic back the way it was before the last change",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "topic",
+					Description: "The topic to undo. Default is whatever was changed last.",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "history",
+			Description: "Show who changed a topic, and when",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "topic",
+					Description: "The topic in question",
+					Required:    true,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "category",
+			Description: "Put a topic in a category, for /faq browse",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "topic",
+					Description: "The topic in question",
+					Required:    true,
+				},
+				&discord.StringOption{
+					OptionName:  "category",
+					Description: "The category to put it in. Leave blank to take it out of the one it's in.",
+					Required:    false,
+				},
+			},
+		},
+		faqAutoGroup,
 	},
 }
 
-// CommandFaq processes a command to retrieve a FAQ item.
-func CommandFaq(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, cmd *discord.CommandInteraction) command.Response {
-	if cmd.Options == nil || len(cmd.Options) != 1 {
-		log.Printf("[%s] /faq command structure is somehow nil or not a single element. Wat.\n", event.GuildID)
+// CommandFaq processes the /faq command, dispatching to the right subcommand.
+func CommandFaq(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /faq command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "show":
+		return SubCommandFaqShow(state, kvs, event, cmd.Options[0].Options)
+	case "browse":
+		return SubCommandFaqBrowse(kvs, event)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandFaqShow processes a command to retrieve a FAQ item.
+func SubCommandFaqShow(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	if options.Find("topic").Name == "" {
+		log.Printf("[%s] /faq show command structure is somehow missing the topic. Wat.\n", event.GuildID)
 		return command.Response{Response: response.Ephemeral("Invalid command structure."), Callback: nil}
 	}
-	topic := strings.ToLower(cmd.Options[0].String())
+	topic := strings.ToLower(options.Find("topic").String())
+	private, _ := options.Find("private").BoolValue()
+	return command.Response{Response: faqShow(state, kvs, event, topic, private), Callback: nil}
+}
+
+// faqShow shows the topic, or suggests some others if there's no such topic.
+func faqShow(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, topic string, private bool) api.InteractionResponse {
 	value := ""
 	exists, err := kvs.Get(event.GuildID, "faq", topic, &value)
 	if err != nil {
 		log.Printf("[%s] /faq failed to GetString the topic %s: %s", event.GuildID, topic, err)
-		return command.Response{Response: response.Ephemeral("An error occured, and has been logged."), Callback: nil}
+		return response.Ephemeral("An error occured, and has been logged.")
 	}
 	if !exists {
-		return command.Response{Response: response.Ephemeral(fmt.Sprintf("Sorry, I've never heard of %s", topic)), Callback: nil}
+		return faqDidYouMean(kvs, event, topic, private)
+	}
+	value = utility.RenderTemplate(value, templateValues(state, event.GuildID, event.ChannelID, event.SenderID(), nil))
+	if private {
+		return response.EphemeralShareable(value)
+	}
+	return response.MessageNoMention(value)
+}
+
+// faqDidYouMean says there's no such topic, with buttons for any that look like it.
+func faqDidYouMean(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, topic string, private bool) api.InteractionResponse {
+	notFound := response.Ephemeral(fmt.Sprintf("Sorry, I've never heard of %s", topic))
+	topics, err := kvs.Keys(event.GuildID, "faq")
+	if err != nil {
+		log.Printf("[%s] /faq failed to get the topics to suggest some: %s", event.GuildID, err)
+		return notFound
+	}
+	closest := utility.ClosestMatches(topic, topics, faqSuggestions)
+	privately := ""
+	if private {
+		privately = "private"
+	}
+	buttons := discord.ActionRowComponent{}
+	for _, suggestion := range closest {
+		id := component.ID("faqshow", suggestion, privately)
+		if len(id) > 100 {
+			continue // Too long for Discord to have as a button, so that one has to be typed out.
+		}
+		buttons = append(buttons, &discord.ButtonComponent{
+			Style:    discord.SecondaryButtonStyle(),
+			CustomID: id,
+			Label:    utility.Substring(utility.UcFirst(suggestion), 0, 80),
+		})
+	}
+	if len(buttons) == 0 {
+		return notFound
 	}
-	return command.Response{Response: response.MessageNoMention(value), Callback: nil}
+	notFound.Data.Content = option.NewNullableString(fmt.Sprintf("Sorry, I've never heard of %s. Did you mean one of these?", topic))
+	notFound.Data.Components = &discord.ContainerComponents{&buttons}
+	return notFound
+}
+
+// ComponentFaqShow shows the topic picked from the suggestions, when the one asked for doesn't exist.
+func ComponentFaqShow(ctx *command.Context, interaction discord.ComponentInteraction, params []string) api.InteractionResponse {
+	state, kvs, e := ctx.State, ctx.KVS, ctx.Event
+	// faqshow/topic/private
+	if len(params) != 2 {
+		log.Printf("[%s] Malformed faqshow component ID: %s", e.GuildID, interaction.ID())
+		return response.Ephemeral("That button is broken somehow. It has been logged.")
+	}
+	return faqShow(state, kvs, e, params[0], params[1] == "private")
 }
 
 // CommandFaqSet processes commands to faff about in the topics list
-func CommandFaqSet(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, cmd *discord.CommandInteraction) command.Response {
+func CommandFaqSet(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
 	if cmd.Options == nil || len(cmd.Options) != 1 {
 		log.Printf("[%s] /faqset command structure is somehow nil or not a single element. Wat.\n", event.GuildID)
 		return command.Response{Response: response.Message("I'm sorry, what? Something very weird happened."), Callback: nil}
 	}
+	if cmd.Options[0].Type == discord.SubcommandGroupOptionType {
+		return SubCommandFaqSetAuto(kvs, event, cmd.Options[0])
+	}
 	switch cmd.Options[0].Name {
 	case "list":
 		return command.Response{Response: SubCommandFaqList(kvs, event.GuildID), Callback: nil}
 	case "add":
-		return command.Response{Response: SubCommandFaqAdd(kvs, event.GuildID, event.SenderID(), cmd.Options[0].Options), Callback: nil}
+		return command.Response{Response: SubCommandFaqAdd(kvs, event, cmd.Options[0].Options), Callback: nil}
 	case "remove":
-		return command.Response{Response: SubCommandFaqRemove(kvs, event.GuildID, cmd.Options[0].Options), Callback: nil}
+		return command.Response{Response: SubCommandFaqRemove(kvs, event, cmd.Options[0].Options), Callback: nil}
+	case "undo":
+		return command.Response{Response: SubCommandFaqUndo(kvs, event, cmd.Options[0].Options), Callback: nil}
+	case "history":
+		return command.Response{Response: SubCommandFaqHistory(kvs, event.GuildID, cmd.Options[0].Options), Callback: nil}
+	case "category":
+		return command.Response{Response: SubCommandFaqCategory(kvs, event, cmd.Options[0].Options), Callback: nil}
 	default:
 		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!"), Callback: nil}
 	}
 }
 
 // SubCommandFaqAdd processes a subcommand to store a FAQ item.
-func SubCommandFaqAdd(kvs storage.KeyValueStore, guildID discord.GuildID, userID discord.UserID, options []discord.CommandInteractionOption) api.InteractionResponse {
+func SubCommandFaqAdd(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options []discord.CommandInteractionOption) api.InteractionResponse {
+	guildID := event.GuildID
 	if options == nil || len(options) != 1 {
 		log.Printf("[%s] /faqset add command structure is somehow not exactly one element. Wat.\n", guildID)
 		return response.Ephemeral("Invalid command structure.")
@@ -129,9 +284,9 @@ func SubCommandFaqAdd(kvs storage.KeyValueStore, guildID discord.GuildID, userID
 	}
 
 	return modal.Respond(
-		userID, guildID, "faqadd", addOrUpdate,
+		kvs, event, "faqadd", addOrUpdate, map[string]string{"topic": key},
 		discord.TextInputComponent{
-			CustomID:     discord.ComponentID(key),
+			CustomID:     discord.ComponentID("text"),
 			Label:        key,
 			Value:        option.NewNullableString(value),
 			Style:        discord.TextInputParagraphStyle,
@@ -141,7 +296,8 @@ func SubCommandFaqAdd(kvs storage.KeyValueStore, guildID discord.GuildID, userID
 }
 
 // SubCommandFaqRemove processes a command to remove a FAQ item.
-func SubCommandFaqRemove(kvs storage.KeyValueStore, guildID discord.GuildID, options []discord.CommandInteractionOption) api.InteractionResponse {
+func SubCommandFaqRemove(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options []discord.CommandInteractionOption) api.InteractionResponse {
+	guildID := event.GuildID
 	if options == nil || len(options) != 1 {
 		log.Printf("[%s] /faqset remove command structure is somehow nil or not one element. Wat.\n", guildID)
 		return response.Ephemeral("Invalid command structure.")
@@ -156,12 +312,99 @@ func SubCommandFaqRemove(kvs storage.KeyValueStore, guildID discord.GuildID, opt
 	if !exists {
 		return response.Ephemeral(fmt.Sprintf("Sorry, I've never heard of %s", topic))
 	}
-	err = kvs.Delete(guildID, "faq", topic)
+	return confirm.Ask(kvs, event, "faqremove", fmt.Sprintf("Really forget %s? It can't be undone.\n> %s", topic, utility.Substring(value, 0, 1500)), map[string]string{"topic": topic})
+}
+
+// ConfirmFaqRemove forgets a FAQ topic, once the removal is confirmed.
+func ConfirmFaqRemove(ctx *command.Context, confirmation storage.Confirmation) string {
+	kvs, event := ctx.KVS, ctx.Event
+	topic := confirmation.Data["topic"]
+	if err := storage.RemoveFAQTopic(kvs, event.GuildID, topic, event.SenderID()); err != nil {
+		log.Printf("[%s] /faqset remove failed to Delete the topic %s: %s", event.GuildID, topic, err)
+		return "An error occured, and has been logged."
+	}
+	log.Printf("[%s] <@%s> removed FAQ topic %s", event.GuildID, event.SenderID(), topic)
+	return fmt.Sprintf("Forgot %s. Use `/faqset undo` if that was a mistake.", topic)
+}
+
+// SubCommandFaqUndo puts a topic back the way it was before the last change.
+func SubCommandFaqUndo(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) api.InteractionResponse {
+	topic := strings.ToLower(strings.TrimSpace(options.Find("topic").String()))
+	undone, change, err := storage.UndoFAQChange(kvs, event.GuildID, topic)
 	if err != nil {
-		log.Printf("[%s] /faqset remove failed to Delete the topic %s: %s", guildID, topic, err)
+		log.Printf("[%s] /faqset undo failed for %q: %s", event.GuildID, topic, err)
 		return response.Ephemeral("An error occured, and has been logged.")
 	}
-	return response.MessageNoMention(fmt.Sprintf("Forgot %s: %s", topic, value))
+	if !undone {
+		if topic == "" {
+			return response.Ephemeral("There are no changes to undo.")
+		}
+		return response.Ephemeral(fmt.Sprintf("There are no changes to %s to undo.", topic))
+	}
+	log.Printf("[%s] <@%s> undid a change to FAQ topic %s", event.GuildID, event.SenderID(), change.Topic)
+	if !change.Existed {
+		return response.MessageNoMention(fmt.Sprintf("Undone! %s was new, so it's gone again.", change.Topic))
+	}
+	return response.MessageNoMention(fmt.Sprintf("Undone! %s is back the way it was <t:%d:R>: %s", change.Topic, change.When, change.Before))
+}
+
+// SubCommandFaqHistory shows who changed a topic, and when.
+func SubCommandFaqHistory(kvs storage.KeyValueStore, guildID discord.GuildID, options discord.CommandInteractionOptions) api.InteractionResponse {
+	topic := strings.ToLower(strings.TrimSpace(options.Find("topic").String()))
+	history, err := storage.GetFAQHistory(kvs, guildID, topic)
+	if err != nil {
+		log.Printf("[%s] /faqset history failed for %q: %s", guildID, topic, err)
+		return response.Ephemeral("An error occured, and has been logged.")
+	}
+	if len(history) == 0 {
+		return response.Ephemeral(fmt.Sprintf("There are no changes to %s that I remember.", topic))
+	}
+	lines := []string{fmt.Sprintf("The last %d changes to %s, newest first:", len(history), topic)}
+	for i := len(history) - 1; i >= 0; i-- {
+		change := history[i]
+		by := "the REST API"
+		if change.By.IsValid() {
+			by = change.By.Mention()
+		}
+		what := "changed"
+		if change.Removed {
+			what = "removed"
+		} else if !change.Existed {
+			what = "added"
+		}
+		line := fmt.Sprintf("<t:%d:f> %s by %s", change.When, what, by)
+		if !change.Removed {
+			line += ": " + utility.Substring(strings.ReplaceAll(change.After, "\n", " "), 0, 80)
+		}
+		lines = append(lines, line)
+	}
+	return response.Ephemeral(utility.Substring(strings.Join(lines, "\n"), 0, 2000))
+}
+
+// SubCommandFaqCategory puts a topic in a category, or takes it out of the one it's in.
+func SubCommandFaqCategory(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) api.InteractionResponse {
+	topic := strings.ToLower(strings.TrimSpace(options.Find("topic").String()))
+	category := strings.ToLower(strings.Join(strings.Fields(options.Find("category").String()), " "))
+	if len([]rune(category)) > 50 {
+		return response.Ephemeral("Category names can't be longer than 50 characters.")
+	}
+	exist, _, err := storage.GetFAQTopic(kvs, event.GuildID, topic)
+	if err != nil {
+		log.Printf("[%s] /faqset category failed to look up %q: %s", event.GuildID, topic, err)
+		return response.Ephemeral("An error occured, and has been logged.")
+	}
+	if !exist {
+		return response.Ephemeral(fmt.Sprintf("Sorry, I've never heard of %s", topic))
+	}
+	if err := storage.SetFAQCategory(kvs, event.GuildID, topic, category); err != nil {
+		log.Printf("[%s] /faqset category failed to store the category of %q: %s", event.GuildID, topic, err)
+		return response.Ephemeral("An error occured, and has been logged.")
+	}
+	log.Printf("[%s] <@%s> put FAQ topic %s in the category %q", event.GuildID, event.SenderID(), topic, category)
+	if category == "" {
+		return response.Ephemeral(fmt.Sprintf("%s isn't in a category any more.", utility.UcFirst(topic)))
+	}
+	return response.Ephemeral(fmt.Sprintf("%s is in the category %s now.", utility.UcFirst(topic), category))
 }
 
 // SubCommandFaqList processes a subcommand to list all FAQ items.
@@ -183,15 +426,19 @@ func SubCommandFaqList(kvs storage.KeyValueStore, guildID discord.GuildID) api.I
 	return response.Ephemeral("I'm sad to say, there are no known topics.")
 }
 
-func FAQAddModalHandler(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, interaction *discord.ModalInteraction) command.Response {
-	data := modal.DecodeModalResponse(interaction.Components)
-	for key, value := range data {
-		err := kvs.Set(event.GuildID, "faq", key, value)
+func FAQAddModalHandler(ctx *command.Context, interaction *discord.ModalInteraction, session map[string]string) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	key := session["topic"]
+	value, ok := modal.DecodeModalResponse(interaction.Components)["text"]
+	if ok && key != "" {
+		if err := utility.ValidateTemplate(value); err != nil {
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("I didn't save %q, as it won't work: %s", key, err)), Callback: nil}
+		}
+		err := storage.SetFAQTopic(kvs, event.GuildID, key, value, event.SenderID())
 		if err != nil {
 			log.Printf("[%s] Error storing FAQ item %q: %s", event.GuildID, key, err)
 			return command.Response{Response: response.Ephemeral("There was an error saving that, but it has been logged!"), Callback: nil}
 		}
-		// Early return because we only expect one, but ranging over the one is the simplest code. *shrug*
 		return command.Response{Response: response.MessageNoMention(fmt.Sprintf("Neat! I learned all about %q", key)), Callback: nil}
 	}
 	log.Printf("[%s] There was no data when trying to sote FAQ data?!  %#v", event.GuildID, interaction.Components)
@@ -0,0 +1,292 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+// faqAutoExpire is how long a reply from the FAQ auto-responder stays up, with the reply style.
+const faqAutoExpire = 5 * time.Minute
+
+// faqAutoGroup is /faqset auto, for setting up the FAQ auto-responder.
+var faqAutoGroup = &discord.SubcommandGroupOption{
+	OptionName:  "auto",
+	Description: "Answer questions with FAQ topics without being asked",
+	Subcommands: []*discord.SubcommandOption{
+		{
+			OptionName:  "trigger",
+			Description: "Answer with a topic when someone says a phrase, or stop doing that",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "phrase",
+					Description: "What to look for in messages, like \"how do I join\"",
+					Required:    true,
+				},
+				&discord.StringOption{
+					OptionName:  "topic",
+					Description: "The topic to answer with. Leave blank to remove the trigger.",
+					Required:    false,
+				},
+			},
+		},
+		{
+			OptionName:  "channel",
+			Description: "Turn the auto-responder on or off in a channel",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:  "channel",
+					Description: "The channel to toggle",
+					Required:    true,
+				},
+			},
+		},
+		{
+			OptionName:  "config",
+			Description: "Change how it answers. Anything left blank stays as it is.",
+			Options: []discord.CommandOptionValue{
+				&discord.IntegerOption{
+					OptionName:  "cooldown",
+					Description: fmt.Sprintf("Minutes before the same topic is answered again in a channel. Default is %d.", storage.FAQAutoCooldownDefault),
+					Required:    false,
+					Min:         option.NewInt(1),
+					Max:         option.NewInt(1440),
+				},
+				&discord.StringOption{
+					OptionName:  "style",
+					Description: "How to answer",
+					Required:    false,
+					Choices: []discord.StringChoice{
+						{Name: "Reply, and delete it again after a few minutes", Value: "reply"},
+						{Name: "Reply, and leave it", Value: "keep"},
+						{Name: "Start a thread on the message, and answer there", Value: "thread"},
+					},
+				},
+			},
+		},
+		{
+			OptionName:  "list",
+			Description: "Show how the auto-responder is set up",
+			Options:     []discord.CommandOptionValue{},
+		},
+	},
+}
+
+// faqAutoCooldown is when each topic was last answered in each channel, so the same answer isn't spammed.
+var faqAutoCooldown = map[discord.ChannelID]map[string]time.Time{}
+var faqAutoCooldownLock sync.Mutex
+
+// SubCommandFaqSetAuto processes /faqset auto, dispatching to the right subcommand.
+func SubCommandFaqSetAuto(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, group discord.CommandInteractionOption) command.Response {
+	if len(group.Options) != 1 {
+		log.Printf("[%s] /faqset %s command structure is somehow not a single subcommand. Wat.\n", event.GuildID, group.Name)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	config, err := storage.GetFAQAutoConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to get FAQ auto-responder config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	sub := group.Options[0]
+	options := sub.Options
+
+	switch sub.Name {
+	case "trigger":
+		phrase := strings.ToLower(strings.Join(strings.Fields(options.Find("phrase").String()), " "))
+		if len([]rune(phrase)) < 3 {
+			return command.Response{Response: response.Ephemeral("The phrase has to be at least 3 characters, or it'll go off all the time.")}
+		}
+		topic := strings.ToLower(strings.TrimSpace(options.Find("topic").String()))
+		if topic == "" {
+			if _, ok := config.Triggers[phrase]; !ok {
+				return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no trigger for %q.", phrase))}
+			}
+			delete(config.Triggers, phrase)
+			break
+		}
+		exist, _, err := storage.GetFAQTopic(kvs, event.GuildID, topic)
+		if err != nil {
+			log.Printf("[%s] /faqset auto trigger failed to look up %q: %s", event.GuildID, topic, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		if !exist {
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("Sorry, I've never heard of %s", topic))}
+		}
+		config.Triggers[phrase] = topic
+	case "channel":
+		channelSnowflake, err := options.Find("channel").SnowflakeValue()
+		if err != nil {
+			log.Printf("[%s] /faqset auto channel failed to get channel snowflake: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+		}
+		channelID := discord.ChannelID(channelSnowflake)
+		if config.Channels[channelID] {
+			delete(config.Channels, channelID)
+		} else {
+			config.Channels[channelID] = true
+		}
+	case "config":
+		if options.Find("cooldown").Name != "" {
+			config.CooldownMinutes, err = options.Find("cooldown").IntValue()
+			if err != nil {
+				log.Printf("[%s] /faqset auto config failed to get cooldown: %s", event.GuildID, err)
+				return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+			}
+		}
+		if style := options.Find("style").String(); style != "" {
+			config.Style = style
+		}
+	case "list":
+		return command.Response{Response: response.Ephemeral(describeFAQAutoConfig(config))}
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+
+	if err := storage.SetFAQAutoConfig(kvs, event.GuildID, config); err != nil {
+		log.Printf("[%s] Failed to store FAQ auto-responder config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> changed the FAQ auto-responder config", event.GuildID, event.SenderID())
+	return command.Response{Response: response.Ephemeral(describeFAQAutoConfig(config))}
+}
+
+// describeFAQAutoConfig says how the FAQ auto-responder is set up.
+func describeFAQAutoConfig(config storage.FAQAutoConfig) string {
+	var sb strings.Builder
+	if len(config.Channels) == 0 {
+		sb.WriteString("The auto-responder isn't on in any channels.\n")
+	} else {
+		sb.WriteString("The auto-responder is on in:")
+		for channelID := range config.Channels {
+			sb.WriteString(" " + channelID.Mention())
+		}
+		sb.WriteString("\n")
+	}
+	switch config.Style {
+	case "keep":
+		sb.WriteString("It replies to the message.\n")
+	case "thread":
+		sb.WriteString("It starts a thread on the message, and answers there.\n")
+	default:
+		fmt.Fprintf(&sb, "It replies to the message, and deletes the reply again after %d minutes.\n", int(faqAutoExpire.Minutes()))
+	}
+	fmt.Fprintf(&sb, "The same topic is answered at most once every %d minutes in each channel.\n", config.Cooldown())
+	if len(config.Triggers) == 0 {
+		sb.WriteString("There are no triggers. Add some with `/faqset auto trigger`.")
+		return sb.String()
+	}
+	phrases := make([]string, 0, len(config.Triggers))
+	for phrase := range config.Triggers {
+		phrases = append(phrases, phrase)
+	}
+	sort.Strings(phrases)
+	sb.WriteString("Triggers:")
+	for _, phrase := range phrases {
+		fmt.Fprintf(&sb, "\n- %q answers with %s", phrase, config.Triggers[phrase])
+	}
+	return utility.Substring(sb.String(), 0, 2000)
+}
+
+// faqAutoMatch finds the trigger phrase in the message, going with the longest if there are several, as that's the most
+// specific one. Blank if there are none.
+func faqAutoMatch(config storage.FAQAutoConfig, content string) string {
+	content = strings.ToLower(strings.Join(strings.Fields(content), " "))
+	found := ""
+	for phrase := range config.Triggers {
+		if len(phrase) > len(found) && strings.Contains(content, phrase) {
+			found = phrase
+		}
+	}
+	return found
+}
+
+// faqAutoReady checks if the topic can be answered in the channel, and if it can, starts the cooldown.
+func faqAutoReady(channelID discord.ChannelID, topic string, cooldown time.Duration) bool {
+	faqAutoCooldownLock.Lock()
+	defer faqAutoCooldownLock.Unlock()
+	if faqAutoCooldown[channelID] == nil {
+		faqAutoCooldown[channelID] = map[string]time.Time{}
+	}
+	if last, ok := faqAutoCooldown[channelID][topic]; ok && time.Since(last) < cooldown {
+		return false
+	}
+	faqAutoCooldown[channelID][topic] = time.Now()
+	return true
+}
+
+// MessageFAQAuto answers messages that say a trigger phrase with the matching FAQ topic, in the channels it's on in.
+func MessageFAQAuto(state *state.State, kvs storage.KeyValueStore, event *gateway.MessageCreateEvent) {
+	if event.GuildID == discord.NullGuildID || event.Author.Bot || event.Content == "" {
+		return
+	}
+	config, err := storage.GetFAQAutoConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] FAQ auto-responder failed to get config: %s", event.GuildID, err)
+		return
+	}
+	if !config.Channels[event.ChannelID] {
+		return
+	}
+	phrase := faqAutoMatch(config, event.Content)
+	if phrase == "" {
+		return
+	}
+	topic := config.Triggers[phrase]
+	if !faqAutoReady(event.ChannelID, topic, time.Duration(config.Cooldown())*time.Minute) {
+		return
+	}
+	exist, text, err := storage.GetFAQTopic(kvs, event.GuildID, topic)
+	if err != nil {
+		log.Printf("[%s] FAQ auto-responder failed to get the topic %q: %s", event.GuildID, topic, err)
+		return
+	}
+	if !exist {
+		return // Removed since the trigger was made.
+	}
+	text = utility.RenderTemplate(text, templateValues(state, event.GuildID, event.ChannelID, event.Author.ID, nil))
+	data := api.SendMessageData{
+		Content:         utility.Substring(text, 0, 2000),
+		AllowedMentions: &api.AllowedMentions{Parse: []api.AllowedMentionType{}, RepliedUser: option.False},
+	}
+
+	if config.Style == "thread" {
+		thread, err := state.StartThreadWithMessage(event.ChannelID, event.ID, api.StartThreadData{
+			Name:                utility.UcFirst(topic),
+			AutoArchiveDuration: discord.OneDayArchive,
+		})
+		if err != nil {
+			log.Printf("[%s] FAQ auto-responder failed to start a thread in <#%s>: %s", event.GuildID, event.ChannelID, err)
+			return
+		}
+		if _, err := state.SendMessageComplex(thread.ID, data); err != nil {
+			log.Printf("[%s] FAQ auto-responder failed to answer in <#%s>: %s", event.GuildID, thread.ID, err)
+		}
+		return
+	}
+
+	data.Reference = &discord.MessageReference{MessageID: event.ID}
+	reply, err := state.SendMessageComplex(event.ChannelID, data)
+	if err != nil {
+		log.Printf("[%s] FAQ auto-responder failed to answer in <#%s>: %s", event.GuildID, event.ChannelID, err)
+		return
+	}
+	if config.Style == "keep" {
+		return
+	}
+	if err := scheduleMessageDeletion(kvs, event.GuildID, reply, time.Now().Add(faqAutoExpire)); err != nil {
+		log.Printf("[%s] FAQ auto-responder failed to schedule the deletion of its answer: %s", event.GuildID, err)
+	}
+}
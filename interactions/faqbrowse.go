@@ -0,0 +1,201 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/component"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+// faqBrowseLimit is how many choices Discord allows in a select menu.
+const faqBrowseLimit = 25
+
+// faqBrowseOther is what topics that aren't in a category are listed as.
+const faqBrowseOther = "Everything else"
+
+// SubCommandFaqBrowse shows the FAQ categories to pick from, or the topics if there are no categories.
+func SubCommandFaqBrowse(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	categories, err := storage.GetFAQCategories(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /faq browse failed to get the categories: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(categories) == 0 {
+		return command.Response{Response: response.Ephemeral("I'm sad to say, there are no known topics.")}
+	}
+	content, components := faqBrowseCategories(categories)
+	if _, only := categories[""]; only && len(categories) == 1 {
+		content, components = faqBrowseTopics(categories, "", "")
+	}
+	return command.Response{Response: api.InteractionResponse{
+		Type: api.MessageInteractionWithSource,
+		Data: &api.InteractionResponseData{
+			Content:         option.NewNullableString(content),
+			Components:      components,
+			Flags:           api.EphemeralResponse,
+			AllowedMentions: &api.AllowedMentions{Parse: []api.AllowedMentionType{}},
+		},
+	}}
+}
+
+// ComponentFaqBrowse handles picking a category or a topic, and the button to go back to the categories.
+func ComponentFaqBrowse(ctx *command.Context, interaction discord.ComponentInteraction, params []string) api.InteractionResponse {
+	state, kvs, e := ctx.State, ctx.KVS, ctx.Event
+	// faqbrowse, faqbrowse/category or faqbrowse/topic/category
+	categories, err := storage.GetFAQCategories(kvs, e.GuildID)
+	if err != nil {
+		log.Printf("[%s] FAQ browser failed to get the categories: %s", e.GuildID, err)
+		return response.Ephemeral("An error occured, and has been logged.")
+	}
+	if len(categories) == 0 {
+		return faqBrowseUpdate("I'm sad to say, there are no known topics.", &discord.ContainerComponents{})
+	}
+	if len(params) == 0 {
+		content, components := faqBrowseCategories(categories)
+		return faqBrowseUpdate(content, components)
+	}
+
+	selected, ok := interaction.(*discord.SelectInteraction)
+	if !ok || len(selected.Values) != 1 {
+		log.Printf("[%s] FAQ browser got a component interaction that isn't a single pick: %s", e.GuildID, interaction.ID())
+		return response.Ephemeral("I'm sorry, what? Something very weird happened.")
+	}
+	switch {
+	case params[0] == "category" && len(params) == 1:
+		content, components := faqBrowseTopics(categories, strings.TrimPrefix(selected.Values[0], "c/"), "")
+		return faqBrowseUpdate(content, components)
+	case params[0] == "topic" && len(params) == 2:
+		_, components := faqBrowseTopics(categories, params[1], selected.Values[0])
+		return faqBrowseUpdate(faqBrowseTopic(state, kvs, e, selected.Values[0]), components)
+	}
+	log.Printf("[%s] Malformed FAQ browser component ID: %s", e.GuildID, interaction.ID())
+	return response.Ephemeral("That menu is broken somehow. It has been logged.")
+}
+
+// faqBrowseUpdate changes the browser message to show something else.
+func faqBrowseUpdate(content string, components *discord.ContainerComponents) api.InteractionResponse {
+	return api.InteractionResponse{
+		Type: api.UpdateMessage,
+		Data: &api.InteractionResponseData{
+			Content:         option.NewNullableString(content),
+			Components:      components,
+			AllowedMentions: &api.AllowedMentions{Parse: []api.AllowedMentionType{}},
+		},
+	}
+}
+
+// faqBrowseCategories is the browser showing the categories to pick from.
+func faqBrowseCategories(categories map[string][]string) (string, *discord.ContainerComponents) {
+	names := make([]string, 0, len(categories))
+	for name := range categories {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := categories[""]; ok {
+		names = append(names, "") // Everything else goes last.
+	}
+
+	content := "**What would you like to know about?** Pick a category."
+	if len(names) > faqBrowseLimit {
+		content += fmt.Sprintf(" There are too many to list them all, so only the first %d are here.", faqBrowseLimit)
+		names = names[:faqBrowseLimit]
+	}
+	options := make([]discord.SelectOption, len(names))
+	for i, name := range names {
+		label := utility.UcFirst(name)
+		if name == "" {
+			label = faqBrowseOther
+		}
+		options[i] = discord.SelectOption{
+			Label:       label,
+			Value:       "c/" + name, // Never blank, as Discord won't have that, even for everything else.
+			Description: fmt.Sprintf("%d topics", len(categories[name])),
+		}
+	}
+	return content, &discord.ContainerComponents{
+		&discord.ActionRowComponent{
+			&discord.SelectComponent{
+				CustomID:    component.ID("faqbrowse", "category"),
+				Placeholder: "Pick a category",
+				Options:     options,
+			},
+		},
+	}
+}
+
+// faqBrowseTopics is the browser showing the topics in a category to pick from, with the current one picked already.
+// There's a button to go back to the categories, unless there's only the one.
+func faqBrowseTopics(categories map[string][]string, category string, current string) (string, *discord.ContainerComponents) {
+	topics, ok := categories[category]
+	if !ok {
+		return "That category is gone now. Pick another one.", &discord.ContainerComponents{
+			&discord.ActionRowComponent{faqBrowseBack()},
+		}
+	}
+	name := utility.UcFirst(category)
+	if category == "" {
+		name = faqBrowseOther
+	}
+	content := fmt.Sprintf("**%s** Pick a topic.", name)
+	if len(topics) > faqBrowseLimit {
+		content += fmt.Sprintf(" There are too many to list them all, so only the first %d are here. Use `/faq show` for the rest.", faqBrowseLimit)
+		topics = topics[:faqBrowseLimit]
+	}
+	options := make([]discord.SelectOption, len(topics))
+	for i, topic := range topics {
+		options[i] = discord.SelectOption{
+			Label:   utility.UcFirst(topic),
+			Value:   topic,
+			Default: topic == current,
+		}
+	}
+	components := discord.ContainerComponents{
+		&discord.ActionRowComponent{
+			&discord.SelectComponent{
+				CustomID:    component.ID("faqbrowse", "topic", category),
+				Placeholder: "Pick a topic",
+				Options:     options,
+			},
+		},
+	}
+	if len(categories) > 1 {
+		components = append(components, &discord.ActionRowComponent{faqBrowseBack()})
+	}
+	return content, &components
+}
+
+// faqBrowseBack is the button that goes back to the categories.
+func faqBrowseBack() *discord.ButtonComponent {
+	return &discord.ButtonComponent{
+		Style:    discord.SecondaryButtonStyle(),
+		CustomID: component.ID("faqbrowse"),
+		Label:    "Back to the categories",
+	}
+}
+
+// faqBrowseTopic is what the topic says, as shown in the browser.
+func faqBrowseTopic(state *state.State, kvs storage.KeyValueStore, e *gateway.InteractionCreateEvent, topic string) string {
+	exist, text, err := storage.GetFAQTopic(kvs, e.GuildID, topic)
+	if err != nil {
+		log.Printf("[%s] FAQ browser failed to get the topic %q: %s", e.GuildID, topic, err)
+		return "An error occured, and has been logged."
+	}
+	if !exist {
+		return fmt.Sprintf("Sorry, %s is gone now.", topic)
+	}
+	text = utility.RenderTemplate(text, templateValues(state, e.GuildID, e.ChannelID, e.SenderID(), nil))
+	return utility.Substring(fmt.Sprintf("**%s**\n%s", utility.UcFirst(topic), text), 0, 2000)
+}
@@ -0,0 +1,194 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+var feedModule = module.Basic{
+	ModuleName: "feed",
+	CommandHandlers: map[string]command.Handler{
+		"feed": commandFeedObject,
+	},
+}
+
+var commandFeedObject = command.Handler{
+	Description: "Post new entries from RSS and Atom feeds",
+	Code:        CommandFeed,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "add",
+			Description: "Follow a feed, posting new entries in a channel",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "url",
+					Description: "The address of the feed",
+					Required:    true,
+				},
+				&discord.ChannelOption{
+					OptionName:   "channel",
+					Description:  "Where to post new entries",
+					Required:     true,
+					ChannelTypes: []discord.ChannelType{discord.GuildText, discord.GuildNews},
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "remove",
+			Description: "Stop following a feed",
+			Options: []discord.CommandOptionValue{
+				&discord.IntegerOption{
+					OptionName:  "feed",
+					Description: "The number of the feed, as shown by /feed list",
+					Required:    true,
+					Min:         option.NewInt(1),
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "list",
+			Description: "List the feeds followed here",
+			Options:     []discord.CommandOptionValue{},
+		},
+	},
+}
+
+// CommandFeed processes the /feed command, dispatching to the right subcommand.
+func CommandFeed(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /feed command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "add":
+		return SubCommandFeedAdd(state, kvs, event, cmd.Options[0].Options)
+	case "remove":
+		return SubCommandFeedRemove(kvs, event, cmd.Options[0].Options)
+	case "list":
+		return SubCommandFeedList(kvs, event)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandFeedAdd follows a feed, after checking that it can be read. Whatever is in it already is not posted.
+func SubCommandFeedAdd(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	address := strings.TrimSpace(options.Find("url").String())
+	parsed, err := url.Parse(address)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return command.Response{Response: response.Ephemeral("That doesn't look like the address of a feed.")}
+	}
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /feed add failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	channelID := discord.ChannelID(channelSnowflake)
+
+	feeds, err := storage.GetFeeds(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /feed add failed to get the feeds: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(feeds) >= storage.FeedLimit {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("This guild already follows %d feeds, which is as many as it gets.", storage.FeedLimit))}
+	}
+	for _, feed := range feeds {
+		if feed.URL == parsed.String() && feed.ChannelID == channelID {
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("That feed is already posted in %s, as number %d.", channelID.Mention(), feed.ID))}
+		}
+	}
+
+	// Fetching the feed can take a while, so this is deferred.
+	return command.DeferredData(state, event, true, func() api.EditInteractionResponseData {
+		reply := func(text string) api.EditInteractionResponseData {
+			return api.EditInteractionResponseData{Content: option.NewNullableString(text)}
+		}
+		fetched, err := storage.FetchFeed(parsed.String())
+		if err != nil {
+			return reply(fmt.Sprintf("I couldn't read that feed: %s", err))
+		}
+		id, err := storage.NextNumber(kvs, event.GuildID, "feeds")
+		if err != nil {
+			log.Printf("[%s] /feed add failed to get a number: %s", event.GuildID, err)
+			return reply("An error occured, and has been logged.")
+		}
+		feed := storage.Feed{
+			ID:        id,
+			GuildID:   event.GuildID,
+			ChannelID: channelID,
+			URL:       parsed.String(),
+			Title:     fetched.Title,
+			AddedBy:   event.SenderID(),
+		}
+		if feed.Title == "" {
+			feed.Title = parsed.Host
+		}
+		feed.MarkSeen(fetched.Items...)
+		if err := feed.Store(kvs); err != nil {
+			log.Printf("[%s] /feed add failed to store the feed: %s", event.GuildID, err)
+			return reply("An error occured, and has been logged.")
+		}
+		log.Printf("[%s] <@%s> added feed %d, %s, posting in <#%s>", event.GuildID, event.SenderID(), feed.ID, feed.URL, channelID)
+		return reply(fmt.Sprintf("Following **%s** as feed number %d. New entries will show up in %s.", feed.Title, feed.ID, channelID.Mention()))
+	})
+}
+
+// SubCommandFeedRemove stops following a feed.
+func SubCommandFeedRemove(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	id, err := options.Find("feed").IntValue()
+	if err != nil {
+		log.Printf("[%s] /feed remove failed to get the feed number: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	exist, feed, err := storage.GetFeed(kvs, event.GuildID, id)
+	if err != nil {
+		log.Printf("[%s] /feed remove failed to get feed %d: %s", event.GuildID, id, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no feed number %d here.", id))}
+	}
+	if err := feed.Delete(kvs); err != nil {
+		log.Printf("[%s] /feed remove failed to remove feed %d: %s", event.GuildID, id, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> removed feed %d, %s", event.GuildID, event.SenderID(), feed.ID, feed.URL)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("No longer following **%s**.", feed.Title))}
+}
+
+// SubCommandFeedList lists the feeds followed, with where they post and if anything is wrong with them.
+func SubCommandFeedList(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	feeds, err := storage.GetFeeds(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /feed list failed to get the feeds: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(feeds) == 0 {
+		return command.Response{Response: response.Ephemeral("No feeds are followed here. Add one with `/feed add`.")}
+	}
+	lines := make([]string, len(feeds))
+	for i, feed := range feeds {
+		lines[i] = fmt.Sprintf("**%d** %s in %s\n<%s>", feed.ID, feed.Title, feed.ChannelID.Mention(), feed.URL)
+		if feed.LastChecked > 0 {
+			lines[i] += fmt.Sprintf(", checked <t:%d:R>", feed.LastChecked)
+		}
+		if feed.LastError != "" {
+			lines[i] += "\n⚠️ " + feed.LastError
+		}
+	}
+	return command.Response{Response: response.Ephemeral(strings.Join(lines, "\n"))}
+}
@@ -0,0 +1,217 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+// githubRepository matches "owner/repo", optionally as part of a github.com address.
+var githubRepository = regexp.MustCompile(`^(?:https?://)?(?:www\.)?(?:github\.com/)?([A-Za-z0-9-]{1,39})/([A-Za-z0-9._-]{1,100}?)(?:\.git)?/?$`)
+
+var gitHubModule = module.Basic{
+	ModuleName: "github",
+	CommandHandlers: map[string]command.Handler{
+		"github": commandGitHubObject,
+	},
+}
+
+var commandGitHubObject = command.Handler{
+	Description: "Post new releases, issues and pull requests of GitHub repositories",
+	Code:        CommandGitHub,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "watch",
+			Description: "Start posting about a repository",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "repository",
+					Description: "The repository, like owner/repo",
+					Required:    true,
+				},
+				&discord.ChannelOption{
+					OptionName:   "channel",
+					Description:  "Where to post",
+					Required:     true,
+					ChannelTypes: []discord.ChannelType{discord.GuildText, discord.GuildNews},
+				},
+				&discord.BooleanOption{
+					OptionName:  "releases",
+					Description: "Post new releases. Default is yes.",
+					Required:    false,
+				},
+				&discord.BooleanOption{
+					OptionName:  "issues",
+					Description: "Post new issues. Default is yes.",
+					Required:    false,
+				},
+				&discord.BooleanOption{
+					OptionName:  "pulls",
+					Description: "Post new pull requests. Default is yes.",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "unwatch",
+			Description: "Stop posting about a repository",
+			Options: []discord.CommandOptionValue{
+				&discord.IntegerOption{
+					OptionName:  "watch",
+					Description: "The number of the repository, as shown by /github list",
+					Required:    true,
+					Min:         option.NewInt(1),
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "list",
+			Description: "List the repositories watched here",
+			Options:     []discord.CommandOptionValue{},
+		},
+	},
+}
+
+// CommandGitHub processes the /github command, dispatching to the right subcommand.
+func CommandGitHub(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /github command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "watch":
+		return SubCommandGitHubWatch(kvs, event, cmd.Options[0].Options)
+	case "unwatch":
+		return SubCommandGitHubUnwatch(kvs, event, cmd.Options[0].Options)
+	case "list":
+		return SubCommandGitHubList(kvs, event)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// githubOption returns the value of the boolean option, or true if it was left out.
+func githubOption(options discord.CommandInteractionOptions, name string) bool {
+	opt := options.Find(name)
+	if opt.Name == "" {
+		return true
+	}
+	value, err := opt.BoolValue()
+	return err != nil || value
+}
+
+// SubCommandGitHubWatch starts watching a repository. What's there already isn't posted, only what comes after.
+func SubCommandGitHubWatch(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	match := githubRepository.FindStringSubmatch(strings.TrimSpace(options.Find("repository").String()))
+	if match == nil {
+		return command.Response{Response: response.Ephemeral("That doesn't look like a repository. I need it like `owner/repo`.")}
+	}
+	repository := match[1] + "/" + match[2]
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /github watch failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	watch := storage.GitHubWatch{
+		GuildID:    event.GuildID,
+		ChannelID:  discord.ChannelID(channelSnowflake),
+		Repository: repository,
+		Releases:   githubOption(options, "releases"),
+		Issues:     githubOption(options, "issues"),
+		Pulls:      githubOption(options, "pulls"),
+		AddedBy:    event.SenderID(),
+	}
+	if !watch.Releases && !watch.Issues && !watch.Pulls {
+		return command.Response{Response: response.Ephemeral("With releases, issues and pull requests all turned off, there's nothing left to post.")}
+	}
+
+	watches, err := storage.GetGitHubWatches(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /github watch failed to get the watches: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(watches) >= storage.GitHubLimit {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("This guild already watches %d repositories, which is as many as it gets.", storage.GitHubLimit))}
+	}
+	for _, existing := range watches {
+		if strings.EqualFold(existing.Repository, repository) && existing.ChannelID == watch.ChannelID {
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("That repository is already posted in %s, as number %d.", watch.ChannelID.Mention(), existing.ID))}
+		}
+	}
+
+	id, err := storage.NextNumber(kvs, event.GuildID, "github")
+	if err != nil {
+		log.Printf("[%s] /github watch failed to get a number: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	watch.ID = id
+	if err := watch.Store(kvs); err != nil {
+		log.Printf("[%s] /github watch failed to store the watch: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> started watching GitHub repository %s as %d, posting in <#%s>", event.GuildID, event.SenderID(), repository, watch.ID, watch.ChannelID)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Watching **%s** as number %d. What's new will show up in %s. If anything is wrong with the repository, `/github list` will say so in a few minutes.", repository, watch.ID, watch.ChannelID.Mention()))}
+}
+
+// SubCommandGitHubUnwatch stops watching a repository.
+func SubCommandGitHubUnwatch(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	id, err := options.Find("watch").IntValue()
+	if err != nil {
+		log.Printf("[%s] /github unwatch failed to get the number: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	exist, watch, err := storage.GetGitHubWatch(kvs, event.GuildID, id)
+	if err != nil {
+		log.Printf("[%s] /github unwatch failed to get watch %d: %s", event.GuildID, id, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no repository number %d here.", id))}
+	}
+	if err := watch.Delete(kvs); err != nil {
+		log.Printf("[%s] /github unwatch failed to remove watch %d: %s", event.GuildID, id, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> stopped watching GitHub repository %s", event.GuildID, event.SenderID(), watch.Repository)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("No longer watching **%s**.", watch.Repository))}
+}
+
+// SubCommandGitHubList lists the repositories watched, with what is posted where, and if anything is wrong with them.
+func SubCommandGitHubList(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	watches, err := storage.GetGitHubWatches(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /github list failed to get the watches: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(watches) == 0 {
+		return command.Response{Response: response.Ephemeral("No repositories are watched here. Add one with `/github watch`.")}
+	}
+	lines := make([]string, len(watches))
+	for i, watch := range watches {
+		what := []string{}
+		if watch.Releases {
+			what = append(what, "releases")
+		}
+		if watch.Issues {
+			what = append(what, "issues")
+		}
+		if watch.Pulls {
+			what = append(what, "pull requests")
+		}
+		lines[i] = fmt.Sprintf("**%d** %s: %s in %s", watch.ID, watch.Repository, strings.Join(what, ", "), watch.ChannelID.Mention())
+		if watch.LastError != "" {
+			lines[i] += "\n⚠️ " + watch.LastError
+		}
+	}
+	return command.Response{Response: response.Ephemeral(strings.Join(lines, "\n"))}
+}
@@ -0,0 +1,35 @@
+package guildcreate
+
+import (
+	"komainu/storage"
+
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+type Handler struct {
+	Code HandlerFunction
+}
+
+type HandlerFunction func(
+	state *state.State,
+	kvs storage.KeyValueStore,
+	event *gateway.GuildCreateEvent,
+)
+
+var guildcreatehandlers = []Handler{}
+
+// Register makes the Code turn over when a guild becomes available, like when the bot starts, or joins it
+func Register(handler Handler) {
+	guildcreatehandlers = append(guildcreatehandlers, handler)
+}
+
+// Add the guild create handler to the given state
+// This is mostly just pointless abstraction for uniformity across events.
+func AddHandler(state *state.State, kvs storage.KeyValueStore) {
+	state.AddHandler(func(event *gateway.GuildCreateEvent) {
+		for _, handler := range guildcreatehandlers {
+			handler.Code(state, kvs, event)
+		}
+	})
+}
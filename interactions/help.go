@@ -0,0 +1,154 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/message"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+const (
+	mentionHelpCollection = "mentionhelp"
+	mentionHelpKey        = "text"
+	mentionHelpDefault    = "Hi! I'm a bot, and I do my thing through slash commands."
+)
+
+// mentionOnlyPattern matches a message that is nothing but a single mention.
+var mentionOnlyPattern = regexp.MustCompile(`^\s*<@!?(\d+)>\s*$`)
+
+var helpModule = module.Basic{
+	ModuleName: "help",
+	CommandHandlers: map[string]command.Handler{
+		"help": commandHelpObject,
+	},
+	Handlers: []module.EventHandler{
+		module.Message(message.Handler{Code: MessageMentionHelp, Match: mentionOnlyPattern}),
+	},
+}
+
+var commandHelpObject = command.Handler{
+	Description: "List the commands you can use",
+	Code:        CommandHelp,
+	Public:      true,
+	DMs:         true,
+	Options:     []discord.CommandOption{},
+}
+
+// helpCommands returns the names of the commands the user can use in the channel, sorted.
+// Only the public ones, unless they are an administrator.
+func helpCommands(state *state.State, channelID discord.ChannelID, userID discord.UserID) []string {
+	admin := false
+	permissions, err := state.Permissions(channelID, userID)
+	if err != nil {
+		log.Printf("Failed to get permissions of <@%s> in <#%s> for help: %s", userID, channelID, err)
+	} else {
+		admin = permissions.Has(discord.PermissionAdministrator)
+	}
+	names := []string{}
+	for _, name := range command.Names() {
+		handler, _ := command.Lookup(name)
+		if handler.Owner && !command.IsOwner(userID) {
+			continue
+		}
+		if admin || handler.Public {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// privateHelpCommands returns the names of the commands the user can use in private, sorted.
+func privateHelpCommands(userID discord.UserID) []string {
+	names := []string{}
+	for _, name := range command.Names() {
+		if handler, _ := command.Lookup(name); handler.DMs && (!handler.Owner || command.IsOwner(userID)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CommandHelp lists the commands the user can use, with their descriptions.
+func CommandHelp(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, event := ctx.State, ctx.Event
+	if event.GuildID == discord.NullGuildID {
+		lines := []string{"Most of what I do only works in a server. In private, you can use:"}
+		for _, name := range privateHelpCommands(event.SenderID()) {
+			handler, _ := command.Lookup(name)
+			lines = append(lines, fmt.Sprintf("%s: %s", command.Mention(name), handler.Description))
+		}
+		return command.Response{Response: response.Ephemeral(strings.Join(lines, "\n"))}
+	}
+	names := helpCommands(state, event.ChannelID, event.SenderID())
+	lines := make([]string, len(names))
+	for i, name := range names {
+		handler, _ := command.Lookup(name)
+		lines[i] = fmt.Sprintf("%s: %s", command.Mention(name), handler.Description)
+	}
+	return command.Response{Response: response.Ephemeral(strings.Join(lines, "\n"))}
+}
+
+// getMentionHelpText gets the guild's own orientation text, or the default one.
+func getMentionHelpText(kvs storage.KeyValueStore, guildID discord.GuildID) (string, error) {
+	text := mentionHelpDefault
+	_, err := kvs.Get(guildID, mentionHelpCollection, mentionHelpKey, &text)
+	return text, err
+}
+
+// MessageMentionHelp replies to a bare mention of the bot with a short orientation.
+func MessageMentionHelp(state *state.State, kvs storage.KeyValueStore, event *gateway.MessageCreateEvent) {
+	if event.GuildID == discord.NullGuildID || event.Author.Bot {
+		return
+	}
+	me, err := state.Me()
+	if err != nil {
+		log.Printf("[%s] Mention help failed to look up itself: %s", event.GuildID, err)
+		return
+	}
+	if mentionOnlyPattern.FindStringSubmatch(event.Content)[1] != me.ID.String() {
+		return
+	}
+	text, err := getMentionHelpText(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Mention help failed to get the text: %s", event.GuildID, err)
+		return
+	}
+
+	text = utility.RenderTemplate(text, templateValues(state, event.GuildID, event.ChannelID, event.Author.ID, nil))
+
+	public := []string{}
+	count := 0
+	for _, name := range helpCommands(state, event.ChannelID, event.Author.ID) {
+		count++
+		if handler, _ := command.Lookup(name); handler.Public && name != "help" {
+			public = append(public, command.Mention(name))
+		}
+	}
+	if len(public) > 0 {
+		text += "\nYou can use " + strings.Join(public, ", ") + "."
+	}
+	text += fmt.Sprintf("\nSee %s for all %d commands you can use.", command.Mention("help"), count)
+
+	_, err = state.SendMessageComplex(event.ChannelID, api.SendMessageData{
+		Content:         text,
+		Reference:       &discord.MessageReference{MessageID: event.ID},
+		AllowedMentions: &api.AllowedMentions{Parse: []api.AllowedMentionType{}},
+	})
+	if err != nil {
+		log.Printf("[%s] Mention help failed to reply in <#%s>: %s", event.GuildID, event.ChannelID, err)
+	}
+}
@@ -0,0 +1,219 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+var lockdownModule = module.Basic{
+	ModuleName: "lockdown",
+	CommandHandlers: map[string]command.Handler{
+		"lockdown": commandLockdownObject,
+	},
+}
+
+var lockdownCategoryOption = &discord.ChannelOption{
+	OptionName:   "category",
+	Description:  "Only the channels in this category",
+	Required:     false,
+	ChannelTypes: []discord.ChannelType{discord.GuildCategory},
+}
+
+var lockdownDryRunOption = &discord.BooleanOption{
+	OptionName:  "dryrun",
+	Description: "Only list the channels it would change, without changing them",
+	Required:    false,
+}
+
+var commandLockdownObject = command.Handler{
+	Description: "Stop everyone from talking, for when things get out of hand",
+	Code:        CommandLockdown,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "start",
+			Description: "Lock down the lockdown channels, or every text channel if none are set",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "reason",
+					Description: "Why? This goes in the audit log.",
+					Required:    false,
+				},
+				lockdownCategoryOption,
+				lockdownDryRunOption,
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "end",
+			Description: "Put every locked channel back the way it was",
+			Options:     []discord.CommandOptionValue{lockdownCategoryOption, lockdownDryRunOption},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "channel",
+			Description: "Add a channel to the ones a lockdown covers, or take it out if it's already in",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:   "channel",
+					Description:  "The channel in question",
+					Required:     true,
+					ChannelTypes: []discord.ChannelType{discord.GuildText, discord.GuildNews},
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "list",
+			Description: "List the channels a lockdown covers, and the ones locked right now",
+			Options:     []discord.CommandOptionValue{},
+		},
+	},
+}
+
+// CommandLockdown processes the /lockdown command, dispatching to the right subcommand.
+func CommandLockdown(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /lockdown command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	categoryID := discord.NullChannelID
+	if opt := cmd.Options[0].Options.Find("category"); opt.Name != "" {
+		snowflake, err := opt.SnowflakeValue()
+		if err != nil {
+			log.Printf("[%s] /lockdown %s failed to get category snowflake: %s", event.GuildID, cmd.Options[0].Name, err)
+			return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+		}
+		categoryID = discord.ChannelID(snowflake)
+	}
+	dryRun, _ := cmd.Options[0].Options.Find("dryrun").BoolValue()
+	switch cmd.Options[0].Name {
+	case "start":
+		reason := cmd.Options[0].Options.Find("reason").String()
+		if reason == "" {
+			reason = fmt.Sprintf("Started by %s", event.SenderID())
+		}
+		return command.Deferred(state, event, func() string {
+			locked, err := storage.Lockdown(state, kvs, event.GuildID, categoryID, reason, dryRun)
+			if err != nil {
+				log.Printf("[%s] /lockdown start failed after %d channels: %s", event.GuildID, len(locked), err)
+				if dryRun {
+					return "I couldn't work out which channels it would lock. The error has been logged."
+				}
+				return fmt.Sprintf("I locked %d channels, but then something went wrong. The error has been logged. Use `/lockdown end` to undo what was done.", len(locked))
+			}
+			if dryRun {
+				return lockdownDryRun("lock", locked)
+			}
+			log.Printf("[%s] <@%s> locked down %d channels", event.GuildID, event.SenderID(), len(locked))
+			return fmt.Sprintf("🔒 Lockdown! %d channels locked.", len(locked))
+		})
+	case "end":
+		return command.Deferred(state, event, func() string {
+			unlocked, err := storage.LiftLockdown(state, kvs, event.GuildID, categoryID, dryRun)
+			if err != nil {
+				log.Printf("[%s] /lockdown end failed after %d channels: %s", event.GuildID, len(unlocked), err)
+				return fmt.Sprintf("I unlocked %d channels, but then something went wrong. The error has been logged. Try again to unlock the rest.", len(unlocked))
+			}
+			if len(unlocked) == 0 {
+				return "There is no lockdown to end."
+			}
+			if dryRun {
+				return lockdownDryRun("unlock", unlocked)
+			}
+			log.Printf("[%s] <@%s> lifted the lockdown on %d channels", event.GuildID, event.SenderID(), len(unlocked))
+			return fmt.Sprintf("🔓 Lockdown lifted. %d channels are back to normal.", len(unlocked))
+		})
+	case "channel":
+		return SubCommandLockdownChannel(kvs, event, cmd.Options[0].Options)
+	case "list":
+		return SubCommandLockdownList(kvs, event)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// lockdownDryRunShown is how many channels a dry run lists, to stay well within what fits in a message.
+const lockdownDryRunShown = 50
+
+// lockdownDryRun says which channels a lockdown, or lifting it, would change.
+func lockdownDryRun(verb string, channels []discord.ChannelID) string {
+	if len(channels) == 0 {
+		return fmt.Sprintf("Dry run: There's nothing to %s.", verb)
+	}
+	mentions := []string{}
+	for i, channelID := range channels {
+		if i == lockdownDryRunShown {
+			mentions = append(mentions, fmt.Sprintf("and %d more", len(channels)-i))
+			break
+		}
+		mentions = append(mentions, channelID.Mention())
+	}
+	return fmt.Sprintf("Dry run: I would %s %d channels, and nothing was changed.\n%s", verb, len(channels), strings.Join(mentions, " "))
+}
+
+// SubCommandLockdownChannel adds a channel to the ones a lockdown covers, or takes it out if it's already in.
+func SubCommandLockdownChannel(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /lockdown channel failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	channelID := discord.ChannelID(channelSnowflake)
+	config, err := storage.GetLockdownConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to get lockdown config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	message := fmt.Sprintf("A lockdown now covers %s.", channelID.Mention())
+	if config.Channels[channelID] {
+		delete(config.Channels, channelID)
+		message = fmt.Sprintf("A lockdown no longer covers %s.", channelID.Mention())
+	} else {
+		config.Channels[channelID] = true
+	}
+	if len(config.Channels) == 0 {
+		message += " There are no lockdown channels left, so a lockdown covers every text channel again."
+	}
+	if err := storage.SetLockdownConfig(kvs, event.GuildID, config); err != nil {
+		log.Printf("[%s] Failed to store lockdown config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> toggled <#%s> as a lockdown channel", event.GuildID, event.SenderID(), channelID)
+	return command.Response{Response: response.Ephemeral(message)}
+}
+
+// SubCommandLockdownList lists the channels a lockdown covers, and the ones that are locked right now.
+func SubCommandLockdownList(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	config, err := storage.GetLockdownConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to get lockdown config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	locked, err := storage.LockedChannels(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /lockdown list failed to get the locked channels: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	covered := "A lockdown covers every text channel."
+	if len(config.Channels) > 0 {
+		mentions := []string{}
+		for channelID := range config.Channels {
+			mentions = append(mentions, channelID.Mention())
+		}
+		covered = "A lockdown covers " + strings.Join(mentions, ", ") + "."
+	}
+	if len(locked) == 0 {
+		return command.Response{Response: response.Ephemeral(covered + "\nNothing is locked right now.")}
+	}
+	lines := []string{covered, "", "Locked right now:"}
+	for _, channel := range locked {
+		lines = append(lines, fmt.Sprintf("%s, since <t:%d:R>", channel.ChannelID.Mention(), channel.Locked))
+	}
+	return command.Response{Response: response.Ephemeral(strings.Join(lines, "\n"))}
+}
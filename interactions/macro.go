@@ -0,0 +1,405 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/modal"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+// macroMaxSteps is how many steps a single macro can have, so running one doesn't take forever.
+const macroMaxSteps = 20
+
+var macroModule = module.Basic{
+	ModuleName: "macro",
+	CommandHandlers: map[string]command.Handler{
+		"macro": commandMacroObject,
+	},
+	Handlers: []module.EventHandler{
+		module.Modal("macrodefine", modal.Handler{Code: MacroDefineModalHandler}),
+	},
+}
+
+var commandMacroObject = command.Handler{
+	Description: "Record a few bot actions, and run them all at once",
+	Code:        CommandMacro,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "define",
+			Description: "Write the steps of a macro, or change one",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "name",
+					Description: "What the macro is called, in lowercase",
+					Required:    true,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "run",
+			Description: "Do all the steps of a macro, one after the other",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "name",
+					Description: "The macro to run",
+					Required:    true,
+				},
+				&discord.UserOption{
+					OptionName:  "user",
+					Description: "Who the give and take steps are for",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "remove",
+			Description: "Remove a macro",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "name",
+					Description: "The macro to remove",
+					Required:    true,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "list",
+			Description: "List the macros made here",
+			Options:     []discord.CommandOptionValue{},
+		},
+	},
+}
+
+// CommandMacro processes the /macro command, dispatching to the right subcommand.
+func CommandMacro(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /macro command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "define":
+		return SubCommandMacroDefine(kvs, event, cmd.Options[0].Options)
+	case "run":
+		return SubCommandMacroRun(state, kvs, event, cmd.Options[0].Options)
+	case "remove":
+		return SubCommandMacroRemove(kvs, event, cmd.Options[0].Options)
+	case "list":
+		return SubCommandMacroList(kvs, event)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// macroName cleans up the name of a macro as typed.
+func macroName(options discord.CommandInteractionOptions) string {
+	return strings.ToLower(strings.TrimSpace(options.Find("name").String()))
+}
+
+// SubCommandMacroDefine asks for the steps of the macro, with any it already has filled in.
+func SubCommandMacroDefine(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	name := macroName(options)
+	if !customCommandName.MatchString(name) {
+		return command.Response{Response: response.Ephemeral("Macro names can only have letters, numbers, `-` and `_` in them, and no spaces.")}
+	}
+	exist, macro, err := storage.GetMacro(kvs, event.GuildID, name)
+	if err != nil {
+		log.Printf("[%s] /macro define failed to look up %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	title := "New macro: " + name
+	if exist {
+		title = "Change macro: " + name
+	}
+	lines := make([]string, len(macro.Steps))
+	for i, step := range macro.Steps {
+		lines[i] = macroStepLine(step)
+	}
+	return command.Response{Response: modal.Respond(
+		kvs, event, "macrodefine", title, map[string]string{"name": name},
+		discord.TextInputComponent{
+			CustomID:     discord.ComponentID("steps"),
+			Label:        "One step on each line",
+			Value:        option.NewNullableString(strings.Join(lines, "\n")),
+			Placeholder:  option.NewNullableString("give @Role\ntake @Role\nsay #channel Some text\nslowmode #channel 30s"),
+			Style:        discord.TextInputParagraphStyle,
+			LengthLimits: [2]int{1, 4000},
+		},
+	)}
+}
+
+// macroStepLine writes the step the way it's written when defining a macro.
+func macroStepLine(step storage.MacroStep) string {
+	switch step.Action {
+	case "give", "take":
+		return step.Action + " " + step.RoleID.Mention()
+	case "say":
+		return "say " + step.ChannelID.Mention() + " " + step.Text
+	case "slowmode":
+		if step.Seconds == 0 {
+			return "slowmode " + step.ChannelID.Mention() + " off"
+		}
+		return fmt.Sprintf("slowmode %s %s", step.ChannelID.Mention(), time.Duration(step.Seconds)*time.Second)
+	}
+	return step.Action
+}
+
+// parseMacroMention gets the snowflake out of a mention or a bare ID.
+func parseMacroMention(word string) (discord.Snowflake, error) {
+	snowflake, err := discord.ParseSnowflake(strings.Trim(word, "<@&#!>"))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a mention or an ID", word)
+	}
+	return snowflake, nil
+}
+
+// parseMacroStep makes sense of a single line of a macro definition, checking that whoever defines it could do it themselves.
+func parseMacroStep(state *state.State, guildID discord.GuildID, actor *discord.Member, line string) (storage.MacroStep, error) {
+	words := strings.Fields(line)
+	if len(words) < 2 {
+		return storage.MacroStep{}, fmt.Errorf("I don't know how to %q", line)
+	}
+	step := storage.MacroStep{Action: strings.ToLower(words[0])}
+	switch step.Action {
+	case "give", "take":
+		if len(words) != 2 {
+			return step, fmt.Errorf("%s only takes a role, like `%s @Role`", step.Action, step.Action)
+		}
+		snowflake, err := parseMacroMention(words[1])
+		if err != nil {
+			return step, err
+		}
+		step.RoleID = discord.RoleID(snowflake)
+		if err := utility.CanManageRole(state, guildID, actor, step.RoleID); err != nil {
+			if !utility.OutOfReach(err) {
+				log.Printf("[%s] /macro define failed to check the hierarchy for <@&%s>: %s", guildID, step.RoleID, err)
+				return step, fmt.Errorf("I couldn't check the role %s", step.RoleID.Mention())
+			}
+			return step, err
+		}
+		return step, nil
+	case "say", "slowmode":
+		snowflake, err := parseMacroMention(words[1])
+		if err != nil {
+			return step, err
+		}
+		step.ChannelID = discord.ChannelID(snowflake)
+		channel, err := state.Channel(step.ChannelID)
+		if err != nil || channel.GuildID != guildID {
+			return step, fmt.Errorf("%s is not a channel here", words[1])
+		}
+	default:
+		return step, fmt.Errorf("I don't know how to %q. I can give, take, say and slowmode.", step.Action)
+	}
+
+	rest := strings.TrimSpace(strings.SplitN(line, words[1], 2)[1])
+	if step.Action == "say" {
+		if rest == "" {
+			return step, fmt.Errorf("say needs something to say, like `say #channel Hello!`")
+		}
+		if err := utility.ValidateTemplate(rest); err != nil {
+			return step, err
+		}
+		step.Text = rest
+		return step, nil
+	}
+	if strings.EqualFold(rest, "off") {
+		return step, nil
+	}
+	duration, err := utility.ParseDuration(rest)
+	if err != nil {
+		return step, fmt.Errorf("slowmode needs a duration or off, like `slowmode #channel 30s`")
+	}
+	step.Seconds = int(duration.Seconds())
+	if step.Seconds < 0 || step.Seconds > slowmodeMax {
+		return step, fmt.Errorf("slowmode can't be longer than %s", time.Duration(slowmodeMax)*time.Second)
+	}
+	return step, nil
+}
+
+// MacroDefineModalHandler checks every step of the submitted macro, and stores it if they're all good.
+func MacroDefineModalHandler(ctx *command.Context, interaction *discord.ModalInteraction, session map[string]string) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	name := session["name"]
+	text, ok := modal.DecodeModalResponse(interaction.Components)["steps"]
+	if !ok || name == "" {
+		log.Printf("[%s] There was no data when trying to define a macro?!  %#v", event.GuildID, interaction.Components)
+		return command.Response{Response: response.Ephemeral("There was a weird problem, but don't worry! It has been logged for review.")}
+	}
+
+	macro := storage.Macro{Name: name, CreatedBy: event.SenderID(), Updated: time.Now().Unix()}
+	problems := []string{}
+	for i, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		step, err := parseMacroStep(state, event.GuildID, event.Member, strings.TrimSpace(line))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("Line %d: %s", i+1, err))
+			continue
+		}
+		macro.Steps = append(macro.Steps, step)
+	}
+	if len(problems) > 0 {
+		return command.Response{Response: response.Ephemeral(utility.Substring(fmt.Sprintf("I didn't save %s, as it won't work:\n%s", name, strings.Join(problems, "\n")), 0, 2000))}
+	}
+	if len(macro.Steps) == 0 {
+		return command.Response{Response: response.Ephemeral("A macro needs at least one step.")}
+	}
+	if len(macro.Steps) > macroMaxSteps {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("A macro can't have more than %d steps.", macroMaxSteps))}
+	}
+	if err := storage.SetMacro(kvs, event.GuildID, macro); err != nil {
+		log.Printf("[%s] Failed to store macro %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> defined the macro %s with %d steps", event.GuildID, event.SenderID(), name, len(macro.Steps))
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Saved %s with %d steps. Run it with `/macro run name:%s`.", name, len(macro.Steps), name))}
+}
+
+// SubCommandMacroRun does every step of the macro in order, carrying on past any that fail, and says how each one went.
+func SubCommandMacroRun(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	name := macroName(options)
+	exist, macro, err := storage.GetMacro(kvs, event.GuildID, name)
+	if err != nil {
+		log.Printf("[%s] /macro run failed to look up %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no macro called %s.", name))}
+	}
+	var userID discord.UserID
+	if opt := options.Find("user"); opt.Name != "" {
+		snowflake, err := opt.SnowflakeValue()
+		if err != nil {
+			log.Printf("[%s] /macro run failed to get user snowflake: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("There was an issue figuring out the user. It has been logged.")}
+		}
+		userID = discord.UserID(snowflake)
+	}
+
+	log.Printf("[%s] <@%s> ran the macro %s", event.GuildID, event.SenderID(), name)
+	return command.DeferredData(state, event, true, func() api.EditInteractionResponseData {
+		lines := []string{fmt.Sprintf("Ran %s:", name)}
+		failed := 0
+		for i, step := range macro.Steps {
+			err := runMacroStep(state, event, step, userID)
+			if err != nil {
+				failed++
+				lines = append(lines, fmt.Sprintf("❌ %d. `%s`: %s", i+1, macroStepLine(step), err))
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("✅ %d. `%s`", i+1, macroStepLine(step)))
+		}
+		if failed > 0 {
+			lines = append(lines, fmt.Sprintf("%d of %d steps failed.", failed, len(macro.Steps)))
+		}
+		return api.EditInteractionResponseData{Content: option.NewNullableString(utility.Substring(strings.Join(lines, "\n"), 0, 2000))}
+	})
+}
+
+// runMacroStep does a single step of a macro, returning what went wrong in words fit for whoever ran it.
+func runMacroStep(state *state.State, event *gateway.InteractionCreateEvent, step storage.MacroStep, userID discord.UserID) error {
+	reason := api.AuditLogReason(fmt.Sprintf("Macro run by %s", event.Sender().Tag()))
+	switch step.Action {
+	case "give", "take":
+		if !userID.IsValid() {
+			return fmt.Errorf("there's nobody to %s it, so pick a user", step.Action)
+		}
+		// The hierarchy may well have changed since the macro was defined, and it's whoever runs it that counts now.
+		if err := utility.CanManageRole(state, event.GuildID, event.Member, step.RoleID); err != nil {
+			if utility.OutOfReach(err) {
+				return err
+			}
+			log.Printf("[%s] /macro run failed to check the hierarchy for <@&%s>: %s", event.GuildID, step.RoleID, err)
+			return fmt.Errorf("I couldn't check the role hierarchy. The error has been logged")
+		}
+		var err error
+		if step.Action == "give" {
+			err = state.AddRole(event.GuildID, userID, step.RoleID, api.AddRoleData{AuditLogReason: reason})
+		} else {
+			err = state.RemoveRole(event.GuildID, userID, step.RoleID, reason)
+		}
+		if err != nil {
+			log.Printf("[%s] /macro run failed to %s <@&%s> for <@%s>: %s", event.GuildID, step.Action, step.RoleID, userID, err)
+			return fmt.Errorf("Discord wouldn't let me. The error has been logged")
+		}
+	case "say":
+		who := userID
+		if !who.IsValid() {
+			who = event.SenderID()
+		}
+		text := utility.RenderTemplate(step.Text, templateValues(state, event.GuildID, step.ChannelID, who, nil))
+		_, err := state.SendMessageComplex(step.ChannelID, api.SendMessageData{
+			Content:         text,
+			AllowedMentions: &api.AllowedMentions{Parse: []api.AllowedMentionType{api.AllowUserMention}},
+		})
+		if err != nil {
+			log.Printf("[%s] /macro run failed to say something in <#%s>: %s", event.GuildID, step.ChannelID, err)
+			return fmt.Errorf("Discord wouldn't let me. The error has been logged")
+		}
+	case "slowmode":
+		err := state.ModifyChannel(step.ChannelID, api.ModifyChannelData{
+			UserRateLimit:  option.NewNullableUint(uint(step.Seconds)),
+			AuditLogReason: reason,
+		})
+		if err != nil {
+			log.Printf("[%s] /macro run failed to set slowmode in <#%s>: %s", event.GuildID, step.ChannelID, err)
+			return fmt.Errorf("Discord wouldn't let me. The error has been logged")
+		}
+	default:
+		return fmt.Errorf("I don't know how to %q", step.Action)
+	}
+	return nil
+}
+
+// SubCommandMacroRemove forgets the macro.
+func SubCommandMacroRemove(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	name := macroName(options)
+	exist, _, err := storage.GetMacro(kvs, event.GuildID, name)
+	if err != nil {
+		log.Printf("[%s] /macro remove failed to look up %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no macro called %s.", name))}
+	}
+	if err := storage.DeleteMacro(kvs, event.GuildID, name); err != nil {
+		log.Printf("[%s] Failed to delete macro %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> removed the macro %s", event.GuildID, event.SenderID(), name)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Forgot the macro %s.", name))}
+}
+
+// SubCommandMacroList lists the macros of the guild, with their steps.
+func SubCommandMacroList(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	macros, err := storage.GetMacros(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /macro list failed to get the macros: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(macros) == 0 {
+		return command.Response{Response: response.Ephemeral("There are no macros here. Make one with `/macro define`.")}
+	}
+	var sb strings.Builder
+	for _, macro := range macros {
+		fmt.Fprintf(&sb, "**%s**, by %s <t:%d:R>\n", macro.Name, macro.CreatedBy.Mention(), macro.Updated)
+		for _, step := range macro.Steps {
+			fmt.Fprintf(&sb, "- `%s`\n", macroStepLine(step))
+		}
+	}
+	return command.Response{Response: response.Ephemeral(utility.Substring(sb.String(), 0, 2000))}
+}
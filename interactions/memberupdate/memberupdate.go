@@ -0,0 +1,35 @@
+package memberupdate
+
+import (
+	"komainu/storage"
+
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+type Handler struct {
+	Code HandlerFunction
+}
+
+type HandlerFunction func(
+	state *state.State,
+	kvs storage.KeyValueStore,
+	event *gateway.GuildMemberUpdateEvent,
+)
+
+var memberupdatehandlers = []Handler{}
+
+// Register makes the Code turn over when a member changes, like getting a new role or nickname
+func Register(handler Handler) {
+	memberupdatehandlers = append(memberupdatehandlers, handler)
+}
+
+// Add the member update handler to the given state
+// This is mostly just pointless abstraction for uniformity across events.
+func AddHandler(state *state.State, kvs storage.KeyValueStore) {
+	state.AddHandler(func(event *gateway.GuildMemberUpdateEvent) {
+		for _, handler := range memberupdatehandlers {
+			handler.Code(state, kvs, event)
+		}
+	})
+}
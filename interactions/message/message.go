@@ -2,15 +2,23 @@ package message
 
 import (
 	"komainu/storage"
+	"log"
 	"regexp"
+	"strings"
+	"sync"
 
 	"github.com/diamondburned/arikawa/v3/gateway"
 	"github.com/diamondburned/arikawa/v3/state"
 )
 
+// IntentMessageContent is the privileged intent that gets the content of messages, mentioning the bot or not.
+// This version of arikawa predates it, so here it is.
+const IntentMessageContent gateway.Intents = 1 << 15
+
 type Handler struct {
-	Code  HandlerFunction
-	Match *regexp.Regexp
+	Code         HandlerFunction
+	Match        *regexp.Regexp
+	NeedsContent string // What stops working without the content of messages, for /status. Blank if it gets by without.
 }
 
 type HandlerFunction func(
@@ -21,15 +29,54 @@ type HandlerFunction func(
 
 var messagehandlers = []Handler{}
 
+// warnOnce keeps every shard from saying what's off, when once is plenty.
+var warnOnce sync.Once
+
 // Register adds a handler for messages
 func Register(handler Handler) {
 	messagehandlers = append(messagehandlers, handler)
 }
 
+// HasContent tells if the bot gets the content of messages. Without it, messages are all blank, apart from those
+// mentioning the bot and those sent to it in private.
+func HasContent(state *state.State) bool {
+	return state.HasIntents(IntentMessageContent)
+}
+
+// Unavailable lists what doesn't work, as the bot doesn't get the content of messages. Empty if it does.
+func Unavailable(state *state.State) []string {
+	if HasContent(state) {
+		return nil
+	}
+	unavailable := []string{}
+	for _, handler := range messagehandlers {
+		if handler.NeedsContent != "" {
+			unavailable = append(unavailable, handler.NeedsContent)
+		}
+	}
+	return unavailable
+}
+
 // Add the message handler to the given state
+// Handlers that need the content of messages are left out if the bot doesn't get it, rather than having them
+// act on blank messages.
 func AddHandler(state *state.State, kvs storage.KeyValueStore) {
-	state.AddHandler(func(event *gateway.MessageCreateEvent) {
+	handlers := messagehandlers
+	if !HasContent(state) {
+		handlers = []Handler{}
 		for _, handler := range messagehandlers {
+			if handler.NeedsContent == "" {
+				handlers = append(handlers, handler)
+			}
+		}
+		warnOnce.Do(func() {
+			if unavailable := Unavailable(state); len(unavailable) > 0 {
+				log.Printf("Not asking Discord for the content of messages, so these are off: %s", strings.Join(unavailable, ", "))
+			}
+		})
+	}
+	state.AddHandler(func(event *gateway.MessageCreateEvent) {
+		for _, handler := range handlers {
 			if handler.Match == nil || handler.Match.MatchString(event.Content) {
 				handler.Code(state, kvs, event)
 			}
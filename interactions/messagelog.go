@@ -0,0 +1,283 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/delete"
+	"komainu/interactions/edit"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// The collection is still named after the delete log, which this grew out of, so the log channel setting carries over.
+const (
+	messageLogCollection = "deletelog"
+	messageLogKey        = "channel"
+	messageLogIgnoreKey  = "ignore"
+)
+
+var messagelogModule = module.Basic{
+	ModuleName: "messagelog",
+	CommandHandlers: map[string]command.Handler{
+		"messagelog": commandMessagelogObject,
+	},
+	Handlers: []module.EventHandler{
+		module.Delete(delete.Handler{Code: DeleteLogging}),
+		module.Edit(edit.Handler{Code: EditLogging}),
+	},
+}
+
+var commandMessagelogObject = command.Handler{
+	Description: "Log edited and deleted messages",
+	Code:        CommandMessagelog,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "channel",
+			Description: "Designate what channel to log edits and deletions in",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:  "channel",
+					Description: "Where to log edits and deletions, blank to disable",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "ignore",
+			Description: "Stop logging edits and deletions in a channel, or start again",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:  "channel",
+					Description: "The channel to toggle",
+					Required:    true,
+				},
+			},
+		},
+	},
+}
+
+// CommandMessagelog processes the /messagelog command, dispatching to the right subcommand.
+func CommandMessagelog(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /messagelog command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "channel":
+		return SubCommandMessagelogChannel(state, kvs, event, cmd.Options[0].Options)
+	case "ignore":
+		return SubCommandMessagelogIgnore(kvs, event, cmd.Options[0].Options)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandMessagelogChannel sets or clears the log channel.
+func SubCommandMessagelogChannel(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	if options.Find("channel").Name == "" {
+		log.Printf("[%s] <@%s> disabled message log functionality", event.GuildID, event.SenderID())
+		err := kvs.Delete(event.GuildID, messageLogCollection, messageLogKey)
+		if err != nil {
+			log.Printf("[%s] Failed to remove Message Log Channel setting: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("Sorry, there was a hickup disabling the message log functionality. The error was logged.")}
+		}
+		return command.Response{Response: response.Message("Okay, I will not log edits and deletions.")}
+	}
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] Message Log setting failed to get snowflake:  %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue setting the message log channel. It has been logged.")}
+	}
+	channelId := discord.ChannelID(channelSnowflake)
+	logChannel, err := state.Channel(channelId)
+	if err != nil {
+		log.Printf("[%s] Message Log setting failed to get channel object: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was a problem setting the message log channel. It has been logged.")}
+	}
+
+	if err := kvs.Set(event.GuildID, messageLogCollection, messageLogKey, channelId); err != nil {
+		log.Printf("[%s] Failed to store Message Log Channel setting: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> set message logging to <#%s>", event.GuildID, event.SenderID(), channelId)
+
+	return command.Response{Response: response.Message(fmt.Sprintf("<#%s> is now the message log channel", logChannel.ID))}
+}
+
+// getMessageLogIgnored gets the channels that edits and deletions are not logged for.
+func getMessageLogIgnored(kvs storage.KeyValueStore, guildID discord.GuildID) (map[discord.ChannelID]bool, error) {
+	ignored := map[discord.ChannelID]bool{}
+	_, err := kvs.Get(guildID, messageLogCollection, messageLogIgnoreKey, &ignored)
+	if ignored == nil {
+		ignored = map[discord.ChannelID]bool{} // gob doesn't bother with empty maps.
+	}
+	return ignored, err
+}
+
+// SubCommandMessagelogIgnore toggles if a channel is left out of the message log.
+func SubCommandMessagelogIgnore(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /messagelog ignore failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	channelID := discord.ChannelID(channelSnowflake)
+	ignored, err := getMessageLogIgnored(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to get Message Log ignored channels: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	reply := "Edits and deletions will no longer be logged for"
+	if ignored[channelID] {
+		// The builtin delete is shadowed by the delete event package here.
+		stillIgnored := map[discord.ChannelID]bool{}
+		for ignoredID := range ignored {
+			if ignoredID != channelID {
+				stillIgnored[ignoredID] = true
+			}
+		}
+		ignored = stillIgnored
+		reply = "Edits and deletions will be logged again for"
+	} else {
+		ignored[channelID] = true
+	}
+	if err := kvs.Set(event.GuildID, messageLogCollection, messageLogIgnoreKey, ignored); err != nil {
+		log.Printf("[%s] Failed to store Message Log ignored channels: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> toggled message logging for <#%s>", event.GuildID, event.SenderID(), channelID)
+	return command.Response{Response: response.Ephemeral(reply, channelID.Mention())}
+}
+
+// messageLogChannel gets the channel to log to, unless there is none or the given channel is ignored.
+func messageLogChannel(kvs storage.KeyValueStore, guildID discord.GuildID, channelID discord.ChannelID) (bool, discord.ChannelID, error) {
+	messageLogChannelID := discord.NullChannelID
+	exist, err := kvs.Get(guildID, messageLogCollection, messageLogKey, &messageLogChannelID)
+	if err != nil || !exist {
+		return false, messageLogChannelID, err
+	}
+	ignored, err := getMessageLogIgnored(kvs, guildID)
+	if err != nil {
+		return false, messageLogChannelID, err
+	}
+	return !ignored[channelID] && channelID != messageLogChannelID, messageLogChannelID, nil
+}
+
+// messageLogTruncate makes the text fit in an embed field.
+func messageLogTruncate(text string) string {
+	if runes := []rune(text); len(runes) > 1000 {
+		return string(runes[:1000]) + "…"
+	}
+	if text == "" {
+		return "*Nothing*"
+	}
+	return text
+}
+
+func DeleteLogging(state *state.State, kvs storage.KeyValueStore, event *gateway.MessageDeleteEvent) {
+	logIt, deleteLogChannelID, err := messageLogChannel(kvs, event.GuildID, event.ChannelID)
+	if err != nil {
+		log.Printf("[%s] Message deleted, but error looking up message log channel ID: %s", event.GuildID, err)
+		return
+	}
+	if !logIt {
+		return
+	}
+	message, err := state.Message(event.ChannelID, event.ID)
+	if err != nil {
+		_, sendErr := state.SendMessage(deleteLogChannelID, fmt.Sprintf("Unknown message %s in <#%s> was deleted. Originally posted <t:%d>", event.ID, event.ChannelID, event.ID.Time().Unix()))
+		if sendErr != nil {
+			log.Printf("[%s] UNKNOWN message deleted, error logging to message log channel: %s", event.GuildID, err)
+		}
+		return
+	}
+
+	metaMessage := fmt.Sprintf("<@%s> had their message in <#%s> deleted. Originally posted <t:%d>", message.Author.ID, message.ChannelID, message.Timestamp.Time().Unix())
+
+	color := discord.Color(0xFF0000)
+
+	origialContent := message.Content
+	if origialContent == "" {
+		origialContent = "No actual message, maybe it was just an image?"
+		color = discord.Color(0xFFFF00)
+	}
+
+	payload := []discord.Embed{
+		{
+			Type:        discord.NormalEmbed,
+			Description: origialContent,
+			Color:       color,
+		},
+	}
+	if len(message.Attachments) > 0 {
+		attachments := make([]string, len(message.Attachments))
+		for i, attachment := range message.Attachments {
+			attachments[i] = fmt.Sprintf("[%s](%s)", attachment.Filename, attachment.URL)
+		}
+		payload[0].Fields = []discord.EmbedField{{Name: "Attachments", Value: messageLogTruncate(strings.Join(attachments, "\n"))}}
+	}
+
+	if _, err := state.SendMessageComplex(deleteLogChannelID, api.SendMessageData{
+		Content: metaMessage,
+		Embeds:  payload,
+		AllowedMentions: &api.AllowedMentions{
+			Parse: []api.AllowedMentionType{},
+		},
+	}); err != nil {
+		log.Printf("[%s] Message deleted, but error logging it: %s", event.GuildID, err)
+	}
+}
+
+// EditLogging posts the before and after of edited messages. It runs before the cache is updated, so the cache still has the before.
+func EditLogging(state *state.State, kvs storage.KeyValueStore, event *gateway.MessageUpdateEvent) {
+	if event.GuildID == discord.NullGuildID {
+		return
+	}
+	before, err := state.Message(event.ChannelID, event.ID)
+	if err != nil {
+		return // Not in the cache, so there is nothing to compare with. Logging every embed unfurl as an edit would be noise.
+	}
+	if before.Author.Bot || before.Content == event.Content {
+		return
+	}
+	logIt, editLogChannelID, err := messageLogChannel(kvs, event.GuildID, event.ChannelID)
+	if err != nil {
+		log.Printf("[%s] Message edited, but error looking up message log channel ID: %s", event.GuildID, err)
+		return
+	}
+	if !logIt {
+		return
+	}
+
+	link := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", event.GuildID, event.ChannelID, event.ID)
+	metaMessage := fmt.Sprintf("<@%s> edited their message in <#%s>. Originally posted <t:%d> %s", before.Author.ID, before.ChannelID, before.Timestamp.Time().Unix(), link)
+	payload := []discord.Embed{
+		{
+			Type:  discord.NormalEmbed,
+			Color: discord.Color(0xFFA500),
+			Fields: []discord.EmbedField{
+				{Name: "Before", Value: messageLogTruncate(before.Content)},
+				{Name: "After", Value: messageLogTruncate(event.Content)},
+			},
+		},
+	}
+
+	if _, err := state.SendMessageComplex(editLogChannelID, api.SendMessageData{
+		Content: metaMessage,
+		Embeds:  payload,
+		AllowedMentions: &api.AllowedMentions{
+			Parse: []api.AllowedMentionType{},
+		},
+	}); err != nil {
+		log.Printf("[%s] Message edited, but error logging it: %s", event.GuildID, err)
+	}
+}
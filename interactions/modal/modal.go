@@ -3,6 +3,8 @@ package modal
 import (
 	"komainu/interactions/command"
 	"komainu/interactions/response"
+	"komainu/locale"
+	"komainu/metrics"
 	"komainu/storage"
 	"log"
 	"time"
@@ -21,79 +23,90 @@ type Handler struct {
 }
 
 type HandlerFunction func(
-	state *state.State,
-	kvs storage.KeyValueStore,
-	event *gateway.InteractionCreateEvent,
+	ctx *command.Context,
 	interaction *discord.ModalInteraction,
+	session map[string]string,
 ) command.Response
 
-type Secret struct {
-	Handler string
-	User    discord.UserID
-	Guild   discord.GuildID
-	Created time.Time
-}
-
+// modalMaxAge is how long someone has to fill in a modal before it's forgotten.
 var modalMaxAge time.Duration = time.Hour * 24
 
 // modals holds the modal handlers to accept.
 var modals = map[string]Handler{}
 
-var modalSecrets = map[string]Secret{}
-
-func init() {
-	go startRemovingStaleSecrets()
-}
-
 // AddHandler adds the modal interactin handler to the given state
 func AddHandler(state *state.State, kvs storage.KeyValueStore) {
 	state.AddHandler(func(e *gateway.InteractionCreateEvent) {
 		if interaction, ok := e.Data.(*discord.ModalInteraction); ok {
-			id := string(interaction.CustomID)
-			if secret, exist := modalSecrets[id]; exist {
-				if secret.User != e.SenderID() {
-					log.Printf("[%s] Modal form submission from WRONG USER: %s, but expected %s", e.GuildID, e.SenderID(), secret.User)
-				}
-				if val, ok := modals[secret.Handler]; ok {
-					response := val.Code(state, kvs, e, interaction)
-					if err := state.RespondInteraction(e.ID, e.Token, response.Response); err != nil {
-						log.Printf("[%s] Failed to send modal interaction response: %s", e.GuildID, err)
-					}
-					if response.Callback != nil {
-						message, err := state.InteractionResponse(e.AppID, e.Token)
-						if err != nil {
-							log.Printf("Error %s getting message reference for %s modal callback\n", err, id)
-							return
-						}
-						if message != nil && message.ID != discord.NullMessageID {
-							response.Callback(message)
-						}
-					}
-				} else {
-					log.Printf("[%s] has UNKNOWN modal interaction %#v", e.GuildID, secret)
-				}
-				delete(modalSecrets, id)
-			} else {
-				log.Printf("[%s] expired/invalid modal token %s used by %s\n", e.GuildID, id, e.SenderID())
-				if err := state.RespondInteraction(e.ID, e.Token, response.Ephemeral("Sorry, access was denied. Took too long to respond?")); err != nil {
-					log.Printf("[%s] ...and there was an error telling them their token expired: %s", e.GuildID, err)
-				}
-				return
-			}
+			received := time.Now()
+			command.Enqueue(state, kvs, e, "modal", func() {
+				handleModal(state, kvs, e, interaction, received)
+			})
 		}
 	})
 }
 
-func startRemovingStaleSecrets() {
-	ticker := time.NewTicker(1 * time.Minute)
-	for {
-		<-ticker.C
-		now := time.Now()
-		for key, secret := range modalSecrets {
-			if now.Sub(secret.Created) > modalMaxAge {
-				delete(modalSecrets, key)
+// handleModal handles a modal interaction, on one of the workers.
+func handleModal(state *state.State, kvs storage.KeyValueStore, e *gateway.InteractionCreateEvent, interaction *discord.ModalInteraction, received time.Time) {
+	id := string(interaction.CustomID)
+	scope := command.Scope(e)
+	exist, session, err := storage.GetModalSession(kvs, scope, id)
+	if err != nil {
+		log.Printf("[%s] Failed to get modal session %s: %s", e.GuildID, id, err)
+	}
+	if exist {
+		if session.UserID != e.SenderID() {
+			// The session is left alone, so whoever it's for can still submit it.
+			log.Printf("[%s] Modal form submission from WRONG USER: %s, but expected %s", e.GuildID, e.SenderID(), session.UserID)
+			if err := state.RespondInteraction(e.ID, e.Token, response.Ephemeral(locale.Translate(locale.ForGuild(kvs, e.GuildID), "Sorry, that form isn't yours to fill in."))); err != nil {
+				log.Printf("[%s] ...and there was an error telling them so: %s", e.GuildID, err)
 			}
+			return
 		}
+		if val, ok := modals[session.Handler]; ok {
+			ctx := command.NewContext(state, kvs, e, received, "modal", session.Handler)
+			logger := ctx.Logger
+			start := time.Now()
+			resp, ok := command.Run(ctx, func() command.Response { return val.Code(ctx, interaction, session.Data) }, command.Response{Response: response.Ephemeral("An error occured, and has been logged.")})
+			if !ok {
+				logger.Error("Modal took too long to respond, and was given up on", "after", time.Since(start))
+				metrics.Errors.Inc("modal")
+				return
+			}
+			metrics.Interactions.Inc("modal", session.Handler)
+			metrics.HandlerSeconds.Since(start, "modal", session.Handler)
+			logger.Debug("Modal handled", "duration", time.Since(start))
+			if response.IsError(resp.Response) {
+				logger.Error("Modal responded with an error", "response", resp.Response.Data.Content.Val)
+				metrics.Errors.Inc("modal")
+			}
+			locale.TranslateResponse(locale.ForGuild(kvs, e.GuildID), &resp.Response)
+			if err := state.RespondInteraction(e.ID, e.Token, resp.Response); err != nil {
+				logger.Error("Failed to send modal interaction response", "error", err)
+				metrics.Errors.Inc("modal")
+			}
+			if resp.Callback != nil {
+				message, err := state.InteractionResponse(e.AppID, e.Token)
+				if err != nil {
+					logger.Error("Failed to get message reference for modal callback", "error", err)
+					return
+				}
+				if message != nil && message.ID != discord.NullMessageID {
+					command.Protect(logger, func() { resp.Callback(message) })
+				}
+			}
+		} else {
+			log.Printf("[%s] has UNKNOWN modal interaction %#v", e.GuildID, session)
+		}
+		if err := storage.ForgetModalSession(kvs, scope, id); err != nil {
+			log.Printf("[%s] Failed to forget modal session %s: %s", e.GuildID, id, err)
+		}
+	} else {
+		log.Printf("[%s] expired/invalid modal token %s used by %s\n", e.GuildID, id, e.SenderID())
+		if err := state.RespondInteraction(e.ID, e.Token, response.Ephemeral(locale.Translate(locale.ForGuild(kvs, e.GuildID), "Sorry, access was denied. Took too long to respond?"))); err != nil {
+			log.Printf("[%s] ...and there was an error telling them their token expired: %s", e.GuildID, err)
+		}
+		return
 	}
 }
 
@@ -131,19 +144,30 @@ func decodeActionRowComponent(arc discord.ActionRowComponent, response map[strin
 	}
 }
 
-func Respond(user discord.UserID, guild discord.GuildID, name string, title string, tics ...discord.TextInputComponent) api.InteractionResponse {
-	id := uuid.New().String()
-	modalSecrets[id] = Secret{
+// Respond opens a modal with the given text inputs, to be handled by the named handler once submitted.
+// Whatever is in data is kept until then, and handed to the handler along with what was filled in.
+func Respond(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, name string, title string, data map[string]string, tics ...discord.TextInputComponent) api.InteractionResponse {
+	scope := command.Scope(event)
+	if err := storage.ForgetExpiredModalSessions(kvs, scope); err != nil {
+		log.Printf("[%s] Failed to forget expired modal sessions: %s", event.GuildID, err)
+	}
+	session := storage.ModalSession{
+		ID:      uuid.New().String(), // Random, so nobody can guess the ID of someone else's session.
+		GuildID: scope,
+		UserID:  event.SenderID(),
 		Handler: name,
-		User:    user,
-		Guild:   guild,
-		Created: time.Now(),
+		Data:    data,
+		Expires: time.Now().Add(modalMaxAge).Unix(),
+	}
+	if err := session.Store(kvs); err != nil {
+		log.Printf("[%s] Failed to store %s modal session: %s", event.GuildID, name, err)
+		return response.Ephemeral("An error occured, and has been logged.")
 	}
 	return api.InteractionResponse{
 		Type: api.ModalResponse,
 		Data: &api.InteractionResponseData{
 			Title:      option.NewNullableString(title),
-			CustomID:   option.NewNullableString(id),
+			CustomID:   option.NewNullableString(session.ID),
 			Components: generateModalComponents(tics),
 		},
 	}
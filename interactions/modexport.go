@@ -0,0 +1,212 @@
+package interactions
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"github.com/diamondburned/arikawa/v3/utils/sendpart"
+)
+
+// modexportAuditPages is how many pages of 100 audit log entries are looked through for each kind of action.
+// Discord only keeps them for 45 days anyway.
+const modexportAuditPages = 5
+
+var modexportModule = module.Basic{
+	ModuleName: "modexport",
+	CommandHandlers: map[string]command.Handler{
+		"modexport": commandModexportObject,
+	},
+}
+
+var commandModexportObject = command.Handler{
+	Description: "Export someone's moderation history as a file",
+	Code:        CommandModexport,
+	Options: []discord.CommandOption{
+		&discord.UserOption{
+			OptionName:  "user",
+			Description: "Whose history to export",
+			Required:    true,
+		},
+		&discord.StringOption{
+			OptionName:  "format",
+			Description: "What kind of file. Default is JSON.",
+			Required:    false,
+			Choices: []discord.StringChoice{
+				{Name: "JSON", Value: "json"},
+				{Name: "CSV", Value: "csv"},
+			},
+		},
+	},
+}
+
+// modRecord is a single thing that happened to someone, as far as moderation is concerned.
+type modRecord struct {
+	When      time.Time      `json:"when"`
+	Kind      string         `json:"kind"`
+	Moderator discord.UserID `json:"moderator"`
+	Reason    string         `json:"reason"`
+	Detail    string         `json:"detail,omitempty"`
+}
+
+type modExport struct {
+	GuildID    discord.GuildID `json:"guild"`
+	UserID     discord.UserID  `json:"user"`
+	Exported   time.Time       `json:"exported"`
+	ExportedBy discord.UserID  `json:"exported_by"`
+	Records    []modRecord     `json:"records"`
+}
+
+// CommandModexport processes the /modexport command.
+func CommandModexport(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	userSnowflake, err := cmd.Options.Find("user").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /modexport failed to get user snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the user. It has been logged.")}
+	}
+	userID := discord.UserID(userSnowflake)
+	format := cmd.Options.Find("format").String()
+	if format == "" {
+		format = "json"
+	}
+
+	return command.DeferredData(state, event, true, func() api.EditInteractionResponseData {
+		export := modExport{
+			GuildID:    event.GuildID,
+			UserID:     userID,
+			Exported:   time.Now().UTC(),
+			ExportedBy: event.SenderID(),
+			Records:    []modRecord{},
+		}
+		warnings, err := storage.GetWarnings(kvs, event.GuildID, userID)
+		if err != nil {
+			log.Printf("[%s] /modexport failed to get warnings for <@%s>: %s", event.GuildID, userID, err)
+			return api.EditInteractionResponseData{Content: option.NewNullableString("An error occured, and has been logged.")}
+		}
+		for _, warning := range warnings {
+			export.Records = append(export.Records, modRecord{
+				When:      time.Unix(warning.Created, 0).UTC(),
+				Kind:      "warning",
+				Moderator: warning.Moderator,
+				Reason:    warning.Reason,
+			})
+		}
+		notice := ""
+		audited, err := modexportAuditLog(state, event.GuildID, userID)
+		if err != nil {
+			log.Printf("[%s] /modexport failed to read the audit log for <@%s>: %s", event.GuildID, userID, err)
+			notice = "\nI couldn't read the audit log, so timeouts, kicks and bans are missing. Do I have permission to view it?"
+		}
+		export.Records = append(export.Records, audited...)
+		sort.Slice(export.Records, func(i, j int) bool {
+			return export.Records[i].When.Before(export.Records[j].When)
+		})
+
+		var buf bytes.Buffer
+		if format == "csv" {
+			err = writeModExportCSV(&buf, export)
+		} else {
+			encoder := json.NewEncoder(&buf)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(export)
+		}
+		if err != nil {
+			log.Printf("[%s] /modexport failed to encode the export for <@%s>: %s", event.GuildID, userID, err)
+			return api.EditInteractionResponseData{Content: option.NewNullableString("An error occured, and has been logged.")}
+		}
+
+		log.Printf("[%s] <@%s> exported the moderation history of <@%s>, %d records", event.GuildID, event.SenderID(), userID, len(export.Records))
+		return api.EditInteractionResponseData{
+			Content: option.NewNullableString(fmt.Sprintf("Moderation history of %s: %d records.%s", userID.Mention(), len(export.Records), notice)),
+			Files: []sendpart.File{{
+				Name:   fmt.Sprintf("modexport-%s.%s", userID, format),
+				Reader: &buf,
+			}},
+		}
+	})
+}
+
+func writeModExportCSV(buf *bytes.Buffer, export modExport) error {
+	writer := csv.NewWriter(buf)
+	writer.Write([]string{"when", "kind", "moderator", "reason", "detail"})
+	for _, record := range export.Records {
+		writer.Write([]string{record.When.Format(time.RFC3339), record.Kind, record.Moderator.String(), record.Reason, record.Detail})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// modexportAuditLog digs the timeouts, kicks and bans of the user out of the audit log.
+func modexportAuditLog(state *state.State, guildID discord.GuildID, userID discord.UserID) ([]modRecord, error) {
+	kinds := map[discord.AuditLogEvent]string{
+		discord.MemberKick:      "kick",
+		discord.MemberBanAdd:    "ban",
+		discord.MemberBanRemove: "unban",
+		discord.MemberUpdate:    "timeout",
+	}
+	records := []modRecord{}
+	for actionType, kind := range kinds {
+		before := discord.AuditLogEntryID(0)
+		for page := 0; page < modexportAuditPages; page++ {
+			auditLog, err := state.AuditLog(guildID, api.AuditLogData{
+				ActionType: actionType,
+				Before:     before,
+				Limit:      100,
+			})
+			if err != nil {
+				return records, err
+			}
+			for _, entry := range auditLog.Entries {
+				before = entry.ID
+				if discord.UserID(entry.TargetID) != userID {
+					continue
+				}
+				record := modRecord{
+					When:      entry.CreatedAt().UTC(),
+					Kind:      kind,
+					Moderator: entry.UserID,
+					Reason:    entry.Reason,
+				}
+				if actionType == discord.MemberUpdate {
+					var ok bool
+					if record.Kind, record.Detail, ok = modexportTimeoutChange(entry); !ok {
+						continue // Some other change to the member, like a nickname.
+					}
+				}
+				records = append(records, record)
+			}
+			if len(auditLog.Entries) < 100 {
+				break
+			}
+		}
+	}
+	return records, nil
+}
+
+// modexportTimeoutChange figures out if the member update was a timeout, and if so if it was given or taken away.
+func modexportTimeoutChange(entry discord.AuditLogEntry) (kind string, detail string, ok bool) {
+	for _, change := range entry.Changes {
+		if change.Key != "communication_disabled_until" {
+			continue
+		}
+		var until *discord.Timestamp
+		if err := change.NewValue.UnmarshalTo(&until); err != nil || until == nil || !until.IsValid() {
+			return "timeout removed", "", true
+		}
+		return "timeout", "until " + until.Time().UTC().Format(time.RFC3339), true
+	}
+	return "", "", false
+}
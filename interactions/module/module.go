@@ -0,0 +1,264 @@
+// Package module is how features are added to the bot. Each feature is a Module, with the commands, components and
+// event handlers it needs, and anything it needs done to storage first. Modules are registered, and then loaded, which
+// hooks up everything belonging to the ones that are turned on. A package outside the bot can add a feature the same
+// way, by registering its own Module before the bot connects.
+package module
+
+import (
+	"fmt"
+	"komainu/interactions/autocomplete"
+	"komainu/interactions/command"
+	"komainu/interactions/component"
+	"komainu/interactions/confirm"
+	"komainu/interactions/delete"
+	"komainu/interactions/edit"
+	"komainu/interactions/guildcreate"
+	"komainu/interactions/join"
+	"komainu/interactions/leave"
+	"komainu/interactions/memberupdate"
+	"komainu/interactions/message"
+	"komainu/interactions/modal"
+	"komainu/interactions/pins"
+	"komainu/interactions/presence"
+	"komainu/interactions/reaction"
+	"komainu/interactions/thread"
+	"komainu/interactions/timer"
+	"komainu/interactions/voice"
+	"komainu/storage"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Module is a feature of the bot, that can be turned on or off as a whole.
+type Module interface {
+	Name() string
+	Commands() map[string]command.Handler
+	Components() map[string]component.Handler
+	EventHandlers() []EventHandler
+	Migrations() []Migration
+}
+
+// EventHandler hooks a module up to something other than its commands and components, like messages being sent or
+// timers going off. Make them with the functions named after what they handle, like Message or Timer.
+type EventHandler struct {
+	Kind     string // What it handles, like "message" or "timer selftest".
+	register func()
+}
+
+// Migration is something that has to be done to storage once, before the module can use it, like moving things around
+// after changing how they are stored. Each is run once, the first time the module is loaded with it.
+type Migration struct {
+	ID  string // Unique within the module, and never changed, as that's how it's known to be done.
+	Run func(kvs storage.KeyValueStore) error
+}
+
+// Basic is a Module that is just what it's made with, which covers most of them.
+type Basic struct {
+	ModuleName        string
+	CommandHandlers   map[string]command.Handler
+	ComponentHandlers map[string]component.Handler
+	Handlers          []EventHandler
+	MigrationSteps    []Migration
+}
+
+func (b Basic) Name() string                             { return b.ModuleName }
+func (b Basic) Commands() map[string]command.Handler     { return b.CommandHandlers }
+func (b Basic) Components() map[string]component.Handler { return b.ComponentHandlers }
+func (b Basic) EventHandlers() []EventHandler            { return b.Handlers }
+func (b Basic) Migrations() []Migration                  { return b.MigrationSteps }
+
+// Message hooks the handler up to messages being sent.
+func Message(handler message.Handler) EventHandler {
+	return EventHandler{"message", func() { message.Register(handler) }}
+}
+
+// Join hooks the handler up to members joining.
+func Join(handler join.Handler) EventHandler {
+	return EventHandler{"join", func() { join.Register(handler) }}
+}
+
+// Leave hooks the handler up to members leaving.
+func Leave(handler leave.Handler) EventHandler {
+	return EventHandler{"leave", func() { leave.Register(handler) }}
+}
+
+// Delete hooks the handler up to messages being deleted.
+func Delete(handler delete.Handler) EventHandler {
+	return EventHandler{"delete", func() { delete.Register(handler) }}
+}
+
+// Edit hooks the handler up to messages being edited.
+func Edit(handler edit.Handler) EventHandler {
+	return EventHandler{"edit", func() { edit.Register(handler) }}
+}
+
+// Reaction hooks the handler up to reactions being added and removed.
+func Reaction(handler reaction.Handler) EventHandler {
+	return EventHandler{"reaction", func() { reaction.Register(handler) }}
+}
+
+// Voice hooks the handler up to members moving between voice channels.
+func Voice(handler voice.Handler) EventHandler {
+	return EventHandler{"voice", func() { voice.Register(handler) }}
+}
+
+// Thread hooks the handler up to threads being created and changed.
+func Thread(handler thread.Handler) EventHandler {
+	return EventHandler{"thread", func() { thread.Register(handler) }}
+}
+
+// Pins hooks the handler up to messages being pinned and unpinned.
+func Pins(handler pins.Handler) EventHandler {
+	return EventHandler{"pins", func() { pins.Register(handler) }}
+}
+
+// Presence hooks the handler up to presences changing.
+func Presence(handler presence.Handler) EventHandler {
+	return EventHandler{"presence", func() { presence.Register(handler) }}
+}
+
+// MemberUpdate hooks the handler up to members being changed, like getting a role.
+func MemberUpdate(handler memberupdate.Handler) EventHandler {
+	return EventHandler{"memberupdate", func() { memberupdate.Register(handler) }}
+}
+
+// GuildCreate hooks the handler up to guilds becoming available, like when the bot starts.
+func GuildCreate(handler guildcreate.Handler) EventHandler {
+	return EventHandler{"guildcreate", func() { guildcreate.Register(handler) }}
+}
+
+// Modal handles the modal with the name being submitted.
+func Modal(name string, handler modal.Handler) EventHandler {
+	return EventHandler{"modal " + name, func() { modal.Register(name, handler) }}
+}
+
+// Timer handles timers of the kind going off.
+func Timer(kind string, handler timer.Handler) EventHandler {
+	return EventHandler{"timer " + kind, func() { timer.Register(kind, handler) }}
+}
+
+// Autocomplete suggests values for the named command.
+func Autocomplete(name string, handler autocomplete.Handler) EventHandler {
+	return EventHandler{"autocomplete " + name, func() { autocomplete.Register(name, handler) }}
+}
+
+// Confirm handles the action being confirmed.
+func Confirm(action string, handler confirm.Handler) EventHandler {
+	return EventHandler{"confirm " + action, func() { confirm.Register(action, handler) }}
+}
+
+// GuildCommands adds commands that only exist in some guilds, like the ones a guild made itself.
+func GuildCommands(source command.GuildCommandSource, code command.Command) EventHandler {
+	return EventHandler{"guild commands", func() { command.RegisterGuild(source, code) }}
+}
+
+// Hook is for anything else, like adding to something the module's package keeps track of itself.
+func Hook(kind string, register func()) EventHandler {
+	return EventHandler{kind, register}
+}
+
+var modules = []Module{}
+var loaded = map[string]bool{}
+
+// Register adds modules to the bot. They do nothing until they are loaded. Names have to be unique.
+func Register(added ...Module) {
+	for _, m := range added {
+		for _, existing := range modules {
+			if existing.Name() == m.Name() {
+				panic(fmt.Sprintf("there is already a module called %q", m.Name()))
+			}
+		}
+		modules = append(modules, m)
+	}
+}
+
+// All lists every registered module, in the order they were registered.
+func All() []Module {
+	return append([]Module{}, modules...)
+}
+
+// Loaded tells if the named module was loaded, which is to say turned on.
+func Loaded(name string) bool {
+	return loaded[name]
+}
+
+// Load hooks up everything belonging to the registered modules that are turned on, and runs any migrations they have
+// that haven't been run yet. It's done once, before connecting, as the commands are registered with Discord then.
+func Load(kvs storage.KeyValueStore, enabled func(name string) bool) error {
+	for _, m := range modules {
+		if !enabled(m.Name()) {
+			log.Printf("The %s module is turned off", m.Name())
+			continue
+		}
+		if err := migrate(kvs, m); err != nil {
+			return fmt.Errorf("the %s module: %w", m.Name(), err)
+		}
+		for name, handler := range m.Commands() {
+			command.Register(name, handler)
+		}
+		for name, handler := range m.Components() {
+			component.Register(name, handler)
+		}
+		for _, handler := range m.EventHandlers() {
+			handler.register()
+		}
+		loaded[m.Name()] = true
+	}
+	return nil
+}
+
+// migrate runs the migrations of the module that haven't been run yet, noting each as done when it is.
+func migrate(kvs storage.KeyValueStore, m Module) error {
+	for _, migration := range m.Migrations() {
+		key := m.Name() + "/" + migration.ID
+		var done int64
+		exist, err := kvs.Get(storage.GlobalScope, "migrations", key, &done)
+		if err != nil {
+			return fmt.Errorf("could not check migration %s: %w", migration.ID, err)
+		}
+		if exist {
+			continue
+		}
+		log.Printf("Running migration %s", key)
+		if err := migration.Run(kvs); err != nil {
+			return fmt.Errorf("migration %s failed: %w", migration.ID, err)
+		}
+		if err := kvs.Set(storage.GlobalScope, "migrations", key, time.Now().Unix()); err != nil {
+			return fmt.Errorf("could not note migration %s as done: %w", migration.ID, err)
+		}
+	}
+	return nil
+}
+
+// Describe lists what the module has, for people to read, like "3 commands, 1 component, message, timer drop".
+func Describe(m Module) string {
+	parts := []string{}
+	if count := len(m.Commands()); count > 0 {
+		parts = append(parts, plural(count, "command"))
+	}
+	if count := len(m.Components()); count > 0 {
+		parts = append(parts, plural(count, "component"))
+	}
+	kinds := []string{}
+	for _, handler := range m.EventHandlers() {
+		kinds = append(kinds, handler.Kind)
+	}
+	sort.Strings(kinds)
+	parts = append(parts, kinds...)
+	if count := len(m.Migrations()); count > 0 {
+		parts = append(parts, plural(count, "migration"))
+	}
+	if len(parts) == 0 {
+		return "nothing at all"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func plural(count int, thing string) string {
+	if count == 1 {
+		return "1 " + thing
+	}
+	return fmt.Sprintf("%d %ss", count, thing)
+}
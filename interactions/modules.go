@@ -0,0 +1,60 @@
+package interactions
+
+import "komainu/interactions/module"
+
+// Modules lists the modules that come with the bot, in the order their handlers are hooked up.
+func Modules() []module.Module {
+	return []module.Module{
+		adminModule,
+		announceModule,
+		antispamModule,
+		archiveModule,
+		ateBallModule,
+		automodModule,
+		broadcastModule,
+		calendarModule,
+		configModule,
+		countingModule,
+		customCommandModule,
+		debugModule,
+		dropModule,
+		eventModule,
+		faqModule,
+		feedModule,
+		gitHubModule,
+		helpModule,
+		lockdownModule,
+		macroModule,
+		messagelogModule,
+		modexportModule,
+		nameHistoryModule,
+		noteModule,
+		permModule,
+		permAuditModule,
+		pinboardModule,
+		pollModule,
+		reportModule,
+		roleMassModule,
+		roleMenuModule,
+		rolesModule,
+		sayModule,
+		seenModule,
+		shareModule,
+		slowmodeModule,
+		starboardModule,
+		statsModule,
+		statusModule,
+		stickyRolesModule,
+		streamModule,
+		suggestionModule,
+		threadsModule,
+		ticketModule,
+		timelineModule,
+		trafficLogModule,
+		verifyModule,
+		voiceLobbyModule,
+		voiceStatsModule,
+		voteModule,
+		xpModule,
+	}
+}
@@ -0,0 +1,104 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/join"
+	"komainu/interactions/memberupdate"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// Discord only sends UserUpdate for the bot's own account, so username changes are picked up from member updates instead.
+// Those are sent to every guild the user shares with the bot when they change their username.
+var nameHistoryModule = module.Basic{
+	ModuleName: "namehistory",
+	CommandHandlers: map[string]command.Handler{
+		"namehistory": commandNameHistoryObject,
+	},
+	Handlers: []module.EventHandler{
+		module.MemberUpdate(memberupdate.Handler{Code: MemberUpdateNameHistory}),
+		module.Join(join.Handler{Code: JoinNameHistory}),
+		module.Hook("timeline source", func() { registerTimelineSource(timelineNames) }),
+	},
+}
+
+var commandNameHistoryObject = command.Handler{
+	Description: "Show the names someone has had",
+	Code:        CommandNameHistory,
+	Options: []discord.CommandOption{
+		&discord.UserOption{
+			OptionName:  "user",
+			Description: "Whose names to show",
+			Required:    true,
+		},
+	},
+}
+
+// describeName formats a name record like "username (nickname)".
+func describeName(record storage.NameRecord) string {
+	if record.Nick == "" {
+		return fmt.Sprintf("`%s`", record.Username)
+	}
+	return fmt.Sprintf("`%s` (`%s`)", record.Username, record.Nick)
+}
+
+// CommandNameHistory processes the /namehistory command.
+func CommandNameHistory(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	userSnowflake, err := cmd.Options.Find("user").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /namehistory failed to get user snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the user. It has been logged.")}
+	}
+	userID := discord.UserID(userSnowflake)
+	history, err := storage.GetNameHistory(kvs, event.GuildID, userID)
+	if err != nil {
+		log.Printf("[%s] /namehistory failed to get the names of <@%s>: %s", event.GuildID, userID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(history) == 0 {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("I haven't seen %s change their name, or join, since I started keeping track.", userID.Mention()))}
+	}
+	lines := make([]string, len(history))
+	for i, record := range history {
+		lines[len(history)-1-i] = fmt.Sprintf("<t:%d:f> %s", record.When, describeName(record))
+	}
+	return command.Response{Response: response.EphemeralShareable(fmt.Sprintf("Names of %s, newest first:\n%s", userID.Mention(), strings.Join(lines, "\n")))}
+}
+
+// MemberUpdateNameHistory records nickname and username changes.
+func MemberUpdateNameHistory(state *state.State, kvs storage.KeyValueStore, event *gateway.GuildMemberUpdateEvent) {
+	if _, err := storage.RecordName(kvs, event.GuildID, event.User.ID, event.User.Username, event.Nick); err != nil {
+		log.Printf("[%s] Failed to record the name of <@%s>: %s", event.GuildID, event.User.ID, err)
+	}
+}
+
+// JoinNameHistory records the name people have when they arrive.
+func JoinNameHistory(state *state.State, kvs storage.KeyValueStore, event *gateway.GuildMemberAddEvent) {
+	if _, err := storage.RecordName(kvs, event.GuildID, event.User.ID, event.User.Username, event.Nick); err != nil {
+		log.Printf("[%s] Failed to record the name of <@%s>: %s", event.GuildID, event.User.ID, err)
+	}
+}
+
+func timelineNames(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID, userID discord.UserID) ([]timelineEntry, error) {
+	history, err := storage.GetNameHistory(kvs, guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	entries := []timelineEntry{}
+	for _, record := range history {
+		entries = append(entries, timelineEntry{
+			When: record.When,
+			Text: "Called " + describeName(record),
+		})
+	}
+	return entries, nil
+}
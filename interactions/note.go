@@ -0,0 +1,254 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+// noteMaxLength is how long a note can be. Anything longer belongs in a ticket.
+const noteMaxLength = 1000
+
+// staffInfoShown is how many of the newest warnings and notes "Staff info" shows. /note list and /timeline have the rest.
+const staffInfoShown = 5
+
+var noteModule = module.Basic{
+	ModuleName: "note",
+	CommandHandlers: map[string]command.Handler{
+		"note": commandNoteObject,
+		"Staff info": command.Handler{
+			Type: discord.UserCommand,
+			Code: CommandStaffInfo,
+		},
+	},
+	Handlers: []module.EventHandler{
+		module.Hook("timeline source", func() { registerTimelineSource(timelineNotes) }),
+	},
+}
+
+var commandNoteObject = command.Handler{
+	Description: "Keep private notes about people, for the staff only",
+	Code:        CommandNote,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "add",
+			Description: "Write down a note about someone",
+			Options: []discord.CommandOptionValue{
+				&discord.UserOption{
+					OptionName:  "user",
+					Description: "Who the note is about",
+					Required:    true,
+				},
+				&discord.StringOption{
+					OptionName:  "text",
+					Description: "What to write down",
+					Required:    true,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "list",
+			Description: "List the notes about someone",
+			Options: []discord.CommandOptionValue{
+				&discord.UserOption{
+					OptionName:  "user",
+					Description: "Who to list the notes about",
+					Required:    true,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "remove",
+			Description: "Throw away a note",
+			Options: []discord.CommandOptionValue{
+				&discord.IntegerOption{
+					OptionName:  "number",
+					Description: "The number of the note, as seen in /note list",
+					Required:    true,
+					Min:         option.NewInt(1),
+				},
+			},
+		},
+	},
+}
+
+// CommandNote processes the /note command, dispatching to the right subcommand.
+func CommandNote(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /note command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "add":
+		return SubCommandNoteAdd(kvs, event, cmd.Options[0].Options)
+	case "list":
+		return SubCommandNoteList(kvs, event, cmd.Options[0].Options)
+	case "remove":
+		return SubCommandNoteRemove(kvs, event, cmd.Options[0].Options)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandNoteAdd writes down a note about someone.
+func SubCommandNoteAdd(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	userSnowflake, err := options.Find("user").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /note add failed to get user snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the user. It has been logged.")}
+	}
+	userID := discord.UserID(userSnowflake)
+	text := strings.TrimSpace(options.Find("text").String())
+	if text == "" {
+		return command.Response{Response: response.Ephemeral("A note has to say something.")}
+	}
+	if len([]rune(text)) > noteMaxLength {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("That's too long for a note. Keep it under %d characters.", noteMaxLength))}
+	}
+	note, err := storage.AddNote(kvs, event.GuildID, userID, event.SenderID(), text)
+	if err != nil {
+		log.Printf("[%s] /note add failed to store a note about <@%s>: %s", event.GuildID, userID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> added note %d about <@%s>", event.GuildID, event.SenderID(), note.ID, userID)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Noted as number **%d**. Only the staff can see it.", note.ID))}
+}
+
+// SubCommandNoteList lists the notes about someone.
+func SubCommandNoteList(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	userSnowflake, err := options.Find("user").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /note list failed to get user snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the user. It has been logged.")}
+	}
+	userID := discord.UserID(userSnowflake)
+	notes, err := storage.GetNotes(kvs, event.GuildID, userID)
+	if err != nil {
+		log.Printf("[%s] /note list failed to get the notes about <@%s>: %s", event.GuildID, userID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(notes) == 0 {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There are no notes about %s.", userID.Mention()))}
+	}
+	lines := make([]string, len(notes))
+	for i, note := range notes {
+		lines[i] = describeNote(note)
+	}
+	content := fmt.Sprintf("Notes about %s:\n%s", userID.Mention(), strings.Join(lines, "\n"))
+	if len([]rune(content)) > 2000 {
+		return command.Response{Response: response.EphemeralAttachFile(
+			fmt.Sprintf("There are %d notes about %s, which is too much to show here, so they're in the file.", len(notes), userID.Mention()),
+			fmt.Sprintf("notes-%s.txt", userID),
+			strings.NewReader(strings.Join(lines, "\n")+"\n"),
+		)}
+	}
+	return command.Response{Response: response.Ephemeral(content)}
+}
+
+// SubCommandNoteRemove throws away a note.
+func SubCommandNoteRemove(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	number, err := options.Find("number").IntValue()
+	if err != nil {
+		log.Printf("[%s] /note remove failed to get the number: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	exist, note, err := storage.GetNote(kvs, event.GuildID, number)
+	if err != nil {
+		log.Printf("[%s] /note remove failed to get note %d: %s", event.GuildID, number, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no note %d.", number))}
+	}
+	if err := storage.DeleteNote(kvs, event.GuildID, number); err != nil {
+		log.Printf("[%s] /note remove failed to delete note %d: %s", event.GuildID, number, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> removed note %d about <@%s>", event.GuildID, event.SenderID(), note.ID, note.UserID)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Note %d about %s is gone.", note.ID, note.UserID.Mention()))}
+}
+
+// CommandStaffInfo shows what the staff knows about someone: when they joined and were last seen, their warnings and the notes about them.
+func CommandStaffInfo(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	userID := cmd.TargetUserID()
+	lines := []string{fmt.Sprintf("**Staff info for %s**", userID.Mention())}
+
+	if member, err := state.Member(event.GuildID, userID); err == nil {
+		lines = append(lines, fmt.Sprintf("Joined <t:%d:R>.", member.Joined.Time().Unix()))
+	} else {
+		lines = append(lines, "Is not currently a member of this server.")
+	}
+	seen, when, err := storage.LastSeen(kvs, event.GuildID, userID)
+	if err != nil {
+		log.Printf("[%s] Staff info failed to get when <@%s> was seen: %s", event.GuildID, userID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if seen {
+		lines = append(lines, fmt.Sprintf("Last seen <t:%d:R>.", when))
+	} else {
+		lines = append(lines, "Has never been seen saying anything.")
+	}
+
+	warnings, err := storage.GetWarnings(kvs, event.GuildID, userID)
+	if err != nil {
+		log.Printf("[%s] Staff info failed to get the warnings of <@%s>: %s", event.GuildID, userID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	lines = append(lines, "", fmt.Sprintf("**Warnings:** %d", len(warnings)))
+	if len(warnings) > staffInfoShown {
+		warnings = warnings[len(warnings)-staffInfoShown:]
+	}
+	for _, warning := range warnings {
+		lines = append(lines, fmt.Sprintf("<t:%d:d> by %s: %s", warning.Created, warning.Moderator.Mention(), utility.Substring(warning.Reason, 0, 200)))
+	}
+
+	notes, err := storage.GetNotes(kvs, event.GuildID, userID)
+	if err != nil {
+		log.Printf("[%s] Staff info failed to get the notes about <@%s>: %s", event.GuildID, userID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	lines = append(lines, "", fmt.Sprintf("**Notes:** %d", len(notes)))
+	if len(notes) > staffInfoShown {
+		lines = append(lines, fmt.Sprintf("Only the newest %d are shown here. `/note list` has them all.", staffInfoShown))
+		notes = notes[len(notes)-staffInfoShown:]
+	}
+	for _, note := range notes {
+		note.Text = utility.Substring(note.Text, 0, 200)
+		lines = append(lines, describeNote(note))
+	}
+
+	log.Printf("[%s] <@%s> looked at the staff info of <@%s>", event.GuildID, event.SenderID(), userID)
+	return command.Response{Response: response.Ephemeral(utility.Substring(strings.Join(lines, "\n"), 0, 2000))}
+}
+
+// describeNote is a note as a single line, with the number to remove it by.
+func describeNote(note storage.Note) string {
+	return fmt.Sprintf("**%d**: <t:%d:d> by %s: %s", note.ID, note.Created, note.Author.Mention(), note.Text)
+}
+
+func timelineNotes(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID, userID discord.UserID) ([]timelineEntry, error) {
+	notes, err := storage.GetNotes(kvs, guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	entries := []timelineEntry{}
+	for _, note := range notes {
+		entries = append(entries, timelineEntry{
+			When: note.Created,
+			Text: fmt.Sprintf("Note by %s: %s", note.Author.Mention(), note.Text),
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,172 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/confirm"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+var permModule = module.Basic{
+	ModuleName: "perm",
+	CommandHandlers: map[string]command.Handler{
+		"perm": commandPermObject,
+	},
+	Handlers: []module.EventHandler{
+		module.Confirm("permrestore", confirm.Handler{Code: ConfirmPermRestore}),
+	},
+}
+
+var permChannelOption = []discord.CommandOptionValue{
+	&discord.ChannelOption{
+		OptionName:  "channel",
+		Description: "The channel in question",
+		Required:    true,
+	},
+}
+
+var commandPermObject = command.Handler{
+	Description: "Save and restore the permissions of channels",
+	Code:        CommandPerm,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "snapshot",
+			Description: "Save every permission overwrite in a channel, replacing any earlier snapshot of it",
+			Options:     permChannelOption,
+		},
+		&discord.SubcommandOption{
+			OptionName:  "restore",
+			Description: "Put the permissions in a channel back exactly the way they were in the snapshot",
+			Options:     permChannelOption,
+		},
+		&discord.SubcommandOption{
+			OptionName:  "forget",
+			Description: "Throw away the snapshot of a channel",
+			Options:     permChannelOption,
+		},
+		&discord.SubcommandOption{
+			OptionName:  "list",
+			Description: "List the channels that have a snapshot",
+			Options:     []discord.CommandOptionValue{},
+		},
+	},
+}
+
+// CommandPerm processes the /perm command, dispatching to the right subcommand.
+func CommandPerm(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /perm command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	if cmd.Options[0].Name == "list" {
+		return SubCommandPermList(kvs, event)
+	}
+
+	channelSnowflake, err := cmd.Options[0].Options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /perm %s failed to get channel snowflake: %s", event.GuildID, cmd.Options[0].Name, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	channelID := discord.ChannelID(channelSnowflake)
+
+	switch cmd.Options[0].Name {
+	case "snapshot":
+		return SubCommandPermSnapshot(state, kvs, event, channelID)
+	case "restore":
+		return SubCommandPermRestore(state, kvs, event, channelID)
+	case "forget":
+		return SubCommandPermForget(kvs, event, channelID)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandPermSnapshot saves the permission overwrites of a channel.
+func SubCommandPermSnapshot(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, channelID discord.ChannelID) command.Response {
+	snapshot, err := storage.TakePermissionSnapshot(state, kvs, event.GuildID, channelID, event.SenderID())
+	if err != nil {
+		log.Printf("[%s] /perm snapshot failed: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> took a permission snapshot of <#%s>", event.GuildID, event.SenderID(), channelID)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Saved %d permission overwrites in %s. Use `/perm restore` to put them back.", len(snapshot.Overwrites), channelID.Mention()))}
+}
+
+// SubCommandPermRestore puts the permission overwrites of a channel back the way they were in the snapshot.
+func SubCommandPermRestore(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, channelID discord.ChannelID) command.Response {
+	exist, snapshot, err := storage.GetPermissionSnapshot(kvs, event.GuildID, channelID)
+	if err != nil {
+		log.Printf("[%s] /perm restore failed to get the snapshot of <#%s>: %s", event.GuildID, channelID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no snapshot of %s.", channelID.Mention()))}
+	}
+	question := fmt.Sprintf("Really put the permissions in %s back the way they were <t:%d:R>? Any changes since then are lost.", channelID.Mention(), snapshot.Taken)
+	return command.Response{Response: confirm.Ask(kvs, event, "permrestore", question, map[string]string{"channel": channelID.String()})}
+}
+
+// ConfirmPermRestore puts the permission overwrites of a channel back the way they were, once the restore is confirmed.
+func ConfirmPermRestore(ctx *command.Context, confirmation storage.Confirmation) string {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	channelSnowflake, err := discord.ParseSnowflake(confirmation.Data["channel"])
+	if err != nil {
+		log.Printf("[%s] Permission restore confirmation has a weird channel: %s", event.GuildID, err)
+		return "I'm sorry, what? Something very weird happened."
+	}
+	channelID := discord.ChannelID(channelSnowflake)
+	exist, snapshot, err := storage.GetPermissionSnapshot(kvs, event.GuildID, channelID)
+	if err != nil {
+		log.Printf("[%s] /perm restore failed to get the snapshot of <#%s>: %s", event.GuildID, channelID, err)
+		return "An error occured, and has been logged."
+	}
+	if !exist {
+		return fmt.Sprintf("There is no snapshot of %s any more.", channelID.Mention())
+	}
+	if err := storage.RestorePermissionSnapshot(state, snapshot, fmt.Sprintf("Snapshot restored by %s", event.SenderID())); err != nil {
+		log.Printf("[%s] /perm restore failed: %s", event.GuildID, err)
+		return "I couldn't restore the permissions. Do I have permission to manage them? The error has been logged."
+	}
+	log.Printf("[%s] <@%s> restored the permission snapshot of <#%s>", event.GuildID, event.SenderID(), channelID)
+	return fmt.Sprintf("The permissions in %s are back the way they were <t:%d:R>.", channelID.Mention(), snapshot.Taken)
+}
+
+// SubCommandPermForget throws away the snapshot of a channel.
+func SubCommandPermForget(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, channelID discord.ChannelID) command.Response {
+	if err := storage.DeletePermissionSnapshot(kvs, event.GuildID, channelID); err != nil {
+		log.Printf("[%s] /perm forget failed for <#%s>: %s", event.GuildID, channelID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> forgot the permission snapshot of <#%s>", event.GuildID, event.SenderID(), channelID)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no snapshot of %s any more.", channelID.Mention()))}
+}
+
+// SubCommandPermList lists the channels that have a snapshot.
+func SubCommandPermList(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	snapshots, err := storage.GetPermissionSnapshots(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /perm list failed: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(snapshots) == 0 {
+		return command.Response{Response: response.Ephemeral("There are no permission snapshots.")}
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Taken > snapshots[j].Taken
+	})
+	lines := make([]string, len(snapshots))
+	for i, snapshot := range snapshots {
+		lines[i] = fmt.Sprintf("%s: %d overwrites, taken <t:%d:R> by %s", snapshot.ChannelID.Mention(), len(snapshot.Overwrites), snapshot.Taken, snapshot.TakenBy.Mention())
+	}
+	return command.Response{Response: response.Ephemeral(strings.Join(lines, "\n"))}
+}
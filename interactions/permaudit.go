@@ -0,0 +1,177 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+var permAuditModule = module.Basic{
+	ModuleName: "permaudit",
+	CommandHandlers: map[string]command.Handler{
+		"permaudit": commandPermAuditObject,
+	},
+}
+
+var commandPermAuditObject = command.Handler{
+	Description: "Snapshot the permissions of every role and channel, to see what changed later",
+	Code:        CommandPermAudit,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "snapshot",
+			Description: "Save the permissions of every role and every channel overwrite",
+			Options:     []discord.CommandOptionValue{},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "diff",
+			Description: "Show what changed between two snapshots, or between a snapshot and now",
+			Options: []discord.CommandOptionValue{
+				&discord.IntegerOption{
+					OptionName:  "from",
+					Description: "The snapshot to compare from. Default is the newest one.",
+					Required:    false,
+					Min:         option.NewInt(1),
+				},
+				&discord.IntegerOption{
+					OptionName:  "to",
+					Description: "The snapshot to compare to. Default is how things are right now.",
+					Required:    false,
+					Min:         option.NewInt(1),
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "list",
+			Description: "List the snapshots there are",
+			Options:     []discord.CommandOptionValue{},
+		},
+	},
+}
+
+// CommandPermAudit processes the /permaudit command, dispatching to the right subcommand.
+func CommandPermAudit(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /permaudit command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "snapshot":
+		return SubCommandPermAuditSnapshot(state, kvs, event)
+	case "diff":
+		return SubCommandPermAuditDiff(state, kvs, event, cmd.Options[0].Options)
+	case "list":
+		return SubCommandPermAuditList(kvs, event)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandPermAuditSnapshot saves the permissions of every role and channel.
+func SubCommandPermAuditSnapshot(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	audit, err := storage.TakePermissionAudit(state, kvs, event.GuildID, event.SenderID())
+	if err != nil {
+		log.Printf("[%s] /permaudit snapshot failed: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> took permission audit snapshot %d", event.GuildID, event.SenderID(), audit.ID)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf(
+		"Saved snapshot **%d**, with %d roles and %d channels. Use `/permaudit diff` later to see what changed. Only the newest %d snapshots are kept.",
+		audit.ID, len(audit.Roles), len(audit.Channels), storage.PermissionAuditLimit,
+	))}
+}
+
+// SubCommandPermAuditDiff shows what changed between two snapshots, or between a snapshot and how things are now.
+func SubCommandPermAuditDiff(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	audits, err := storage.GetPermissionAudits(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /permaudit diff failed to get the snapshots: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(audits) == 0 {
+		return command.Response{Response: response.Ephemeral("There are no snapshots to compare with. Take one with `/permaudit snapshot` first.")}
+	}
+
+	from := audits[len(audits)-1]
+	if opt := options.Find("from"); opt.Name != "" {
+		number, err := opt.IntValue()
+		if err != nil {
+			return command.Response{Response: response.Ephemeral("The snapshot has to be a number, like the ones in `/permaudit list`.")}
+		}
+		exist, audit, err := storage.GetPermissionAudit(kvs, event.GuildID, number)
+		if err != nil {
+			log.Printf("[%s] /permaudit diff failed to get snapshot %d: %s", event.GuildID, number, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		if !exist {
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no snapshot %d. See `/permaudit list` for the ones there are.", number))}
+		}
+		from = audit
+	}
+
+	to := storage.PermissionAudit{}
+	toName := "now"
+	if opt := options.Find("to"); opt.Name != "" {
+		number, err := opt.IntValue()
+		if err != nil {
+			return command.Response{Response: response.Ephemeral("The snapshot has to be a number, like the ones in `/permaudit list`.")}
+		}
+		exist, audit, err := storage.GetPermissionAudit(kvs, event.GuildID, number)
+		if err != nil {
+			log.Printf("[%s] /permaudit diff failed to get snapshot %d: %s", event.GuildID, number, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		if !exist {
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no snapshot %d. See `/permaudit list` for the ones there are.", number))}
+		}
+		to = audit
+		toName = fmt.Sprintf("snapshot **%d**", audit.ID)
+	} else {
+		to, err = storage.CapturePermissionAudit(state, event.GuildID)
+		if err != nil {
+			log.Printf("[%s] /permaudit diff failed to capture the permissions: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+	}
+
+	header := fmt.Sprintf("From snapshot **%d**, taken <t:%d:R> by %s, to %s:", from.ID, from.Taken, from.TakenBy.Mention(), toName)
+	changes := storage.DiffPermissionAudits(event.GuildID, from, to)
+	if len(changes) == 0 {
+		return command.Response{Response: response.Ephemeral(header + "\nNothing changed.")}
+	}
+	text := header + "\n" + strings.Join(changes, "\n")
+	if len(text) <= 2000 {
+		return command.Response{Response: response.Ephemeral(text)}
+	}
+	return command.Response{Response: response.EphemeralAttachFile(
+		fmt.Sprintf("%s\nThat's %d changes, which is too much to show here, so they're in the file.", header, len(changes)),
+		fmt.Sprintf("permaudit-%d.txt", from.ID),
+		strings.NewReader(strings.Join(changes, "\n")+"\n"),
+	)}
+}
+
+// SubCommandPermAuditList lists the snapshots there are.
+func SubCommandPermAuditList(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	audits, err := storage.GetPermissionAudits(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /permaudit list failed: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(audits) == 0 {
+		return command.Response{Response: response.Ephemeral("There are no permission audit snapshots.")}
+	}
+	lines := make([]string, len(audits))
+	for i, audit := range audits {
+		lines[len(audits)-1-i] = fmt.Sprintf("**%d**: %d roles and %d channels, taken <t:%d:R> by %s", audit.ID, len(audit.Roles), len(audit.Channels), audit.Taken, audit.TakenBy.Mention())
+	}
+	return command.Response{Response: response.Ephemeral(strings.Join(lines, "\n"))}
+}
@@ -0,0 +1,278 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/pins"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+const (
+	pinLimit      = 50 // How many pins Discord allows in a channel.
+	pinsAfterFull = 45 // How many pins are left when a full channel is archived, so it isn't full again right away.
+)
+
+// pinboardLock keeps the unpinning from one archive run from starting another while it's still going.
+var pinboardLock sync.Mutex
+
+var pinboardModule = module.Basic{
+	ModuleName: "pinboard",
+	CommandHandlers: map[string]command.Handler{
+		"pinboard": commandPinboardObject,
+		"Archive pin": command.Handler{
+			Type: discord.MessageCommand,
+			Code: CommandArchivePin,
+		},
+	},
+	Handlers: []module.EventHandler{
+		module.Pins(pins.Handler{Code: PinsPinboard}),
+	},
+}
+
+var commandPinboardObject = command.Handler{
+	Description: "Copy pinned messages to an archive channel",
+	Code:        CommandPinboard,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "config",
+			Description: "Set up the pin archive",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:   "channel",
+					Description:  "Where to copy the archived pins",
+					Required:     true,
+					ChannelTypes: []discord.ChannelType{discord.GuildText, discord.GuildNews},
+				},
+				&discord.BooleanOption{
+					OptionName:  "auto",
+					Description: fmt.Sprintf("Archive the oldest pins when a channel reaches %d. Default is yes.", pinLimit),
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "disable",
+			Description: "Stop archiving pins",
+			Options:     []discord.CommandOptionValue{},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "list",
+			Description: "List the most recently archived pins",
+			Options:     []discord.CommandOptionValue{},
+		},
+	},
+}
+
+// CommandPinboard processes the /pinboard command, dispatching to the right subcommand.
+func CommandPinboard(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /pinboard command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "config":
+		return SubCommandPinboardConfig(kvs, event, cmd.Options[0].Options)
+	case "disable":
+		if err := storage.DisablePinboard(kvs, event.GuildID); err != nil {
+			log.Printf("[%s] Failed to disable the pin archive: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		log.Printf("[%s] <@%s> disabled the pin archive", event.GuildID, event.SenderID())
+		return command.Response{Response: response.Ephemeral("Okay, pins will no longer be archived. What's already archived stays where it is.")}
+	case "list":
+		return SubCommandPinboardList(kvs, event)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandPinboardConfig sets up the pin archive.
+func SubCommandPinboardConfig(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /pinboard config failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	config := storage.PinboardConfig{
+		ChannelID: discord.ChannelID(channelSnowflake),
+		Auto:      true,
+	}
+	if opt := options.Find("auto"); opt.Name != "" {
+		config.Auto, err = opt.BoolValue()
+		if err != nil {
+			log.Printf("[%s] /pinboard config failed to get auto value: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+	}
+
+	if err := storage.SetPinboardConfig(kvs, event.GuildID, config); err != nil {
+		log.Printf("[%s] Failed to store pin archive config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> set the pin archive to <#%s>, automatic: %t", event.GuildID, event.SenderID(), config.ChannelID, config.Auto)
+	if config.Auto {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("Pins will be archived in %s, either with the *Archive pin* app command, or automatically when a channel reaches %d pins.", config.ChannelID.Mention(), pinLimit))}
+	}
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Pins archived with the *Archive pin* app command will go in %s.", config.ChannelID.Mention()))}
+}
+
+// SubCommandPinboardList lists the most recently archived pins, with links to both the archived copy and the original.
+func SubCommandPinboardList(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	exist, config, err := storage.GetPinboardConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /pinboard list failed to get the config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	archived, err := storage.GetArchivedPins(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /pinboard list failed to get the archived pins: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(archived) == 0 {
+		return command.Response{Response: response.Ephemeral("Nothing has been archived yet.")}
+	}
+	lines := []string{fmt.Sprintf("%d pins archived. The most recent:", len(archived))}
+	for i, pin := range archived {
+		if i == 10 {
+			break
+		}
+		original := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", event.GuildID, pin.ChannelID, pin.MessageID)
+		line := fmt.Sprintf("<t:%d:d> %s by %s, [original](%s)", pin.Archived.Unix(), pin.ChannelID.Mention(), pin.AuthorID.Mention(), original)
+		if exist {
+			line += fmt.Sprintf(", [archived](https://discord.com/channels/%s/%s/%s)", event.GuildID, config.ChannelID, pin.ArchiveMessageID)
+		}
+		lines = append(lines, line)
+	}
+	return command.Response{Response: response.Ephemeral(strings.Join(lines, "\n"))}
+}
+
+// CommandArchivePin archives the message it's used on, and unpins it if it's pinned.
+func CommandArchivePin(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	exist, config, err := storage.GetPinboardConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Archive pin failed to get the config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral("There's nowhere to archive it. Set that up with `/pinboard config` first.")}
+	}
+	messageID := cmd.TargetMessageID()
+	exist, pin, err := storage.GetArchivedPin(kvs, event.GuildID, messageID)
+	if err != nil {
+		log.Printf("[%s] Archive pin failed to look up message %s: %s", event.GuildID, messageID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if exist {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("That one is already archived: https://discord.com/channels/%s/%s/%s", event.GuildID, config.ChannelID, pin.ArchiveMessageID))}
+	}
+
+	message, err := state.Client.Message(event.ChannelID, messageID)
+	if err != nil {
+		log.Printf("[%s] Archive pin failed to get message %s in <#%s>: %s", event.GuildID, messageID, event.ChannelID, err)
+		return command.Response{Response: response.Ephemeral("I couldn't get that message. Maybe I can't see this channel properly?")}
+	}
+	message.GuildID = event.GuildID
+
+	pinboardLock.Lock()
+	defer pinboardLock.Unlock()
+	pin, err = archivePin(state, kvs, config, message, event.SenderID())
+	if err != nil {
+		log.Printf("[%s] Archive pin failed to archive message %s: %s", event.GuildID, messageID, err)
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("I couldn't archive it. Can I post in %s?", config.ChannelID.Mention()))}
+	}
+	log.Printf("[%s] <@%s> archived message %s in <#%s>", event.GuildID, event.SenderID(), messageID, event.ChannelID)
+	if message.Pinned {
+		if err := state.UnpinMessage(event.ChannelID, messageID, "Archived"); err != nil {
+			log.Printf("[%s] Archive pin failed to unpin message %s: %s", event.GuildID, messageID, err)
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("Archived in %s, but I couldn't unpin it here.", config.ChannelID.Mention()))}
+		}
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("Archived in %s and unpinned.", config.ChannelID.Mention()))}
+	}
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Archived in %s.", config.ChannelID.Mention()))}
+}
+
+// archivePin copies the message to the archive channel, and remembers where it went.
+// The caller is expected to hold pinboardLock.
+func archivePin(state *state.State, kvs storage.KeyValueStore, config storage.PinboardConfig, message *discord.Message, by discord.UserID) (storage.ArchivedPin, error) {
+	copied, err := state.SendMessageComplex(config.ChannelID, api.SendMessageData{
+		Content:         fmt.Sprintf("📌 %s", message.ChannelID.Mention()),
+		Embeds:          []discord.Embed{quoteEmbed(message, discord.Color(0xDD2E44))},
+		AllowedMentions: &api.AllowedMentions{Parse: []api.AllowedMentionType{}},
+	})
+	if err != nil {
+		return storage.ArchivedPin{}, err
+	}
+	pin := storage.ArchivedPin{
+		ChannelID:        message.ChannelID,
+		MessageID:        message.ID,
+		ArchiveMessageID: copied.ID,
+		AuthorID:         message.Author.ID,
+		ArchivedBy:       by,
+		Archived:         time.Now(),
+	}
+	return pin, storage.SetArchivedPin(kvs, message.GuildID, pin)
+}
+
+// PinsPinboard archives the oldest pins of a channel when it has run out of room for more, if the guild wants that.
+func PinsPinboard(state *state.State, kvs storage.KeyValueStore, event *gateway.ChannelPinsUpdateEvent) {
+	if !event.GuildID.IsValid() {
+		return
+	}
+	exist, config, err := storage.GetPinboardConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to get pin archive config: %s", event.GuildID, err)
+		return
+	}
+	if !exist || !config.Auto {
+		return
+	}
+
+	pinboardLock.Lock()
+	defer pinboardLock.Unlock()
+
+	// Asking Discord directly, as every unpin below changes what the cache would say.
+	pinned, err := state.Client.PinnedMessages(event.ChannelID)
+	if err != nil {
+		log.Printf("[%s] Pin archive failed to get the pins of <#%s>: %s", event.GuildID, event.ChannelID, err)
+		return
+	}
+	if len(pinned) < pinLimit {
+		return
+	}
+	// Discord lists the most recently pinned first, so the oldest are at the end.
+	archived := 0
+	for i := len(pinned) - 1; i >= pinsAfterFull; i-- {
+		message := pinned[i]
+		message.GuildID = event.GuildID
+		exist, _, err := storage.GetArchivedPin(kvs, event.GuildID, message.ID)
+		if err != nil {
+			log.Printf("[%s] Pin archive failed to look up message %s: %s", event.GuildID, message.ID, err)
+			return
+		}
+		if !exist {
+			if _, err := archivePin(state, kvs, config, &message, discord.NullUserID); err != nil {
+				log.Printf("[%s] Pin archive failed to archive message %s: %s", event.GuildID, message.ID, err)
+				return
+			}
+		}
+		if err := state.UnpinMessage(event.ChannelID, message.ID, "Archived, as the channel ran out of pins"); err != nil {
+			log.Printf("[%s] Pin archive failed to unpin message %s: %s", event.GuildID, message.ID, err)
+			return
+		}
+		archived++
+	}
+	log.Printf("[%s] Archived %d pins from <#%s>, as it was full", event.GuildID, archived, event.ChannelID)
+}
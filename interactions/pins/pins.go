@@ -0,0 +1,35 @@
+package pins
+
+import (
+	"komainu/storage"
+
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+type Handler struct {
+	Code HandlerFunction
+}
+
+type HandlerFunction func(
+	state *state.State,
+	kvs storage.KeyValueStore,
+	event *gateway.ChannelPinsUpdateEvent,
+)
+
+var pinshandlers = []Handler{}
+
+// Register makes the Code turn over when a message is pinned or unpinned
+func Register(handler Handler) {
+	pinshandlers = append(pinshandlers, handler)
+}
+
+// Add the pins handler to the given state
+// This is mostly just pointless abstraction for uniformity across events.
+func AddHandler(state *state.State, kvs storage.KeyValueStore) {
+	state.AddHandler(func(event *gateway.ChannelPinsUpdateEvent) {
+		for _, handler := range pinshandlers {
+			handler.Code(state, kvs, event)
+		}
+	})
+}
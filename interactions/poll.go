@@ -0,0 +1,104 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// pollMaxOptions is how many options a poll can have. Any more, and it's time for a proper /vote.
+const pollMaxOptions = 10
+
+var pollModule = module.Basic{
+	ModuleName: "poll",
+	CommandHandlers: map[string]command.Handler{
+		"poll": commandPollObject,
+	},
+}
+
+var commandPollObject = command.Handler{
+	Description: "Quickly ask a question, without filling anything in",
+	Code:        CommandPoll,
+	Options:     pollOptions(),
+}
+
+// pollOptions are the question, the options to answer with, and how long it runs.
+func pollOptions() []discord.CommandOption {
+	options := []discord.CommandOption{
+		&discord.StringOption{
+			OptionName:  "question",
+			Description: "What to ask",
+			Required:    true,
+		},
+	}
+	for i := 1; i <= pollMaxOptions; i++ {
+		options = append(options, &discord.StringOption{
+			OptionName:  fmt.Sprintf("option%d", i),
+			Description: "Something to answer. Leave them all out for yes or no.",
+			Required:    false,
+		})
+	}
+	return append(options, &discord.StringOption{
+		OptionName:  "length",
+		Description: "How long, like 36h or 3d12h, or when it ends, like 2024-07-01 18:00. Default is one day.",
+		Required:    false,
+	})
+}
+
+// CommandPoll posts a vote straight away, with the options given rather than ones filled in through a modal.
+func CommandPoll(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	location, err := storage.GetTimezone(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /poll failed to get the time zone, going with UTC: %s", event.GuildID, err)
+	}
+	now := time.Now()
+	end, _, problem := parseVoteEnd(ctx.Language, cmd.Options.Find("length").String(), now, location)
+	if problem != "" {
+		return command.Response{Response: response.Ephemeral(problem)}
+	}
+
+	vote := storage.Vote{
+		StartTime: now.Unix(),
+		EndTime:   end.Unix(),
+		GuildID:   event.GuildID,
+		Question:  strings.TrimSpace(cmd.Options.Find("question").String()),
+		Options:   map[string]string{},
+		Order:     []string{},
+		Votes:     map[discord.UserID]string{},
+		Creator:   event.SenderID(),
+	}
+	seen := map[string]bool{}
+	for i := 1; i <= pollMaxOptions; i++ {
+		label := strings.TrimSpace(cmd.Options.Find(fmt.Sprintf("option%d", i)).String())
+		if label == "" {
+			continue
+		}
+		label = utility.Substring(label, 0, 100)
+		if seen[strings.ToLower(label)] {
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("%q is in there twice. Every option has to be different.", label))}
+		}
+		seen[strings.ToLower(label)] = true
+		key := "vote/" + strconv.Itoa(len(vote.Order))
+		vote.Options[key] = label
+		vote.Order = append(vote.Order, key)
+	}
+	switch len(vote.Order) {
+	case 0:
+		vote.Options, vote.Order = blankVote().Options, blankVote().Order
+	case 1:
+		return command.Response{Response: response.Ephemeral("With only one option, there's not much to choose between. Give at least two, or none for yes or no.")}
+	}
+
+	log.Printf("[%s] <@%s> started a poll with %d options", event.GuildID, event.SenderID(), len(vote.Order))
+	return postVote(state, kvs, vote)
+}
@@ -0,0 +1,36 @@
+package presence
+
+import (
+	"komainu/storage"
+
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+type Handler struct {
+	Code HandlerFunction
+}
+
+type HandlerFunction func(
+	state *state.State,
+	kvs storage.KeyValueStore,
+	event *gateway.PresenceUpdateEvent,
+)
+
+var presencehandlers = []Handler{}
+
+// Register makes the Code turn over when someone's presence changes, like coming online.
+// These only arrive if the bot asks for them, with PresenceIntent in the configuration.
+func Register(handler Handler) {
+	presencehandlers = append(presencehandlers, handler)
+}
+
+// Add the presence handler to the given state
+// This is mostly just pointless abstraction for uniformity across events.
+func AddHandler(state *state.State, kvs storage.KeyValueStore) {
+	state.AddHandler(func(event *gateway.PresenceUpdateEvent) {
+		for _, handler := range presencehandlers {
+			handler.Code(state, kvs, event)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package reaction
+
+import (
+	"komainu/storage"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// Change describes a reaction being added or removed. Discord has four different events for this, which is three too many.
+type Change struct {
+	GuildID   discord.GuildID
+	ChannelID discord.ChannelID
+	MessageID discord.MessageID
+	UserID    discord.UserID // Null when several reactions were removed at once.
+	Emoji     discord.Emoji  // Zero when all reactions were removed, regardless of emoji.
+	Added     bool
+}
+
+type Handler struct {
+	Code HandlerFunction
+}
+
+type HandlerFunction func(
+	state *state.State,
+	kvs storage.KeyValueStore,
+	change Change,
+)
+
+var reactionHandlers = []Handler{}
+
+// Register makes the Code run whenever a reaction comes or goes
+func Register(handler Handler) {
+	reactionHandlers = append(reactionHandlers, handler)
+}
+
+// Add the reaction handlers to the given state
+func AddHandler(state *state.State, kvs storage.KeyValueStore) {
+	dispatch := func(change Change) {
+		if change.GuildID == discord.NullGuildID {
+			return // Reactions in private aren't anyone's business.
+		}
+		for _, handler := range reactionHandlers {
+			handler.Code(state, kvs, change)
+		}
+	}
+	state.AddHandler(func(event *gateway.MessageReactionAddEvent) {
+		dispatch(Change{event.GuildID, event.ChannelID, event.MessageID, event.UserID, event.Emoji, true})
+	})
+	state.AddHandler(func(event *gateway.MessageReactionRemoveEvent) {
+		dispatch(Change{event.GuildID, event.ChannelID, event.MessageID, event.UserID, event.Emoji, false})
+	})
+	state.AddHandler(func(event *gateway.MessageReactionRemoveEmojiEvent) {
+		dispatch(Change{event.GuildID, event.ChannelID, event.MessageID, discord.NullUserID, event.Emoji, false})
+	})
+	state.AddHandler(func(event *gateway.MessageReactionRemoveAllEvent) {
+		dispatch(Change{event.GuildID, event.ChannelID, event.MessageID, discord.NullUserID, discord.Emoji{}, false})
+	})
+}
@@ -1,27 +1,298 @@
-//go:build ignore
-
-// TODO: Flesh out the report function. Maybe.
 package interactions
 
 import (
+	"fmt"
 	"komainu/interactions/command"
+	"komainu/interactions/component"
 	"komainu/interactions/modal"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
 	"komainu/storage"
+	"log"
+	"strings"
 
+	"github.com/diamondburned/arikawa/v3/api"
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/gateway"
 	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
 )
 
-func init() {
-	command.Register("Report message", command.Handler{
-		//Description: "Bring message to moderator attention",
-		Type: discord.MessageCommand,
-		Code: CommandReport,
+var reportModule = module.Basic{
+	ModuleName: "report",
+	CommandHandlers: map[string]command.Handler{
+		"report": commandReportObject,
+		"Report to moderators": command.Handler{
+			Type:   discord.MessageCommand,
+			Code:   CommandReport,
+			Public: true,
+		},
+	},
+	ComponentHandlers: map[string]component.Handler{
+		"report": component.Handler{Code: ComponentReport},
+	},
+	Handlers: []module.EventHandler{
+		module.Modal("report", modal.Handler{Code: ReportModalHandler}),
+	},
+}
+
+var commandReportObject = command.Handler{
+	Description: "Set up where messages reported to the moderators go",
+	Code:        CommandReportConfig,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "config",
+			Description: "Set the staff channel reports go to",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:   "channel",
+					Description:  "Where reports go. Keep it staff only!",
+					Required:     true,
+					ChannelTypes: []discord.ChannelType{discord.GuildText},
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "disable",
+			Description: "Stop taking reports",
+			Options:     []discord.CommandOptionValue{},
+		},
+	},
+}
+
+// CommandReportConfig processes the /report command, dispatching to the right subcommand.
+func CommandReportConfig(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /report command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "config":
+		channelSnowflake, err := cmd.Options[0].Options.Find("channel").SnowflakeValue()
+		if err != nil {
+			log.Printf("[%s] /report config failed to get channel snowflake: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+		}
+		config := storage.ReportConfig{ChannelID: discord.ChannelID(channelSnowflake)}
+		if err := storage.SetReportConfig(kvs, event.GuildID, config); err != nil {
+			log.Printf("[%s] Failed to store report config: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		log.Printf("[%s] <@%s> set reports to go to <#%s>", event.GuildID, event.SenderID(), config.ChannelID)
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("Reported messages now go to %s. Anyone can report a message with *Apps* → *Report to moderators*.", config.ChannelID.Mention()))}
+	case "disable":
+		if err := storage.DisableReports(kvs, event.GuildID); err != nil {
+			log.Printf("[%s] Failed to disable reports: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		log.Printf("[%s] <@%s> disabled reports", event.GuildID, event.SenderID())
+		return command.Response{Response: response.Ephemeral("Okay, no more reports.")}
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// CommandReport asks whoever is reporting a message why they're reporting it.
+func CommandReport(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	exist, _, err := storage.GetReportConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Report failed to get config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral("Reporting isn't set up here. You'll have to contact the moderators some other way.")}
+	}
+	return command.Response{Response: modal.Respond(
+		kvs, event, "report", "Report to moderators",
+		map[string]string{"channel": event.ChannelID.String(), "message": cmd.TargetMessageID().String()},
+		discord.TextInputComponent{
+			CustomID:     discord.ComponentID("reason"),
+			Label:        "What's wrong with this message?",
+			Style:        discord.TextInputParagraphStyle,
+			Required:     true,
+			LengthLimits: [2]int{1, 1000},
+		},
+	)}
+}
+
+// ReportModalHandler forwards the reported message, who reported it and why, to the staff channel.
+func ReportModalHandler(ctx *command.Context, interaction *discord.ModalInteraction, session map[string]string) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	reason, ok := modal.DecodeModalResponse(interaction.Components)["reason"]
+	if !ok {
+		log.Printf("[%s] Report modal had no reason in it", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	channelSnowflake, err := discord.ParseSnowflake(session["channel"])
+	if err != nil {
+		log.Printf("[%s] Report modal has a weird channel: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	messageSnowflake, err := discord.ParseSnowflake(session["message"])
+	if err != nil {
+		log.Printf("[%s] Report modal has a weird message: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	return reportMessage(state, kvs, event, discord.ChannelID(channelSnowflake), discord.MessageID(messageSnowflake), strings.TrimSpace(reason))
+}
+
+// reportMessage posts the report in the staff channel, with buttons to deal with it.
+func reportMessage(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, channelID discord.ChannelID, messageID discord.MessageID, reason string) command.Response {
+	exist, config, err := storage.GetReportConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Report failed to get config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral("Reporting isn't set up here. You'll have to contact the moderators some other way.")}
+	}
+	message, err := state.Message(channelID, messageID)
+	if err != nil {
+		log.Printf("[%s] Report failed to get message %s in <#%s>: %s", event.GuildID, messageID, channelID, err)
+		return command.Response{Response: response.Ephemeral("I couldn't find that message. Maybe it was deleted already?")}
+	}
+	message.GuildID = event.GuildID
+
+	embed := quoteEmbed(message, 0xE67E22)
+	embed.Title = "Reported message"
+	embed.Fields = append(embed.Fields,
+		discord.EmbedField{Name: "Author", Value: message.Author.ID.Mention(), Inline: true},
+		discord.EmbedField{Name: "Channel", Value: channelID.Mention(), Inline: true},
+		discord.EmbedField{Name: "Reported by", Value: event.SenderID().Mention(), Inline: true},
+		discord.EmbedField{Name: "Reason", Value: reason},
+	)
+	target := []string{channelID.String(), messageID.String(), message.Author.ID.String()}
+	_, err = state.SendMessageComplex(config.ChannelID, api.SendMessageData{
+		Embeds: []discord.Embed{embed},
+		Components: discord.ContainerComponents{
+			&discord.ActionRowComponent{
+				&discord.ButtonComponent{
+					Style:    discord.DangerButtonStyle(),
+					CustomID: component.ID("report", append([]string{"delete"}, target...)...),
+					Label:    "Delete message",
+				},
+				&discord.ButtonComponent{
+					Style:    discord.PrimaryButtonStyle(),
+					CustomID: component.ID("report", append([]string{"warn"}, target...)...),
+					Label:    "Warn author",
+				},
+				&discord.ButtonComponent{
+					Style:    discord.SecondaryButtonStyle(),
+					CustomID: component.ID("report", append([]string{"ignore"}, target...)...),
+					Label:    "Ignore",
+				},
+			},
+		},
+		AllowedMentions: &api.AllowedMentions{Parse: []api.AllowedMentionType{}},
 	})
-	// TODO: Write and register a handler for a modal response here.
+	if err != nil {
+		log.Printf("[%s] Report failed to post in <#%s>: %s", event.GuildID, config.ChannelID, err)
+		return command.Response{Response: response.Ephemeral("I couldn't pass the report on to the moderators. The error has been logged.")}
+	}
+	log.Printf("[%s] <@%s> reported message %s by <@%s> in <#%s>", event.GuildID, event.SenderID(), messageID, message.Author.ID, channelID)
+	return command.Response{Response: response.Ephemeral("Thank you! The moderators have been told, and will have a look.")}
+}
+
+// ComponentReport deals with a report, as the moderator clicking the button wants.
+func ComponentReport(ctx *command.Context, interaction discord.ComponentInteraction, params []string) api.InteractionResponse {
+	state, kvs, e := ctx.State, ctx.KVS, ctx.Event
+	// report/action/channel/message/author
+	if len(params) != 4 {
+		log.Printf("[%s] Report button has a weird ID: %s", e.GuildID, interaction.ID())
+		return response.Ephemeral("I'm sorry, what? Something very weird happened.")
+	}
+	channelSnowflake, channelErr := discord.ParseSnowflake(params[1])
+	messageSnowflake, messageErr := discord.ParseSnowflake(params[2])
+	authorSnowflake, authorErr := discord.ParseSnowflake(params[3])
+	if channelErr != nil || messageErr != nil || authorErr != nil {
+		log.Printf("[%s] Report button has a weird ID: %s", e.GuildID, interaction.ID())
+		return response.Ephemeral("I'm sorry, what? Something very weird happened.")
+	}
+	channelID := discord.ChannelID(channelSnowflake)
+	messageID := discord.MessageID(messageSnowflake)
+	authorID := discord.UserID(authorSnowflake)
+
+	permissions, err := state.Permissions(e.ChannelID, e.SenderID())
+	if err != nil {
+		log.Printf("[%s] Report button failed to get permissions of <@%s>: %s", e.GuildID, e.SenderID(), err)
+		return response.Ephemeral("An error occured, and has been logged.")
+	}
+	if !permissions.Has(discord.PermissionManageMessages) {
+		return response.Ephemeral("Only moderators can deal with reports.")
+	}
+
+	outcome := ""
+	switch params[0] {
+	case "delete":
+		err := state.DeleteMessage(channelID, messageID, api.AuditLogReason(fmt.Sprintf("Reported, and deleted by %s", e.Sender().Tag())))
+		if err != nil {
+			log.Printf("[%s] Report failed to delete message %s in <#%s>: %s", e.GuildID, messageID, channelID, err)
+			return response.Ephemeral("I couldn't delete the message. Maybe it's gone already, or I'm not allowed? The error has been logged.")
+		}
+		outcome = fmt.Sprintf("🗑️ Message deleted by %s", e.SenderID().Mention())
+	case "warn":
+		reason := "Reported message"
+		if e.Message != nil && len(e.Message.Embeds) > 0 {
+			for _, field := range e.Message.Embeds[0].Fields {
+				if field.Name == "Reason" {
+					reason = "Reported message: " + field.Value
+				}
+			}
+		}
+		warning, err := storage.AddWarning(kvs, e.GuildID, authorID, e.SenderID(), reason)
+		if err != nil {
+			log.Printf("[%s] Report failed to warn <@%s>: %s", e.GuildID, authorID, err)
+			return response.Ephemeral("An error occured, and has been logged.")
+		}
+		outcome = fmt.Sprintf("⚠️ %s warned by %s, as warning %d", authorID.Mention(), e.SenderID().Mention(), warning.ID)
+	case "ignore":
+		outcome = fmt.Sprintf("👍 Ignored by %s", e.SenderID().Mention())
+	default:
+		log.Printf("[%s] Report button has an unknown action: %s", e.GuildID, params[0])
+		return response.Ephemeral("I'm sorry, what? Something very weird happened.")
+	}
+	log.Printf("[%s] <@%s> dealt with the report of message %s: %s", e.GuildID, e.SenderID(), messageID, params[0])
+
+	content := outcome
+	components := discord.ContainerComponents{}
+	if e.Message != nil {
+		if e.Message.Content != "" {
+			content = e.Message.Content + "\n" + outcome
+		}
+		if params[0] != "ignore" {
+			components = reportButtonsLeft(e.Message.Components, string(interaction.ID()))
+		}
+	}
+	return api.InteractionResponse{
+		Type: api.UpdateMessage,
+		Data: &api.InteractionResponseData{
+			Content:         option.NewNullableString(content),
+			Components:      &components,
+			AllowedMentions: &api.AllowedMentions{Parse: []api.AllowedMentionType{}},
+		},
+	}
 }
 
-func CommandReport(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, cmd *discord.CommandInteraction) command.Response {
-	return modal.Respond() // blah blah, take a report message.
+// reportButtonsLeft is the buttons of the report, without the one that was just clicked, and without Ignore, as it has been looked at now.
+func reportButtonsLeft(components discord.ContainerComponents, clicked string) discord.ContainerComponents {
+	left := discord.ActionRowComponent{}
+	for _, container := range components {
+		row, ok := container.(*discord.ActionRowComponent)
+		if !ok {
+			continue
+		}
+		for _, cmp := range *row {
+			button, ok := cmp.(*discord.ButtonComponent)
+			if !ok || string(button.CustomID) == clicked || strings.HasPrefix(string(button.CustomID), "report/ignore/") {
+				continue
+			}
+			left = append(left, button)
+		}
+	}
+	if len(left) == 0 {
+		return discord.ContainerComponents{}
+	}
+	return discord.ContainerComponents{&left}
 }
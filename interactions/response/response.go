@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/utils/json/option"
 	"github.com/diamondburned/arikawa/v3/utils/sendpart"
 )
@@ -20,6 +21,24 @@ func Ephemeral(message ...string) api.InteractionResponse {
 	}
 }
 
+// ShareButtonID is the CustomID of the button added by EphemeralShareable.
+const ShareButtonID = "share"
+
+// EphemeralShareable generates an ephemeral response message from the strings given, with a button to post it publicly.
+func EphemeralShareable(message ...string) api.InteractionResponse {
+	resp := Ephemeral(message...)
+	resp.Data.Components = &discord.ContainerComponents{
+		&discord.ActionRowComponent{
+			&discord.ButtonComponent{
+				Style:    discord.SecondaryButtonStyle(),
+				CustomID: ShareButtonID,
+				Label:    "Share to channel",
+			},
+		},
+	}
+	return resp
+}
+
 // Message generates an InteractionResponse from the strings given.
 func Message(message ...string) api.InteractionResponse {
 	return api.InteractionResponse{
@@ -58,3 +77,95 @@ func MessageAttachFile(message string, name string, reader io.Reader) api.Intera
 		},
 	}
 }
+
+// EphemeralAttachFile generates an ephemeral response message from the string given, and attaches the given file.
+func EphemeralAttachFile(message string, name string, reader io.Reader) api.InteractionResponse {
+	resp := MessageAttachFile(message, name, reader)
+	resp.Data.Flags = api.EphemeralResponse
+	return resp
+}
+
+// IsShareable checks if the response is one made with EphemeralShareable, that has the button to post it publicly.
+func IsShareable(resp api.InteractionResponse) bool {
+	if resp.Data == nil || resp.Data.Components == nil {
+		return false
+	}
+	for _, row := range *resp.Data.Components {
+		actionRow, ok := row.(*discord.ActionRowComponent)
+		if !ok {
+			continue
+		}
+		for _, component := range *actionRow {
+			if button, ok := component.(*discord.ButtonComponent); ok && button.CustomID == ShareButtonID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MakePublic turns a response made with EphemeralShareable into one everyone can see, as if the button was clicked.
+// Anything else is left as it is, so errors and the like stay private. Returns whether it was made public.
+func MakePublic(resp *api.InteractionResponse) bool {
+	if !IsShareable(*resp) {
+		return false
+	}
+	resp.Data.Flags &^= api.EphemeralResponse
+	rows := discord.ContainerComponents{}
+	for _, row := range *resp.Data.Components {
+		actionRow, ok := row.(*discord.ActionRowComponent)
+		if !ok {
+			rows = append(rows, row)
+			continue
+		}
+		kept := discord.ActionRowComponent{}
+		for _, component := range *actionRow {
+			if button, ok := component.(*discord.ButtonComponent); ok && button.CustomID == ShareButtonID {
+				continue
+			}
+			kept = append(kept, component)
+		}
+		if len(kept) > 0 {
+			rows = append(rows, &kept)
+		}
+	}
+	if len(rows) == 0 {
+		resp.Data.Components = nil
+	} else {
+		resp.Data.Components = &rows
+	}
+	if resp.Data.AllowedMentions == nil {
+		// It was only meant for whoever asked, so whoever it mentions wasn't expecting a ping.
+		resp.Data.AllowedMentions = &api.AllowedMentions{Parse: []api.AllowedMentionType{}}
+	}
+	return true
+}
+
+// MakeEphemeral turns a message response into one only whoever used the command can see. Returns whether it did, as
+// responses that aren't messages, like modals, can't be.
+func MakeEphemeral(resp *api.InteractionResponse) bool {
+	if resp.Type != api.MessageInteractionWithSource && resp.Type != api.DeferredMessageInteractionWithSource {
+		return false
+	}
+	if resp.Data == nil {
+		resp.Data = &api.InteractionResponseData{}
+	}
+	resp.Data.Flags |= api.EphemeralResponse
+	return true
+}
+
+// loggedPhrases are how responses say something went wrong, and that it has been logged.
+var loggedPhrases = []string{"been logged", "was logged", "I've logged"}
+
+// IsError tells if the response is telling someone something went wrong, and has been logged.
+func IsError(resp api.InteractionResponse) bool {
+	if resp.Data == nil || resp.Data.Content == nil {
+		return false
+	}
+	for _, phrase := range loggedPhrases {
+		if strings.Contains(resp.Data.Content.Val, phrase) {
+			return true
+		}
+	}
+	return false
+}
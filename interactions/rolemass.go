@@ -0,0 +1,289 @@
+package interactions
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"github.com/diamondburned/arikawa/v3/utils/sendpart"
+)
+
+var roleMassModule = module.Basic{
+	ModuleName: "rolemass",
+	CommandHandlers: map[string]command.Handler{
+		"role": commandRoleObject,
+	},
+}
+
+// roleMassFilters are the ways to narrow down who a mass role change is for. Leave them all out for everyone.
+var roleMassFilters = []discord.CommandOptionValue{
+	&discord.RoleOption{
+		OptionName:  "hasrole",
+		Description: "Only those who have this role",
+		Required:    false,
+	},
+	&discord.StringOption{
+		OptionName:  "joinedbefore",
+		Description: "Only those who joined before this date, like 2024-07-01",
+		Required:    false,
+	},
+	&discord.IntegerOption{
+		OptionName:  "inactive",
+		Description: "Only those who haven't been seen for at least this many days",
+		Required:    false,
+		Min:         option.NewInt(1),
+		Max:         option.NewInt(3650),
+	},
+	&discord.BooleanOption{
+		OptionName:  "dryrun",
+		Description: "Only list who it would change, without changing anyone",
+		Required:    false,
+	},
+}
+
+var commandRoleObject = command.Handler{
+	Description: "Give or take a role from lots of people at once",
+	Code:        CommandRole,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "massadd",
+			Description: "Give a role to everyone matching the filters",
+			Options: append([]discord.CommandOptionValue{&discord.RoleOption{
+				OptionName:  "role",
+				Description: "The role to give",
+				Required:    true,
+			}}, roleMassFilters...),
+		},
+		&discord.SubcommandOption{
+			OptionName:  "massremove",
+			Description: "Take a role from everyone matching the filters",
+			Options: append([]discord.CommandOptionValue{&discord.RoleOption{
+				OptionName:  "role",
+				Description: "The role to take",
+				Required:    true,
+			}}, roleMassFilters...),
+		},
+	},
+}
+
+// roleMassRunning keeps track of the guilds with a mass role change going, so they don't pile up.
+var roleMassRunning = map[discord.GuildID]bool{}
+var roleMassLock sync.Mutex
+
+// roleMassFilter is who a mass role change is for.
+type roleMassFilter struct {
+	HasRole      discord.RoleID
+	JoinedBefore time.Time
+	Inactive     int64 // Days, or zero for no filter.
+}
+
+// Matches checks if the member is one the change is for.
+func (filter roleMassFilter) Matches(kvs storage.KeyValueStore, guildID discord.GuildID, member discord.Member) (bool, error) {
+	if filter.HasRole.IsValid() && !utility.ContainsRole(member.RoleIDs, filter.HasRole) {
+		return false, nil
+	}
+	if !filter.JoinedBefore.IsZero() && !member.Joined.Time().Before(filter.JoinedBefore) {
+		return false, nil
+	}
+	if filter.Inactive > 0 {
+		seen, when, err := storage.LastSeen(kvs, guildID, member.User.ID)
+		if err != nil {
+			return false, err
+		}
+		if seen && when > time.Now().Unix()-filter.Inactive*24*3600 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Describe says who the filter matches, to go after "everyone".
+func (filter roleMassFilter) Describe() string {
+	parts := []string{}
+	if filter.HasRole.IsValid() {
+		parts = append(parts, "with "+filter.HasRole.Mention())
+	}
+	if !filter.JoinedBefore.IsZero() {
+		parts = append(parts, "who joined before "+filter.JoinedBefore.Format("2006-01-02"))
+	}
+	if filter.Inactive > 0 {
+		parts = append(parts, fmt.Sprintf("who hasn't been seen for %d days", filter.Inactive))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " " + strings.Join(parts, ", ")
+}
+
+// CommandRole processes the /role command, dispatching to the right subcommand.
+func CommandRole(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /role command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "massadd":
+		return SubCommandRoleMass(state, kvs, event, cmd.Options[0].Options, true)
+	case "massremove":
+		return SubCommandRoleMass(state, kvs, event, cmd.Options[0].Options, false)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandRoleMass gives or takes a role from everyone matching the filters, reporting how it's going as it goes.
+func SubCommandRoleMass(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions, add bool) command.Response {
+	roleSnowflake, err := options.Find("role").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /role failed to get role snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the role. It has been logged.")}
+	}
+	roleID := discord.RoleID(roleSnowflake)
+	if err := utility.CanManageRole(state, event.GuildID, event.Member, roleID); err != nil {
+		return command.Response{Response: response.Ephemeral(hierarchyProblem(event.GuildID, err))}
+	}
+
+	filter := roleMassFilter{}
+	if opt := options.Find("hasrole"); opt.Name != "" {
+		snowflake, err := opt.SnowflakeValue()
+		if err != nil {
+			log.Printf("[%s] /role failed to get hasrole snowflake: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("There was an issue figuring out the role. It has been logged.")}
+		}
+		filter.HasRole = discord.RoleID(snowflake)
+	}
+	if opt := options.Find("joinedbefore"); opt.Name != "" {
+		location, err := storage.GetTimezone(kvs, event.GuildID)
+		if err != nil {
+			log.Printf("[%s] /role failed to get the time zone, going with UTC: %s", event.GuildID, err)
+		}
+		filter.JoinedBefore, err = time.ParseInLocation("2006-01-02", strings.TrimSpace(opt.String()), location)
+		if err != nil {
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("I don't understand the date %q. Write it like 2024-07-01.", opt.String()))}
+		}
+	}
+	if opt := options.Find("inactive"); opt.Name != "" {
+		days, err := opt.IntValue()
+		if err != nil || days < 1 {
+			return command.Response{Response: response.Ephemeral("The number of inactive days has to be a whole number, at least 1.")}
+		}
+		filter.Inactive = days
+	}
+	dryRun, _ := options.Find("dryrun").BoolValue()
+
+	// A dry run changes nothing, so there's no need to wait for, or hold up, a real one.
+	if !dryRun {
+		roleMassLock.Lock()
+		if roleMassRunning[event.GuildID] {
+			roleMassLock.Unlock()
+			return command.Response{Response: response.Ephemeral("There's already a mass role change going. Wait for that one to finish first.")}
+		}
+		roleMassRunning[event.GuildID] = true
+		roleMassLock.Unlock()
+		log.Printf("[%s] <@%s> started a mass role change: %s <@&%s>%s", event.GuildID, event.SenderID(), strings.ToLower(roleMassVerbs(add).verb), roleID, filter.Describe())
+	}
+
+	return command.DeferredDataProgress(state, event, true, func(progress func(string)) api.EditInteractionResponseData {
+		if !dryRun {
+			defer func() {
+				roleMassLock.Lock()
+				delete(roleMassRunning, event.GuildID)
+				roleMassLock.Unlock()
+			}()
+		}
+		var changed bytes.Buffer
+		result := roleMass(state, kvs, event, roleID, filter, add, dryRun, &changed, progress)
+		if !dryRun || changed.Len() == 0 {
+			return api.EditInteractionResponseData{Content: option.NewNullableString(result)}
+		}
+		return api.EditInteractionResponseData{
+			Content: option.NewNullableString(result),
+			Files:   []sendpart.File{{Name: "dryrun.txt", Reader: &changed}},
+		}
+	})
+}
+
+// roleMassWords are what to call giving or taking a role, in the various ways it comes up.
+type roleMassWords struct {
+	verb, past, preposition string
+}
+
+func roleMassVerbs(add bool) roleMassWords {
+	if add {
+		return roleMassWords{"Giving", "gave", "to"}
+	}
+	return roleMassWords{"Taking", "took", "from"}
+}
+
+// roleMass gives or takes the role from everyone matching the filter, and says how it went. Each member changed is
+// written to changed, one on each line. With dryRun, everything is done the same way, apart from actually changing
+// anyone, so what it says it would do is exactly what it would.
+func roleMass(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, roleID discord.RoleID, filter roleMassFilter, add bool, dryRun bool, changed io.Writer, progress func(string)) string {
+	words := roleMassVerbs(add)
+	reason := api.AuditLogReason(fmt.Sprintf("Mass role change by %s", event.Sender().Tag()))
+
+	members, err := state.Session.Members(event.GuildID, 0)
+	if err != nil {
+		log.Printf("[%s] /role failed to get the members: %s", event.GuildID, err)
+		return "I couldn't get the member list, so nothing was changed. The error has been logged."
+	}
+	targets := []discord.Member{}
+	for _, member := range members {
+		if member.User.Bot || utility.ContainsRole(member.RoleIDs, roleID) == add {
+			continue
+		}
+		matches, err := filter.Matches(kvs, event.GuildID, member)
+		if err != nil {
+			log.Printf("[%s] /role failed to check <@%s> against the filters: %s", event.GuildID, member.User.ID, err)
+			return "I couldn't check who matches the filters, so nothing was changed. The error has been logged."
+		}
+		if matches {
+			targets = append(targets, member)
+		}
+	}
+	if len(targets) == 0 {
+		return fmt.Sprintf("Nobody%s needed changing, so there's nothing to do.", filter.Describe())
+	}
+
+	failed := 0
+	for i, member := range targets {
+		if !dryRun {
+			progress(fmt.Sprintf("%s %s %s everyone%s... %d of %d done.", words.verb, roleID.Mention(), words.preposition, filter.Describe(), i, len(targets)))
+			if add {
+				err = state.AddRole(event.GuildID, member.User.ID, roleID, api.AddRoleData{AuditLogReason: reason})
+			} else {
+				err = state.RemoveRole(event.GuildID, member.User.ID, roleID, reason)
+			}
+			if err != nil {
+				failed++
+				log.Printf("[%s] /role failed to change <@&%s> on <@%s>: %s", event.GuildID, roleID, member.User.ID, err)
+				continue
+			}
+		}
+		fmt.Fprintf(changed, "%s %s\n", member.User.ID, member.User.Tag())
+	}
+	if dryRun {
+		return fmt.Sprintf("Dry run: I would have %s %s %s %d members%s, and nothing was changed. Here's who.", words.past, roleID.Mention(), words.preposition, len(targets), filter.Describe())
+	}
+	log.Printf("[%s] Mass role change by <@%s> done: %d changed, %d failed", event.GuildID, event.SenderID(), len(targets)-failed, failed)
+	result := fmt.Sprintf("Done! I %s %s %s %d members%s.", words.past, roleID.Mention(), words.preposition, len(targets)-failed, filter.Describe())
+	if failed > 0 {
+		result += fmt.Sprintf(" %d failed. The errors have been logged.", failed)
+	}
+	return result
+}
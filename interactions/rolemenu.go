@@ -0,0 +1,467 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/component"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+const (
+	roleMenuMaxGroups = 5  // A message can have five rows of components, and each select needs its own.
+	roleMenuMaxRoles  = 25 // A select can have 25 options.
+)
+
+var roleMenuModule = module.Basic{
+	ModuleName: "rolemenu",
+	CommandHandlers: map[string]command.Handler{
+		"rolemenu": commandRoleMenuObject,
+	},
+	ComponentHandlers: map[string]component.Handler{
+		"rolemenu": component.Handler{Code: ComponentRoleMenu},
+	},
+}
+
+var commandRoleMenuObject = command.Handler{
+	Description: "Let people pick their own roles from menus",
+	Code:        CommandRoleMenu,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "group",
+			Description: "Make a group of roles, like colors or pronouns, or change one",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "name",
+					Description: "What the group is called",
+					Required:    true,
+				},
+				&discord.BooleanOption{
+					OptionName:  "exclusive",
+					Description: "Only allow one role from the group at a time",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "add",
+			Description: "Add a role to a group",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "group",
+					Description: "The group to add it to",
+					Required:    true,
+				},
+				&discord.RoleOption{
+					OptionName:  "role",
+					Description: "The role to add",
+					Required:    true,
+				},
+				&discord.StringOption{
+					OptionName:  "description",
+					Description: "What the role is for, shown in the menu",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "remove",
+			Description: "Remove a role from a group, or the whole group",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "group",
+					Description: "The group to remove from",
+					Required:    true,
+				},
+				&discord.RoleOption{
+					OptionName:  "role",
+					Description: "The role to remove, blank to remove the whole group",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "list",
+			Description: "List the groups and their roles",
+			Options:     []discord.CommandOptionValue{},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "create",
+			Description: "Post a menu for people to pick roles from",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "groups",
+					Description: "The groups to include, separated by commas. Default is all of them.",
+					Required:    false,
+				},
+				&discord.StringOption{
+					OptionName:  "text",
+					Description: "What it says above the menus",
+					Required:    false,
+				},
+			},
+		},
+	},
+}
+
+// roleMenuGroupName makes group names case insensitive.
+func roleMenuGroupName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// CommandRoleMenu processes the /rolemenu command, dispatching to the right subcommand.
+func CommandRoleMenu(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /rolemenu command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "group":
+		return SubCommandRoleMenuGroup(kvs, event, cmd.Options[0].Options)
+	case "add":
+		return SubCommandRoleMenuAdd(state, kvs, event, cmd.Options[0].Options)
+	case "remove":
+		return SubCommandRoleMenuRemove(kvs, event, cmd.Options[0].Options)
+	case "list":
+		return SubCommandRoleMenuList(kvs, event)
+	case "create":
+		return SubCommandRoleMenuCreate(state, kvs, event, cmd.Options[0].Options)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandRoleMenuGroup makes a group, or changes if it's exclusive.
+func SubCommandRoleMenuGroup(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	name := roleMenuGroupName(options.Find("name").String())
+	if name == "" || strings.Contains(name, ",") || len(name) > 50 {
+		return command.Response{Response: response.Ephemeral("Group names can't be blank, have commas in them, or be longer than 50 characters.")}
+	}
+	exist, group, err := storage.GetRoleMenuGroup(kvs, event.GuildID, name)
+	if err != nil {
+		log.Printf("[%s] /rolemenu group failed to get group %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		group = storage.RoleMenuGroup{Name: name}
+	}
+	if options.Find("exclusive").Name != "" {
+		group.Exclusive, _ = options.Find("exclusive").BoolValue()
+	}
+	if err := group.Store(kvs, event.GuildID); err != nil {
+		log.Printf("[%s] Failed to store role menu group %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> set up the role menu group %s", event.GuildID, event.SenderID(), name)
+	return command.Response{Response: response.Ephemeral(describeRoleMenuGroup(group))}
+}
+
+// SubCommandRoleMenuAdd adds a role to a group.
+func SubCommandRoleMenuAdd(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	name := roleMenuGroupName(options.Find("group").String())
+	exist, group, err := storage.GetRoleMenuGroup(kvs, event.GuildID, name)
+	if err != nil {
+		log.Printf("[%s] /rolemenu add failed to get group %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no group called %q. Make it with `/rolemenu group` first.", name))}
+	}
+	roleSnowflake, err := options.Find("role").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /rolemenu add failed to get role snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the role. It has been logged.")}
+	}
+	roleID := discord.RoleID(roleSnowflake)
+	if err := utility.CanManageRole(state, event.GuildID, event.Member, roleID); err != nil {
+		return command.Response{Response: response.Ephemeral(hierarchyProblem(event.GuildID, err))}
+	}
+	description := strings.TrimSpace(options.Find("description").String())
+	if len([]rune(description)) > 100 {
+		return command.Response{Response: response.Ephemeral("The description can't be longer than 100 characters.")}
+	}
+	replaced := false
+	for i, role := range group.Roles {
+		if role.RoleID == roleID {
+			group.Roles[i].Description = description
+			replaced = true
+		}
+	}
+	if !replaced {
+		if len(group.Roles) >= roleMenuMaxRoles {
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("A group can only have %d roles, as that's all a menu can hold.", roleMenuMaxRoles))}
+		}
+		group.Roles = append(group.Roles, storage.RoleMenuRole{RoleID: roleID, Description: description})
+	}
+	if err := group.Store(kvs, event.GuildID); err != nil {
+		log.Printf("[%s] Failed to store role menu group %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> added <@&%s> to the role menu group %s", event.GuildID, event.SenderID(), roleID, name)
+	return command.Response{Response: response.Ephemeral(describeRoleMenuGroup(group))}
+}
+
+// SubCommandRoleMenuRemove removes a role from a group, or the whole group.
+func SubCommandRoleMenuRemove(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	name := roleMenuGroupName(options.Find("group").String())
+	exist, group, err := storage.GetRoleMenuGroup(kvs, event.GuildID, name)
+	if err != nil {
+		log.Printf("[%s] /rolemenu remove failed to get group %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no group called %q.", name))}
+	}
+	if options.Find("role").Name == "" {
+		if err := storage.DeleteRoleMenuGroup(kvs, event.GuildID, name); err != nil {
+			log.Printf("[%s] Failed to delete role menu group %s: %s", event.GuildID, name, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		log.Printf("[%s] <@%s> removed the role menu group %s", event.GuildID, event.SenderID(), name)
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("The %q group is gone. Menus that had it don't work for it anymore.", name))}
+	}
+	roleSnowflake, err := options.Find("role").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /rolemenu remove failed to get role snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the role. It has been logged.")}
+	}
+	roleID := discord.RoleID(roleSnowflake)
+	kept := []storage.RoleMenuRole{}
+	for _, role := range group.Roles {
+		if role.RoleID != roleID {
+			kept = append(kept, role)
+		}
+	}
+	group.Roles = kept
+	if err := group.Store(kvs, event.GuildID); err != nil {
+		log.Printf("[%s] Failed to store role menu group %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> removed <@&%s> from the role menu group %s", event.GuildID, event.SenderID(), roleID, name)
+	return command.Response{Response: response.Ephemeral(describeRoleMenuGroup(group))}
+}
+
+// SubCommandRoleMenuList lists the groups and their roles.
+func SubCommandRoleMenuList(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	groups, err := storage.GetRoleMenuGroups(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /rolemenu list failed to get groups: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(groups) == 0 {
+		return command.Response{Response: response.Ephemeral("There are no role menu groups. Make one with `/rolemenu group`.")}
+	}
+	described := make([]string, len(groups))
+	for i, group := range groups {
+		described[i] = describeRoleMenuGroup(group)
+	}
+	return command.Response{Response: response.Ephemeral(strings.Join(described, "\n\n"))}
+}
+
+func describeRoleMenuGroup(group storage.RoleMenuGroup) string {
+	kind := "pick any"
+	if group.Exclusive {
+		kind = "pick one"
+	}
+	lines := []string{fmt.Sprintf("**%s** (%s)", group.Name, kind)}
+	if len(group.Roles) == 0 {
+		lines = append(lines, "No roles yet. Add some with `/rolemenu add`.")
+	}
+	for _, role := range group.Roles {
+		line := "- " + role.RoleID.Mention()
+		if role.Description != "" {
+			line += ": " + role.Description
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SubCommandRoleMenuCreate posts a message with a select menu for each group.
+func SubCommandRoleMenuCreate(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	groups := []storage.RoleMenuGroup{}
+	if options.Find("groups").Name == "" {
+		all, err := storage.GetRoleMenuGroups(kvs, event.GuildID)
+		if err != nil {
+			log.Printf("[%s] /rolemenu create failed to get groups: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		groups = all
+	} else {
+		for _, name := range strings.Split(options.Find("groups").String(), ",") {
+			name = roleMenuGroupName(name)
+			if name == "" {
+				continue
+			}
+			exist, group, err := storage.GetRoleMenuGroup(kvs, event.GuildID, name)
+			if err != nil {
+				log.Printf("[%s] /rolemenu create failed to get group %s: %s", event.GuildID, name, err)
+				return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+			}
+			if !exist {
+				return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no group called %q.", name))}
+			}
+			groups = append(groups, group)
+		}
+	}
+	if len(groups) == 0 {
+		return command.Response{Response: response.Ephemeral("There are no groups to make a menu of. Make one with `/rolemenu group`.")}
+	}
+	if len(groups) > roleMenuMaxGroups {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("A message can only have %d menus. Pick some groups with the `groups` option, and make another menu for the rest.", roleMenuMaxGroups))}
+	}
+
+	guild, err := state.Guild(event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /rolemenu create could not get the guild: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	guildRoles := map[discord.RoleID]discord.Role{}
+	for _, role := range guild.Roles {
+		guildRoles[role.ID] = role
+	}
+
+	components := discord.ContainerComponents{}
+	for _, group := range groups {
+		selectOptions := []discord.SelectOption{}
+		for _, role := range group.Roles {
+			guildRole, ok := guildRoles[role.RoleID]
+			if !ok {
+				continue // Deleted since it was added.
+			}
+			selectOptions = append(selectOptions, discord.SelectOption{
+				Label:       guildRole.Name,
+				Value:       role.RoleID.String(),
+				Description: role.Description,
+			})
+		}
+		if len(selectOptions) == 0 {
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("The %q group has no roles to pick from.", group.Name))}
+		}
+		limits := [2]int{0, len(selectOptions)}
+		if group.Exclusive {
+			limits[1] = 1
+		}
+		components = append(components, &discord.ActionRowComponent{
+			&discord.SelectComponent{
+				CustomID:    component.ID("rolemenu", group.Name),
+				Placeholder: "Pick your " + group.Name,
+				Options:     selectOptions,
+				ValueLimits: limits,
+			},
+		})
+	}
+
+	text := strings.TrimSpace(options.Find("text").String())
+	if text == "" {
+		text = "Pick your roles! What you pick in a menu replaces what you had from it, so pick nothing to get rid of them all."
+	}
+	log.Printf("[%s] <@%s> posted a role menu in <#%s>", event.GuildID, event.SenderID(), event.ChannelID)
+	return command.Response{Response: api.InteractionResponse{
+		Type: api.MessageInteractionWithSource,
+		Data: &api.InteractionResponseData{
+			Content:         option.NewNullableString(text),
+			Components:      &components,
+			AllowedMentions: &api.AllowedMentions{Parse: []api.AllowedMentionType{}},
+		},
+	}}
+}
+
+// ComponentRoleMenu gives the member the roles they picked from a group, and takes away the ones they didn't, all in one go.
+func ComponentRoleMenu(ctx *command.Context, interaction discord.ComponentInteraction, params []string) api.InteractionResponse {
+	state, kvs, e := ctx.State, ctx.KVS, ctx.Event
+	selected, ok := interaction.(*discord.SelectInteraction)
+	if !ok {
+		log.Printf("[%s] Role menu got a component interaction that isn't a select: %s", e.GuildID, interaction.ID())
+		return response.Ephemeral("I'm sorry, what? Something very weird happened.")
+	}
+	name := strings.Join(params, "/") // Menus posted before names were encoded may have slashes in them.
+	exist, group, err := storage.GetRoleMenuGroup(kvs, e.GuildID, name)
+	if err != nil {
+		log.Printf("[%s] Role menu failed to get group %s: %s", e.GuildID, name, err)
+		return response.Ephemeral("There was an issue processing your role request. It has been logged.")
+	}
+	if !exist {
+		return response.Ephemeral("This menu is out of date, as its group is gone.")
+	}
+
+	picked := map[discord.RoleID]bool{}
+	for _, value := range selected.Values {
+		snowflake, err := discord.ParseSnowflake(value)
+		if err != nil {
+			log.Printf("[%s] Malformed role menu value: %s", e.GuildID, value)
+			return response.Ephemeral("That was kind of a malformed role request. What happened?")
+		}
+		roleID := discord.RoleID(snowflake)
+		if !group.Has(roleID) {
+			continue // Taken out of the group since the menu was posted.
+		}
+		picked[roleID] = true
+		if group.Exclusive {
+			break
+		}
+	}
+
+	member, err := state.Member(e.GuildID, e.SenderID())
+	if err != nil {
+		log.Printf("[%s] Role menu could not get the member <@%s>: %s", e.GuildID, e.SenderID(), err)
+		return response.Ephemeral("There was an issue processing your role request. It has been logged.")
+	}
+	roles := []discord.RoleID{}
+	removed := []string{}
+	had := map[discord.RoleID]bool{}
+	for _, roleID := range member.RoleIDs {
+		had[roleID] = true
+		if group.Has(roleID) && !picked[roleID] {
+			removed = append(removed, roleID.Mention())
+			continue
+		}
+		roles = append(roles, roleID)
+	}
+	added := []string{}
+	for _, role := range group.Roles {
+		if picked[role.RoleID] && !had[role.RoleID] {
+			roles = append(roles, role.RoleID)
+			added = append(added, role.RoleID.Mention())
+		}
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		return response.Ephemeral("That's what you already have, so nothing changed.")
+	}
+	for _, role := range group.Roles {
+		if picked[role.RoleID] == had[role.RoleID] {
+			continue
+		}
+		if err := utility.CanManageRole(state, e.GuildID, nil, role.RoleID); err != nil {
+			return response.Ephemeral("I'm sorry, but I can't change", role.RoleID.Mention()+".", hierarchyProblem(e.GuildID, err))
+		}
+	}
+
+	err = state.ModifyMember(e.GuildID, e.SenderID(), api.ModifyMemberData{
+		Roles:          &roles,
+		AuditLogReason: api.AuditLogReason("Member picked roles from the " + group.Name + " role menu"),
+	})
+	if err != nil {
+		log.Printf("[%s] Role menu failed to change the roles of <@%s>: %s", e.GuildID, e.SenderID(), err)
+		return response.Ephemeral("Ooops! I'm sorry, but I couldn't change your roles. The error has been logged.")
+	}
+	reply := []string{}
+	if len(added) > 0 {
+		reply = append(reply, "You now have "+strings.Join(added, " "))
+	}
+	if len(removed) > 0 {
+		reply = append(reply, "You no longer have "+strings.Join(removed, " "))
+	}
+	return response.Ephemeral(strings.Join(reply, "\n"))
+}
@@ -5,6 +5,7 @@ import (
 	"komainu/interactions/component"
 	"komainu/interactions/delete"
 	"komainu/interactions/modal"
+	"komainu/interactions/module"
 	"komainu/interactions/response"
 	"komainu/storage"
 	"komainu/utility"
@@ -20,29 +21,35 @@ import (
 	"github.com/diamondburned/arikawa/v3/utils/json/option"
 )
 
-func init() {
-	delete.Register(delete.Handler{Code: DeleteRoleSelector})
-	command.Register("roleselect", command.Handler{
-		Description: "Create a role self-assignment message",
-		Options:     createRoleOptions(),
-		Code:        CommandRoleSelector,
-	})
-	modal.Register("roleselect", modal.Handler{Code: RoleSelectorModalHandler})
-	component.Register("roleselect", component.Handler{Code: ComponentRoleSelector})
-
-	delete.Register(delete.Handler{Code: DeleteRoleButton})
-	command.Register("rolebutton", command.Handler{
-		Description: "Create a message with a button that assigns a role",
-		Options: []discord.CommandOption{
-			&discord.RoleOption{
-				OptionName:  "role",
-				Description: "The role you want a button for",
+var rolesModule = module.Basic{
+	ModuleName: "roles",
+	CommandHandlers: map[string]command.Handler{
+		"roleselect": command.Handler{
+			Description: "Create a role self-assignment message",
+			Options:     createRoleOptions(),
+			Code:        CommandRoleSelector,
+		},
+		"rolebutton": command.Handler{
+			Description: "Create a message with a button that assigns a role",
+			Options: []discord.CommandOption{
+				&discord.RoleOption{
+					OptionName:  "role",
+					Description: "The role you want a button for",
+				},
 			},
+			Code: CommandRoleButton,
 		},
-		Code: CommandRoleButton,
-	})
-	modal.Register("rolebutton", modal.Handler{Code: RoleButtonModalHandler})
-	component.Register("rolebutton", component.Handler{Code: ComponentRoleButton})
+	},
+	ComponentHandlers: map[string]component.Handler{
+		"roleselect": component.Handler{Code: ComponentRoleSelector},
+		"rolebutton": component.Handler{Code: ComponentRoleButton},
+	},
+	Handlers: []module.EventHandler{
+		module.Delete(delete.Handler{Code: DeleteRoleSelector}),
+		module.Modal("roleselect", modal.Handler{Code: RoleSelectorModalHandler}),
+		module.Delete(delete.Handler{Code: DeleteRoleButton}),
+		module.Modal("rolebutton", modal.Handler{Code: RoleButtonModalHandler}),
+	},
 }
 
 var roleFinder = regexp.MustCompile("<@&[0-9]+>")
@@ -58,6 +65,16 @@ func createRoleOptions() []discord.CommandOption {
 	return opt
 }
 
+// hierarchyProblem says why a role or member is out of reach, as found by utility.CanManageRole or utility.CanModerate.
+// Failing to look it all up is logged, as there's nothing whoever asked can do about that.
+func hierarchyProblem(guildID discord.GuildID, err error) string {
+	if utility.OutOfReach(err) {
+		return err.Error()
+	}
+	log.Printf("[%s] Failed to check the role hierarchy: %s", guildID, err)
+	return "I couldn't check if that's within my reach. The error has been logged."
+}
+
 func makeRoleMap(guild *discord.Guild) map[int64]discord.Role {
 	roleMap := map[int64]discord.Role{}
 	for _, role := range guild.Roles {
@@ -92,7 +109,7 @@ func makeRoleButtons(roleIDs []int64, guildRoles map[int64]discord.Role) *discor
 		}
 		button := &discord.ButtonComponent{
 			Style:    discord.PrimaryButtonStyle(),
-			CustomID: discord.ComponentID("roleselect/" + strconv.FormatInt(roleID, 10)),
+			CustomID: component.ID("roleselect", strconv.FormatInt(roleID, 10)),
 			Label:    guildRoles[roleID].Name,
 		}
 		row = append(row, button)
@@ -124,7 +141,8 @@ func DeleteRoleButton(state *state.State, kvs storage.KeyValueStore, e *gateway.
 }
 
 // CommandRoleSelector handles when the /roleselector command is issued
-func CommandRoleSelector(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, cmd *discord.CommandInteraction) command.Response {
+func CommandRoleSelector(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
 	thisGuild, err := state.Guild(event.GuildID)
 	if err != nil {
 		log.Printf("[%s] Could not determine current guild: %s\n", event.GuildID, err)
@@ -140,6 +158,9 @@ func CommandRoleSelector(state *state.State, kvs storage.KeyValueStore, event *g
 				continue
 			}
 			if role, ok := guildRoles[roleID]; ok {
+				if err := utility.CanManageRole(state, event.GuildID, event.Member, role.ID); err != nil {
+					return command.Response{Response: response.Ephemeral(role.ID.Mention()+":", hierarchyProblem(event.GuildID, err))}
+				}
 				rolesList.WriteString("<@&")
 				rolesList.WriteString(strconv.FormatInt(roleID, 10))
 				rolesList.WriteString("> (Describe \"")
@@ -153,7 +174,7 @@ func CommandRoleSelector(state *state.State, kvs storage.KeyValueStore, event *g
 		}
 	}
 	return command.Response{Response: modal.Respond(
-		event.SenderID(), event.GuildID, "roleselect", "Describe and tag the roles",
+		kvs, event, "roleselect", "Describe and tag the roles", nil,
 		discord.TextInputComponent{
 			CustomID:     discord.ComponentID("roles"),
 			Style:        discord.TextInputParagraphStyle,
@@ -165,7 +186,8 @@ func CommandRoleSelector(state *state.State, kvs storage.KeyValueStore, event *g
 }
 
 // CommandRoleButton handles when the /rolebutton command is issued
-func CommandRoleButton(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, cmd *discord.CommandInteraction) command.Response {
+func CommandRoleButton(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
 
 	roleID := discord.NullRoleID
 
@@ -190,6 +212,9 @@ func CommandRoleButton(state *state.State, kvs storage.KeyValueStore, event *gat
 				log.Printf("[%s] Non-existant role attached to /rolebutton ?!", event.GuildID)
 				return command.Response{Response: response.Ephemeral("Sorry, this role is not available.")}
 			}
+			if err := utility.CanManageRole(state, event.GuildID, event.Member, useRole.ID); err != nil {
+				return command.Response{Response: response.Ephemeral(hierarchyProblem(event.GuildID, err))}
+			}
 			roleID = useRole.ID
 		}
 	}
@@ -199,7 +224,7 @@ func CommandRoleButton(state *state.State, kvs storage.KeyValueStore, event *gat
 	}
 
 	return command.Response{Response: modal.Respond(
-		event.SenderID(), event.GuildID, "rolebutton", "Make a button for role assignment",
+		kvs, event, "rolebutton", "Make a button for role assignment", nil,
 		discord.TextInputComponent{
 			CustomID:     discord.ComponentID("description"),
 			Style:        discord.TextInputParagraphStyle,
@@ -227,7 +252,8 @@ func CommandRoleButton(state *state.State, kvs storage.KeyValueStore, event *gat
 }
 
 // RoleSelectorModalHandler handles when a modal for a Role Selector configuration is submitted
-func RoleSelectorModalHandler(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, interaction *discord.ModalInteraction) command.Response {
+func RoleSelectorModalHandler(ctx *command.Context, interaction *discord.ModalInteraction, session map[string]string) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
 	data := modal.DecodeModalResponse(interaction.Components)
 	rawText := ""
 	if val, ok := data["roles"]; ok {
@@ -280,7 +306,8 @@ func RoleSelectorModalHandler(state *state.State, kvs storage.KeyValueStore, eve
 }
 
 // RoleButtonModalHandler handles the returned data from the role button creation modal
-func RoleButtonModalHandler(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, interaction *discord.ModalInteraction) command.Response {
+func RoleButtonModalHandler(ctx *command.Context, interaction *discord.ModalInteraction, session map[string]string) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
 	data := modal.DecodeModalResponse(interaction.Components)
 	roleButton := storage.RoleButton{
 		GuildID:   event.GuildID,
@@ -342,7 +369,8 @@ func RoleButtonModalHandler(state *state.State, kvs storage.KeyValueStore, event
 }
 
 // ComponentRoleButton handles interactions from a role button
-func ComponentRoleButton(state *state.State, kvs storage.KeyValueStore, e *gateway.InteractionCreateEvent, interaction discord.ComponentInteraction) api.InteractionResponse {
+func ComponentRoleButton(ctx *command.Context, interaction discord.ComponentInteraction, params []string) api.InteractionResponse {
+	state, kvs, e := ctx.State, ctx.KVS, ctx.Event
 	exist, roleID, err := storage.GetRoleForButton(kvs, e.GuildID, e.Message.ID)
 	if err != nil {
 		log.Printf("[%s] Error while trying to fetch the RoleButton while processing a role request:  %s", e.GuildID, err)
@@ -374,6 +402,9 @@ func ComponentRoleButton(state *state.State, kvs storage.KeyValueStore, e *gatew
 	if utility.ContainsRole(member.RoleIDs, roleID) {
 		return response.Ephemeral("You already have the role. Enjoy!")
 	} else {
+		if err := utility.CanManageRole(state, e.GuildID, nil, roleID); err != nil {
+			return response.Ephemeral("I'm sorry, but I can't give you that role.", hierarchyProblem(e.GuildID, err))
+		}
 		err := state.AddRole(e.GuildID, member.User.ID, roleID, api.AddRoleData{
 			AuditLogReason: "Member got the role using a RoleButton",
 		})
@@ -387,7 +418,8 @@ func ComponentRoleButton(state *state.State, kvs storage.KeyValueStore, e *gatew
 }
 
 // ComponentRoleSelector handkes intractions from the role selector button components
-func ComponentRoleSelector(state *state.State, kvs storage.KeyValueStore, e *gateway.InteractionCreateEvent, interaction discord.ComponentInteraction) api.InteractionResponse {
+func ComponentRoleSelector(ctx *command.Context, interaction discord.ComponentInteraction, params []string) api.InteractionResponse {
+	state, kvs, e := ctx.State, ctx.KVS, ctx.Event
 	exist, selector, err := storage.GetRoleSelector(kvs, e.GuildID, e.Message.ID)
 	if err != nil {
 		log.Printf("[%s] Error while trying to fetch the RoleSelector while processing a role request:  %s", e.GuildID, err)
@@ -398,11 +430,13 @@ func ComponentRoleSelector(state *state.State, kvs storage.KeyValueStore, e *gat
 		return response.Ephemeral("I'm very sorry, but I couldn't authenticate the role request.")
 	}
 
-	selectionString := strings.TrimPrefix(string(interaction.ID()), "roleselect/")
-
-	roleID, err := strconv.ParseInt(selectionString, 10, 64)
+	if len(params) != 1 {
+		log.Printf("[%s] Malformed role request: %s", e.GuildID, interaction.ID())
+		return response.Ephemeral("That was kind of a malformed role request. What happened?")
+	}
+	roleID, err := strconv.ParseInt(params[0], 10, 64)
 	if err != nil {
-		log.Printf("[%s] Malformed role request: %s", e.GuildID, selectionString)
+		log.Printf("[%s] Malformed role request: %s", e.GuildID, params[0])
 		return response.Ephemeral("That was kind of a malformed role request. What happened?")
 	}
 
@@ -430,6 +464,9 @@ func ComponentRoleSelector(state *state.State, kvs storage.KeyValueStore, e *gat
 		return response.Ephemeral("That was kind of an odd role request. What happened?")
 	}
 
+	if err := utility.CanManageRole(state, e.GuildID, nil, role.ID); err != nil {
+		return response.Ephemeral("I'm sorry, but I can't do anything about that role.", hierarchyProblem(e.GuildID, err))
+	}
 	if utility.ContainsRole(member.RoleIDs, role.ID) {
 		err := state.RemoveRole(e.GuildID, member.User.ID, role.ID, api.AuditLogReason("Member removed role using a RoleSelector button"))
 		if err != nil {
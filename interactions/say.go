@@ -0,0 +1,433 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/modal"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/interactions/timer"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+// sayScheduleLimit is how many messages a guild can have waiting to be posted.
+const sayScheduleLimit = 25
+
+// sayMaxAhead is how far ahead a message can be scheduled.
+const sayMaxAhead = 365 * 24 * time.Hour
+
+var sayModule = module.Basic{
+	ModuleName: "say",
+	CommandHandlers: map[string]command.Handler{
+		"say": commandSayObject,
+	},
+	Handlers: []module.EventHandler{
+		module.Modal("say", modal.Handler{Code: SayModalHandler}),
+		module.Timer("say", timer.Handler{Code: TimerSay}),
+	},
+}
+
+var commandSayObject = command.Handler{
+	Description: "Make the bot post a message, now or later",
+	Code:        CommandSay,
+	Public:      true, // Checked in CommandSay, as the roles allowed to use it are set with /say access.
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "compose",
+			Description: "Write a message or embed for the bot to post in a channel",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:   "channel",
+					Description:  "Where to post it",
+					Required:     true,
+					ChannelTypes: []discord.ChannelType{discord.GuildText, discord.GuildNews},
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "schedule",
+			Description: "Post a message in a channel at a later time",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:   "channel",
+					Description:  "Where to post it",
+					Required:     true,
+					ChannelTypes: []discord.ChannelType{discord.GuildText, discord.GuildNews},
+				},
+				&discord.StringOption{
+					OptionName:  "time",
+					Description: "When to post it, like 2024-07-01 18:00, 18:00, or 2h from now",
+					Required:    true,
+				},
+				&discord.StringOption{
+					OptionName:  "message",
+					Description: "What to post",
+					Required:    true,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "list",
+			Description: "List the messages waiting to be posted",
+			Options:     []discord.CommandOptionValue{},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "cancel",
+			Description: "Stop a message from being posted",
+			Options: []discord.CommandOptionValue{
+				&discord.IntegerOption{
+					OptionName:  "number",
+					Description: "The number of the message, as seen in /say list",
+					Required:    true,
+					Min:         option.NewInt(1),
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "access",
+			Description: "Let a role use /say, or stop letting it",
+			Options: []discord.CommandOptionValue{
+				&discord.RoleOption{
+					OptionName:  "role",
+					Description: "The role to let in, or keep out",
+					Required:    true,
+				},
+			},
+		},
+	},
+}
+
+// CommandSay processes the /say command, dispatching to the right subcommand.
+func CommandSay(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /say command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	permissions, err := state.Permissions(event.ChannelID, event.SenderID())
+	if err != nil {
+		log.Printf("[%s] /say failed to get permissions of <@%s>: %s", event.GuildID, event.SenderID(), err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	admin := permissions.Has(discord.PermissionAdministrator)
+	if cmd.Options[0].Name == "access" {
+		if !admin {
+			return command.Response{Response: response.Ephemeral("Only administrators can change who gets to use /say.")}
+		}
+		return SubCommandSayAccess(kvs, event, cmd.Options[0].Options)
+	}
+	if !admin {
+		allowed, err := storage.GetSayRoles(kvs, event.GuildID)
+		if err != nil {
+			log.Printf("[%s] /say failed to get the roles allowed to use it: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		if !utility.RoleInCommon(allowed, event.Member.RoleIDs) {
+			return command.Response{Response: response.Ephemeral("Sorry, you don't have a role that's allowed to use /say.")}
+		}
+	}
+	switch cmd.Options[0].Name {
+	case "compose":
+		return SubCommandSayCompose(kvs, event, cmd.Options[0].Options)
+	case "schedule":
+		return SubCommandSaySchedule(kvs, event, cmd.Options[0].Options)
+	case "list":
+		return SubCommandSayList(kvs, event)
+	case "cancel":
+		return SubCommandSayCancel(kvs, event, cmd.Options[0].Options)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandSayAccess toggles if a role can use /say.
+func SubCommandSayAccess(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	roleSnowflake, err := options.Find("role").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /say access failed to get role snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the role. It has been logged.")}
+	}
+	roleID := discord.RoleID(roleSnowflake)
+	roles, err := storage.GetSayRoles(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /say access failed to get the roles: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	verb := "can no longer"
+	if utility.ContainsRole(roles, roleID) {
+		kept := []discord.RoleID{}
+		for _, role := range roles {
+			if role != roleID {
+				kept = append(kept, role)
+			}
+		}
+		roles = kept
+	} else {
+		roles = append(roles, roleID)
+		verb = "can now"
+	}
+	if err := storage.SetSayRoles(kvs, event.GuildID, roles); err != nil {
+		log.Printf("[%s] /say access failed to store the roles: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> changed /say access: <@&%s> %s use it", event.GuildID, event.SenderID(), roleID, verb)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("%s %s use /say.", roleID.Mention(), verb))}
+}
+
+// SubCommandSayCompose opens a form for writing the message, and the embed to go with it if any.
+func SubCommandSayCompose(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /say compose failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	return command.Response{Response: modal.Respond(
+		kvs, event, "say", "Say something", map[string]string{"channel": discord.ChannelID(channelSnowflake).String()},
+		discord.TextInputComponent{
+			CustomID:     discord.ComponentID("content"),
+			Label:        "Message",
+			Style:        discord.TextInputParagraphStyle,
+			LengthLimits: [2]int{0, 2000},
+		},
+		discord.TextInputComponent{
+			CustomID:     "title",
+			Label:        "Embed title",
+			Style:        discord.TextInputShortStyle,
+			LengthLimits: [2]int{0, 256},
+		},
+		discord.TextInputComponent{
+			CustomID:     "description",
+			Label:        "Embed text",
+			Style:        discord.TextInputParagraphStyle,
+			LengthLimits: [2]int{0, 4000},
+		},
+		discord.TextInputComponent{
+			CustomID:     "color",
+			Label:        "Embed color, like #5865F2",
+			Style:        discord.TextInputShortStyle,
+			LengthLimits: [2]int{0, 7},
+		},
+		discord.TextInputComponent{
+			CustomID:     "image",
+			Label:        "Embed image link",
+			Style:        discord.TextInputShortStyle,
+			LengthLimits: [2]int{0, 1000},
+		},
+	)}
+}
+
+// SayModalHandler posts whatever was written in the /say compose form.
+func SayModalHandler(ctx *command.Context, interaction *discord.ModalInteraction, session map[string]string) command.Response {
+	state, event := ctx.State, ctx.Event
+	fields := modal.DecodeModalResponse(interaction.Components)
+	channelSnowflake, err := discord.ParseSnowflake(session["channel"])
+	if err != nil {
+		log.Printf("[%s] Say modal has a weird channel: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	channelID := discord.ChannelID(channelSnowflake)
+
+	message := api.SendMessageData{Content: strings.TrimSpace(fields["content"])}
+	embed := discord.Embed{
+		Title:       strings.TrimSpace(fields["title"]),
+		Description: strings.TrimSpace(fields["description"]),
+	}
+	if color := strings.TrimPrefix(strings.TrimSpace(fields["color"]), "#"); color != "" {
+		value, err := strconv.ParseUint(color, 16, 32)
+		if err != nil || value > 0xFFFFFF {
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("I don't understand %q as a color. Give it as a hex code, like `#5865F2`.", fields["color"]))}
+		}
+		embed.Color = discord.Color(value)
+	}
+	if image := strings.TrimSpace(fields["image"]); image != "" {
+		if !strings.HasPrefix(image, "https://") && !strings.HasPrefix(image, "http://") {
+			return command.Response{Response: response.Ephemeral("The image has to be a link, starting with `https://`.")}
+		}
+		embed.Image = &discord.EmbedImage{URL: image}
+	}
+	if embed.Title != "" || embed.Description != "" || embed.Image != nil {
+		message.Embeds = []discord.Embed{embed}
+	}
+	if message.Content == "" && len(message.Embeds) == 0 {
+		return command.Response{Response: response.Ephemeral("There's nothing to say! Write a message, or fill in the embed.")}
+	}
+
+	posted, err := state.SendMessageComplex(channelID, message)
+	if err != nil {
+		log.Printf("[%s] /say compose failed to post in <#%s>: %s", event.GuildID, channelID, err)
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("I couldn't post that in %s. Do I have permission to?", channelID.Mention()))}
+	}
+	log.Printf("[%s] <@%s> made me say something in <#%s>", event.GuildID, event.SenderID(), channelID)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Posted: %s", posted.URL()))}
+}
+
+// parseSayTime figures out when to post a scheduled message. That's either a date and time, like "2024-07-01 18:00",
+// a time of day, like "18:00", which is the next time it comes around, or an amount of time, like "2h" or "1d12h".
+// Times are in the given time zone. If it won't work, problem says why.
+func parseSayTime(text string, now time.Time, location *time.Location) (when time.Time, problem string) {
+	text = strings.TrimSpace(text)
+	if length, err := utility.ParseDuration(text); err == nil {
+		when = now.Add(length)
+	} else if clock, err := time.Parse("15:04", text); err == nil {
+		local := now.In(location)
+		when = time.Date(local.Year(), local.Month(), local.Day(), clock.Hour(), clock.Minute(), 0, 0, location)
+		if !when.After(now) {
+			when = when.AddDate(0, 0, 1)
+		}
+	} else {
+		parsed := false
+		for _, layout := range []string{"2006-01-02 15:04", "2006-01-02T15:04"} {
+			if when, err = time.ParseInLocation(layout, text, location); err == nil {
+				parsed = true
+				break
+			}
+		}
+		if !parsed {
+			return when, fmt.Sprintf("I don't understand %q as a time. Give a date and time, like `2024-07-01 18:00`, a time of day, like `18:00`, or an amount of time, like `2h`.", text)
+		}
+	}
+	if !when.After(now) {
+		return when, fmt.Sprintf("%s has already passed.", when.In(location).Format("2006-01-02 15:04"))
+	}
+	if when.Sub(now) > sayMaxAhead {
+		return when, "A message can't be scheduled more than a year ahead."
+	}
+	return when, ""
+}
+
+// scheduledSays gets the timers of the messages waiting to be posted, soonest first.
+func scheduledSays(kvs storage.KeyValueStore, guildID discord.GuildID) ([]storage.Timer, error) {
+	timers, err := storage.GetTimers(kvs, guildID)
+	if err != nil {
+		return nil, err
+	}
+	says := []storage.Timer{}
+	for _, t := range timers {
+		if t.Kind == "say" {
+			says = append(says, t)
+		}
+	}
+	sort.Slice(says, func(i, j int) bool {
+		return says[i].When < says[j].When
+	})
+	return says, nil
+}
+
+// SubCommandSaySchedule queues a message to be posted at a later time.
+func SubCommandSaySchedule(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /say schedule failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	channelID := discord.ChannelID(channelSnowflake)
+	content := strings.TrimSpace(options.Find("message").String())
+	if len([]rune(content)) > 2000 {
+		return command.Response{Response: response.Ephemeral("That's too long for a single message, sorry. Discord won't take more than 2000 characters.")}
+	}
+	location, err := storage.GetTimezone(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /say schedule failed to get the time zone, going with UTC: %s", event.GuildID, err)
+	}
+	when, problem := parseSayTime(options.Find("time").String(), time.Now(), location)
+	if problem != "" {
+		return command.Response{Response: response.Ephemeral(problem)}
+	}
+
+	says, err := scheduledSays(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /say schedule failed to get the scheduled messages: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(says) >= sayScheduleLimit {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There are already %d messages waiting to be posted, which is as many as there can be.", sayScheduleLimit))}
+	}
+	number, err := storage.NextNumber(kvs, event.GuildID, "say")
+	if err != nil {
+		log.Printf("[%s] /say schedule failed to get a number: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	_, err = timer.Schedule(kvs, event.GuildID, "say", when, map[string]string{
+		"number":  strconv.FormatInt(number, 10),
+		"channel": channelID.String(),
+		"content": content,
+		"by":      event.SenderID().String(),
+	})
+	if err != nil {
+		log.Printf("[%s] /say schedule failed to schedule the timer: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> scheduled message %d for <#%s> at %s", event.GuildID, event.SenderID(), number, channelID, when.UTC().Format(time.RFC3339))
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Message **%d** will be posted in %s <t:%d:R>, at <t:%d:f>.", number, channelID.Mention(), when.Unix(), when.Unix()))}
+}
+
+// SubCommandSayList lists the messages waiting to be posted.
+func SubCommandSayList(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	says, err := scheduledSays(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /say list failed to get the scheduled messages: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(says) == 0 {
+		return command.Response{Response: response.Ephemeral("There are no messages waiting to be posted.")}
+	}
+	lines := make([]string, len(says))
+	for i, t := range says {
+		preview := utility.Substring(strings.ReplaceAll(t.Data["content"], "\n", " "), 0, 60)
+		lines[i] = fmt.Sprintf("**%s**: <#%s> <t:%d:R>, by <@%s>: %s", t.Data["number"], t.Data["channel"], t.When, t.Data["by"], preview)
+	}
+	return command.Response{Response: response.Ephemeral(utility.Substring(strings.Join(lines, "\n"), 0, 2000))}
+}
+
+// SubCommandSayCancel stops a message from being posted.
+func SubCommandSayCancel(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	number, err := options.Find("number").IntValue()
+	if err != nil {
+		log.Printf("[%s] /say cancel failed to get the number: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	says, err := scheduledSays(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /say cancel failed to get the scheduled messages: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	for _, t := range says {
+		if t.Data["number"] != strconv.FormatInt(number, 10) {
+			continue
+		}
+		if err := storage.CancelTimer(kvs, event.GuildID, t.ID); err != nil {
+			log.Printf("[%s] /say cancel failed to cancel message %d: %s", event.GuildID, number, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		log.Printf("[%s] <@%s> cancelled scheduled message %d", event.GuildID, event.SenderID(), number)
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("Message %d won't be posted.", number))}
+	}
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no message %d waiting to be posted.", number))}
+}
+
+// TimerSay posts a scheduled message.
+func TimerSay(state *state.State, kvs storage.KeyValueStore, t storage.Timer) {
+	channelID, err := discord.ParseSnowflake(t.Data["channel"])
+	if err != nil {
+		log.Printf("[%s] Scheduled message timer has a weird channel: %s", t.GuildID, err)
+		return
+	}
+	_, err = state.SendMessageComplex(discord.ChannelID(channelID), api.SendMessageData{Content: t.Data["content"]})
+	if err != nil {
+		log.Printf("[%s] Failed to post scheduled message %s in <#%s>: %s", t.GuildID, t.Data["number"], channelID, err)
+		return
+	}
+	log.Printf("[%s] Posted scheduled message %s in <#%s>", t.GuildID, t.Data["number"], channelID)
+}
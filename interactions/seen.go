@@ -2,74 +2,109 @@ package interactions
 
 import (
 	"bytes"
+	"context"
+	"encoding/csv"
 	"fmt"
 	"komainu/interactions/command"
+	"komainu/interactions/join"
 	"komainu/interactions/message"
+	"komainu/interactions/module"
+	"komainu/interactions/presence"
 	"komainu/interactions/response"
+	"komainu/membercache"
 	"komainu/storage"
+	"komainu/utility"
 	"log"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/diamondburned/arikawa/v3/api"
 	"github.com/diamondburned/arikawa/v3/discord"
 	"github.com/diamondburned/arikawa/v3/gateway"
 	"github.com/diamondburned/arikawa/v3/state"
 	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"github.com/diamondburned/arikawa/v3/utils/sendpart"
 )
 
-func init() {
-	command.Register("seen", command.Handler{
-		Description: "Check when someone was last around",
-		Code:        CommandSeen,
-		Options: []discord.CommandOption{
-			&discord.UserOption{
-				OptionName:  "user",
-				Description: "The user to look up",
-				Required:    true,
+var seenModule = module.Basic{
+	ModuleName: "seen",
+	CommandHandlers: map[string]command.Handler{
+		"seen": command.Handler{
+			Description: "Check when someone was last around",
+			Code:        CommandSeen,
+			Options: []discord.CommandOption{
+				&discord.UserOption{
+					OptionName:  "user",
+					Description: "The user to look up",
+					Required:    true,
+				},
 			},
 		},
-	})
-	command.Register("neverseen", command.Handler{
-		Description: "Get a list of people that the bot has never seen say anything!",
-		Code:        CommandNeverSeen,
-		Options:     []discord.CommandOption{},
-	})
-	command.Register("inactive", command.Handler{
-		Description: "Get a list of inactive people",
-		Code:        CommandInactive,
-		Options: []discord.CommandOption{
-			&discord.IntegerOption{
-				OptionName:  "days",
-				Description: "How many days of quiet makes someone inactive?",
-				Required:    true,
-			},
+		"neverseen": command.Handler{
+			Description: "Get a list of people that the bot has never seen say anything!",
+			Code:        CommandNeverSeen,
+			Options:     []discord.CommandOption{},
 		},
-	})
-	command.Register("activerole", command.Handler{
-		Description: "Set what role is granted and revoked for active/inactive users, and under what conditions.",
-		Code:        CommandActiveRole,
-		Options: []discord.CommandOption{
-			&discord.RoleOption{
-				OptionName:  "role",
-				Description: "The role to giveth and taketh away.",
-				Required:    true,
+		"inactive": command.Handler{
+			Description: "Get a list of inactive people",
+			Code:        CommandInactive,
+			Options: []discord.CommandOption{
+				&discord.IntegerOption{
+					OptionName:  "days",
+					Description: "How many days of quiet makes someone inactive? Default is 30.",
+					Required:    false,
+				},
+				&discord.StringOption{
+					OptionName:  "format",
+					Description: "What kind of file. Default is plain text.",
+					Required:    false,
+					Choices: []discord.StringChoice{
+						{Name: "Text", Value: "text"},
+						{Name: "CSV", Value: "csv"},
+					},
+				},
 			},
-			&discord.NumberOption{
-				OptionName:  "days",
-				Description: "How many days someone needs to be inactive to lose the role. Set to zero to disable this function.",
-				Required:    true,
-				Min:         option.NewFloat(0),
-				Max:         option.NewFloat(365),
+		},
+		"activerole": command.Handler{
+			Description: "Set what role is granted and revoked for active/inactive users, and under what conditions.",
+			Code:        CommandActiveRole,
+			Options: []discord.CommandOption{
+				&discord.RoleOption{
+					OptionName:  "role",
+					Description: "The role to giveth and taketh away.",
+					Required:    true,
+				},
+				&discord.NumberOption{
+					OptionName:  "days",
+					Description: "How many days someone needs to be inactive to lose the role. Set to zero to disable this function.",
+					Required:    true,
+					Min:         option.NewFloat(0),
+					Max:         option.NewFloat(365),
+				},
 			},
 		},
-	})
-	command.Register("seeeveryone", command.Handler{
-		Description: "Ruin the /seen system by marking everyone here as seen right now.",
-		Code:        CommandSeeEveryone,
-		Options:     []discord.CommandOption{},
-	})
-	message.Register(message.Handler{Code: MessageSeen})
+		"seeeveryone": command.Handler{
+			Description: "Ruin the /seen system by marking everyone here as seen right now.",
+			Code:        CommandSeeEveryone,
+			Options:     []discord.CommandOption{},
+		},
+	},
+	Handlers: []module.EventHandler{
+		module.Message(message.Handler{Code: MessageSeen}),
+		module.Join(join.Handler{Code: JoinSeen}),
+		module.Presence(presence.Handler{Code: PresenceSeen}),
+	},
 }
 
+// onlineInterval is how often someone is noted as online at most, as presence updates come thick and fast.
+const onlineInterval = 10 * time.Minute
+
+// onlineNoted is when each member was last noted as online, by guild.
+var onlineNoted = map[discord.GuildID]map[discord.UserID]time.Time{}
+var onlineLock sync.Mutex
+
 func MessageSeen(state *state.State, kvs storage.KeyValueStore, event *gateway.MessageCreateEvent) {
 	if event.GuildID == 0 {
 		return // It's either a private message, or an ephemeral-response command. Doesn't count.
@@ -79,14 +114,67 @@ func MessageSeen(state *state.State, kvs storage.KeyValueStore, event *gateway.M
 		log.Printf("[%s] Error seeing %s in %s: %s\n", event.GuildID, event.Author.ID, event.ChannelID, err)
 	} else {
 		log.Printf("[%s] <@%s> seen in <#%s>\n", event.GuildID, event.Author.ID, event.ChannelID)
+		if err := storage.RecordActivity(kvs, event.GuildID, event.Author.ID, time.Now()); err != nil {
+			log.Printf("[%s] Failed to record activity for %s: %s\n", event.GuildID, event.Author.ID, err)
+		}
 		if err := storage.MaybeGiveActiveRole(kvs, state, event.GuildID, event.Member); err != nil {
 			log.Printf("[%s] Failed to give active role to %s: %s\n", event.GuildID, event.Author.ID, err)
 		}
 	}
 }
 
+// JoinSeen notes when someone joined, so /inactive can tell new members from those that have been around.
+func JoinSeen(state *state.State, kvs storage.KeyValueStore, event *gateway.GuildMemberAddEvent) {
+	if event.User.Bot {
+		return
+	}
+	if err := storage.RecordJoin(kvs, event.GuildID, event.User.ID, event.Joined.Time()); err != nil {
+		log.Printf("[%s] Failed to record that <@%s> joined: %s", event.GuildID, event.User.ID, err)
+	}
+}
+
+// PresenceSeen notes when someone is online, if the guild opted in to that with /config presence.
+func PresenceSeen(state *state.State, kvs storage.KeyValueStore, event *gateway.PresenceUpdateEvent) {
+	if event.Status == discord.OfflineStatus || event.Status == discord.InvisibleStatus {
+		return
+	}
+	enabled, err := storage.GetPresenceTracking(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to check if presence is tracked: %s", event.GuildID, err)
+		return
+	}
+	if !enabled {
+		return
+	}
+
+	onlineLock.Lock()
+	noted, ok := onlineNoted[event.GuildID]
+	if !ok {
+		noted = map[discord.UserID]time.Time{}
+		onlineNoted[event.GuildID] = noted
+	}
+	if time.Since(noted[event.User.ID]) < onlineInterval {
+		onlineLock.Unlock()
+		return
+	}
+	noted[event.User.ID] = time.Now()
+	onlineLock.Unlock()
+
+	if err := storage.SeeOnline(kvs, event.GuildID, event.User.ID); err != nil {
+		log.Printf("[%s] Error noting %s as online: %s", event.GuildID, event.User.ID, err)
+	}
+}
+
+// forgetOnline forgets who was recently noted as online in the guild, so it starts over if it's turned back on.
+func forgetOnline(guildID discord.GuildID) {
+	onlineLock.Lock()
+	defer onlineLock.Unlock()
+	delete(onlineNoted, guildID)
+}
+
 // CommandSeen processes a command to look up when a user was last seen.
-func CommandSeen(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, cmd *discord.CommandInteraction) command.Response {
+func CommandSeen(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
 	if cmd.Options != nil && len(cmd.Options) > 0 {
 		option, err := cmd.Options[0].SnowflakeValue()
 		if err != nil {
@@ -105,19 +193,99 @@ func CommandSeen(state *state.State, kvs storage.KeyValueStore, event *gateway.I
 			log.Printf("[%s] Failed to get %s from Key/Value Store for /seen lookup: %s\n", event.GuildID, option, err)
 			return command.Response{Response: response.Ephemeral("An error occured, and has been logged."), Callback: nil}
 		}
+		online, onlineTimestamp, err := storage.LastOnline(kvs, event.GuildID, discord.UserID(option))
+		if err != nil {
+			log.Printf("[%s] Failed to get when %s was online for /seen lookup: %s\n", event.GuildID, option, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged."), Callback: nil}
+		}
+		message := fmt.Sprintf("I last saw <@%s> %s", option, utility.Timestamp(timestamp, utility.Relative))
 		if !found {
-			return command.Response{Response: response.MessageNoMention(fmt.Sprintf("Sorry, I've never seen <@%s> say anything at all!", option)), Callback: nil}
+			message = fmt.Sprintf("Sorry, I've never seen <@%s> say anything at all!", option)
+		}
+		if online {
+			message += fmt.Sprintf("\nThey were last online %s", utility.Timestamp(onlineTimestamp, utility.Relative))
 		}
-		return command.Response{Response: response.MessageNoMention(fmt.Sprintf("I last saw <@%s> <t:%d:R>", option, timestamp)), Callback: nil}
+		return command.Response{Response: response.EphemeralShareable(message), Callback: nil}
 	}
 	return command.Response{Response: response.Ephemeral("No user given?!"), Callback: nil}
 }
 
+// inactiveMember is someone on the /inactive report, either last seen a while ago, or never seen at all.
+type inactiveMember struct {
+	discord.Member
+	Seen     bool
+	LastSeen time.Time
+	Activity storage.Activity
+}
+
+// inactiveSection is a kind of inactive member on the /inactive report, each calling for something different.
+type inactiveSection struct {
+	Key     string // What the section is called in the CSV.
+	Title   string
+	Advice  string
+	Members []inactiveMember
+}
+
+// newInactiveSections gives the sections of the /inactive report, in the order they are listed.
+// Keep them in the order of the constants below, as that's how members are put in them.
+func newInactiveSections(days int64) []*inactiveSection {
+	return []*inactiveSection{
+		{
+			Key:    "new",
+			Title:  "New members that haven't said anything yet",
+			Advice: fmt.Sprintf("They joined in the last %d days, and may still be finding their feet. A welcome, or a pointer to where to start, could help.", days),
+		},
+		{
+			Key:    "lurking",
+			Title:  "Members that have never said anything",
+			Advice: "They've been here a while without a word, as far as I've seen. Maybe check in, or consider if they should stay.",
+		},
+		{
+			Key:    "regular",
+			Title:  "Regulars that have gone quiet",
+			Advice: "They used to be around most weeks. A friendly check-in might be welcome.",
+		},
+		{
+			Key:    "quiet",
+			Title:  "Members that have gone quiet",
+			Advice: "They were only around now and then, or since before I counted how often. Probably nothing to worry about yet.",
+		},
+	}
+}
+
+const (
+	inactiveNew = iota
+	inactiveLurking
+	inactiveRegular
+	inactiveQuiet
+)
+
+// inactiveSectionOf figures out which section of the /inactive report the member goes in.
+// Anyone that joined before the cutoff, or has been here before, isn't new.
+func inactiveSectionOf(member inactiveMember, cutoff int64) int {
+	if member.Seen {
+		if member.Activity.Regular() {
+			return inactiveRegular
+		}
+		return inactiveQuiet
+	}
+	joined := member.Joined.Time().Unix()
+	if member.Activity.Joined != 0 && member.Activity.Joined < joined {
+		joined = member.Activity.Joined
+	}
+	if joined > cutoff {
+		return inactiveNew
+	}
+	return inactiveLurking
+}
+
 // CommandInactive processes a command to list who has not been active in a given timeframe.
-func CommandInactive(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, cmd *discord.CommandInteraction) command.Response {
+// Going through everyone takes a while in big guilds, so it's deferred, and the list is attached as a file.
+func CommandInactive(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
 	days := int64(30)
-	if cmd.Options != nil && len(cmd.Options) > 0 {
-		d, err := cmd.Options[0].IntValue()
+	if opt := cmd.Options.Find("days"); opt.Name != "" {
+		d, err := opt.IntValue()
 		if err != nil {
 			log.Printf("[%s] Failed to get int value for /inactive: %s", event.GuildID, err)
 			return command.Response{Response: response.Ephemeral("An error occured, and has been logged."), Callback: nil}
@@ -127,113 +295,219 @@ func CommandInactive(state *state.State, kvs storage.KeyValueStore, event *gatew
 		}
 		days = d
 	}
-	atLeast := time.Now().Unix() - (24 * 3600 * days)
-	members, err := state.Session.Members(event.GuildID, 0)
-	if err != nil {
-		log.Printf("[%s] Failed to get member list for /inactive lookup: %s", event.GuildID, err)
-		return command.Response{Response: response.Ephemeral("An error occured, and has been logged."), Callback: nil}
+	format := cmd.Options.Find("format").String()
+	if format == "" {
+		format = "text"
 	}
+	atLeast := time.Now().Unix() - (24 * 3600 * days)
 
-	var bt bytes.Buffer
-	never := 0
-	inactiveCount := 0
-
-	now := time.Now()
-
-	for _, member := range members {
+	return command.DeferredDataProgress(state, event, false, func(progress func(string)) api.EditInteractionResponseData {
+		failed := api.EditInteractionResponseData{Content: option.NewNullableString("An error occured, and has been logged.")}
 
-		if member.User.Bot {
-			continue
+		progress("Getting the member list...")
+		membersCtx, cancel := context.WithTimeout(ctx, membercache.RequestTimeout)
+		members, err := membercache.Get(membersCtx, state, event.GuildID)
+		cancel()
+		if err != nil {
+			log.Printf("[%s] Failed to get member list for /inactive lookup: %s", event.GuildID, err)
+			return failed
 		}
-
-		seen, when, err := storage.LastSeen(kvs, event.GuildID, member.User.ID)
+		progress(fmt.Sprintf("Checking when I last saw each of the %d members...", len(members)))
+		userIDs := membercache.UserIDs(members)
+		lastSeen, err := storage.LastSeenMany(kvs, event.GuildID, userIDs)
 		if err != nil {
-			log.Printf("[%s] Failed to get a storage.LastSeen for %s: %s", event.GuildID, member.User.ID, err)
-			return command.Response{Response: response.Ephemeral("An error occured, and has been logged."), Callback: nil}
-		} else if !seen {
-			never++
-			joinTime := member.Joined.Format("2006-01-02")
-			if now.Sub(member.Joined.Time()).Hours() < 24 {
-				joinTime = "very recently"
+			log.Printf("[%s] Failed to get a storage.LastSeenMany for /inactive lookup: %s", event.GuildID, err)
+			return failed
+		}
+		activities, err := storage.ActivityMany(kvs, event.GuildID, userIDs)
+		if err != nil {
+			log.Printf("[%s] Failed to get a storage.ActivityMany for /inactive lookup: %s", event.GuildID, err)
+			return failed
+		}
+
+		inactive := []inactiveMember{}
+		for _, member := range members {
+			if member.User.Bot {
+				continue
 			}
-			if member.Nick != "" {
-				fmt.Fprintf(&bt, "<%s#%s> (%s) never, joined %s\n", member.User.Username, member.User.Discriminator, member.Nick, joinTime)
-			} else {
-				fmt.Fprintf(&bt, "<%s#%s> never, joined %s\n", member.User.Username, member.User.Discriminator, joinTime)
+			activity := activities[member.User.ID]
+			when, seen := lastSeen[member.User.ID]
+			if !seen {
+				inactive = append(inactive, inactiveMember{Member: member, Activity: activity})
+			} else if when <= atLeast {
+				inactive = append(inactive, inactiveMember{Member: member, Seen: true, LastSeen: time.Unix(when, 0), Activity: activity})
 			}
-		} else if when <= atLeast {
-			then := time.Unix(when, 0)
-			timeDiff := now.Sub(then)
-			if member.Nick != "" {
-				fmt.Fprintf(&bt, "<%s#%s> (%s) %d days\n", member.User.Username, member.User.Discriminator, member.Nick, int(timeDiff.Hours()/24))
-			} else {
-				fmt.Fprintf(&bt, "<%s#%s> %d days\n", member.User.Username, member.User.Discriminator, int(timeDiff.Hours()/24))
+		}
+		// Never seen first, by when they joined, then the rest with the longest gone first.
+		sort.Slice(inactive, func(i, j int) bool {
+			if inactive[i].Seen != inactive[j].Seen {
+				return !inactive[i].Seen
 			}
-			inactiveCount++
+			if !inactive[i].Seen {
+				return inactive[i].Joined.Time().Before(inactive[j].Joined.Time())
+			}
+			return inactive[i].LastSeen.Before(inactive[j].LastSeen)
+		})
+
+		sections := newInactiveSections(days)
+		for _, member := range inactive {
+			section := sections[inactiveSectionOf(member, atLeast)]
+			section.Members = append(section.Members, member)
 		}
+
+		message := fmt.Sprintf("%d inactive in the last %d days, out of %d members.\n", len(inactive), days, len(members))
+		for _, section := range sections {
+			if len(section.Members) > 0 {
+				message += fmt.Sprintf("- %s: %d\n", section.Title, len(section.Members))
+			}
+		}
+		if len(inactive) == 0 {
+			return api.EditInteractionResponseData{Content: option.NewNullableString(message)}
+		}
+
+		var buf bytes.Buffer
+		extension := "txt"
+		if format == "csv" {
+			extension = "csv"
+			err = writeInactiveCSV(&buf, sections)
+		} else {
+			writeInactiveText(&buf, sections)
+		}
+		if err != nil {
+			log.Printf("[%s] Failed to write the /inactive report: %s", event.GuildID, err)
+			return failed
+		}
+		log.Printf("[%s] <@%s> got the /inactive report for %d days, %d of %d members", event.GuildID, event.SenderID(), days, len(inactive), len(members))
+		return api.EditInteractionResponseData{
+			Content: option.NewNullableString(message),
+			Files: []sendpart.File{{
+				Name:   fmt.Sprintf("inactive_report_%s.%s", time.Now().Format("2006-01-02"), extension),
+				Reader: &buf,
+			}},
+		}
+	})
+}
+
+// writeInactiveText writes the /inactive report as plain text, a section at a time, with one member on each line.
+func writeInactiveText(buf *bytes.Buffer, sections []*inactiveSection) {
+	now := time.Now()
+	for _, section := range sections {
+		if len(section.Members) == 0 {
+			continue
+		}
+		fmt.Fprintf(buf, "%s (%d)\n%s\n\n", section.Title, len(section.Members), section.Advice)
+		for _, member := range section.Members {
+			writeInactiveLine(buf, member, now)
+		}
+		buf.WriteString("\n")
 	}
+}
 
-	message := fmt.Sprintf("%d inactive in the last %d days, out of %d members.", inactiveCount+never, days, len(members))
-	if never > 0 {
-		message += fmt.Sprintf(" (Including %d that I have never seen say anything!)", never)
+// writeInactiveLine writes a single member of the /inactive report as plain text.
+func writeInactiveLine(buf *bytes.Buffer, member inactiveMember, now time.Time) {
+	name := fmt.Sprintf("<%s#%s>", member.User.Username, member.User.Discriminator)
+	if member.Nick != "" {
+		name += fmt.Sprintf(" (%s)", member.Nick)
 	}
-	message += "\n"
-
-	if inactiveCount+never > 0 {
-		return command.Response{Response: response.MessageAttachFile(
-			message,
-			fmt.Sprintf("inactive_report_%s.txt", time.Now().Format("2006-01-02")),
-			&bt,
-		), Callback: nil}
-	} else {
-		return command.Response{Response: response.Message(message), Callback: nil}
+	if !member.Seen {
+		joinTime := member.Joined.Format("2006-01-02")
+		if now.Sub(member.Joined.Time()).Hours() < 24 {
+			joinTime = "very recently"
+		}
+		fmt.Fprintf(buf, "%s never, joined %s\n", name, joinTime)
+		return
 	}
+	fmt.Fprintf(buf, "%s %s", name, utility.FormatAgo(now.Sub(member.LastSeen)))
+	if member.Activity.ActiveDays > 0 {
+		fmt.Fprintf(buf, ", active on %d days before that", member.Activity.ActiveDays)
+	}
+	buf.WriteString("\n")
 }
 
-// CommandNeverSeen processes a command to list everyone that has never been seen by the bot.
-func CommandNeverSeen(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, cmd *discord.CommandInteraction) command.Response {
-	members, err := state.Session.Members(event.GuildID, 0)
-	if err != nil {
-		log.Printf("[%s] Failed to get member list for /neverseen lookup: %s", event.GuildID, err)
-		return command.Response{Response: response.Ephemeral("An error occured, and has been logged."), Callback: nil}
+// writeInactiveCSV writes the /inactive report as CSV, for sorting and filtering in a spreadsheet.
+func writeInactiveCSV(buf *bytes.Buffer, sections []*inactiveSection) error {
+	now := time.Now()
+	writer := csv.NewWriter(buf)
+	writer.Write([]string{"section", "user_id", "username", "nickname", "joined", "last_seen", "days_inactive", "active_days"})
+	for _, section := range sections {
+		for _, member := range section.Members {
+			lastSeen, daysInactive := "", ""
+			if member.Seen {
+				lastSeen = member.LastSeen.UTC().Format(time.RFC3339)
+				daysInactive = strconv.Itoa(int(now.Sub(member.LastSeen).Hours() / 24))
+			}
+			writer.Write([]string{
+				section.Key,
+				member.User.ID.String(),
+				member.User.Tag(),
+				member.Nick,
+				member.Joined.Time().UTC().Format(time.RFC3339),
+				lastSeen,
+				daysInactive,
+				strconv.Itoa(member.Activity.ActiveDays),
+			})
+		}
 	}
-	count := 0
+	writer.Flush()
+	return writer.Error()
+}
 
-	var bt bytes.Buffer
-	for _, member := range members {
+// CommandNeverSeen processes a command to list everyone that has never been seen by the bot.
+// Getting everyone takes a while in big guilds, so it's deferred, like /inactive.
+func CommandNeverSeen(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	return command.DeferredDataProgress(state, event, false, func(progress func(string)) api.EditInteractionResponseData {
+		failed := api.EditInteractionResponseData{Content: option.NewNullableString("An error occured, and has been logged.")}
 
-		if member.User.Bot {
-			continue
+		progress("Getting the member list...")
+		membersCtx, cancel := context.WithTimeout(ctx, membercache.RequestTimeout)
+		members, err := membercache.Get(membersCtx, state, event.GuildID)
+		cancel()
+		if err != nil {
+			log.Printf("[%s] Failed to get member list for /neverseen lookup: %s", event.GuildID, err)
+			return failed
 		}
-
-		seen, _, err := storage.LastSeen(kvs, event.GuildID, member.User.ID)
+		progress(fmt.Sprintf("Checking if I ever saw each of the %d members...", len(members)))
+		lastSeen, err := storage.LastSeenMany(kvs, event.GuildID, membercache.UserIDs(members))
 		if err != nil {
-			log.Printf("[%s] Failed to get a storage.LastSeen for %s: %s", event.GuildID, member.User.ID, err)
-			return command.Response{Response: response.Ephemeral("An error occured, and has been logged."), Callback: nil}
+			log.Printf("[%s] Failed to get a storage.LastSeenMany for /neverseen lookup: %s", event.GuildID, err)
+			return failed
 		}
-		if !seen {
-			count++
-			if member.Nick != "" {
-				fmt.Fprintf(&bt, "%s#%s (%s) joined %s\n", member.User.Username, member.User.Discriminator, member.Nick, member.Joined.Format("2006-01-02"))
-			} else {
-				fmt.Fprintf(&bt, "%s#%s joined %s\n", member.User.Username, member.User.Discriminator, member.Joined.Format("2006-01-02"))
+		count := 0
+
+		var bt bytes.Buffer
+		for _, member := range members {
+
+			if member.User.Bot {
+				continue
+			}
+
+			if _, seen := lastSeen[member.User.ID]; !seen {
+				count++
+				if member.Nick != "" {
+					fmt.Fprintf(&bt, "%s#%s (%s) joined %s\n", member.User.Username, member.User.Discriminator, member.Nick, member.Joined.Format("2006-01-02"))
+				} else {
+					fmt.Fprintf(&bt, "%s#%s joined %s\n", member.User.Username, member.User.Discriminator, member.Joined.Format("2006-01-02"))
+				}
 			}
 		}
-	}
 
-	if count > 0 {
-		return command.Response{Response: response.MessageAttachFile(
-			fmt.Sprintf("%d users have never been seen by me.", count),
-			fmt.Sprintf("never_seen_report_%s.txt", time.Now().Format("2006-01-02")),
-			&bt,
-		), Callback: nil}
-	} else {
-		return command.Response{Response: response.Message("Everyone seems to have at least said at least *something!*"), Callback: nil}
-	}
+		if count == 0 {
+			return api.EditInteractionResponseData{Content: option.NewNullableString("Everyone seems to have at least said at least *something!*")}
+		}
+		return api.EditInteractionResponseData{
+			Content: option.NewNullableString(fmt.Sprintf("%d users have never been seen by me.", count)),
+			Files: []sendpart.File{{
+				Name:   fmt.Sprintf("never_seen_report_%s.txt", time.Now().Format("2006-01-02")),
+				Reader: &bt,
+			}},
+		}
+	})
 }
 
 // CommandActiveRole processes a command to set an automatic "active" role and revoke it after a certain amount of days.
-func CommandActiveRole(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, cmd *discord.CommandInteraction) command.Response {
+func CommandActiveRole(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
 	if cmd.Options == nil || len(cmd.Options) != 2 {
 		log.Printf("[%s] /activerole has a weird number of arguments\n", event.GuildID)
 		return command.Response{Response: response.Ephemeral("Wait, what? Something odd happened, and was logged."), Callback: nil}
@@ -267,6 +541,9 @@ func CommandActiveRole(state *state.State, kvs storage.KeyValueStore, event *gat
 		return command.Response{Response: response.Ephemeral("That's very odd. I've logged that it didn't go as planned."), Callback: nil}
 	}
 	roleID := discord.RoleID(snowflake)
+	if err := utility.CanManageRole(state, event.GuildID, event.Member, roleID); err != nil {
+		return command.Response{Response: response.Ephemeral(hierarchyProblem(event.GuildID, err))}
+	}
 	if err := kvs.Set(event.GuildID, "activerole", "role", roleID); err != nil {
 		log.Printf("[%s] Error storing the role for /activerole: %s\n", event.GuildID, err)
 		return command.Response{Response: response.Ephemeral("There is something strange in this neighbourhood. I've logged it for the Bug Busters to look at later."), Callback: nil}
@@ -277,18 +554,26 @@ func CommandActiveRole(state *state.State, kvs storage.KeyValueStore, event *gat
 }
 
 // CommandSeeEveryone processes a command to mark eeeeveryone in the guild as "seen" right now.
-func CommandSeeEveryone(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, cmd *discord.CommandInteraction) command.Response {
-	members, err := state.Session.Members(event.GuildID, 0)
-	if err != nil {
-		log.Printf("[%s] Failed to get member list for /SeeEveryone: %s", event.GuildID, err)
-		return command.Response{Response: response.Ephemeral("An error occured, and has been logged."), Callback: nil}
-	}
-	for _, member := range members {
-		err := storage.See(kvs, event.GuildID, member.User.ID)
+// Getting everyone takes a while in big guilds, so it's deferred, like /inactive.
+func CommandSeeEveryone(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	return command.DeferredProgress(state, event, false, func(progress func(string)) string {
+		progress("Getting the member list...")
+		membersCtx, cancel := context.WithTimeout(ctx, membercache.RequestTimeout)
+		members, err := membercache.Get(membersCtx, state, event.GuildID)
+		cancel()
 		if err != nil {
-			log.Printf("[%s] Failed to See member during seeing spree: %s", event.GuildID, err)
-			return command.Response{Response: response.Message("Okay, something weird happened partway through that. It was logged."), Callback: nil}
+			log.Printf("[%s] Failed to get member list for /SeeEveryone: %s", event.GuildID, err)
+			return "An error occured, and has been logged."
 		}
-	}
-	return command.Response{Response: response.Message("Eeeeeveryone was marked as being seen just now."), Callback: nil}
+		for i, member := range members {
+			progress(fmt.Sprintf("Marking everyone as seen, %d of %d done...", i, len(members)))
+			err := storage.See(kvs, event.GuildID, member.User.ID)
+			if err != nil {
+				log.Printf("[%s] Failed to See member during seeing spree: %s", event.GuildID, err)
+				return "Okay, something weird happened partway through that. It was logged."
+			}
+		}
+		return "Eeeeeveryone was marked as being seen just now."
+	})
 }
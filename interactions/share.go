@@ -0,0 +1,38 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/component"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+var shareModule = module.Basic{
+	ModuleName: "share",
+	ComponentHandlers: map[string]component.Handler{
+		response.ShareButtonID: component.Handler{Code: ComponentShare},
+	},
+}
+
+// ComponentShare reposts an ephemeral response publicly, for whoever clicked "Share to channel" on it.
+func ComponentShare(ctx *command.Context, interaction discord.ComponentInteraction, params []string) api.InteractionResponse {
+	e := ctx.Event
+	if e.Message == nil || (e.Message.Content == "" && len(e.Message.Embeds) == 0) {
+		return response.Ephemeral("There's nothing there to share, somehow.")
+	}
+	return api.InteractionResponse{
+		Type: api.MessageInteractionWithSource,
+		Data: &api.InteractionResponseData{
+			Content: option.NewNullableString(fmt.Sprintf("%s\n*Shared by %s*", e.Message.Content, e.SenderID().Mention())),
+			Embeds:  &e.Message.Embeds,
+			AllowedMentions: &api.AllowedMentions{
+				Parse: []api.AllowedMentionType{},
+			},
+		},
+	}
+}
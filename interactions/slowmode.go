@@ -0,0 +1,424 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/message"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/interactions/timer"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+const (
+	slowmodeMax             = 21600 // The longest slowmode Discord allows, in seconds.
+	slowmodeDefaultDuration = 10 * time.Minute
+)
+
+var slowmodeModule = module.Basic{
+	ModuleName: "slowmode",
+	CommandHandlers: map[string]command.Handler{
+		"slowmode": commandSlowmodeObject,
+	},
+	Handlers: []module.EventHandler{
+		module.Message(message.Handler{Code: MessageSlowmodeSpike}),
+		module.Timer("slowmodedaily", timer.Handler{Code: TimerSlowmodeDaily}),
+	},
+}
+
+var commandSlowmodeObject = command.Handler{
+	Description: "Change the slowmode of channels by itself",
+	Code:        CommandSlowmode,
+	Options: []discord.CommandOption{
+		&discord.SubcommandGroupOption{
+			OptionName:  "schedule",
+			Description: "Set slowmode at times of day, or when a channel gets busy",
+			Subcommands: []*discord.SubcommandOption{
+				{
+					OptionName:  "daily",
+					Description: "Set the slowmode of a channel at the same time every day",
+					Options: []discord.CommandOptionValue{
+						&discord.ChannelOption{
+							OptionName:   "channel",
+							Description:  "The channel to slow down",
+							Required:     true,
+							ChannelTypes: []discord.ChannelType{discord.GuildText, discord.GuildNews},
+						},
+						&discord.StringOption{
+							OptionName:  "time",
+							Description: "When to do it, as HH:MM in the time zone of the guild",
+							Required:    true,
+						},
+						&discord.IntegerOption{
+							OptionName:  "seconds",
+							Description: "The slowmode to set, in seconds. 0 turns it off.",
+							Required:    true,
+							Min:         option.NewInt(0),
+							Max:         option.NewInt(slowmodeMax),
+						},
+					},
+				},
+				{
+					OptionName:  "spike",
+					Description: "Set the slowmode of a channel for a while when it gets busy",
+					Options: []discord.CommandOptionValue{
+						&discord.ChannelOption{
+							OptionName:   "channel",
+							Description:  "The channel to watch",
+							Required:     true,
+							ChannelTypes: []discord.ChannelType{discord.GuildText, discord.GuildNews},
+						},
+						&discord.IntegerOption{
+							OptionName:  "messages",
+							Description: "How many messages in a minute is busy",
+							Required:    true,
+							Min:         option.NewInt(5),
+							Max:         option.NewInt(1000),
+						},
+						&discord.IntegerOption{
+							OptionName:  "seconds",
+							Description: "The slowmode to set, in seconds",
+							Required:    true,
+							Min:         option.NewInt(1),
+							Max:         option.NewInt(slowmodeMax),
+						},
+						&discord.StringOption{
+							OptionName:  "duration",
+							Description: "How long it lasts, like 30m. Default is 10m.",
+							Required:    false,
+						},
+					},
+				},
+				{
+					OptionName:  "list",
+					Description: "List the slowmode schedules",
+					Options:     []discord.CommandOptionValue{},
+				},
+				{
+					OptionName:  "remove",
+					Description: "Remove a slowmode schedule",
+					Options: []discord.CommandOptionValue{
+						&discord.IntegerOption{
+							OptionName:  "schedule",
+							Description: "The number of the schedule, as shown by /slowmode schedule list",
+							Required:    true,
+							Min:         option.NewInt(1),
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+// CommandSlowmode processes the /slowmode command, dispatching to the right subcommand.
+func CommandSlowmode(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 || len(cmd.Options[0].Options) != 1 {
+		log.Printf("[%s] /slowmode command structure is somehow not a single subcommand in a group. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	group := cmd.Options[0]
+	sub := group.Options[0]
+	switch group.Name + " " + sub.Name {
+	case "schedule daily":
+		return SubCommandSlowmodeScheduleDaily(kvs, event, sub.Options)
+	case "schedule spike":
+		return SubCommandSlowmodeScheduleSpike(kvs, event, sub.Options)
+	case "schedule list":
+		return SubCommandSlowmodeScheduleList(kvs, event)
+	case "schedule remove":
+		return SubCommandSlowmodeScheduleRemove(kvs, event, sub.Options)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// slowmodeRuleFromOptions starts a rule with what every kind of rule has, or returns what's wrong with the options.
+func slowmodeRuleFromOptions(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions, kind string) (storage.SlowmodeRule, string) {
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /slowmode schedule %s failed to get channel snowflake: %s", event.GuildID, kind, err)
+		return storage.SlowmodeRule{}, "There was an issue figuring out the channel. It has been logged."
+	}
+	seconds, err := options.Find("seconds").IntValue()
+	if err != nil || seconds < 0 || seconds > slowmodeMax {
+		return storage.SlowmodeRule{}, fmt.Sprintf("The slowmode has to be between 0 and %d seconds.", slowmodeMax)
+	}
+	rules, err := storage.GetSlowmodeRules(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /slowmode schedule %s failed to get the rules: %s", event.GuildID, kind, err)
+		return storage.SlowmodeRule{}, "An error occured, and has been logged."
+	}
+	if len(rules) >= storage.SlowmodeRuleLimit {
+		return storage.SlowmodeRule{}, fmt.Sprintf("This guild already has %d slowmode schedules, which is as many as it gets.", storage.SlowmodeRuleLimit)
+	}
+	return storage.SlowmodeRule{
+		GuildID:   event.GuildID,
+		ChannelID: discord.ChannelID(channelSnowflake),
+		Kind:      kind,
+		Seconds:   seconds,
+		AddedBy:   event.SenderID(),
+	}, ""
+}
+
+// SubCommandSlowmodeScheduleDaily adds a rule setting the slowmode of a channel at a time of day.
+func SubCommandSlowmodeScheduleDaily(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	clock, err := time.Parse("15:04", strings.TrimSpace(options.Find("time").String()))
+	if err != nil {
+		return command.Response{Response: response.Ephemeral("I couldn't make sense of that time. Please use HH:MM, like 22:30")}
+	}
+	rule, problem := slowmodeRuleFromOptions(kvs, event, options, storage.SlowmodeDaily)
+	if problem != "" {
+		return command.Response{Response: response.Ephemeral(problem)}
+	}
+	rule.Minute = clock.Hour()*60 + clock.Minute()
+	location, err := storage.GetTimezone(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /slowmode schedule daily failed to get the time zone, going with UTC: %s", event.GuildID, err)
+	}
+
+	rule.ID, err = storage.NextNumber(kvs, event.GuildID, "slowmode")
+	if err != nil {
+		log.Printf("[%s] /slowmode schedule daily failed to get a number: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	next := rule.NextDaily(time.Now(), location)
+	t, err := timer.Schedule(kvs, event.GuildID, "slowmodedaily", next, map[string]string{"rule": strconv.FormatInt(rule.ID, 10)})
+	if err != nil {
+		log.Printf("[%s] /slowmode schedule daily failed to schedule the timer: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	rule.TimerID = t.ID
+	if err := rule.Store(kvs); err != nil {
+		log.Printf("[%s] /slowmode schedule daily failed to store the rule: %s", event.GuildID, err)
+		storage.CancelTimer(kvs, event.GuildID, t.ID)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> scheduled slowmode %d in <#%s> daily at %s, as %d", event.GuildID, event.SenderID(), rule.Seconds, rule.ChannelID, clock.Format("15:04"), rule.ID)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Every day at %s, %s. The first time is <t:%d:R>. This is schedule number %d.", clock.Format("15:04"), describeSlowmodeChange(rule), next.Unix(), rule.ID))}
+}
+
+// SubCommandSlowmodeScheduleSpike adds a rule setting the slowmode of a channel for a while, when it gets busy.
+func SubCommandSlowmodeScheduleSpike(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	messages, err := options.Find("messages").IntValue()
+	if err != nil || messages < 5 {
+		return command.Response{Response: response.Ephemeral("The number of messages has to be a whole number, at least 5.")}
+	}
+	duration := slowmodeDefaultDuration
+	if text := options.Find("duration").String(); text != "" {
+		duration, err = utility.ParseDuration(text)
+		if err != nil || duration < time.Minute || duration > 24*time.Hour {
+			return command.Response{Response: response.Ephemeral("The duration has to be somewhere between a minute and a day, like `30m` or `2h`.")}
+		}
+	}
+	rule, problem := slowmodeRuleFromOptions(kvs, event, options, storage.SlowmodeSpike)
+	if problem != "" {
+		return command.Response{Response: response.Ephemeral(problem)}
+	}
+	rule.Messages = int(messages)
+	rule.Duration = int64(duration.Seconds())
+
+	rule.ID, err = storage.NextNumber(kvs, event.GuildID, "slowmode")
+	if err != nil {
+		log.Printf("[%s] /slowmode schedule spike failed to get a number: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if err := rule.Store(kvs); err != nil {
+		log.Printf("[%s] /slowmode schedule spike failed to store the rule: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> scheduled slowmode %d in <#%s> at %d messages a minute, as %d", event.GuildID, event.SenderID(), rule.Seconds, rule.ChannelID, rule.Messages, rule.ID)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("When %s gets %d messages in a minute, %s for %s. This is schedule number %d.", rule.ChannelID.Mention(), rule.Messages, describeSlowmodeChange(rule), duration, rule.ID))}
+}
+
+// describeSlowmodeChange says what the rule does to the slowmode of its channel.
+func describeSlowmodeChange(rule storage.SlowmodeRule) string {
+	if rule.Seconds == 0 {
+		return fmt.Sprintf("slowmode in %s is turned off", rule.ChannelID.Mention())
+	}
+	return fmt.Sprintf("slowmode in %s is set to %s", rule.ChannelID.Mention(), time.Duration(rule.Seconds)*time.Second)
+}
+
+// SubCommandSlowmodeScheduleList lists the slowmode rules of the guild.
+func SubCommandSlowmodeScheduleList(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	rules, err := storage.GetSlowmodeRules(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /slowmode schedule list failed to get the rules: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(rules) == 0 {
+		return command.Response{Response: response.Ephemeral("There are no slowmode schedules here. Add one with `/slowmode schedule daily` or `/slowmode schedule spike`.")}
+	}
+	lines := make([]string, len(rules))
+	for i, rule := range rules {
+		if rule.Kind == storage.SlowmodeDaily {
+			lines[i] = fmt.Sprintf("**%d** Every day at %02d:%02d, %s", rule.ID, rule.Minute/60, rule.Minute%60, describeSlowmodeChange(rule))
+		} else {
+			lines[i] = fmt.Sprintf("**%d** At %d messages a minute, %s for %s", rule.ID, rule.Messages, describeSlowmodeChange(rule), time.Duration(rule.Duration)*time.Second)
+		}
+	}
+	return command.Response{Response: response.Ephemeral(strings.Join(lines, "\n"))}
+}
+
+// SubCommandSlowmodeScheduleRemove removes a slowmode rule. Slowmode it has already set is left as it is.
+func SubCommandSlowmodeScheduleRemove(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	id, err := options.Find("schedule").IntValue()
+	if err != nil {
+		log.Printf("[%s] /slowmode schedule remove failed to get the number: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	exist, rule, err := storage.GetSlowmodeRule(kvs, event.GuildID, id)
+	if err != nil {
+		log.Printf("[%s] /slowmode schedule remove failed to get rule %d: %s", event.GuildID, id, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no slowmode schedule number %d here.", id))}
+	}
+	if err := rule.Delete(kvs); err != nil {
+		log.Printf("[%s] /slowmode schedule remove failed to remove rule %d: %s", event.GuildID, id, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> removed slowmode schedule %d in <#%s>", event.GuildID, event.SenderID(), rule.ID, rule.ChannelID)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Removed slowmode schedule %d. The slowmode in %s is left as it is now.", rule.ID, rule.ChannelID.Mention()))}
+}
+
+// TimerSlowmodeDaily sets the slowmode of a daily rule, and schedules the next time.
+func TimerSlowmodeDaily(state *state.State, kvs storage.KeyValueStore, t storage.Timer) {
+	id, err := strconv.ParseInt(t.Data["rule"], 10, 64)
+	if err != nil {
+		log.Printf("[%s] Daily slowmode timer has a weird rule: %s", t.GuildID, err)
+		return
+	}
+	exist, rule, err := storage.GetSlowmodeRule(kvs, t.GuildID, id)
+	if err != nil {
+		log.Printf("[%s] Daily slowmode timer failed to get rule %d: %s", t.GuildID, id, err)
+		return
+	}
+	if !exist || rule.TimerID != t.ID {
+		return // Removed, or replaced, since the timer was set.
+	}
+	err = state.ModifyChannel(rule.ChannelID, api.ModifyChannelData{
+		UserRateLimit:  option.NewNullableUint(uint(rule.Seconds)),
+		AuditLogReason: api.AuditLogReason(fmt.Sprintf("Slowmode schedule %d", rule.ID)),
+	})
+	if err != nil {
+		log.Printf("[%s] Daily slowmode failed to set slowmode in <#%s>: %s", t.GuildID, rule.ChannelID, err)
+	}
+
+	location, err := storage.GetTimezone(kvs, t.GuildID)
+	if err != nil {
+		log.Printf("[%s] Daily slowmode failed to get the time zone, going with UTC: %s", t.GuildID, err)
+	}
+	next, err := timer.Schedule(kvs, t.GuildID, "slowmodedaily", rule.NextDaily(time.Now(), location), t.Data)
+	if err != nil {
+		log.Printf("[%s] Daily slowmode failed to schedule rule %d again: %s", t.GuildID, rule.ID, err)
+		return
+	}
+	rule.TimerID = next.ID
+	if err := rule.Store(kvs); err != nil {
+		log.Printf("[%s] Daily slowmode failed to store rule %d: %s", t.GuildID, rule.ID, err)
+	}
+}
+
+// messageRate counts the messages recently sent in channels with spike rules.
+// Nothing here is stored, as it's only interesting for a minute anyway.
+type messageRate struct {
+	lock     sync.Mutex
+	messages map[discord.ChannelID][]int64
+}
+
+var channelRate = messageRate{messages: map[discord.ChannelID][]int64{}}
+
+// message records a message in the channel, and returns how many were sent in the last minute.
+func (rate *messageRate) message(channelID discord.ChannelID, when int64) int {
+	rate.lock.Lock()
+	defer rate.lock.Unlock()
+	kept := []int64{}
+	for _, previous := range rate.messages[channelID] {
+		if previous > when-60 {
+			kept = append(kept, previous)
+		}
+	}
+	rate.messages[channelID] = append(kept, when)
+	return len(rate.messages[channelID])
+}
+
+// forget clears the count for the channel, so the same spike doesn't count again.
+func (rate *messageRate) forget(channelID discord.ChannelID) {
+	rate.lock.Lock()
+	defer rate.lock.Unlock()
+	delete(rate.messages, channelID)
+}
+
+// MessageSlowmodeSpike sets the slowmode of a channel for a while, when a spike rule finds it busy.
+func MessageSlowmodeSpike(state *state.State, kvs storage.KeyValueStore, event *gateway.MessageCreateEvent) {
+	if event.GuildID == discord.NullGuildID || event.Author.Bot {
+		return
+	}
+	rules, err := storage.GetSlowmodeRules(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Slowmode spike failed to get the rules: %s", event.GuildID, err)
+		return
+	}
+	counted := false
+	count := 0
+	for _, rule := range rules {
+		if rule.Kind != storage.SlowmodeSpike || rule.ChannelID != event.ChannelID {
+			continue
+		}
+		if !counted {
+			count = channelRate.message(event.ChannelID, time.Now().Unix())
+			counted = true
+		}
+		if count < rule.Messages {
+			continue
+		}
+		channelRate.forget(event.ChannelID)
+		slowmodeSpike(state, kvs, rule, count)
+		return
+	}
+}
+
+// slowmodeSpike sets the slowmode of the rule, and schedules putting it back the way it was.
+func slowmodeSpike(state *state.State, kvs storage.KeyValueStore, rule storage.SlowmodeRule, count int) {
+	channel, err := state.Channel(rule.ChannelID)
+	if err != nil {
+		log.Printf("[%s] Slowmode spike failed to get <#%s>: %s", rule.GuildID, rule.ChannelID, err)
+		return
+	}
+	if int64(channel.UserRateLimit) >= rule.Seconds {
+		return // Already at least this slow.
+	}
+	err = state.ModifyChannel(rule.ChannelID, api.ModifyChannelData{
+		UserRateLimit:  option.NewNullableUint(uint(rule.Seconds)),
+		AuditLogReason: api.AuditLogReason(fmt.Sprintf("Slowmode schedule %d: %d messages in a minute", rule.ID, count)),
+	})
+	if err != nil {
+		log.Printf("[%s] Slowmode spike failed to set slowmode in <#%s>: %s", rule.GuildID, rule.ChannelID, err)
+		return
+	}
+	_, err = timer.Schedule(kvs, rule.GuildID, "slowmodeoff", time.Now().Add(time.Duration(rule.Duration)*time.Second), map[string]string{
+		"channel":  rule.ChannelID.String(),
+		"previous": strconv.FormatInt(int64(channel.UserRateLimit), 10),
+		"reason":   fmt.Sprintf("Slowmode schedule %d over", rule.ID),
+	})
+	if err != nil {
+		log.Printf("[%s] Slowmode spike failed to schedule the end of slowmode in <#%s>: %s", rule.GuildID, rule.ChannelID, err)
+		return
+	}
+	log.Printf("[%s] Slowmode schedule %d slowed down <#%s> after %d messages in a minute", rule.GuildID, rule.ID, rule.ChannelID, count)
+}
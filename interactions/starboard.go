@@ -0,0 +1,256 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/delete"
+	"komainu/interactions/module"
+	"komainu/interactions/reaction"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+const (
+	starboardDefaultEmoji     = "⭐"
+	starboardDefaultThreshold = 3
+)
+
+// starboardLock keeps two reactions arriving at once from reposting the same message twice.
+var starboardLock sync.Mutex
+
+var starboardModule = module.Basic{
+	ModuleName: "starboard",
+	CommandHandlers: map[string]command.Handler{
+		"starboard": commandStarboardObject,
+	},
+	Handlers: []module.EventHandler{
+		module.Reaction(reaction.Handler{Code: ReactionStarboard}),
+		module.Delete(delete.Handler{Code: DeleteStarboard}),
+	},
+}
+
+var commandStarboardObject = command.Handler{
+	Description: "Repost popular messages to a channel of their own",
+	Code:        CommandStarboard,
+	Feature:     "starboard",
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "config",
+			Description: "Set up the starboard",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:  "channel",
+					Description: "Where to repost the starred messages",
+					Required:    true,
+				},
+				&discord.StringOption{
+					OptionName:  "emoji",
+					Description: "What reaction counts as a star. Default is " + starboardDefaultEmoji,
+					Required:    false,
+				},
+				&discord.IntegerOption{
+					OptionName:  "threshold",
+					Description: fmt.Sprintf("How many stars a message needs to be reposted. Default is %d.", starboardDefaultThreshold),
+					Required:    false,
+					Min:         option.NewInt(1),
+					Max:         option.NewInt(1000),
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "disable",
+			Description: "Stop reposting starred messages",
+			Options:     []discord.CommandOptionValue{},
+		},
+	},
+}
+
+// CommandStarboard processes the /starboard command, dispatching to the right subcommand.
+func CommandStarboard(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /starboard command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "config":
+		return SubCommandStarboardConfig(kvs, event, cmd.Options[0].Options)
+	case "disable":
+		if err := storage.DisableStarboard(kvs, event.GuildID); err != nil {
+			log.Printf("[%s] Failed to disable the starboard: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		log.Printf("[%s] <@%s> disabled the starboard", event.GuildID, event.SenderID())
+		return command.Response{Response: response.Ephemeral("Okay, no more starboard.")}
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandStarboardConfig sets up the starboard.
+func SubCommandStarboardConfig(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /starboard config failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	config := storage.StarboardConfig{
+		ChannelID: discord.ChannelID(channelSnowflake),
+		Emoji:     starboardDefaultEmoji,
+		Threshold: starboardDefaultThreshold,
+	}
+	if emojiText := options.Find("emoji").String(); emojiText != "" {
+		config.Emoji, err = storage.ParseEmoji(emojiText)
+		if err != nil {
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("I can't use that as a star: %s", err))}
+		}
+	}
+	if threshold := options.Find("threshold"); threshold.Name != "" {
+		t, err := threshold.IntValue()
+		if err != nil || t < 1 {
+			return command.Response{Response: response.Ephemeral("The threshold has to be a whole number, at least 1.")}
+		}
+		config.Threshold = int(t)
+	}
+
+	if err := storage.SetStarboardConfig(kvs, event.GuildID, config); err != nil {
+		log.Printf("[%s] Failed to store starboard config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> set the starboard to <#%s>, %d of %s", event.GuildID, event.SenderID(), config.ChannelID, config.Threshold, config.Emoji)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Messages with %d or more %s will be reposted in %s.", config.Threshold, starboardEmojiString(config.Emoji), config.ChannelID.Mention()))}
+}
+
+// starboardEmojiString turns the API emoji back into something the client will render.
+func starboardEmojiString(emoji discord.APIEmoji) string {
+	if name, id, custom := strings.Cut(string(emoji), ":"); custom {
+		return fmt.Sprintf("<:%s:%s>", name, id)
+	}
+	return string(emoji)
+}
+
+// ReactionStarboard recounts the stars on a message when its reactions change, and reposts or updates it as needed.
+func ReactionStarboard(state *state.State, kvs storage.KeyValueStore, change reaction.Change) {
+	if enabled, err := storage.FeatureEnabled(kvs, change.GuildID, "starboard"); err != nil || !enabled {
+		return
+	}
+	exist, config, err := storage.GetStarboardConfig(kvs, change.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to get starboard config: %s", change.GuildID, err)
+		return
+	}
+	if !exist || change.ChannelID == config.ChannelID {
+		return // No starboard, or someone is starring the starboard itself.
+	}
+	if change.Emoji.Name != "" && change.Emoji.APIString() != config.Emoji {
+		return
+	}
+
+	starboardLock.Lock()
+	defer starboardLock.Unlock()
+
+	exist, post, err := storage.GetStarboardPost(kvs, change.GuildID, change.MessageID)
+	if err != nil {
+		log.Printf("[%s] Failed to get starboard post for %s: %s", change.GuildID, change.MessageID, err)
+		return
+	}
+
+	// Asking Discord directly, as the cache might not have caught up with the reaction yet.
+	message, err := state.Client.Message(change.ChannelID, change.MessageID)
+	if err != nil {
+		log.Printf("[%s] Starboard failed to get message %s in <#%s>: %s", change.GuildID, change.MessageID, change.ChannelID, err)
+		return
+	}
+	message.GuildID = change.GuildID
+	count := 0
+	for _, r := range message.Reactions {
+		if r.Emoji.APIString() == config.Emoji {
+			count = r.Count
+		}
+	}
+
+	if !exist {
+		if count < config.Threshold {
+			return
+		}
+		repost, err := state.SendMessageComplex(config.ChannelID, starboardMessage(config, message, count))
+		if err != nil {
+			log.Printf("[%s] Failed to post %s to the starboard: %s", change.GuildID, change.MessageID, err)
+			return
+		}
+		post = storage.StarboardPost{ChannelID: change.ChannelID, PostID: repost.ID, Count: count}
+		if err := storage.SetStarboardPost(kvs, change.GuildID, change.MessageID, post); err != nil {
+			log.Printf("[%s] Failed to store starboard post for %s: %s", change.GuildID, change.MessageID, err)
+		}
+		return
+	}
+
+	if count == post.Count {
+		return
+	}
+	data := starboardMessage(config, message, count)
+	if _, err := state.EditMessageComplex(config.ChannelID, post.PostID, api.EditMessageData{
+		Content: option.NewNullableString(data.Content),
+		Embeds:  &data.Embeds,
+	}); err != nil {
+		log.Printf("[%s] Failed to update starboard post for %s: %s", change.GuildID, change.MessageID, err)
+		return
+	}
+	post.Count = count
+	if err := storage.SetStarboardPost(kvs, change.GuildID, change.MessageID, post); err != nil {
+		log.Printf("[%s] Failed to store starboard post for %s: %s", change.GuildID, change.MessageID, err)
+	}
+}
+
+// starboardMessage builds the repost of the given message.
+func starboardMessage(config storage.StarboardConfig, message *discord.Message, count int) api.SendMessageData {
+	return api.SendMessageData{
+		Content: fmt.Sprintf("%s **%d** %s", starboardEmojiString(config.Emoji), count, message.ChannelID.Mention()),
+		Embeds:  []discord.Embed{quoteEmbed(message, discord.Color(0xFFAC33))},
+	}
+}
+
+// quoteEmbed copies the given message into an embed, with a link back to where it came from.
+func quoteEmbed(message *discord.Message, color discord.Color) discord.Embed {
+	link := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", message.GuildID, message.ChannelID, message.ID)
+	embed := discord.Embed{
+		Type:        discord.NormalEmbed,
+		Description: message.Content,
+		Color:       color,
+		Timestamp:   message.Timestamp,
+		Author: &discord.EmbedAuthor{
+			Name: message.Author.Username,
+			Icon: message.Author.AvatarURL(),
+		},
+		Fields: []discord.EmbedField{
+			{Name: "Source", Value: fmt.Sprintf("[Jump to message](%s)", link)},
+		},
+	}
+	for _, attachment := range message.Attachments {
+		if strings.HasPrefix(attachment.ContentType, "image/") {
+			embed.Image = &discord.EmbedImage{URL: attachment.URL}
+			break
+		}
+	}
+	return embed
+}
+
+// DeleteStarboard forgets the starboard post of a deleted message. The repost stays, as it may be all that's left of it.
+func DeleteStarboard(state *state.State, kvs storage.KeyValueStore, event *gateway.MessageDeleteEvent) {
+	exist, _, err := storage.GetStarboardPost(kvs, event.GuildID, event.ID)
+	if err != nil || !exist {
+		return
+	}
+	if err := storage.ForgetStarboardPost(kvs, event.GuildID, event.ID); err != nil {
+		log.Printf("[%s] Failed to forget starboard post for deleted message %s: %s", event.GuildID, event.ID, err)
+	}
+}
@@ -0,0 +1,220 @@
+package interactions
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+var statsModule = module.Basic{
+	ModuleName: "stats",
+	CommandHandlers: map[string]command.Handler{
+		"stats": commandStatsObject,
+	},
+}
+
+func statsDaysOption() discord.CommandOptionValue {
+	return &discord.IntegerOption{
+		OptionName:  "days",
+		Description: fmt.Sprintf("How many days back to look. Default is 7, and at most %d are kept.", storage.AnalyticsDays),
+		Required:    false,
+		Min:         option.NewInt(1),
+		Max:         option.NewInt(storage.AnalyticsDays),
+	}
+}
+
+var commandStatsObject = command.Handler{
+	Description: "See how the bot's commands are used",
+	Code:        CommandStats,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "commands",
+			Description: "How much each command was used here, and on which days",
+			Options:     []discord.CommandOptionValue{statsDaysOption()},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "global",
+			Description: "How much each command was used in every guild. Only for whoever runs the bot.",
+			Options:     []discord.CommandOptionValue{statsDaysOption()},
+		},
+	},
+}
+
+// commandStats is how much a single command was used, added up.
+type commandStats struct {
+	Command   string
+	Count     int
+	Failed    int
+	Millis    int64
+	MaxMillis int64
+	Users     map[discord.UserID]bool // Who used it. Not known for the global stats.
+}
+
+// usageStats is how much every command was used, in all and on each day.
+type usageStats struct {
+	Commands map[string]*commandStats
+	Days     map[string]int
+	Count    int
+	Failed   int
+}
+
+func newUsageStats() *usageStats {
+	return &usageStats{Commands: map[string]*commandStats{}, Days: map[string]int{}}
+}
+
+// add counts count uses of the command on the day, failed of which failed, taking millis in all.
+func (stats *usageStats) add(day string, name string, count int, failed int, millis int64, maxMillis int64) *commandStats {
+	cmd, ok := stats.Commands[name]
+	if !ok {
+		cmd = &commandStats{Command: name, Users: map[discord.UserID]bool{}}
+		stats.Commands[name] = cmd
+	}
+	cmd.Count += count
+	cmd.Failed += failed
+	cmd.Millis += millis
+	if maxMillis > cmd.MaxMillis {
+		cmd.MaxMillis = maxMillis
+	}
+	stats.Days[day] += count
+	stats.Count += count
+	stats.Failed += failed
+	return cmd
+}
+
+// write puts the stats in a form humans can read, the most used command first, then the days in order.
+func (stats *usageStats) write(w io.Writer, days int) {
+	fmt.Fprintf(w, "%d commands used in the last %d days, %d of which failed.\n", stats.Count, days, stats.Failed)
+	if stats.Count == 0 {
+		return
+	}
+
+	commands := make([]*commandStats, 0, len(stats.Commands))
+	for _, cmd := range stats.Commands {
+		commands = append(commands, cmd)
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		if commands[i].Count != commands[j].Count {
+			return commands[i].Count > commands[j].Count
+		}
+		return commands[i].Command < commands[j].Command
+	})
+	fmt.Fprintln(w, "\nBy command:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "/%s: %d", cmd.Command, cmd.Count)
+		if len(cmd.Users) > 0 {
+			fmt.Fprintf(w, " by %d people", len(cmd.Users))
+		}
+		if cmd.Failed > 0 {
+			fmt.Fprintf(w, ", %d failed", cmd.Failed)
+		}
+		fmt.Fprintf(w, ", %d ms on average, %d ms at worst\n", cmd.Millis/int64(cmd.Count), cmd.MaxMillis)
+	}
+
+	dayNames := make([]string, 0, len(stats.Days))
+	for day := range stats.Days {
+		dayNames = append(dayNames, day)
+	}
+	sort.Strings(dayNames)
+	fmt.Fprintln(w, "\nBy day:")
+	for _, day := range dayNames {
+		fmt.Fprintf(w, "%s: %d\n", day, stats.Days[day])
+	}
+}
+
+// statsResponse gives the stats as a message, or as a file if there's too much for one.
+func statsResponse(stats *usageStats, days int, filename string) command.Response {
+	var buf bytes.Buffer
+	stats.write(&buf, days)
+	if buf.Len() > 1500 {
+		return command.Response{Response: response.EphemeralAttachFile(
+			fmt.Sprintf("%d commands used in the last %d days, %d of which failed. There's too much to show, so here it is as a file.", stats.Count, days, stats.Failed),
+			filename,
+			&buf,
+		)}
+	}
+	return command.Response{Response: response.Ephemeral("```\n" + buf.String() + "```")}
+}
+
+// CommandStats processes the /stats command, dispatching to the right subcommand.
+func CommandStats(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /stats command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "commands":
+		return SubCommandStatsCommands(kvs, event, cmd.Options[0].Options)
+	case "global":
+		return SubCommandStatsGlobal(kvs, event, cmd.Options[0].Options)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// statsDays gets the days option, or the default of 7.
+func statsDays(options discord.CommandInteractionOptions) int {
+	days, err := options.Find("days").IntValue()
+	if err != nil || days < 1 {
+		return 7
+	}
+	if days > storage.AnalyticsDays {
+		return storage.AnalyticsDays
+	}
+	return int(days)
+}
+
+// SubCommandStatsCommands shows how much each command was used in the guild, with days in the guild's time zone.
+func SubCommandStatsCommands(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	days := statsDays(options)
+	uses, err := storage.CommandUses(kvs, event.GuildID, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		log.Printf("[%s] /stats commands failed to get the command uses: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	location, err := storage.GetTimezone(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /stats commands failed to get the time zone, so going with UTC: %s", event.GuildID, err)
+		location = time.UTC
+	}
+	stats := newUsageStats()
+	for _, use := range uses {
+		failed := 0
+		if use.Failed {
+			failed = 1
+		}
+		day := time.Unix(use.At, 0).In(location).Format("2006-01-02")
+		stats.add(day, use.Command, 1, failed, use.Millis, use.Millis).Users[use.UserID] = true
+	}
+	return statsResponse(stats, days, fmt.Sprintf("stats-%s.txt", event.GuildID))
+}
+
+// SubCommandStatsGlobal shows how much each command was used in every guild, with days in UTC.
+// Only the owners get to see it, as it says something about every guild.
+func SubCommandStatsGlobal(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	if !command.IsOwner(event.SenderID()) {
+		return command.Response{Response: response.Ephemeral("Sorry, `/stats global` is only for whoever runs the bot.")}
+	}
+	days := statsDays(options)
+	totals, err := storage.CommandTotals(kvs, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		log.Printf("[%s] /stats global failed to get the command totals: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	stats := newUsageStats()
+	for _, total := range totals {
+		stats.add(total.Day, total.Command, total.Count, total.Failed, total.Millis, total.MaxMillis)
+	}
+	return statsResponse(stats, days, "stats-global.txt")
+}
@@ -0,0 +1,117 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/message"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// started is roughly when the bot started, as this is set as the package is loaded.
+var started = time.Now()
+
+var statusModule = module.Basic{
+	ModuleName: "status",
+	CommandHandlers: map[string]command.Handler{
+		"status": commandStatusObject,
+	},
+}
+
+var commandStatusObject = command.Handler{
+	Description: "Show how the bot itself is doing",
+	Code:        CommandStatus,
+	Options:     []discord.CommandOption{},
+}
+
+// CommandStatus shows uptime, latency, guilds, storage, scheduled timers and memory use.
+func CommandStatus(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**Up since** <t:%d:R>\n", started.Unix())
+
+	if gw := state.Gateway(); gw != nil {
+		fmt.Fprintf(&sb, "**Gateway latency** %s\n", gw.Latency().Round(time.Millisecond))
+		if shard := gw.State().Identifier.Shard; shard != nil && shard.NumShards() > 1 {
+			fmt.Fprintf(&sb, "**Shard** %d of %d, the guild count below is for this shard only\n", shard.ShardID(), shard.NumShards())
+		}
+	} else {
+		sb.WriteString("**Gateway latency** not connected\n")
+	}
+
+	guilds, err := state.Guilds()
+	if err != nil {
+		log.Printf("[%s] /status failed to get guilds: %s", event.GuildID, err)
+		sb.WriteString("**Guilds** unknown\n")
+	} else {
+		fmt.Fprintf(&sb, "**Guilds** %d\n", len(guilds))
+		pending, due := 0, 0
+		now := time.Now()
+		for _, guild := range guilds {
+			timers, err := storage.GetTimers(kvs, guild.ID)
+			if err != nil {
+				log.Printf("[%s] /status failed to get timers: %s", guild.ID, err)
+				continue
+			}
+			pending += len(timers)
+			for _, t := range timers {
+				if t.Due(now) {
+					due++
+				}
+			}
+		}
+		fmt.Fprintf(&sb, "**Scheduled timers** %d, %d of them due\n", pending, due)
+	}
+
+	if unavailable := message.Unavailable(state); len(unavailable) > 0 {
+		fmt.Fprintf(&sb, "**Message content** not asked for, so these are off: %s\n", strings.Join(unavailable, ", "))
+		sb.WriteString("Whoever runs the bot can turn on the Message Content Intent for it, and set ContentIntent in the configuration.\n")
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	fmt.Fprintf(&sb, "**Memory** %s in use, %s from the system, %d goroutines\n", humanBytes(mem.HeapAlloc), humanBytes(mem.Sys), runtime.NumGoroutine())
+
+	usage, err := kvs.Usage(event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /status failed to get storage usage: %s", event.GuildID, err)
+		sb.WriteString("\n**Storage here** unknown\n")
+	} else {
+		collections := make([]string, 0, len(usage))
+		for collection := range usage {
+			collections = append(collections, collection)
+		}
+		sort.Slice(collections, func(i, j int) bool {
+			return usage[collections[i]].Bytes > usage[collections[j]].Bytes
+		})
+		sb.WriteString("\n**Storage here**\n")
+		if len(collections) == 0 {
+			sb.WriteString("Nothing stored yet.\n")
+		}
+		for _, collection := range collections {
+			fmt.Fprintf(&sb, "%s: %d keys, %s\n", collection, usage[collection].Keys, humanBytes(uint64(usage[collection].Bytes)))
+		}
+	}
+	return command.Response{Response: response.Ephemeral(sb.String())}
+}
+
+// humanBytes formats a number of bytes for people, like "1.5 MB".
+func humanBytes(bytes uint64) string {
+	switch {
+	case bytes >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(bytes)/(1<<30))
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(bytes)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", bytes)
+}
@@ -0,0 +1,187 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/join"
+	"komainu/interactions/memberupdate"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+var stickyRolesModule = module.Basic{
+	ModuleName: "stickyroles",
+	CommandHandlers: map[string]command.Handler{
+		"stickyroles": commandStickyRolesObject,
+	},
+	Handlers: []module.EventHandler{
+		module.MemberUpdate(memberupdate.Handler{Code: MemberUpdateStickyRoles}),
+		module.Join(join.Handler{Code: JoinStickyRoles}),
+	},
+}
+
+var commandStickyRolesObject = command.Handler{
+	Description: "Give people their roles back when they leave and come back",
+	Code:        CommandStickyRoles,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "enable",
+			Description: "Start remembering roles, and giving them back",
+			Options:     []discord.CommandOptionValue{},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "disable",
+			Description: "Stop giving roles back",
+			Options:     []discord.CommandOptionValue{},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "exclude",
+			Description: "Never give a role back, or stop excluding it",
+			Options: []discord.CommandOptionValue{
+				&discord.RoleOption{
+					OptionName:  "role",
+					Description: "The role to toggle",
+					Required:    true,
+				},
+			},
+		},
+	},
+}
+
+// CommandStickyRoles processes the /stickyroles command, dispatching to the right subcommand.
+func CommandStickyRoles(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /stickyroles command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	config, err := storage.GetStickyRolesConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to get sticky roles config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+
+	switch cmd.Options[0].Name {
+	case "enable":
+		config.Enabled = true
+		if err := storage.SetStickyRolesConfig(kvs, event.GuildID, config); err != nil {
+			log.Printf("[%s] Failed to store sticky roles config: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		log.Printf("[%s] <@%s> enabled sticky roles", event.GuildID, event.SenderID())
+		// Remembering everyone's roles as they are now, as changes are all we hear about from here on.
+		return command.Deferred(state, event, func() string {
+			members, err := state.Session.Members(event.GuildID, 0)
+			if err != nil {
+				log.Printf("[%s] Sticky roles failed to get the members: %s", event.GuildID, err)
+				return "Sticky roles are on, but I couldn't get the member list, so I only remember roles that change from now on. The error has been logged."
+			}
+			for _, member := range members {
+				if err := storage.RememberRoles(kvs, event.GuildID, member.User.ID, member.RoleIDs); err != nil {
+					log.Printf("[%s] Sticky roles failed to remember the roles of <@%s>: %s", event.GuildID, member.User.ID, err)
+					return "Sticky roles are on, but I couldn't remember everyone's roles. The error has been logged."
+				}
+			}
+			return fmt.Sprintf("Sticky roles are on! I remember the roles of all %d members, and give them back if they leave and come back.", len(members))
+		})
+	case "disable":
+		config.Enabled = false
+	case "exclude":
+		roleSnowflake, err := cmd.Options[0].Options.Find("role").SnowflakeValue()
+		if err != nil {
+			log.Printf("[%s] /stickyroles exclude failed to get role snowflake: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("There was an issue figuring out the role. It has been logged.")}
+		}
+		roleID := discord.RoleID(roleSnowflake)
+		if config.Exclude[roleID] {
+			delete(config.Exclude, roleID)
+		} else {
+			config.Exclude[roleID] = true
+		}
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+
+	if err := storage.SetStickyRolesConfig(kvs, event.GuildID, config); err != nil {
+		log.Printf("[%s] Failed to store sticky roles config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> changed the sticky roles config", event.GuildID, event.SenderID())
+	return command.Response{Response: response.Ephemeral(describeStickyRolesConfig(config))}
+}
+
+func describeStickyRolesConfig(config storage.StickyRolesConfig) string {
+	reply := "Sticky roles are off."
+	if config.Enabled {
+		reply = "Sticky roles are on."
+	}
+	if len(config.Exclude) == 0 {
+		return reply + " No roles are excluded."
+	}
+	excluded := make([]string, 0, len(config.Exclude))
+	for roleID := range config.Exclude {
+		excluded = append(excluded, roleID.Mention())
+	}
+	return reply + " These roles are never given back: " + strings.Join(excluded, " ")
+}
+
+// MemberUpdateStickyRoles remembers the roles of members as they change.
+func MemberUpdateStickyRoles(state *state.State, kvs storage.KeyValueStore, event *gateway.GuildMemberUpdateEvent) {
+	config, err := storage.GetStickyRolesConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Sticky roles failed to get config: %s", event.GuildID, err)
+		return
+	}
+	if !config.Enabled {
+		return
+	}
+	if err := storage.RememberRoles(kvs, event.GuildID, event.User.ID, event.RoleIDs); err != nil {
+		log.Printf("[%s] Sticky roles failed to remember the roles of <@%s>: %s", event.GuildID, event.User.ID, err)
+	}
+}
+
+// JoinStickyRoles gives people back the roles they had when they left.
+func JoinStickyRoles(state *state.State, kvs storage.KeyValueStore, event *gateway.GuildMemberAddEvent) {
+	config, err := storage.GetStickyRolesConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Sticky roles failed to get config: %s", event.GuildID, err)
+		return
+	}
+	if !config.Enabled {
+		return
+	}
+	roles, err := storage.RememberedRoles(kvs, event.GuildID, event.User.ID)
+	if err != nil {
+		log.Printf("[%s] Sticky roles failed to get the remembered roles of <@%s>: %s", event.GuildID, event.User.ID, err)
+		return
+	}
+	restored := 0
+	for _, roleID := range roles {
+		if config.Exclude[roleID] {
+			continue
+		}
+		// Roles can be deleted, or be above the bot, or be managed by an integration. None of that should stop the rest.
+		if err := utility.CanManageRole(state, event.GuildID, nil, roleID); utility.OutOfReach(err) {
+			log.Printf("[%s] Sticky roles can't give <@%s> back <@&%s>: %s", event.GuildID, event.User.ID, roleID, err)
+			continue
+		}
+		err := state.AddRole(event.GuildID, event.User.ID, roleID, api.AddRoleData{AuditLogReason: "Sticky roles"})
+		if err != nil {
+			log.Printf("[%s] Sticky roles failed to give <@%s> back <@&%s>: %s", event.GuildID, event.User.ID, roleID, err)
+			continue
+		}
+		restored++
+	}
+	if restored > 0 {
+		log.Printf("[%s] Sticky roles gave <@%s> back %d roles", event.GuildID, event.User.ID, restored)
+	}
+}
@@ -0,0 +1,261 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+var (
+	twitchLogin      = regexp.MustCompile(`^[a-zA-Z0-9_]{3,25}$`)
+	youTubeChannelID = regexp.MustCompile(`UC[0-9A-Za-z_-]{22}`)
+)
+
+var streamModule = module.Basic{
+	ModuleName: "stream",
+	CommandHandlers: map[string]command.Handler{
+		"stream": commandStreamObject,
+	},
+}
+
+var commandStreamObject = command.Handler{
+	Description: "Announce when Twitch channels go live, or YouTube channels upload",
+	Code:        CommandStream,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "add",
+			Description: "Start announcing a channel",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "platform",
+					Description: "Where the channel is",
+					Required:    true,
+					Choices: []discord.StringChoice{
+						{Name: "Twitch", Value: storage.StreamTwitch},
+						{Name: "YouTube", Value: storage.StreamYouTube},
+					},
+				},
+				&discord.StringOption{
+					OptionName:  "account",
+					Description: "The Twitch name, or the YouTube channel ID or address",
+					Required:    true,
+				},
+				&discord.ChannelOption{
+					OptionName:   "channel",
+					Description:  "Where to announce it",
+					Required:     true,
+					ChannelTypes: []discord.ChannelType{discord.GuildText, discord.GuildNews},
+				},
+				&discord.StringOption{
+					OptionName:  "message",
+					Description: "What to say. {name}, {title}, {url} and {game} are filled in.",
+					Required:    false,
+				},
+				&discord.RoleOption{
+					OptionName:  "role",
+					Description: "A role to ping with every announcement",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "remove",
+			Description: "Stop announcing a channel",
+			Options: []discord.CommandOptionValue{
+				&discord.IntegerOption{
+					OptionName:  "stream",
+					Description: "The number of the channel, as shown by /stream list",
+					Required:    true,
+					Min:         option.NewInt(1),
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "list",
+			Description: "List the channels announced here",
+			Options:     []discord.CommandOptionValue{},
+		},
+	},
+}
+
+// CommandStream processes the /stream command, dispatching to the right subcommand.
+func CommandStream(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /stream command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "add":
+		return SubCommandStreamAdd(state, kvs, event, cmd.Options[0].Options)
+	case "remove":
+		return SubCommandStreamRemove(kvs, event, cmd.Options[0].Options)
+	case "list":
+		return SubCommandStreamList(kvs, event)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// streamAccount works out the Twitch login or YouTube channel ID from what was typed, which could be an address.
+func streamAccount(platform string, typed string) (string, bool) {
+	typed = strings.TrimSpace(typed)
+	switch platform {
+	case storage.StreamTwitch:
+		typed = strings.TrimSuffix(typed, "/")
+		if slash := strings.LastIndex(typed, "/"); slash >= 0 {
+			typed = typed[slash+1:]
+		}
+		return strings.ToLower(typed), twitchLogin.MatchString(typed)
+	case storage.StreamYouTube:
+		id := youTubeChannelID.FindString(typed)
+		return id, id != ""
+	}
+	return "", false
+}
+
+// SubCommandStreamAdd starts announcing a channel. YouTube channels are checked right away, and their current videos
+// are not announced. Twitch channels are announced the first time they're seen live, which could be right away.
+func SubCommandStreamAdd(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	platform := options.Find("platform").String()
+	account, ok := streamAccount(platform, options.Find("account").String())
+	if !ok {
+		if platform == storage.StreamYouTube {
+			return command.Response{Response: response.Ephemeral("I need the channel ID, the one starting with `UC`, or an address with it in. You'll find it under *Share channel* on the channel page.")}
+		}
+		return command.Response{Response: response.Ephemeral("That doesn't look like a Twitch name.")}
+	}
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /stream add failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	watch := storage.StreamWatch{
+		GuildID:   event.GuildID,
+		ChannelID: discord.ChannelID(channelSnowflake),
+		Platform:  platform,
+		Account:   account,
+		Name:      account,
+		Message:   strings.TrimSpace(options.Find("message").String()),
+		AddedBy:   event.SenderID(),
+	}
+	if err := utility.ValidateTemplate(watch.Message, storage.StreamPlaceholders...); err != nil {
+		return command.Response{Response: response.Ephemeral("That message won't work: " + err.Error())}
+	}
+	if opt := options.Find("role"); opt.Name != "" {
+		roleSnowflake, err := opt.SnowflakeValue()
+		if err != nil {
+			log.Printf("[%s] /stream add failed to get role snowflake: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("There was an issue figuring out the role. It has been logged.")}
+		}
+		watch.RoleID = discord.RoleID(roleSnowflake)
+	}
+
+	watches, err := storage.GetStreamWatches(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /stream add failed to get the stream watches: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(watches) >= storage.StreamLimit {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("This guild already announces %d channels, which is as many as it gets.", storage.StreamLimit))}
+	}
+	for _, existing := range watches {
+		if existing.Platform == platform && existing.Account == account && existing.ChannelID == watch.ChannelID {
+			return command.Response{Response: response.Ephemeral(fmt.Sprintf("That is already announced in %s, as number %d.", watch.ChannelID.Mention(), existing.ID))}
+		}
+	}
+
+	// Looking the YouTube channel up can take a while, so this is deferred.
+	return command.DeferredData(state, event, true, func() api.EditInteractionResponseData {
+		reply := func(text string) api.EditInteractionResponseData {
+			return api.EditInteractionResponseData{Content: option.NewNullableString(text)}
+		}
+		if platform == storage.StreamYouTube {
+			fetched, err := storage.FetchFeed(storage.YouTubeFeedURL(account))
+			if err != nil {
+				return reply(fmt.Sprintf("I couldn't find that YouTube channel: %s", err))
+			}
+			if fetched.Title != "" {
+				watch.Name = fetched.Title
+			}
+			watch.MarkSeen(fetched.Items...)
+		}
+		id, err := storage.NextNumber(kvs, event.GuildID, "streams")
+		if err != nil {
+			log.Printf("[%s] /stream add failed to get a number: %s", event.GuildID, err)
+			return reply("An error occured, and has been logged.")
+		}
+		watch.ID = id
+		if err := watch.Store(kvs); err != nil {
+			log.Printf("[%s] /stream add failed to store the stream watch: %s", event.GuildID, err)
+			return reply("An error occured, and has been logged.")
+		}
+		log.Printf("[%s] <@%s> added stream watch %d, %s %s, announcing in <#%s>", event.GuildID, event.SenderID(), watch.ID, platform, account, watch.ChannelID)
+		if platform == storage.StreamYouTube {
+			return reply(fmt.Sprintf("New videos from **%s** will be announced in %s, as number %d.", watch.Name, watch.ChannelID.Mention(), watch.ID))
+		}
+		return reply(fmt.Sprintf("**%s** going live will be announced in %s, as number %d.", watch.Name, watch.ChannelID.Mention(), watch.ID))
+	})
+}
+
+// SubCommandStreamRemove stops announcing a channel.
+func SubCommandStreamRemove(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	id, err := options.Find("stream").IntValue()
+	if err != nil {
+		log.Printf("[%s] /stream remove failed to get the number: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	exist, watch, err := storage.GetStreamWatch(kvs, event.GuildID, id)
+	if err != nil {
+		log.Printf("[%s] /stream remove failed to get stream watch %d: %s", event.GuildID, id, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no stream number %d here.", id))}
+	}
+	if err := watch.Delete(kvs); err != nil {
+		log.Printf("[%s] /stream remove failed to remove stream watch %d: %s", event.GuildID, id, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> removed stream watch %d, %s %s", event.GuildID, event.SenderID(), watch.ID, watch.Platform, watch.Account)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("No longer announcing **%s**.", watch.Name))}
+}
+
+// SubCommandStreamList lists the channels announced, with where and if anything is wrong with them.
+func SubCommandStreamList(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	watches, err := storage.GetStreamWatches(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /stream list failed to get the stream watches: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(watches) == 0 {
+		return command.Response{Response: response.Ephemeral("No channels are announced here. Add one with `/stream add`.")}
+	}
+	lines := make([]string, len(watches))
+	for i, watch := range watches {
+		platform := "Twitch"
+		if watch.Platform == storage.StreamYouTube {
+			platform = "YouTube"
+		}
+		lines[i] = fmt.Sprintf("**%d** %s on %s, in %s", watch.ID, watch.Name, platform, watch.ChannelID.Mention())
+		if watch.Live {
+			lines[i] += ", live now"
+		}
+		if watch.LastError != "" {
+			lines[i] += "\n⚠️ " + watch.LastError
+		}
+	}
+	return command.Response{Response: response.Ephemeral(strings.Join(lines, "\n"))}
+}
@@ -0,0 +1,278 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/component"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+const (
+	suggestionCollection = "suggestionconfig"
+	suggestionKey        = "channel"
+)
+
+var suggestionModule = module.Basic{
+	ModuleName: "suggestion",
+	CommandHandlers: map[string]command.Handler{
+		"suggest":    commandSuggestObject,
+		"suggestion": commandSuggestionObject,
+	},
+	ComponentHandlers: map[string]component.Handler{
+		"suggestion": component.Handler{Code: ComponentSuggestion},
+	},
+}
+
+var commandSuggestObject = command.Handler{
+	Description: "Suggest something for everyone to vote on",
+	Code:        CommandSuggest,
+	Public:      true,
+	Options: []discord.CommandOption{
+		&discord.StringOption{
+			OptionName:  "suggestion",
+			Description: "What you would like to see happen",
+			Required:    true,
+		},
+	},
+}
+
+var suggestionDecisionOptions = []discord.CommandOptionValue{
+	&discord.IntegerOption{
+		OptionName:  "id",
+		Description: "The number of the suggestion",
+		Required:    true,
+		Min:         option.NewInt(1),
+	},
+	&discord.StringOption{
+		OptionName:  "reason",
+		Description: "Why? This is shown on the suggestion.",
+		Required:    false,
+	},
+}
+
+var commandSuggestionObject = command.Handler{
+	Description: "Decide on suggestions",
+	Code:        CommandSuggestion,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "approve",
+			Description: "Approve a suggestion. It can still be voted on.",
+			Options:     suggestionDecisionOptions,
+		},
+		&discord.SubcommandOption{
+			OptionName:  "deny",
+			Description: "Deny a suggestion, and close it for voting",
+			Options:     suggestionDecisionOptions,
+		},
+		&discord.SubcommandOption{
+			OptionName:  "implement",
+			Description: "Mark a suggestion as done, and close it for voting",
+			Options:     suggestionDecisionOptions,
+		},
+	},
+}
+
+// getSuggestionChannel gets the channel suggestions go to, if one is set.
+func getSuggestionChannel(kvs storage.KeyValueStore, guildID discord.GuildID) (exist bool, channel discord.ChannelID, err error) {
+	exist, err = kvs.Get(guildID, suggestionCollection, suggestionKey, &channel)
+	return
+}
+
+// CommandSuggest posts a suggestion in the suggestion channel.
+func CommandSuggest(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	exist, channelID, err := getSuggestionChannel(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /suggest failed to get the suggestion channel: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral("Suggestions aren't enabled here. Ask the staff to set a channel for them with `/config suggestions`.")}
+	}
+	text := strings.TrimSpace(cmd.Options.Find("suggestion").String())
+	if text == "" {
+		return command.Response{Response: response.Ephemeral("You have to actually suggest something.")}
+	}
+
+	suggestion, err := storage.NewSuggestion(kvs, event.GuildID, event.SenderID(), text, time.Now().Unix())
+	if err != nil {
+		log.Printf("[%s] /suggest failed to create the suggestion: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	message, err := state.SendMessageComplex(channelID, api.SendMessageData{
+		Embeds:     []discord.Embed{suggestion.Embed()},
+		Components: *makeSuggestionButtons(suggestion),
+	})
+	if err != nil {
+		log.Printf("[%s] /suggest failed to post in <#%s>: %s", event.GuildID, channelID, err)
+		return command.Response{Response: response.Ephemeral("I couldn't post your suggestion. The error has been logged.")}
+	}
+	suggestion.ChannelID = message.ChannelID
+	suggestion.MessageID = message.ID
+	if err := suggestion.Store(kvs); err != nil {
+		log.Printf("[%s] Failed to store suggestion #%d after posting it: %s", event.GuildID, suggestion.ID, err)
+	}
+	log.Printf("[%s] <@%s> made suggestion #%d", event.GuildID, event.SenderID(), suggestion.ID)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Your suggestion is posted as #%d in %s.", suggestion.ID, channelID.Mention()))}
+}
+
+func makeSuggestionButtons(suggestion *storage.Suggestion) *discord.ContainerComponents {
+	if !suggestion.Open() {
+		return &discord.ContainerComponents{}
+	}
+	return &discord.ContainerComponents{
+		&discord.ActionRowComponent{
+			&discord.ButtonComponent{
+				Style:    discord.SuccessButtonStyle(),
+				CustomID: component.ID("suggestion", "up", strconv.FormatInt(suggestion.ID, 10)),
+				Label:    "👍",
+			},
+			&discord.ButtonComponent{
+				Style:    discord.DangerButtonStyle(),
+				CustomID: component.ID("suggestion", "down", strconv.FormatInt(suggestion.ID, 10)),
+				Label:    "👎",
+			},
+		},
+	}
+}
+
+// ComponentSuggestion handles the vote buttons on a suggestion. Voting the same way twice takes the vote back.
+func ComponentSuggestion(ctx *command.Context, interaction discord.ComponentInteraction, params []string) api.InteractionResponse {
+	kvs, e := ctx.KVS, ctx.Event
+	// suggestion/up|down/id
+	if len(params) != 2 {
+		log.Printf("[%s] Malformed suggestion component ID: %s", e.GuildID, interaction.ID())
+		return response.Ephemeral("That button is broken somehow. It has been logged.")
+	}
+	id, err := strconv.ParseInt(params[1], 10, 64)
+	if err != nil {
+		log.Printf("[%s] Malformed suggestion number in component: %s", e.GuildID, err)
+		return response.Ephemeral("That button is broken somehow. It has been logged.")
+	}
+	unlock, err := storage.LockSuggestion(kvs, e.GuildID, id)
+	if err != nil {
+		log.Printf("[%s] Failed to lock suggestion #%d for a vote: %s", e.GuildID, id, err)
+		return response.Ephemeral("Lots of people are voting right now. Please try again in a moment.")
+	}
+	defer unlock()
+	exist, suggestion, err := storage.GetSuggestion(kvs, e.GuildID, id)
+	if err != nil {
+		log.Printf("[%s] Error while trying to fetch suggestion #%d for a vote: %s", e.GuildID, id, err)
+		return response.Ephemeral("There was an issue processing your vote. It has been logged.")
+	}
+	if !exist || !suggestion.Open() {
+		return response.Ephemeral("That suggestion isn't open for voting.")
+	}
+
+	vote := 1
+	if params[0] == "down" {
+		vote = -1
+	}
+	voter := e.SenderID()
+	if suggestion.Votes[voter] == vote {
+		delete(suggestion.Votes, voter)
+	} else {
+		suggestion.Votes[voter] = vote
+	}
+	if err := suggestion.Store(kvs); err != nil {
+		log.Printf("[%s] Error storing vote on suggestion #%d: %s", e.GuildID, id, err)
+		return response.Ephemeral("There was an issue storing your vote. It has been logged.")
+	}
+	return api.InteractionResponse{
+		Type: api.UpdateMessage,
+		Data: &api.InteractionResponseData{
+			Embeds:     &[]discord.Embed{suggestion.Embed()},
+			Components: makeSuggestionButtons(suggestion),
+		},
+	}
+}
+
+// CommandSuggestion processes the staff decisions on suggestions.
+func CommandSuggestion(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /suggestion command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	status := map[string]string{
+		"approve":   storage.SuggestionApproved,
+		"deny":      storage.SuggestionDenied,
+		"implement": storage.SuggestionImplemented,
+	}[cmd.Options[0].Name]
+	if status == "" {
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+	options := cmd.Options[0].Options
+	id, err := options.Find("id").IntValue()
+	if err != nil {
+		log.Printf("[%s] /suggestion failed to get the ID: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+
+	unlock, err := storage.LockSuggestion(kvs, event.GuildID, id)
+	if err != nil {
+		log.Printf("[%s] /suggestion failed to lock suggestion #%d: %s", event.GuildID, id, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	defer unlock()
+	exist, suggestion, err := storage.GetSuggestion(kvs, event.GuildID, id)
+	if err != nil {
+		log.Printf("[%s] /suggestion failed to get suggestion #%d: %s", event.GuildID, id, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral(fmt.Sprintf("There is no suggestion #%d.", id))}
+	}
+	suggestion.Status = status
+	suggestion.Reason = options.Find("reason").String()
+	if err := suggestion.Store(kvs); err != nil {
+		log.Printf("[%s] /suggestion failed to store suggestion #%d: %s", event.GuildID, id, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> marked suggestion #%d as %s", event.GuildID, event.SenderID(), id, status)
+
+	_, err = state.EditMessageComplex(suggestion.ChannelID, suggestion.MessageID, api.EditMessageData{
+		Embeds:     &[]discord.Embed{suggestion.Embed()},
+		Components: makeSuggestionButtons(suggestion),
+	})
+	if err != nil {
+		log.Printf("[%s] Failed to update the message of suggestion #%d: %s", event.GuildID, id, err)
+	}
+
+	notified := notifySuggestionAuthor(state, suggestion)
+	reply := fmt.Sprintf("Suggestion #%d is now %s.", id, status)
+	if !notified {
+		reply += " I couldn't DM the author about it, though."
+	}
+	return command.Response{Response: response.Ephemeral(reply)}
+}
+
+// notifySuggestionAuthor tells the author what happened with their suggestion, and returns false if that didn't work out.
+func notifySuggestionAuthor(state *state.State, suggestion *storage.Suggestion) bool {
+	dm, err := state.CreatePrivateChannel(suggestion.Author)
+	if err != nil {
+		log.Printf("[%s] Could not open a DM with <@%s> about suggestion #%d: %s", suggestion.GuildID, suggestion.Author, suggestion.ID, err)
+		return false
+	}
+	link := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", suggestion.GuildID, suggestion.ChannelID, suggestion.MessageID)
+	content := fmt.Sprintf("Your suggestion #%d has been %s! %s", suggestion.ID, suggestion.Status, link)
+	if suggestion.Reason != "" {
+		content += "\nReason: " + suggestion.Reason
+	}
+	if _, err := state.SendMessage(dm.ID, content); err != nil {
+		log.Printf("[%s] Could not DM <@%s> about suggestion #%d: %s", suggestion.GuildID, suggestion.Author, suggestion.ID, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package thread
+
+import (
+	"komainu/storage"
+
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+type Handler struct {
+	Code HandlerFunction
+}
+
+type HandlerFunction func(
+	state *state.State,
+	kvs storage.KeyValueStore,
+	event *gateway.ThreadCreateEvent,
+)
+
+var threadhandlers = []Handler{}
+
+// Register makes the Code turn over when a thread is created
+func Register(handler Handler) {
+	threadhandlers = append(threadhandlers, handler)
+}
+
+// Add the thread handler to the given state
+// This is mostly just pointless abstraction for uniformity across events.
+func AddHandler(state *state.State, kvs storage.KeyValueStore) {
+	state.AddHandler(func(event *gateway.ThreadCreateEvent) {
+		for _, handler := range threadhandlers {
+			handler.Code(state, kvs, event)
+		}
+	})
+}
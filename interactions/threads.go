@@ -0,0 +1,196 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/message"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/interactions/thread"
+	"komainu/storage"
+	"log"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+var threadsModule = module.Basic{
+	ModuleName: "threads",
+	CommandHandlers: map[string]command.Handler{
+		"threads": commandThreadsObject,
+	},
+	Handlers: []module.EventHandler{
+		module.Message(message.Handler{Code: MessageAutoThread}),
+		module.Thread(thread.Handler{Code: ThreadAutoJoin}),
+	},
+}
+
+var commandThreadsObject = command.Handler{
+	Description: "Let the bot take care of threads",
+	Code:        CommandThreads,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "auto",
+			Description: "Make a thread for every message in a channel, or stop doing that",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:  "channel",
+					Description: "The channel to toggle",
+					Required:    true,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "config",
+			Description: "Change how threads are looked after. Anything left blank stays as it is.",
+			Options: []discord.CommandOptionValue{
+				&discord.IntegerOption{
+					OptionName:  "stale_hours",
+					Description: "Archive threads nobody has said anything in for this many hours. 0 to never do that.",
+					Required:    false,
+					Min:         option.NewInt(0),
+					Max:         option.NewInt(8760),
+				},
+				&discord.BooleanOption{
+					OptionName:  "join",
+					Description: "Join every new thread, so things like automod and /seen work in them",
+					Required:    false,
+				},
+			},
+		},
+	},
+}
+
+// CommandThreads processes the /threads command, dispatching to the right subcommand.
+func CommandThreads(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /threads command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	config, err := storage.GetThreadConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to get thread config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	options := cmd.Options[0].Options
+
+	switch cmd.Options[0].Name {
+	case "auto":
+		channelSnowflake, err := options.Find("channel").SnowflakeValue()
+		if err != nil {
+			log.Printf("[%s] /threads auto failed to get channel snowflake: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+		}
+		channelID := discord.ChannelID(channelSnowflake)
+		if config.AutoThread[channelID] {
+			delete(config.AutoThread, channelID)
+		} else {
+			config.AutoThread[channelID] = true
+		}
+	case "config":
+		if options.Find("stale_hours").Name != "" {
+			config.StaleHours, err = options.Find("stale_hours").IntValue()
+			if err != nil {
+				log.Printf("[%s] /threads config failed to get stale_hours: %s", event.GuildID, err)
+				return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+			}
+		}
+		if options.Find("join").Name != "" {
+			config.AutoJoin, err = options.Find("join").BoolValue()
+			if err != nil {
+				log.Printf("[%s] /threads config failed to get join: %s", event.GuildID, err)
+				return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+			}
+		}
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+
+	if err := storage.SetThreadConfig(kvs, event.GuildID, config); err != nil {
+		log.Printf("[%s] Failed to store thread config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> changed the thread config", event.GuildID, event.SenderID())
+	return command.Response{Response: response.Ephemeral(describeThreadConfig(config))}
+}
+
+func describeThreadConfig(config storage.ThreadConfig) string {
+	var sb strings.Builder
+	if len(config.AutoThread) == 0 {
+		sb.WriteString("No channels get a thread for every message.\n")
+	} else {
+		sb.WriteString("Every message gets a thread in:")
+		for channelID := range config.AutoThread {
+			sb.WriteString(" " + channelID.Mention())
+		}
+		sb.WriteString("\n")
+	}
+	if config.StaleHours > 0 {
+		fmt.Fprintf(&sb, "Threads are archived after %d hours without a message.\n", config.StaleHours)
+	} else {
+		sb.WriteString("Threads are left to Discord to archive.\n")
+	}
+	if config.AutoJoin {
+		sb.WriteString("I join every new thread.")
+	} else {
+		sb.WriteString("I don't join new threads, so I don't see what's said in them unless someone adds me.")
+	}
+	return sb.String()
+}
+
+// autoThreadName makes a thread name from the first line of the message, or the author's name if there is no text.
+func autoThreadName(event *gateway.MessageCreateEvent) string {
+	name := strings.TrimSpace(strings.SplitN(event.Content, "\n", 2)[0])
+	if name == "" {
+		name = event.Author.Username + "'s post"
+	}
+	if runes := []rune(name); len(runes) > 100 {
+		name = string(runes[:99]) + "…"
+	}
+	return name
+}
+
+// MessageAutoThread makes a thread for every message in the channels that are set up for it.
+func MessageAutoThread(state *state.State, kvs storage.KeyValueStore, event *gateway.MessageCreateEvent) {
+	if event.GuildID == discord.NullGuildID || event.Author.Bot {
+		return
+	}
+	if event.Type != discord.DefaultMessage && event.Type != discord.InlinedReplyMessage {
+		return
+	}
+	config, err := storage.GetThreadConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Auto thread failed to get config: %s", event.GuildID, err)
+		return
+	}
+	if !config.AutoThread[event.ChannelID] {
+		return
+	}
+	_, err = state.StartThreadWithMessage(event.ChannelID, event.ID, api.StartThreadData{
+		Name:                autoThreadName(event),
+		AutoArchiveDuration: discord.OneDayArchive,
+	})
+	if err != nil {
+		log.Printf("[%s] Auto thread failed to start a thread in <#%s>: %s", event.GuildID, event.ChannelID, err)
+	}
+}
+
+// ThreadAutoJoin joins new threads, if the guild wants that.
+func ThreadAutoJoin(state *state.State, kvs storage.KeyValueStore, event *gateway.ThreadCreateEvent) {
+	config, err := storage.GetThreadConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Auto join failed to get thread config: %s", event.GuildID, err)
+		return
+	}
+	if !config.AutoJoin {
+		return
+	}
+	if err := state.JoinThread(event.ID); err != nil {
+		log.Printf("[%s] Failed to join thread <#%s>: %s", event.GuildID, event.ID, err)
+	}
+}
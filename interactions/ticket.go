@@ -0,0 +1,338 @@
+package interactions
+
+import (
+	"bytes"
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/component"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+	"github.com/diamondburned/arikawa/v3/utils/sendpart"
+)
+
+const ticketDefaultPreamble = "Need to talk to the staff in private? Click the button below, and a private thread will be opened for you."
+
+var ticketModule = module.Basic{
+	ModuleName: "ticket",
+	CommandHandlers: map[string]command.Handler{
+		"ticket": commandTicketObject,
+	},
+	ComponentHandlers: map[string]component.Handler{
+		"ticket": component.Handler{Code: ComponentTicket},
+	},
+}
+
+var commandTicketObject = command.Handler{
+	Description: "Private threads between members and staff",
+	Code:        CommandTicket,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "setup",
+			Description: "Post the \"Open ticket\" button, and decide where transcripts go",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:  "channel",
+					Description: "Where to post the button. Tickets become private threads in this channel.",
+					Required:    true,
+				},
+				&discord.ChannelOption{
+					OptionName:  "log",
+					Description: "Where to post the transcript when a ticket is closed",
+					Required:    true,
+				},
+				&discord.RoleOption{
+					OptionName:  "staff",
+					Description: "Who to pull into new tickets",
+					Required:    true,
+				},
+				&discord.StringOption{
+					OptionName:  "text",
+					Description: "What to say above the button",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "close",
+			Description: "Close the ticket this is used in",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "reason",
+					Description: "Why it's being closed. This goes in the log.",
+					Required:    false,
+				},
+			},
+		},
+	},
+}
+
+// CommandTicket processes the /ticket command, dispatching to the right subcommand.
+func CommandTicket(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /ticket command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "setup":
+		return SubCommandTicketSetup(state, kvs, event, cmd.Options[0].Options)
+	case "close":
+		return command.Response{Response: ticketClose(state, kvs, event, cmd.Options[0].Options.Find("reason").String())}
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandTicketSetup stores the ticket setup and posts the button to open tickets with.
+func SubCommandTicketSetup(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /ticket setup failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	logSnowflake, err := options.Find("log").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /ticket setup failed to get log channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the log channel. It has been logged.")}
+	}
+	staffSnowflake, err := options.Find("staff").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /ticket setup failed to get staff role snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the staff role. It has been logged.")}
+	}
+	preamble := options.Find("text").String()
+	if preamble == "" {
+		preamble = ticketDefaultPreamble
+	}
+
+	config := storage.TicketConfig{
+		LogChannelID: discord.ChannelID(logSnowflake),
+		StaffRoleID:  discord.RoleID(staffSnowflake),
+	}
+	if err := storage.SetTicketConfig(kvs, event.GuildID, config); err != nil {
+		log.Printf("[%s] Failed to store ticket config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+
+	channelID := discord.ChannelID(channelSnowflake)
+	_, err = state.SendMessageComplex(channelID, api.SendMessageData{
+		Content: preamble,
+		Components: discord.ContainerComponents{
+			&discord.ActionRowComponent{
+				&discord.ButtonComponent{
+					Style:    discord.PrimaryButtonStyle(),
+					CustomID: component.ID("ticket", "open"),
+					Label:    "Open ticket",
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("[%s] /ticket setup failed to post the button in <#%s>: %s", event.GuildID, channelID, err)
+		return command.Response{Response: response.Ephemeral("I couldn't post in that channel. Do I have access to it?")}
+	}
+	log.Printf("[%s] <@%s> set up tickets in <#%s>, logging to <#%s>", event.GuildID, event.SenderID(), channelID, config.LogChannelID)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Tickets can now be opened in %s, and transcripts will go to %s.", channelID.Mention(), config.LogChannelID.Mention()))}
+}
+
+// ComponentTicket handles the "Open ticket" and "Close ticket" buttons.
+func ComponentTicket(ctx *command.Context, interaction discord.ComponentInteraction, params []string) api.InteractionResponse {
+	state, kvs, e := ctx.State, ctx.KVS, ctx.Event
+	action := ""
+	if len(params) == 1 {
+		action = params[0]
+	}
+	switch action {
+	case "open":
+		return ticketOpen(state, kvs, e)
+	case "close":
+		exist, ticket, err := storage.GetTicket(kvs, e.GuildID, e.ChannelID)
+		if err != nil {
+			log.Printf("[%s] Failed to get ticket for close button in <#%s>: %s", e.GuildID, e.ChannelID, err)
+			return response.Ephemeral("An error occured, and has been logged.")
+		}
+		_, config, err := storage.GetTicketConfig(kvs, e.GuildID)
+		if err != nil {
+			log.Printf("[%s] Failed to get ticket config for close button: %s", e.GuildID, err)
+			return response.Ephemeral("An error occured, and has been logged.")
+		}
+		if exist && ticket.Opener != e.SenderID() && !utility.ContainsRole(e.Member.RoleIDs, config.StaffRoleID) {
+			return response.Ephemeral("Only the one that opened the ticket, or the staff, can close it.")
+		}
+		return ticketClose(state, kvs, e, "")
+	default:
+		log.Printf("[%s] Unknown ticket button %q", e.GuildID, interaction.ID())
+		return response.Ephemeral("Something odd happened. It has been logged.")
+	}
+}
+
+// ticketOpen opens a private thread for whoever clicked the button, and pulls in the staff.
+func ticketOpen(state *state.State, kvs storage.KeyValueStore, e *gateway.InteractionCreateEvent) api.InteractionResponse {
+	exist, config, err := storage.GetTicketConfig(kvs, e.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to get ticket config: %s", e.GuildID, err)
+		return response.Ephemeral("An error occured, and has been logged.")
+	}
+	if !exist {
+		return response.Ephemeral("Tickets aren't set up here any more, sorry.")
+	}
+
+	opener := e.SenderID()
+	unlock, err := storage.LockTicketOpener(kvs, e.GuildID, opener)
+	if err != nil {
+		log.Printf("[%s] Failed to lock opening tickets for <@%s>: %s", e.GuildID, opener, err)
+		return response.Ephemeral("Your ticket is being opened already. Give it a moment.")
+	}
+	defer unlock()
+	exist, ticket, err := storage.OpenTicketFor(kvs, e.GuildID, opener)
+	if err != nil {
+		log.Printf("[%s] Failed to look for open tickets by <@%s>: %s", e.GuildID, opener, err)
+		return response.Ephemeral("An error occured, and has been logged.")
+	}
+	if exist {
+		return response.Ephemeral("You already have a ticket open in", ticket.ThreadID.Mention())
+	}
+
+	thread, err := state.StartThreadWithoutMessage(e.ChannelID, api.StartThreadData{
+		Name:                fmt.Sprintf("ticket-%s", e.Member.User.Username),
+		AutoArchiveDuration: discord.OneDayArchive,
+		Type:                discord.GuildPrivateThread,
+		Invitable:           false,
+		AuditLogReason:      api.AuditLogReason(fmt.Sprintf("Ticket opened by %s", opener)),
+	})
+	if err != nil {
+		log.Printf("[%s] Failed to start a ticket thread in <#%s>: %s", e.GuildID, e.ChannelID, err)
+		return response.Ephemeral("I couldn't open a thread for you. The error has been logged.")
+	}
+	ticket = storage.Ticket{
+		ThreadID: thread.ID,
+		GuildID:  e.GuildID,
+		Opener:   opener,
+		Opened:   time.Now().Unix(),
+	}
+	if err := ticket.Store(kvs); err != nil {
+		log.Printf("[%s] Failed to store ticket %s: %s", e.GuildID, thread.ID, err)
+	}
+	storage.PublishTicketOpened(ticket)
+	if err := state.AddThreadMember(thread.ID, opener); err != nil {
+		log.Printf("[%s] Failed to add <@%s> to their ticket %s: %s", e.GuildID, opener, thread.ID, err)
+	}
+
+	// Mentioning the staff role is what pulls them into the private thread.
+	_, err = state.SendMessageComplex(thread.ID, api.SendMessageData{
+		Content: fmt.Sprintf("%s opened a ticket. %s will be with you shortly.", opener.Mention(), config.StaffRoleID.Mention()),
+		Components: discord.ContainerComponents{
+			&discord.ActionRowComponent{
+				&discord.ButtonComponent{
+					Style:    discord.DangerButtonStyle(),
+					CustomID: component.ID("ticket", "close"),
+					Label:    "Close ticket",
+				},
+			},
+		},
+		AllowedMentions: &api.AllowedMentions{
+			Parse: []api.AllowedMentionType{},
+			Roles: []discord.RoleID{config.StaffRoleID},
+			Users: []discord.UserID{opener},
+		},
+	})
+	if err != nil {
+		log.Printf("[%s] Failed to post the welcome in ticket %s: %s", e.GuildID, thread.ID, err)
+	}
+	log.Printf("[%s] <@%s> opened ticket %s", e.GuildID, opener, thread.ID)
+	return response.Ephemeral("Your ticket is open in", thread.ID.Mention())
+}
+
+// ticketClose posts the transcript of the ticket in the current channel to the log, and archives the thread.
+func ticketClose(state *state.State, kvs storage.KeyValueStore, e *gateway.InteractionCreateEvent, reason string) api.InteractionResponse {
+	exist, ticket, err := storage.GetTicket(kvs, e.GuildID, e.ChannelID)
+	if err != nil {
+		log.Printf("[%s] Failed to get ticket in <#%s>: %s", e.GuildID, e.ChannelID, err)
+		return response.Ephemeral("An error occured, and has been logged.")
+	}
+	if !exist {
+		return response.Ephemeral("This isn't a ticket. Use it in the ticket thread you want to close.")
+	}
+	_, config, err := storage.GetTicketConfig(kvs, e.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to get ticket config: %s", e.GuildID, err)
+		return response.Ephemeral("An error occured, and has been logged.")
+	}
+
+	messages, err := state.Client.Messages(ticket.ThreadID, 0)
+	if err != nil {
+		log.Printf("[%s] Failed to get messages for the transcript of ticket %s: %s", e.GuildID, ticket.ThreadID, err)
+		return response.Ephemeral("I couldn't read the ticket to make a transcript, so it stays open. The error has been logged.")
+	}
+
+	closer := e.SenderID()
+	summary := fmt.Sprintf("Ticket by %s, opened <t:%d:f>, closed by %s.", ticket.Opener.Mention(), ticket.Opened, closer.Mention())
+	if reason != "" {
+		summary += "\nReason: " + reason
+	}
+	if config.LogChannelID.IsValid() {
+		_, err = state.SendMessageComplex(config.LogChannelID, api.SendMessageData{
+			Content: summary,
+			Files: []sendpart.File{{
+				Name:   fmt.Sprintf("ticket_%s_%s.txt", ticket.ThreadID, time.Now().Format("2006-01-02")),
+				Reader: ticketTranscript(messages),
+			}},
+			AllowedMentions: &api.AllowedMentions{Parse: []api.AllowedMentionType{}},
+		})
+		if err != nil {
+			log.Printf("[%s] Failed to post the transcript of ticket %s: %s", e.GuildID, ticket.ThreadID, err)
+			return response.Ephemeral("I couldn't post the transcript, so the ticket stays open. The error has been logged.")
+		}
+	}
+
+	if err := ticket.Delete(kvs); err != nil {
+		log.Printf("[%s] Failed to forget ticket %s: %s", e.GuildID, ticket.ThreadID, err)
+	}
+	log.Printf("[%s] <@%s> closed ticket %s", e.GuildID, closer, ticket.ThreadID)
+
+	// Archiving is left a moment, so the response saying the ticket is closed gets in first.
+	time.AfterFunc(3*time.Second, func() {
+		err := state.ModifyChannel(ticket.ThreadID, api.ModifyChannelData{
+			Archived:       option.True,
+			Locked:         option.True,
+			AuditLogReason: api.AuditLogReason("Ticket closed"),
+		})
+		if err != nil {
+			log.Printf("[%s] Failed to archive ticket %s: %s", ticket.GuildID, ticket.ThreadID, err)
+		}
+	})
+	return response.MessageNoMention(summary)
+}
+
+// ticketTranscript writes the messages out as plain text, oldest first.
+func ticketTranscript(messages []discord.Message) *bytes.Buffer {
+	var transcript bytes.Buffer
+	for i := len(messages) - 1; i >= 0; i-- {
+		message := messages[i]
+		fmt.Fprintf(&transcript, "[%s] %s#%s: %s\n",
+			message.Timestamp.Time().UTC().Format("2006-01-02 15:04:05"),
+			message.Author.Username, message.Author.Discriminator,
+			message.Content,
+		)
+		for _, attachment := range message.Attachments {
+			fmt.Fprintf(&transcript, "    Attachment: %s\n", attachment.URL)
+		}
+		for _, embed := range message.Embeds {
+			if embed.Title != "" || embed.Description != "" {
+				fmt.Fprintf(&transcript, "    Embed: %s %s\n", embed.Title, strings.ReplaceAll(embed.Description, "\n", " "))
+			}
+		}
+	}
+	return &transcript
+}
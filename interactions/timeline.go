@@ -0,0 +1,270 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/component"
+	"komainu/interactions/join"
+	"komainu/interactions/memberupdate"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+const timelinePageSize = 10
+
+var timelineModule = module.Basic{
+	ModuleName: "timeline",
+	CommandHandlers: map[string]command.Handler{
+		"timeline": commandTimelineObject,
+	},
+	ComponentHandlers: map[string]component.Handler{
+		"timeline": component.Handler{Code: ComponentTimeline},
+	},
+	Handlers: []module.EventHandler{
+		module.Hook("timeline source", func() { registerTimelineSource(timelineJoined) }),
+		module.Hook("timeline source", func() { registerTimelineSource(timelineSeen) }),
+		module.Hook("timeline source", func() { registerTimelineSource(timelineVotes) }),
+		module.Hook("timeline source", func() { registerTimelineSource(timelineRoles) }),
+		module.MemberUpdate(memberupdate.Handler{Code: MemberUpdateTimeline}),
+		module.Join(join.Handler{Code: JoinTimeline}),
+	},
+}
+
+var commandTimelineObject = command.Handler{
+	Description: "Show everything I know about someone, in chronological order",
+	Code:        CommandTimeline,
+	Options: []discord.CommandOption{
+		&discord.UserOption{
+			OptionName:  "user",
+			Description: "The user to look up",
+			Required:    true,
+		},
+	},
+}
+
+// timelineEntry is a single line in a user timeline.
+type timelineEntry struct {
+	When int64
+	Text string
+}
+
+// timelineSource looks up whatever one subsystem knows about a user, and returns it as timeline entries.
+type timelineSource func(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID, userID discord.UserID) ([]timelineEntry, error)
+
+var timelineSources = []timelineSource{}
+
+// registerTimelineSource adds a source of entries to the /timeline command.
+// Anything that keeps per-user records should register one of these in it's init()
+func registerTimelineSource(source timelineSource) {
+	timelineSources = append(timelineSources, source)
+}
+
+// CommandTimeline processes a command to show the timeline of a user.
+func CommandTimeline(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if cmd.Options == nil || len(cmd.Options) != 1 {
+		log.Printf("[%s] /timeline command structure is somehow nil or not a single element. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("Invalid command structure.")}
+	}
+	snowflake, err := cmd.Options[0].SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] Failed to get snowflake value for /timeline: %s\n", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	content, components := timelinePage(state, kvs, event.GuildID, discord.UserID(snowflake), 0)
+	return command.Response{Response: api.InteractionResponse{
+		Type: api.MessageInteractionWithSource,
+		Data: &api.InteractionResponseData{
+			Content:    option.NewNullableString(content),
+			Components: components,
+			Flags:      api.EphemeralResponse,
+			AllowedMentions: &api.AllowedMentions{
+				Parse: []api.AllowedMentionType{},
+			},
+		},
+	}}
+}
+
+// ComponentTimeline handles the page buttons on a timeline.
+func ComponentTimeline(ctx *command.Context, interaction discord.ComponentInteraction, params []string) api.InteractionResponse {
+	state, kvs, e := ctx.State, ctx.KVS, ctx.Event
+	// timeline/userID/page
+	if len(params) != 2 {
+		log.Printf("[%s] Malformed timeline component ID: %s", e.GuildID, interaction.ID())
+		return response.Ephemeral("That button is broken somehow. It has been logged.")
+	}
+	userID, err := discord.ParseSnowflake(params[0])
+	if err != nil {
+		log.Printf("[%s] Malformed user ID in timeline component: %s", e.GuildID, err)
+		return response.Ephemeral("That button is broken somehow. It has been logged.")
+	}
+	page, err := strconv.Atoi(params[1])
+	if err != nil {
+		log.Printf("[%s] Malformed page number in timeline component: %s", e.GuildID, err)
+		return response.Ephemeral("That button is broken somehow. It has been logged.")
+	}
+	content, components := timelinePage(state, kvs, e.GuildID, discord.UserID(userID), page)
+	return api.InteractionResponse{
+		Type: api.UpdateMessage,
+		Data: &api.InteractionResponseData{
+			Content:    option.NewNullableString(content),
+			Components: components,
+			AllowedMentions: &api.AllowedMentions{
+				Parse: []api.AllowedMentionType{},
+			},
+		},
+	}
+}
+
+// collectTimeline asks every registered source about the user, and returns the entries sorted oldest first.
+func collectTimeline(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID, userID discord.UserID) []timelineEntry {
+	entries := []timelineEntry{}
+	for _, source := range timelineSources {
+		found, err := source(state, kvs, guildID, userID)
+		if err != nil {
+			log.Printf("[%s] A timeline source failed for %s: %s", guildID, userID, err)
+			entries = append(entries, timelineEntry{Text: "(Some data could not be retrieved. The error was logged.)"})
+			continue
+		}
+		entries = append(entries, found...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].When < entries[j].When
+	})
+	return entries
+}
+
+// timelinePage renders the given page of a user's timeline, along with the buttons to flip through it.
+func timelinePage(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID, userID discord.UserID, page int) (string, *discord.ContainerComponents) {
+	entries := collectTimeline(state, kvs, guildID, userID)
+	pages := (len(entries) + timelinePageSize - 1) / timelinePageSize
+	if pages == 0 {
+		return fmt.Sprintf("I don't know anything at all about %s.", userID.Mention()), &discord.ContainerComponents{}
+	}
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**Timeline for %s** (page %d of %d)\n\n", userID.Mention(), page+1, pages)
+	end := (page + 1) * timelinePageSize
+	if end > len(entries) {
+		end = len(entries)
+	}
+	for _, entry := range entries[page*timelinePageSize : end] {
+		if entry.When == 0 {
+			fmt.Fprintf(&sb, "- %s\n", entry.Text)
+		} else {
+			fmt.Fprintf(&sb, "<t:%d:d> %s\n", entry.When, entry.Text)
+		}
+	}
+
+	if pages == 1 {
+		return sb.String(), &discord.ContainerComponents{}
+	}
+	return sb.String(), &discord.ContainerComponents{
+		&discord.ActionRowComponent{
+			&discord.ButtonComponent{
+				Style:    discord.SecondaryButtonStyle(),
+				CustomID: component.ID("timeline", userID.String(), strconv.Itoa(page-1)),
+				Label:    "Previous",
+				Disabled: page == 0,
+			},
+			&discord.ButtonComponent{
+				Style:    discord.SecondaryButtonStyle(),
+				CustomID: component.ID("timeline", userID.String(), strconv.Itoa(page+1)),
+				Label:    "Next",
+				Disabled: page == pages-1,
+			},
+		},
+	}
+}
+
+func timelineJoined(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID, userID discord.UserID) ([]timelineEntry, error) {
+	member, err := state.Member(guildID, userID)
+	if err != nil {
+		// Not being a member isn't an error. They might have left, and the timeline is still interesting.
+		return []timelineEntry{{Text: "Is not currently a member of this server."}}, nil
+	}
+	return []timelineEntry{{When: member.Joined.Time().Unix(), Text: "Joined the server"}}, nil
+}
+
+func timelineSeen(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID, userID discord.UserID) ([]timelineEntry, error) {
+	seen, when, err := storage.LastSeen(kvs, guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !seen {
+		return []timelineEntry{{Text: "Has never been seen saying anything."}}, nil
+	}
+	return []timelineEntry{{When: when, Text: fmt.Sprintf("Last seen saying something (<t:%d:R>)", when)}}, nil
+}
+
+func timelineVotes(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID, userID discord.UserID) ([]timelineEntry, error) {
+	keys, err := kvs.Keys(guildID, "votes")
+	if err != nil {
+		return nil, err
+	}
+	entries := []timelineEntry{}
+	for _, key := range keys {
+		vote := storage.Vote{}
+		exist, err := kvs.Get(guildID, "votes", key, &vote)
+		if err != nil {
+			return nil, err
+		}
+		if !exist {
+			continue
+		}
+		if _, voted := vote.Votes[userID]; voted {
+			entries = append(entries, timelineEntry{
+				When: vote.StartTime,
+				Text: fmt.Sprintf("Voted in https://discord.com/channels/%s/%s/%s", guildID, vote.ChannelID, vote.MessageID),
+			})
+		}
+	}
+	return entries, nil
+}
+
+// MemberUpdateTimeline records the roles people get and lose, for their timeline.
+func MemberUpdateTimeline(state *state.State, kvs storage.KeyValueStore, event *gateway.GuildMemberUpdateEvent) {
+	if _, err := storage.RecordRoles(kvs, event.GuildID, event.User.ID, event.RoleIDs); err != nil {
+		log.Printf("[%s] Failed to record the roles of <@%s>: %s", event.GuildID, event.User.ID, err)
+	}
+}
+
+// JoinTimeline notes the roles people have when they arrive, so the first change after that is recorded too.
+func JoinTimeline(state *state.State, kvs storage.KeyValueStore, event *gateway.GuildMemberAddEvent) {
+	if err := storage.NoteRoles(kvs, event.GuildID, event.User.ID, event.RoleIDs); err != nil {
+		log.Printf("[%s] Failed to note the roles of <@%s>: %s", event.GuildID, event.User.ID, err)
+	}
+}
+
+func timelineRoles(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID, userID discord.UserID) ([]timelineEntry, error) {
+	changes, err := storage.GetRoleChanges(kvs, guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	entries := []timelineEntry{}
+	for _, change := range changes {
+		text := "Lost " + change.RoleID.Mention()
+		if change.Added {
+			text = "Got " + change.RoleID.Mention()
+		}
+		entries = append(entries, timelineEntry{When: change.When, Text: text})
+	}
+	return entries, nil
+}
@@ -0,0 +1,93 @@
+package timer
+
+import (
+	"komainu/storage"
+	"log"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+type Handler struct {
+	Code HandlerFunction
+}
+
+type HandlerFunction func(
+	state *state.State,
+	kvs storage.KeyValueStore,
+	timer storage.Timer,
+)
+
+var timerHandlers = map[string]Handler{}
+
+// lockTTL is how long the lock for firing timers in a guild is held at most, if whoever has it goes away.
+const lockTTL = 2 * time.Minute
+
+// Register sets what function should handle timers of the given kind when they are due.
+func Register(kind string, handler Handler) {
+	timerHandlers[kind] = handler
+}
+
+// Schedule stores a timer of the given kind, to fire at the given time.
+func Schedule(kvs storage.KeyValueStore, guildID discord.GuildID, kind string, when time.Time, data map[string]string) (storage.Timer, error) {
+	timer := storage.NewTimer(guildID, kind, when, data)
+	return timer, timer.Store(kvs)
+}
+
+// Start checks for due timers right away, to catch up on anything that was due while the bot was offline, and then every 15 seconds.
+// Intended to be called as a goroutine.
+func Start(state *state.State, kvs storage.KeyValueStore) {
+	fireDueTimers(state, kvs)
+	ticker := time.NewTicker(15 * time.Second)
+	for {
+		<-ticker.C
+		fireDueTimers(state, kvs)
+	}
+}
+
+func fireDueTimers(state *state.State, kvs storage.KeyValueStore) {
+	guilds, err := state.Guilds()
+	if err != nil {
+		log.Printf("Firing timers could not fetch current guilds: %s", err)
+		return
+	}
+	now := time.Now()
+	for _, guild := range guilds {
+		fireDueGuildTimers(state, kvs, guild.ID, now)
+	}
+}
+
+// fireDueGuildTimers fires the due timers in one guild. When several instances of the bot share storage, only the one
+// that gets the lock does it, so nothing fires twice.
+func fireDueGuildTimers(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID, now time.Time) {
+	unlock, acquired, err := kvs.Lock(guildID, "timers", lockTTL)
+	if err != nil {
+		log.Printf("[%s] Firing timers could not take the lock: %s", guildID, err)
+		return
+	}
+	if !acquired {
+		return // Someone else is on it.
+	}
+	defer unlock()
+	timers, err := storage.GetTimers(kvs, guildID)
+	if err != nil {
+		log.Printf("[%s] Firing timers could not get the list of timers: %s", guildID, err)
+		return
+	}
+	for _, timer := range timers {
+		if !timer.Due(now) {
+			continue
+		}
+		// Removing it first, so a handler that blows up doesn't get called again every 15 seconds forever.
+		if err := storage.CancelTimer(kvs, guildID, timer.ID); err != nil {
+			log.Printf("[%s] Could not remove due %s timer, not firing it: %s", guildID, timer.Kind, err)
+			continue
+		}
+		if handler, ok := timerHandlers[timer.Kind]; ok {
+			handler.Code(state, kvs, timer)
+		} else {
+			log.Printf("[%s] Got a due %q timer, but there is no registered handler!", guildID, timer.Kind)
+		}
+	}
+}
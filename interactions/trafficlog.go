@@ -5,6 +5,7 @@ import (
 	"komainu/interactions/command"
 	"komainu/interactions/join"
 	"komainu/interactions/leave"
+	"komainu/interactions/module"
 	"komainu/interactions/response"
 	"komainu/storage"
 	"log"
@@ -20,10 +21,15 @@ const (
 	trafficLogKey        = "channel"
 )
 
-func init() {
-	command.Register("trafficlog", commandTrafficLogObject)
-	join.Register(join.Handler{Code: joinLogging})
-	leave.Register(leave.Handler{Code: leaveLogging})
+var trafficLogModule = module.Basic{
+	ModuleName: "trafficlog",
+	CommandHandlers: map[string]command.Handler{
+		"trafficlog": commandTrafficLogObject,
+	},
+	Handlers: []module.EventHandler{
+		module.Join(join.Handler{Code: joinLogging}),
+		module.Leave(leave.Handler{Code: leaveLogging}),
+	},
 }
 
 var commandTrafficLogObject = command.Handler{
@@ -38,7 +44,8 @@ var commandTrafficLogObject = command.Handler{
 	},
 }
 
-func CommandTrafficLog(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, cmd *discord.CommandInteraction) command.Response {
+func CommandTrafficLog(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
 	if cmd.Options == nil || len(cmd.Options) != 1 {
 		log.Printf("[%s] <@%s> disabled traffic log functionality", event.GuildID, event.SenderID())
 		err := kvs.Delete(event.GuildID, trafficLogCollection, trafficLogKey)
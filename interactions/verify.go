@@ -0,0 +1,201 @@
+package interactions
+
+import (
+	"komainu/interactions/command"
+	"komainu/interactions/component"
+	"komainu/interactions/modal"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+var verifyModule = module.Basic{
+	ModuleName: "verify",
+	CommandHandlers: map[string]command.Handler{
+		"verify": commandVerifyObject,
+	},
+	ComponentHandlers: map[string]component.Handler{
+		"verify": component.Handler{Code: ComponentVerify},
+	},
+	Handlers: []module.EventHandler{
+		module.Modal("verify", modal.Handler{Code: VerifyModalHandler}),
+	},
+}
+
+var commandVerifyObject = command.Handler{
+	Description: "Make new members prove they are people before they get in",
+	Code:        CommandVerify,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "config",
+			Description: "Set up verification, and post the Verify button",
+			Options: []discord.CommandOptionValue{
+				&discord.RoleOption{
+					OptionName:  "role",
+					Description: "The role given once verified, which should be what lets people see the rest",
+					Required:    true,
+				},
+				&discord.ChannelOption{
+					OptionName:  "channel",
+					Description: "Where to post the Verify button, which should be all new members can see",
+					Required:    true,
+				},
+				&discord.StringOption{
+					OptionName:  "question",
+					Description: "What to ask. Default is a simple sum.",
+					Required:    false,
+				},
+				&discord.StringOption{
+					OptionName:  "answer",
+					Description: "The answer to the question. Capital letters don't matter.",
+					Required:    false,
+				},
+			},
+		},
+	},
+}
+
+// CommandVerify processes the /verify command.
+func CommandVerify(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 || cmd.Options[0].Name != "config" {
+		log.Printf("[%s] /verify command structure is somehow not a single config subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	options := cmd.Options[0].Options
+	roleSnowflake, err := options.Find("role").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /verify config failed to get role snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the role. It has been logged.")}
+	}
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /verify config failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	config := storage.VerifyConfig{
+		RoleID:    discord.RoleID(roleSnowflake),
+		ChannelID: discord.ChannelID(channelSnowflake),
+		Question:  strings.TrimSpace(options.Find("question").String()),
+		Answer:    strings.TrimSpace(options.Find("answer").String()),
+	}
+	if (config.Question == "") != (config.Answer == "") {
+		return command.Response{Response: response.Ephemeral("Give both a `question` and an `answer`, or neither to ask a simple sum.")}
+	}
+	if len([]rune(config.Question)) > 45 {
+		return command.Response{Response: response.Ephemeral("The question can't be longer than 45 characters, as that's all Discord shows.")}
+	}
+	if err := utility.CanManageRole(state, event.GuildID, event.Member, config.RoleID); err != nil {
+		return command.Response{Response: response.Ephemeral(hierarchyProblem(event.GuildID, err))}
+	}
+
+	_, old, err := storage.GetVerifyConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /verify config failed to get the old config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	message, err := state.SendMessageComplex(config.ChannelID, api.SendMessageData{
+		Content: "Welcome! Press the button to show you're a person, and you'll be let in.",
+		Components: discord.ContainerComponents{
+			&discord.ActionRowComponent{
+				&discord.ButtonComponent{
+					Style:    discord.SuccessButtonStyle(),
+					CustomID: discord.ComponentID("verify"),
+					Label:    "Verify",
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("[%s] /verify config failed to post the button in <#%s>: %s", event.GuildID, config.ChannelID, err)
+		return command.Response{Response: response.Ephemeral("I couldn't post the Verify button in " + config.ChannelID.Mention() + ". Can I send messages there?")}
+	}
+	config.MessageID = message.ID
+	if err := storage.SetVerifyConfig(kvs, event.GuildID, config); err != nil {
+		log.Printf("[%s] Failed to store verify config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if old.MessageID.IsValid() {
+		// There's only supposed to be the one button, and it's fine if someone already deleted the old one.
+		state.DeleteMessage(old.ChannelID, old.MessageID, "Replaced by a new Verify button")
+	}
+	log.Printf("[%s] <@%s> set up verification with <@&%s> in <#%s>", event.GuildID, event.SenderID(), config.RoleID, config.ChannelID)
+	return command.Response{Response: response.Ephemeral(ctx.T(
+		"The Verify button is up in %s, and gives %s. Make sure new members can only see that channel, and that %s can see the rest.",
+		config.ChannelID.Mention(), config.RoleID.Mention(), config.RoleID.Mention(),
+	))}
+}
+
+// ComponentVerify asks the challenge of whoever pressed the Verify button.
+func ComponentVerify(ctx *command.Context, interaction discord.ComponentInteraction, params []string) api.InteractionResponse {
+	kvs, e := ctx.KVS, ctx.Event
+	exist, config, err := storage.GetVerifyConfig(kvs, e.GuildID)
+	if err != nil {
+		log.Printf("[%s] Verify button failed to get config: %s", e.GuildID, err)
+		return response.Ephemeral("An error occured, and has been logged.")
+	}
+	if !exist {
+		return response.Ephemeral("Verification isn't set up here anymore.")
+	}
+	if e.Member != nil && utility.ContainsRole(e.Member.RoleIDs, config.RoleID) {
+		return response.Ephemeral("You're already verified!")
+	}
+	question := config.Question
+	session := map[string]string{}
+	if question == "" {
+		a, b := rand.Intn(10)+1, rand.Intn(10)+1
+		question = ctx.T("What is %d plus %d?", a, b)
+		session["answer"] = strconv.Itoa(a + b)
+	}
+	return modal.Respond(
+		kvs, e, "verify", "Verification", session,
+		discord.TextInputComponent{
+			CustomID:     discord.ComponentID("answer"),
+			Label:        question,
+			Style:        discord.TextInputShortStyle,
+			LengthLimits: [2]int{1, 100},
+		},
+	)
+}
+
+// VerifyModalHandler checks the answer, and gives the role if it's right.
+func VerifyModalHandler(ctx *command.Context, interaction *discord.ModalInteraction, session map[string]string) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	exist, config, err := storage.GetVerifyConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Verify modal failed to get config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral("Verification isn't set up here anymore.")}
+	}
+	expected := config.Answer
+	if config.Question == "" {
+		expected = session["answer"]
+	}
+	given := strings.TrimSpace(modal.DecodeModalResponse(interaction.Components)["answer"])
+	if expected == "" || !strings.EqualFold(given, expected) {
+		log.Printf("[%s] <@%s> failed verification", event.GuildID, event.SenderID())
+		return command.Response{Response: response.Ephemeral("That's not right. Press Verify to try again.")}
+	}
+	if err := utility.CanManageRole(state, event.GuildID, nil, config.RoleID); err != nil {
+		log.Printf("[%s] <@%s> passed verification, but the verified role <@&%s> is out of reach: %s", event.GuildID, event.SenderID(), config.RoleID, err)
+		return command.Response{Response: response.Ephemeral("That's right, but I couldn't let you in, as the role is out of my reach. Let the staff know, so they can sort it out.")}
+	}
+	err = state.AddRole(event.GuildID, event.SenderID(), config.RoleID, api.AddRoleData{AuditLogReason: "Passed verification"})
+	if err != nil {
+		log.Printf("[%s] Failed to give <@%s> the verified role <@&%s>: %s", event.GuildID, event.SenderID(), config.RoleID, err)
+		return command.Response{Response: response.Ephemeral("That's right, but I couldn't let you in. The error has been logged, so someone will sort it out.")}
+	}
+	log.Printf("[%s] <@%s> passed verification", event.GuildID, event.SenderID())
+	storage.PublishMemberVerified(event.GuildID, event.SenderID(), config.RoleID)
+	return command.Response{Response: response.Ephemeral("That's right! Welcome in.")}
+}
@@ -0,0 +1,35 @@
+package voice
+
+import (
+	"komainu/storage"
+
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+type Handler struct {
+	Code HandlerFunction
+}
+
+type HandlerFunction func(
+	state *state.State,
+	kvs storage.KeyValueStore,
+	event *gateway.VoiceStateUpdateEvent,
+)
+
+var voicehandlers = []Handler{}
+
+// Register makes the Code turn over when someone joins, leaves or moves between voice channels
+func Register(handler Handler) {
+	voicehandlers = append(voicehandlers, handler)
+}
+
+// Add the voice handler to the given state
+// This is mostly just pointless abstraction for uniformity across events.
+func AddHandler(state *state.State, kvs storage.KeyValueStore) {
+	state.AddHandler(func(event *gateway.VoiceStateUpdateEvent) {
+		for _, handler := range voicehandlers {
+			handler.Code(state, kvs, event)
+		}
+	})
+}
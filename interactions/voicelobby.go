@@ -0,0 +1,214 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/interactions/voice"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+const voiceLobbyDefaultName = "{user}'s channel"
+
+var voiceLobbyModule = module.Basic{
+	ModuleName: "voicelobby",
+	CommandHandlers: map[string]command.Handler{
+		"voicelobby": commandVoiceLobbyObject,
+	},
+	Handlers: []module.EventHandler{
+		module.Voice(voice.Handler{Code: VoiceLobbies}),
+	},
+}
+
+var commandVoiceLobbyObject = command.Handler{
+	Description: "Let people make their own voice channels, which go away when they're empty",
+	Code:        CommandVoiceLobby,
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "config",
+			Description: "Set up voice lobbies",
+			Options: []discord.CommandOptionValue{
+				&discord.ChannelOption{
+					OptionName:   "channel",
+					Description:  "The voice channel people join to make their own",
+					Required:     true,
+					ChannelTypes: []discord.ChannelType{discord.GuildVoice},
+				},
+				&discord.ChannelOption{
+					OptionName:   "category",
+					Description:  "The category the new channels go in",
+					Required:     true,
+					ChannelTypes: []discord.ChannelType{discord.GuildCategory},
+				},
+				&discord.StringOption{
+					OptionName:  "name",
+					Description: "What to call the new channels. {user} and {number} are filled in.",
+					Required:    false,
+				},
+				&discord.IntegerOption{
+					OptionName:  "limit",
+					Description: "How many people fit in a new channel. Default is no limit.",
+					Required:    false,
+					Min:         option.NewInt(0),
+					Max:         option.NewInt(99),
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "disable",
+			Description: "Stop making voice lobbies",
+			Options:     []discord.CommandOptionValue{},
+		},
+	},
+}
+
+// CommandVoiceLobby processes the /voicelobby command, dispatching to the right subcommand.
+func CommandVoiceLobby(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /voicelobby command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	switch cmd.Options[0].Name {
+	case "config":
+		return SubCommandVoiceLobbyConfig(kvs, event, cmd.Options[0].Options)
+	case "disable":
+		if err := storage.DisableVoiceLobbies(kvs, event.GuildID); err != nil {
+			log.Printf("[%s] Failed to disable voice lobbies: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		log.Printf("[%s] <@%s> disabled voice lobbies", event.GuildID, event.SenderID())
+		return command.Response{Response: response.Ephemeral("Okay, no more voice lobbies. The ones already made go away when they're empty.")}
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandVoiceLobbyConfig sets up voice lobbies.
+func SubCommandVoiceLobbyConfig(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	channelSnowflake, err := options.Find("channel").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /voicelobby config failed to get channel snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	categorySnowflake, err := options.Find("category").SnowflakeValue()
+	if err != nil {
+		log.Printf("[%s] /voicelobby config failed to get category snowflake: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+	}
+	config := storage.VoiceLobbyConfig{
+		ChannelID:  discord.ChannelID(channelSnowflake),
+		CategoryID: discord.ChannelID(categorySnowflake),
+		Name:       strings.TrimSpace(options.Find("name").String()),
+	}
+	if config.Name == "" {
+		config.Name = voiceLobbyDefaultName
+	}
+	if err := utility.ValidateTemplate(config.Name, storage.VoiceLobbyPlaceholders...); err != nil {
+		return command.Response{Response: response.Ephemeral("That name won't work: " + err.Error())}
+	}
+	if opt := options.Find("limit"); opt.Name != "" {
+		limit, err := opt.IntValue()
+		if err != nil || limit < 0 || limit > 99 {
+			return command.Response{Response: response.Ephemeral("The limit has to be a whole number, up to 99.")}
+		}
+		config.UserLimit = int(limit)
+	}
+
+	if err := storage.SetVoiceLobbyConfig(kvs, event.GuildID, config); err != nil {
+		log.Printf("[%s] Failed to store voice lobby config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> set up voice lobbies from <#%s> in <#%s>", event.GuildID, event.SenderID(), config.ChannelID, config.CategoryID)
+	return command.Response{Response: response.Ephemeral(fmt.Sprintf("Joining %s now makes a new voice channel in %s, owned by whoever joined. It goes away once it's empty. I need the *Manage Channels* and *Move Members* permissions for this.", config.ChannelID.Mention(), config.CategoryID.Mention()))}
+}
+
+// VoiceLobbies makes a lobby for whoever joins the lobby channel, and deletes the lobbies nobody is in anymore.
+func VoiceLobbies(state *state.State, kvs storage.KeyValueStore, event *gateway.VoiceStateUpdateEvent) {
+	if !event.GuildID.IsValid() {
+		return
+	}
+	exist, config, err := storage.GetVoiceLobbyConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Voice lobbies failed to get config: %s", event.GuildID, err)
+		return
+	}
+	if exist && event.ChannelID == config.ChannelID && event.Member != nil && !event.Member.User.Bot {
+		createVoiceLobby(state, kvs, event, config)
+	}
+	if err := storage.CleanVoiceLobbies(state, kvs, event.GuildID); err != nil {
+		log.Printf("[%s] Voice lobbies failed to clean up: %s", event.GuildID, err)
+	}
+}
+
+// createVoiceLobby makes a voice channel owned by whoever joined the lobby channel, and moves them into it.
+func createVoiceLobby(state *state.State, kvs storage.KeyValueStore, event *gateway.VoiceStateUpdateEvent, config storage.VoiceLobbyConfig) {
+	lobbies, err := storage.GetVoiceLobbies(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Voice lobbies failed to get the lobbies: %s", event.GuildID, err)
+		return
+	}
+	displayName := event.Member.Nick
+	if displayName == "" {
+		displayName = event.Member.User.Username
+	}
+	serverName := event.GuildID.String()
+	if guild, err := state.Guild(event.GuildID); err == nil {
+		serverName = guild.Name
+	}
+	name := strings.TrimSpace(utility.RenderTemplate(config.Name, utility.TemplateValues{
+		User:   displayName,
+		Server: serverName,
+		Extra:  map[string]string{"number": strconv.Itoa(len(lobbies) + 1)},
+	}))
+	if len([]rune(name)) < 2 {
+		name = utility.RenderTemplate(voiceLobbyDefaultName, utility.TemplateValues{User: displayName})
+	}
+
+	channel, err := state.CreateChannel(event.GuildID, api.CreateChannelData{
+		Name:           utility.Substring(name, 0, 100),
+		Type:           discord.GuildVoice,
+		VoiceUserLimit: uint(config.UserLimit),
+		CategoryID:     config.CategoryID,
+		Overwrites: []discord.Overwrite{{
+			ID:    discord.Snowflake(event.UserID),
+			Type:  discord.OverwriteMember,
+			Allow: discord.PermissionConnect | discord.PermissionManageChannels | discord.PermissionMoveMembers,
+		}},
+		AuditLogReason: api.AuditLogReason("Voice lobby for " + event.Member.User.Tag()),
+	})
+	if err != nil {
+		log.Printf("[%s] Voice lobbies failed to make a channel for <@%s>: %s", event.GuildID, event.UserID, err)
+		return
+	}
+	lobby := storage.VoiceLobby{ChannelID: channel.ID, OwnerID: event.UserID, Created: time.Now().Unix()}
+	if err := storage.AddVoiceLobby(kvs, event.GuildID, lobby); err != nil {
+		log.Printf("[%s] Voice lobbies failed to store lobby <#%s>: %s", event.GuildID, channel.ID, err)
+	}
+	err = state.ModifyMember(event.GuildID, event.UserID, api.ModifyMemberData{VoiceChannel: channel.ID})
+	if err != nil {
+		// They probably left already, so there's nobody to have it.
+		log.Printf("[%s] Voice lobbies failed to move <@%s> to <#%s>: %s", event.GuildID, event.UserID, channel.ID, err)
+		if err := state.DeleteChannel(channel.ID, api.AuditLogReason("Voice lobby owner never arrived")); err != nil {
+			log.Printf("[%s] Voice lobbies failed to delete unused lobby <#%s>: %s", event.GuildID, channel.ID, err)
+			return
+		}
+		if err := storage.ForgetVoiceLobby(kvs, event.GuildID, channel.ID); err != nil {
+			log.Printf("[%s] Voice lobbies failed to forget unused lobby <#%s>: %s", event.GuildID, channel.ID, err)
+		}
+		return
+	}
+	log.Printf("[%s] <@%s> got voice lobby <#%s>", event.GuildID, event.UserID, channel.ID)
+}
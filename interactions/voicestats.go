@@ -0,0 +1,139 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/interactions/voice"
+	"komainu/storage"
+	"log"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// voiceLeaderboardSize is how many people /voiceleaderboard shows.
+const voiceLeaderboardSize = 10
+
+var voiceStatsModule = module.Basic{
+	ModuleName: "voicestats",
+	CommandHandlers: map[string]command.Handler{
+		"voicestats":       commandVoiceStatsObject,
+		"voiceleaderboard": commandVoiceLeaderboardObject,
+	},
+	Handlers: []module.EventHandler{
+		module.Voice(voice.Handler{Code: VoiceStats}),
+	},
+}
+
+var commandVoiceStatsObject = command.Handler{
+	Description: "See how much time you've spent in voice, or someone else has",
+	Code:        CommandVoiceStats,
+	Public:      true,
+	Options: []discord.CommandOption{
+		&discord.UserOption{
+			OptionName:  "user",
+			Description: "Whose time to show. Default is you.",
+			Required:    false,
+		},
+	},
+}
+
+var commandVoiceLeaderboardObject = command.Handler{
+	Description: "See who has spent the most time in voice",
+	Code:        CommandVoiceLeaderboard,
+	Public:      true,
+	Options:     []discord.CommandOption{},
+}
+
+// voiceDuration makes a number of seconds readable, down to the minute.
+func voiceDuration(seconds int64) string {
+	hours, minutes := seconds/3600, seconds%3600/60
+	if hours == 0 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh %dm", hours, minutes)
+}
+
+// CommandVoiceStats processes the /voicestats command.
+func CommandVoiceStats(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	userID := event.SenderID()
+	if cmd.Options.Find("user").Name != "" {
+		userSnowflake, err := cmd.Options.Find("user").SnowflakeValue()
+		if err != nil {
+			log.Printf("[%s] /voicestats failed to get user snowflake: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("There was an issue figuring out the user. It has been logged.")}
+		}
+		userID = discord.UserID(userSnowflake)
+	}
+	board, err := storage.VoiceLeaderboard(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /voicestats failed to get the leaderboard: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	for place, voiceTime := range board {
+		if voiceTime.UserID != userID {
+			continue
+		}
+		return command.Response{Response: response.MessageNoMention(fmt.Sprintf(
+			"%s has spent **%s** in voice over %d visits, #%d of %d. Last time was <t:%d:R>.",
+			userID.Mention(), voiceDuration(voiceTime.Seconds), voiceTime.Sessions, place+1, len(board), voiceTime.Last,
+		))}
+	}
+	return command.Response{Response: response.MessageNoMention(fmt.Sprintf("%s hasn't spent any time in voice yet.", userID.Mention()))}
+}
+
+// CommandVoiceLeaderboard processes the /voiceleaderboard command.
+func CommandVoiceLeaderboard(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	board, err := storage.VoiceLeaderboard(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /voiceleaderboard failed to get the leaderboard: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(board) == 0 {
+		return command.Response{Response: response.Ephemeral("Nobody has spent any time in voice yet.")}
+	}
+	if len(board) > voiceLeaderboardSize {
+		board = board[:voiceLeaderboardSize]
+	}
+	lines := make([]string, len(board))
+	for place, voiceTime := range board {
+		lines[place] = fmt.Sprintf("**#%d** %s: %s", place+1, voiceTime.UserID.Mention(), voiceDuration(voiceTime.Seconds))
+	}
+	return command.Response{Response: response.MessageNoMention(strings.Join(lines, "\n"))}
+}
+
+// VoiceStats starts and stops counting voice time as people join and leave voice channels.
+// Joining voice counts as being seen.
+func VoiceStats(state *state.State, kvs storage.KeyValueStore, event *gateway.VoiceStateUpdateEvent) {
+	if !event.GuildID.IsValid() {
+		return
+	}
+	guild, err := state.Guild(event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Voice stats failed to get the guild: %s", event.GuildID, err)
+		return
+	}
+	if !storage.CountsAsVoice(*guild, event.VoiceState) {
+		if err := storage.LeaveVoice(kvs, event.GuildID, event.UserID); err != nil {
+			log.Printf("[%s] Voice stats failed to stop counting <@%s>: %s", event.GuildID, event.UserID, err)
+		}
+		return
+	}
+	if err := storage.JoinVoice(kvs, event.GuildID, event.UserID, event.ChannelID); err != nil {
+		log.Printf("[%s] Voice stats failed to start counting <@%s>: %s", event.GuildID, event.UserID, err)
+		return
+	}
+	if err := storage.See(kvs, event.GuildID, event.UserID); err != nil {
+		log.Printf("[%s] Error seeing %s in voice: %s\n", event.GuildID, event.UserID, err)
+		return
+	}
+	if err := storage.MaybeGiveActiveRole(kvs, state, event.GuildID, event.Member); err != nil {
+		log.Printf("[%s] Failed to give active role to %s: %s\n", event.GuildID, event.UserID, err)
+	}
+}
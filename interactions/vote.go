@@ -1,17 +1,26 @@
 package interactions
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"komainu/interactions/command"
 	"komainu/interactions/component"
+	"komainu/interactions/confirm"
 	"komainu/interactions/delete"
+	"komainu/interactions/guildcreate"
 	"komainu/interactions/modal"
+	"komainu/interactions/module"
 	"komainu/interactions/response"
+	"komainu/interactions/timer"
+	"komainu/locale"
 	"komainu/storage"
+	"komainu/utility"
 	"log"
+	"math/rand"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/diamondburned/arikawa/v3/api"
@@ -21,23 +30,262 @@ import (
 	"github.com/diamondburned/arikawa/v3/utils/json/option"
 )
 
-func init() {
-	command.Register("vote", commandVoteObject)
-	component.Register("vote", component.Handler{Code: ComponentVote})
-	delete.Register(delete.Handler{Code: DeleteVote})
-	modal.Register("votestart", modal.Handler{Code: VoteModalHandler})
+var voteModule = module.Basic{
+	ModuleName: "vote",
+	CommandHandlers: map[string]command.Handler{
+		"vote": commandVoteObject,
+	},
+	ComponentHandlers: map[string]component.Handler{
+		"vote":               component.Handler{Code: ComponentVote},
+		"votecomment":        component.Handler{Code: ComponentVoteComment},
+		storage.VoteExportID: component.Handler{Code: ComponentVoteExport},
+	},
+	Handlers: []module.EventHandler{
+		module.Delete(delete.Handler{Code: DeleteVote}),
+		module.GuildCreate(guildcreate.Handler{Code: GuildCreateVote}),
+		module.Modal("votestart", modal.Handler{Code: VoteModalHandler}),
+		module.Modal("votetemplate", modal.Handler{Code: VoteTemplateModalHandler}),
+		module.Modal("votecomment", modal.Handler{Code: VoteCommentModalHandler}),
+		module.Timer("voteremind", timer.Handler{Code: TimerVoteRemind}),
+		module.Confirm("votecancel", confirm.Handler{Code: ConfirmVoteCancel}),
+	},
+}
+
+// voteMaxReminders is how many reminders a single vote can have.
+const voteMaxReminders = 5
+
+// voteSettingOptions are the options for how a vote runs, shared by starting one and saving a template.
+func voteSettingOptions() []discord.CommandOptionValue {
+	return []discord.CommandOptionValue{
+		&discord.StringOption{
+			OptionName:  "length",
+			Description: "How long, like 36h or 3d12h, or when it ends, like 2024-07-01 18:00. Default is one day.",
+			Required:    false,
+		},
+		&discord.ChannelOption{
+			OptionName:  "unlock_channel",
+			Description: "If the vote passes, open this channel",
+			Required:    false,
+		},
+		&discord.RoleOption{
+			OptionName:  "unlock_role",
+			Description: "Who to open the channel for. Default is everyone.",
+			Required:    false,
+		},
+		&discord.StringOption{
+			OptionName:  "revert_unlock",
+			Description: "If the vote passes, revert this earlier channel unlock",
+			Required:    false,
+		},
+		&discord.IntegerOption{
+			OptionName:  "pass",
+			Description: "Which option, counting from 1, has to win for the vote to pass. Default is 1.",
+			Required:    false,
+			Min:         option.NewInt(1),
+			Max:         option.NewInt(25),
+		},
+		&discord.StringOption{
+			OptionName:  "quorum",
+			Description: "How many votes it takes for the vote to count, like 10, or 25% of the members",
+			Required:    false,
+		},
+		&discord.BooleanOption{
+			OptionName:  "abstain",
+			Description: "Let voters abstain. It counts towards the quorum, but not for any option.",
+			Required:    false,
+		},
+		&discord.BooleanOption{
+			OptionName:  "buttons",
+			Description: "Vote with a button per option, rather than a menu. Only for 5 options or fewer.",
+			Required:    false,
+		},
+	}
+}
+
+// voteEndLayouts are the ways an explicit end time for a vote can be written.
+var voteEndLayouts = []string{"2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"}
+
+// voteMaxLength is as long as a vote can run.
+const voteMaxLength = 365 * 24 * time.Hour
+
+// parseVoteEnd figures out when a vote should end from its length. That's either a plain number of days, like "1.5", an
+// amount of time, like "36h" or "3d12h", or an end time, like "2024-07-01 18:00", in the given time zone. Blank is one day.
+// If an end time was given, rather than an amount of time, absolute is true. If it won't work, problem says why, in the language.
+func parseVoteEnd(language discord.Language, text string, now time.Time, location *time.Location) (end time.Time, absolute bool, problem string) {
+	text = strings.TrimSpace(text)
+	switch {
+	case text == "":
+		end = now.Add(24 * time.Hour)
+	default:
+		if days, err := strconv.ParseFloat(text, 64); err == nil {
+			end = now.Add(time.Duration(days * float64(24*time.Hour)))
+			break
+		}
+		if length, err := utility.ParseDuration(text); err == nil {
+			end = now.Add(length)
+			break
+		}
+		for _, layout := range voteEndLayouts {
+			if parsed, err := time.ParseInLocation(layout, text, location); err == nil {
+				end, absolute = parsed, true
+				break
+			}
+		}
+		if !absolute {
+			return end, false, locale.T(language, "I don't understand %q as a length. Give an amount of time, like `36h` or `3d12h`, or when the vote ends, like `2024-07-01 18:00`.", text)
+		}
+	}
+	if !end.After(now) {
+		return end, absolute, locale.T(language, "The vote would be over before it started, as %s has already passed.", utility.FormatLocal(end, location))
+	}
+	if end.Sub(now) > voteMaxLength {
+		return end, absolute, "A vote can't run for longer than a year."
+	}
+	return end, absolute, ""
+}
+
+// parseVoteQuorum understands a quorum given as either a number of votes, like "10", or a percentage of the members, like "25%".
+func parseVoteQuorum(text string) (count int, percent float64, err error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, 0, nil
+	}
+	if strings.HasSuffix(text, "%") {
+		percent, err = strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(text, "%")), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return 0, 0, fmt.Errorf("invalid quorum percentage %q", text)
+		}
+		return 0, percent, nil
+	}
+	count, err = strconv.Atoi(text)
+	if err != nil || count < 0 {
+		return 0, 0, fmt.Errorf("invalid quorum %q", text)
+	}
+	return count, 0, nil
 }
 
 var commandVoteObject = command.Handler{
-	Description: "Initiate a vote",
+	Description: "Manage votes",
 	Code:        CommandVote,
 	Options: []discord.CommandOption{
-		&discord.NumberOption{
-			OptionName:  "length",
-			Description: "The number of days the vote should run.",
-			Required:    true,
-			Min:         option.NewFloat(0),
-			Max:         option.NewFloat(365),
+		&discord.SubcommandOption{
+			OptionName:  "start",
+			Description: "Initiate a vote",
+			Options:     voteSettingOptions(),
+		},
+		&discord.SubcommandOption{
+			OptionName:  "revert",
+			Description: "Put a channel back the way it was before a vote unlocked it",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "unlock",
+					Description: "The ID of the unlock, as given when the vote passed",
+					Required:    true,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "remind",
+			Description: "Remind people to vote before a vote closes, or stop the reminders",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "vote",
+					Description: "The message ID of the vote",
+					Required:    true,
+				},
+				&discord.StringOption{
+					OptionName:  "hours",
+					Description: "How many hours before it closes to remind, like 24, 1. Blank stops the reminders.",
+					Required:    false,
+				},
+				&discord.RoleOption{
+					OptionName:  "role",
+					Description: "Who to ping. Default is nobody, just a message in the channel.",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "list",
+			Description: "List the votes that are still open",
+			Options:     []discord.CommandOptionValue{},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "results",
+			Description: "See the results and comments of a vote you started",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "vote",
+					Description: "The message ID of the vote",
+					Required:    true,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "refresh",
+			Description: "Update vote messages with the current standings, and make them work again if they don't",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "vote",
+					Description: "The message ID of the vote. Blank refreshes every open vote.",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "cancel",
+			Description: "Close a vote early, without results",
+			Options: []discord.CommandOptionValue{
+				&discord.StringOption{
+					OptionName:  "vote",
+					Description: "The message ID of the vote",
+					Required:    true,
+				},
+			},
+		},
+		&discord.SubcommandGroupOption{
+			OptionName:  "template",
+			Description: "Save votes to run again later",
+			Subcommands: []*discord.SubcommandOption{
+				{
+					OptionName:  "save",
+					Description: "Save a vote as a template, or change one",
+					Options: append([]discord.CommandOptionValue{
+						&discord.StringOption{
+							OptionName:  "name",
+							Description: "What to call the template",
+							Required:    true,
+						},
+					}, voteSettingOptions()...),
+				},
+				{
+					OptionName:  "run",
+					Description: "Start a vote from a template",
+					Options: []discord.CommandOptionValue{
+						&discord.StringOption{
+							OptionName:  "name",
+							Description: "The template to run",
+							Required:    true,
+						},
+					},
+				},
+				{
+					OptionName:  "list",
+					Description: "List the saved templates",
+					Options:     []discord.CommandOptionValue{},
+				},
+				{
+					OptionName:  "delete",
+					Description: "Delete a template",
+					Options: []discord.CommandOptionValue{
+						&discord.StringOption{
+							OptionName:  "name",
+							Description: "The template to delete",
+							Required:    true,
+						},
+					},
+				},
+			},
 		},
 	},
 }
@@ -54,12 +302,26 @@ func DeleteVote(state *state.State, kvs storage.KeyValueStore, e *gateway.Messag
 }
 
 // ComponentVote attempts to handle the given interaction as a vote
-func ComponentVote(state *state.State, kvs storage.KeyValueStore, e *gateway.InteractionCreateEvent, interaction discord.ComponentInteraction) api.InteractionResponse {
-	isVote, resp, err := handleInteractionAsVote(state, kvs, e, interaction)
+func ComponentVote(ctx *command.Context, interaction discord.ComponentInteraction, params []string) api.InteractionResponse {
+	state, kvs, e := ctx.State, ctx.KVS, ctx.Event
+	isVote, registered, resp, err := handleInteractionAsVote(state, kvs, e, interaction, params)
 	if err != nil {
 		log.Printf("[%s] error while trying to handle an interaction as a vote: %s\n", e.GuildID, err)
 		return response.Ephemeral("Something went wrong. It was logged, so hopefully it'll get fixed.")
 	}
+	if isVote && registered {
+		reply := response.Ephemeral(resp)
+		reply.Data.Components = &discord.ContainerComponents{
+			&discord.ActionRowComponent{
+				&discord.ButtonComponent{
+					Style:    discord.SecondaryButtonStyle(),
+					CustomID: component.ID("votecomment", e.Message.ID.String()),
+					Label:    "Add a comment",
+				},
+			},
+		}
+		return reply
+	}
 	if isVote && resp != "" {
 		return response.Ephemeral(resp)
 	}
@@ -67,44 +329,697 @@ func ComponentVote(state *state.State, kvs storage.KeyValueStore, e *gateway.Int
 	return response.Ephemeral("I'm sorry, but I can't find the poll you are trying to vote on?!")
 }
 
-// CommandVote processes a command to start a vote
-func CommandVote(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, cmd *discord.CommandInteraction) command.Response {
-	if cmd.Options != nil && len(cmd.Options) > 1 {
-		log.Printf("[%s] /vote command structure is somehow nil or not the correct number of elements. Wat.\n", event.GuildID)
-		return command.Response{Response: response.Ephemeral("Yeah, no, that didn't work."), Callback: nil}
+// CommandVote processes the /vote command and it's subcommands
+func CommandVote(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if cmd.Options == nil || len(cmd.Options) != 1 {
+		log.Printf("[%s] /vote command structure is somehow nil or not a single element. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("Yeah, no, that didn't work.")}
+	}
+	if cmd.Options[0].Type == discord.SubcommandOptionType {
+		switch cmd.Options[0].Name {
+		case "start":
+			return SubCommandVoteStart(kvs, event, cmd.Options[0].Options)
+		case "revert":
+			return SubCommandVoteRevert(state, kvs, event, cmd.Options[0].Options)
+		case "remind":
+			return SubCommandVoteRemind(kvs, event, cmd.Options[0].Options)
+		case "list":
+			return SubCommandVoteList(kvs, event)
+		case "refresh":
+			return SubCommandVoteRefresh(state, kvs, event, cmd.Options[0].Options)
+		case "cancel":
+			return SubCommandVoteCancel(state, kvs, event, cmd.Options[0].Options)
+		case "results":
+			return SubCommandVoteResults(kvs, event, cmd.Options[0].Options)
+		default:
+			return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+		}
+	}
+	group := cmd.Options[0]
+	if len(group.Options) != 1 {
+		log.Printf("[%s] /vote %s command structure is somehow not a single subcommand. Wat.\n", event.GuildID, group.Name)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
 	}
+	sub := group.Options[0]
+	switch group.Name + " " + sub.Name {
+	case "template save":
+		return SubCommandVoteTemplateSave(kvs, event, sub.Options)
+	case "template run":
+		return SubCommandVoteTemplateRun(state, kvs, event, sub.Options)
+	case "template list":
+		return SubCommandVoteTemplateList(kvs, event)
+	case "template delete":
+		return SubCommandVoteTemplateDelete(kvs, event, sub.Options)
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+}
+
+// SubCommandVoteStart processes a subcommand to start a vote
+func SubCommandVoteStart(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	return voteModal(kvs, event, options, "votestart", "Call a vote!", blankVote())
+}
+
+// blankVote is what the vote modal is filled in with when there's nothing else to go on.
+func blankVote() storage.Vote {
+	return storage.Vote{Order: []string{"yes", "no"}, Options: map[string]string{"yes": "Yes", "no": "No"}}
+}
 
-	days, err := cmd.Options[0].FloatValue()
+// voteModal asks for the description and options of a vote, with the settings from the options kept in the modal session.
+// The modal starts out filled in with what's in prefill. Any extra inputs are added at the end.
+func voteModal(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions, handler string, title string, prefill storage.Vote, extra ...discord.TextInputComponent) command.Response {
+	location, err := storage.GetTimezone(kvs, event.GuildID)
 	if err != nil {
-		log.Printf("[%s] /vote command structure is somehow weird. Could not get the Float value of the days option.\n", event.GuildID)
-		return command.Response{Response: response.Ephemeral("Wait, what? How many hours? Try again."), Callback: nil}
+		log.Printf("[%s] /vote failed to get the time zone, going with UTC: %s", event.GuildID, err)
+	}
+	now := time.Now()
+	end, absolute, problem := parseVoteEnd(locale.ForGuild(kvs, event.GuildID), options.Find("length").String(), now, location)
+	if problem != "" {
+		return command.Response{Response: response.Ephemeral(problem)}
+	}
+	// Amounts of time start counting when the modal is sent, while end times stay put.
+	length := fmt.Sprintf("+%d", int64(end.Sub(now).Seconds()))
+	if absolute {
+		length = fmt.Sprintf("@%d", end.Unix())
+	}
+
+	outcome, problem := voteOutcomeFromOptions(kvs, event, options)
+	if problem != "" {
+		return command.Response{Response: response.Ephemeral(problem)}
+	}
+	quorum := strings.ReplaceAll(options.Find("quorum").String(), " ", "")
+	if _, _, err := parseVoteQuorum(quorum); err != nil {
+		return command.Response{Response: response.Ephemeral("I don't understand the quorum. Give a number of votes, like `10`, or a percentage of the members, like `25%`.")}
+	}
+	abstain := false
+	if opt := options.Find("abstain"); opt.Name != "" {
+		var err error
+		if abstain, err = opt.BoolValue(); err != nil {
+			log.Printf("[%s] /vote command structure is somehow weird. Could not get the Bool value of the abstain option.\n", event.GuildID)
+			return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+		}
+	}
+	buttons := false
+	if opt := options.Find("buttons"); opt.Name != "" {
+		var err error
+		if buttons, err = opt.BoolValue(); err != nil {
+			log.Printf("[%s] /vote command structure is somehow weird. Could not get the Bool value of the buttons option.\n", event.GuildID)
+			return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+		}
+	}
+	settings := map[string]string{"length": length, "quorum": quorum, "abstain": strconv.FormatBool(abstain), "buttons": strconv.FormatBool(buttons), "outcome": outcome}
+
+	labels := make([]string, len(prefill.Order))
+	for i, key := range prefill.Order {
+		labels[i] = prefill.Options[key]
 	}
 
 	form := []discord.TextInputComponent{
 		{
-			CustomID:     discord.ComponentID(fmt.Sprintf("desc/%f", days)),
+			CustomID:     discord.ComponentID("description"),
 			Style:        discord.TextInputParagraphStyle,
 			Label:        "Description of the vote",
 			LengthLimits: [2]int{1, 500},
-			Value:        option.NewNullableString(""),
+			Value:        option.NewNullableString(prefill.Question),
 			Placeholder:  option.NewNullableString("Describe what everyone is supposed to be voting about."),
 		},
 		{
 			CustomID:    discord.ComponentID("options"),
 			Style:       discord.TextInputParagraphStyle,
 			Label:       "Options, 1/line, max 25, max 100 chars/line",
-			Value:       option.NewNullableString("Yes\nNo"),
+			Value:       option.NewNullableString(strings.Join(labels, "\n")),
 			Placeholder: &option.NullableStringData{},
 		},
 	}
+	form = append(form, extra...)
 
 	return command.Response{Response: modal.Respond(
-		event.SenderID(), event.GuildID, "votestart", "Call a vote!", form...,
+		kvs, event, handler, title, settings, form...,
 	), Callback: nil}
 }
 
-func VoteModalHandler(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, interaction *discord.ModalInteraction) command.Response {
-	vote := storage.Vote{
+// SubCommandVoteRevert processes a subcommand to revert a channel unlock a vote did.
+func SubCommandVoteRevert(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	unlockID, err := discord.ParseSnowflake(strings.TrimSpace(options.Find("unlock").String()))
+	if err != nil {
+		return command.Response{Response: response.Ephemeral("That doesn't look like an unlock ID to me.")}
+	}
+	exist, unlock, err := storage.RevertChannelUnlock(state, kvs, event.GuildID, discord.MessageID(unlockID))
+	if err != nil {
+		log.Printf("[%s] /vote revert failed: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("I couldn't revert that unlock. The error has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral("I don't know of any unlock with that ID. Maybe it was already reverted?")}
+	}
+	log.Printf("[%s] <@%s> reverted channel unlock %s", event.GuildID, event.SenderID(), unlock.ID)
+	return command.Response{Response: response.MessageNoMention(fmt.Sprintf("%s is back the way it was for %s.", unlock.ChannelID.Mention(), unlock.RoleID.Mention()))}
+}
+
+// SubCommandVoteRemind replaces the reminders for a vote with the ones given.
+func SubCommandVoteRemind(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	voteID, err := discord.ParseSnowflake(strings.TrimSpace(options.Find("vote").String()))
+	if err != nil {
+		return command.Response{Response: response.Ephemeral("That doesn't look like a message ID to me.")}
+	}
+	exist, vote, err := storage.GetVote(kvs, event.GuildID, discord.MessageID(voteID))
+	if err != nil {
+		log.Printf("[%s] /vote remind failed to get vote %s: %s", event.GuildID, voteID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist || vote.Closed {
+		return command.Response{Response: response.Ephemeral("I don't know of any open vote with that message ID.")}
+	}
+
+	var hours []float64
+	for _, field := range strings.Split(options.Find("hours").String(), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		h, err := strconv.ParseFloat(field, 64)
+		if err != nil || h <= 0 {
+			return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "I don't understand %q as a number of hours.", field))}
+		}
+		hours = append(hours, h)
+	}
+	if len(hours) > voteMaxReminders {
+		return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "That's a lot of reminders. A vote can have at most %d.", voteMaxReminders))}
+	}
+	var roleID discord.RoleID
+	if opt := options.Find("role"); opt.Name != "" {
+		roleSnowflake, err := opt.SnowflakeValue()
+		if err != nil {
+			log.Printf("[%s] /vote remind failed to get role snowflake: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("There was an issue figuring out the role. It has been logged.")}
+		}
+		roleID = discord.RoleID(roleSnowflake)
+	}
+
+	if err := cancelVoteReminders(kvs, event.GuildID, vote.MessageID); err != nil {
+		log.Printf("[%s] /vote remind failed to cancel old reminders for %s: %s", event.GuildID, vote.MessageID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(hours) == 0 {
+		log.Printf("[%s] <@%s> stopped the reminders for vote %s", event.GuildID, event.SenderID(), vote.MessageID)
+		return command.Response{Response: response.Ephemeral("There will be no reminders for that vote.")}
+	}
+
+	end := time.Unix(vote.EndTime, 0)
+	scheduled := []string{}
+	for _, h := range hours {
+		when := end.Add(-time.Duration(h * float64(time.Hour)))
+		if when.Before(time.Now()) {
+			continue
+		}
+		data := map[string]string{"vote": vote.MessageID.String()}
+		if roleID.IsValid() {
+			data["role"] = roleID.String()
+		}
+		if _, err := timer.Schedule(kvs, event.GuildID, "voteremind", when, data); err != nil {
+			log.Printf("[%s] /vote remind failed to schedule a reminder for %s: %s", event.GuildID, vote.MessageID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		scheduled = append(scheduled, utility.Timestamp(when.Unix(), utility.ShortDateTime))
+	}
+	if len(scheduled) == 0 {
+		return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "The vote closes %s, so all of those reminders would be in the past.", utility.Timestamp(vote.EndTime, utility.Relative)))}
+	}
+	log.Printf("[%s] <@%s> set %d reminders for vote %s", event.GuildID, event.SenderID(), len(scheduled), vote.MessageID)
+	return command.Response{Response: response.Ephemeral("There will be reminders to vote at " + strings.Join(scheduled, ", ") + ".")}
+}
+
+// SubCommandVoteList lists the open votes, with links to them.
+func SubCommandVoteList(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	votes, err := storage.GetOpenVotes(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /vote list failed to get votes: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(votes) == 0 {
+		return command.Response{Response: response.Ephemeral("There are no open votes right now.")}
+	}
+	lines := make([]string, len(votes))
+	for i, vote := range votes {
+		question := strings.SplitN(vote.Question, "\n", 2)[0]
+		if runes := []rune(question); len(runes) > 80 {
+			question = string(runes[:80]) + "…"
+		}
+		link := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", vote.GuildID, vote.ChannelID, vote.MessageID)
+		lines[i] = fmt.Sprintf("%s closes %s, %d votes so far. %s\nID: `%s`", question, utility.Timestamp(vote.EndTime, utility.Relative), len(vote.Votes), link, vote.MessageID)
+	}
+	return command.Response{Response: response.Ephemeral(strings.Join(lines, "\n\n"))}
+}
+
+// refreshVote edits the vote message to show the current standings, with what it's voted on with made anew.
+func refreshVote(state *state.State, vote *storage.Vote) error {
+	_, err := state.EditMessageComplex(vote.ChannelID, vote.MessageID, api.EditMessageData{
+		Content:    option.NewNullableString(vote.String()),
+		Components: voteComponents(vote),
+		AllowedMentions: &api.AllowedMentions{
+			Parse: []api.AllowedMentionType{},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("refreshing vote %s: %w", vote.MessageID, err)
+	}
+	return nil
+}
+
+// refreshOpenVotes refreshes every open vote in the guild, and returns how many were refreshed, and how many failed.
+// The failures are logged, and don't stop the rest from being refreshed. Votes that have ended are left for
+// CloseExpiredVotes, which will be along shortly.
+func refreshOpenVotes(state *state.State, kvs storage.KeyValueStore, guildID discord.GuildID) (refreshed int, failed int, err error) {
+	votes, err := storage.GetOpenVotes(kvs, guildID)
+	if err != nil {
+		return 0, 0, err
+	}
+	now := time.Now().Unix()
+	for i := range votes {
+		if votes[i].EndTime <= now {
+			continue
+		}
+		if err := refreshVote(state, &votes[i]); err != nil {
+			log.Printf("[%s] Failed to refresh a vote: %s", guildID, err)
+			failed++
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, failed, nil
+}
+
+// SubCommandVoteRefresh updates the vote message, or all the open ones, with the current standings.
+func SubCommandVoteRefresh(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	text := strings.TrimSpace(options.Find("vote").String())
+	if text == "" {
+		// There could be quite a few, and each is an edit, so this might take a while.
+		return command.DeferredData(state, event, true, func() api.EditInteractionResponseData {
+			refreshed, failed, err := refreshOpenVotes(state, kvs, event.GuildID)
+			content := locale.T(locale.ForGuild(kvs, event.GuildID), "Refreshed %d open votes.", refreshed)
+			if err != nil {
+				log.Printf("[%s] /vote refresh failed to get the open votes: %s", event.GuildID, err)
+				content = "An error occured, and has been logged."
+			} else if failed > 0 {
+				content = locale.T(locale.ForGuild(kvs, event.GuildID), "Refreshed %d open votes, but %d couldn't be. The errors have been logged.", refreshed, failed)
+			}
+			log.Printf("[%s] <@%s> refreshed %d open votes", event.GuildID, event.SenderID(), refreshed)
+			return api.EditInteractionResponseData{Content: option.NewNullableString(content)}
+		})
+	}
+	voteID, err := discord.ParseSnowflake(text)
+	if err != nil {
+		return command.Response{Response: response.Ephemeral("That doesn't look like a message ID to me.")}
+	}
+	exist, vote, err := storage.GetVote(kvs, event.GuildID, discord.MessageID(voteID))
+	if err != nil {
+		log.Printf("[%s] /vote refresh failed to get vote %s: %s", event.GuildID, voteID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist || vote.Closed {
+		return command.Response{Response: response.Ephemeral("I don't know of any open vote with that message ID.")}
+	}
+	if vote.EndTime <= time.Now().Unix() {
+		return command.Response{Response: response.Ephemeral("That vote has ended, and will be closed with the results in a minute or so.")}
+	}
+	if err := refreshVote(state, vote); err != nil {
+		log.Printf("[%s] /vote refresh failed: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("I couldn't update the vote message. The error has been logged.")}
+	}
+	log.Printf("[%s] <@%s> refreshed vote %s", event.GuildID, event.SenderID(), vote.MessageID)
+	return command.Response{Response: response.Ephemeral("The vote message is up to date, and can be voted on.")}
+}
+
+// votesRefreshed holds the guilds that have had their open votes refreshed since the bot started.
+var votesRefreshed = map[discord.GuildID]bool{}
+var votesRefreshedLock sync.Mutex
+
+// GuildCreateVote refreshes the open votes in the guild, the first time it's seen after the bot starts, so votes cast
+// on them work even if the bot was offline long enough for something to go wrong with the messages.
+func GuildCreateVote(state *state.State, kvs storage.KeyValueStore, e *gateway.GuildCreateEvent) {
+	votesRefreshedLock.Lock()
+	done := votesRefreshed[e.ID]
+	votesRefreshed[e.ID] = true
+	votesRefreshedLock.Unlock()
+	if done {
+		return
+	}
+	go func() {
+		refreshed, failed, err := refreshOpenVotes(state, kvs, e.ID)
+		if err != nil {
+			log.Printf("[%s] Failed to get the open votes to refresh on startup: %s", e.ID, err)
+			return
+		}
+		if refreshed > 0 || failed > 0 {
+			log.Printf("[%s] Refreshed %d open votes on startup, and failed to refresh %d", e.ID, refreshed, failed)
+		}
+	}()
+}
+
+// SubCommandVoteCancel closes a vote early. Only the one that started it, or an administrator, can do that.
+func SubCommandVoteCancel(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	voteID, err := discord.ParseSnowflake(strings.TrimSpace(options.Find("vote").String()))
+	if err != nil {
+		return command.Response{Response: response.Ephemeral("That doesn't look like a message ID to me.")}
+	}
+	exist, vote, err := storage.GetVote(kvs, event.GuildID, discord.MessageID(voteID))
+	if err != nil {
+		log.Printf("[%s] /vote cancel failed to get vote %s: %s", event.GuildID, voteID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist || vote.Closed {
+		return command.Response{Response: response.Ephemeral("I don't know of any open vote with that message ID.")}
+	}
+	if vote.Creator != event.SenderID() {
+		permissions, err := state.Permissions(event.ChannelID, event.SenderID())
+		if err != nil {
+			log.Printf("[%s] /vote cancel failed to get permissions of <@%s>: %s", event.GuildID, event.SenderID(), err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		if !permissions.Has(discord.PermissionAdministrator) {
+			return command.Response{Response: response.Ephemeral("Only the one that started the vote, or an administrator, can cancel it.")}
+		}
+	}
+	question := locale.T(locale.ForGuild(kvs, event.GuildID), "Really cancel the vote on %q? Nobody's votes will count, and it can't be undone.", vote.Question)
+	return command.Response{Response: confirm.Ask(kvs, event, "votecancel", question, map[string]string{"vote": vote.MessageID.String()})}
+}
+
+// ConfirmVoteCancel cancels a vote, once the cancellation is confirmed.
+func ConfirmVoteCancel(ctx *command.Context, confirmation storage.Confirmation) string {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	voteID, err := discord.ParseSnowflake(confirmation.Data["vote"])
+	if err != nil {
+		log.Printf("[%s] Vote cancel confirmation has a weird vote: %s", event.GuildID, err)
+		return "I'm sorry, what? Something very weird happened."
+	}
+	unlock, err := storage.LockVote(kvs, event.GuildID, discord.MessageID(voteID))
+	if err != nil {
+		log.Printf("[%s] /vote cancel failed to lock vote %s: %s", event.GuildID, voteID, err)
+		return "An error occured, and has been logged."
+	}
+	defer unlock()
+	exist, vote, err := storage.GetVote(kvs, event.GuildID, discord.MessageID(voteID))
+	if err != nil {
+		log.Printf("[%s] /vote cancel failed to get vote %s: %s", event.GuildID, voteID, err)
+		return "An error occured, and has been logged."
+	}
+	if !exist || vote.Closed {
+		return "That vote is already closed."
+	}
+	if err := vote.Cancel(state, kvs); err != nil {
+		log.Printf("[%s] /vote cancel failed: %s", event.GuildID, err)
+		return "I couldn't cancel that vote. The error has been logged."
+	}
+	if err := cancelVoteReminders(kvs, event.GuildID, vote.MessageID); err != nil {
+		log.Printf("[%s] /vote cancel failed to cancel reminders for %s: %s", event.GuildID, vote.MessageID, err)
+	}
+	log.Printf("[%s] <@%s> cancelled vote %s", event.GuildID, event.SenderID(), vote.MessageID)
+	return "The vote is cancelled."
+}
+
+// SubCommandVoteResults shows the one that started a vote how it's going, and what people commented.
+// The comments are shuffled about, so the order doesn't give away who wrote what.
+func SubCommandVoteResults(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	voteID, err := discord.ParseSnowflake(strings.TrimSpace(options.Find("vote").String()))
+	if err != nil {
+		return command.Response{Response: response.Ephemeral("That doesn't look like a message ID to me.")}
+	}
+	exist, vote, err := storage.GetVote(kvs, event.GuildID, discord.MessageID(voteID))
+	if err != nil {
+		log.Printf("[%s] /vote results failed to get vote %s: %s", event.GuildID, voteID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral("I don't know of any vote with that message ID.")}
+	}
+	if vote.Creator != event.SenderID() {
+		return command.Response{Response: response.Ephemeral("Only the one that started the vote can see the results this way.")}
+	}
+	var sb strings.Builder
+	sb.WriteString(vote.String())
+	comments := make([]string, 0, len(vote.Comments))
+	for _, comment := range vote.Comments {
+		comments = append(comments, comment)
+	}
+	rand.Shuffle(len(comments), func(i, j int) {
+		comments[i], comments[j] = comments[j], comments[i]
+	})
+	if len(comments) == 0 {
+		sb.WriteString("\nNobody has commented.")
+	} else {
+		fmt.Fprintf(&sb, "\n**Comments** (%d)\n", len(comments))
+	}
+	for i, comment := range comments {
+		line := "> " + strings.ReplaceAll(comment, "\n", "\n> ") + "\n"
+		if sb.Len()+len(line) > 1900 {
+			fmt.Fprintf(&sb, "...and %d more that don't fit.", len(comments)-i)
+			break
+		}
+		sb.WriteString(line)
+	}
+	return command.Response{Response: response.Ephemeral(sb.String())}
+}
+
+// ComponentVoteExport sends the one that started the vote the results as a CSV file.
+func ComponentVoteExport(ctx *command.Context, interaction discord.ComponentInteraction, params []string) api.InteractionResponse {
+	kvs, e := ctx.KVS, ctx.Event
+	exist, vote, err := storage.GetVote(kvs, e.GuildID, e.Message.ID)
+	if err != nil {
+		log.Printf("[%s] Vote export failed to get vote %s: %s", e.GuildID, e.Message.ID, err)
+		return response.Ephemeral("An error occured, and has been logged.")
+	}
+	if !exist {
+		return response.Ephemeral("I don't have the results of that vote anymore.")
+	}
+	if vote.Creator != e.SenderID() {
+		return response.Ephemeral("Only the one that started the vote can export the results.")
+	}
+	data, err := vote.CSV()
+	if err != nil {
+		log.Printf("[%s] Vote export failed for %s: %s", e.GuildID, vote.MessageID, err)
+		return response.Ephemeral("An error occured, and has been logged.")
+	}
+	return response.EphemeralAttachFile("Here are the results.", fmt.Sprintf("vote-%s.csv", vote.MessageID), bytes.NewReader(data))
+}
+
+// ComponentVoteComment asks the voter for a comment on the vote, filled in with what they commented before.
+func ComponentVoteComment(ctx *command.Context, interaction discord.ComponentInteraction, params []string) api.InteractionResponse {
+	kvs, e := ctx.KVS, ctx.Event
+	voteID := ""
+	if len(params) == 1 {
+		voteID = params[0]
+	}
+	snowflake, err := discord.ParseSnowflake(voteID)
+	if err != nil {
+		log.Printf("[%s] Vote comment button has a weird vote: %s", e.GuildID, err)
+		return response.Ephemeral("I'm sorry, what? Something very weird happened.")
+	}
+	exist, vote, err := storage.GetVote(kvs, e.GuildID, discord.MessageID(snowflake))
+	if err != nil {
+		log.Printf("[%s] Vote comment button failed to get vote %s: %s", e.GuildID, voteID, err)
+		return response.Ephemeral("An error occured, and has been logged.")
+	}
+	if !exist || vote.Closed {
+		return response.Ephemeral("I'm sorry, that vote is closed!")
+	}
+	return modal.Respond(
+		kvs, e, "votecomment", "Comment on the vote", map[string]string{"vote": voteID},
+		discord.TextInputComponent{
+			CustomID:     discord.ComponentID("comment"),
+			Label:        "Only the one that started the vote sees this",
+			Style:        discord.TextInputParagraphStyle,
+			Required:     false,
+			LengthLimits: [2]int{0, 1000},
+			Value:        option.NewNullableString(vote.Comments[e.SenderID()]),
+		},
+	)
+}
+
+// VoteCommentModalHandler stores the comment on the vote. A blank comment removes it.
+func VoteCommentModalHandler(ctx *command.Context, interaction *discord.ModalInteraction, session map[string]string) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	value, ok := modal.DecodeModalResponse(interaction.Components)["comment"]
+	if !ok {
+		log.Printf("[%s] Vote comment modal had no comment in it", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	snowflake, err := discord.ParseSnowflake(session["vote"])
+	if err != nil {
+		log.Printf("[%s] Vote comment modal has a weird vote: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	unlock, err := storage.LockVote(kvs, event.GuildID, discord.MessageID(snowflake))
+	if err != nil {
+		log.Printf("[%s] Vote comment modal failed to lock vote %s: %s", event.GuildID, snowflake, err)
+		return command.Response{Response: response.Ephemeral("Lots of people are voting right now. Please try again in a moment.")}
+	}
+	defer unlock()
+	exist, vote, err := storage.GetVote(kvs, event.GuildID, discord.MessageID(snowflake))
+	if err != nil {
+		log.Printf("[%s] Vote comment modal failed to get vote %s: %s", event.GuildID, snowflake, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist || vote.Closed {
+		return command.Response{Response: response.Ephemeral("I'm sorry, that vote is closed!")}
+	}
+	value = strings.TrimSpace(value)
+	vote.SetComment(event.SenderID(), value)
+	if err := vote.Store(kvs); err != nil {
+		log.Printf("[%s] Failed to store comment on vote %s: %s", event.GuildID, snowflake, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if value == "" {
+		return command.Response{Response: response.Ephemeral("Your comment is removed.")}
+	}
+	return command.Response{Response: response.Ephemeral("Your comment is saved. Only the one that started the vote can see it, and not who wrote it.")}
+}
+
+// cancelVoteReminders cancels all the reminders for the given vote.
+func cancelVoteReminders(kvs storage.KeyValueStore, guildID discord.GuildID, voteID discord.MessageID) error {
+	timers, err := storage.GetTimers(kvs, guildID)
+	if err != nil {
+		return fmt.Errorf("could not get timers: %w", err)
+	}
+	for _, t := range timers {
+		if t.Kind != "voteremind" || t.Data["vote"] != voteID.String() {
+			continue
+		}
+		if err := storage.CancelTimer(kvs, guildID, t.ID); err != nil {
+			return fmt.Errorf("could not cancel reminder %s: %w", t.ID, err)
+		}
+	}
+	return nil
+}
+
+// TimerVoteRemind reminds the channel of a vote that it's still open, pinging the role if there is one.
+func TimerVoteRemind(state *state.State, kvs storage.KeyValueStore, t storage.Timer) {
+	voteID, err := discord.ParseSnowflake(t.Data["vote"])
+	if err != nil {
+		log.Printf("[%s] Vote reminder timer has a weird vote: %s", t.GuildID, err)
+		return
+	}
+	exist, vote, err := storage.GetVote(kvs, t.GuildID, discord.MessageID(voteID))
+	if err != nil {
+		log.Printf("[%s] Vote reminder failed to get vote %s: %s", t.GuildID, voteID, err)
+		return
+	}
+	if !exist || vote.Closed || vote.EndTime <= time.Now().Unix() {
+		return
+	}
+	content := fmt.Sprintf("Voting closes %s! If you haven't yet, have your say.", utility.Timestamp(vote.EndTime, utility.Relative))
+	mentions := &api.AllowedMentions{Parse: []api.AllowedMentionType{}}
+	if roleID, err := discord.ParseSnowflake(t.Data["role"]); err == nil {
+		content = discord.RoleID(roleID).Mention() + " " + content
+		mentions.Roles = []discord.RoleID{discord.RoleID(roleID)}
+	}
+	_, err = state.SendMessageComplex(vote.ChannelID, api.SendMessageData{
+		Content:         content,
+		Reference:       &discord.MessageReference{MessageID: vote.MessageID},
+		AllowedMentions: mentions,
+	})
+	if err != nil {
+		log.Printf("[%s] Failed to send reminder for vote %s: %s", t.GuildID, vote.MessageID, err)
+	}
+}
+
+// voteOutcomeFromOptions figures out what outcome, if any, the /vote start options ask for, and encodes it for the modal.
+// If there is something wrong with the options, problem is a message explaining what.
+func voteOutcomeFromOptions(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) (encoded string, problem string) {
+	pass := int64(1)
+	if opt := options.Find("pass"); opt.Name != "" {
+		var err error
+		if pass, err = opt.IntValue(); err != nil {
+			return "", "I don't understand which option has to win."
+		}
+	}
+
+	channelOpt := options.Find("unlock_channel")
+	revertOpt := options.Find("revert_unlock")
+	if channelOpt.Name != "" && revertOpt.Name != "" {
+		return "", "A vote can either unlock a channel or revert an unlock, not both at once."
+	}
+
+	if channelOpt.Name != "" {
+		channel, err := channelOpt.SnowflakeValue()
+		if err != nil {
+			return "", "I don't understand what channel to unlock."
+		}
+		role := discord.RoleID(event.GuildID) // The @everyone role has the same ID as the guild.
+		if roleOpt := options.Find("unlock_role"); roleOpt.Name != "" {
+			snowflake, err := roleOpt.SnowflakeValue()
+			if err != nil {
+				return "", "I don't understand what role to unlock the channel for."
+			}
+			role = discord.RoleID(snowflake)
+		}
+		return fmt.Sprintf("%s:%s:%s:%d", storage.OutcomeUnlock, channel, role, pass-1), ""
+	}
+
+	if revertOpt.Name != "" {
+		unlockID, err := discord.ParseSnowflake(strings.TrimSpace(revertOpt.String()))
+		if err != nil {
+			return "", "That doesn't look like an unlock ID to me."
+		}
+		exist, _, err := storage.GetChannelUnlock(kvs, event.GuildID, discord.MessageID(unlockID))
+		if err != nil {
+			log.Printf("[%s] Failed to look up channel unlock for /vote start: %s", event.GuildID, err)
+			return "", "An error occured, and has been logged."
+		}
+		if !exist {
+			return "", "I don't know of any unlock with that ID. Maybe it was already reverted?"
+		}
+		return fmt.Sprintf("%s:%s:%d", storage.OutcomeRevert, unlockID, pass-1), ""
+	}
+
+	return "", ""
+}
+
+// decodeVoteOutcome turns what voteOutcomeFromOptions made back into an outcome.
+func decodeVoteOutcome(encoded string) (*storage.VoteOutcome, error) {
+	parts := strings.Split(encoded, ":")
+	outcome := storage.VoteOutcome{Action: parts[0]}
+	var pass string
+	switch {
+	case outcome.Action == storage.OutcomeUnlock && len(parts) == 4:
+		channel, err := discord.ParseSnowflake(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("decoding unlock channel: %w", err)
+		}
+		role, err := discord.ParseSnowflake(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("decoding unlock role: %w", err)
+		}
+		outcome.ChannelID = discord.ChannelID(channel)
+		outcome.RoleID = discord.RoleID(role)
+		pass = parts[3]
+	case outcome.Action == storage.OutcomeRevert && len(parts) == 3:
+		unlock, err := discord.ParseSnowflake(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("decoding unlock to revert: %w", err)
+		}
+		outcome.UnlockID = discord.MessageID(unlock)
+		pass = parts[2]
+	default:
+		return nil, fmt.Errorf("malformed vote outcome %q", encoded)
+	}
+	outcome.Pass = "vote/" + pass
+	return &outcome, nil
+}
+
+func VoteModalHandler(ctx *command.Context, interaction *discord.ModalInteraction, session map[string]string) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	vote, _, problem := voteFromModal(ctx.Language, event, interaction, session)
+	if problem != "" {
+		return command.Response{Response: response.Ephemeral(problem)}
+	}
+	return postVote(state, kvs, vote)
+}
+
+// voteFromModal makes a vote from what was filled in, and the settings kept in the modal session. If the modal was for
+// saving a template, the name of the template is also returned. If something is wrong, problem says what.
+func voteFromModal(language discord.Language, event *gateway.InteractionCreateEvent, interaction *discord.ModalInteraction, session map[string]string) (vote storage.Vote, templateName string, problem string) {
+	vote = storage.Vote{
 		StartTime: time.Now().Unix(),
 		EndTime:   0,
 		GuildID:   event.GuildID,
@@ -114,29 +1029,64 @@ func VoteModalHandler(state *state.State, kvs storage.KeyValueStore, event *gate
 		Options:   map[string]string{},
 		Order:     []string{},
 		Votes:     map[discord.UserID]string{},
+		Creator:   event.SenderID(),
 	}
 	data := modal.DecodeModalResponse(interaction.Components)
 	for key, value := range data {
-		if strings.HasPrefix(key, "desc/") {
-			if vote.Question != "" {
-				log.Printf("[%s] Duplicate Question in vote configuration.", event.GuildID)
-				return command.Response{Response: response.Ephemeral("There was a problem processing your vote configuration. It has been logged.")}
-			}
+		if key == "description" {
 			vote.Question = value
-			days, err := strconv.ParseFloat(strings.TrimPrefix(key, "desc/"), 64)
+			length := session["length"]
+			if length == "" {
+				log.Printf("[%s] Vote settings are missing from the modal session", event.GuildID)
+				return vote, "", "There was an error processing your vote configuration. It has been logged."
+			}
+			count, percent, err := parseVoteQuorum(session["quorum"])
+			if err != nil {
+				log.Printf("[%s] Error processing vote quorum: %s", event.GuildID, err)
+				return vote, "", "There was an error processing your vote configuration. It has been logged."
+			}
+			vote.QuorumCount, vote.QuorumPercent = count, percent
+			if vote.Abstain, err = strconv.ParseBool(session["abstain"]); err != nil {
+				log.Printf("[%s] Error processing vote abstain setting: %s", event.GuildID, err)
+				return vote, "", "There was an error processing your vote configuration. It has been logged."
+			}
+			if session["buttons"] != "" { // Modals opened before there were buttons don't have it.
+				if vote.Buttons, err = strconv.ParseBool(session["buttons"]); err != nil {
+					log.Printf("[%s] Error processing vote buttons setting: %s", event.GuildID, err)
+					return vote, "", "There was an error processing your vote configuration. It has been logged."
+				}
+			}
+			if session["outcome"] != "" {
+				outcome, err := decodeVoteOutcome(session["outcome"])
+				if err != nil {
+					log.Printf("[%s] Error processing vote outcome: %s", event.GuildID, err)
+					return vote, "", "There was an error processing your vote configuration. It has been logged."
+				}
+				vote.Outcome = outcome
+			}
+			seconds, err := strconv.ParseInt(strings.TrimLeft(length, "+@"), 10, 64)
 			if err != nil {
 				log.Printf("[%s] Error processing vote length: %s", event.GuildID, err)
-				return command.Response{Response: response.Ephemeral("There was an error processing your vote configuration. It has been logged.")}
+				return vote, "", "There was an error processing your vote configuration. It has been logged."
+			}
+			if strings.HasPrefix(length, "@") {
+				vote.EndTime = seconds
+				if vote.EndTime <= vote.StartTime {
+					return vote, "", locale.T(language, "The vote was supposed to end %s, so it's too late to start it now.", utility.Timestamp(vote.EndTime, utility.Relative))
+				}
+			} else {
+				vote.EndTime = vote.StartTime + seconds
 			}
-			vote.EndTime = vote.StartTime + int64(days*24*float64(3600)) // 24 hours per day, 3600 seconds per hour
+		} else if key == "template" {
+			templateName = strings.ToLower(strings.TrimSpace(value))
 		} else if key == "options" {
 			optionList := strings.Split(value, "\n")
 			for i, opt := range optionList {
 				if i > 24 {
 					break
 				}
-				if len(opt) > 100 {
-					opt = opt[0:100]
+				if runes := []rune(opt); len(runes) > 100 {
+					opt = string(runes[:100])
 				}
 				item := "vote/" + strconv.Itoa(i)
 				vote.Options[item] = opt
@@ -144,16 +1094,52 @@ func VoteModalHandler(state *state.State, kvs storage.KeyValueStore, event *gate
 			}
 		} else {
 			log.Printf("[%s] Unknown prefix while processing vote modal: %s", event.GuildID, key)
-			return command.Response{Response: response.Ephemeral("Something strange happened while processing your vote configuration. It has been logged.")}
+			return vote, "", "Something strange happened while processing your vote configuration. It has been logged."
+		}
+	}
+
+	if vote.Abstain && len(vote.Order) > 24 {
+		return vote, "", "With abstaining allowed, there can only be 24 options, as abstaining takes up the last spot."
+	}
+	if vote.Buttons && len(vote.Order) > storage.VoteMaxButtons {
+		return vote, "", locale.T(language, "There's only room for %d buttons, so a vote with %d options has to use the menu.", storage.VoteMaxButtons, len(vote.Order))
+	}
+	if vote.Buttons {
+		for _, key := range vote.Order {
+			if len([]rune(vote.Options[key])) > storage.VoteMaxButtonLabel {
+				return vote, "", locale.T(language, "Buttons can only say %d characters, so %q is too long for one. Shorten it, or use the menu.", storage.VoteMaxButtonLabel, vote.Options[key])
+			}
+		}
+	}
+	if vote.Outcome != nil {
+		if _, ok := vote.Options[vote.Outcome.Pass]; !ok {
+			return vote, "", "The option that has to win for the vote to pass isn't one of the options!"
 		}
 	}
 
+	return vote, templateName, ""
+}
+
+// postVote posts the vote as the response, and stores it once it's posted.
+// If the quorum is a percentage, this is when the members that could vote are counted.
+func postVote(state *state.State, kvs storage.KeyValueStore, vote storage.Vote) command.Response {
+	if vote.QuorumPercent > 0 {
+		guild, err := state.GuildWithCount(vote.GuildID)
+		if err != nil {
+			log.Printf("[%s] Failed to count the members for a vote quorum: %s", vote.GuildID, err)
+			return command.Response{Response: response.Ephemeral("I couldn't count the members to work out the quorum. The error has been logged.")}
+		}
+		vote.Eligible = int(guild.ApproximateMembers)
+	}
 	return command.Response{
 		Response: api.InteractionResponse{
 			Type: api.MessageInteractionWithSource,
 			Data: &api.InteractionResponseData{
 				Content:    option.NewNullableString(vote.String()),
-				Components: makeVoteSelector(&vote),
+				Components: voteComponents(&vote),
+				AllowedMentions: &api.AllowedMentions{
+					Parse: []api.AllowedMentionType{},
+				},
 			},
 		},
 		Callback: func(message *discord.Message) {
@@ -167,6 +1153,37 @@ func VoteModalHandler(state *state.State, kvs storage.KeyValueStore, event *gate
 	}
 }
 
+// voteComponents makes what the vote is voted on with, which is either a button per option, or a menu of them.
+func voteComponents(vote *storage.Vote) *discord.ContainerComponents {
+	if vote.Buttons {
+		return makeVoteButtons(vote)
+	}
+	return makeVoteSelector(vote)
+}
+
+// makeVoteButtons makes a row with a button per option, in order, and another for abstaining if that's allowed.
+func makeVoteButtons(vote *storage.Vote) *discord.ContainerComponents {
+	options := discord.ActionRowComponent{}
+	for _, key := range vote.Order {
+		options = append(options, &discord.ButtonComponent{
+			Style:    discord.PrimaryButtonStyle(),
+			CustomID: component.ID("vote", key),
+			Label:    vote.Options[key],
+		})
+	}
+	rows := discord.ContainerComponents{&options}
+	if label, ok := vote.Label(storage.VoteAbstain); ok {
+		rows = append(rows, &discord.ActionRowComponent{
+			&discord.ButtonComponent{
+				Style:    discord.SecondaryButtonStyle(),
+				CustomID: component.ID("vote", storage.VoteAbstain),
+				Label:    label,
+			},
+		})
+	}
+	return &rows
+}
+
 func makeVoteSelector(vote *storage.Vote) *discord.ContainerComponents {
 	var selectable []discord.SelectOption
 	for key, label := range vote.Options {
@@ -175,6 +1192,12 @@ func makeVoteSelector(vote *storage.Vote) *discord.ContainerComponents {
 			Value: key,
 		})
 	}
+	if label, ok := vote.Label(storage.VoteAbstain); ok {
+		selectable = append(selectable, discord.SelectOption{
+			Label: label,
+			Value: storage.VoteAbstain,
+		})
+	}
 	row := discord.ActionRowComponent([]discord.InteractiveComponent{
 		&discord.SelectComponent{
 			Options:     selectable,
@@ -186,44 +1209,167 @@ func makeVoteSelector(vote *storage.Vote) *discord.ContainerComponents {
 	return discord.ComponentsPtr(&row)
 }
 
-// handleInteractionAsVote determines if the given interaction is a vote button click, and acts accordingly.
-func handleInteractionAsVote(state *state.State, kvs storage.KeyValueStore, e *gateway.InteractionCreateEvent, interaction discord.ComponentInteraction) (isVote bool, response string, err error) {
+// handleInteractionAsVote determines if the given interaction is a pick from a vote menu or a click on a vote button,
+// and acts accordingly. Buttons have what they vote for in params. If the vote was registered, so is registered.
+func handleInteractionAsVote(state *state.State, kvs storage.KeyValueStore, e *gateway.InteractionCreateEvent, interaction discord.ComponentInteraction, params []string) (isVote bool, registered bool, response string, err error) {
+	unlock, err := storage.LockVote(kvs, e.GuildID, e.Message.ID)
+	if err != nil {
+		return true, false, "Something very odd happened.", fmt.Errorf("locking vote: %w", err)
+	}
+	defer unlock()
 	exist, vote, err := storage.GetVote(kvs, e.GuildID, e.Message.ID)
 	if err != nil {
-		return true, "Something very odd happened.", fmt.Errorf("handling interaction as vote: %w", err)
+		return true, false, "Something very odd happened.", fmt.Errorf("handling interaction as vote: %w", err)
 	}
 	if !exist {
-		return false, "", nil
+		return false, false, "", nil
 	}
 
 	now := time.Now().Unix()
 	if vote.EndTime <= now {
-		return true, "I'm sorry, that vote is closed!", nil
+		return true, false, "I'm sorry, that vote is closed!", nil
 	}
 
-	selector, ok := interaction.(*discord.SelectInteraction)
+	var voted string
+	switch picked := interaction.(type) {
+	case *discord.SelectInteraction:
+		if len(picked.Values) != 1 {
+			return true, false, "You must select exactly one item", fmt.Errorf("%d values selected in vote, expected 1", len(picked.Values))
+		}
+		voted = picked.Values[0]
+	case *discord.ButtonInteraction:
+		if len(params) != 1 {
+			return true, false, "That button is broken somehow.", fmt.Errorf("vote button has %d params, expected 1", len(params))
+		}
+		voted = params[0]
+	default:
+		return true, false, "Your response was not in the right format, somehow?!", errors.New("submitted vote was neither a SelectInteraction nor a ButtonInteraction")
+	}
 
+	label, ok := vote.Label(voted)
 	if !ok {
-		return true, "Your response was not in the right format, somehow?!", errors.New("submitted vote was not from a SelectInteraction")
+		return true, false, "Sorry, you can't vote for that.", fmt.Errorf("vote cast for %s, which is not an option", voted)
 	}
 
-	if len(selector.Values) != 1 {
-		return true, "You must select exactly one item", fmt.Errorf("%d values selected in vote, expected 1", len(selector.Values))
+	vote.Votes[e.SenderID()] = voted
+	if _, err := state.EditMessage(e.ChannelID, e.Message.ID, vote.String()); err != nil {
+		return true, false, "There was an error registering your vote.", fmt.Errorf("handling interaction as vote: %w", err)
 	}
+	if err := vote.Store(kvs); err != nil {
+		return true, false, "There was an error storing your vote.", fmt.Errorf("storing a vote: %w", err)
+	}
+	return true, true, locale.T(locale.ForGuild(kvs, e.GuildID), "Your vote for...\n%s\n...is registered.", label), nil
+}
 
-	voted := selector.Values[0]
+// SubCommandVoteTemplateSave asks for the description and options of a template, filled in with what it had if it exists.
+func SubCommandVoteTemplateSave(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	name := strings.ToLower(strings.TrimSpace(options.Find("name").String()))
+	if name == "" || len(name) > 50 {
+		return command.Response{Response: response.Ephemeral("Template names can't be blank, or longer than 50 characters.")}
+	}
+	exist, template, err := storage.GetVoteTemplate(kvs, event.GuildID, name)
+	if err != nil {
+		log.Printf("[%s] /vote template save failed to get template %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	prefill := blankVote()
+	if exist {
+		prefill = template.Vote(event.GuildID)
+	}
+	return voteModal(kvs, event, options, "votetemplate", "Save a vote template", prefill, discord.TextInputComponent{
+		CustomID:     discord.ComponentID("template"),
+		Style:        discord.TextInputShortStyle,
+		Label:        "Template name",
+		LengthLimits: [2]int{1, 50},
+		Value:        option.NewNullableString(name),
+	})
+}
 
-	label, ok := vote.Options[voted]
-	if !ok {
-		return true, "Sorry, you can't vote for that.", fmt.Errorf("vote cast for %s, which is not an option", voted)
+// VoteTemplateModalHandler saves the template once the description and options are filled in.
+func VoteTemplateModalHandler(ctx *command.Context, interaction *discord.ModalInteraction, session map[string]string) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	vote, name, problem := voteFromModal(ctx.Language, event, interaction, session)
+	if problem != "" {
+		return command.Response{Response: response.Ephemeral(problem)}
+	}
+	if name == "" {
+		return command.Response{Response: response.Ephemeral("The template needs a name.")}
 	}
+	template := storage.VoteTemplate{
+		Name:     name,
+		Question: vote.Question,
+		Order:    vote.Order,
+		Options:  vote.Options,
+		Length:   vote.EndTime - vote.StartTime,
+		Outcome:  vote.Outcome,
+		Creator:  event.SenderID(),
 
-	vote.Votes[e.SenderID()] = voted
-	if _, err := state.EditMessage(e.ChannelID, e.Message.ID, vote.String()); err != nil {
-		return true, "There was an error registering your vote.", fmt.Errorf("handling interaction as vote: %w", err)
+		QuorumCount:   vote.QuorumCount,
+		QuorumPercent: vote.QuorumPercent,
+		Abstain:       vote.Abstain,
+		Buttons:       vote.Buttons,
 	}
-	if err := vote.Store(kvs); err != nil {
-		return true, "There was an error storing your vote.", fmt.Errorf("storing a vote: %w", err)
+	if err := template.Store(kvs, event.GuildID); err != nil {
+		log.Printf("[%s] Failed to store vote template %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> saved the vote template %s", event.GuildID, event.SenderID(), name)
+	return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "Saved the %q template. Start a vote from it with `/vote template run name:%s`", name, name))}
+}
+
+// SubCommandVoteTemplateRun starts a vote from a template, without asking for anything.
+func SubCommandVoteTemplateRun(state *state.State, kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	name := strings.ToLower(strings.TrimSpace(options.Find("name").String()))
+	exist, template, err := storage.GetVoteTemplate(kvs, event.GuildID, name)
+	if err != nil {
+		log.Printf("[%s] /vote template run failed to get template %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "There is no template called %q.", name))}
+	}
+	log.Printf("[%s] <@%s> started a vote from the template %s", event.GuildID, event.SenderID(), name)
+	vote := template.Vote(event.GuildID)
+	vote.Creator = event.SenderID()
+	return postVote(state, kvs, vote)
+}
+
+// SubCommandVoteTemplateList lists the saved templates.
+func SubCommandVoteTemplateList(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent) command.Response {
+	templates, err := storage.GetVoteTemplates(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /vote template list failed to get templates: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(templates) == 0 {
+		return command.Response{Response: response.Ephemeral("There are no vote templates. Save one with `/vote template save`.")}
+	}
+	lines := make([]string, len(templates))
+	for i, template := range templates {
+		question := strings.SplitN(template.Question, "\n", 2)[0]
+		if runes := []rune(question); len(runes) > 80 {
+			question = string(runes[:80]) + "…"
+		}
+		lines[i] = fmt.Sprintf("**%s**: %s (%d options, runs for %s)", template.Name, question, len(template.Order), time.Duration(template.Length)*time.Second)
+	}
+	return command.Response{Response: response.Ephemeral(strings.Join(lines, "\n"))}
+}
+
+// SubCommandVoteTemplateDelete forgets a template.
+func SubCommandVoteTemplateDelete(kvs storage.KeyValueStore, event *gateway.InteractionCreateEvent, options discord.CommandInteractionOptions) command.Response {
+	name := strings.ToLower(strings.TrimSpace(options.Find("name").String()))
+	exist, _, err := storage.GetVoteTemplate(kvs, event.GuildID, name)
+	if err != nil {
+		log.Printf("[%s] /vote template delete failed to get template %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if !exist {
+		return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "There is no template called %q.", name))}
+	}
+	if err := storage.DeleteVoteTemplate(kvs, event.GuildID, name); err != nil {
+		log.Printf("[%s] Failed to delete vote template %s: %s", event.GuildID, name, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
 	}
-	return true, fmt.Sprintf("Your vote for...\n%s\n...is registered.", label), nil
+	log.Printf("[%s] <@%s> deleted the vote template %s", event.GuildID, event.SenderID(), name)
+	return command.Response{Response: response.Ephemeral(locale.T(locale.ForGuild(kvs, event.GuildID), "The %q template is gone.", name))}
 }
@@ -0,0 +1,299 @@
+package interactions
+
+import (
+	"komainu/interactions/command"
+	"komainu/interactions/component"
+	"komainu/interactions/modal"
+	"komainu/interactions/module"
+	"komainu/komainutest"
+	"komainu/storage"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+func TestMain(m *testing.M) {
+	module.Register(Modules()...)
+	if err := module.Load(storage.OpenMemory(), func(string) bool { return true }); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// connect makes a Discord with every kind of interaction handled, as it is when the bot runs.
+func connect(kvs storage.KeyValueStore) *komainutest.Discord {
+	d := komainutest.New()
+	command.AddHandler(d.State, kvs)
+	component.AddHandler(d.State, kvs)
+	modal.AddHandler(d.State, kvs)
+	return d
+}
+
+func TestVoteFlow(t *testing.T) {
+	kvs := storage.OpenMemory()
+	d := connect(kvs)
+
+	start := komainutest.Command("vote", komainutest.Subcommand("start", komainutest.Option("length", "1h")))
+	d.Dispatch(start)
+	opened, ok := d.Response(start)
+	if !ok || opened.Type != api.ModalResponse {
+		t.Fatalf("Expected /vote start to open a modal, Got %+v", opened)
+	}
+
+	inputs := map[string]string{
+		"description": "Pizza for lunch?",
+		"options":     "Yes\nNo",
+	}
+	intruder := komainutest.As(komainutest.Modal(opened.Data.CustomID.Val, inputs), komainutest.UserID+1)
+	d.Dispatch(intruder)
+	if refused, ok := d.Response(intruder); !ok || !strings.Contains(refused.Data.Content.Val, "isn't yours") {
+		t.Fatalf("Expected someone else to be refused the modal, Got %+v", refused)
+	}
+
+	submit := komainutest.Modal(opened.Data.CustomID.Val, inputs)
+	d.Dispatch(submit)
+	posted, ok := d.Response(submit)
+	if !ok || posted.Type != api.MessageInteractionWithSource || !strings.Contains(posted.Data.Content.Val, "Pizza for lunch?") {
+		t.Fatalf("Expected the vote to be posted, Got %+v", posted)
+	}
+
+	// The first message the fake Discord makes up is the vote, once the callback has stored it.
+	messageID := discord.MessageID(komainutest.MessageID + 1)
+	var vote *storage.Vote
+	stored := komainutest.Eventually(func() bool {
+		exist, found, err := storage.GetVote(kvs, komainutest.GuildID, messageID)
+		vote = found
+		return exist && err == nil
+	})
+	if !stored {
+		t.Fatal("The vote was never stored")
+	}
+	if vote.Question != "Pizza for lunch?" || len(vote.Order) != 2 || vote.EndTime <= time.Now().Unix() {
+		t.Fatalf("Expected an open vote with two options, Got %+v", vote)
+	}
+
+	cast := komainutest.Select("vote", messageID, vote.Order[0])
+	d.Dispatch(cast)
+	registered, ok := d.Response(cast)
+	if !ok || !strings.Contains(registered.Data.Content.Val, "is registered") {
+		t.Fatalf("Expected the vote to be registered, Got %+v", registered)
+	}
+	if _, edited := d.Find(http.MethodPatch, "channels/*/messages/"+messageID.String()); !edited {
+		t.Error("Expected the vote message to be edited with the new tally")
+	}
+	if _, vote, _ = storage.GetVote(kvs, komainutest.GuildID, messageID); vote.Votes[komainutest.UserID] != vote.Order[0] {
+		t.Errorf("Expected the vote for %s to be stored, Got %v", vote.Order[0], vote.Votes)
+	}
+
+	vote.EndTime = time.Now().Add(-time.Minute).Unix()
+	if err := vote.Store(kvs); err != nil {
+		t.Fatalf("Could not close the vote: %s", err)
+	}
+	late := komainutest.Select("vote", messageID, vote.Order[1])
+	d.Dispatch(late)
+	if closed, ok := d.Response(late); !ok || !strings.Contains(closed.Data.Content.Val, "closed") {
+		t.Errorf("Expected to be told the vote is closed, Got %+v", closed)
+	}
+}
+
+func TestVoteButtons(t *testing.T) {
+	kvs := storage.OpenMemory()
+	d := connect(kvs)
+
+	start := komainutest.Command("vote", komainutest.Subcommand("start", komainutest.Option("buttons", true)))
+	d.Dispatch(start)
+	opened, ok := d.Response(start)
+	if !ok || opened.Type != api.ModalResponse {
+		t.Fatalf("Expected /vote start to open a modal, Got %+v", opened)
+	}
+
+	tooMany := komainutest.Modal(opened.Data.CustomID.Val, map[string]string{
+		"description": "Which day?",
+		"options":     "Mon\nTue\nWed\nThu\nFri\nSat",
+	})
+	d.Dispatch(tooMany)
+	if refused, ok := d.Response(tooMany); !ok || !strings.Contains(refused.Data.Content.Val, "only room for 5 buttons") {
+		t.Fatalf("Expected six buttons to be refused, Got %+v", refused)
+	}
+
+	start = komainutest.Command("vote", komainutest.Subcommand("start", komainutest.Option("buttons", true)))
+	d.Dispatch(start)
+	opened, _ = d.Response(start)
+	tooLong := komainutest.Modal(opened.Data.CustomID.Val, map[string]string{
+		"description": "Pizza for lunch?",
+		"options":     "Yes\n" + strings.Repeat("Nø", 41),
+	})
+	d.Dispatch(tooLong)
+	if refused, ok := d.Response(tooLong); !ok || !strings.Contains(refused.Data.Content.Val, "only say 80 characters") {
+		t.Fatalf("Expected an option too long for a button to be refused, Got %+v", refused)
+	}
+
+	start = komainutest.Command("vote", komainutest.Subcommand("start", komainutest.Option("buttons", true)))
+	d.Dispatch(start)
+	opened, _ = d.Response(start)
+	submit := komainutest.Modal(opened.Data.CustomID.Val, map[string]string{
+		"description": "Pizza for lunch?",
+		"options":     "Yes\nNo",
+	})
+	d.Dispatch(submit)
+	posted, ok := d.Response(submit)
+	if !ok || posted.Data.Components == nil || len(*posted.Data.Components) != 1 {
+		t.Fatalf("Expected the vote to be posted with a row of buttons, Got %+v", posted)
+	}
+	row := (*posted.Data.Components)[0].(*discord.ActionRowComponent)
+	if len(*row) != 2 {
+		t.Fatalf("Expected a button per option, Got %d", len(*row))
+	}
+	yes, ok := (*row)[0].(*discord.ButtonComponent)
+	if !ok || yes.Label != "Yes" {
+		t.Fatalf("Expected the first button to be Yes, Got %+v", (*row)[0])
+	}
+
+	messageID := discord.MessageID(komainutest.MessageID + 1)
+	if !komainutest.Eventually(func() bool { exist, _, _ := storage.GetVote(kvs, komainutest.GuildID, messageID); return exist }) {
+		t.Fatal("The vote was never stored")
+	}
+	click := komainutest.Button(yes.CustomID, messageID)
+	d.Dispatch(click)
+	if registered, ok := d.Response(click); !ok || !strings.Contains(registered.Data.Content.Val, "Yes\n...is registered") {
+		t.Fatalf("Expected the vote to be registered, Got %+v", registered)
+	}
+	_, vote, _ := storage.GetVote(kvs, komainutest.GuildID, messageID)
+	if tally, _ := vote.Tally(); tally["Yes"] != 1 || tally["No"] != 0 {
+		t.Errorf("Expected one vote for Yes, Got %v", tally)
+	}
+}
+
+func TestVoteMalformed(t *testing.T) {
+	d := connect(storage.OpenMemory())
+
+	// Discord never sends this, but the "Wat." in the log says it happened once.
+	e := komainutest.As(komainutest.Command("vote"), komainutest.UserID+1)
+	d.Dispatch(e)
+	if resp, ok := d.Response(e); !ok || resp.Data.Flags&api.EphemeralResponse == 0 {
+		t.Errorf("Expected an ephemeral complaint, Got %+v", resp)
+	}
+}
+
+func TestVoteRefresh(t *testing.T) {
+	kvs := storage.OpenMemory()
+	d := connect(kvs)
+	messageID := discord.MessageID(komainutest.MessageID)
+
+	vote := storage.Vote{
+		StartTime: time.Now().Unix(),
+		EndTime:   time.Now().Add(time.Hour).Unix(),
+		GuildID:   komainutest.GuildID,
+		ChannelID: komainutest.ChannelID,
+		MessageID: messageID,
+		Question:  "Pizza for lunch?",
+		Order:     []string{"vote/0", "vote/1"},
+		Options:   map[string]string{"vote/0": "Yes", "vote/1": "No"},
+		Votes:     map[discord.UserID]string{komainutest.UserID: "vote/0"},
+		Buttons:   true,
+	}
+	if err := vote.Store(kvs); err != nil {
+		t.Fatalf("Could not store the vote: %s", err)
+	}
+
+	refresh := komainutest.Command("vote", komainutest.Subcommand("refresh", komainutest.Option("vote", messageID.String())))
+	d.Dispatch(refresh)
+	if resp, ok := d.Response(refresh); !ok || !strings.Contains(resp.Data.Content.Val, "up to date") {
+		t.Fatalf("Expected to be told the vote was refreshed, Got %+v", resp)
+	}
+	call, edited := d.Find(http.MethodPatch, "channels/*/messages/"+messageID.String())
+	if !edited {
+		t.Fatal("Expected the vote message to be edited")
+	}
+	data := api.EditMessageData{}
+	if err := call.Decode(&data); err != nil {
+		t.Fatalf("Could not decode the edit: %s", err)
+	}
+	if !strings.Contains(data.Content.Val, "**Yes** (1 vote)") || data.Components == nil || len(*data.Components) != 1 {
+		t.Errorf("Expected the current standings and the buttons, Got %q with %+v", data.Content.Val, data.Components)
+	}
+
+	before := len(d.Calls())
+	GuildCreateVote(d.State, kvs, &gateway.GuildCreateEvent{Guild: discord.Guild{ID: komainutest.GuildID}})
+	if !komainutest.Eventually(func() bool { return len(d.Calls()) > before }) {
+		t.Error("Expected the open vote to be refreshed when the guild comes in")
+	}
+}
+
+func TestVoteAtOnce(t *testing.T) {
+	kvs := storage.OpenMemory()
+	d := connect(kvs)
+	messageID := discord.MessageID(komainutest.MessageID)
+
+	vote := storage.Vote{
+		StartTime: time.Now().Unix(),
+		EndTime:   time.Now().Add(time.Hour).Unix(),
+		GuildID:   komainutest.GuildID,
+		ChannelID: komainutest.ChannelID,
+		MessageID: messageID,
+		Question:  "Pizza for lunch?",
+		Order:     []string{"vote/0", "vote/1"},
+		Options:   map[string]string{"vote/0": "Yes", "vote/1": "No"},
+		Votes:     map[discord.UserID]string{},
+	}
+	if err := vote.Store(kvs); err != nil {
+		t.Fatalf("Could not store the vote: %s", err)
+	}
+
+	// Enough to keep every worker busy, all on the same vote.
+	const voters = 20
+	casts := make([]*gateway.InteractionCreateEvent, voters)
+	for i := range casts {
+		casts[i] = komainutest.As(komainutest.Select("vote", messageID, "vote/0"), komainutest.UserID+discord.UserID(i+1))
+		d.Dispatch(casts[i])
+	}
+	for _, cast := range casts {
+		if resp, ok := d.Response(cast); !ok || !strings.Contains(resp.Data.Content.Val, "is registered") {
+			t.Fatalf("Expected every vote to be registered, Got %+v", resp)
+		}
+	}
+	_, stored, err := storage.GetVote(kvs, komainutest.GuildID, messageID)
+	if err != nil {
+		t.Fatalf("Could not get the vote: %s", err)
+	}
+	if len(stored.Votes) != voters {
+		t.Errorf("Expected all %d votes to be kept, Got %d", voters, len(stored.Votes))
+	}
+}
+
+func TestVoteTranslated(t *testing.T) {
+	kvs := storage.OpenMemory()
+	d := connect(kvs)
+	messageID := discord.MessageID(komainutest.MessageID)
+
+	if err := storage.SetLocale(kvs, komainutest.GuildID, string(discord.Norwegian)); err != nil {
+		t.Fatalf("Could not set the locale: %s", err)
+	}
+	vote := storage.Vote{
+		StartTime: time.Now().Unix(),
+		EndTime:   time.Now().Add(time.Hour).Unix(),
+		GuildID:   komainutest.GuildID,
+		ChannelID: komainutest.ChannelID,
+		MessageID: messageID,
+		Question:  "Pizza for lunch?",
+		Order:     []string{"vote/0", "vote/1"},
+		Options:   map[string]string{"vote/0": "Yes", "vote/1": "No"},
+		Votes:     map[discord.UserID]string{},
+	}
+	if err := vote.Store(kvs); err != nil {
+		t.Fatalf("Could not store the vote: %s", err)
+	}
+
+	cast := komainutest.Select("vote", messageID, "vote/0")
+	d.Dispatch(cast)
+	expected := "Stemmen din på...\nYes\n...er registrert."
+	if resp, ok := d.Response(cast); !ok || resp.Data.Content.Val != expected {
+		t.Errorf("Expected %q, with what was voted for filled in, Got %+v", expected, resp)
+	}
+}
@@ -0,0 +1,300 @@
+package interactions
+
+import (
+	"fmt"
+	"komainu/interactions/command"
+	"komainu/interactions/message"
+	"komainu/interactions/module"
+	"komainu/interactions/response"
+	"komainu/storage"
+	"komainu/utility"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+// xpLeaderboardSize is how many people /leaderboard shows.
+const xpLeaderboardSize = 10
+
+var xpModule = module.Basic{
+	ModuleName: "xp",
+	CommandHandlers: map[string]command.Handler{
+		"xp":          commandXPObject,
+		"rank":        commandRankObject,
+		"leaderboard": commandLeaderboardObject,
+	},
+	Handlers: []module.EventHandler{
+		module.Message(message.Handler{Code: MessageXP}),
+	},
+}
+
+var commandXPObject = command.Handler{
+	Description: "Set up XP and levels",
+	Code:        CommandXP,
+	Feature:     "xp",
+	Options: []discord.CommandOption{
+		&discord.SubcommandOption{
+			OptionName:  "config",
+			Description: "Turn XP on or off, and set how it's handed out",
+			Options: []discord.CommandOptionValue{
+				&discord.BooleanOption{
+					OptionName:  "enabled",
+					Description: "Give XP for messages",
+					Required:    false,
+				},
+				&discord.IntegerOption{
+					OptionName:  "cooldown",
+					Description: "Seconds between messages that give XP. Default is 60.",
+					Required:    false,
+					Min:         option.NewInt(0),
+				},
+				&discord.ChannelOption{
+					OptionName:  "announce",
+					Description: "Where to announce level-ups. Default is where the message was sent.",
+					Required:    false,
+				},
+			},
+		},
+		&discord.SubcommandOption{
+			OptionName:  "reward",
+			Description: "Give a role to everyone who reaches a level",
+			Options: []discord.CommandOptionValue{
+				&discord.IntegerOption{
+					OptionName:  "level",
+					Description: "The level that gives the role",
+					Required:    true,
+					Min:         option.NewInt(1),
+				},
+				&discord.RoleOption{
+					OptionName:  "role",
+					Description: "The role to give, blank to remove the reward",
+					Required:    false,
+				},
+			},
+		},
+	},
+}
+
+var commandRankObject = command.Handler{
+	Description: "See your level and XP, or someone else's",
+	Code:        CommandRank,
+	Feature:     "xp",
+	Public:      true,
+	Options: []discord.CommandOption{
+		&discord.UserOption{
+			OptionName:  "user",
+			Description: "Whose rank to show. Default is you.",
+			Required:    false,
+		},
+	},
+}
+
+var commandLeaderboardObject = command.Handler{
+	Description: "See who has the most XP",
+	Code:        CommandLeaderboard,
+	Feature:     "xp",
+	Public:      true,
+	Options:     []discord.CommandOption{},
+}
+
+// CommandXP processes the /xp command, dispatching to the right subcommand.
+func CommandXP(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	state, kvs, event := ctx.State, ctx.KVS, ctx.Event
+	if len(cmd.Options) != 1 {
+		log.Printf("[%s] /xp command structure is somehow not a single subcommand. Wat.\n", event.GuildID)
+		return command.Response{Response: response.Ephemeral("I'm sorry, what? Something very weird happened.")}
+	}
+	config, err := storage.GetXPConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] Failed to get XP config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	options := cmd.Options[0].Options
+
+	switch cmd.Options[0].Name {
+	case "config":
+		if options.Find("enabled").Name != "" {
+			enabled, err := options.Find("enabled").BoolValue()
+			if err != nil {
+				log.Printf("[%s] /xp config failed to get enabled: %s", event.GuildID, err)
+				return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+			}
+			config.Enabled = enabled
+		}
+		if options.Find("cooldown").Name != "" {
+			cooldown, err := options.Find("cooldown").IntValue()
+			if err != nil {
+				log.Printf("[%s] /xp config failed to get cooldown: %s", event.GuildID, err)
+				return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+			}
+			config.CooldownSeconds = int(cooldown)
+		}
+		if options.Find("announce").Name != "" {
+			channelSnowflake, err := options.Find("announce").SnowflakeValue()
+			if err != nil {
+				log.Printf("[%s] /xp config failed to get channel snowflake: %s", event.GuildID, err)
+				return command.Response{Response: response.Ephemeral("There was an issue figuring out the channel. It has been logged.")}
+			}
+			config.Announce = discord.ChannelID(channelSnowflake)
+		}
+	case "reward":
+		level, err := options.Find("level").IntValue()
+		if err != nil {
+			log.Printf("[%s] /xp reward failed to get level: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+		}
+		if options.Find("role").Name == "" {
+			delete(config.Rewards, int(level))
+		} else {
+			roleSnowflake, err := options.Find("role").SnowflakeValue()
+			if err != nil {
+				log.Printf("[%s] /xp reward failed to get role snowflake: %s", event.GuildID, err)
+				return command.Response{Response: response.Ephemeral("There was an issue figuring out the role. It has been logged.")}
+			}
+			if err := utility.CanManageRole(state, event.GuildID, event.Member, discord.RoleID(roleSnowflake)); err != nil {
+				return command.Response{Response: response.Ephemeral(hierarchyProblem(event.GuildID, err))}
+			}
+			config.Rewards[int(level)] = discord.RoleID(roleSnowflake)
+		}
+	default:
+		return command.Response{Response: response.Ephemeral("Unknown subcommand! Clearly *someone* dropped the ball!")}
+	}
+
+	if err := storage.SetXPConfig(kvs, event.GuildID, config); err != nil {
+		log.Printf("[%s] Failed to store XP config: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	log.Printf("[%s] <@%s> changed the XP config", event.GuildID, event.SenderID())
+	return command.Response{Response: response.Ephemeral(describeXPConfig(config))}
+}
+
+func describeXPConfig(config storage.XPConfig) string {
+	lines := []string{"XP is off."}
+	if config.Enabled {
+		lines[0] = fmt.Sprintf("XP is on, given at most once every %s per person.", config.Cooldown())
+	}
+	if config.Announce.IsValid() {
+		lines = append(lines, "Level-ups are announced in "+config.Announce.Mention())
+	} else {
+		lines = append(lines, "Level-ups are announced where the message was sent.")
+	}
+	levels := make([]int, 0, len(config.Rewards))
+	for level := range config.Rewards {
+		levels = append(levels, level)
+	}
+	sort.Ints(levels)
+	for _, level := range levels {
+		lines = append(lines, fmt.Sprintf("Level %d gives %s", level, config.Rewards[level].Mention()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CommandRank processes the /rank command.
+func CommandRank(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	userID := event.SenderID()
+	if cmd.Options.Find("user").Name != "" {
+		userSnowflake, err := cmd.Options.Find("user").SnowflakeValue()
+		if err != nil {
+			log.Printf("[%s] /rank failed to get user snowflake: %s", event.GuildID, err)
+			return command.Response{Response: response.Ephemeral("There was an issue figuring out the user. It has been logged.")}
+		}
+		userID = discord.UserID(userSnowflake)
+	}
+	board, err := storage.XPLeaderboard(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /rank failed to get the leaderboard: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	for place, xp := range board {
+		if xp.UserID != userID {
+			continue
+		}
+		level := xp.Level()
+		return command.Response{Response: response.MessageNoMention(fmt.Sprintf(
+			"%s is level **%d** with %d XP, #%d of %d. Level %d is %d XP away.",
+			userID.Mention(), level, xp.Points, place+1, len(board), level+1, storage.XPForLevel(level+1)-xp.Points,
+		))}
+	}
+	return command.Response{Response: response.MessageNoMention(fmt.Sprintf("%s doesn't have any XP yet.", userID.Mention()))}
+}
+
+// CommandLeaderboard processes the /leaderboard command.
+func CommandLeaderboard(ctx *command.Context, cmd *discord.CommandInteraction) command.Response {
+	kvs, event := ctx.KVS, ctx.Event
+	board, err := storage.XPLeaderboard(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] /leaderboard failed to get the leaderboard: %s", event.GuildID, err)
+		return command.Response{Response: response.Ephemeral("An error occured, and has been logged.")}
+	}
+	if len(board) == 0 {
+		return command.Response{Response: response.Ephemeral("Nobody has any XP yet.")}
+	}
+	if len(board) > xpLeaderboardSize {
+		board = board[:xpLeaderboardSize]
+	}
+	lines := make([]string, len(board))
+	for place, xp := range board {
+		lines[place] = fmt.Sprintf("**#%d** %s: level %d, %d XP", place+1, xp.UserID.Mention(), xp.Level(), xp.Points)
+	}
+	return command.Response{Response: response.MessageNoMention(strings.Join(lines, "\n"))}
+}
+
+// MessageXP gives XP for messages, announces level-ups and hands out reward roles.
+func MessageXP(state *state.State, kvs storage.KeyValueStore, event *gateway.MessageCreateEvent) {
+	if event.GuildID == discord.NullGuildID || event.Author.Bot {
+		return
+	}
+	config, err := storage.GetXPConfig(kvs, event.GuildID)
+	if err != nil {
+		log.Printf("[%s] XP failed to get config: %s", event.GuildID, err)
+		return
+	}
+	if !config.Enabled {
+		return
+	}
+	if enabled, err := storage.FeatureEnabled(kvs, event.GuildID, "xp"); err != nil || !enabled {
+		return
+	}
+	_, level, err := storage.AwardXP(kvs, event.GuildID, event.Author.ID, config.Cooldown())
+	if err != nil {
+		log.Printf("[%s] Failed to give <@%s> XP: %s", event.GuildID, event.Author.ID, err)
+		return
+	}
+	if level == 0 {
+		return
+	}
+
+	announceID := event.ChannelID
+	if config.Announce.IsValid() {
+		announceID = config.Announce
+	}
+	if _, err := state.SendMessage(announceID, fmt.Sprintf("🎉 %s reached level **%d**!", event.Author.Mention(), level)); err != nil {
+		log.Printf("[%s] Failed to announce the level-up of <@%s>: %s", event.GuildID, event.Author.ID, err)
+	}
+
+	// Giving every reward up to this level, in case some were set up after they passed it.
+	held := []discord.RoleID{}
+	if event.Member != nil {
+		held = event.Member.RoleIDs
+	}
+	for _, roleID := range config.RewardsUpTo(level) {
+		if utility.ContainsRole(held, roleID) {
+			continue
+		}
+		if err := utility.CanManageRole(state, event.GuildID, nil, roleID); utility.OutOfReach(err) {
+			log.Printf("[%s] Not giving <@%s> the level reward <@&%s>: %s", event.GuildID, event.Author.ID, roleID, err)
+			continue
+		}
+		err := state.AddRole(event.GuildID, event.Author.ID, roleID, api.AddRoleData{AuditLogReason: api.AuditLogReason(fmt.Sprintf("Reached level %d", level))})
+		if err != nil {
+			log.Printf("[%s] Failed to give <@%s> the level reward <@&%s>: %s", event.GuildID, event.Author.ID, roleID, err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Package komainutest helps test handlers without talking to Discord. Discord is a state.State that never connects,
+// and writes down every call made to the API instead of making it, answering with whatever the test says to. The
+// events are built with the functions in events.go, and storage is storage.OpenMemory.
+package komainutest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/state/store/defaultstore"
+	"github.com/diamondburned/arikawa/v3/utils/handler"
+	"github.com/diamondburned/arikawa/v3/utils/httputil"
+	"github.com/diamondburned/arikawa/v3/utils/httputil/httpdriver"
+)
+
+// WaitTimeout is how long WaitFor waits before giving up. Handlers that defer their work respond on another goroutine.
+const WaitTimeout = 2 * time.Second
+
+// Call is a call made to the Discord API.
+type Call struct {
+	Method string
+	Path   string // Relative to the API endpoint, like "channels/123/messages".
+	Body   []byte
+}
+
+// Decode decodes the JSON body of the call into out.
+func (c Call) Decode(out interface{}) error {
+	return json.Unmarshal(c.Body, out)
+}
+
+func (c Call) String() string {
+	return c.Method + " " + c.Path
+}
+
+// reply is what the API answers with, for calls with a path matching the pattern.
+type reply struct {
+	method  string
+	pattern string
+	status  int
+	body    []byte
+}
+
+// Discord stands in for Discord. Set up the replies to expect, hand State to the handlers, and look at Calls after.
+type Discord struct {
+	State *state.State
+
+	mutex    sync.Mutex
+	calls    []Call
+	replies  []reply
+	messages int64 // For making up message IDs.
+}
+
+// New makes a Discord, with a State that has never connected, and never will.
+// Calls answer with nothing at all unless a reply is set up for them, except for the interaction response message,
+// which is made up so callbacks get called.
+func New() *Discord {
+	d := &Discord{}
+	client := httputil.NewClient()
+	client.Client = d
+	client.Retries = 1
+	id := gateway.DefaultIdentifier("Bot komainutest")
+	d.State = state.NewFromSession(session.NewCustom(id, api.NewCustomClient(id.Token, client), handler.New()), defaultstore.New())
+	return d
+}
+
+// Reply makes calls with the method, and a path matching the pattern, answer with body encoded as JSON. The pattern is
+// as for path.Match, so "channels/*/messages" matches messages sent to any channel. Later replies win over earlier ones.
+func (d *Discord) Reply(method string, pattern string, body interface{}) {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		panic(fmt.Sprintf("komainutest could not encode the reply to %s %s: %s", method, pattern, err))
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.replies = append(d.replies, reply{method, pattern, http.StatusOK, encoded})
+}
+
+// Fail makes calls with the method, and a path matching the pattern, fail with the HTTP status.
+func (d *Discord) Fail(method string, pattern string, status int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.replies = append(d.replies, reply{method, pattern, status, []byte(`{"code":0,"message":"komainutest says no"}`)})
+}
+
+// Calls lists every call made so far, oldest first.
+func (d *Discord) Calls() []Call {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return append([]Call{}, d.calls...)
+}
+
+// Find gets the latest call with the method, and a path matching the pattern, if there is one.
+func (d *Discord) Find(method string, pattern string) (Call, bool) {
+	calls := d.Calls()
+	for i := len(calls) - 1; i >= 0; i-- {
+		if calls[i].Method == method && matches(pattern, calls[i].Path) {
+			return calls[i], true
+		}
+	}
+	return Call{}, false
+}
+
+// WaitFor is like Find, but waits up to WaitTimeout for the call to be made.
+func (d *Discord) WaitFor(method string, pattern string) (call Call, found bool) {
+	Eventually(func() bool {
+		call, found = d.Find(method, pattern)
+		return found
+	})
+	return
+}
+
+// Eventually checks the condition until it's true, for up to WaitTimeout, and returns whether it ever was.
+func Eventually(condition func() bool) bool {
+	deadline := time.Now().Add(WaitTimeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return true
+}
+
+// Response waits for the response to the interaction, and decodes it.
+func (d *Discord) Response(event *gateway.InteractionCreateEvent) (api.InteractionResponse, bool) {
+	response := api.InteractionResponse{}
+	call, ok := d.WaitFor(http.MethodPost, fmt.Sprintf("interactions/%s/%s/callback", event.ID, event.Token))
+	if !ok {
+		return response, false
+	}
+	return response, call.Decode(&response) == nil
+}
+
+// Dispatch hands the event to every handler added to the State, as if it came from the gateway.
+func (d *Discord) Dispatch(event interface{}) {
+	d.State.Handler.Call(event)
+}
+
+func matches(pattern string, name string) bool {
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
+
+// NewRequest is for httpdriver.Client, which is how Discord sits in for the actual API.
+func (d *Discord) NewRequest(ctx context.Context, method string, address string) (httpdriver.Request, error) {
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	return &request{ctx: ctx, method: method, url: parsed, header: http.Header{}}, nil
+}
+
+// Do is for httpdriver.Client. It writes down the call, and answers it.
+func (d *Discord) Do(req httpdriver.Request) (httpdriver.Response, error) {
+	r := req.(*request)
+	call := Call{Method: r.method, Path: strings.TrimPrefix(r.url.String(), api.Endpoint)}
+	if i := strings.IndexByte(call.Path, '?'); i >= 0 {
+		call.Path = call.Path[:i]
+	}
+	if r.body != nil {
+		body, err := io.ReadAll(r.body)
+		r.body.Close()
+		if err != nil {
+			return nil, err
+		}
+		call.Body = body
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.calls = append(d.calls, call)
+	for i := len(d.replies) - 1; i >= 0; i-- {
+		if reply := d.replies[i]; reply.method == call.Method && matches(reply.pattern, call.Path) {
+			return &response{status: reply.status, body: reply.body}, nil
+		}
+	}
+	if call.Method == http.MethodGet && matches("webhooks/*/*/messages/@original", call.Path) {
+		d.messages++
+		message := discord.Message{ID: discord.MessageID(MessageID + discord.Snowflake(d.messages)), ChannelID: ChannelID, GuildID: GuildID}
+		body, _ := json.Marshal(message)
+		return &response{status: http.StatusOK, body: body}, nil
+	}
+	if call.Method == http.MethodPost && matches("interactions/*/*/callback", call.Path) {
+		return &response{status: http.StatusNoContent}, nil
+	}
+	return &response{status: http.StatusOK, body: []byte("null")}, nil
+}
+
+// request is a call on its way to Discord.
+type request struct {
+	ctx    context.Context
+	method string
+	url    *url.URL
+	header http.Header
+	body   io.ReadCloser
+}
+
+func (r *request) GetPath() string              { return r.url.Path }
+func (r *request) GetContext() context.Context  { return r.ctx }
+func (r *request) AddHeader(header http.Header) { mergeHeader(r.header, header) }
+func (r *request) WithBody(body io.ReadCloser)  { r.body = body }
+
+func (r *request) AddQuery(values url.Values) {
+	query := r.url.Query()
+	for key, value := range values {
+		query[key] = append(query[key], value...)
+	}
+	r.url.RawQuery = query.Encode()
+}
+
+func mergeHeader(into http.Header, from http.Header) {
+	for key, value := range from {
+		into[key] = append(into[key], value...)
+	}
+}
+
+// response is how Discord answered.
+type response struct {
+	status int
+	body   []byte
+}
+
+func (r *response) GetStatus() int         { return r.status }
+func (r *response) GetHeader() http.Header { return http.Header{} }
+func (r *response) GetBody() io.ReadCloser { return io.NopCloser(bytes.NewReader(r.body)) }
@@ -0,0 +1,135 @@
+package komainutest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+// Where the events happen, and who they come from, unless changed with In or As.
+const (
+	GuildID   = discord.GuildID(211575243083350016)
+	ChannelID = discord.ChannelID(211575243083350017)
+	UserID    = discord.UserID(211575243083350018)
+	AppID     = discord.AppID(211575243083350019)
+	MessageID = discord.Snowflake(211575243083350100) // Messages made up by Discord count up from here.
+)
+
+// interactions counts up, so every event gets an ID and token of its own.
+var interactions atomic.Int64
+
+// event makes an interaction event in GuildID and ChannelID, from UserID, with the data.
+func event(data discord.InteractionData) *gateway.InteractionCreateEvent {
+	id := discord.InteractionID(interactions.Add(1))
+	return &gateway.InteractionCreateEvent{InteractionEvent: discord.InteractionEvent{
+		ID:        id,
+		Data:      data,
+		AppID:     AppID,
+		ChannelID: ChannelID,
+		Token:     fmt.Sprintf("token%d", id),
+		Member:    &discord.Member{User: discord.User{ID: UserID, Username: "tester"}},
+		GuildID:   GuildID,
+	}}
+}
+
+// Command makes the event for someone using the named command, with the options.
+func Command(name string, options ...discord.CommandInteractionOption) *gateway.InteractionCreateEvent {
+	return event(&discord.CommandInteraction{Name: name, Options: options})
+}
+
+// Subcommand makes the option for a subcommand, with the options.
+func Subcommand(name string, options ...discord.CommandInteractionOption) discord.CommandInteractionOption {
+	return discord.CommandInteractionOption{Type: discord.SubcommandOptionType, Name: name, Options: options}
+}
+
+// Group makes the option for a group of subcommands, with the subcommand used.
+func Group(name string, subcommand discord.CommandInteractionOption) discord.CommandInteractionOption {
+	return discord.CommandInteractionOption{Type: discord.SubcommandGroupOptionType, Name: name, Options: discord.CommandInteractionOptions{subcommand}}
+}
+
+// Option makes an option with the value, of the type that goes with the value. Only the types of value options are
+// supported, and anything else panics.
+func Option(name string, value interface{}) discord.CommandInteractionOption {
+	var optionType discord.CommandOptionType
+	switch value.(type) {
+	case string:
+		optionType = discord.StringOptionType
+	case int, int64:
+		optionType = discord.IntegerOptionType
+	case float64:
+		optionType = discord.NumberOptionType
+	case bool:
+		optionType = discord.BooleanOptionType
+	case discord.UserID:
+		optionType = discord.UserOptionType
+	case discord.ChannelID:
+		optionType = discord.ChannelOptionType
+	case discord.RoleID:
+		optionType = discord.RoleOptionType
+	default:
+		panic(fmt.Sprintf("komainutest has no option type for %T", value))
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		panic(fmt.Sprintf("komainutest could not encode the %s option: %s", name, err))
+	}
+	return discord.CommandInteractionOption{Type: optionType, Name: name, Value: raw}
+}
+
+// Select makes the event for someone choosing the values in a select menu on the message.
+func Select(customID discord.ComponentID, messageID discord.MessageID, values ...string) *gateway.InteractionCreateEvent {
+	e := event(&discord.SelectInteraction{CustomID: customID, Values: values})
+	e.Message = &discord.Message{ID: messageID, ChannelID: ChannelID, GuildID: GuildID}
+	return e
+}
+
+// Button makes the event for someone clicking a button on the message.
+func Button(customID discord.ComponentID, messageID discord.MessageID) *gateway.InteractionCreateEvent {
+	e := event(&discord.ButtonInteraction{CustomID: customID})
+	e.Message = &discord.Message{ID: messageID, ChannelID: ChannelID, GuildID: GuildID}
+	return e
+}
+
+// Modal makes the event for someone submitting a modal with the text inputs filled in. The custom ID of a modal
+// opened with modal.Respond is in the response that opened it.
+func Modal(customID string, inputs map[string]string) *gateway.InteractionCreateEvent {
+	components := discord.ContainerComponents{}
+	for id, value := range inputs {
+		components = append(components, &discord.ActionRowComponent{
+			&discord.TextInputComponent{CustomID: discord.ComponentID(id), Value: option.NewNullableString(value)},
+		})
+	}
+	return event(&discord.ModalInteraction{CustomID: discord.ComponentID(customID), Components: components})
+}
+
+// As makes the event come from someone else.
+func As(e *gateway.InteractionCreateEvent, userID discord.UserID) *gateway.InteractionCreateEvent {
+	if e.Member != nil {
+		e.Member.User.ID = userID
+	}
+	if e.User != nil {
+		e.User.ID = userID
+	}
+	return e
+}
+
+// In moves the event to another guild and channel.
+func In(e *gateway.InteractionCreateEvent, guildID discord.GuildID, channelID discord.ChannelID) *gateway.InteractionCreateEvent {
+	e.GuildID, e.ChannelID = guildID, channelID
+	if e.Message != nil {
+		e.Message.GuildID, e.Message.ChannelID = guildID, channelID
+	}
+	return e
+}
+
+// Private makes the event happen in private with the bot, where there is no guild, and no member.
+func Private(e *gateway.InteractionCreateEvent) *gateway.InteractionCreateEvent {
+	e.User = &e.Member.User
+	e.Member = nil
+	e.GuildID = discord.NullGuildID
+	return e
+}
@@ -0,0 +1,111 @@
+// Package locale translates what the bot says into the language the guild picked.
+//
+// The catalogs are keyed by the English text itself, so anything the bot says that isn't in a catalog yet
+// just stays in English. Adding a translation is a matter of adding the English text and its translation
+// to the catalog of the language, no code changes needed.
+//
+// Messages with something filled in, like a name or a count, are cataloged by their format, verbs and all, and
+// go through T with the arguments. The translation can use explicit argument indexes, like %[2]s, when the
+// language wants things in a different order.
+package locale
+
+import (
+	"fmt"
+	"komainu/storage"
+	"log"
+	"sort"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+// English is the language the bot is written in, so it needs no catalog.
+const English = discord.EnglishUS
+
+// catalogs maps each language to its translations, keyed by the English text.
+var catalogs = map[discord.Language]map[string]string{
+	discord.Norwegian: norwegian,
+}
+
+// names are what each language is called, in that language.
+var names = map[discord.Language]string{
+	English:           "English",
+	discord.Norwegian: "Norsk",
+}
+
+// Languages lists the languages there is a catalog for, English first.
+func Languages() []discord.Language {
+	languages := []discord.Language{}
+	for language := range catalogs {
+		languages = append(languages, language)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		return languages[i] < languages[j]
+	})
+	return append([]discord.Language{English}, languages...)
+}
+
+// Name returns what the language is called, in that language.
+func Name(language discord.Language) string {
+	if name, ok := names[language]; ok {
+		return name
+	}
+	return string(language)
+}
+
+// Translate returns the text in the given language, if the catalog has it, or the text as it is.
+func Translate(language discord.Language, text string) string {
+	if translated, ok := catalogs[language][text]; ok {
+		return translated
+	}
+	return text
+}
+
+// T translates the format into the given language, if the catalog has it, and fills in the arguments.
+// Without arguments, it's the same as Translate.
+func T(language discord.Language, format string, args ...interface{}) string {
+	translated := Translate(language, format)
+	if len(args) == 0 {
+		return translated
+	}
+	return fmt.Sprintf(translated, args...)
+}
+
+// Localizations returns the text in every language that has it, for Discord's localized names and descriptions.
+func Localizations(text string) discord.StringLocales {
+	var locales discord.StringLocales
+	for language, catalog := range catalogs {
+		if translated, ok := catalog[text]; ok {
+			if locales == nil {
+				locales = discord.StringLocales{}
+			}
+			locales[language] = translated
+		}
+	}
+	return locales
+}
+
+// ForGuild returns the language the guild picked, or English if it didn't.
+func ForGuild(kvs storage.KeyValueStore, guildID discord.GuildID) discord.Language {
+	if guildID == discord.NullGuildID {
+		return English
+	}
+	language, err := storage.GetLocale(kvs, guildID)
+	if err != nil {
+		log.Printf("[%s] Failed to get the locale, going with English: %s", guildID, err)
+		return English
+	}
+	if _, ok := catalogs[discord.Language(language)]; !ok {
+		return English
+	}
+	return discord.Language(language)
+}
+
+// TranslateResponse translates the content of the response into the language, if there is content and a translation for it.
+func TranslateResponse(language discord.Language, response *api.InteractionResponse) {
+	if language == English || response.Data == nil || response.Data.Content == nil {
+		return
+	}
+	response.Data.Content = option.NewNullableString(Translate(language, response.Data.Content.Val))
+}
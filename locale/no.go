@@ -0,0 +1,126 @@
+package locale
+
+// norwegian is the Norwegian (bokmål) catalog.
+var norwegian = map[string]string{
+	// Things said all over the place.
+	"An error occured, and has been logged.":                                                "Det oppstod en feil, og den er logget.",
+	"I'm sorry, what? Something very weird happened.":                                       "Unnskyld, hva? Noe veldig rart skjedde.",
+	"Unknown subcommand! Clearly *someone* dropped the ball!":                               "Ukjent underkommando! *Noen* har tydeligvis rotet det til!",
+	"There was an issue figuring out the channel. It has been logged.":                      "Det var et problem med å finne ut av kanalen. Det er logget.",
+	"There was an issue figuring out the role. It has been logged.":                         "Det var et problem med å finne ut av rollen. Det er logget.",
+	"There was an issue figuring out the user. It has been logged.":                         "Det var et problem med å finne ut av brukeren. Det er logget.",
+	"That button is broken somehow. It has been logged.":                                    "Den knappen er ødelagt på et vis. Det er logget.",
+	"Invalid command structure.":                                                            "Ugyldig kommandostruktur.",
+	"Something odd happened. It has been logged.":                                           "Noe rart skjedde. Det er logget.",
+	"That doesn't look like a message ID to me.":                                            "Det ser ikke ut som en meldings-ID for meg.",
+	"I couldn't post in that channel. Do I have access to it?":                              "Jeg fikk ikke postet i den kanalen. Har jeg tilgang til den?",
+	"I'm sorry, I do not respond to commands in private.":                                   "Beklager, jeg svarer ikke på kommandoer privat.",
+	"You are using too many commands too quickly. Calm down.":                               "Du bruker for mange kommandoer for fort. Ro deg ned.",
+	"Too many commands being processed in this channel right now. Please wait.":             "For mange kommandoer behandles i denne kanalen akkurat nå. Vent litt.",
+	"Sorry, access was denied. Took too long to respond?":                                   "Beklager, ingen tilgang. Tok det for lang tid å svare?",
+	"Sorry, that form isn't yours to fill in.":                                              "Beklager, det skjemaet er ikke ditt å fylle ut.",
+	"I'm very busy right now. Please try again in a moment.":                                "Jeg er veldig opptatt akkurat nå. Prøv igjen om litt.",
+	"Sorry, that's turned off here. An administrator can turn it on with /config features.": "Beklager, det er slått av her. En administrator kan slå det på med /config features.",
+	"You used /%s just now. Try again in %ds.":                                              "Du brukte /%s akkurat nå. Prøv igjen om %ds.",
+	"/%s was used in this channel just now. Try again in %ds.":                              "/%s ble brukt i denne kanalen akkurat nå. Prøv igjen om %ds.",
+
+	// Votes.
+	"I'm sorry, that vote is closed!":                                                             "Beklager, den avstemningen er avsluttet!",
+	"I don't know of any open vote with that message ID.":                                         "Jeg vet ikke om noen åpen avstemning med den meldings-ID-en.",
+	"Your comment is saved. Only the one that started the vote can see it, and not who wrote it.": "Kommentaren din er lagret. Bare den som startet avstemningen kan se den, og ikke hvem som skrev den.",
+	"Your comment is removed.":                                                                    "Kommentaren din er fjernet.",
+	"There are no open votes right now.":                                                          "Det er ingen åpne avstemninger akkurat nå.",
+	"The vote is cancelled.":                                                                      "Avstemningen er avlyst.",
+	"Only the one that started the vote can export the results.":                                  "Bare den som startet avstemningen kan eksportere resultatene.",
+	"Here are the results.":                                                                       "Her er resultatene.",
+	"Your vote for...\n%s\n...is registered.":                                                     "Stemmen din på...\n%s\n...er registrert.",
+	"I don't understand %q as a length. Give an amount of time, like `36h` or `3d12h`, or when the vote ends, like `2024-07-01 18:00`.": "Jeg forstår ikke %q som en lengde. Oppgi en tidsmengde, som `36h` eller `3d12h`, eller når avstemningen slutter, som `2024-07-01 18:00`.",
+	"The vote would be over before it started, as %s has already passed.":                                                               "Avstemningen ville vært over før den startet, siden %s allerede har vært.",
+	"The vote was supposed to end %s, so it's too late to start it now.":                                                                "Avstemningen skulle slutte %s, så det er for sent å starte den nå.",
+	"There's only room for %d buttons, so a vote with %d options has to use the menu.":                                                  "Det er bare plass til %d knapper, så en avstemning med %d alternativer må bruke menyen.",
+	"Buttons can only say %d characters, so %q is too long for one. Shorten it, or use the menu.":                                       "Knapper kan bare ha %d tegn, så %q er for langt for en. Kort det ned, eller bruk menyen.",
+	"I don't understand %q as a number of hours.":                                                                                       "Jeg forstår ikke %q som et antall timer.",
+	"That's a lot of reminders. A vote can have at most %d.":                                                                            "Det var mange påminnelser. En avstemning kan ha maks %d.",
+	"The vote closes %s, so all of those reminders would be in the past.":                                                               "Avstemningen avsluttes %s, så alle de påminnelsene ville vært i fortiden.",
+	"Refreshed %d open votes.": "Oppdaterte %d åpne avstemninger.",
+	"Refreshed %d open votes, but %d couldn't be. The errors have been logged.":        "Oppdaterte %d åpne avstemninger, men %d kunne ikke oppdateres. Feilene er logget.",
+	"Really cancel the vote on %q? Nobody's votes will count, and it can't be undone.": "Vil du virkelig avlyse avstemningen om %q? Ingen stemmer vil telle, og det kan ikke angres.",
+	"Saved the %q template. Start a vote from it with `/vote template run name:%s`":    "Lagret malen %q. Start en avstemning fra den med `/vote template run name:%s`",
+	"There is no template called %q.":                                                  "Det finnes ingen mal som heter %q.",
+	"The %q template is gone.":                                                         "Malen %q er borte.",
+
+	// Verification.
+	"Verification isn't set up here anymore.":      "Verifisering er ikke satt opp her lenger.",
+	"You're already verified!":                     "Du er allerede verifisert!",
+	"That's not right. Press Verify to try again.": "Det er ikke riktig. Trykk Verify for å prøve igjen.",
+	"That's right! Welcome in.":                    "Det stemmer! Velkommen inn.",
+	"What is %d plus %d?":                          "Hva er %d pluss %d?",
+	"The Verify button is up in %s, and gives %s. Make sure new members can only see that channel, and that %s can see the rest.": "Verify-knappen er oppe i %s, og gir %s. Sørg for at nye medlemmer bare ser den kanalen, og at %s ser resten.",
+
+	// Settings.
+	"Times are now given in %s. Right now, that's %s.":                                    "Tider oppgis nå i %s. Akkurat nå er klokka %s.",
+	"I don't know the time zone %q. Use a name like `Europe/Oslo` or `America/New_York`.": "Jeg kjenner ikke tidssonen %q. Bruk et navn som `Europe/Oslo` eller `America/New_York`.",
+	"The bot now speaks %s here.":                                                         "Boten snakker nå %s her.",
+	"I can't find that: %s":                                                               "Jeg finner ikke det: %s",
+	"That won't work for `%s`: %s":                                                        "Det fungerer ikke for `%s`: %s",
+	"`%s` can't be left blank in `/%s`, so a preset would never be used.":                 "`%s` kan ikke stå tomt i `/%s`, så en forhåndsverdi ville aldri blitt brukt.",
+	"When `%s` is left blank in `/%s`, it will now be `%s`.":                              "Når `%s` står tomt i `/%s`, blir det nå `%s`.",
+	"There is no preset for `%s` in `/%s`.":                                               "Det er ingen forhåndsverdi for `%s` i `/%s`.",
+	"Preset for `%s` in `/%s` cleared.":                                                   "Forhåndsverdien for `%s` i `/%s` er fjernet.",
+	"Here is the new token, with %s access. Any old token no longer works. This is the only time it is shown, so keep it somewhere safe:\n||`%s`||": "Her er den nye nøkkelen, med %s-tilgang. Gamle nøkler virker ikke lenger. Dette er eneste gang den vises, så ta vare på den:\n||`%s`||",
+	"Webhooks can only go to addresses out on the internet, not to the machine I run on or a network it's on.":                                      "Webhooks kan bare gå til adresser ute på internett, ikke til maskinen jeg kjører på eller et nettverk den er på.",
+	"I couldn't look up %s: %s": "Jeg fikk ikke slått opp %s: %s",
+	"Webhooks now go to %s. Every one is signed with this secret, in the `X-Komainu-Signature` header. This is the only time it is shown:\n||`%s`||": "Webhooks går nå til %s. Hver av dem er signert med denne hemmeligheten, i `X-Komainu-Signature`-headeren. Dette er eneste gang den vises:\n||`%s`||",
+	"There's no feature called %q.":                     "Det finnes ingen funksjon som heter %q.",
+	"There is no `/%s` command.":                        "Det finnes ingen `/%s`-kommando.",
+	"`/%s` has no cooldown.":                            "`/%s` har ingen nedkjøling.",
+	"`/%s` can be used as often as people like again.":  "`/%s` kan brukes så ofte folk vil igjen.",
+	"`/%s` answers so everyone can see it.":             "`/%s` svarer så alle kan se det.",
+	"`/%s` answers so only whoever used it can see it.": "`/%s` svarer så bare den som brukte den kan se det.",
+	"`/%s` answers however it usually does.":            "`/%s` svarer slik den vanligvis gjør.",
+	"Every command answers however it usually does.":    "Alle kommandoer svarer slik de vanligvis gjør.",
+
+	// Command descriptions, as shown by Discord.
+	"Ask the mystical ateball!":                                      "Spør den mystiske spiseballen!",
+	"Automatically deal with messages that break the rules":          "Håndter meldinger som bryter reglene automatisk",
+	"Automatically deal with spam and raids":                         "Håndter spam og raid automatisk",
+	"Check when someone was last around":                             "Sjekk når noen sist var innom",
+	"Configure how the bot behaves in this guild":                    "Still inn hvordan boten oppfører seg i denne serveren",
+	"Create a message with a button that assigns a role":             "Lag en melding med en knapp som gir en rolle",
+	"Create a role self-assignment message":                          "Lag en melding der folk kan gi seg selv roller",
+	"Decide on suggestions":                                          "Avgjør forslag",
+	"Designate what channel to log joining and leaving in":           "Velg kanalen som logger hvem som kommer og går",
+	"Drop a prize at a random time, for the quickest to claim":       "Slipp en premie på et tilfeldig tidspunkt, til den raskeste",
+	"Everything coming up, in one place":                             "Alt som skjer fremover, på ett sted",
+	"Export someone's moderation history as a file":                  "Eksporter noens moderasjonshistorikk som en fil",
+	"Get a list of inactive people":                                  "Få en liste over inaktive folk",
+	"Get a list of people that the bot has never seen say anything!": "Få en liste over folk boten aldri har sett si noe!",
+	"Give people their roles back when they leave and come back":     "Gi folk rollene sine tilbake når de forlater og kommer tilbake",
+	"Let people pick their own roles from menus":                     "La folk velge sine egne roller fra menyer",
+	"Let the bot take care of threads":                               "La boten ta seg av tråder",
+	"List the commands you can use":                                  "List kommandoene du kan bruke",
+	"Log edited and deleted messages":                                "Logg redigerte og slettede meldinger",
+	"Look up a FAQ topic":                                            "Slå opp et FAQ-emne",
+	"Make new members prove they are people before they get in":      "La nye medlemmer bevise at de er mennesker før de slipper inn",
+	"Make slash commands of your own":                                "Lag dine egne skråstrekkommandoer",
+	"Make the bot post a message in a channel":                       "La boten poste en melding i en kanal",
+	"Manage FAQ topics":                                              "Administrer FAQ-emner",
+	"Manage events people can sign up for":                           "Administrer arrangementer folk kan melde seg på",
+	"Manage votes":                                                   "Administrer avstemninger",
+	"Private threads between members and staff":                      "Private tråder mellom medlemmer og stab",
+	"Repost popular messages to a channel of their own":              "Post populære meldinger på nytt i en egen kanal",
+	"Save and restore the permissions of channels":                   "Lagre og gjenopprett tillatelsene til kanaler",
+	"See who has the most XP":                                        "Se hvem som har mest XP",
+	"See your level and XP, or someone else's":                       "Se nivået og XP-en din, eller noen andres",
+	"Set up XP and levels":                                           "Sett opp XP og nivåer",
+	"Show everything I know about someone, in chronological order":   "Vis alt jeg vet om noen, i kronologisk rekkefølge",
+	"Show the names someone has had":                                 "Vis navnene noen har hatt",
+	"Stop everyone from talking, for when things get out of hand":    "Stopp alle fra å snakke, for når ting går over styr",
+	"Suggest something for everyone to vote on":                      "Foreslå noe alle kan stemme over",
+	"Things for checking on the bot itself":                          "Ting for å sjekke selve boten",
+	"Initiate a vote":                                                "Start en avstemning",
+	"List the votes that are still open":                             "List avstemningene som fortsatt er åpne",
+	"Close a vote early, without results":                            "Avslutt en avstemning tidlig, uten resultater",
+	"The language the bot speaks in this guild":                      "Språket boten snakker i denne serveren",
+	"The time zone times are given in, like when a vote ends":        "Tidssonen tider oppgis i, som når en avstemning slutter",
+}
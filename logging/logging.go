@@ -0,0 +1,115 @@
+// Package logging sets the bot up with structured logging, with guild, user, interaction and command as fields.
+//
+// Everything still logging through the standard log package goes through here as well. Those lines mostly start
+// with the guild ID in brackets, like "[1234] Something happened", so that is picked out and made a guild field.
+package logging
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+// Setup makes a structured logger writing to out the default, for both slog and the standard log package.
+// The level is one of debug, info, warn or error, and blank means info. If json is set, every line is a JSON object.
+func Setup(out io.Writer, level string, json bool, source bool) {
+	options := &slog.HandlerOptions{Level: parseLevel(level), AddSource: source}
+	var handler slog.Handler
+	if json {
+		handler = slog.NewJSONHandler(out, options)
+	} else {
+		handler = slog.NewTextHandler(out, options)
+	}
+	slog.SetDefault(slog.New(guildPrefixHandler{handler}))
+}
+
+func parseLevel(level string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	case "", "info":
+		return slog.LevelInfo
+	default:
+		log.Printf("Unknown log level %q, going with info", level)
+		return slog.LevelInfo
+	}
+}
+
+// shards is how many gateway shards the bot runs, for working out which shard a guild is on.
+var shards atomic.Int64
+
+// SetShards sets how many gateway shards the bot runs. With more than one, guild fields come with a shard field too.
+func SetShards(count int) {
+	shards.Store(int64(count))
+}
+
+// guildAttrs returns the guild field, and the shard field for it if the bot is sharded.
+func guildAttrs(guildID string) []any {
+	attrs := []any{"guild", guildID}
+	count := shards.Load()
+	if count <= 1 {
+		return attrs
+	}
+	id, err := strconv.ParseUint(guildID, 10, 64)
+	if err != nil {
+		return attrs
+	}
+	return append(attrs, "shard", int64((id>>22)%uint64(count)))
+}
+
+// guildPrefix matches the "[1234] " guild ID at the start of a line from the standard log package.
+var guildPrefix = regexp.MustCompile(`^\[([0-9]+)\] ?`)
+
+// guildPrefixHandler turns the guild ID at the start of a message into a guild field.
+type guildPrefixHandler struct {
+	slog.Handler
+}
+
+func (h guildPrefixHandler) Handle(ctx context.Context, r slog.Record) error {
+	match := guildPrefix.FindStringSubmatch(r.Message)
+	if match == nil {
+		return h.Handler.Handle(ctx, r)
+	}
+	record := slog.NewRecord(r.Time, r.Level, strings.TrimSpace(r.Message[len(match[0]):]), r.PC)
+	record.Add(guildAttrs(match[1])...)
+	r.Attrs(func(attr slog.Attr) bool {
+		record.AddAttrs(attr)
+		return true
+	})
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h guildPrefixHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return guildPrefixHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h guildPrefixHandler) WithGroup(name string) slog.Handler {
+	return guildPrefixHandler{h.Handler.WithGroup(name)}
+}
+
+// Guild returns a logger with the guild, and the shard it is on if the bot is sharded, as fields.
+func Guild(guildID discord.GuildID) *slog.Logger {
+	return slog.Default().With(guildAttrs(guildID.String())...)
+}
+
+// Interaction returns a logger with the guild, user and interaction as fields, and the kind and name of the interaction,
+// like "command" and "vote".
+func Interaction(e *gateway.InteractionCreateEvent, kind string, name string) *slog.Logger {
+	return slog.Default().With(guildAttrs(e.GuildID.String())...).With(
+		"user", e.SenderID().String(),
+		"interaction", e.ID.String(),
+		kind, name,
+	)
+}
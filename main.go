@@ -1,9 +1,22 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"komainu/bot"
+	"komainu/errorreport"
+	"komainu/interactions"
+	"komainu/interactions/command"
+	"komainu/interactions/module"
+	"komainu/logging"
+	"komainu/metrics"
+	"komainu/replay"
 	"komainu/storage"
+	"komainu/webapi"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -12,6 +25,14 @@ import (
 
 func main() {
 
+	replaying := flag.Bool("replay", false, "Replay the interactions recorded in the files or directories given, offline, and show what was done about them")
+	replayData := flag.Bool("replay-data", false, "Replay against a copy of what's in storage, rather than nothing at all")
+	flag.Parse()
+	replayPaths := make([]string, flag.NArg())
+	for i, path := range flag.Args() {
+		replayPaths[i], _ = filepath.Abs(path) // Before changing directory, as they're relative to where we were.
+	}
+
 	if os.Getenv("DEV_MODE") != "" {
 		log.SetFlags(log.Lshortfile | log.Ltime)
 	}
@@ -37,31 +58,131 @@ func main() {
 	}
 
 	cfg := storage.GetConfiguration()
+	var logOutput io.Writer = os.Stderr
 	if cfg.Logfile != "" {
 		log.Printf("Using %s for a log file\n", cfg.Logfile)
 		if logfileHandle, err := os.OpenFile(cfg.Logfile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640); err == nil {
-			log.SetOutput(logfileHandle)
+			logOutput = logfileHandle
 			defer logfileHandle.Close()
 		}
 	} else {
-		log.Println("No logfile specified: Will just output to STDOUT and hope for the best.")
+		log.Println("No logfile specified: Will just output to STDERR and hope for the best.")
+	}
+	setupLogging(logOutput, cfg)
+	errorreport.Setup(errorReportSettings(cfg))
+
+	if *replaying {
+		if err := replayInteractions(&cfg, replayPaths, *replayData); err != nil {
+			log.Fatalln("Replay failed:", err)
+		}
+		return
 	}
 
-	kvs, err := storage.OpenKomainuBolt("data/komainubolt")
+	kvs, err := storage.OpenStorage(&cfg)
 	if err != nil {
 		log.Fatalln("Could not open KVS:", err)
 	}
 	defer kvs.Close()
 
+	var store storage.KeyValueStore = kvs
+	if cfg.MetricsAddress != "" {
+		store = metrics.InstrumentKVS(kvs)
+		go metrics.Serve(cfg.MetricsAddress)
+	}
+	if cfg.APIAddress != "" {
+		go webapi.Serve(store, cfg.APIAddress)
+	}
+	if interval := cfg.CompactInterval(); interval > 0 {
+		go storage.StartCompacting(store, interval)
+	}
+
+	module.Register(interactions.Modules()...)
+	if err := module.Load(store, cfg.ModuleEnabled); err != nil {
+		log.Fatalln("Could not load modules:", err)
+	}
+
 	log.Println("Preparing to connect to Discord")
 
-	state := bot.Connect(&cfg, kvs)
-	defer state.Close()
+	shards := bot.Connect(&cfg, store)
+	defer shards.Close()
+
+	go storage.WatchConfiguration(&cfg, func(old storage.Configuration, current storage.Configuration) {
+		if old.LogLevel != current.LogLevel || old.LogJSON != current.LogJSON {
+			setupLogging(logOutput, current)
+			log.Printf("Now logging at level %q, JSON: %t", current.LogLevel, current.LogJSON)
+		}
+		errorreport.Setup(errorReportSettings(current))
+		command.SetOwners(current.OwnerIDs)
+		storage.SetFeatureDefaults(current.Features)
+	})
 
 	WaitForInterrupt()
 
 }
 
+// setupLogging sets up logging as configured, with everything logged as an error reported, too.
+func setupLogging(out io.Writer, cfg storage.Configuration) {
+	logging.Setup(out, cfg.LogLevel, cfg.LogJSON, os.Getenv("DEV_MODE") != "")
+	slog.SetDefault(slog.New(errorreport.Handler(slog.Default().Handler())))
+}
+
+func errorReportSettings(cfg storage.Configuration) errorreport.Settings {
+	return errorreport.Settings{
+		SentryDSN:  cfg.SentryDSN,
+		WebhookURL: cfg.ErrorWebhookURL,
+		SampleRate: cfg.ErrorSampleRate,
+		Scrub:      cfg.ErrorScrub,
+	}
+}
+
+// replayInteractions replays the recorded interactions, writing what was done about them to STDOUT. Nothing is sent
+// to Discord, and nothing is stored, as it's all done against a copy of storage, or nothing at all.
+func replayInteractions(cfg *storage.Configuration, paths []string, withData bool) error {
+	files, err := replay.Files(paths)
+	if err != nil {
+		return fmt.Errorf("could not find the recordings: %w", err)
+	}
+	if len(files) == 0 {
+		return errors.New("nothing to replay. Give the recordings, or the directory they're in, after -replay")
+	}
+
+	var kvs storage.KeyValueStore = storage.OpenMemory()
+	if withData {
+		copied := *cfg
+		copied.RedisURL = ""
+		copied.StoragePath = filepath.Join(os.TempDir(), fmt.Sprintf("komainu-replay-%d", os.Getpid()))
+		if err := copyFile(cfg.Storage(), copied.StoragePath); err != nil {
+			return fmt.Errorf("could not copy storage: %w", err)
+		}
+		defer os.Remove(copied.StoragePath)
+		if kvs, err = storage.OpenStorage(&copied); err != nil {
+			return fmt.Errorf("could not open the copy of storage: %w", err)
+		}
+	}
+	defer kvs.Close()
+
+	module.Register(interactions.Modules()...)
+	return replay.Run(files, kvs, cfg.ModuleEnabled, os.Stdout)
+}
+
+// copyFile copies the file at from to a new file at to.
+func copyFile(from string, to string) error {
+	source, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	destination, err := os.OpenFile(to, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(destination, source); err != nil {
+		destination.Close()
+		return err
+	}
+	return destination.Close()
+}
+
 // WaitForInterrupt blocks until a SIGINT, SIGTERM or another OS interrupt is received.
 // "Pause until Ctrl+C", basically.
 func WaitForInterrupt() {
@@ -0,0 +1,177 @@
+// Package membercache keeps the full member list of each guild, so commands going through everyone don't have to page
+// through the REST API every time.
+//
+// The first time a guild's members are wanted, they are requested over the gateway, and Discord sends them back in
+// chunks of up to a thousand. After that, joins, leaves and member updates keep the list current, and it's fetched
+// again only once it's gotten old, in case something was missed while the connection was down.
+package membercache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/ws"
+)
+
+// MaxAge is how long a member list is trusted before it's fetched again.
+const MaxAge = 6 * time.Hour
+
+// RequestTimeout is how long the chunks get to arrive before the members are requested again.
+const RequestTimeout = time.Minute
+
+// guildMembers is the member list of a single guild, either complete, or on its way in chunks.
+type guildMembers struct {
+	members   map[discord.UserID]discord.Member
+	nonce     string        // Tells the chunks answering the latest request apart from anything older.
+	chunks    int           // How many chunks have arrived for the latest request.
+	done      chan struct{} // Closed once every chunk has arrived.
+	complete  bool
+	requested time.Time
+	fetched   time.Time
+}
+
+// requestMembers is the gateway command asking for everyone in a guild. The one arikawa has leaves the query and
+// limit out when they're blank, and sends the guild as a list, while Discord wants a single guild with both set.
+type requestMembers struct {
+	GuildID discord.GuildID `json:"guild_id"`
+	Query   string          `json:"query"`
+	Limit   uint            `json:"limit"`
+	Nonce   string          `json:"nonce"`
+}
+
+func (*requestMembers) Op() ws.OpCode           { return 8 }
+func (*requestMembers) EventType() ws.EventType { return "" }
+
+var (
+	lock   sync.Mutex
+	guilds = map[discord.GuildID]*guildMembers{}
+	nonces atomic.Int64
+)
+
+// AddHandler has the state keep the member lists up to date.
+func AddHandler(state *state.State) {
+	state.AddHandler(func(event *gateway.GuildMembersChunkEvent) {
+		lock.Lock()
+		defer lock.Unlock()
+		guild, ok := guilds[event.GuildID]
+		if !ok || guild.complete || guild.nonce != event.Nonce {
+			return // Not something asked for, or an answer to an older request.
+		}
+		for _, member := range event.Members {
+			guild.members[member.User.ID] = member
+		}
+		guild.chunks++
+		if guild.chunks >= event.ChunkCount {
+			guild.complete = true
+			guild.fetched = time.Now()
+			close(guild.done)
+		}
+	})
+	state.AddHandler(func(event *gateway.GuildMemberAddEvent) {
+		update(event.GuildID, func(guild *guildMembers) {
+			guild.members[event.User.ID] = event.Member
+		})
+	})
+	state.AddHandler(func(event *gateway.GuildMemberUpdateEvent) {
+		update(event.GuildID, func(guild *guildMembers) {
+			member, ok := guild.members[event.User.ID]
+			if !ok {
+				member = discord.Member{}
+			}
+			event.UpdateMember(&member)
+			guild.members[event.User.ID] = member
+		})
+	})
+	state.AddHandler(func(event *gateway.GuildMemberRemoveEvent) {
+		update(event.GuildID, func(guild *guildMembers) {
+			delete(guild.members, event.User.ID)
+		})
+	})
+	state.AddHandler(func(event *gateway.GuildDeleteEvent) {
+		lock.Lock()
+		defer lock.Unlock()
+		delete(guilds, event.ID)
+	})
+}
+
+// update changes the member list of the guild, if it's there and complete. A list on its way in will have whatever
+// changed in the chunks, or close enough.
+func update(guildID discord.GuildID, change func(guild *guildMembers)) {
+	lock.Lock()
+	defer lock.Unlock()
+	if guild, ok := guilds[guildID]; ok && guild.complete {
+		change(guild)
+	}
+}
+
+// Get gets everyone in the guild, requesting them over the gateway if they haven't been, or if it's been too long.
+// It waits for the chunks to arrive until ctx is done.
+func Get(ctx context.Context, state *state.State, guildID discord.GuildID) ([]discord.Member, error) {
+	guild, err := request(ctx, state, guildID)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-guild.done:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("gave up waiting for the members of %s: %w", guildID, ctx.Err())
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	members := make([]discord.Member, 0, len(guild.members))
+	for _, member := range guild.members {
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// request asks Discord for the members of the guild, unless that's already done or underway, and gives the member
+// list they go into.
+func request(ctx context.Context, state *state.State, guildID discord.GuildID) (*guildMembers, error) {
+	lock.Lock()
+	guild, ok := guilds[guildID]
+	if ok && !guild.complete && time.Since(guild.requested) < RequestTimeout {
+		lock.Unlock()
+		return guild, nil
+	}
+	if ok && guild.complete && time.Since(guild.fetched) < MaxAge {
+		lock.Unlock()
+		return guild, nil
+	}
+	guild = &guildMembers{
+		members:   map[discord.UserID]discord.Member{},
+		nonce:     strconv.FormatInt(nonces.Add(1), 36),
+		done:      make(chan struct{}),
+		requested: time.Now(),
+	}
+	guilds[guildID] = guild
+	lock.Unlock()
+
+	err := state.Gateway().Send(ctx, &requestMembers{GuildID: guildID, Nonce: guild.nonce})
+	if err != nil {
+		lock.Lock()
+		if guilds[guildID] == guild {
+			delete(guilds, guildID) // So the next one to ask tries again.
+		}
+		lock.Unlock()
+		return nil, fmt.Errorf("failed to request the members of %s: %w", guildID, err)
+	}
+	return guild, nil
+}
+
+// UserIDs gives the user IDs of the members, in the same order.
+func UserIDs(members []discord.Member) []discord.UserID {
+	userIDs := make([]discord.UserID, len(members))
+	for i, member := range members {
+		userIDs[i] = member.User.ID
+	}
+	return userIDs
+}
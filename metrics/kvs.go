@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"komainu/storage"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// kvs times every operation on the KeyValueStore it wraps, and counts the errors.
+type kvs struct {
+	storage.KeyValueStore
+}
+
+// InstrumentKVS wraps the KeyValueStore so every operation on it is timed.
+func InstrumentKVS(store storage.KeyValueStore) storage.KeyValueStore {
+	return kvs{store}
+}
+
+func observe(operation string, start time.Time, err error) {
+	KVSSeconds.Since(start, operation)
+	if err != nil {
+		Errors.Inc("kvs")
+	}
+}
+
+func (k kvs) Set(guild discord.GuildID, collection string, key any, rawValue any) error {
+	start := time.Now()
+	err := k.KeyValueStore.Set(guild, collection, key, rawValue)
+	observe("set", start, err)
+	return err
+}
+
+func (k kvs) Get(guild discord.GuildID, collection string, key any, out any) (bool, error) {
+	start := time.Now()
+	exist, err := k.KeyValueStore.Get(guild, collection, key, out)
+	observe("get", start, err)
+	return exist, err
+}
+
+func (k kvs) GetMany(guild discord.GuildID, collection string, keys []any, out func(i int) any) ([]bool, error) {
+	start := time.Now()
+	exist, err := k.KeyValueStore.GetMany(guild, collection, keys, out)
+	observe("getmany", start, err)
+	return exist, err
+}
+
+func (k kvs) Delete(guild discord.GuildID, collection string, key any) error {
+	start := time.Now()
+	err := k.KeyValueStore.Delete(guild, collection, key)
+	observe("delete", start, err)
+	return err
+}
+
+func (k kvs) Restore(guild discord.GuildID, collection string, key any) (bool, error) {
+	start := time.Now()
+	restored, err := k.KeyValueStore.Restore(guild, collection, key)
+	observe("restore", start, err)
+	return restored, err
+}
+
+func (k kvs) Keys(guild discord.GuildID, collection string) ([]string, error) {
+	start := time.Now()
+	keys, err := k.KeyValueStore.Keys(guild, collection)
+	observe("keys", start, err)
+	return keys, err
+}
+
+func (k kvs) Usage(guild discord.GuildID) (map[string]storage.CollectionUsage, error) {
+	start := time.Now()
+	usage, err := k.KeyValueStore.Usage(guild)
+	observe("usage", start, err)
+	return usage, err
+}
@@ -0,0 +1,184 @@
+// Package metrics keeps count of what the bot is doing, and serves it in the Prometheus text format.
+//
+// This is deliberately tiny, covering just the counters, summaries and gauges the bot needs,
+// rather than pulling in the whole Prometheus client for it.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// Interactions counts the interactions handled, by type (command, component, modal) and name.
+	Interactions = NewCounter("komainu_interactions_total", "Interactions handled, by type and name.", "type", "name")
+	// HandlerSeconds is how long interaction handlers take, by type and name.
+	HandlerSeconds = NewSummary("komainu_handler_seconds", "Time spent in interaction handlers, by type and name.", "type", "name")
+	// KVSSeconds is how long storage operations take, by operation.
+	KVSSeconds = NewSummary("komainu_kvs_seconds", "Time spent on storage operations, by operation.", "operation")
+	// GatewayEvents counts the events received from Discord, by event and shard.
+	GatewayEvents = NewCounter("komainu_gateway_events_total", "Gateway events received, by event and shard.", "event", "shard")
+	// Shed counts the interactions turned away for the bot being too busy, by type.
+	Shed = NewCounter("komainu_interactions_shed_total", "Interactions turned away for being too busy, by type.", "type")
+	// Errors counts errors, by where they happened.
+	Errors = NewCounter("komainu_errors_total", "Errors, by where they happened.", "source")
+)
+
+// family is a metric with all its series, one for each combination of label values.
+type family struct {
+	sync.Mutex
+	name   string
+	help   string
+	kind   string
+	labels []string
+	series map[string][]float64 // Keyed by the rendered labels. Counters have one value, summaries have sum and count.
+}
+
+var registry = struct {
+	sync.Mutex
+	families []*family
+	gauges   []*GaugeFunc
+}{}
+
+func newFamily(name, help, kind string, labels []string) *family {
+	f := &family{name: name, help: help, kind: kind, labels: labels, series: map[string][]float64{}}
+	registry.Lock()
+	registry.families = append(registry.families, f)
+	registry.Unlock()
+	return f
+}
+
+// labelEscaper escapes label values the way the text format wants them.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// renderLabels turns the label values into the {name="value"} part of a series.
+func (f *family) renderLabels(values []string) string {
+	if len(f.labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(f.labels))
+	for i, label := range f.labels {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		value = labelEscaper.Replace(value)
+		pairs[i] = label + `="` + value + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// add adds the deltas to the series for the label values.
+func (f *family) add(values []string, deltas ...float64) {
+	key := f.renderLabels(values)
+	f.Lock()
+	defer f.Unlock()
+	series, ok := f.series[key]
+	if !ok {
+		series = make([]float64, len(deltas))
+		f.series[key] = series
+	}
+	for i, delta := range deltas {
+		series[i] += delta
+	}
+}
+
+func (f *family) write(w io.Writer) {
+	f.Lock()
+	defer f.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		series := f.series[key]
+		if f.kind == "summary" {
+			fmt.Fprintf(w, "%s_sum%s %g\n%s_count%s %g\n", f.name, key, series[0], f.name, key, series[1])
+		} else {
+			fmt.Fprintf(w, "%s%s %g\n", f.name, key, series[0])
+		}
+	}
+}
+
+// Counter is a number that only goes up, like how many times something happened.
+type Counter struct{ f *family }
+
+// NewCounter makes a counter, with the given label names.
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{newFamily(name, help, "counter", labels)}
+}
+
+// Inc adds one to the counter with the given label values.
+func (c *Counter) Inc(values ...string) {
+	c.f.add(values, 1)
+}
+
+// Summary keeps the sum and count of observations, like how long something took in total and how many times it happened.
+type Summary struct{ f *family }
+
+// NewSummary makes a summary, with the given label names.
+func NewSummary(name, help string, labels ...string) *Summary {
+	return &Summary{newFamily(name, help, "summary", labels)}
+}
+
+// Observe adds a single observation to the summary with the given label values.
+func (s *Summary) Observe(value float64, values ...string) {
+	s.f.add(values, value, 1)
+}
+
+// Since observes the time since start, in seconds, for timing things.
+func (s *Summary) Since(start time.Time, values ...string) {
+	s.Observe(time.Since(start).Seconds(), values...)
+}
+
+// GaugeFunc is a number that can go up and down, worked out when the metrics are asked for.
+type GaugeFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+// NewGaugeFunc makes a gauge that calls fn for its value every time the metrics are asked for.
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help, fn: fn}
+	registry.Lock()
+	registry.gauges = append(registry.gauges, g)
+	registry.Unlock()
+	return g
+}
+
+// Write writes all the metrics in the Prometheus text format.
+func Write(w io.Writer) {
+	registry.Lock()
+	families := append([]*family{}, registry.families...)
+	gauges := append([]*GaugeFunc{}, registry.gauges...)
+	registry.Unlock()
+	for _, f := range families {
+		f.write(w)
+	}
+	for _, g := range gauges {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", g.name, g.help, g.name, g.name, g.fn())
+	}
+}
+
+// Serve serves the metrics on /metrics at the given address, like ":9100".
+// Intended to be called as a goroutine.
+func Serve(address string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		Write(w)
+	})
+	log.Printf("Serving metrics on %s", address)
+	if err := http.ListenAndServe(address, mux); err != nil {
+		log.Printf("Metrics server stopped: %s", err)
+	}
+}
@@ -0,0 +1,61 @@
+// Package replay records interactions as they come in, and replays them later, offline. Replaying runs the handlers
+// against a Discord that only writes down what it's asked to do, so whatever happened with an interaction can be seen
+// again, as often as needed, without anyone having to use the command again.
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// unsafeName is anything that shouldn't be in a file name, like the slashes in custom IDs.
+var unsafeName = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// Record writes every interaction the state gets to a JSON file in dir, as it comes in. They hold what people typed,
+// and who they are, so it's for debugging, not for keeping.
+func Record(state *state.State, dir string) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		log.Printf("Not recording interactions, as %s could not be made: %s", dir, err)
+		return
+	}
+	log.Printf("Recording every interaction to %s", dir)
+	state.AddHandler(func(e *gateway.InteractionCreateEvent) {
+		if err := record(dir, e); err != nil {
+			log.Printf("[%s] Failed to record interaction %s: %s", e.GuildID, e.ID, err)
+		}
+	})
+}
+
+func record(dir string, e *gateway.InteractionCreateEvent) error {
+	encoded, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s-%s.json", time.Now().UTC().Format("20060102-150405"), unsafeName.ReplaceAllString(Describe(e), "_"), e.ID)
+	return os.WriteFile(filepath.Join(dir, name), encoded, 0640)
+}
+
+// Describe says what kind of interaction it is, and what for, like "command vote" or "component votecomment/123".
+func Describe(e *gateway.InteractionCreateEvent) string {
+	switch data := e.Data.(type) {
+	case *discord.CommandInteraction:
+		return "command " + data.Name
+	case *discord.AutocompleteInteraction:
+		return "autocomplete " + data.Name
+	case *discord.ModalInteraction:
+		return "modal " + string(data.CustomID)
+	case discord.ComponentInteraction:
+		return "component " + string(data.ID())
+	default:
+		return fmt.Sprintf("%T", data)
+	}
+}
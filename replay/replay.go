@@ -0,0 +1,108 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"komainu/interactions/autocomplete"
+	"komainu/interactions/command"
+	"komainu/interactions/component"
+	"komainu/interactions/modal"
+	"komainu/interactions/module"
+	"komainu/komainutest"
+	"komainu/storage"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/gateway"
+)
+
+// settleTime is how long it has to be since the last call to Discord before an interaction is considered done with.
+// Deferred responses are edited in on another goroutine, a little while after responding.
+const settleTime = 250 * time.Millisecond
+
+// Files lists the recordings at the paths, in the order they were recorded. Directories give every recording in them.
+func Files(paths []string) ([]string, error) {
+	files := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		found, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(found) // They start with when they were recorded.
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// Run replays the recorded interactions in the files, one after the other, writing what was done about each to out.
+// The modules that are enabled are loaded, using kvs, and that's where the handlers store things, too. Give it a copy,
+// or storage.OpenMemory, as the handlers do whatever they do to it.
+func Run(files []string, kvs storage.KeyValueStore, enabled func(name string) bool, out io.Writer) error {
+	if err := module.Load(kvs, enabled); err != nil {
+		return fmt.Errorf("could not load modules: %w", err)
+	}
+	command.SetThrottling(false)
+	d := komainutest.New()
+	command.AddHandler(d.State, kvs)
+	autocomplete.AddHandler(d.State, kvs)
+	modal.AddHandler(d.State, kvs)
+	component.AddHandler(d.State, kvs)
+
+	for _, file := range files {
+		e, err := load(file)
+		if err != nil {
+			return fmt.Errorf("could not load %s: %w", file, err)
+		}
+		fmt.Fprintf(out, "=== %s: %s in %s by %s\n", filepath.Base(file), Describe(e), e.GuildID, e.SenderID())
+		before := len(d.Calls())
+		d.Dispatch(e)
+		if _, ok := d.Response(e); !ok {
+			fmt.Fprintln(out, "(No response at all!)")
+		}
+		calls := settle(d)
+		for _, call := range calls[before:] {
+			fmt.Fprintln(out, call)
+			if len(call.Body) > 0 {
+				fmt.Fprintf(out, "%s\n", bytes.TrimSpace(call.Body))
+			}
+		}
+	}
+	return nil
+}
+
+func load(file string) (*gateway.InteractionCreateEvent, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	e := &gateway.InteractionCreateEvent{}
+	if err := json.Unmarshal(raw, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// settle waits for the handlers to stop calling Discord, and returns every call made so far.
+func settle(d *komainutest.Discord) []komainutest.Call {
+	calls := d.Calls()
+	last := time.Now()
+	for time.Since(last) < settleTime {
+		time.Sleep(settleTime / 10)
+		if now := d.Calls(); len(now) != len(calls) {
+			calls, last = now, time.Now()
+		}
+	}
+	return calls
+}
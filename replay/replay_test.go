@@ -0,0 +1,32 @@
+package replay
+
+import (
+	"bytes"
+	"komainu/interactions"
+	"komainu/interactions/module"
+	"komainu/komainutest"
+	"komainu/storage"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	// The malformed /vote from the "Wat." in the log, which Discord is never supposed to send.
+	if err := record(dir, komainutest.Command("vote")); err != nil {
+		t.Fatalf("Could not record the interaction: %s", err)
+	}
+	files, err := Files([]string{dir})
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected the recording to be found, Got %v (%v)", files, err)
+	}
+
+	module.Register(interactions.Modules()...)
+	var out bytes.Buffer
+	if err := Run(files, storage.OpenMemory(), func(string) bool { return true }, &out); err != nil {
+		t.Fatalf("Could not replay: %s", err)
+	}
+	if !strings.Contains(out.String(), "command vote") || !strings.Contains(out.String(), "that didn't work") {
+		t.Errorf("Expected the replay to show the response to /vote, Got:\n%s", out.String())
+	}
+}
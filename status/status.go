@@ -0,0 +1,134 @@
+// Package status takes turns showing different things as the bot's status, on every shard, unless the owners
+// have set something else for a while with /debug status.
+package status
+
+import (
+	"context"
+	"komainu/storage"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/gateway"
+	"github.com/diamondburned/arikawa/v3/session/shard"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// DefaultStatuses are shown when the configuration doesn't have any Statuses of its own.
+var DefaultStatuses = []string{
+	"Watching {guilds} guilds",
+	"Counting votes",
+	"Listening to /help",
+}
+
+// activityPrefixes are how a status says what kind it is. Anything else is shown just as it is.
+var activityPrefixes = []struct {
+	prefix string
+	kind   discord.ActivityType
+}{
+	{"Playing ", discord.GameActivity},
+	{"Watching ", discord.WatchingActivity},
+	{"Listening to ", discord.ListeningActivity},
+	{"Competing in ", discord.CompetingActivity},
+}
+
+// refresh asks the rotation to update the status right away, rather than wait its turn.
+var refresh = make(chan struct{}, 1)
+
+// Refresh updates the status right away, like after the override is changed.
+func Refresh() {
+	select {
+	case refresh <- struct{}{}:
+	default:
+	}
+}
+
+// Activity makes the activity Discord shows for the status text.
+func Activity(text string) discord.Activity {
+	for _, ap := range activityPrefixes {
+		if strings.HasPrefix(text, ap.prefix) {
+			return discord.Activity{Name: strings.TrimPrefix(text, ap.prefix), Type: ap.kind}
+		}
+	}
+	return discord.Activity{Name: "Custom Status", Type: discord.CustomActivity, State: text}
+}
+
+// fill fills in the placeholders in the status text.
+func fill(manager *shard.Manager, text string) string {
+	if strings.Contains(text, "{guilds}") {
+		guilds := 0
+		manager.ForEach(func(s shard.Shard) {
+			if shardGuilds, err := s.(*state.State).Guilds(); err == nil {
+				guilds += len(shardGuilds)
+			}
+		})
+		text = strings.ReplaceAll(text, "{guilds}", strconv.Itoa(guilds))
+	}
+	return strings.ReplaceAll(text, "{shards}", strconv.Itoa(manager.NumShards()))
+}
+
+// current picks what to show: the override if there is one that hasn't run out, or the turn-th of the statuses.
+// If it's an override that runs out, until is when.
+func current(kvs storage.KeyValueStore, statuses []string, turn int) (text string, until time.Time) {
+	exist, override, err := storage.GetStatusOverride(kvs)
+	if err != nil {
+		log.Printf("Failed to get the status override, so going with the rotation: %s", err)
+	}
+	if exist && override.Until != 0 && override.Until <= time.Now().Unix() {
+		log.Printf("The status override %q ran out", override.Text)
+		if err := storage.ClearStatusOverride(kvs); err != nil {
+			log.Printf("Failed to clear the status override that ran out: %s", err)
+		}
+		exist = false
+	}
+	if exist {
+		if override.Until != 0 {
+			until = time.Unix(override.Until, 0)
+		}
+		return override.Text, until
+	}
+	return statuses[turn%len(statuses)], until
+}
+
+// show sets the status on every shard.
+func show(manager *shard.Manager, text string) {
+	presence := &gateway.UpdatePresenceCommand{
+		Activities: []discord.Activity{Activity(fill(manager, text))},
+		Status:     discord.OnlineStatus,
+	}
+	manager.ForEach(func(s shard.Shard) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.(*state.State).Gateway().Send(ctx, presence); err != nil {
+			log.Printf("Failed to set the status to %q: %s", text, err)
+		}
+	})
+}
+
+// Start shows the next status every StatusInterval, or right away when asked to Refresh or an override runs out.
+// Intended to be called as a goroutine.
+func Start(manager *shard.Manager, kvs storage.KeyValueStore, cfg *storage.Configuration) {
+	turn := 0
+	for {
+		snapshot := cfg.Snapshot()
+		statuses := snapshot.Statuses
+		if len(statuses) == 0 {
+			statuses = DefaultStatuses
+		}
+		text, until := current(kvs, statuses, turn)
+		show(manager, text)
+
+		wait := snapshot.StatusInterval()
+		if !until.IsZero() && time.Until(until) < wait {
+			// Back to the rotation as soon as the override runs out, not whenever the next turn is.
+			wait = time.Until(until)
+		}
+		select {
+		case <-time.After(wait):
+			turn++
+		case <-refresh:
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// AnalyticsDays is how many days of command use are kept for /stats. Anything older is pruned.
+const AnalyticsDays = 30
+
+// CommandUse is a single use of a command in a guild, kept under the ID of the interaction.
+type CommandUse struct {
+	Command string
+	UserID  discord.UserID
+	At      int64 // When it was used, in Unix time.
+	Millis  int64 // How long it took from the command coming in to the response going out.
+	Failed  bool  // If it took too long, or the response didn't make it.
+}
+
+// CommandTotal is how much a command was used on a single day, across every guild, kept in GlobalScope.
+type CommandTotal struct {
+	Day       string // As 2006-01-02, in UTC.
+	Command   string
+	Count     int
+	Failed    int
+	Millis    int64 // All of them added up.
+	MaxMillis int64
+}
+
+// analyticsLock keeps two shards from counting the same day and command at once, and losing one of them.
+var analyticsLock sync.Mutex
+
+func analyticsDay(when time.Time) string {
+	return when.UTC().Format("2006-01-02")
+}
+
+// RecordCommandUse keeps the use of the command, both in the guild and in the totals for the day.
+// Commands used in private only count towards the totals, as there's no guild to keep them in.
+func RecordCommandUse(kvs KeyValueStore, guildID discord.GuildID, interactionID discord.InteractionID, use CommandUse) error {
+	if guildID != discord.NullGuildID {
+		if err := kvs.Set(guildID, "analytics", interactionID, use); err != nil {
+			return fmt.Errorf("could not store the use of /%s: %w", use.Command, err)
+		}
+	}
+
+	analyticsLock.Lock()
+	defer analyticsLock.Unlock()
+	day := analyticsDay(interactionID.Time())
+	key := day + " " + use.Command
+	total := CommandTotal{Day: day, Command: use.Command}
+	if _, err := kvs.Get(GlobalScope, "analytics", key, &total); err != nil {
+		return fmt.Errorf("could not get the totals for /%s: %w", use.Command, err)
+	}
+	total.Count++
+	if use.Failed {
+		total.Failed++
+	}
+	total.Millis += use.Millis
+	if use.Millis > total.MaxMillis {
+		total.MaxMillis = use.Millis
+	}
+	if err := kvs.Set(GlobalScope, "analytics", key, total); err != nil {
+		return fmt.Errorf("could not update the totals for /%s: %w", use.Command, err)
+	}
+	return nil
+}
+
+// CommandUses gets every use of a command in the guild since the given time.
+func CommandUses(kvs KeyValueStore, guildID discord.GuildID, since time.Time) ([]CommandUse, error) {
+	keys, err := kvs.Keys(guildID, "analytics")
+	if err != nil {
+		return nil, err
+	}
+	lookup := []any{}
+	for _, key := range keys {
+		// The key is the ID of the interaction, which says when it was, so there's no need to look at the rest.
+		snowflake, err := discord.ParseSnowflake(key)
+		if err == nil && !snowflake.Time().Before(since) {
+			lookup = append(lookup, key)
+		}
+	}
+	found := make([]CommandUse, len(lookup))
+	exist, err := kvs.GetMany(guildID, "analytics", lookup, func(i int) any { return &found[i] })
+	if err != nil {
+		return nil, err
+	}
+	uses := []CommandUse{}
+	for i := range found {
+		if exist[i] {
+			uses = append(uses, found[i])
+		}
+	}
+	return uses, nil
+}
+
+// CommandTotals gets how much each command was used each day since the given time, across every guild.
+func CommandTotals(kvs KeyValueStore, since time.Time) ([]CommandTotal, error) {
+	keys, err := kvs.Keys(GlobalScope, "analytics")
+	if err != nil {
+		return nil, err
+	}
+	first := analyticsDay(since)
+	lookup := []any{}
+	for _, key := range keys {
+		// The day comes first in the key, and sorts as text.
+		if day, _, _ := strings.Cut(key, " "); day >= first {
+			lookup = append(lookup, key)
+		}
+	}
+	found := make([]CommandTotal, len(lookup))
+	exist, err := kvs.GetMany(GlobalScope, "analytics", lookup, func(i int) any { return &found[i] })
+	if err != nil {
+		return nil, err
+	}
+	totals := []CommandTotal{}
+	for i := range found {
+		if exist[i] {
+			totals = append(totals, found[i])
+		}
+	}
+	return totals, nil
+}
+
+// PruneAnalytics forgets command use older than AnalyticsDays, in the guilds on the shard and in the totals.
+func PruneAnalytics(state *state.State, kvs KeyValueStore) error {
+	cutoff := time.Now().AddDate(0, 0, -AnalyticsDays)
+	guilds, err := state.Guilds()
+	if err != nil {
+		return fmt.Errorf("pruning analytics could not fetch current guilds: %w", err)
+	}
+	for _, guild := range guilds {
+		err := GuildJob(kvs, guild.ID, "analytics", time.Hour, func() error {
+			keys, err := kvs.Keys(guild.ID, "analytics")
+			if err != nil {
+				return fmt.Errorf("pruning analytics could not get keys for guild: %w", err)
+			}
+			for _, key := range keys {
+				snowflake, err := discord.ParseSnowflake(key)
+				if err == nil && !snowflake.Time().Before(cutoff) {
+					continue
+				}
+				if err := kvs.Delete(guild.ID, "analytics", key); err != nil {
+					return fmt.Errorf("pruning analytics could not delete a command use: %w", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return GuildJob(kvs, GlobalScope, "analytics", time.Hour, func() error {
+		keys, err := kvs.Keys(GlobalScope, "analytics")
+		if err != nil {
+			return fmt.Errorf("pruning analytics could not get keys for the totals: %w", err)
+		}
+		last := analyticsDay(cutoff)
+		for _, key := range keys {
+			if day, _, _ := strings.Cut(key, " "); day >= last {
+				continue
+			}
+			if err := kvs.Delete(GlobalScope, "analytics", key); err != nil {
+				return fmt.Errorf("pruning analytics could not delete a total: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// StartPruningAnalytics starts a ticker and, once an hour, calls PruneAnalytics.
+// Intended to be called as a goroutine.
+func StartPruningAnalytics(state *state.State, kvs KeyValueStore) {
+	ticker := time.NewTicker(1 * time.Hour)
+	for {
+		<-ticker.C
+		if err := PruneAnalytics(state, kvs); err != nil {
+			log.Printf("Error encountered pruning analytics: %s", err)
+		}
+	}
+}
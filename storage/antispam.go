@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+const (
+	AntispamTimeout  = "timeout"
+	AntispamSlowmode = "slowmode"
+
+	RaidLockdown = "lockdown"
+	RaidReport   = "report"
+)
+
+// AntispamConfig is how spam and raids are detected in a guild, and what is done about them.
+type AntispamConfig struct {
+	Enabled         bool
+	MessageLimit    int   // This many messages...
+	MessageWindow   int64 // ...in this many seconds is spam.
+	DuplicateLimit  int   // This many identical messages within the window is spam too.
+	SpamAction      string
+	TimeoutSeconds  int64
+	SlowmodeSeconds int64
+	JoinLimit       int   // This many joins...
+	JoinWindow      int64 // ...in this many seconds is a raid.
+	RaidAction      string
+	LogChannelID    discord.ChannelID
+}
+
+// GetAntispamConfig gets the antispam setup for the guild, or the defaults if there isn't one.
+func GetAntispamConfig(kvs KeyValueStore, guildID discord.GuildID) (AntispamConfig, error) {
+	config := AntispamConfig{
+		MessageLimit:    8,
+		MessageWindow:   10,
+		DuplicateLimit:  4,
+		SpamAction:      AntispamTimeout,
+		TimeoutSeconds:  10 * 60,
+		SlowmodeSeconds: 10,
+		JoinLimit:       10,
+		JoinWindow:      60,
+		RaidAction:      RaidReport,
+	}
+	// gob leaves out zero values, so anything stored as zero comes back as the default. Don't let people set zeroes.
+	_, err := kvs.Get(guildID, "antispam", "config", &config)
+	return config, err
+}
+
+// SetAntispamConfig stores the antispam setup for the guild.
+func SetAntispamConfig(kvs KeyValueStore, guildID discord.GuildID, config AntispamConfig) error {
+	return kvs.Set(guildID, "antispam", "config", config)
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// APIToken is the token a guild uses for the REST API. Only a hash of it is kept.
+type APIToken struct {
+	Hash      string
+	Write     bool // If the token can change things, and not just read them.
+	CreatedBy discord.UserID
+	Created   int64
+}
+
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewAPIToken makes a new REST API token for the guild, replacing any it had, and returns it.
+// This is the only time the token itself is around, so it has to be handed over right away.
+func NewAPIToken(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID, write bool) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+	apiToken := APIToken{
+		Hash:      hashAPIToken(token),
+		Write:     write,
+		CreatedBy: userID,
+		Created:   time.Now().Unix(),
+	}
+	if err := kvs.Set(guildID, "apitoken", "token", apiToken); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// GetAPIToken gets what is known about the REST API token of the guild, if it has one.
+func GetAPIToken(kvs KeyValueStore, guildID discord.GuildID) (exist bool, apiToken APIToken, err error) {
+	exist, err = kvs.Get(guildID, "apitoken", "token", &apiToken)
+	return
+}
+
+// RevokeAPIToken removes the REST API token of the guild, if it has one.
+func RevokeAPIToken(kvs KeyValueStore, guildID discord.GuildID) error {
+	return kvs.Delete(guildID, "apitoken", "token")
+}
+
+// CheckAPIToken checks if the token is the REST API token of the guild, and if so, if it can write.
+func CheckAPIToken(kvs KeyValueStore, guildID discord.GuildID, token string) (valid bool, write bool, err error) {
+	apiToken := APIToken{}
+	exist, err := kvs.Get(guildID, "apitoken", "token", &apiToken)
+	if err != nil || !exist || token == "" {
+		return false, false, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashAPIToken(token)), []byte(apiToken.Hash)) != 1 {
+		return false, false, nil
+	}
+	return true, apiToken.Write, nil
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"fmt"
+	"komainu/bus"
+	"log"
+	"sort"
+	"strconv"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// auditLogKeep is how many entries the audit log keeps per guild. Older ones are forgotten as new ones come in.
+const auditLogKeep = 1000
+
+// AuditEntry is something that happened, as the audit log remembers it.
+type AuditEntry struct {
+	ID      int64
+	Event   string
+	UserID  discord.UserID
+	Summary string
+	Time    int64
+}
+
+// SubscribeAuditLog makes the audit log remember every event.
+func SubscribeAuditLog(kvs KeyValueStore) {
+	bus.Subscribe(bus.Everything, func(event bus.Event) {
+		if err := addAuditEntry(kvs, event); err != nil {
+			log.Printf("[%s] Could not add %s to the audit log: %s", event.GuildID, event.Name, err)
+		}
+	})
+}
+
+func addAuditEntry(kvs KeyValueStore, event bus.Event) error {
+	id, err := NextNumber(kvs, event.GuildID, "auditlog")
+	if err != nil {
+		return err
+	}
+	entry := AuditEntry{
+		ID:      id,
+		Event:   event.Name,
+		UserID:  event.UserID,
+		Summary: event.Summary,
+		Time:    event.Time.Unix(),
+	}
+	if err := kvs.Set(event.GuildID, "auditlog", id, entry); err != nil {
+		return err
+	}
+	if id > auditLogKeep {
+		return kvs.Delete(event.GuildID, "auditlog", id-auditLogKeep)
+	}
+	return nil
+}
+
+// GetAuditLog gets the latest entries in the audit log, newest first, and at most limit of them.
+func GetAuditLog(kvs KeyValueStore, guildID discord.GuildID, limit int) ([]AuditEntry, error) {
+	keys, err := kvs.Keys(guildID, "auditlog")
+	if err != nil {
+		return nil, fmt.Errorf("getting the audit log could not get keys: %w", err)
+	}
+	ids := make([]int64, 0, len(keys))
+	for _, key := range keys {
+		if id, err := strconv.ParseInt(key, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] > ids[j]
+	})
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	entries := []AuditEntry{}
+	for _, id := range ids {
+		entry := AuditEntry{}
+		exist, err := kvs.Get(guildID, "auditlog", id, &entry)
+		if err != nil {
+			return nil, fmt.Errorf("getting the audit log could not get entry %d: %w", id, err)
+		}
+		if exist {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
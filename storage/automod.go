@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+const (
+	AutomodWord   = "word"
+	AutomodRegex  = "regex"
+	AutomodInvite = "invite"
+
+	AutomodDelete  = "delete"
+	AutomodWarn    = "warn"
+	AutomodTimeout = "timeout"
+)
+
+// AutomodFilter is one thing automod looks for in messages, and what it does about it.
+type AutomodFilter struct {
+	ID      int64
+	GuildID discord.GuildID
+	Kind    string
+	Pattern string // The word or regular expression. Unused for invites.
+	Action  string
+}
+
+// Describe returns a short human readable description of what the filter looks for.
+func (filter *AutomodFilter) Describe() string {
+	switch filter.Kind {
+	case AutomodInvite:
+		return "invite links"
+	case AutomodRegex:
+		return fmt.Sprintf("regex `%s`", filter.Pattern)
+	default:
+		return fmt.Sprintf("word `%s`", filter.Pattern)
+	}
+}
+
+// AutomodConfig is how automod behaves in a guild, apart from the filters themselves.
+type AutomodConfig struct {
+	LogChannelID   discord.ChannelID
+	TimeoutSeconds int64
+	Exempt         map[discord.ChannelID]bool
+}
+
+// GetAutomodConfig gets the automod setup for the guild, or an empty one.
+func GetAutomodConfig(kvs KeyValueStore, guildID discord.GuildID) (config AutomodConfig, err error) {
+	_, err = kvs.Get(guildID, "automod", "config", &config)
+	if config.Exempt == nil {
+		config.Exempt = map[discord.ChannelID]bool{} // gob doesn't bother with empty maps.
+	}
+	return
+}
+
+// SetAutomodConfig stores the automod setup for the guild.
+func SetAutomodConfig(kvs KeyValueStore, guildID discord.GuildID, config AutomodConfig) error {
+	return kvs.Set(guildID, "automod", "config", config)
+}
+
+// AddAutomodFilter gives the filter a number, and stores it.
+func AddAutomodFilter(kvs KeyValueStore, filter *AutomodFilter) error {
+	id, err := NextNumber(kvs, filter.GuildID, "automodfilters")
+	if err != nil {
+		return fmt.Errorf("adding automod filter could not get a number: %w", err)
+	}
+	filter.ID = id
+	return kvs.Set(filter.GuildID, "automodfilters", filter.ID, filter)
+}
+
+// RemoveAutomodFilter removes the filter with the given number, and reports if it existed.
+func RemoveAutomodFilter(kvs KeyValueStore, guildID discord.GuildID, id int64) (bool, error) {
+	filter := AutomodFilter{}
+	exist, err := kvs.Get(guildID, "automodfilters", id, &filter)
+	if err != nil || !exist {
+		return exist, err
+	}
+	return true, kvs.Delete(guildID, "automodfilters", id)
+}
+
+// GetAutomodFilters gets all the filters in the guild, in the order they were added.
+func GetAutomodFilters(kvs KeyValueStore, guildID discord.GuildID) ([]AutomodFilter, error) {
+	keys, err := kvs.Keys(guildID, "automodfilters")
+	if err != nil {
+		return nil, fmt.Errorf("getting automod filters could not get keys: %w", err)
+	}
+	filters := []AutomodFilter{}
+	for _, key := range keys {
+		filter := AutomodFilter{}
+		exist, err := kvs.Get(guildID, "automodfilters", key, &filter)
+		if err != nil {
+			return nil, fmt.Errorf("getting automod filters could not get %s: %w", key, err)
+		}
+		if exist {
+			filters = append(filters, filter)
+		}
+	}
+	sort.Slice(filters, func(i, j int) bool {
+		return filters[i].ID < filters[j].ID
+	})
+	return filters, nil
+}
+
+const (
+	AutomodLinks       = "links"
+	AutomodAttachments = "attachments"
+
+	AutomodAllowOnly = "allow"
+	AutomodBlock     = "deny"
+)
+
+// AutomodListRule restricts what domains can be linked, or what kinds of files can be attached, in a channel.
+// A rule for the null channel applies to every channel that doesn't have its own.
+type AutomodListRule struct {
+	ChannelID discord.ChannelID
+	Kind      string   // AutomodLinks or AutomodAttachments
+	Mode      string   // AutomodAllowOnly or AutomodBlock
+	Entries   []string // Domains, or file extensions without the dot, all lower case.
+	Action    string
+}
+
+// Where returns a short human readable description of where the rule applies.
+func (rule *AutomodListRule) Where() string {
+	if rule.ChannelID.IsValid() {
+		return "in " + rule.ChannelID.Mention()
+	}
+	return "everywhere"
+}
+
+// Describe returns a short human readable description of the rule.
+func (rule *AutomodListRule) Describe() string {
+	what := "links to"
+	if rule.Kind == AutomodAttachments {
+		what = "attachments of type"
+	}
+	how := "blocking"
+	if rule.Mode == AutomodAllowOnly {
+		how = "only allowing"
+	}
+	return fmt.Sprintf("%s, %s %s `%s`", rule.Where(), how, what, strings.Join(rule.Entries, "`, `"))
+}
+
+// Allows checks if the domain or extension passes the rule.
+// Domains also match their subdomains, so allowing example.com allows www.example.com too.
+func (rule *AutomodListRule) Allows(value string) bool {
+	value = strings.ToLower(value)
+	listed := false
+	for _, entry := range rule.Entries {
+		if value == entry || (rule.Kind == AutomodLinks && strings.HasSuffix(value, "."+entry)) {
+			listed = true
+			break
+		}
+	}
+	if rule.Mode == AutomodAllowOnly {
+		return listed
+	}
+	return !listed
+}
+
+func automodListRuleKey(channelID discord.ChannelID, kind string) string {
+	return fmt.Sprintf("%s/%s", channelID, kind)
+}
+
+// SetAutomodListRule stores the rule, replacing any earlier rule of the same kind for the same channel.
+func SetAutomodListRule(kvs KeyValueStore, guildID discord.GuildID, rule AutomodListRule) error {
+	return kvs.Set(guildID, "automodlists", automodListRuleKey(rule.ChannelID, rule.Kind), rule)
+}
+
+// RemoveAutomodListRule removes the rule of the given kind for the channel, and reports if it existed.
+func RemoveAutomodListRule(kvs KeyValueStore, guildID discord.GuildID, channelID discord.ChannelID, kind string) (bool, error) {
+	key := automodListRuleKey(channelID, kind)
+	exist, err := kvs.Get(guildID, "automodlists", key, &AutomodListRule{})
+	if err != nil || !exist {
+		return exist, err
+	}
+	return true, kvs.Delete(guildID, "automodlists", key)
+}
+
+// GetAutomodListRules gets all the list rules in the guild.
+func GetAutomodListRules(kvs KeyValueStore, guildID discord.GuildID) ([]AutomodListRule, error) {
+	keys, err := kvs.Keys(guildID, "automodlists")
+	if err != nil {
+		return nil, fmt.Errorf("getting automod list rules could not get keys: %w", err)
+	}
+	rules := []AutomodListRule{}
+	for _, key := range keys {
+		rule := AutomodListRule{}
+		exist, err := kvs.Get(guildID, "automodlists", key, &rule)
+		if err != nil {
+			return nil, fmt.Errorf("getting automod list rules could not get %s: %w", key, err)
+		}
+		if exist {
+			rules = append(rules, rule)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].ChannelID != rules[j].ChannelID {
+			return rules[i].ChannelID < rules[j].ChannelID
+		}
+		return rules[i].Kind < rules[j].Kind
+	})
+	return rules, nil
+}
+
+// AutomodListRulesFor picks the rules that apply in the channel: its own rule of each kind, or the guild-wide one if it has none.
+func AutomodListRulesFor(rules []AutomodListRule, channelID discord.ChannelID) []AutomodListRule {
+	picked := map[string]AutomodListRule{}
+	for _, rule := range rules {
+		if rule.ChannelID == channelID {
+			picked[rule.Kind] = rule
+		} else if _, ok := picked[rule.Kind]; !ok && !rule.ChannelID.IsValid() {
+			picked[rule.Kind] = rule
+		}
+	}
+	applied := []AutomodListRule{}
+	for _, kind := range []string{AutomodLinks, AutomodAttachments} {
+		if rule, ok := picked[kind]; ok {
+			applied = append(applied, rule)
+		}
+	}
+	return applied
+}
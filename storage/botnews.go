@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// Where each guild wants news about the bot is kept in GlobalScope, so a broadcast can find them all without
+// having to know which guilds there are.
+
+// SetBotNewsChannel makes the guild get news about the bot, like maintenance and changes, in the given channel.
+func SetBotNewsChannel(kvs KeyValueStore, guildID discord.GuildID, channelID discord.ChannelID) error {
+	return kvs.Set(GlobalScope, "botnews", guildID, channelID)
+}
+
+// ClearBotNewsChannel makes the guild stop getting news about the bot.
+func ClearBotNewsChannel(kvs KeyValueStore, guildID discord.GuildID) error {
+	return kvs.Delete(GlobalScope, "botnews", guildID)
+}
+
+// GetBotNewsChannel gets where the guild wants news about the bot, if anywhere.
+func GetBotNewsChannel(kvs KeyValueStore, guildID discord.GuildID) (exist bool, channelID discord.ChannelID, err error) {
+	exist, err = kvs.Get(GlobalScope, "botnews", guildID, &channelID)
+	return
+}
+
+// BotNewsChannels gets where every guild that wants news about the bot wants it.
+func BotNewsChannels(kvs KeyValueStore) (map[discord.GuildID]discord.ChannelID, error) {
+	keys, err := kvs.Keys(GlobalScope, "botnews")
+	if err != nil {
+		return nil, err
+	}
+	guildIDs := make([]discord.GuildID, 0, len(keys))
+	for _, key := range keys {
+		snowflake, err := discord.ParseSnowflake(key)
+		if err != nil {
+			continue
+		}
+		guildIDs = append(guildIDs, discord.GuildID(snowflake))
+	}
+	lookup := make([]any, len(guildIDs))
+	for i, guildID := range guildIDs {
+		lookup[i] = guildID
+	}
+	channelIDs := make([]discord.ChannelID, len(guildIDs))
+	exist, err := kvs.GetMany(GlobalScope, "botnews", lookup, func(i int) any { return &channelIDs[i] })
+	if err != nil {
+		return nil, err
+	}
+	channels := make(map[discord.GuildID]discord.ChannelID, len(guildIDs))
+	for i, guildID := range guildIDs {
+		if exist[i] {
+			channels[guildID] = channelIDs[i]
+		}
+	}
+	return channels, nil
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// unlockPermissions is what a role gets when a channel is unlocked for them.
+const unlockPermissions = discord.PermissionViewChannel | discord.PermissionSendMessages | discord.PermissionReadMessageHistory
+
+// ChannelUnlock remembers what a role's permission overwrite in a channel was before it was unlocked, so it can be put back.
+type ChannelUnlock struct {
+	ID           discord.MessageID // The message of the vote that caused the unlock.
+	GuildID      discord.GuildID
+	ChannelID    discord.ChannelID
+	RoleID       discord.RoleID
+	HadOverwrite bool
+	Allow        discord.Permissions
+	Deny         discord.Permissions
+	Unlocked     int64
+}
+
+// GetChannelUnlock gets the unlock with the given ID, if it exists.
+func GetChannelUnlock(kvs KeyValueStore, guildID discord.GuildID, id discord.MessageID) (exist bool, unlock ChannelUnlock, err error) {
+	exist, err = kvs.Get(guildID, "channelunlocks", id, &unlock)
+	return
+}
+
+// UnlockChannel grants the role access to the channel, and stores what it was before so RevertChannelUnlock can undo it.
+func UnlockChannel(state *state.State, kvs KeyValueStore, guildID discord.GuildID, id discord.MessageID, channelID discord.ChannelID, roleID discord.RoleID) error {
+	channel, err := state.Channel(channelID)
+	if err != nil {
+		return fmt.Errorf("unlocking channel could not get the channel: %w", err)
+	}
+	unlock := ChannelUnlock{
+		ID:        id,
+		GuildID:   guildID,
+		ChannelID: channelID,
+		RoleID:    roleID,
+		Unlocked:  time.Now().Unix(),
+	}
+	for _, overwrite := range channel.Overwrites {
+		if overwrite.Type == discord.OverwriteRole && overwrite.ID == discord.Snowflake(roleID) {
+			unlock.HadOverwrite = true
+			unlock.Allow = overwrite.Allow
+			unlock.Deny = overwrite.Deny
+		}
+	}
+	// Storing it first, because an unlock we can't revert is worse than one that didn't happen.
+	if err := kvs.Set(guildID, "channelunlocks", id, unlock); err != nil {
+		return fmt.Errorf("unlocking channel could not store the previous state: %w", err)
+	}
+	return state.EditChannelPermission(channelID, discord.Snowflake(roleID), api.EditChannelPermissionData{
+		Type:           discord.OverwriteRole,
+		Allow:          unlock.Allow | unlockPermissions,
+		Deny:           unlock.Deny &^ unlockPermissions,
+		AuditLogReason: api.AuditLogReason("Channel unlocked by vote"),
+	})
+}
+
+// RevertChannelUnlock puts the permission overwrite back the way it was before the given unlock, and forgets about the unlock.
+func RevertChannelUnlock(state *state.State, kvs KeyValueStore, guildID discord.GuildID, id discord.MessageID) (exist bool, unlock ChannelUnlock, err error) {
+	exist, unlock, err = GetChannelUnlock(kvs, guildID, id)
+	if err != nil || !exist {
+		return
+	}
+	if unlock.HadOverwrite {
+		err = state.EditChannelPermission(unlock.ChannelID, discord.Snowflake(unlock.RoleID), api.EditChannelPermissionData{
+			Type:           discord.OverwriteRole,
+			Allow:          unlock.Allow,
+			Deny:           unlock.Deny,
+			AuditLogReason: api.AuditLogReason("Channel unlock reverted"),
+		})
+	} else {
+		err = state.DeleteChannelPermission(unlock.ChannelID, discord.Snowflake(unlock.RoleID), api.AuditLogReason("Channel unlock reverted"))
+	}
+	if err != nil {
+		return exist, unlock, fmt.Errorf("reverting channel unlock could not restore the permissions: %w", err)
+	}
+	err = kvs.Delete(guildID, "channelunlocks", id)
+	return
+}
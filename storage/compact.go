@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	bolt "go.etcd.io/bbolt"
+)
+
+// compactTxSize is how much is copied in each transaction while compacting, so it doesn't all have to fit in memory.
+const compactTxSize = 64 * 1024 * 1024
+
+// Guilds lists every guild, and private scope, that has anything stored, sorted.
+func (kb *komainuBolt) Guilds() (guilds []discord.GuildID, err error) {
+	err = kb.view(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			snowflake, err := discord.ParseSnowflake(string(name))
+			if err != nil {
+				return nil // Not a guild, so it's none of our business.
+			}
+			guilds = append(guilds, discord.GuildID(snowflake))
+			return nil
+		})
+	})
+	sort.Slice(guilds, func(i, j int) bool {
+		return guilds[i] < guilds[j]
+	})
+	return
+}
+
+// Compact copies everything into a fresh database file and swaps it in, as bolt never gives back the space freed by
+// deleting things. Everything else using storage waits while it happens. Returns the file size before and after.
+func (kb *komainuBolt) Compact() (before int64, after int64, err error) {
+	kb.lock.Lock()
+	defer kb.lock.Unlock()
+
+	if info, err := os.Stat(kb.path); err == nil {
+		before = info.Size()
+	}
+	compactPath := kb.path + ".compact"
+	os.Remove(compactPath) // Left over from a compaction that went wrong, if it's there.
+	compacted, err := bolt.Open(compactPath, 0660, nil)
+	if err != nil {
+		return before, 0, fmt.Errorf("unable to open a file to compact into: %w", err)
+	}
+	if err := bolt.Compact(compacted, kb.bolt, compactTxSize); err != nil {
+		compacted.Close()
+		os.Remove(compactPath)
+		return before, 0, fmt.Errorf("unable to compact: %w", err)
+	}
+	if err := compacted.Close(); err != nil {
+		os.Remove(compactPath)
+		return before, 0, fmt.Errorf("unable to close the compacted file: %w", err)
+	}
+
+	if err := kb.bolt.Close(); err != nil {
+		os.Remove(compactPath)
+		return before, 0, fmt.Errorf("unable to close the database to swap it out: %w", err)
+	}
+	swapErr := os.Rename(compactPath, kb.path)
+	reopened, err := bolt.Open(kb.path, 0660, nil)
+	if err != nil {
+		// Nothing works without it, so there's no point going on.
+		log.Fatalf("Could not open the database again after compacting: %s", err)
+	}
+	kb.bolt = reopened
+	if swapErr != nil {
+		os.Remove(compactPath)
+		return before, 0, fmt.Errorf("unable to swap in the compacted file: %w", swapErr)
+	}
+
+	if info, err := os.Stat(kb.path); err == nil {
+		after = info.Size()
+	}
+	return before, after, nil
+}
+
+// StartCompacting starts a ticker and, every interval, compacts storage.
+// Intended to be called as a goroutine.
+func StartCompacting(kvs KeyValueStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	for {
+		<-ticker.C
+		before, after, err := kvs.Compact()
+		if err != nil {
+			log.Printf("Error encountered compacting storage: %s", err)
+			continue
+		}
+		log.Printf("Compacted storage from %d KB to %d KB", before/1024, after/1024)
+	}
+}
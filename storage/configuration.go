@@ -1,6 +1,15 @@
 package storage
 
-import "log"
+import (
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
 
 // GetConfiguration gets a freshly loaded configuration.
 func GetConfiguration() Configuration {
@@ -11,8 +20,155 @@ func GetConfiguration() Configuration {
 	return cfg
 }
 
+const (
+	RetentionArchive = "archive"
+	RetentionDelete  = "delete"
+	RetentionKeep    = "keep"
+)
+
 type Configuration struct {
-	Logfile string
+	Logfile              string
+	VoteRetentionDays    int              // How long closed votes are kept around. Zero means 30.
+	VoteRetentionAction  string           // What happens to them after that. One of RetentionArchive, RetentionDelete or RetentionKeep. Blank means RetentionArchive.
+	ArchivePath          string           // Where archived things go. Blank means data/archive.
+	MetricsAddress       string           // Where to serve Prometheus metrics, like ":9100". Blank means no metrics.
+	HealthAddress        string           // Where to answer health checks on /healthz, like ":8080". Blank means no health checks.
+	LogLevel             string           // One of debug, info, warn or error. Blank means info.
+	LogJSON              bool             // Log every line as a JSON object, rather than as text.
+	TokenFile            string           // File to read the bot token from. Blank means the BOT_TOKEN environment variable.
+	StoragePath          string           // Where the bolt database lives. Blank means data/komainubolt.
+	Features             map[string]bool  // Feature flags, by name, for guilds that haven't set them with /config features. Missing means the default of the flag.
+	APIAddress           string           // Where to serve the REST API, like ":8081". Blank means no REST API.
+	TwitchClientID       string           // For go-live announcements from Twitch. Both this and the secret are needed.
+	TwitchClientSecret   string           // The client secret of the Twitch application. Blank means no Twitch announcements.
+	GitHubToken          string           // A GitHub token for watching repositories. Optional, but without one GitHub allows far fewer checks.
+	ShardCount           int              // How many gateway shards to run. Zero means as many as Discord recommends.
+	ContentIntent        bool             // Ask Discord for the content of messages. Needs the Message Content Intent turned on for the bot, or automod and counting are off.
+	OwnerIDs             []discord.UserID // Who runs the bot, and may use /debug.
+	PresenceIntent       bool             // Ask Discord for presence updates, so guilds can opt in to /config presence. Needs the Presence Intent turned on for the bot.
+	Statuses             []string         // What the bot takes turns showing as its status. {guilds} and {shards} are filled in. Blank means a few of its own.
+	StatusMinutes        int              // How often the status changes, in minutes. Zero means 10.
+	EncryptionKeyFile    string           // File to read the base64 AES key for encrypting storage from. Blank means the ENCRYPTION_KEY environment variable, and if that's blank too, nothing is encrypted.
+	EncryptedCollections []string         // The collections to encrypt, when there is a key. Blank means DefaultEncryptedCollections.
+	CompactHours         int              // How often storage is compacted, in hours. Zero means once a week, and less than zero means never.
+	RedisURL             string           // Keep everything in Redis instead, like "redis://:password@localhost:6379/0", so several instances can share it. Blank means bolt, at StoragePath.
+	Modules              map[string]bool  // Modules to turn on or off, by name, like {"xp": false}. Missing means on. Only read when starting up.
+	RecordInteractions   string           // Where to record every interaction, as JSON, for replaying with -replay. Blank means nothing is recorded. They hold what people typed, so only for debugging.
+	SentryDSN            string           // Report errors and panics to Sentry, like "https://key@sentry.example.com/42". Blank means no Sentry.
+	ErrorWebhookURL      string           // POST every error and panic to this URL, as JSON. Blank means no webhook.
+	ErrorSampleRate      float64          // The share of errors to report, from 0 to 1. Zero means all of them.
+	ErrorScrub           bool             // Leave out who it was, and what they typed, from error reports.
+}
+
+// configurationLock keeps the configuration from being read while it is being reloaded.
+var configurationLock sync.RWMutex
+
+// Snapshot returns a copy of the configuration as it is right now, safe from being reloaded halfway through reading it.
+func (c *Configuration) Snapshot() Configuration {
+	configurationLock.RLock()
+	defer configurationLock.RUnlock()
+	snapshot := *c
+	snapshot.Features = make(map[string]bool, len(c.Features))
+	for name, enabled := range c.Features {
+		snapshot.Features[name] = enabled
+	}
+	snapshot.Modules = make(map[string]bool, len(c.Modules))
+	for name, enabled := range c.Modules {
+		snapshot.Modules[name] = enabled
+	}
+	return snapshot
+}
+
+// Feature returns true if the named feature flag is on, in guilds that haven't set it with /config features.
+func (c *Configuration) Feature(name string) bool {
+	configurationLock.RLock()
+	defer configurationLock.RUnlock()
+	if enabled, ok := c.Features[name]; ok {
+		return enabled
+	}
+	flag, _ := FindFeatureFlag(name)
+	return flag.Default
+}
+
+// ModuleEnabled returns true unless the named module is turned off.
+func (c *Configuration) ModuleEnabled(name string) bool {
+	configurationLock.RLock()
+	defer configurationLock.RUnlock()
+	enabled, ok := c.Modules[name]
+	return enabled || !ok
+}
+
+// StatusInterval returns how often the status changes.
+func (c *Configuration) StatusInterval() time.Duration {
+	if c.StatusMinutes <= 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(c.StatusMinutes) * time.Minute
+}
+
+// Token returns the bot token, from TokenFile if there is one, or from the BOT_TOKEN environment variable.
+func (c *Configuration) Token() (string, error) {
+	if c.TokenFile == "" {
+		token := os.Getenv("BOT_TOKEN")
+		if token == "" {
+			return "", errors.New("no BOT_TOKEN found in environment variables")
+		}
+		return token, nil
+	}
+	raw, err := os.ReadFile(c.TokenFile)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(raw))
+	if token == "" {
+		return "", errors.New("the token file " + c.TokenFile + " is empty")
+	}
+	return token, nil
+}
+
+// EncryptionKey returns the key to encrypt storage with, from EncryptionKeyFile if there is one, or from the
+// ENCRYPTION_KEY environment variable. Nil, and no error, means there is no key and nothing is encrypted.
+func (c *Configuration) EncryptionKey() ([]byte, error) {
+	encoded := os.Getenv("ENCRYPTION_KEY")
+	if c.EncryptionKeyFile != "" {
+		raw, err := os.ReadFile(c.EncryptionKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		encoded = string(raw)
+	}
+	if strings.TrimSpace(encoded) == "" {
+		return nil, nil
+	}
+	return ParseEncryptionKey(encoded)
+}
+
+// Encrypted returns the collections to encrypt, when there is a key.
+func (c *Configuration) Encrypted() []string {
+	if len(c.EncryptedCollections) == 0 {
+		return DefaultEncryptedCollections
+	}
+	return c.EncryptedCollections
+}
+
+// CompactInterval returns how often storage is compacted. Zero means never, which is always the case with Redis.
+func (c *Configuration) CompactInterval() time.Duration {
+	switch {
+	case c.CompactHours < 0, c.RedisURL != "":
+		return 0
+	case c.CompactHours == 0:
+		return 7 * 24 * time.Hour
+	default:
+		return time.Duration(c.CompactHours) * time.Hour
+	}
+}
+
+// Storage returns the path to the bolt database.
+func (c *Configuration) Storage() string {
+	if c.StoragePath == "" {
+		return "data/komainubolt"
+	}
+	return c.StoragePath
 }
 
 // Path returns the path to where the configuration is stored.
@@ -29,6 +185,9 @@ func (c *Configuration) Load() error {
 	} else {
 		log.Println("Configuration file not found, will create a new one!")
 		c.Logfile = "komainu.log"
+		c.VoteRetentionDays = 30
+		c.VoteRetentionAction = RetentionArchive
+		c.ArchivePath = "data/archive"
 		return c.Save()
 	}
 }
@@ -37,3 +196,33 @@ func (c *Configuration) Load() error {
 func (c *Configuration) Save() error {
 	return SaveJSON(c)
 }
+
+// VoteRetention returns how long closed votes are kept before VoteRetentionAction is applied.
+func (c *Configuration) VoteRetention() time.Duration {
+	days := c.VoteRetentionDays
+	if days <= 0 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// VoteRetentionPolicy returns what to do with closed votes that are older than VoteRetention.
+func (c *Configuration) VoteRetentionPolicy() string {
+	switch c.VoteRetentionAction {
+	case RetentionDelete, RetentionKeep:
+		return c.VoteRetentionAction
+	case RetentionArchive, "":
+		return RetentionArchive
+	default:
+		log.Printf("Unknown VoteRetentionAction %q in the configuration, keeping votes to be on the safe side.", c.VoteRetentionAction)
+		return RetentionKeep
+	}
+}
+
+// Archive returns the directory archived things should be stored in.
+func (c *Configuration) Archive() string {
+	if c.ArchivePath == "" {
+		return "data/archive"
+	}
+	return c.ArchivePath
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// WatchConfiguration checks the configuration file for changes every few seconds, and reloads it when it changes.
+// The token, storage, log file, shard count and listening addresses only take effect on a restart, so those are kept as they are.
+// Once reloaded, apply is called with the old and new configuration, to put the rest into effect.
+// Intended to be called as a goroutine.
+func WatchConfiguration(cfg *Configuration, apply func(old Configuration, current Configuration)) {
+	lastModified := configurationModified(cfg)
+	ticker := time.NewTicker(10 * time.Second)
+	for {
+		<-ticker.C
+		modified := configurationModified(cfg)
+		if modified.IsZero() || modified.Equal(lastModified) {
+			continue
+		}
+		lastModified = modified
+		if err := reloadConfiguration(cfg, apply); err != nil {
+			log.Printf("Configuration changed, but could not be reloaded, so keeping the old one: %s", err)
+		}
+	}
+}
+
+// configurationModified returns when the configuration file was last changed, or the zero time if that can't be found.
+func configurationModified(cfg *Configuration) time.Time {
+	info, err := os.Stat(cfg.Path())
+	if err != nil {
+		log.Printf("Could not check the configuration file for changes: %s", err)
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// reloadConfiguration loads the configuration file again, and swaps it in for all the settings that don't need a restart.
+func reloadConfiguration(cfg *Configuration, apply func(old Configuration, current Configuration)) error {
+	current := Configuration{}
+	if err := LoadJSON(&current); err != nil {
+		return err
+	}
+	old := cfg.Snapshot()
+
+	restartOnly := []struct {
+		name    string
+		old     string
+		current *string
+	}{
+		{"TokenFile", old.TokenFile, &current.TokenFile},
+		{"StoragePath", old.StoragePath, &current.StoragePath},
+		{"Logfile", old.Logfile, &current.Logfile},
+		{"MetricsAddress", old.MetricsAddress, &current.MetricsAddress},
+		{"HealthAddress", old.HealthAddress, &current.HealthAddress},
+		{"APIAddress", old.APIAddress, &current.APIAddress},
+	}
+	for _, setting := range restartOnly {
+		if setting.old != *setting.current {
+			log.Printf("Configuration changed %s, but that only takes effect on a restart.", setting.name)
+			*setting.current = setting.old
+		}
+	}
+
+	if old.ShardCount != current.ShardCount {
+		log.Println("Configuration changed ShardCount, but that only takes effect on a restart.")
+		current.ShardCount = old.ShardCount
+	}
+	if old.ContentIntent != current.ContentIntent {
+		log.Println("Configuration changed ContentIntent, but that only takes effect on a restart.")
+		current.ContentIntent = old.ContentIntent
+	}
+	if old.PresenceIntent != current.PresenceIntent {
+		log.Println("Configuration changed PresenceIntent, but that only takes effect on a restart.")
+		current.PresenceIntent = old.PresenceIntent
+	}
+
+	configurationLock.Lock()
+	*cfg = current
+	configurationLock.Unlock()
+
+	log.Println("Configuration reloaded.")
+	if apply != nil {
+		apply(old, current)
+	}
+	return nil
+}
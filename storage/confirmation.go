@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/google/uuid"
+)
+
+// Confirmation is something destructive someone asked for, waiting for them to click Yes.
+type Confirmation struct {
+	ID      string
+	GuildID discord.GuildID
+	UserID  discord.UserID
+	Action  string // What registered confirmation handler carries it out.
+	Data    map[string]string
+	Expires int64
+}
+
+// NewConfirmation creates a confirmation with a fresh ID, good for the given time. It is not stored until you call Store on it.
+func NewConfirmation(guildID discord.GuildID, userID discord.UserID, action string, ttl time.Duration, data map[string]string) Confirmation {
+	return Confirmation{
+		ID:      uuid.New().String(),
+		GuildID: guildID,
+		UserID:  userID,
+		Action:  action,
+		Data:    data,
+		Expires: time.Now().Add(ttl).Unix(),
+	}
+}
+
+// Store saves the confirmation to kvs.
+func (confirmation *Confirmation) Store(kvs KeyValueStore) error {
+	return kvs.Set(confirmation.GuildID, "confirmations", confirmation.ID, confirmation)
+}
+
+// Expired checks if it's too late to confirm.
+func (confirmation *Confirmation) Expired(now time.Time) bool {
+	return confirmation.Expires <= now.Unix()
+}
+
+// GetConfirmation gets the confirmation with the given ID, if it exists and hasn't expired.
+func GetConfirmation(kvs KeyValueStore, guildID discord.GuildID, id string) (exist bool, confirmation Confirmation, err error) {
+	exist, err = kvs.Get(guildID, "confirmations", id, &confirmation)
+	if exist && confirmation.Expired(time.Now()) {
+		return false, Confirmation{}, err
+	}
+	return exist, confirmation, err
+}
+
+// TakeConfirmation gets the confirmation and forgets it, if it was asked of the user, so however many clicks come in at
+// once, only one of them gets it. If it was asked of someone else, it's left as it is.
+func TakeConfirmation(kvs KeyValueStore, guildID discord.GuildID, id string, userID discord.UserID) (exist bool, confirmation Confirmation, err error) {
+	unlock, err := LockRecord(kvs, guildID, "confirmations", id)
+	if err != nil {
+		return false, Confirmation{}, err
+	}
+	defer unlock()
+	exist, confirmation, err = GetConfirmation(kvs, guildID, id)
+	if err != nil || !exist || confirmation.UserID != userID {
+		return exist, confirmation, err
+	}
+	if err := ForgetConfirmation(kvs, guildID, id); err != nil {
+		return false, Confirmation{}, fmt.Errorf("forgetting confirmation %s: %w", id, err)
+	}
+	return true, confirmation, nil
+}
+
+// ForgetConfirmation removes a confirmation, so it can't be confirmed again.
+func ForgetConfirmation(kvs KeyValueStore, guildID discord.GuildID, id string) error {
+	return kvs.Delete(guildID, "confirmations", id)
+}
+
+// ForgetExpiredConfirmations removes the confirmations nobody clicked in time.
+func ForgetExpiredConfirmations(kvs KeyValueStore, guildID discord.GuildID) error {
+	keys, err := kvs.Keys(guildID, "confirmations")
+	if err != nil {
+		return fmt.Errorf("forgetting expired confirmations could not get keys: %w", err)
+	}
+	now := time.Now()
+	for _, key := range keys {
+		confirmation := Confirmation{}
+		exist, err := kvs.Get(guildID, "confirmations", key, &confirmation)
+		if err != nil {
+			return fmt.Errorf("forgetting expired confirmations could not get %s: %w", key, err)
+		}
+		if exist && confirmation.Expired(now) {
+			if err := kvs.Delete(guildID, "confirmations", key); err != nil {
+				return fmt.Errorf("forgetting expired confirmations could not delete %s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// MaxCooldown is the longest a command's cooldown can be, in seconds. Longer than a day is turning it off, which is
+// what /config features is for.
+const MaxCooldown = 24 * 60 * 60
+
+// Cooldown is how long has to pass between uses of a command in a guild.
+type Cooldown struct {
+	Seconds    int64
+	PerChannel bool // Counted for the whole channel, rather than for each user.
+}
+
+// SetCooldown stores the cooldown for the named command. A cooldown of zero seconds removes it.
+func SetCooldown(kvs KeyValueStore, guildID discord.GuildID, name string, cooldown Cooldown) error {
+	if cooldown.Seconds <= 0 {
+		return kvs.Delete(guildID, "cooldowns", name)
+	}
+	if cooldown.Seconds > MaxCooldown {
+		return fmt.Errorf("a cooldown of %d seconds is longer than the %d allowed", cooldown.Seconds, MaxCooldown)
+	}
+	return kvs.Set(guildID, "cooldowns", name, cooldown)
+}
+
+// GetCooldown gets the cooldown for the named command, if it has one.
+func GetCooldown(kvs KeyValueStore, guildID discord.GuildID, name string) (exist bool, cooldown Cooldown, err error) {
+	exist, err = kvs.Get(guildID, "cooldowns", name, &cooldown)
+	if err != nil {
+		return false, Cooldown{}, fmt.Errorf("getting cooldown for %s: %w", name, err)
+	}
+	return exist, cooldown, nil
+}
+
+// AllCooldowns gets every cooldown in the guild, keyed by command name.
+func AllCooldowns(kvs KeyValueStore, guildID discord.GuildID) (map[string]Cooldown, error) {
+	keys, err := kvs.Keys(guildID, "cooldowns")
+	if err != nil {
+		return nil, fmt.Errorf("getting cooldown keys: %w", err)
+	}
+	cooldowns := map[string]Cooldown{}
+	for _, key := range keys {
+		_, cooldown, err := GetCooldown(kvs, guildID, key)
+		if err != nil {
+			return nil, err
+		}
+		cooldowns[key] = cooldown
+	}
+	return cooldowns, nil
+}
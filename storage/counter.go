@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// NextNumber counts up the named counter in the guild, and returns the new value. The first number is 1.
+// Handy for giving things short IDs that humans can type.
+func NextNumber(kvs KeyValueStore, guildID discord.GuildID, name string) (int64, error) {
+	// Locked in storage rather than in the process, so instances of the bot sharing storage can't hand out the same number.
+	unlock, err := LockRecord(kvs, guildID, "counters", name)
+	if err != nil {
+		return 0, fmt.Errorf("could not lock the %s counter: %w", name, err)
+	}
+	defer unlock()
+	var last int64
+	if _, err := kvs.Get(guildID, "counters", name, &last); err != nil {
+		return 0, fmt.Errorf("could not get the %s counter: %w", name, err)
+	}
+	last++
+	if err := kvs.Set(guildID, "counters", name, last); err != nil {
+		return 0, fmt.Errorf("could not update the %s counter: %w", name, err)
+	}
+	return last, nil
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// CountingConfig is how the counting game is set up in a guild.
+type CountingConfig struct {
+	ChannelID discord.ChannelID
+	Milestone int // Every this many is celebrated. Zero means 100.
+}
+
+// Every returns how often a milestone is reached.
+func (c CountingConfig) Every() int {
+	if c.Milestone <= 0 {
+		return 100
+	}
+	return c.Milestone
+}
+
+// CountingState is how far the counting has come.
+type CountingState struct {
+	Count       int
+	LastUserID  discord.UserID // Who counted last, as nobody gets to count twice in a row.
+	HighScore   int
+	HighScoreAt int64 // When the high score was reached.
+	HighScoreBy discord.UserID
+	Resets      int  // How many times someone messed it up.
+	PastHigh    bool // This count has gone past the high score from before it started.
+}
+
+// CountingResult is what came of a number being counted.
+type CountingResult struct {
+	Correct   bool
+	Before    CountingState // How it was before the number, which is what was lost if it was wrong.
+	After     CountingState
+	Milestone bool // The count reached a milestone.
+	NewHigh   bool // The count just went past the old high score.
+}
+
+// countingLock keeps two numbers arriving at once from both being counted against the same state.
+var countingLock sync.Mutex
+
+// GetCountingConfig gets the counting setup for the guild, if there is one.
+func GetCountingConfig(kvs KeyValueStore, guildID discord.GuildID) (exist bool, config CountingConfig, err error) {
+	exist, err = kvs.Get(guildID, "counting", "config", &config)
+	return
+}
+
+// SetCountingConfig stores the counting setup for the guild.
+func SetCountingConfig(kvs KeyValueStore, guildID discord.GuildID, config CountingConfig) error {
+	return kvs.Set(guildID, "counting", "config", config)
+}
+
+// DisableCounting forgets the counting setup for the guild. How far it got, and the high score, are kept.
+func DisableCounting(kvs KeyValueStore, guildID discord.GuildID) error {
+	return kvs.Delete(guildID, "counting", "config")
+}
+
+// GetCountingState gets how far the counting has come in the guild.
+func GetCountingState(kvs KeyValueStore, guildID discord.GuildID) (state CountingState, err error) {
+	_, err = kvs.Get(guildID, "counting", "state", &state)
+	return
+}
+
+// Count counts the number the user posted. The right number, from someone other than whoever counted last, carries on
+// the count. Anything else starts it over.
+func Count(kvs KeyValueStore, guildID discord.GuildID, config CountingConfig, userID discord.UserID, number int) (CountingResult, error) {
+	countingLock.Lock()
+	defer countingLock.Unlock()
+	state, err := GetCountingState(kvs, guildID)
+	if err != nil {
+		return CountingResult{}, err
+	}
+	result := CountingResult{Before: state}
+	if number == state.Count+1 && userID != state.LastUserID {
+		result.Correct = true
+		state.Count = number
+		state.LastUserID = userID
+		if state.Count > state.HighScore {
+			result.NewHigh = state.HighScore > 0 && !state.PastHigh
+			state.PastHigh = true
+			state.HighScore = state.Count
+			state.HighScoreAt = time.Now().Unix()
+			state.HighScoreBy = userID
+		}
+		result.Milestone = state.Count%config.Every() == 0
+	} else {
+		if state.Count > 0 {
+			state.Resets++
+		}
+		state.Count = 0
+		state.LastUserID = discord.NullUserID
+		state.PastHigh = false
+	}
+	result.After = state
+	return result, kvs.Set(guildID, "counting", "state", state)
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// CustomCommand is a slash command a guild made for itself, that just replies with some text.
+type CustomCommand struct {
+	Name        string
+	Description string
+	Response    string
+	Creator     discord.UserID
+	Created     int64
+}
+
+// SetCustomCommand stores the custom command, replacing any with the same name.
+func SetCustomCommand(kvs KeyValueStore, guildID discord.GuildID, custom CustomCommand) error {
+	return kvs.Set(guildID, "customcommands", custom.Name, custom)
+}
+
+// GetCustomCommand gets the custom command with the given name.
+func GetCustomCommand(kvs KeyValueStore, guildID discord.GuildID, name string) (bool, CustomCommand, error) {
+	custom := CustomCommand{}
+	exist, err := kvs.Get(guildID, "customcommands", name, &custom)
+	return exist, custom, err
+}
+
+// DeleteCustomCommand forgets the custom command with the given name.
+func DeleteCustomCommand(kvs KeyValueStore, guildID discord.GuildID, name string) error {
+	return kvs.Delete(guildID, "customcommands", name)
+}
+
+// GetCustomCommands gets all the custom commands of the guild, sorted by name.
+func GetCustomCommands(kvs KeyValueStore, guildID discord.GuildID) ([]CustomCommand, error) {
+	keys, err := kvs.Keys(guildID, "customcommands")
+	if err != nil {
+		return nil, fmt.Errorf("custom commands could not get keys: %w", err)
+	}
+	sort.Strings(keys)
+	customs := make([]CustomCommand, 0, len(keys))
+	for _, key := range keys {
+		exist, custom, err := GetCustomCommand(kvs, guildID, key)
+		if err != nil {
+			return nil, fmt.Errorf("custom commands could not get %s: %w", key, err)
+		}
+		if exist {
+			customs = append(customs, custom)
+		}
+	}
+	return customs, nil
+}
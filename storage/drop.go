@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// Drop is a prize that shows up at a random time, for the first few to click to claim.
+type Drop struct {
+	ID        int64
+	GuildID   discord.GuildID
+	ChannelID discord.ChannelID
+	MessageID discord.MessageID
+	Host      discord.UserID
+	Prize     string
+	Winners   int
+	Opened    int64 // Unix milliseconds when the button became clickable. Zero until then.
+	Closed    bool
+	Claims    []DropClaim
+}
+
+// DropClaim is someone who got to the button in time.
+type DropClaim struct {
+	UserID       discord.UserID
+	ReactionTime int64 // Milliseconds from the button becoming clickable.
+}
+
+// NewDrop gives the drop a number, and stores it.
+func NewDrop(kvs KeyValueStore, drop *Drop) error {
+	id, err := NextNumber(kvs, drop.GuildID, "drops")
+	if err != nil {
+		return fmt.Errorf("creating drop could not get a number: %w", err)
+	}
+	drop.ID = id
+	return drop.Store(kvs)
+}
+
+// Store saves the drop to kvs.
+func (drop *Drop) Store(kvs KeyValueStore) error {
+	return kvs.Set(drop.GuildID, "drops", drop.ID, drop)
+}
+
+// GetDrop gets the drop with the given number, if it exists.
+func GetDrop(kvs KeyValueStore, guildID discord.GuildID, id int64) (bool, Drop, error) {
+	drop := Drop{}
+	exist, err := kvs.Get(guildID, "drops", id, &drop)
+	return exist, drop, err
+}
+
+// Claimed checks if the user already claimed the drop.
+func (drop *Drop) Claimed(userID discord.UserID) bool {
+	for _, claim := range drop.Claims {
+		if claim.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Full checks if every prize in the drop has been claimed.
+func (drop *Drop) Full() bool {
+	return len(drop.Claims) >= drop.Winners
+}
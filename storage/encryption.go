@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// encryptedMarker starts every encrypted value. A gob never starts with a zero byte, so values stored before a
+// collection was encrypted can still be told apart, and read.
+var encryptedMarker = []byte{0, 'k', 'e', '1'}
+
+// DefaultEncryptedCollections are what gets encrypted when there is a key, but no collections are configured.
+var DefaultEncryptedCollections = []string{"notes", "warnings", "tickets"}
+
+// encryption encrypts and decrypts values on their way in and out of a store. It does nothing until Encrypt is called.
+type encryption struct {
+	aead      cipher.AEAD     // Nil unless Encrypt has been called.
+	encrypted map[string]bool // The collections Encrypt was called with.
+}
+
+// ErrNoEncryptionKey is returned when reading an encrypted value without a key to decrypt it with.
+var ErrNoEncryptionKey = errors.New("the value is encrypted, but there is no encryption key")
+
+// ParseEncryptionKey decodes a base64 AES key, which has to be 16, 24 or 32 bytes.
+func ParseEncryptionKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("the encryption key is not valid base64: %w", err)
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("the encryption key is %d bytes, but has to be 16, 24 or 32", len(key))
+	}
+}
+
+// Encrypt makes the store encrypt the values in the given collections with AES-GCM from now on, and decrypt them when
+// they are read. Keys aren't encrypted, so they can still be listed. Values stored before this are still read as they
+// are, and encrypted the next time they are stored. Call it before anything else uses the store.
+func (e *encryption) Encrypt(key []byte, collections []string) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("unable to make the cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return fmt.Errorf("unable to make GCM: %w", err)
+	}
+	e.aead = aead
+	e.encrypted = make(map[string]bool, len(collections))
+	for _, collection := range collections {
+		e.encrypted[collection] = true
+	}
+	return nil
+}
+
+// additionalData ties an encrypted value to where it is stored, so it can't be quietly moved somewhere else.
+func additionalData(guild []byte, collection []byte, key []byte) []byte {
+	return bytes.Join([][]byte{guild, collection, key}, []byte{0})
+}
+
+// seal encrypts the value if the collection is encrypted, or leaves it as it is if it isn't.
+func (e *encryption) seal(guild []byte, collection []byte, key []byte, value []byte) ([]byte, error) {
+	if !e.encrypted[string(collection)] {
+		return value, nil
+	}
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("unable to make a nonce: %w", err)
+	}
+	sealed := append(append([]byte{}, encryptedMarker...), nonce...)
+	return e.aead.Seal(sealed, nonce, value, additionalData(guild, collection, key)), nil
+}
+
+// open decrypts the value if it is encrypted, or leaves it as it is if it isn't.
+func (e *encryption) open(guild []byte, collection []byte, key []byte, value []byte) ([]byte, error) {
+	if !bytes.HasPrefix(value, encryptedMarker) {
+		return value, nil
+	}
+	if e.aead == nil {
+		return nil, ErrNoEncryptionKey
+	}
+	value = value[len(encryptedMarker):]
+	if len(value) < e.aead.NonceSize() {
+		return nil, errors.New("the encrypted value is too short to be real")
+	}
+	nonce, ciphertext := value[:e.aead.NonceSize()], value[e.aead.NonceSize():]
+	opened, err := e.aead.Open(nil, nonce, ciphertext, additionalData(guild, collection, key))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt: %w", err)
+	}
+	return opened, nil
+}
@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"fmt"
+	"komainu/utility"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+const (
+	RSVPGoing = "going"
+	RSVPMaybe = "maybe"
+	RSVPNo    = "no"
+)
+
+// Event describes an event people can RSVP to, attached to a Discord message.
+type Event struct {
+	GuildID      discord.GuildID
+	ChannelID    discord.ChannelID
+	MessageID    discord.MessageID
+	Creator      discord.UserID
+	Title        string
+	Description  string
+	StartTime    int64
+	RemindBefore int64 // Seconds before StartTime to ping everyone that's coming.
+	Reminded     bool
+	RSVP         map[discord.UserID]string
+}
+
+// Store saves the event struct to kvs
+func (event *Event) Store(kvs KeyValueStore) error {
+	return kvs.Set(event.GuildID, "events", event.MessageID, event)
+}
+
+// Attendees returns the users that gave the given response, sorted by ID so the list doesn't jump around.
+func (event *Event) Attendees(rsvp string) []discord.UserID {
+	users := []discord.UserID{}
+	for user, response := range event.RSVP {
+		if response == rsvp {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i] < users[j]
+	})
+	return users
+}
+
+// Embed returns the event formatted as a Discord embed.
+func (event *Event) Embed() discord.Embed {
+	var description strings.Builder
+	if event.Description != "" {
+		fmt.Fprintf(&description, "%s\n\n", event.Description)
+	}
+	if event.StartTime <= time.Now().Unix() {
+		fmt.Fprintf(&description, "Started %s", utility.Timestamp(event.StartTime, utility.LongDateTime))
+	} else {
+		fmt.Fprintf(&description, "Starts %s", utility.TimestampAndRelative(event.StartTime, utility.LongDateTime))
+	}
+
+	return discord.Embed{
+		Type:        discord.NormalEmbed,
+		Title:       event.Title,
+		Description: description.String(),
+		Color:       discord.Color(0x5865F2),
+		Fields: []discord.EmbedField{
+			event.attendeeField("Going", RSVPGoing),
+			event.attendeeField("Maybe", RSVPMaybe),
+			event.attendeeField("Can't", RSVPNo),
+		},
+	}
+}
+
+func (event *Event) attendeeField(label string, rsvp string) discord.EmbedField {
+	users := event.Attendees(rsvp)
+	mentions := make([]string, len(users))
+	for i, user := range users {
+		mentions[i] = user.Mention()
+	}
+	value := strings.Join(mentions, "\n")
+	if value == "" {
+		value = "Nobody yet"
+	}
+	if len(value) > 1024 { // Discord's limit for a field value.
+		value = fmt.Sprintf("%d people", len(users))
+	}
+	return discord.EmbedField{
+		Name:   fmt.Sprintf("%s (%d)", label, len(users)),
+		Value:  value,
+		Inline: true,
+	}
+}
+
+// GetEvent gets a specific event for the given guild and message.
+func GetEvent(kvs KeyValueStore, guildID discord.GuildID, messageID discord.MessageID) (exist bool, event *Event, err error) {
+	exist, err = kvs.Get(guildID, "events", messageID, &event)
+	return exist, event, err
+}
+
+// LockEvent locks the event, so RSVPs to it at the same time can't lose each other. Call unlock when done.
+func LockEvent(kvs KeyValueStore, guildID discord.GuildID, messageID discord.MessageID) (unlock func(), err error) {
+	return LockRecord(kvs, guildID, "events", messageID)
+}
+
+// RemindAndCloseEvents pings the attendees of events that are about to start, and closes the ones that have started.
+func RemindAndCloseEvents(state *state.State, kvs KeyValueStore) error {
+	guilds, err := state.Guilds()
+	if err != nil {
+		return fmt.Errorf("reminding events could not fetch current guilds: %w", err)
+	}
+	now := time.Now().Unix()
+	for _, guild := range guilds {
+		err := GuildJob(kvs, guild.ID, "events", time.Minute, func() error {
+			keys, err := kvs.Keys(guild.ID, "events")
+			if err != nil {
+				return fmt.Errorf("reminding events could not get keys for guild: %w", err)
+			}
+			for _, key := range keys {
+				if err := remindOrCloseEvent(state, kvs, guild.ID, key, now); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remindOrCloseEvent reminds or closes the event with the key, if it's time, with the event locked so no RSVP is lost.
+func remindOrCloseEvent(state *state.State, kvs KeyValueStore, guildID discord.GuildID, key string, now int64) error {
+	unlock, err := LockRecord(kvs, guildID, "events", key)
+	if err != nil {
+		log.Printf("[%s] Reminding events skipped event %s for now, as it's busy: %s", guildID, key, err)
+		return nil
+	}
+	defer unlock()
+	event := Event{}
+	exist, err := kvs.Get(guildID, "events", key, &event)
+	if err != nil {
+		return fmt.Errorf("reminding events could not obtain event object: %w", err)
+	}
+	if !exist {
+		return nil
+	}
+	if event.StartTime <= now {
+		_, err := state.EditMessageComplex(event.ChannelID, event.MessageID, api.EditMessageData{
+			Embeds:     &[]discord.Embed{event.Embed()},
+			Components: &discord.ContainerComponents{},
+		})
+		if err != nil {
+			log.Printf("[%s] Could not update started event message: %s", guildID, err)
+		}
+		if err := kvs.Delete(guildID, "events", key); err != nil {
+			return fmt.Errorf("encountered an error removing started event: %w", err)
+		}
+		return nil
+	}
+	if !event.Reminded && event.StartTime-event.RemindBefore <= now {
+		if err := event.remind(state); err != nil {
+			log.Printf("[%s] Failed to send event reminder: %s", guildID, err)
+		}
+		event.Reminded = true // Even if it failed. Spamming the channel with retries is worse.
+		if err := event.Store(kvs); err != nil {
+			return fmt.Errorf("encountered an error storing reminded event: %w", err)
+		}
+	}
+	return nil
+}
+
+// eventReminderMentions is how many people a single reminder pings, as Discord won't allow more than 100 user mentions
+// per message.
+const eventReminderMentions = 100
+
+// eventReminderLength is how long a single reminder can be, as Discord won't take messages over 2000 characters.
+const eventReminderLength = 2000
+
+// remind pings everyone that's coming, in as many messages as it takes to fit them all.
+func (event *Event) remind(state *state.State) error {
+	attending := append(event.Attendees(RSVPGoing), event.Attendees(RSVPMaybe)...)
+	if len(attending) == 0 {
+		return nil
+	}
+	header := fmt.Sprintf("**%s** starts %s!\n", event.Title, utility.Timestamp(event.StartTime, utility.Relative))
+	var failed error
+	for len(attending) > 0 {
+		var sb strings.Builder
+		sb.WriteString(header)
+		length := len([]rune(header))
+		pinged := []discord.UserID{}
+		for _, user := range attending {
+			mention := user.Mention() + " "
+			if len(pinged) == eventReminderMentions || length+len(mention) > eventReminderLength {
+				break
+			}
+			sb.WriteString(mention)
+			length += len(mention)
+			pinged = append(pinged, user)
+		}
+		attending = attending[len(pinged):]
+		header = "" // The rest are only more people that are coming.
+		_, err := state.SendMessageComplex(event.ChannelID, api.SendMessageData{
+			Content: sb.String(),
+			Reference: &discord.MessageReference{
+				MessageID: event.MessageID,
+			},
+			AllowedMentions: &api.AllowedMentions{
+				Parse: []api.AllowedMentionType{},
+				Users: pinged,
+			},
+		})
+		if err != nil && failed == nil {
+			failed = err // Still sending the rest, so as few as possible miss out.
+		}
+	}
+	return failed
+}
+
+// StartRemindingEvents starts a ticker and, once a minute, calls RemindAndCloseEvents.
+// Intended to be called as a goroutine.
+func StartRemindingEvents(state *state.State, kvs KeyValueStore) {
+	ticker := time.NewTicker(1 * time.Minute)
+	for {
+		<-ticker.C
+		if err := RemindAndCloseEvents(state, kvs); err != nil {
+			log.Printf("Error encountered reminding events: %s", err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"fmt"
+	"komainu/bus"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// closedVote is a closed vote, as published.
+type closedVote struct {
+	MessageID discord.MessageID `json:"message_id"`
+	ChannelID discord.ChannelID `json:"channel_id"`
+	Question  string            `json:"question"`
+	Creator   discord.UserID    `json:"creator"`
+	Tally     map[string]int    `json:"tally"`
+	Winner    string            `json:"winner,omitempty"`
+	QuorumMet bool              `json:"quorum_met"`
+	Cancelled bool              `json:"cancelled"`
+}
+
+// publishClosed tells everyone interested that the vote closed, or was cancelled.
+func (vote *Vote) publishClosed() {
+	tally, _ := vote.Tally()
+	payload := closedVote{
+		MessageID: vote.MessageID,
+		ChannelID: vote.ChannelID,
+		Question:  vote.Question,
+		Creator:   vote.Creator,
+		Tally:     tally,
+		QuorumMet: vote.QuorumMet(),
+		Cancelled: vote.Cancelled,
+	}
+	summary := fmt.Sprintf("The vote %q closed", vote.Question)
+	if winner, ok := vote.Winner(); ok && !vote.Cancelled {
+		payload.Winner = vote.Options[winner]
+		summary += fmt.Sprintf(", and %q won", payload.Winner)
+	}
+	if vote.Cancelled {
+		summary = fmt.Sprintf("The vote %q was cancelled", vote.Question)
+	}
+	bus.Publish(bus.Event{
+		Name:    bus.VoteClosed,
+		GuildID: vote.GuildID,
+		UserID:  vote.Creator,
+		Summary: summary,
+		Data:    payload,
+	})
+}
+
+// publishWarningIssued tells everyone interested that someone was warned.
+func publishWarningIssued(warning Warning) {
+	bus.Publish(bus.Event{
+		Name:    bus.WarningIssued,
+		GuildID: warning.GuildID,
+		UserID:  warning.UserID,
+		Summary: fmt.Sprintf("%s was warned by %s: %s", warning.UserID.Mention(), warning.Moderator.Mention(), warning.Reason),
+		Data:    warning,
+	})
+}
+
+// PublishTicketOpened tells everyone interested that the ticket was opened.
+func PublishTicketOpened(ticket Ticket) {
+	bus.Publish(bus.Event{
+		Name:    bus.TicketOpened,
+		GuildID: ticket.GuildID,
+		UserID:  ticket.Opener,
+		Summary: fmt.Sprintf("%s opened the ticket %s", ticket.Opener.Mention(), ticket.ThreadID.Mention()),
+		Data: struct {
+			ThreadID discord.ChannelID `json:"thread_id"`
+			Opener   discord.UserID    `json:"opener"`
+		}{ticket.ThreadID, ticket.Opener},
+	})
+}
+
+// PublishMemberVerified tells everyone interested that the member passed verification.
+func PublishMemberVerified(guildID discord.GuildID, userID discord.UserID, roleID discord.RoleID) {
+	bus.Publish(bus.Event{
+		Name:    bus.MemberVerified,
+		GuildID: guildID,
+		UserID:  userID,
+		Summary: fmt.Sprintf("%s passed verification, and got %s", userID.Mention(), roleID.Mention()),
+		Data: struct {
+			UserID discord.UserID `json:"user_id"`
+			RoleID discord.RoleID `json:"role_id"`
+		}{userID, roleID},
+	})
+}
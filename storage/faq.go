@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// FAQHistoryLimit is how many changes are remembered for each FAQ topic. Older ones are forgotten.
+const FAQHistoryLimit = 20
+
+// FAQChange is a single change to a FAQ topic, with what it said before so it can be undone.
+type FAQChange struct {
+	Topic   string
+	Existed bool // Whether the topic was there before the change.
+	Before  string
+	Removed bool // Whether the change removed the topic.
+	After   string
+	By      discord.UserID // Nobody, for changes made through the REST API.
+	When    int64
+}
+
+// GetFAQTopic gets what a FAQ topic says, if there is such a topic.
+func GetFAQTopic(kvs KeyValueStore, guildID discord.GuildID, topic string) (exist bool, text string, err error) {
+	exist, err = kvs.Get(guildID, "faq", topic, &text)
+	return
+}
+
+// SetFAQTopic adds or changes a FAQ topic, and remembers what it was before.
+func SetFAQTopic(kvs KeyValueStore, guildID discord.GuildID, topic string, text string, by discord.UserID) error {
+	if err := recordFAQChange(kvs, guildID, topic, false, text, by); err != nil {
+		return err
+	}
+	return kvs.Set(guildID, "faq", topic, text)
+}
+
+// RemoveFAQTopic removes a FAQ topic, and remembers what it was before.
+func RemoveFAQTopic(kvs KeyValueStore, guildID discord.GuildID, topic string, by discord.UserID) error {
+	if err := recordFAQChange(kvs, guildID, topic, true, "", by); err != nil {
+		return err
+	}
+	return kvs.Delete(guildID, "faq", topic)
+}
+
+// recordFAQChange adds a change to the history of the topic, forgetting the oldest if there are too many.
+func recordFAQChange(kvs KeyValueStore, guildID discord.GuildID, topic string, removed bool, after string, by discord.UserID) error {
+	existed, before, err := GetFAQTopic(kvs, guildID, topic)
+	if err != nil {
+		return fmt.Errorf("recording FAQ change could not get %q: %w", topic, err)
+	}
+	history, err := GetFAQHistory(kvs, guildID, topic)
+	if err != nil {
+		return err
+	}
+	history = append(history, FAQChange{
+		Topic:   topic,
+		Existed: existed,
+		Before:  before,
+		Removed: removed,
+		After:   after,
+		By:      by,
+		When:    time.Now().Unix(),
+	})
+	if len(history) > FAQHistoryLimit {
+		history = history[len(history)-FAQHistoryLimit:]
+	}
+	return kvs.Set(guildID, "faqhistory", topic, history)
+}
+
+// GetFAQHistory gets the changes made to a FAQ topic, oldest first.
+func GetFAQHistory(kvs KeyValueStore, guildID discord.GuildID, topic string) ([]FAQChange, error) {
+	history := []FAQChange{}
+	if _, err := kvs.Get(guildID, "faqhistory", topic, &history); err != nil {
+		return nil, fmt.Errorf("getting FAQ history could not get %q: %w", topic, err)
+	}
+	return history, nil
+}
+
+// UndoFAQChange puts a FAQ topic back the way it was before the last change, and forgets that change.
+// Without a topic, it undoes the last change to any topic. It returns the change that was undone, if there was one.
+func UndoFAQChange(kvs KeyValueStore, guildID discord.GuildID, topic string) (undone bool, change FAQChange, err error) {
+	if topic == "" {
+		topics, err := kvs.Keys(guildID, "faqhistory")
+		if err != nil {
+			return false, change, fmt.Errorf("undoing FAQ change could not get keys: %w", err)
+		}
+		for _, candidate := range topics {
+			history, err := GetFAQHistory(kvs, guildID, candidate)
+			if err != nil {
+				return false, change, err
+			}
+			if len(history) > 0 && history[len(history)-1].When >= change.When {
+				change = history[len(history)-1]
+				topic = candidate
+			}
+		}
+		if topic == "" {
+			return false, change, nil
+		}
+	}
+
+	history, err := GetFAQHistory(kvs, guildID, topic)
+	if err != nil || len(history) == 0 {
+		return false, change, err
+	}
+	change = history[len(history)-1]
+	if change.Existed {
+		err = kvs.Set(guildID, "faq", topic, change.Before)
+	} else {
+		err = kvs.Delete(guildID, "faq", topic)
+	}
+	if err != nil {
+		return false, change, fmt.Errorf("undoing FAQ change could not restore %q: %w", topic, err)
+	}
+	history = history[:len(history)-1]
+	if len(history) == 0 {
+		err = kvs.Delete(guildID, "faqhistory", topic)
+	} else {
+		err = kvs.Set(guildID, "faqhistory", topic, history)
+	}
+	if err != nil {
+		return true, change, fmt.Errorf("undoing FAQ change could not forget it: %w", err)
+	}
+	return true, change, nil
+}
+
+// GetFAQCategory gets the category a FAQ topic is in, or blank if it isn't in one.
+func GetFAQCategory(kvs KeyValueStore, guildID discord.GuildID, topic string) (category string, err error) {
+	_, err = kvs.Get(guildID, "faqcategory", topic, &category)
+	return
+}
+
+// SetFAQCategory puts a FAQ topic in a category, or takes it out of the one it's in if the category is blank.
+// The category is kept when the topic is removed, so it's still there if the removal is undone.
+func SetFAQCategory(kvs KeyValueStore, guildID discord.GuildID, topic string, category string) error {
+	if category == "" {
+		return kvs.Delete(guildID, "faqcategory", topic)
+	}
+	return kvs.Set(guildID, "faqcategory", topic, category)
+}
+
+// GetFAQCategories gets every FAQ topic by the category it's in, each list sorted. Topics that aren't in a category are
+// under a blank one.
+func GetFAQCategories(kvs KeyValueStore, guildID discord.GuildID) (map[string][]string, error) {
+	topics, err := kvs.Keys(guildID, "faq")
+	if err != nil {
+		return nil, fmt.Errorf("FAQ categories could not get topics: %w", err)
+	}
+	sort.Strings(topics)
+	categories := map[string][]string{}
+	for _, topic := range topics {
+		category, err := GetFAQCategory(kvs, guildID, topic)
+		if err != nil {
+			return nil, fmt.Errorf("FAQ categories could not get the category of %q: %w", topic, err)
+		}
+		categories[category] = append(categories[category], topic)
+	}
+	return categories, nil
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// FAQAutoCooldownDefault is how many minutes the FAQ auto-responder waits before answering with the same topic in the
+// same channel again, unless the guild says otherwise.
+const FAQAutoCooldownDefault = 10
+
+// FAQAutoConfig is how the FAQ auto-responder is set up in a guild.
+type FAQAutoConfig struct {
+	Triggers        map[string]string          // Phrases to look for, lowercase, and the topic each one answers with.
+	Channels        map[discord.ChannelID]bool // Channels where it's on. Nowhere else is it.
+	CooldownMinutes int64                      // Zero for FAQAutoCooldownDefault.
+	Style           string                     // "reply" to reply and delete it again after a while, "keep" to reply and leave it, or "thread". Blank is "reply".
+}
+
+// Cooldown is how many minutes to wait before answering with the same topic in the same channel again.
+func (config FAQAutoConfig) Cooldown() int64 {
+	if config.CooldownMinutes <= 0 {
+		return FAQAutoCooldownDefault
+	}
+	return config.CooldownMinutes
+}
+
+// GetFAQAutoConfig gets the FAQ auto-responder setup for the guild, or an empty one.
+func GetFAQAutoConfig(kvs KeyValueStore, guildID discord.GuildID) (config FAQAutoConfig, err error) {
+	_, err = kvs.Get(guildID, "faqauto", "config", &config)
+	// gob doesn't bother with empty maps.
+	if config.Triggers == nil {
+		config.Triggers = map[string]string{}
+	}
+	if config.Channels == nil {
+		config.Channels = map[discord.ChannelID]bool{}
+	}
+	if config.Style == "" {
+		config.Style = "reply"
+	}
+	return
+}
+
+// SetFAQAutoConfig stores the FAQ auto-responder setup for the guild.
+func SetFAQAutoConfig(kvs KeyValueStore, guildID discord.GuildID, config FAQAutoConfig) error {
+	return kvs.Set(guildID, "faqauto", "config", config)
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"sync"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// FeatureFlag is a part of the bot that can be turned on or off for each guild, with /config features.
+type FeatureFlag struct {
+	Name        string
+	Description string
+	Default     bool // Whether it's on in guilds that haven't said, unless the configuration's Features say otherwise.
+}
+
+// FeatureFlags are the parts of the bot that can be turned on or off for each guild.
+// Something new starts out off by default, so it can be tried out in a guild or two before everyone gets it.
+var FeatureFlags = []FeatureFlag{
+	{Name: "automod", Description: "Automod, checking every message against the filters", Default: true},
+	{Name: "starboard", Description: "The starboard, reposting messages with enough stars", Default: true},
+	{Name: "xp", Description: "XP, levels and the leaderboard", Default: true},
+}
+
+// featureDefaults is the configuration's Features, overriding the defaults of the flags.
+var featureDefaults = map[string]bool{}
+var featureDefaultsLock sync.RWMutex
+
+// SetFeatureDefaults sets which features are on in guilds that haven't said, from the configuration's Features.
+// Flags left out keep their own default.
+func SetFeatureDefaults(defaults map[string]bool) {
+	featureDefaultsLock.Lock()
+	defer featureDefaultsLock.Unlock()
+	featureDefaults = make(map[string]bool, len(defaults))
+	for name, enabled := range defaults {
+		featureDefaults[name] = enabled
+	}
+}
+
+// FindFeatureFlag finds the named feature flag.
+func FindFeatureFlag(name string) (FeatureFlag, bool) {
+	for _, flag := range FeatureFlags {
+		if flag.Name == name {
+			return flag, true
+		}
+	}
+	return FeatureFlag{}, false
+}
+
+// FeatureDefault tells if the named feature is on in guilds that haven't said. Unknown features are off.
+func FeatureDefault(name string) bool {
+	featureDefaultsLock.RLock()
+	enabled, ok := featureDefaults[name]
+	featureDefaultsLock.RUnlock()
+	if ok {
+		return enabled
+	}
+	flag, _ := FindFeatureFlag(name)
+	return flag.Default
+}
+
+// GetFeature gets whether the guild has turned the named feature on or off. If it hasn't said, set is false.
+func GetFeature(kvs KeyValueStore, guildID discord.GuildID, name string) (set bool, enabled bool, err error) {
+	set, err = kvs.Get(guildID, "features", name, &enabled)
+	return
+}
+
+// SetFeature turns the named feature on or off in the guild.
+func SetFeature(kvs KeyValueStore, guildID discord.GuildID, name string, enabled bool) error {
+	return kvs.Set(guildID, "features", name, enabled)
+}
+
+// ResetFeature makes the guild go with the default for the named feature.
+func ResetFeature(kvs KeyValueStore, guildID discord.GuildID, name string) error {
+	return kvs.Delete(guildID, "features", name)
+}
+
+// FeatureEnabled checks if the named feature is on in the guild, whether the guild said so or it's the default.
+func FeatureEnabled(kvs KeyValueStore, guildID discord.GuildID, name string) (bool, error) {
+	set, enabled, err := GetFeature(kvs, guildID, name)
+	if err != nil {
+		return false, err
+	}
+	if set {
+		return enabled, nil
+	}
+	return FeatureDefault(name), nil
+}
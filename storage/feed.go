@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"komainu/utility"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+const (
+	FeedLimit        = 10              // How many feeds a single guild can follow.
+	feedSeenLimit    = 200             // How many entries are remembered per feed, to tell new ones from old ones.
+	feedPostLimit    = 5               // How many new entries are posted per check, so a feed that suddenly dumps its archive doesn't flood the channel.
+	feedMaxBody      = 5 * 1024 * 1024 // Anything bigger than this isn't a feed we want.
+	feedPollInterval = 15 * time.Minute
+)
+
+// Feed is an RSS or Atom feed the guild follows, posting new entries in a channel.
+type Feed struct {
+	ID          int64
+	GuildID     discord.GuildID
+	ChannelID   discord.ChannelID
+	URL         string
+	Title       string
+	AddedBy     discord.UserID
+	Seen        []string // The IDs of the entries already posted, or already there when the feed was added. Newest last.
+	LastChecked int64
+	LastError   string // What went wrong the last time the feed was checked, if anything did.
+}
+
+// feedClient is used to fetch all feeds, so a slow one can't hold up the rest forever.
+var feedClient = &http.Client{Timeout: 30 * time.Second}
+
+// FetchFeed fetches and reads the feed at the given URL.
+func FetchFeed(url string) (utility.Feed, error) {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return utility.Feed{}, err
+	}
+	request.Header.Set("User-Agent", "Komainu")
+	resp, err := feedClient.Do(request)
+	if err != nil {
+		return utility.Feed{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return utility.Feed{}, fmt.Errorf("the feed answered %s", resp.Status)
+	}
+	return utility.ParseFeed(io.LimitReader(resp.Body, feedMaxBody))
+}
+
+// Store stores the feed.
+func (feed *Feed) Store(kvs KeyValueStore) error {
+	return kvs.Set(feed.GuildID, "feeds", feed.ID, feed)
+}
+
+// Delete forgets the feed.
+func (feed *Feed) Delete(kvs KeyValueStore) error {
+	return kvs.Delete(feed.GuildID, "feeds", feed.ID)
+}
+
+// GetFeed gets a single feed followed by the guild.
+func GetFeed(kvs KeyValueStore, guildID discord.GuildID, id int64) (exist bool, feed Feed, err error) {
+	exist, err = kvs.Get(guildID, "feeds", id, &feed)
+	return exist, feed, err
+}
+
+// GetFeeds gets all the feeds followed by the guild, in the order they were added.
+func GetFeeds(kvs KeyValueStore, guildID discord.GuildID) ([]Feed, error) {
+	keys, err := kvs.Keys(guildID, "feeds")
+	if err != nil {
+		return nil, fmt.Errorf("getting feeds could not get keys: %w", err)
+	}
+	feeds := []Feed{}
+	for _, key := range keys {
+		feed := Feed{}
+		exist, err := kvs.Get(guildID, "feeds", key, &feed)
+		if err != nil {
+			return nil, fmt.Errorf("getting feeds could not get %s: %w", key, err)
+		}
+		if exist {
+			feeds = append(feeds, feed)
+		}
+	}
+	sort.Slice(feeds, func(i, j int) bool {
+		return feeds[i].ID < feeds[j].ID
+	})
+	return feeds, nil
+}
+
+// MarkSeen remembers the items as seen, forgetting the oldest ones if there are too many.
+func (feed *Feed) MarkSeen(items ...utility.FeedItem) {
+	feed.Seen = markSeen(feed.Seen, items...)
+}
+
+// markSeen adds the IDs of the items to seen, forgetting the oldest ones if there are too many.
+func markSeen(seen []string, items ...utility.FeedItem) []string {
+	for _, item := range items {
+		if !utility.ContainsString(seen, item.ID) {
+			seen = append(seen, item.ID)
+		}
+	}
+	if len(seen) > feedSeenLimit {
+		seen = seen[len(seen)-feedSeenLimit:]
+	}
+	return seen
+}
+
+// unseen returns the items whose IDs are not in seen, oldest first.
+func unseen(seen []string, items []utility.FeedItem) []utility.FeedItem {
+	fresh := []utility.FeedItem{}
+	for _, item := range items {
+		if !utility.ContainsString(seen, item.ID) {
+			fresh = append(fresh, item)
+		}
+	}
+	// Feeds mostly list the newest first, but not all of them. Going by the dates is safest,
+	// and if some are missing, trusting the feed to be newest first is the next best thing.
+	for _, item := range fresh {
+		if item.Published.IsZero() {
+			for i, j := 0, len(fresh)-1; i < j; i, j = i+1, j-1 {
+				fresh[i], fresh[j] = fresh[j], fresh[i]
+			}
+			return fresh
+		}
+	}
+	sort.SliceStable(fresh, func(i, j int) bool {
+		return fresh[i].Published.Before(fresh[j].Published)
+	})
+	return fresh
+}
+
+// Embed makes the embed posted for a single entry in the feed.
+func (feed *Feed) Embed(item utility.FeedItem) discord.Embed {
+	embed := discord.Embed{
+		Title:       utility.Substring(item.Title, 0, 256),
+		URL:         item.Link,
+		Description: utility.Substring(item.Summary, 0, 300),
+		Footer:      &discord.EmbedFooter{Text: utility.Substring(feed.Title, 0, 200)},
+	}
+	if len([]rune(item.Summary)) > 300 {
+		embed.Description = utility.Substring(item.Summary, 0, 299) + "…"
+	}
+	if !item.Published.IsZero() {
+		embed.Timestamp = discord.NewTimestamp(item.Published)
+	}
+	return embed
+}
+
+// Check fetches the feed, and posts what's new since the last time.
+func (feed *Feed) Check(state *state.State, kvs KeyValueStore) error {
+	feed.LastChecked = time.Now().Unix()
+	fetched, err := FetchFeed(feed.URL)
+	if err != nil {
+		feed.LastError = err.Error()
+		return feed.Store(kvs)
+	}
+	feed.LastError = ""
+	if fetched.Title != "" {
+		feed.Title = fetched.Title
+	}
+	fresh := unseen(feed.Seen, fetched.Items)
+	if len(fresh) > feedPostLimit {
+		// Skip the oldest ones, but remember them so they don't come up next time.
+		feed.MarkSeen(fresh[:len(fresh)-feedPostLimit]...)
+		fresh = fresh[len(fresh)-feedPostLimit:]
+	}
+	for _, item := range fresh {
+		_, err := state.SendMessageComplex(feed.ChannelID, api.SendMessageData{
+			Embeds: []discord.Embed{feed.Embed(item)},
+		})
+		if err != nil {
+			feed.LastError = "could not post in the channel: " + err.Error()
+			break
+		}
+		feed.MarkSeen(item)
+	}
+	// The feed could have been removed while it was being checked, and shouldn't come back from that.
+	if exist, _, err := GetFeed(kvs, feed.GuildID, feed.ID); err != nil || !exist {
+		return err
+	}
+	return feed.Store(kvs)
+}
+
+// CheckFeeds checks all the feeds of all the guilds.
+func CheckFeeds(state *state.State, kvs KeyValueStore) error {
+	guilds, err := state.Guilds()
+	if err != nil {
+		return fmt.Errorf("checking feeds could not fetch current guilds: %w", err)
+	}
+	for _, guild := range guilds {
+		err := GuildJob(kvs, guild.ID, "feeds", feedPollInterval, func() error {
+			feeds, err := GetFeeds(kvs, guild.ID)
+			if err != nil {
+				return err
+			}
+			for _, feed := range feeds {
+				if err := feed.Check(state, kvs); err != nil {
+					log.Printf("[%s] Could not store feed %d after checking it: %s", guild.ID, feed.ID, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartCheckingFeeds starts a ticker and, every feedPollInterval, calls CheckFeeds.
+// Intended to be called as a goroutine.
+func StartCheckingFeeds(state *state.State, kvs KeyValueStore) {
+	ticker := time.NewTicker(feedPollInterval)
+	for {
+		<-ticker.C
+		if err := CheckFeeds(state, kvs); err != nil {
+			log.Printf("Error encountered checking feeds: %s", err)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"komainu/utility"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+const (
+	GitHubLimit        = 10 // How many repositories a single guild can watch.
+	githubPollInterval = 10 * time.Minute
+)
+
+// GitHubWatch is a GitHub repository the guild wants to hear about.
+type GitHubWatch struct {
+	ID          int64
+	GuildID     discord.GuildID
+	ChannelID   discord.ChannelID
+	Repository  string // Like "owner/repo".
+	Releases    bool
+	Issues      bool
+	Pulls       bool
+	AddedBy     discord.UserID
+	LastRelease int64 // The ID of the newest release posted, or there when the watch was added.
+	LastNumber  int   // The highest issue or pull request number posted, or there when the watch was added.
+	CaughtUp    bool  // Set once the first check has found what was there when the watch was added, which isn't posted.
+	LastError   string
+}
+
+type githubUser struct {
+	Login     string `json:"login"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// GitHubRelease is a release, as GitHub describes it.
+type GitHubRelease struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	TagName     string     `json:"tag_name"`
+	Body        string     `json:"body"`
+	HTMLURL     string     `json:"html_url"`
+	Draft       bool       `json:"draft"`
+	Prerelease  bool       `json:"prerelease"`
+	PublishedAt time.Time  `json:"published_at"`
+	Author      githubUser `json:"author"`
+}
+
+// GitHubIssue is an issue or pull request, as GitHub describes it.
+type GitHubIssue struct {
+	Number      int        `json:"number"`
+	Title       string     `json:"title"`
+	Body        string     `json:"body"`
+	HTMLURL     string     `json:"html_url"`
+	CreatedAt   time.Time  `json:"created_at"`
+	User        githubUser `json:"user"`
+	PullRequest *struct{}  `json:"pull_request"` // Only there for pull requests.
+}
+
+var githubClient = &http.Client{Timeout: 15 * time.Second}
+
+// githubGet gets something from the GitHub API, using the token from the configuration if there is one.
+func githubGet(cfg Configuration, path string, out any) error {
+	request, err := http.NewRequest(http.MethodGet, "https://api.github.com"+path, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/vnd.github+json")
+	request.Header.Set("User-Agent", "Komainu")
+	if cfg.GitHubToken != "" {
+		request.Header.Set("Authorization", "Bearer "+cfg.GitHubToken)
+	}
+	resp, err := githubClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return json.NewDecoder(resp.Body).Decode(out)
+	case http.StatusNotFound:
+		return fmt.Errorf("GitHub doesn't know that repository, or it's private")
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		return fmt.Errorf("GitHub says we're asking too much, so this will have to wait")
+	}
+	return fmt.Errorf("GitHub answered %s", resp.Status)
+}
+
+// GitHubReleases gets the newest releases of the repository, newest first.
+func GitHubReleases(cfg Configuration, repository string) ([]GitHubRelease, error) {
+	releases := []GitHubRelease{}
+	err := githubGet(cfg, "/repos/"+repository+"/releases?per_page=10", &releases)
+	return releases, err
+}
+
+// GitHubIssues gets the newest issues and pull requests of the repository, newest first.
+func GitHubIssues(cfg Configuration, repository string) ([]GitHubIssue, error) {
+	issues := []GitHubIssue{}
+	err := githubGet(cfg, "/repos/"+repository+"/issues?state=all&sort=created&direction=desc&per_page=20", &issues)
+	return issues, err
+}
+
+// Catchup marks everything the repository has right now as posted, so only what comes after is.
+// Done on the first check of a new watch.
+func (watch *GitHubWatch) Catchup(cfg Configuration) error {
+	releases, err := GitHubReleases(cfg, watch.Repository)
+	if err != nil {
+		return err
+	}
+	for _, release := range releases {
+		if release.ID > watch.LastRelease {
+			watch.LastRelease = release.ID
+		}
+	}
+	issues, err := GitHubIssues(cfg, watch.Repository)
+	if err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		if issue.Number > watch.LastNumber {
+			watch.LastNumber = issue.Number
+		}
+	}
+	return nil
+}
+
+// Store stores the watch.
+func (watch *GitHubWatch) Store(kvs KeyValueStore) error {
+	return kvs.Set(watch.GuildID, "github", watch.ID, watch)
+}
+
+// Delete forgets the watch.
+func (watch *GitHubWatch) Delete(kvs KeyValueStore) error {
+	return kvs.Delete(watch.GuildID, "github", watch.ID)
+}
+
+// GetGitHubWatch gets a single watch of the guild.
+func GetGitHubWatch(kvs KeyValueStore, guildID discord.GuildID, id int64) (exist bool, watch GitHubWatch, err error) {
+	exist, err = kvs.Get(guildID, "github", id, &watch)
+	return exist, watch, err
+}
+
+// GetGitHubWatches gets all the watches of the guild, in the order they were added.
+func GetGitHubWatches(kvs KeyValueStore, guildID discord.GuildID) ([]GitHubWatch, error) {
+	keys, err := kvs.Keys(guildID, "github")
+	if err != nil {
+		return nil, fmt.Errorf("getting GitHub watches could not get keys: %w", err)
+	}
+	watches := []GitHubWatch{}
+	for _, key := range keys {
+		watch := GitHubWatch{}
+		exist, err := kvs.Get(guildID, "github", key, &watch)
+		if err != nil {
+			return nil, fmt.Errorf("getting GitHub watches could not get %s: %w", key, err)
+		}
+		if exist {
+			watches = append(watches, watch)
+		}
+	}
+	sort.Slice(watches, func(i, j int) bool {
+		return watches[i].ID < watches[j].ID
+	})
+	return watches, nil
+}
+
+// githubEmbed makes the embed for a release, issue or pull request.
+func githubEmbed(repository string, title string, url string, body string, author githubUser, when time.Time, color discord.Color) discord.Embed {
+	body = utility.PlainText(body)
+	if len([]rune(body)) > 300 {
+		body = utility.Substring(body, 0, 299) + "…"
+	}
+	embed := discord.Embed{
+		Title:       utility.Substring(title, 0, 256),
+		URL:         url,
+		Description: body,
+		Color:       color,
+		Footer:      &discord.EmbedFooter{Text: repository},
+	}
+	if author.Login != "" {
+		embed.Author = &discord.EmbedAuthor{Name: author.Login, Icon: author.AvatarURL}
+	}
+	if !when.IsZero() {
+		embed.Timestamp = discord.NewTimestamp(when)
+	}
+	return embed
+}
+
+// newEmbeds returns the embeds for what's new since the last check, oldest first, and moves the watch past them.
+func (watch *GitHubWatch) newEmbeds(cfg Configuration) ([]discord.Embed, error) {
+	embeds := []discord.Embed{}
+	if watch.Releases {
+		releases, err := GitHubReleases(cfg, watch.Repository)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(releases) - 1; i >= 0; i-- {
+			release := releases[i]
+			if release.ID <= watch.LastRelease || release.Draft {
+				continue
+			}
+			watch.LastRelease = release.ID
+			name := release.Name
+			if name == "" {
+				name = release.TagName
+			}
+			kind := "Release"
+			if release.Prerelease {
+				kind = "Pre-release"
+			}
+			embeds = append(embeds, githubEmbed(watch.Repository, fmt.Sprintf("%s %s", kind, name), release.HTMLURL, release.Body, release.Author, release.PublishedAt, 0x8957e5))
+		}
+	}
+	if watch.Issues || watch.Pulls {
+		issues, err := GitHubIssues(cfg, watch.Repository)
+		if err != nil {
+			return nil, err
+		}
+		highest := watch.LastNumber
+		for i := len(issues) - 1; i >= 0; i-- {
+			issue := issues[i]
+			if issue.Number <= watch.LastNumber {
+				continue
+			}
+			if issue.Number > highest {
+				highest = issue.Number
+			}
+			isPull := issue.PullRequest != nil
+			if (isPull && !watch.Pulls) || (!isPull && !watch.Issues) {
+				continue
+			}
+			kind, color := "Issue", discord.Color(0x1f883d)
+			if isPull {
+				kind, color = "Pull request", discord.Color(0x0969da)
+			}
+			embeds = append(embeds, githubEmbed(watch.Repository, fmt.Sprintf("%s #%d: %s", kind, issue.Number, issue.Title), issue.HTMLURL, issue.Body, issue.User, issue.CreatedAt, color))
+		}
+		watch.LastNumber = highest
+	}
+	return embeds, nil
+}
+
+// CheckGitHub checks all the watched repositories of all the guilds, and posts what's new.
+func CheckGitHub(state *state.State, kvs KeyValueStore, cfg Configuration) error {
+	guilds, err := state.Guilds()
+	if err != nil {
+		return fmt.Errorf("checking GitHub could not fetch current guilds: %w", err)
+	}
+	for _, guild := range guilds {
+		err := GuildJob(kvs, guild.ID, "github", githubPollInterval, func() error {
+			watches, err := GetGitHubWatches(kvs, guild.ID)
+			if err != nil {
+				return err
+			}
+			for _, watch := range watches {
+				var embeds []discord.Embed
+				if watch.CaughtUp {
+					embeds, err = watch.newEmbeds(cfg)
+				} else if err = watch.Catchup(cfg); err == nil {
+					watch.CaughtUp = true
+				}
+				if err != nil {
+					watch.LastError = err.Error()
+				} else {
+					watch.LastError = ""
+				}
+				// Discord takes up to ten embeds in a message.
+				for start := 0; start < len(embeds); start += 10 {
+					end := start + 10
+					if end > len(embeds) {
+						end = len(embeds)
+					}
+					if _, err := state.SendMessageComplex(watch.ChannelID, api.SendMessageData{Embeds: embeds[start:end]}); err != nil {
+						watch.LastError = "could not post in the channel: " + err.Error()
+						break
+					}
+				}
+				if exist, _, err := GetGitHubWatch(kvs, guild.ID, watch.ID); err != nil || !exist {
+					continue
+				}
+				if err := watch.Store(kvs); err != nil {
+					log.Printf("[%s] Could not store GitHub watch %d after checking it: %s", guild.ID, watch.ID, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartCheckingGitHub starts a ticker and, every githubPollInterval, calls CheckGitHub with the configuration as it is then.
+// Intended to be called as a goroutine.
+func StartCheckingGitHub(state *state.State, kvs KeyValueStore, cfg *Configuration) {
+	ticker := time.NewTicker(githubPollInterval)
+	for {
+		<-ticker.C
+		if err := CheckGitHub(state, kvs, cfg.Snapshot()); err != nil {
+			log.Printf("Error encountered checking GitHub: %s", err)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/gob"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/diamondburned/arikawa/v3/discord"
 	bolt "go.etcd.io/bbolt"
@@ -12,6 +13,10 @@ import (
 
 type komainuBolt struct {
 	bolt *bolt.DB
+	path string
+	lock sync.RWMutex // Held for writing only while the database is swapped out for a compacted one.
+	encryption
+	localLocks
 }
 
 func OpenKomainuBolt(path string) (*komainuBolt, error) {
@@ -20,10 +25,23 @@ func OpenKomainuBolt(path string) (*komainuBolt, error) {
 		return nil, err
 	}
 	return &komainuBolt{
-		newBolt,
+		bolt: newBolt,
+		path: path,
 	}, nil
 }
 
+func (kb *komainuBolt) update(fn func(*bolt.Tx) error) error {
+	kb.lock.RLock()
+	defer kb.lock.RUnlock()
+	return kb.bolt.Update(fn)
+}
+
+func (kb *komainuBolt) view(fn func(*bolt.Tx) error) error {
+	kb.lock.RLock()
+	defer kb.lock.RUnlock()
+	return kb.bolt.View(fn)
+}
+
 func (kb *komainuBolt) createBucket(transaction *bolt.Tx, guild []byte, collection []byte) (bucket *bolt.Bucket, err error) {
 	guildBucket, err := transaction.CreateBucketIfNotExists(guild)
 	if err != nil {
@@ -45,7 +63,7 @@ func (kb *komainuBolt) getBucket(transaction *bolt.Tx, guild []byte, collection
 }
 
 func (kb *komainuBolt) store(guild []byte, collection []byte, key []byte, value []byte) (err error) {
-	return kb.bolt.Update(func(tx *bolt.Tx) (err error) {
+	return kb.update(func(tx *bolt.Tx) (err error) {
 		if tx == nil {
 			return errors.New("storage failed to open Update transaction")
 		}
@@ -62,7 +80,7 @@ func (kb *komainuBolt) store(guild []byte, collection []byte, key []byte, value
 }
 
 func (kb *komainuBolt) retrieve(guild []byte, collection []byte, key []byte) (found bool, value []byte, err error) {
-	err = kb.bolt.View(func(tx *bolt.Tx) (err error) {
+	err = kb.view(func(tx *bolt.Tx) (err error) {
 		if tx == nil {
 			return errors.New("storage failed to open View transaction")
 		}
@@ -81,8 +99,34 @@ func (kb *komainuBolt) retrieve(guild []byte, collection []byte, key []byte) (fo
 	return
 }
 
-func (kb *komainuBolt) remove(guild []byte, collection []byte, key []byte) (err error) {
-	err = kb.bolt.Update(func(tx *bolt.Tx) (err error) {
+func (kb *komainuBolt) retrieveMany(guild []byte, collection []byte, keys [][]byte, decode func(i int, raw []byte) error) (found []bool, err error) {
+	found = make([]bool, len(keys))
+	err = kb.view(func(tx *bolt.Tx) (err error) {
+		if tx == nil {
+			return errors.New("storage failed to open View transaction")
+		}
+		bucket := kb.getBucket(tx, guild, collection)
+		if bucket == nil {
+			return
+		}
+		for i, key := range keys {
+			got := bucket.Get(key)
+			if got == nil {
+				continue
+			}
+			found[i] = true
+			if err := decode(i, got); err != nil {
+				return err
+			}
+		}
+		return
+	})
+	return
+}
+
+// remove deletes the key, first putting what it was in the trash if keep says so. Both happen, or neither does.
+func (kb *komainuBolt) remove(guild []byte, collection []byte, key []byte, keep bool) (err error) {
+	err = kb.update(func(tx *bolt.Tx) (err error) {
 		if tx == nil {
 			return errors.New("storage failed to open Delete transaction")
 		}
@@ -90,6 +134,11 @@ func (kb *komainuBolt) remove(guild []byte, collection []byte, key []byte) (err
 		if bucket == nil {
 			return
 		}
+		if got := bucket.Get(key); keep && got != nil {
+			if err = kb.trash(tx, guild, collection, key, got); err != nil {
+				return
+			}
+		}
 		err = bucket.Delete(key)
 		if err != nil {
 			return fmt.Errorf("storage failed to delete: %w", err)
@@ -99,8 +148,60 @@ func (kb *komainuBolt) remove(guild []byte, collection []byte, key []byte) (err
 	return
 }
 
+func (kb *komainuBolt) trash(tx *bolt.Tx, guild []byte, collection []byte, key []byte, value []byte) error {
+	entry, err := encodeTrashEntry(string(collection), string(key), value)
+	if err != nil {
+		return err
+	}
+	bucket, err := kb.createBucket(tx, guild, []byte(trashCollection))
+	if err != nil {
+		return err
+	}
+	if err := bucket.Put([]byte(trashKey(string(collection), string(key))), entry); err != nil {
+		return fmt.Errorf("bolt store failed to Put trash entry: %w", err)
+	}
+	return nil
+}
+
+func (kb *komainuBolt) restore(guild []byte, collection []byte, key []byte) (restored bool, err error) {
+	err = kb.update(func(tx *bolt.Tx) (err error) {
+		if tx == nil {
+			return errors.New("storage failed to open Restore transaction")
+		}
+		trash := kb.getBucket(tx, guild, []byte(trashCollection))
+		if trash == nil {
+			return
+		}
+		trashKeyb := []byte(trashKey(string(collection), string(key)))
+		got := trash.Get(trashKeyb)
+		if got == nil {
+			return
+		}
+		entry, err := decodeTrashEntry(got)
+		if err != nil {
+			return
+		}
+		bucket, err := kb.createBucket(tx, guild, collection)
+		if err != nil {
+			return
+		}
+		if bucket.Get(key) != nil {
+			return ErrTrashOccupied
+		}
+		if err = bucket.Put(key, entry.Value); err != nil {
+			return fmt.Errorf("bolt store failed to Put restored value: %w", err)
+		}
+		if err = trash.Delete(trashKeyb); err != nil {
+			return fmt.Errorf("storage failed to delete trash entry: %w", err)
+		}
+		restored = true
+		return
+	})
+	return
+}
+
 func (kb *komainuBolt) keys(guild []byte, collection []byte) (keys []string, err error) {
-	err = kb.bolt.View(func(tx *bolt.Tx) (err error) {
+	err = kb.view(func(tx *bolt.Tx) (err error) {
 		bucket := kb.getBucket(tx, guild, collection)
 		if bucket == nil {
 			return
@@ -114,48 +215,120 @@ func (kb *komainuBolt) keys(guild []byte, collection []byte) (keys []string, err
 	return
 }
 
+func (kb *komainuBolt) usage(guild []byte) (usage map[string]CollectionUsage, err error) {
+	usage = map[string]CollectionUsage{}
+	err = kb.view(func(tx *bolt.Tx) (err error) {
+		guildBucket := tx.Bucket(guild)
+		if guildBucket == nil {
+			return
+		}
+		return guildBucket.ForEach(func(collection, value []byte) error {
+			if value != nil {
+				return nil // Not a bucket, so not a collection.
+			}
+			counted := CollectionUsage{}
+			err := guildBucket.Bucket(collection).ForEach(func(k, v []byte) error {
+				counted.Keys++
+				counted.Bytes += len(k) + len(v)
+				return nil
+			})
+			usage[string(collection)] = counted
+			return err
+		})
+	})
+	return
+}
+
+// guild is the name of the bucket for the guild. GlobalScope has no name of its own, and bolt needs one.
+func (kb *komainuBolt) guild(guildID discord.GuildID) []byte {
+	if guildID == GlobalScope {
+		return []byte("global")
+	}
+	return []byte(guildID.String())
+}
+
 func (kb *komainuBolt) key(raw any) []byte {
 	return []byte(fmt.Sprintf("%v", raw))
 }
 
 func (kb *komainuBolt) Set(guildID discord.GuildID, collection string, key any, value any) (err error) {
-	guildb := []byte(guildID.String())
+	guildb := kb.guild(guildID)
 	collectionb := []byte(collection)
 	keyb := kb.key(key)
 	var inputBuffer bytes.Buffer
 	if err := gob.NewEncoder(&inputBuffer).Encode(value); err != nil {
 		return fmt.Errorf("unable to encode raw value %v as gob for Set: %w", value, err)
 	}
-	return kb.store(guildb, collectionb, keyb, inputBuffer.Bytes())
+	sealed, err := kb.seal(guildb, collectionb, keyb, inputBuffer.Bytes())
+	if err != nil {
+		return fmt.Errorf("unable to encrypt value for Set: %w", err)
+	}
+	return kb.store(guildb, collectionb, keyb, sealed)
 }
 
 func (kb *komainuBolt) Get(guildID discord.GuildID, collection string, key any, out any) (found bool, err error) {
-	guildb := []byte(guildID.String())
+	guildb := kb.guild(guildID)
 	collectionb := []byte(collection)
 	keyb := kb.key(key)
 	found, raw, err := kb.retrieve(guildb, collectionb, keyb)
 	if err != nil || !found {
 		return
 	}
+	if raw, err = kb.open(guildb, collectionb, keyb, raw); err != nil {
+		return
+	}
 	var outputBuffer bytes.Buffer
 	outputBuffer.Write(raw)
 	err = gob.NewDecoder(&outputBuffer).Decode(out)
 	return
 }
 
+// GetMany gets the values for all the keys in one go, decoding each into whatever out gives for its index.
+// That's a lot quicker than one Get per key, when there are thousands of them.
+func (kb *komainuBolt) GetMany(guildID discord.GuildID, collection string, keys []any, out func(i int) any) (found []bool, err error) {
+	guildb := kb.guild(guildID)
+	collectionb := []byte(collection)
+	keysb := make([][]byte, len(keys))
+	for i, key := range keys {
+		keysb[i] = kb.key(key)
+	}
+	return kb.retrieveMany(guildb, collectionb, keysb, func(i int, raw []byte) error {
+		raw, err := kb.open(guildb, collectionb, keysb[i], raw)
+		if err != nil {
+			return err
+		}
+		return gob.NewDecoder(bytes.NewReader(raw)).Decode(out(i))
+	})
+}
+
 func (kb *komainuBolt) Delete(guildID discord.GuildID, collection string, key any) (err error) {
-	guildb := []byte(guildID.String())
+	guildb := kb.guild(guildID)
 	collectionb := []byte(collection)
 	keyb := kb.key(key)
-	return kb.remove(guildb, collectionb, keyb)
+	return kb.remove(guildb, collectionb, keyb, trashedCollections[collection])
+}
+
+// Restore puts back what was last deleted under the key, if it's still in the trash. It won't overwrite anything that
+// has been stored there since, and returns ErrTrashOccupied instead.
+func (kb *komainuBolt) Restore(guildID discord.GuildID, collection string, key any) (restored bool, err error) {
+	guildb := kb.guild(guildID)
+	collectionb := []byte(collection)
+	keyb := kb.key(key)
+	return kb.restore(guildb, collectionb, keyb)
 }
 
 func (kb *komainuBolt) Keys(guildID discord.GuildID, collection string) (keys []string, err error) {
-	guildb := []byte(guildID.String())
+	guildb := kb.guild(guildID)
 	collectionb := []byte(collection)
 	return kb.keys(guildb, collectionb)
 }
 
+func (kb *komainuBolt) Usage(guildID discord.GuildID) (usage map[string]CollectionUsage, err error) {
+	return kb.usage(kb.guild(guildID))
+}
+
 func (kb *komainuBolt) Close() error {
+	kb.lock.Lock()
+	defer kb.lock.Unlock()
 	return kb.bolt.Close()
 }
@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"bytes"
+	"errors"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/diamondburned/arikawa/v3/discord"
 )
@@ -148,3 +151,326 @@ func TestMissingValue(t *testing.T) {
 		return
 	}
 }
+
+func TestUsage(t *testing.T) {
+	kvs, err := GetKVS(filename)
+	if err != nil {
+		t.Errorf("Could not open test file: %s", err)
+	}
+	t.Cleanup(func() {
+		kvs.Close()
+		os.Remove(filename)
+	})
+
+	usage, err := kvs.Usage(testGuild)
+	if err != nil {
+		t.Errorf("Could not get usage of empty guild: %v", err)
+		return
+	}
+	if len(usage) != 0 {
+		t.Errorf("Expected no usage, Got %v", usage)
+		return
+	}
+
+	for _, key := range []string{"first", "second"} {
+		if err := kvs.Set(testGuild, col, key, "some value"); err != nil {
+			t.Errorf("Could not set test input value: %v", err)
+			return
+		}
+	}
+	usage, err = kvs.Usage(testGuild)
+	if err != nil {
+		t.Errorf("Could not get usage: %v", err)
+		return
+	}
+	if usage[col].Keys != 2 {
+		t.Errorf("Expected 2 keys, Got %d", usage[col].Keys)
+	}
+	if usage[col].Bytes <= len("first")+len("second") {
+		t.Errorf("Expected more than the keys in bytes, Got %d", usage[col].Bytes)
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	kvs, err := GetKVS(filename)
+	if err != nil {
+		t.Errorf("Could not open test file: %s", err)
+	}
+	t.Cleanup(func() {
+		kvs.Close()
+		os.Remove(filename)
+	})
+
+	for key, value := range map[string]int{"first": 1, "third": 3} {
+		if err := kvs.Set(testGuild, col, key, value); err != nil {
+			t.Errorf("Could not set test input value: %v", err)
+			return
+		}
+	}
+
+	output := make([]int, 3)
+	found, err := kvs.GetMany(testGuild, col, []any{"first", "second", "third"}, func(i int) any { return &output[i] })
+	if err != nil {
+		t.Errorf("Could not retrieve values: %v", err)
+		return
+	}
+	expectedFound := []bool{true, false, true}
+	expected := []int{1, 0, 3}
+	for i := range expected {
+		if found[i] != expectedFound[i] {
+			t.Errorf("Key %d: Expected found to be %t, Got %t", i, expectedFound[i], found[i])
+		}
+		if output[i] != expected[i] {
+			t.Errorf("Key %d: Expected %d, Got %d", i, expected[i], output[i])
+		}
+	}
+}
+
+func TestEncrypted(t *testing.T) {
+	kvs, err := OpenKomainuBolt(filename)
+	if err != nil {
+		t.Errorf("Could not open test file: %s", err)
+	}
+	t.Cleanup(func() {
+		kvs.Close()
+		os.Remove(filename)
+	})
+
+	key := "some arbituary key"
+	input := "some arbituary secret"
+	if err := kvs.Set(testGuild, col, key, input); err != nil {
+		t.Errorf("Could not set unencrypted value: %v", err)
+		return
+	}
+	if err := kvs.Encrypt(make([]byte, 32), []string{col}); err != nil {
+		t.Errorf("Could not set up encryption: %v", err)
+		return
+	}
+
+	var output string
+	if _, err := kvs.Get(testGuild, col, key, &output); err != nil || output != input {
+		t.Errorf("Expected the value from before encryption, %s, Got %s (%v)", input, output, err)
+		return
+	}
+	if err := kvs.Set(testGuild, col, key, input); err != nil {
+		t.Errorf("Could not set encrypted value: %v", err)
+		return
+	}
+	_, raw, err := kvs.retrieve([]byte(testGuild.String()), []byte(col), []byte(key))
+	if err != nil {
+		t.Errorf("Could not retrieve raw value: %v", err)
+		return
+	}
+	if bytes.Contains(raw, []byte(input)) {
+		t.Error("The value was stored in plain text")
+	}
+	output = ""
+	if _, err := kvs.Get(testGuild, col, key, &output); err != nil || output != input {
+		t.Errorf("Expected %s, Got %s (%v)", input, output, err)
+	}
+
+	kvs.aead = nil
+	if _, err := kvs.Get(testGuild, col, key, &output); !errors.Is(err, ErrNoEncryptionKey) {
+		t.Errorf("Expected ErrNoEncryptionKey without the key, Got %v", err)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	kvs, err := OpenKomainuBolt(filename)
+	if err != nil {
+		t.Errorf("Could not open test file: %s", err)
+	}
+	t.Cleanup(func() {
+		kvs.Close()
+		os.Remove(filename)
+	})
+
+	for i := 0; i < 1000; i++ {
+		if err := kvs.Set(testGuild, col, i, "some arbituary string that takes up a bit of space"); err != nil {
+			t.Errorf("Could not set test input value: %v", err)
+			return
+		}
+	}
+	for i := 1; i < 1000; i++ {
+		if err := kvs.Delete(testGuild, col, i); err != nil {
+			t.Errorf("Could not delete test value: %v", err)
+			return
+		}
+	}
+	before, after, err := kvs.Compact()
+	if err != nil {
+		t.Errorf("Could not compact: %v", err)
+		return
+	}
+	if after >= before {
+		t.Errorf("Expected the file to shrink from %d bytes, Got %d", before, after)
+	}
+
+	var output string
+	found, err := kvs.Get(testGuild, col, 0, &output)
+	if err != nil || !found {
+		t.Errorf("Could not read back the value that was kept: %v", err)
+	}
+	guilds, err := kvs.Guilds()
+	if err != nil || len(guilds) != 1 || guilds[0] != testGuild {
+		t.Errorf("Expected only %s in storage, Got %v (%v)", testGuild, guilds, err)
+	}
+}
+
+func TestGlobalScope(t *testing.T) {
+	kvs, err := GetKVS(filename)
+	if err != nil {
+		t.Errorf("Could not open test file: %s", err)
+	}
+	t.Cleanup(func() {
+		kvs.Close()
+		os.Remove(filename)
+	})
+
+	if err := kvs.Set(GlobalScope, col, "key", 42); err != nil {
+		t.Errorf("Could not set value in GlobalScope: %v", err)
+		return
+	}
+	var output int
+	found, err := kvs.Get(GlobalScope, col, "key", &output)
+	if err != nil || !found || output != 42 {
+		t.Errorf("Expected 42 from GlobalScope, Got %d (found: %t, err: %v)", output, found, err)
+	}
+	guilds, err := kvs.Guilds()
+	if err != nil || len(guilds) != 0 {
+		t.Errorf("Expected GlobalScope not to be listed as a guild, Got %v (%v)", guilds, err)
+	}
+}
+
+func TestTrash(t *testing.T) {
+	for _, encrypted := range []bool{false, true} {
+		kvs, err := OpenKomainuBolt(filename)
+		if err != nil {
+			t.Errorf("Could not open test file: %s", err)
+			return
+		}
+		if encrypted {
+			if err := kvs.Encrypt(make([]byte, 32), []string{"faq"}); err != nil {
+				t.Errorf("Could not set up encryption: %v", err)
+				return
+			}
+		}
+		t.Run(map[bool]string{false: "plain", true: "encrypted"}[encrypted], func(t *testing.T) {
+			testTrash(t, kvs, encrypted)
+		})
+		kvs.Close()
+		os.Remove(filename)
+	}
+}
+
+func testTrash(t *testing.T, kvs *komainuBolt, encrypted bool) {
+	input := "Be nice"
+	if err := kvs.Set(testGuild, "faq", "rules", input); err != nil {
+		t.Fatalf("Could not set test input value: %v", err)
+	}
+	if err := kvs.Delete(testGuild, "faq", "rules"); err != nil {
+		t.Fatalf("Could not delete: %v", err)
+	}
+	var output string
+	if found, err := kvs.Get(testGuild, "faq", "rules", &output); err != nil || found {
+		t.Errorf("Expected it to be gone, Got found: %t, %v", found, err)
+	}
+	trash, err := GetTrash(kvs, testGuild, "faq")
+	if err != nil || len(trash) != 1 || trash[0].Collection != "faq" || trash[0].Key != "rules" {
+		t.Fatalf("Expected it in the trash, Got %+v (%v)", trash, err)
+	}
+	if expires := trash[0].Expires(); expires.Before(time.Now().Add(TrashRetention - time.Minute)) {
+		t.Errorf("Expected it to be kept for %s, Got until %s", TrashRetention, expires)
+	}
+	if other, err := GetTrash(kvs, testGuild, "votes"); err != nil || len(other) != 0 {
+		t.Errorf("Expected nothing deleted from votes, Got %+v (%v)", other, err)
+	}
+	_, raw, err := kvs.retrieve([]byte(testGuild.String()), []byte(trashCollection), []byte(trashKey("faq", "rules")))
+	if err != nil {
+		t.Fatalf("Could not retrieve raw trash entry: %v", err)
+	}
+	if encrypted && bytes.Contains(raw, []byte(input)) {
+		t.Error("The trashed value was kept in plain text")
+	}
+
+	if restored, err := kvs.Restore(testGuild, "faq", "rules"); err != nil || !restored {
+		t.Fatalf("Could not restore: %t (%v)", restored, err)
+	}
+	if _, err := kvs.Get(testGuild, "faq", "rules", &output); err != nil || output != input {
+		t.Errorf("Expected %s back, Got %s (%v)", input, output, err)
+	}
+	if trash, err := GetTrash(kvs, testGuild, ""); err != nil || len(trash) != 0 {
+		t.Errorf("Expected the trash to be empty once restored, Got %+v (%v)", trash, err)
+	}
+	if restored, err := kvs.Restore(testGuild, "faq", "rules"); err != nil || restored {
+		t.Errorf("Expected nothing more to restore, Got %t (%v)", restored, err)
+	}
+
+	if err := kvs.Delete(testGuild, "faq", "rules"); err != nil {
+		t.Fatalf("Could not delete: %v", err)
+	}
+	if err := kvs.Set(testGuild, "faq", "rules", "Be very nice"); err != nil {
+		t.Fatalf("Could not set test input value: %v", err)
+	}
+	if _, err := kvs.Restore(testGuild, "faq", "rules"); !errors.Is(err, ErrTrashOccupied) {
+		t.Errorf("Expected ErrTrashOccupied with something else there, Got %v", err)
+	}
+	if _, err := kvs.Get(testGuild, "faq", "rules", &output); err != nil || output != "Be very nice" {
+		t.Errorf("Expected what was stored since to be kept, Got %s (%v)", output, err)
+	}
+
+	if err := kvs.Set(testGuild, col, "key", input); err != nil {
+		t.Fatalf("Could not set test input value: %v", err)
+	}
+	if err := kvs.Delete(testGuild, col, "key"); err != nil {
+		t.Fatalf("Could not delete: %v", err)
+	}
+	if restored, err := kvs.Restore(testGuild, col, "key"); err != nil || restored {
+		t.Errorf("Expected nothing to restore from a collection without a trash, Got %t (%v)", restored, err)
+	}
+
+	old := TrashEntry{Collection: "faq", Key: "old", Deleted: time.Now().Add(-TrashRetention - time.Hour).Unix()}
+	if err := kvs.Set(testGuild, trashCollection, trashKey(old.Collection, old.Key), old); err != nil {
+		t.Fatalf("Could not put an old entry in the trash: %v", err)
+	}
+	if err := PruneTrash(kvs, testGuild); err != nil {
+		t.Fatalf("Could not prune the trash: %v", err)
+	}
+	trash, err = GetTrash(kvs, testGuild, "faq")
+	if err != nil || len(trash) != 1 || trash[0].Key != "rules" {
+		t.Errorf("Expected only the old entry to be pruned, Got %+v (%v)", trash, err)
+	}
+}
+
+func TestRoleHistory(t *testing.T) {
+	kvs, err := OpenKomainuBolt(filename)
+	if err != nil {
+		t.Errorf("Could not open test file: %s", err)
+		return
+	}
+	t.Cleanup(func() {
+		kvs.Close()
+		os.Remove(filename)
+	})
+	userID := discord.UserID(1)
+
+	if err := NoteRoles(kvs, testGuild, userID, []discord.RoleID{10, 11}); err != nil {
+		t.Fatalf("Could not note roles: %v", err)
+	}
+	if changes, err := GetRoleChanges(kvs, testGuild, userID); err != nil || len(changes) != 0 {
+		t.Errorf("Expected noting roles not to count as changes, Got %+v (%v)", changes, err)
+	}
+	changes, err := RecordRoles(kvs, testGuild, userID, []discord.RoleID{11, 12})
+	if err != nil {
+		t.Fatalf("Could not record roles: %v", err)
+	}
+	if len(changes) != 2 || changes[0].RoleID != 12 || !changes[0].Added || changes[1].RoleID != 10 || changes[1].Added {
+		t.Errorf("Expected 12 to be added and 10 lost, Got %+v", changes)
+	}
+	if changes, err := RecordRoles(kvs, testGuild, userID, []discord.RoleID{12, 11}); err != nil || len(changes) != 0 {
+		t.Errorf("Expected no changes for the same roles, Got %+v (%v)", changes, err)
+	}
+	if changes, err := GetRoleChanges(kvs, testGuild, userID); err != nil || len(changes) != 2 {
+		t.Errorf("Expected both changes to be kept, Got %+v (%v)", changes, err)
+	}
+}
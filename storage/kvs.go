@@ -1,6 +1,11 @@
 package storage
 
 import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
 	"github.com/diamondburned/arikawa/v3/discord"
 )
 
@@ -8,6 +13,65 @@ type KeyValueStore interface {
 	Close() error
 	Set(guild discord.GuildID, collection string, key any, rawValue any) (err error)
 	Get(guild discord.GuildID, collection string, key any, out any) (exist bool, err error)
+	GetMany(guild discord.GuildID, collection string, keys []any, out func(i int) any) (exist []bool, err error)
 	Delete(guild discord.GuildID, collection string, key any) (err error)
+	Restore(guild discord.GuildID, collection string, key any) (restored bool, err error)
 	Keys(guild discord.GuildID, collection string) (keys []string, err error)
+	Usage(guild discord.GuildID) (usage map[string]CollectionUsage, err error)
+	Guilds() (guilds []discord.GuildID, err error)
+	Compact() (before int64, after int64, err error)
+	Lock(guild discord.GuildID, name string, ttl time.Duration) (unlock func(), acquired bool, err error)
 }
+
+// OpenStorage opens the store the configuration says to use, which is Redis if there is a RedisURL, and bolt if not.
+// Encryption is set up too, if there is a key.
+func OpenStorage(c *Configuration) (KeyValueStore, error) {
+	var store interface {
+		KeyValueStore
+		Encrypt(key []byte, collections []string) error
+	}
+	if c.RedisURL != "" {
+		redis, err := OpenRedis(c.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("Using Redis for storage")
+		store = redis
+	} else {
+		bolt, err := OpenKomainuBolt(c.Storage())
+		if err != nil {
+			return nil, err
+		}
+		store = bolt
+	}
+
+	key, err := c.EncryptionKey()
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("could not get the encryption key: %w", err)
+	}
+	if key != nil {
+		if err := store.Encrypt(key, c.Encrypted()); err != nil {
+			store.Close()
+			return nil, fmt.Errorf("could not set up encryption: %w", err)
+		}
+		log.Printf("Encrypting %s in storage\n", strings.Join(c.Encrypted(), ", "))
+	}
+	return store, nil
+}
+
+// CollectionUsage is how much a collection holds, with the size of keys and values added up.
+type CollectionUsage struct {
+	Keys  int
+	Bytes int
+}
+
+// PrivateScope gives what to store things under for a user talking to the bot in private, where there is no guild.
+// Snowflakes are unique across everything on Discord, so it never collides with an actual guild, or another user.
+func PrivateScope(userID discord.UserID) discord.GuildID {
+	return discord.GuildID(userID)
+}
+
+// GlobalScope is what to store things under that span guilds, like which of them want something from the bot.
+// No guild has the null ID, so it never collides with one.
+const GlobalScope = discord.NullGuildID
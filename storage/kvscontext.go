@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// contextKVS refuses to do anything once its context is done, so work that has been given up on stops touching storage.
+type contextKVS struct {
+	KeyValueStore
+	ctx context.Context
+}
+
+// WithContext wraps the KeyValueStore so every operation on it fails once ctx is done.
+// An operation already underway is not interrupted, but nothing new is started.
+func WithContext(ctx context.Context, store KeyValueStore) KeyValueStore {
+	return contextKVS{store, ctx}
+}
+
+func (k contextKVS) check() error {
+	if err := k.ctx.Err(); err != nil {
+		return fmt.Errorf("storage gave up: %w", err)
+	}
+	return nil
+}
+
+func (k contextKVS) Set(guild discord.GuildID, collection string, key any, rawValue any) error {
+	if err := k.check(); err != nil {
+		return err
+	}
+	return k.KeyValueStore.Set(guild, collection, key, rawValue)
+}
+
+func (k contextKVS) Get(guild discord.GuildID, collection string, key any, out any) (bool, error) {
+	if err := k.check(); err != nil {
+		return false, err
+	}
+	return k.KeyValueStore.Get(guild, collection, key, out)
+}
+
+func (k contextKVS) GetMany(guild discord.GuildID, collection string, keys []any, out func(i int) any) ([]bool, error) {
+	if err := k.check(); err != nil {
+		return nil, err
+	}
+	return k.KeyValueStore.GetMany(guild, collection, keys, out)
+}
+
+func (k contextKVS) Delete(guild discord.GuildID, collection string, key any) error {
+	if err := k.check(); err != nil {
+		return err
+	}
+	return k.KeyValueStore.Delete(guild, collection, key)
+}
+
+func (k contextKVS) Restore(guild discord.GuildID, collection string, key any) (bool, error) {
+	if err := k.check(); err != nil {
+		return false, err
+	}
+	return k.KeyValueStore.Restore(guild, collection, key)
+}
+
+func (k contextKVS) Keys(guild discord.GuildID, collection string) ([]string, error) {
+	if err := k.check(); err != nil {
+		return nil, err
+	}
+	return k.KeyValueStore.Keys(guild, collection)
+}
+
+func (k contextKVS) Usage(guild discord.GuildID) (map[string]CollectionUsage, error) {
+	if err := k.check(); err != nil {
+		return nil, err
+	}
+	return k.KeyValueStore.Usage(guild)
+}
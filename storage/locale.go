@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// GetLocale gets the language code the guild picked, like "no", or blank if it didn't pick one.
+func GetLocale(kvs KeyValueStore, guildID discord.GuildID) (string, error) {
+	language := ""
+	_, err := kvs.Get(guildID, "locale", "language", &language)
+	return language, err
+}
+
+// SetLocale stores the language code the guild picked. Blank goes back to English.
+func SetLocale(kvs KeyValueStore, guildID discord.GuildID, language string) error {
+	if language == "" {
+		return kvs.Delete(guildID, "locale", "language")
+	}
+	return kvs.Set(guildID, "locale", "language", language)
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// localLocks are locks that only work within this process, which is plenty for a store only one process can open.
+type localLocks struct {
+	mutex sync.Mutex
+	held  map[string]time.Time // When each lock runs out, in case it's never unlocked.
+}
+
+// Lock takes the named lock for the guild, unless someone else has it. It runs out after ttl, so a lock that is never
+// unlocked, because whoever had it went away, doesn't stay locked forever. Call unlock when done, if it was acquired.
+func (l *localLocks) Lock(guild discord.GuildID, name string, ttl time.Duration) (unlock func(), acquired bool, err error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.held == nil {
+		l.held = map[string]time.Time{}
+	}
+	lockName := guild.String() + ":" + name
+	now := time.Now()
+	if expires, ok := l.held[lockName]; ok && expires.After(now) {
+		return func() {}, false, nil
+	}
+	expires := now.Add(ttl)
+	l.held[lockName] = expires
+	return func() {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		if l.held[lockName] == expires { // Not if it ran out, and someone else has it now.
+			delete(l.held, lockName)
+		}
+	}, true, nil
+}
+
+// recordLockTTL is how long a lock on a single record is held at most. Read-modify-writes are quick, so it only takes
+// this long if whoever had it went away.
+const recordLockTTL = 30 * time.Second
+
+// recordLockWait is how long to wait for someone else to be done with a record. Short enough to still respond in time.
+const recordLockWait = 2 * time.Second
+
+// lockRetry is how often WaitLock tries again for a lock someone else has.
+const lockRetry = 10 * time.Millisecond
+
+// WaitLock is like Lock, but waits up to wait for whoever has the lock to let go of it, rather than giving up right away.
+func WaitLock(kvs KeyValueStore, guild discord.GuildID, name string, ttl time.Duration, wait time.Duration) (unlock func(), err error) {
+	deadline := time.Now().Add(wait)
+	for {
+		unlock, acquired, err := kvs.Lock(guild, name, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			return unlock, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("waited %s for the %s lock, but it's still taken", wait, name)
+		}
+		time.Sleep(lockRetry)
+	}
+}
+
+// LockRecord locks the record with the key in the collection, waiting for anyone else that has it, so two
+// read-modify-writes of it can't overlap and lose one of the updates. Call unlock when done.
+func LockRecord(kvs KeyValueStore, guild discord.GuildID, collection string, key any) (unlock func(), err error) {
+	return WaitLock(kvs, guild, fmt.Sprintf("%s:%v", collection, key), recordLockTTL, recordLockWait)
+}
+
+// GuildJob runs the named job for the guild, unless it's running already. When several instances of the bot share
+// storage, only the one that gets the lock runs it, so nothing is posted or done twice. The lock runs out after ttl,
+// in case whoever had it went away.
+func GuildJob(kvs KeyValueStore, guildID discord.GuildID, name string, ttl time.Duration, job func() error) error {
+	unlock, acquired, err := kvs.Lock(guildID, name, ttl)
+	if err != nil {
+		return fmt.Errorf("%s could not take the lock: %w", name, err)
+	}
+	if !acquired {
+		return nil // Someone else is on it.
+	}
+	defer unlock()
+	return job()
+}
@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// lockdownPermissions is what @everyone loses when a channel is locked down.
+const lockdownPermissions = discord.PermissionSendMessages | discord.PermissionSendMessagesInThreads | discord.PermissionAddReactions
+
+// LockedChannel remembers what the @everyone permission overwrite in a channel was before it was locked down, so it can be put back.
+type LockedChannel struct {
+	ChannelID    discord.ChannelID
+	HadOverwrite bool
+	Allow        discord.Permissions
+	Deny         discord.Permissions
+	Locked       int64
+}
+
+// LockedChannels gets all the channels that are currently locked down in the guild.
+func LockedChannels(kvs KeyValueStore, guildID discord.GuildID) ([]LockedChannel, error) {
+	keys, err := kvs.Keys(guildID, "lockdown")
+	if err != nil {
+		return nil, fmt.Errorf("getting locked channels could not get keys: %w", err)
+	}
+	locked := []LockedChannel{}
+	for _, key := range keys {
+		channel := LockedChannel{}
+		exist, err := kvs.Get(guildID, "lockdown", key, &channel)
+		if err != nil {
+			return nil, fmt.Errorf("getting locked channels could not get %s: %w", key, err)
+		}
+		if exist {
+			locked = append(locked, channel)
+		}
+	}
+	return locked, nil
+}
+
+// LockdownChannel stops @everyone from talking in the channel, and stores what it was before so LiftLockdown can undo it.
+// Locking a channel that is already locked does nothing, and locked is false. With dryRun, it only finds out if it would lock it.
+func LockdownChannel(state *state.State, kvs KeyValueStore, guildID discord.GuildID, channelID discord.ChannelID, reason string, dryRun bool) (locked bool, err error) {
+	exist, err := kvs.Get(guildID, "lockdown", channelID, &LockedChannel{})
+	if err != nil {
+		return false, fmt.Errorf("locking down channel could not check if it already is: %w", err)
+	}
+	if exist {
+		return false, nil
+	}
+	channel, err := state.Channel(channelID)
+	if err != nil {
+		return false, fmt.Errorf("locking down channel could not get the channel: %w", err)
+	}
+	if dryRun {
+		return true, nil
+	}
+	everyone := discord.Snowflake(guildID) // The @everyone role has the same ID as the guild.
+	before := LockedChannel{
+		ChannelID: channelID,
+		Locked:    time.Now().Unix(),
+	}
+	for _, overwrite := range channel.Overwrites {
+		if overwrite.Type == discord.OverwriteRole && overwrite.ID == everyone {
+			before.HadOverwrite = true
+			before.Allow = overwrite.Allow
+			before.Deny = overwrite.Deny
+		}
+	}
+	// Storing it first, because a lockdown we can't lift is worse than one that didn't happen.
+	if err := kvs.Set(guildID, "lockdown", channelID, before); err != nil {
+		return false, fmt.Errorf("locking down channel could not store the previous state: %w", err)
+	}
+	err = state.EditChannelPermission(channelID, everyone, api.EditChannelPermissionData{
+		Type:           discord.OverwriteRole,
+		Allow:          before.Allow &^ lockdownPermissions,
+		Deny:           before.Deny | lockdownPermissions,
+		AuditLogReason: api.AuditLogReason("Lockdown: " + reason),
+	})
+	return err == nil, err
+}
+
+// LockdownConfig is which channels a lockdown covers in a guild. With none set, it covers every text channel.
+type LockdownConfig struct {
+	Channels map[discord.ChannelID]bool
+}
+
+// GetLockdownConfig gets the lockdown setup for the guild.
+func GetLockdownConfig(kvs KeyValueStore, guildID discord.GuildID) (LockdownConfig, error) {
+	config := LockdownConfig{}
+	_, err := kvs.Get(guildID, "lockdownconfig", "config", &config)
+	if config.Channels == nil {
+		config.Channels = map[discord.ChannelID]bool{}
+	}
+	return config, err
+}
+
+// SetLockdownConfig stores the lockdown setup for the guild.
+func SetLockdownConfig(kvs KeyValueStore, guildID discord.GuildID, config LockdownConfig) error {
+	return kvs.Set(guildID, "lockdownconfig", "config", config)
+}
+
+// LockdownTargets gets the channels a lockdown covers: the text channels in the category if one is given,
+// otherwise the configured channels, or every text channel if none are configured.
+func LockdownTargets(state *state.State, kvs KeyValueStore, guildID discord.GuildID, categoryID discord.ChannelID) ([]discord.ChannelID, error) {
+	config, err := GetLockdownConfig(kvs, guildID)
+	if err != nil {
+		return nil, fmt.Errorf("lockdown could not get the config: %w", err)
+	}
+	channels, err := state.Channels(guildID)
+	if err != nil {
+		return nil, fmt.Errorf("lockdown could not get the channels: %w", err)
+	}
+	targets := []discord.ChannelID{}
+	for _, channel := range channels {
+		if channel.Type != discord.GuildText && channel.Type != discord.GuildNews {
+			continue
+		}
+		if categoryID.IsValid() {
+			if channel.ParentID != categoryID {
+				continue
+			}
+		} else if len(config.Channels) > 0 && !config.Channels[channel.ID] {
+			continue
+		}
+		targets = append(targets, channel.ID)
+	}
+	return targets, nil
+}
+
+// Lockdown locks down the channels a lockdown covers, as decided by LockdownTargets, and returns the ones it locked.
+// Channels that were already locked are left out. With dryRun, nothing is locked, and it returns the ones it would lock.
+func Lockdown(state *state.State, kvs KeyValueStore, guildID discord.GuildID, categoryID discord.ChannelID, reason string, dryRun bool) ([]discord.ChannelID, error) {
+	targets, err := LockdownTargets(state, kvs, guildID, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	locked := []discord.ChannelID{}
+	for _, channelID := range targets {
+		changed, err := LockdownChannel(state, kvs, guildID, channelID, reason, dryRun)
+		if err != nil {
+			return locked, fmt.Errorf("lockdown stopped at <#%s>: %w", channelID, err)
+		}
+		if changed {
+			locked = append(locked, channelID)
+		}
+	}
+	return locked, nil
+}
+
+// LiftLockdown puts the @everyone permission overwrites back the way they were in the locked channels, and returns the ones it unlocked.
+// With a category given, only the channels in it are unlocked. With dryRun, nothing is unlocked, and it returns the ones it would unlock.
+func LiftLockdown(state *state.State, kvs KeyValueStore, guildID discord.GuildID, categoryID discord.ChannelID, dryRun bool) ([]discord.ChannelID, error) {
+	locked, err := LockedChannels(kvs, guildID)
+	if err != nil {
+		return nil, err
+	}
+	everyone := discord.Snowflake(guildID)
+	unlocked := []discord.ChannelID{}
+	for _, channel := range locked {
+		if categoryID.IsValid() {
+			if current, err := state.Channel(channel.ChannelID); err != nil || current.ParentID != categoryID {
+				continue
+			}
+		}
+		if dryRun {
+			unlocked = append(unlocked, channel.ChannelID)
+			continue
+		}
+		if channel.HadOverwrite {
+			err = state.EditChannelPermission(channel.ChannelID, everyone, api.EditChannelPermissionData{
+				Type:           discord.OverwriteRole,
+				Allow:          channel.Allow,
+				Deny:           channel.Deny,
+				AuditLogReason: api.AuditLogReason("Lockdown lifted"),
+			})
+		} else {
+			err = state.DeleteChannelPermission(channel.ChannelID, everyone, api.AuditLogReason("Lockdown lifted"))
+		}
+		if err != nil {
+			return unlocked, fmt.Errorf("lifting lockdown could not restore <#%s>: %w", channel.ChannelID, err)
+		}
+		if err := kvs.Delete(guildID, "lockdown", channel.ChannelID); err != nil {
+			return unlocked, fmt.Errorf("lifting lockdown could not forget <#%s>: %w", channel.ChannelID, err)
+		}
+		unlocked = append(unlocked, channel.ChannelID)
+	}
+	return unlocked, nil
+}
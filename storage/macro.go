@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// MacroStep is a single thing a macro does. Which of the fields matter depends on the action.
+type MacroStep struct {
+	Action    string // One of "give", "take", "say" or "slowmode".
+	RoleID    discord.RoleID
+	ChannelID discord.ChannelID
+	Text      string
+	Seconds   int
+}
+
+// Macro is a named list of steps, run one after the other with /macro run.
+type Macro struct {
+	Name      string
+	Steps     []MacroStep
+	CreatedBy discord.UserID
+	Updated   int64
+}
+
+// SetMacro stores the macro, replacing any with the same name.
+func SetMacro(kvs KeyValueStore, guildID discord.GuildID, macro Macro) error {
+	return kvs.Set(guildID, "macros", macro.Name, macro)
+}
+
+// GetMacro gets the macro with the given name.
+func GetMacro(kvs KeyValueStore, guildID discord.GuildID, name string) (bool, Macro, error) {
+	macro := Macro{}
+	exist, err := kvs.Get(guildID, "macros", name, &macro)
+	return exist, macro, err
+}
+
+// DeleteMacro forgets the macro with the given name.
+func DeleteMacro(kvs KeyValueStore, guildID discord.GuildID, name string) error {
+	return kvs.Delete(guildID, "macros", name)
+}
+
+// GetMacros gets all the macros of the guild, sorted by name.
+func GetMacros(kvs KeyValueStore, guildID discord.GuildID) ([]Macro, error) {
+	keys, err := kvs.Keys(guildID, "macros")
+	if err != nil {
+		return nil, fmt.Errorf("macros could not get keys: %w", err)
+	}
+	sort.Strings(keys)
+	macros := make([]Macro, 0, len(keys))
+	for _, key := range keys {
+		exist, macro, err := GetMacro(kvs, guildID, key)
+		if err != nil {
+			return nil, fmt.Errorf("macros could not get %s: %w", key, err)
+		}
+		if exist {
+			macros = append(macros, macro)
+		}
+	}
+	return macros, nil
+}
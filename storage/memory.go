@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// ErrMemoryNoCompact is returned by Compact, as there is no file to give space back from.
+var ErrMemoryNoCompact = errors.New("memory storage has no file, so there's nothing to compact")
+
+// memoryKVS keeps everything in memory, and forgets it all when closed. It's for tests, where a file is just in the way.
+// Values are encoded the same way as in bolt, so anything that can't be stored there can't be stored here either.
+type memoryKVS struct {
+	mutex  sync.RWMutex
+	guilds map[discord.GuildID]map[string]map[string][]byte
+	encryption
+	localLocks
+}
+
+// OpenMemory makes a store that only lives in memory, empty to begin with.
+func OpenMemory() *memoryKVS {
+	return &memoryKVS{guilds: map[discord.GuildID]map[string]map[string][]byte{}}
+}
+
+func (m *memoryKVS) key(raw any) string {
+	return fmt.Sprintf("%v", raw)
+}
+
+// collection gets the collection, making it if create is true and it doesn't exist. Hold the lock before calling.
+func (m *memoryKVS) collection(guildID discord.GuildID, collection string, create bool) map[string][]byte {
+	guild, ok := m.guilds[guildID]
+	if !ok {
+		if !create {
+			return nil
+		}
+		guild = map[string]map[string][]byte{}
+		m.guilds[guildID] = guild
+	}
+	values, ok := guild[collection]
+	if !ok && create {
+		values = map[string][]byte{}
+		guild[collection] = values
+	}
+	return values
+}
+
+func (m *memoryKVS) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.guilds = map[discord.GuildID]map[string]map[string][]byte{}
+	return nil
+}
+
+func (m *memoryKVS) Set(guildID discord.GuildID, collection string, key any, value any) error {
+	keyb := m.key(key)
+	var inputBuffer bytes.Buffer
+	if err := gob.NewEncoder(&inputBuffer).Encode(value); err != nil {
+		return fmt.Errorf("unable to encode raw value %v as gob for Set: %w", value, err)
+	}
+	sealed, err := m.seal([]byte(guildID.String()), []byte(collection), []byte(keyb), inputBuffer.Bytes())
+	if err != nil {
+		return fmt.Errorf("unable to encrypt value for Set: %w", err)
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.collection(guildID, collection, true)[keyb] = sealed
+	return nil
+}
+
+func (m *memoryKVS) Get(guildID discord.GuildID, collection string, key any, out any) (bool, error) {
+	keyb := m.key(key)
+	m.mutex.RLock()
+	raw, found := m.collection(guildID, collection, false)[keyb]
+	m.mutex.RUnlock()
+	if !found {
+		return false, nil
+	}
+	raw, err := m.open([]byte(guildID.String()), []byte(collection), []byte(keyb), raw)
+	if err != nil {
+		return false, err
+	}
+	return true, gob.NewDecoder(bytes.NewReader(raw)).Decode(out)
+}
+
+func (m *memoryKVS) GetMany(guildID discord.GuildID, collection string, keys []any, out func(i int) any) ([]bool, error) {
+	found := make([]bool, len(keys))
+	for i, key := range keys {
+		exist, err := m.Get(guildID, collection, key, out(i))
+		if err != nil {
+			return nil, err
+		}
+		found[i] = exist
+	}
+	return found, nil
+}
+
+func (m *memoryKVS) Delete(guildID discord.GuildID, collection string, key any) error {
+	keyb := m.key(key)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	values := m.collection(guildID, collection, false)
+	got, ok := values[keyb]
+	if !ok {
+		return nil
+	}
+	if trashedCollections[collection] {
+		entry, err := encodeTrashEntry(collection, keyb, got)
+		if err != nil {
+			return err
+		}
+		m.collection(guildID, trashCollection, true)[trashKey(collection, keyb)] = entry
+	}
+	delete(values, keyb)
+	return nil
+}
+
+// Restore puts back what was last deleted under the key, if it's still in the trash. It won't overwrite anything that
+// has been stored there since, and returns ErrTrashOccupied instead.
+func (m *memoryKVS) Restore(guildID discord.GuildID, collection string, key any) (bool, error) {
+	keyb := m.key(key)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	trash := m.collection(guildID, trashCollection, false)
+	got, ok := trash[trashKey(collection, keyb)]
+	if !ok {
+		return false, nil
+	}
+	entry, err := decodeTrashEntry(got)
+	if err != nil {
+		return false, err
+	}
+	values := m.collection(guildID, collection, true)
+	if _, occupied := values[keyb]; occupied {
+		return false, ErrTrashOccupied
+	}
+	values[keyb] = entry.Value
+	delete(trash, trashKey(collection, keyb))
+	return true, nil
+}
+
+func (m *memoryKVS) Keys(guildID discord.GuildID, collection string) ([]string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	keys := []string{}
+	for key := range m.collection(guildID, collection, false) {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // The same order bolt gives them in.
+	return keys, nil
+}
+
+func (m *memoryKVS) Usage(guildID discord.GuildID) (map[string]CollectionUsage, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	usage := map[string]CollectionUsage{}
+	for collection, values := range m.guilds[guildID] {
+		counted := CollectionUsage{}
+		for key, value := range values {
+			counted.Keys++
+			counted.Bytes += len(key) + len(value)
+		}
+		usage[collection] = counted
+	}
+	return usage, nil
+}
+
+func (m *memoryKVS) Guilds() ([]discord.GuildID, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	guilds := []discord.GuildID{}
+	for guildID := range m.guilds {
+		if guildID != GlobalScope {
+			guilds = append(guilds, guildID)
+		}
+	}
+	sort.Slice(guilds, func(i, j int) bool {
+		return guilds[i] < guilds[j]
+	})
+	return guilds, nil
+}
+
+func (m *memoryKVS) Compact() (before int64, after int64, err error) {
+	return 0, 0, ErrMemoryNoCompact
+}
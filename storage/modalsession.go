@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// ModalSession is a modal dialog waiting to be submitted, with whatever the handler needs to know about how it came about.
+type ModalSession struct {
+	ID      string // The ID of the interaction that opened the modal, which is also the CustomID of the modal.
+	GuildID discord.GuildID
+	UserID  discord.UserID
+	Handler string
+	Data    map[string]string
+	Expires int64
+}
+
+// Store saves the session to kvs.
+func (session *ModalSession) Store(kvs KeyValueStore) error {
+	return kvs.Set(session.GuildID, "modalsessions", session.ID, session)
+}
+
+// Expired checks if it's too late to submit the modal.
+func (session *ModalSession) Expired(now time.Time) bool {
+	return session.Expires <= now.Unix()
+}
+
+// GetModalSession gets the session with the given ID, if it exists and hasn't expired.
+func GetModalSession(kvs KeyValueStore, guildID discord.GuildID, id string) (exist bool, session ModalSession, err error) {
+	exist, err = kvs.Get(guildID, "modalsessions", id, &session)
+	if exist && session.Expired(time.Now()) {
+		return false, ModalSession{}, err
+	}
+	if session.Data == nil {
+		session.Data = map[string]string{} // gob doesn't bother storing empty maps.
+	}
+	return exist, session, err
+}
+
+// ForgetModalSession removes a session, so the modal can't be submitted again.
+func ForgetModalSession(kvs KeyValueStore, guildID discord.GuildID, id string) error {
+	return kvs.Delete(guildID, "modalsessions", id)
+}
+
+// ForgetExpiredModalSessions removes the sessions of modals nobody submitted in time.
+func ForgetExpiredModalSessions(kvs KeyValueStore, guildID discord.GuildID) error {
+	keys, err := kvs.Keys(guildID, "modalsessions")
+	if err != nil {
+		return fmt.Errorf("forgetting expired modal sessions could not get keys: %w", err)
+	}
+	now := time.Now()
+	for _, key := range keys {
+		session := ModalSession{}
+		exist, err := kvs.Get(guildID, "modalsessions", key, &session)
+		if err != nil {
+			return fmt.Errorf("forgetting expired modal sessions could not get %s: %w", key, err)
+		}
+		if exist && session.Expired(now) {
+			if err := kvs.Delete(guildID, "modalsessions", key); err != nil {
+				return fmt.Errorf("forgetting expired modal sessions could not delete %s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
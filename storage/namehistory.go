@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// nameHistoryLimit is how many names are kept per user. The oldest are dropped first.
+const nameHistoryLimit = 50
+
+// NameRecord is what someone was called, from a point in time until the next record.
+type NameRecord struct {
+	When     int64
+	Username string
+	Nick     string
+}
+
+// GetNameHistory gets the names the user has had, oldest first.
+func GetNameHistory(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID) ([]NameRecord, error) {
+	history := []NameRecord{}
+	_, err := kvs.Get(guildID, "namehistory", userID, &history)
+	return history, err
+}
+
+// RecordName adds the name to the user's history, if it's different from the last one, and reports if it was.
+func RecordName(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID, username string, nick string) (bool, error) {
+	history, err := GetNameHistory(kvs, guildID, userID)
+	if err != nil {
+		return false, err
+	}
+	if len(history) > 0 {
+		last := history[len(history)-1]
+		if last.Username == username && last.Nick == nick {
+			return false, nil
+		}
+	}
+	history = append(history, NameRecord{
+		When:     time.Now().Unix(),
+		Username: username,
+		Nick:     nick,
+	})
+	if len(history) > nameHistoryLimit {
+		history = history[len(history)-nameHistoryLimit:]
+	}
+	return true, kvs.Set(guildID, "namehistory", userID, history)
+}
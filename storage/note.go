@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// Note is something the staff wrote down about someone, for the staff only.
+type Note struct {
+	ID      int64
+	GuildID discord.GuildID
+	UserID  discord.UserID
+	Author  discord.UserID
+	Text    string
+	Created int64
+}
+
+// AddNote writes down a note about the user, and stores it.
+func AddNote(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID, author discord.UserID, text string) (Note, error) {
+	id, err := NextNumber(kvs, guildID, "notes")
+	if err != nil {
+		return Note{}, fmt.Errorf("adding note could not get a number: %w", err)
+	}
+	note := Note{
+		ID:      id,
+		GuildID: guildID,
+		UserID:  userID,
+		Author:  author,
+		Text:    text,
+		Created: time.Now().Unix(),
+	}
+	return note, kvs.Set(guildID, "notes", id, note)
+}
+
+// GetNote gets a single note by number.
+func GetNote(kvs KeyValueStore, guildID discord.GuildID, id int64) (exist bool, note Note, err error) {
+	exist, err = kvs.Get(guildID, "notes", id, &note)
+	return exist, note, err
+}
+
+// DeleteNote throws away a note.
+func DeleteNote(kvs KeyValueStore, guildID discord.GuildID, id int64) error {
+	return kvs.Delete(guildID, "notes", id)
+}
+
+// GetNotes gets all the notes about the user, oldest first.
+func GetNotes(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID) ([]Note, error) {
+	keys, err := kvs.Keys(guildID, "notes")
+	if err != nil {
+		return nil, fmt.Errorf("getting notes could not get keys: %w", err)
+	}
+	notes := []Note{}
+	for _, key := range keys {
+		note := Note{}
+		exist, err := kvs.Get(guildID, "notes", key, &note)
+		if err != nil {
+			return nil, fmt.Errorf("getting notes could not get %s: %w", key, err)
+		}
+		if exist && note.UserID == userID {
+			notes = append(notes, note)
+		}
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		return notes[i].Created < notes[j].Created
+	})
+	return notes, nil
+}
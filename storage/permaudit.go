@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// PermissionAuditLimit is how many audit snapshots a guild keeps. Taking another throws away the oldest.
+const PermissionAuditLimit = 10
+
+// permissionNames are the permissions as the client calls them, in the order it lists them.
+var permissionNames = []struct {
+	permission discord.Permissions
+	name       string
+}{
+	{discord.PermissionAdministrator, "Administrator"},
+	{discord.PermissionViewChannel, "View Channel"},
+	{discord.PermissionManageChannels, "Manage Channels"},
+	{discord.PermissionManageRoles, "Manage Roles"},
+	{discord.PermissionManageEmojisAndStickers, "Manage Emojis and Stickers"},
+	{discord.PermissionViewAuditLog, "View Audit Log"},
+	{discord.PermissionManageWebhooks, "Manage Webhooks"},
+	{discord.PermissionManageGuild, "Manage Server"},
+	{discord.PermissionCreateInstantInvite, "Create Invite"},
+	{discord.PermissionChangeNickname, "Change Nickname"},
+	{discord.PermissionManageNicknames, "Manage Nicknames"},
+	{discord.PermissionKickMembers, "Kick Members"},
+	{discord.PermissionBanMembers, "Ban Members"},
+	{discord.PermissionModerateMembers, "Timeout Members"},
+	{discord.PermissionSendMessages, "Send Messages"},
+	{discord.PermissionSendMessagesInThreads, "Send Messages in Threads"},
+	{discord.PermissionCreatePublicThreads, "Create Public Threads"},
+	{discord.PermissionCreatePrivateThreads, "Create Private Threads"},
+	{discord.PermissionEmbedLinks, "Embed Links"},
+	{discord.PermissionAttachFiles, "Attach Files"},
+	{discord.PermissionAddReactions, "Add Reactions"},
+	{discord.PermissionUseExternalEmojis, "Use External Emoji"},
+	{discord.PermissionUseExternalStickers, "Use External Stickers"},
+	{discord.PermissionMentionEveryone, "Mention Everyone"},
+	{discord.PermissionManageMessages, "Manage Messages"},
+	{discord.PermissionManageThreads, "Manage Threads"},
+	{discord.PermissionReadMessageHistory, "Read Message History"},
+	{discord.PermissionSendTTSMessages, "Send Text-to-Speech Messages"},
+	{discord.PermissionUseSlashCommands, "Use Application Commands"},
+	{discord.PermissionConnect, "Connect"},
+	{discord.PermissionSpeak, "Speak"},
+	{discord.PermissionStream, "Video"},
+	{discord.PermissionStartEmbeddedActivities, "Use Activities"},
+	{discord.PermissionUseVAD, "Use Voice Activity"},
+	{discord.PermissionPrioritySpeaker, "Priority Speaker"},
+	{discord.PermissionMuteMembers, "Mute Members"},
+	{discord.PermissionDeafenMembers, "Deafen Members"},
+	{discord.PermissionMoveMembers, "Move Members"},
+	{discord.PermissionRequestToSpeak, "Request to Speak"},
+	{discord.PermissionManageEvents, "Manage Events"},
+}
+
+// PermissionNames lists the names of the permissions that are set.
+func PermissionNames(permissions discord.Permissions) []string {
+	names := []string{}
+	for _, known := range permissionNames {
+		if permissions.Has(known.permission) {
+			names = append(names, known.name)
+		}
+	}
+	return names
+}
+
+// RoleSnapshot is what a role was allowed to do when the audit snapshot was taken.
+type RoleSnapshot struct {
+	ID          discord.RoleID
+	Name        string
+	Permissions discord.Permissions
+}
+
+// ChannelSnapshot is the permission overwrites a channel had when the audit snapshot was taken.
+type ChannelSnapshot struct {
+	ID         discord.ChannelID
+	Name       string
+	Overwrites []discord.Overwrite
+}
+
+// PermissionAudit is the permissions of every role and channel in the guild at some point, to compare with later.
+type PermissionAudit struct {
+	ID       int64
+	Taken    int64
+	TakenBy  discord.UserID
+	Roles    []RoleSnapshot
+	Channels []ChannelSnapshot
+}
+
+// CapturePermissionAudit gets the permissions of every role and channel in the guild as they are now, without storing them.
+func CapturePermissionAudit(state *state.State, guildID discord.GuildID) (PermissionAudit, error) {
+	roles, err := state.Roles(guildID)
+	if err != nil {
+		return PermissionAudit{}, fmt.Errorf("capturing permissions could not get the roles: %w", err)
+	}
+	channels, err := state.Channels(guildID)
+	if err != nil {
+		return PermissionAudit{}, fmt.Errorf("capturing permissions could not get the channels: %w", err)
+	}
+	audit := PermissionAudit{Taken: time.Now().Unix()}
+	for _, role := range roles {
+		audit.Roles = append(audit.Roles, RoleSnapshot{ID: role.ID, Name: role.Name, Permissions: role.Permissions})
+	}
+	for _, channel := range channels {
+		if channel.Type == discord.GuildPublicThread || channel.Type == discord.GuildPrivateThread || channel.Type == discord.GuildNewsThread {
+			continue // Threads go by the permissions of their channel.
+		}
+		audit.Channels = append(audit.Channels, ChannelSnapshot{ID: channel.ID, Name: channel.Name, Overwrites: channel.Overwrites})
+	}
+	return audit, nil
+}
+
+// TakePermissionAudit captures the permissions of the guild and stores them, throwing away the oldest snapshot if there are too many.
+func TakePermissionAudit(state *state.State, kvs KeyValueStore, guildID discord.GuildID, takenBy discord.UserID) (PermissionAudit, error) {
+	audit, err := CapturePermissionAudit(state, guildID)
+	if err != nil {
+		return audit, err
+	}
+	audit.TakenBy = takenBy
+	audit.ID, err = NextNumber(kvs, guildID, "permaudit")
+	if err != nil {
+		return audit, fmt.Errorf("taking permission audit could not get a number: %w", err)
+	}
+	if err := kvs.Set(guildID, "permaudits", audit.ID, audit); err != nil {
+		return audit, fmt.Errorf("taking permission audit could not store it: %w", err)
+	}
+	audits, err := GetPermissionAudits(kvs, guildID)
+	if err != nil {
+		return audit, err
+	}
+	for len(audits) > PermissionAuditLimit {
+		if err := kvs.Delete(guildID, "permaudits", audits[0].ID); err != nil {
+			return audit, fmt.Errorf("taking permission audit could not throw away snapshot %d: %w", audits[0].ID, err)
+		}
+		audits = audits[1:]
+	}
+	return audit, nil
+}
+
+// GetPermissionAudit gets a single audit snapshot of the guild.
+func GetPermissionAudit(kvs KeyValueStore, guildID discord.GuildID, id int64) (exist bool, audit PermissionAudit, err error) {
+	exist, err = kvs.Get(guildID, "permaudits", id, &audit)
+	return exist, audit, err
+}
+
+// GetPermissionAudits gets all the audit snapshots of the guild, oldest first.
+func GetPermissionAudits(kvs KeyValueStore, guildID discord.GuildID) ([]PermissionAudit, error) {
+	keys, err := kvs.Keys(guildID, "permaudits")
+	if err != nil {
+		return nil, fmt.Errorf("getting permission audits could not get keys: %w", err)
+	}
+	audits := []PermissionAudit{}
+	for _, key := range keys {
+		audit := PermissionAudit{}
+		exist, err := kvs.Get(guildID, "permaudits", key, &audit)
+		if err != nil {
+			return nil, fmt.Errorf("getting permission audits could not get %s: %w", key, err)
+		}
+		if exist {
+			audits = append(audits, audit)
+		}
+	}
+	sort.Slice(audits, func(i, j int) bool {
+		return audits[i].ID < audits[j].ID
+	})
+	return audits, nil
+}
+
+// describePermissionChange says what was gained and lost going from before to after, or nothing if it's the same.
+func describePermissionChange(gained string, lost string, before discord.Permissions, after discord.Permissions) []string {
+	changes := []string{}
+	if names := PermissionNames(after &^ before); len(names) > 0 {
+		changes = append(changes, gained+" "+strings.Join(names, ", "))
+	}
+	if names := PermissionNames(before &^ after); len(names) > 0 {
+		changes = append(changes, lost+" "+strings.Join(names, ", "))
+	}
+	return changes
+}
+
+// overwriteTarget is who an overwrite is for, in a way the client will render.
+func overwriteTarget(guildID discord.GuildID, overwrite discord.Overwrite) string {
+	if overwrite.Type == discord.OverwriteMember {
+		return discord.UserID(overwrite.ID).Mention()
+	}
+	if discord.GuildID(overwrite.ID) == guildID {
+		return "@everyone"
+	}
+	return discord.RoleID(overwrite.ID).Mention()
+}
+
+// DiffPermissionAudits lists what changed from one audit snapshot to the other, one change per line.
+func DiffPermissionAudits(guildID discord.GuildID, before PermissionAudit, after PermissionAudit) []string {
+	lines := []string{}
+
+	beforeRoles := map[discord.RoleID]RoleSnapshot{}
+	for _, role := range before.Roles {
+		beforeRoles[role.ID] = role
+	}
+	for _, role := range after.Roles {
+		old, existed := beforeRoles[role.ID]
+		delete(beforeRoles, role.ID)
+		if !existed {
+			lines = append(lines, fmt.Sprintf("➕ Role %s was added, with %s.", role.ID.Mention(), describePermissionList(role.Permissions)))
+			continue
+		}
+		if old.Name != role.Name {
+			lines = append(lines, fmt.Sprintf("✏️ Role %s was renamed from **%s**.", role.ID.Mention(), old.Name))
+		}
+		for _, change := range describePermissionChange("gained", "lost", old.Permissions, role.Permissions) {
+			lines = append(lines, fmt.Sprintf("🔑 Role %s %s.", role.ID.Mention(), change))
+		}
+	}
+	for _, role := range beforeRoles {
+		lines = append(lines, fmt.Sprintf("➖ Role **%s** was removed. It had %s.", role.Name, describePermissionList(role.Permissions)))
+	}
+
+	beforeChannels := map[discord.ChannelID]ChannelSnapshot{}
+	for _, channel := range before.Channels {
+		beforeChannels[channel.ID] = channel
+	}
+	for _, channel := range after.Channels {
+		old, existed := beforeChannels[channel.ID]
+		delete(beforeChannels, channel.ID)
+		if !existed {
+			lines = append(lines, fmt.Sprintf("➕ Channel %s was added.", channel.ID.Mention()))
+			continue
+		}
+		oldOverwrites := map[discord.Snowflake]discord.Overwrite{}
+		for _, overwrite := range old.Overwrites {
+			oldOverwrites[overwrite.ID] = overwrite
+		}
+		for _, overwrite := range channel.Overwrites {
+			previous, had := oldOverwrites[overwrite.ID]
+			delete(oldOverwrites, overwrite.ID)
+			changes := append(
+				describePermissionChange("now allows", "no longer allows", previous.Allow, overwrite.Allow),
+				describePermissionChange("now denies", "no longer denies", previous.Deny, overwrite.Deny)...,
+			)
+			if len(changes) == 0 {
+				if !had {
+					lines = append(lines, fmt.Sprintf("🔧 In %s, %s got an overwrite that changes nothing.", channel.ID.Mention(), overwriteTarget(guildID, overwrite)))
+				}
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("🔧 In %s, %s %s.", channel.ID.Mention(), overwriteTarget(guildID, overwrite), strings.Join(changes, "; ")))
+		}
+		for _, overwrite := range oldOverwrites {
+			lines = append(lines, fmt.Sprintf("🔧 In %s, the overwrite for %s was removed. It allowed %s, and denied %s.", channel.ID.Mention(), overwriteTarget(guildID, overwrite), describePermissionList(overwrite.Allow), describePermissionList(overwrite.Deny)))
+		}
+	}
+	for _, channel := range beforeChannels {
+		lines = append(lines, fmt.Sprintf("➖ Channel **#%s** was removed.", channel.Name))
+	}
+	return lines
+}
+
+// describePermissionList names the permissions, or says there are none.
+func describePermissionList(permissions discord.Permissions) string {
+	names := PermissionNames(permissions)
+	if len(names) == 0 {
+		return "no permissions"
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// PermissionSnapshot is every permission overwrite a channel had at some point, so they can all be put back later.
+type PermissionSnapshot struct {
+	ChannelID  discord.ChannelID
+	Overwrites []discord.Overwrite
+	Taken      int64
+	TakenBy    discord.UserID
+}
+
+// TakePermissionSnapshot stores the current permission overwrites of the channel, replacing any earlier snapshot of it.
+func TakePermissionSnapshot(state *state.State, kvs KeyValueStore, guildID discord.GuildID, channelID discord.ChannelID, takenBy discord.UserID) (PermissionSnapshot, error) {
+	channel, err := state.Channel(channelID)
+	if err != nil {
+		return PermissionSnapshot{}, fmt.Errorf("taking permission snapshot could not get the channel: %w", err)
+	}
+	snapshot := PermissionSnapshot{
+		ChannelID:  channelID,
+		Overwrites: channel.Overwrites,
+		Taken:      time.Now().Unix(),
+		TakenBy:    takenBy,
+	}
+	if err := kvs.Set(guildID, "permsnapshots", channelID, snapshot); err != nil {
+		return snapshot, fmt.Errorf("taking permission snapshot could not store it: %w", err)
+	}
+	return snapshot, nil
+}
+
+// GetPermissionSnapshot gets the stored snapshot of the channel, if there is one.
+func GetPermissionSnapshot(kvs KeyValueStore, guildID discord.GuildID, channelID discord.ChannelID) (bool, PermissionSnapshot, error) {
+	snapshot := PermissionSnapshot{}
+	exist, err := kvs.Get(guildID, "permsnapshots", channelID, &snapshot)
+	return exist, snapshot, err
+}
+
+// GetPermissionSnapshots gets all the stored snapshots in the guild.
+func GetPermissionSnapshots(kvs KeyValueStore, guildID discord.GuildID) ([]PermissionSnapshot, error) {
+	keys, err := kvs.Keys(guildID, "permsnapshots")
+	if err != nil {
+		return nil, fmt.Errorf("getting permission snapshots could not get keys: %w", err)
+	}
+	snapshots := []PermissionSnapshot{}
+	for _, key := range keys {
+		snapshot := PermissionSnapshot{}
+		exist, err := kvs.Get(guildID, "permsnapshots", key, &snapshot)
+		if err != nil {
+			return nil, fmt.Errorf("getting permission snapshots could not get %s: %w", key, err)
+		}
+		if exist {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	return snapshots, nil
+}
+
+// RestorePermissionSnapshot replaces all the permission overwrites of the channel with the ones in the snapshot.
+// Overwrites added since the snapshot was taken are removed. The snapshot is kept, so it can be restored again.
+func RestorePermissionSnapshot(state *state.State, snapshot PermissionSnapshot, reason string) error {
+	overwrites := snapshot.Overwrites
+	if overwrites == nil {
+		overwrites = []discord.Overwrite{} // gob gives back nil for an empty list, and Discord wants an actual list.
+	}
+	err := state.ModifyChannel(snapshot.ChannelID, api.ModifyChannelData{
+		Overwrites:     &overwrites,
+		AuditLogReason: api.AuditLogReason(reason),
+	})
+	if err != nil {
+		return fmt.Errorf("restoring permission snapshot of <#%s> failed: %w", snapshot.ChannelID, err)
+	}
+	return nil
+}
+
+// DeletePermissionSnapshot forgets the snapshot of the channel.
+func DeletePermissionSnapshot(kvs KeyValueStore, guildID discord.GuildID, channelID discord.ChannelID) error {
+	return kvs.Delete(guildID, "permsnapshots", channelID)
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// PinboardConfig is how the pin archive is set up in a guild.
+type PinboardConfig struct {
+	ChannelID discord.ChannelID // Where archived pins are copied to.
+	Auto      bool              // Archive the oldest pins of a channel when it runs out of room for more.
+}
+
+// ArchivedPin connects a pinned message to its copy in the archive channel.
+type ArchivedPin struct {
+	ChannelID        discord.ChannelID // Where the original message is.
+	MessageID        discord.MessageID
+	ArchiveMessageID discord.MessageID // The copy in the archive channel.
+	AuthorID         discord.UserID
+	ArchivedBy       discord.UserID // Who archived it, or invalid if it was archived automatically.
+	Archived         time.Time
+}
+
+// GetPinboardConfig gets the pin archive setup for the guild, if there is one.
+func GetPinboardConfig(kvs KeyValueStore, guildID discord.GuildID) (exist bool, config PinboardConfig, err error) {
+	exist, err = kvs.Get(guildID, "pinboard", "config", &config)
+	return
+}
+
+// SetPinboardConfig stores the pin archive setup for the guild.
+func SetPinboardConfig(kvs KeyValueStore, guildID discord.GuildID, config PinboardConfig) error {
+	return kvs.Set(guildID, "pinboard", "config", config)
+}
+
+// DisablePinboard forgets the pin archive setup for the guild. What's already archived is left alone.
+func DisablePinboard(kvs KeyValueStore, guildID discord.GuildID) error {
+	return kvs.Delete(guildID, "pinboard", "config")
+}
+
+// GetArchivedPin looks up the archived copy of the given message, if there is one.
+func GetArchivedPin(kvs KeyValueStore, guildID discord.GuildID, messageID discord.MessageID) (exist bool, pin ArchivedPin, err error) {
+	exist, err = kvs.Get(guildID, "pinarchive", messageID, &pin)
+	return
+}
+
+// SetArchivedPin remembers the archived copy of a message.
+func SetArchivedPin(kvs KeyValueStore, guildID discord.GuildID, pin ArchivedPin) error {
+	return kvs.Set(guildID, "pinarchive", pin.MessageID, pin)
+}
+
+// GetArchivedPins gets all the archived pins of the guild, most recently archived first.
+func GetArchivedPins(kvs KeyValueStore, guildID discord.GuildID) ([]ArchivedPin, error) {
+	keys, err := kvs.Keys(guildID, "pinarchive")
+	if err != nil {
+		return nil, fmt.Errorf("getting archived pins could not get keys: %w", err)
+	}
+	pins := []ArchivedPin{}
+	for _, key := range keys {
+		pin := ArchivedPin{}
+		exist, err := kvs.Get(guildID, "pinarchive", key, &pin)
+		if err != nil {
+			return nil, fmt.Errorf("getting archived pins could not get %s: %w", key, err)
+		}
+		if exist {
+			pins = append(pins, pin)
+		}
+	}
+	sort.Slice(pins, func(i, j int) bool {
+		return pins[i].Archived.After(pins[j].Archived)
+	})
+	return pins, nil
+}
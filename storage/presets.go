@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// presetKey makes the key a preset is stored under, like "vote start/length".
+func presetKey(path string, option string) string {
+	return path + "/" + option
+}
+
+// SetPreset stores the value to use for the option in the given command path when the user leaves it out.
+func SetPreset(kvs KeyValueStore, guildID discord.GuildID, path string, option string, value string) error {
+	return kvs.Set(guildID, "presets", presetKey(path, option), value)
+}
+
+// ClearPreset forgets the preset for the option in the given command path.
+func ClearPreset(kvs KeyValueStore, guildID discord.GuildID, path string, option string) error {
+	return kvs.Delete(guildID, "presets", presetKey(path, option))
+}
+
+// GetPresets gets the presets for the given command path, keyed by option name.
+func GetPresets(kvs KeyValueStore, guildID discord.GuildID, path string) (map[string]string, error) {
+	all, err := AllPresets(kvs, guildID)
+	if err != nil {
+		return nil, err
+	}
+	presets := map[string]string{}
+	for key, value := range all {
+		if strings.HasPrefix(key, path+"/") {
+			presets[strings.TrimPrefix(key, path+"/")] = value
+		}
+	}
+	return presets, nil
+}
+
+// AllPresets gets every preset in the guild, keyed by "command path/option".
+func AllPresets(kvs KeyValueStore, guildID discord.GuildID) (map[string]string, error) {
+	keys, err := kvs.Keys(guildID, "presets")
+	if err != nil {
+		return nil, fmt.Errorf("getting preset keys: %w", err)
+	}
+	presets := map[string]string{}
+	for _, key := range keys {
+		value := ""
+		if _, err := kvs.Get(guildID, "presets", key, &value); err != nil {
+			return nil, fmt.Errorf("getting preset %s: %w", key, err)
+		}
+		presets[key] = value
+	}
+	return presets, nil
+}
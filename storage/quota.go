@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// ModuleOther is where collections that don't belong to any listed module are counted.
+const ModuleOther = "other"
+
+// storageModules maps each collection to the module it is reported under.
+var storageModules = map[string]string{
+	"votes":           "votes",
+	"votetemplates":   "votes",
+	"customcommands":  "customcommands",
+	"faq":             "faq",
+	"faqhistory":      "faq",
+	"faqcategory":     "faq",
+	"faqauto":         "faq",
+	"seen":            "seen",
+	"deletelog":       "logs",
+	"trafficlog":      "logs",
+	"namehistory":     "logs",
+	"rolehistory":     "logs",
+	"auditlog":        "logs",
+	"warnings":        "moderation",
+	"permsnapshots":   "moderation",
+	"permaudits":      "moderation",
+	"notes":           "moderation",
+	"report":          "moderation",
+	"confirmations":   "moderation",
+	"presets":         "presets",
+	"events":          "events",
+	"calendar":        "events",
+	"tickets":         "tickets",
+	"ticketconfig":    "tickets",
+	"starboard":       "starboard",
+	"starboardposts":  "starboard",
+	"pinboard":        "pinboard",
+	"pinarchive":      "pinboard",
+	"rememberedroles": "roles",
+	"stickyroles":     "roles",
+	"rolemenugroups":  "roles",
+	"xp":              "xp",
+	"xpconfig":        "xp",
+	"feeds":           "feeds",
+	"streams":         "feeds",
+	"github":          "feeds",
+	"slowmode":        "moderation",
+	"voicetime":       "voice",
+	"voicesessions":   "voice",
+	"voicelobby":      "voice",
+	"voicelobbies":    "voice",
+	"counting":        "counting",
+	"trash":           "trash",
+}
+
+// StorageModules lists the modules storage is reported under, in the order they are shown.
+func StorageModules() []string {
+	seen := map[string]bool{}
+	modules := []string{}
+	for _, module := range storageModules {
+		if !seen[module] {
+			seen[module] = true
+			modules = append(modules, module)
+		}
+	}
+	sort.Strings(modules)
+	return append(modules, ModuleOther)
+}
+
+// ModuleUsage adds up the storage used by the guild per module.
+func ModuleUsage(kvs KeyValueStore, guildID discord.GuildID) (map[string]CollectionUsage, error) {
+	byCollection, err := kvs.Usage(guildID)
+	if err != nil {
+		return nil, err
+	}
+	byModule := map[string]CollectionUsage{}
+	for collection, usage := range byCollection {
+		module, ok := storageModules[collection]
+		if !ok {
+			module = ModuleOther
+		}
+		total := byModule[module]
+		total.Keys += usage.Keys
+		total.Bytes += usage.Bytes
+		byModule[module] = total
+	}
+	return byModule, nil
+}
+
+// GetQuotas gets the soft quotas of the guild, in kilobytes, keyed by module.
+func GetQuotas(kvs KeyValueStore, guildID discord.GuildID) (map[string]int, error) {
+	quotas := map[string]int{}
+	_, err := kvs.Get(guildID, "quotas", "modules", &quotas)
+	if quotas == nil {
+		quotas = map[string]int{} // gob doesn't bother with empty maps.
+	}
+	return quotas, err
+}
+
+// SetQuota sets the soft quota for the module, in kilobytes. Zero removes it.
+func SetQuota(kvs KeyValueStore, guildID discord.GuildID, module string, kilobytes int) error {
+	quotas, err := GetQuotas(kvs, guildID)
+	if err != nil {
+		return err
+	}
+	if kilobytes <= 0 {
+		delete(quotas, module)
+	} else {
+		quotas[module] = kilobytes
+	}
+	return kvs.Set(guildID, "quotas", "modules", quotas)
+}
+
+// OverQuota lists the modules that use more than their quota.
+func OverQuota(usage map[string]CollectionUsage, quotas map[string]int) []string {
+	over := []string{}
+	for module, kilobytes := range quotas {
+		if usage[module].Bytes > kilobytes*1024 {
+			over = append(over, module)
+		}
+	}
+	sort.Strings(over)
+	return over
+}
+
+// CheckQuotas logs a warning for every guild that is over any of its quotas.
+func CheckQuotas(state *state.State, kvs KeyValueStore) error {
+	guilds, err := state.Guilds()
+	if err != nil {
+		return fmt.Errorf("checking quotas could not fetch current guilds: %w", err)
+	}
+	for _, guild := range guilds {
+		err := GuildJob(kvs, guild.ID, "quotas", time.Hour, func() error {
+			quotas, err := GetQuotas(kvs, guild.ID)
+			if err != nil {
+				return fmt.Errorf("checking quotas could not get quotas for guild: %w", err)
+			}
+			if len(quotas) == 0 {
+				return nil
+			}
+			usage, err := ModuleUsage(kvs, guild.ID)
+			if err != nil {
+				return fmt.Errorf("checking quotas could not get usage for guild: %w", err)
+			}
+			for _, module := range OverQuota(usage, quotas) {
+				log.Printf("[%s] Storage for %s is %d KB, over the quota of %d KB", guild.ID, module, usage[module].Bytes/1024, quotas[module])
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartCheckingQuotas starts a ticker and, once an hour, calls CheckQuotas.
+// Intended to be called as a goroutine.
+func StartCheckingQuotas(state *state.State, kvs KeyValueStore) {
+	ticker := time.NewTicker(1 * time.Hour)
+	for {
+		<-ticker.C
+		if err := CheckQuotas(state, kvs); err != nil {
+			log.Printf("Error encountered checking quotas: %s", err)
+		}
+	}
+}
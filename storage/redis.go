@@ -0,0 +1,462 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/google/uuid"
+)
+
+// redisPoolSize is how many idle connections to Redis are kept around for reuse.
+const redisPoolSize = 8
+
+// redisPrefix starts every key the bot uses in Redis, so it can share a Redis with other things.
+const redisPrefix = "komainu:"
+
+// ErrRedisNoCompact is returned by Compact, as Redis looks after its own memory.
+var ErrRedisNoCompact = errors.New("Redis looks after its own memory, so there's nothing to compact")
+
+// redisKVS keeps everything in Redis, so several instances of the bot, or the bot and something else, can share it.
+// Each collection in each guild is a hash, keyed like bolt keys, holding the same gob values bolt would.
+type redisKVS struct {
+	encryption
+	address  string
+	password string
+	database int
+	timeout  time.Duration
+	pool     chan *redisConn
+	closed   bool
+	lock     sync.Mutex
+}
+
+// redisConn is a single connection to Redis, speaking RESP.
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// redisError is an error Redis answered with, as opposed to something going wrong on the way there.
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// OpenRedis connects to Redis at the URL, like redis://:password@localhost:6379/0, making sure it answers.
+func OpenRedis(rawURL string) (*redisKVS, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("the Redis URL is not a URL: %w", err)
+	}
+	if parsed.Scheme != "redis" {
+		return nil, fmt.Errorf("the Redis URL has to start with redis://, not %s://", parsed.Scheme)
+	}
+	r := &redisKVS{
+		address: parsed.Host,
+		timeout: 10 * time.Second,
+		pool:    make(chan *redisConn, redisPoolSize),
+	}
+	if !strings.Contains(r.address, ":") {
+		r.address += ":6379"
+	}
+	if password, ok := parsed.User.Password(); ok {
+		r.password = password
+	}
+	if db := strings.Trim(parsed.Path, "/"); db != "" {
+		if r.database, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("the Redis database %q is not a number", db)
+		}
+	}
+	if _, err := r.do("PING"); err != nil {
+		return nil, fmt.Errorf("Redis doesn't answer: %w", err)
+	}
+	return r, nil
+}
+
+func (r *redisKVS) dial() (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", r.address, r.timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	if r.password != "" {
+		if _, err := c.do(r.timeout, "AUTH", r.password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if r.database != 0 {
+		if _, err := c.do(r.timeout, "SELECT", strconv.Itoa(r.database)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// do sends a command on a pooled connection, and gives what Redis answered. Arguments are strings or []byte.
+func (r *redisKVS) do(args ...any) (any, error) {
+	var c *redisConn
+	select {
+	case c = <-r.pool:
+	default:
+		var err error
+		if c, err = r.dial(); err != nil {
+			return nil, fmt.Errorf("unable to connect to Redis: %w", err)
+		}
+	}
+	reply, err := c.do(r.timeout, args...)
+	var answered redisError
+	if err != nil && !errors.As(err, &answered) {
+		c.conn.Close() // Who knows what state it's in.
+		return nil, err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.closed {
+		c.conn.Close()
+		return reply, err
+	}
+	select {
+	case r.pool <- c:
+	default:
+		c.conn.Close()
+	}
+	return reply, err
+}
+
+func (c *redisConn) do(timeout time.Duration, args ...any) (any, error) {
+	c.conn.SetDeadline(time.Now().Add(timeout))
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		var raw []byte
+		switch arg := arg.(type) {
+		case []byte:
+			raw = arg
+		case string:
+			raw = []byte(arg)
+		default:
+			raw = []byte(fmt.Sprintf("%v", arg))
+		}
+		fmt.Fprintf(&buf, "$%d\r\n", len(raw))
+		buf.Write(raw)
+		buf.WriteString("\r\n")
+	}
+	if _, err := c.conn.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// read reads a single reply. Bulk strings come back as []byte, nil if there is nothing, arrays as []any, and integers
+// as int64.
+func (c *redisConn) read() (any, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: got an empty line")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		raw := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, raw); err != nil {
+			return nil, err
+		}
+		return raw[:size], nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil || count < 0 {
+			return nil, err
+		}
+		items := make([]any, count)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: got something I don't understand: %q", line)
+}
+
+func (r *redisKVS) hash(guildID discord.GuildID, collection string) string {
+	return redisPrefix + guildID.String() + ":" + collection
+}
+
+func (r *redisKVS) key(raw any) []byte {
+	return []byte(fmt.Sprintf("%v", raw))
+}
+
+// scan finds every key matching the pattern.
+func (r *redisKVS) scan(pattern string) ([]string, error) {
+	found := []string{}
+	cursor := "0"
+	for {
+		reply, err := r.do("SCAN", cursor, "MATCH", pattern, "COUNT", "1000")
+		if err != nil {
+			return nil, err
+		}
+		parts, ok := reply.([]any)
+		if !ok || len(parts) != 2 {
+			return nil, fmt.Errorf("redis: SCAN answered with %v", reply)
+		}
+		next, _ := parts[0].([]byte)
+		keys, _ := parts[1].([]any)
+		for _, key := range keys {
+			if key, ok := key.([]byte); ok {
+				found = append(found, string(key))
+			}
+		}
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return found, nil
+		}
+	}
+}
+
+func (r *redisKVS) Set(guildID discord.GuildID, collection string, key any, value any) error {
+	guildb, collectionb, keyb := []byte(guildID.String()), []byte(collection), r.key(key)
+	var inputBuffer bytes.Buffer
+	if err := gob.NewEncoder(&inputBuffer).Encode(value); err != nil {
+		return fmt.Errorf("unable to encode raw value %v as gob for Set: %w", value, err)
+	}
+	sealed, err := r.seal(guildb, collectionb, keyb, inputBuffer.Bytes())
+	if err != nil {
+		return fmt.Errorf("unable to encrypt value for Set: %w", err)
+	}
+	_, err = r.do("HSET", r.hash(guildID, collection), keyb, sealed)
+	return err
+}
+
+func (r *redisKVS) Get(guildID discord.GuildID, collection string, key any, out any) (bool, error) {
+	guildb, collectionb, keyb := []byte(guildID.String()), []byte(collection), r.key(key)
+	reply, err := r.do("HGET", r.hash(guildID, collection), keyb)
+	if err != nil || reply == nil {
+		return false, err
+	}
+	raw, err := r.open(guildb, collectionb, keyb, reply.([]byte))
+	if err != nil {
+		return true, err
+	}
+	return true, gob.NewDecoder(bytes.NewReader(raw)).Decode(out)
+}
+
+func (r *redisKVS) GetMany(guildID discord.GuildID, collection string, keys []any, out func(i int) any) ([]bool, error) {
+	found := make([]bool, len(keys))
+	if len(keys) == 0 {
+		return found, nil
+	}
+	guildb, collectionb := []byte(guildID.String()), []byte(collection)
+	args := []any{"HMGET", r.hash(guildID, collection)}
+	keysb := make([][]byte, len(keys))
+	for i, key := range keys {
+		keysb[i] = r.key(key)
+		args = append(args, keysb[i])
+	}
+	reply, err := r.do(args...)
+	if err != nil {
+		return found, err
+	}
+	values, ok := reply.([]any)
+	if !ok || len(values) != len(keys) {
+		return found, fmt.Errorf("redis: HMGET answered with %d values for %d keys", len(values), len(keys))
+	}
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		found[i] = true
+		raw, err := r.open(guildb, collectionb, keysb[i], value.([]byte))
+		if err != nil {
+			return found, err
+		}
+		if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(out(i)); err != nil {
+			return found, err
+		}
+	}
+	return found, nil
+}
+
+// redisTrashScript moves the value to the trash, but only if it's still what was read, so nothing stored since is lost.
+const redisTrashScript = `
+if redis.call('HGET', KEYS[1], ARGV[1]) ~= ARGV[2] then return 0 end
+redis.call('HSET', KEYS[2], ARGV[3], ARGV[4])
+redis.call('HDEL', KEYS[1], ARGV[1])
+return 1`
+
+func (r *redisKVS) Delete(guildID discord.GuildID, collection string, key any) error {
+	hash, keyb := r.hash(guildID, collection), r.key(key)
+	if !trashedCollections[collection] {
+		_, err := r.do("HDEL", hash, keyb)
+		return err
+	}
+	for attempt := 0; attempt < 3; attempt++ {
+		reply, err := r.do("HGET", hash, keyb)
+		if err != nil || reply == nil {
+			return err
+		}
+		entry, err := encodeTrashEntry(collection, string(keyb), reply.([]byte))
+		if err != nil {
+			return err
+		}
+		moved, err := r.do("EVAL", redisTrashScript, "2", hash, r.hash(guildID, trashCollection), keyb, reply, trashKey(collection, string(keyb)), entry)
+		if err != nil || moved == int64(1) {
+			return err
+		}
+	}
+	return errors.New("redis: the value kept changing while trying to delete it")
+}
+
+// redisRestoreScript puts the value back from the trash, unless something else is there, or the trash entry changed.
+const redisRestoreScript = `
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 then return -1 end
+if redis.call('HGET', KEYS[2], ARGV[2]) ~= ARGV[3] then return 0 end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[4])
+redis.call('HDEL', KEYS[2], ARGV[2])
+return 1`
+
+func (r *redisKVS) Restore(guildID discord.GuildID, collection string, key any) (bool, error) {
+	trash, keyb := r.hash(guildID, trashCollection), r.key(key)
+	entryKey := trashKey(collection, string(keyb))
+	reply, err := r.do("HGET", trash, entryKey)
+	if err != nil || reply == nil {
+		return false, err
+	}
+	entry, err := decodeTrashEntry(reply.([]byte))
+	if err != nil {
+		return false, err
+	}
+	restored, err := r.do("EVAL", redisRestoreScript, "2", r.hash(guildID, collection), trash, keyb, entryKey, reply, entry.Value)
+	if err != nil {
+		return false, err
+	}
+	if restored == int64(-1) {
+		return false, ErrTrashOccupied
+	}
+	return restored == int64(1), nil
+}
+
+func (r *redisKVS) Keys(guildID discord.GuildID, collection string) ([]string, error) {
+	reply, err := r.do("HKEYS", r.hash(guildID, collection))
+	if err != nil {
+		return nil, err
+	}
+	items, _ := reply.([]any)
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, string(item.([]byte)))
+	}
+	return keys, nil
+}
+
+func (r *redisKVS) Usage(guildID discord.GuildID) (map[string]CollectionUsage, error) {
+	prefix := r.hash(guildID, "")
+	hashes, err := r.scan(prefix + "*")
+	if err != nil {
+		return nil, err
+	}
+	usage := map[string]CollectionUsage{}
+	for _, hash := range hashes {
+		reply, err := r.do("HGETALL", hash)
+		if err != nil {
+			return nil, err
+		}
+		items, _ := reply.([]any)
+		counted := CollectionUsage{Keys: len(items) / 2}
+		for _, item := range items {
+			counted.Bytes += len(item.([]byte))
+		}
+		usage[strings.TrimPrefix(hash, prefix)] = counted
+	}
+	return usage, nil
+}
+
+func (r *redisKVS) Guilds() ([]discord.GuildID, error) {
+	hashes, err := r.scan(redisPrefix + "*")
+	if err != nil {
+		return nil, err
+	}
+	seen := map[discord.GuildID]bool{}
+	guilds := []discord.GuildID{}
+	for _, hash := range hashes {
+		guild, _, _ := strings.Cut(strings.TrimPrefix(hash, redisPrefix), ":")
+		snowflake, err := discord.ParseSnowflake(guild)
+		if err != nil {
+			continue // Not a guild, so it's none of our business.
+		}
+		if guildID := discord.GuildID(snowflake); !seen[guildID] {
+			seen[guildID] = true
+			guilds = append(guilds, guildID)
+		}
+	}
+	sort.Slice(guilds, func(i, j int) bool {
+		return guilds[i] < guilds[j]
+	})
+	return guilds, nil
+}
+
+func (r *redisKVS) Compact() (int64, int64, error) {
+	return 0, 0, ErrRedisNoCompact
+}
+
+// redisUnlockScript only unlocks the lock if it's still ours, and hasn't run out and been taken by someone else.
+const redisUnlockScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('DEL', KEYS[1]) end
+return 0`
+
+// Lock takes the named lock for the guild, shared with every instance using the same Redis, unless someone else has
+// it. It runs out after ttl, so a lock held by an instance that went away doesn't stay locked forever.
+func (r *redisKVS) Lock(guildID discord.GuildID, name string, ttl time.Duration) (unlock func(), acquired bool, err error) {
+	lockKey := redisPrefix + "lock:" + guildID.String() + ":" + name
+	token := uuid.New().String()
+	reply, err := r.do("SET", lockKey, token, "NX", "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil || reply == nil {
+		return func() {}, false, err
+	}
+	return func() {
+		if _, err := r.do("EVAL", redisUnlockScript, "1", lockKey, token); err != nil {
+			log.Printf("[%s] Could not unlock %s, it runs out by itself: %s", guildID, name, err)
+		}
+	}, true, nil
+}
+
+func (r *redisKVS) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.closed = true
+	for {
+		select {
+		case c := <-r.pool:
+			c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
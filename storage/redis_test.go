@@ -0,0 +1,424 @@
+package storage
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is just enough of Redis, on a real socket, to test the client against. The Lua scripts the client sends
+// are recognised, and done in Go instead.
+type fakeRedis struct {
+	password string
+	mutex    sync.Mutex
+	hashes   map[string]map[string]string
+	values   map[string]string
+	expires  map[string]time.Time
+	selected string
+}
+
+// newFakeRedis starts a fakeRedis, and returns the URL to connect to it with.
+func newFakeRedis(t *testing.T, password string) (*fakeRedis, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Could not listen for the fake Redis: %s", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	f := &fakeRedis{
+		password: password,
+		hashes:   map[string]map[string]string{},
+		values:   map[string]string{},
+		expires:  map[string]time.Time{},
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f, fmt.Sprintf("redis://:%s@%s/2", password, listener.Addr())
+}
+
+// serve answers the commands on the connection, reading them with the same parser the client reads replies with.
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	authenticated := f.password == ""
+	for {
+		request, err := c.read()
+		if err != nil {
+			return
+		}
+		items, _ := request.([]any)
+		args := make([]string, len(items))
+		for i, item := range items {
+			raw, _ := item.([]byte)
+			args[i] = string(raw)
+		}
+		if len(args) == 0 {
+			conn.Write([]byte("-ERR empty command\r\n"))
+			continue
+		}
+		if strings.ToUpper(args[0]) == "AUTH" {
+			if len(args) == 2 && args[1] == f.password {
+				authenticated = true
+				conn.Write([]byte("+OK\r\n"))
+			} else {
+				conn.Write([]byte("-WRONGPASS invalid password\r\n"))
+			}
+			continue
+		}
+		if !authenticated {
+			conn.Write([]byte("-NOAUTH Authentication required.\r\n"))
+			continue
+		}
+		conn.Write(f.handle(args))
+	}
+}
+
+func (f *fakeRedis) handle(args []string) []byte {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return []byte("+PONG\r\n")
+	case "SELECT":
+		f.selected = args[1]
+		return []byte("+OK\r\n")
+	case "HSET":
+		f.hset(args[1], args[2], args[3])
+		return []byte(":1\r\n")
+	case "HGET":
+		value, ok := f.hashes[args[1]][args[2]]
+		return bulk(value, ok)
+	case "HMGET":
+		reply := []byte(fmt.Sprintf("*%d\r\n", len(args)-2))
+		for _, key := range args[2:] {
+			value, ok := f.hashes[args[1]][key]
+			reply = append(reply, bulk(value, ok)...)
+		}
+		return reply
+	case "HDEL":
+		return integer(f.hdel(args[1], args[2]))
+	case "HKEYS":
+		return array(sortedKeys(f.hashes[args[1]]))
+	case "HGETALL":
+		items := []string{}
+		for _, key := range sortedKeys(f.hashes[args[1]]) {
+			items = append(items, key, f.hashes[args[1]][key])
+		}
+		return array(items)
+	case "SCAN":
+		matched := []string{}
+		for _, hash := range sortedKeys(f.hashes) {
+			if ok, _ := path.Match(args[3], hash); ok {
+				matched = append(matched, hash)
+			}
+		}
+		return append([]byte("*2\r\n$1\r\n0\r\n"), array(matched)...)
+	case "SET":
+		if _, ok := f.get(args[1]); ok {
+			return []byte("$-1\r\n") // Only ever called with NX.
+		}
+		ms, _ := strconv.Atoi(args[5])
+		f.values[args[1]] = args[2]
+		f.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		return []byte("+OK\r\n")
+	case "EVAL":
+		return f.eval(args[1], args[3:])
+	}
+	return []byte("-ERR unknown command '" + args[0] + "'\r\n")
+}
+
+// eval does what the client's scripts would do in Redis.
+func (f *fakeRedis) eval(script string, args []string) []byte {
+	switch script {
+	case redisTrashScript:
+		hash, trash, key, read, entryKey, entry := args[0], args[1], args[2], args[3], args[4], args[5]
+		if value, ok := f.hashes[hash][key]; !ok || value != read {
+			return integer(0)
+		}
+		f.hset(trash, entryKey, entry)
+		f.hdel(hash, key)
+		return integer(1)
+	case redisRestoreScript:
+		hash, trash, key, entryKey, entry, value := args[0], args[1], args[2], args[3], args[4], args[5]
+		if _, ok := f.hashes[hash][key]; ok {
+			return integer(-1)
+		}
+		if f.hashes[trash][entryKey] != entry {
+			return integer(0)
+		}
+		f.hset(hash, key, value)
+		f.hdel(trash, entryKey)
+		return integer(1)
+	case redisUnlockScript:
+		if value, ok := f.get(args[0]); ok && value == args[1] {
+			delete(f.values, args[0])
+			return integer(1)
+		}
+		return integer(0)
+	}
+	return []byte("-NOSCRIPT unknown script\r\n")
+}
+
+func (f *fakeRedis) get(key string) (string, bool) {
+	if time.Now().After(f.expires[key]) {
+		delete(f.values, key)
+	}
+	value, ok := f.values[key]
+	return value, ok
+}
+
+func (f *fakeRedis) hset(hash string, key string, value string) {
+	if f.hashes[hash] == nil {
+		f.hashes[hash] = map[string]string{}
+	}
+	f.hashes[hash][key] = value
+}
+
+func (f *fakeRedis) hdel(hash string, key string) int {
+	if _, ok := f.hashes[hash][key]; !ok {
+		return 0
+	}
+	delete(f.hashes[hash], key)
+	if len(f.hashes[hash]) == 0 {
+		delete(f.hashes, hash)
+	}
+	return 1
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func bulk(value string, ok bool) []byte {
+	if !ok {
+		return []byte("$-1\r\n")
+	}
+	return []byte(fmt.Sprintf("$%d\r\n%s\r\n", len(value), value))
+}
+
+func integer(n int) []byte {
+	return []byte(fmt.Sprintf(":%d\r\n", n))
+}
+
+func array(items []string) []byte {
+	reply := []byte(fmt.Sprintf("*%d\r\n", len(items)))
+	for _, item := range items {
+		reply = append(reply, bulk(item, true)...)
+	}
+	return reply
+}
+
+func TestRedisRead(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected any
+	}{
+		{"+OK\r\n", "OK"},
+		{":42\r\n", int64(42)},
+		{":-1\r\n", int64(-1)},
+		{"$5\r\nhello\r\n", []byte("hello")},
+		{"$0\r\n\r\n", []byte{}},
+		{"$4\r\na\r\nb\r\n", []byte("a\r\nb")},
+		{"$-1\r\n", nil},
+		{"*-1\r\n", nil},
+		{"*0\r\n", []any{}},
+		{"*3\r\n$1\r\na\r\n:1\r\n$-1\r\n", []any{[]byte("a"), int64(1), nil}},
+		{"*2\r\n$1\r\n0\r\n*1\r\n$3\r\nkey\r\n", []any{[]byte("0"), []any{[]byte("key")}}},
+	}
+	for _, test := range tests {
+		c := &redisConn{reader: bufio.NewReader(strings.NewReader(test.raw))}
+		got, err := c.read()
+		if err != nil {
+			t.Errorf("Could not read %q: %s", test.raw, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Expected %q to read as %#v, Got %#v", test.raw, test.expected, got)
+		}
+	}
+
+	c := &redisConn{reader: bufio.NewReader(strings.NewReader("-ERR wrong type\r\n"))}
+	var answered redisError
+	if _, err := c.read(); !errors.As(err, &answered) || string(answered) != "ERR wrong type" {
+		t.Errorf("Expected the error Redis answered with, Got %v", err)
+	}
+	for _, raw := range []string{"", "\r\n", "?what\r\n", ":many\r\n", "$5\r\nhel", "*2\r\n+OK\r\n"} {
+		c := &redisConn{reader: bufio.NewReader(strings.NewReader(raw))}
+		if got, err := c.read(); err == nil {
+			t.Errorf("Expected %q to fail, Got %#v", raw, got)
+		}
+	}
+}
+
+func TestRedisStorage(t *testing.T) {
+	fake, url := newFakeRedis(t, "secret")
+	kvs, err := OpenRedis(url)
+	if err != nil {
+		t.Fatalf("Could not connect to the fake Redis: %s", err)
+	}
+	t.Cleanup(func() { kvs.Close() })
+	fake.mutex.Lock()
+	if fake.selected != "2" {
+		t.Errorf("Expected database 2 to be selected, Got %q", fake.selected)
+	}
+	fake.mutex.Unlock()
+
+	for i, input := range []string{"zero", "one", "two"} {
+		if err := kvs.Set(testGuild, col, i, input); err != nil {
+			t.Fatalf("Could not set test input value: %v", err)
+		}
+	}
+	var output string
+	if found, err := kvs.Get(testGuild, col, 1, &output); err != nil || !found || output != "one" {
+		t.Errorf("Expected one, Got %q (found: %t, %v)", output, found, err)
+	}
+	if found, err := kvs.Get(testGuild, col, 9, &output); err != nil || found {
+		t.Errorf("Expected nothing for a missing key, Got found: %t, %v", found, err)
+	}
+
+	outputs := make([]string, 3)
+	found, err := kvs.GetMany(testGuild, col, []any{2, 9, 0}, func(i int) any { return &outputs[i] })
+	if err != nil || !reflect.DeepEqual(found, []bool{true, false, true}) || outputs[0] != "two" || outputs[2] != "zero" {
+		t.Errorf("Expected two, nothing and zero, Got %v %q (%v)", found, outputs, err)
+	}
+
+	keys, err := kvs.Keys(testGuild, col)
+	if err != nil || !reflect.DeepEqual(keys, []string{"0", "1", "2"}) {
+		t.Errorf("Expected the three keys, Got %v (%v)", keys, err)
+	}
+	usage, err := kvs.Usage(testGuild)
+	if err != nil || usage[col].Keys != 3 {
+		t.Errorf("Expected three keys in use, Got %+v (%v)", usage, err)
+	}
+	guilds, err := kvs.Guilds()
+	if err != nil || len(guilds) != 1 || guilds[0] != testGuild {
+		t.Errorf("Expected only %s in storage, Got %v (%v)", testGuild, guilds, err)
+	}
+
+	var answered redisError
+	if _, err := kvs.do("NONSENSE"); !errors.As(err, &answered) {
+		t.Errorf("Expected the error Redis answered with, Got %v", err)
+	}
+	if _, err := kvs.do("PING"); err != nil {
+		t.Errorf("Expected the connection to still work after an error, Got %v", err)
+	}
+
+	if _, err := OpenRedis(strings.Replace(url, "secret", "wrong", 1)); err == nil {
+		t.Error("Expected the wrong password to be refused")
+	}
+}
+
+func TestRedisDeleteRestore(t *testing.T) {
+	_, url := newFakeRedis(t, "")
+	kvs, err := OpenRedis(url)
+	if err != nil {
+		t.Fatalf("Could not connect to the fake Redis: %s", err)
+	}
+	t.Cleanup(func() { kvs.Close() })
+
+	if err := kvs.Set(testGuild, col, "key", "not trashed"); err != nil {
+		t.Fatalf("Could not set test input value: %v", err)
+	}
+	if err := kvs.Delete(testGuild, col, "key"); err != nil {
+		t.Fatalf("Could not delete: %v", err)
+	}
+	if restored, err := kvs.Restore(testGuild, col, "key"); err != nil || restored {
+		t.Errorf("Expected nothing to restore from a collection that isn't trashed, Got %t (%v)", restored, err)
+	}
+
+	if err := kvs.Set(testGuild, "faq", "rules", "Be nice"); err != nil {
+		t.Fatalf("Could not set test input value: %v", err)
+	}
+	if err := kvs.Delete(testGuild, "faq", "rules"); err != nil {
+		t.Fatalf("Could not delete: %v", err)
+	}
+	var output string
+	if found, err := kvs.Get(testGuild, "faq", "rules", &output); err != nil || found {
+		t.Errorf("Expected it to be gone, Got found: %t, %v", found, err)
+	}
+	trash, err := GetTrash(kvs, testGuild, "faq")
+	if err != nil || len(trash) != 1 || trash[0].Key != "rules" {
+		t.Fatalf("Expected it in the trash, Got %+v (%v)", trash, err)
+	}
+
+	if err := kvs.Set(testGuild, "faq", "rules", "Something else"); err != nil {
+		t.Fatalf("Could not set test input value: %v", err)
+	}
+	if _, err := kvs.Restore(testGuild, "faq", "rules"); !errors.Is(err, ErrTrashOccupied) {
+		t.Errorf("Expected ErrTrashOccupied with something else there, Got %v", err)
+	}
+	if err := kvs.Delete(testGuild, "faq", "rules"); err != nil {
+		t.Fatalf("Could not delete: %v", err)
+	}
+	if restored, err := kvs.Restore(testGuild, "faq", "rules"); err != nil || !restored {
+		t.Fatalf("Could not restore: %t (%v)", restored, err)
+	}
+	if _, err := kvs.Get(testGuild, "faq", "rules", &output); err != nil || output != "Something else" {
+		t.Errorf("Expected what was deleted last, Got %q (%v)", output, err)
+	}
+	if trash, err := GetTrash(kvs, testGuild, "faq"); err != nil || len(trash) != 0 {
+		t.Errorf("Expected the trash to be empty, Got %+v (%v)", trash, err)
+	}
+}
+
+func TestRedisLock(t *testing.T) {
+	_, url := newFakeRedis(t, "")
+	kvs, err := OpenRedis(url)
+	if err != nil {
+		t.Fatalf("Could not connect to the fake Redis: %s", err)
+	}
+	t.Cleanup(func() { kvs.Close() })
+
+	unlock, acquired, err := kvs.Lock(testGuild, "timers", time.Minute)
+	if err != nil || !acquired {
+		t.Fatalf("Could not take the lock: %t (%v)", acquired, err)
+	}
+	if _, acquired, err := kvs.Lock(testGuild, "timers", time.Minute); err != nil || acquired {
+		t.Errorf("Expected the lock to be taken already, Got %t (%v)", acquired, err)
+	}
+	if _, acquired, err := kvs.Lock(testGuild, "other", time.Minute); err != nil || !acquired {
+		t.Errorf("Expected another lock to be free, Got %t (%v)", acquired, err)
+	}
+	unlock()
+	if _, acquired, err := kvs.Lock(testGuild, "timers", time.Millisecond); err != nil || !acquired {
+		t.Errorf("Expected the lock to be free once unlocked, Got %t (%v)", acquired, err)
+	}
+
+	// The one that ran out shouldn't be able to unlock the one that took over.
+	expired, _, _ := kvs.Lock(testGuild, "expiring", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, acquired, err := kvs.Lock(testGuild, "expiring", time.Minute); err != nil || !acquired {
+		t.Fatalf("Expected the lock to be free once it ran out, Got %t (%v)", acquired, err)
+	}
+	expired()
+	if _, acquired, err := kvs.Lock(testGuild, "expiring", time.Minute); err != nil || acquired {
+		t.Errorf("Expected the lock to stay with the one that took over, Got %t (%v)", acquired, err)
+	}
+
+	for expected := int64(1); expected <= 3; expected++ {
+		if number, err := NextNumber(kvs, testGuild, "test"); err != nil || number != expected {
+			t.Errorf("Expected %d from the counter, which takes a lock, Got %d (%v)", expected, number, err)
+		}
+	}
+}
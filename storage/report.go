@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// ReportConfig is where reported messages go in a guild.
+type ReportConfig struct {
+	ChannelID discord.ChannelID
+}
+
+// GetReportConfig gets the report setup for the guild, if there is one.
+func GetReportConfig(kvs KeyValueStore, guildID discord.GuildID) (exist bool, config ReportConfig, err error) {
+	exist, err = kvs.Get(guildID, "report", "config", &config)
+	return
+}
+
+// SetReportConfig stores the report setup for the guild.
+func SetReportConfig(kvs KeyValueStore, guildID discord.GuildID, config ReportConfig) error {
+	return kvs.Set(guildID, "report", "config", config)
+}
+
+// DisableReports forgets the report setup for the guild.
+func DisableReports(kvs KeyValueStore, guildID discord.GuildID) error {
+	return kvs.Delete(guildID, "report", "config")
+}
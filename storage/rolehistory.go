@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// roleHistoryLimit is how many role changes are kept per user. The oldest are dropped first.
+const roleHistoryLimit = 100
+
+// RoleChange is someone getting or losing a role.
+type RoleChange struct {
+	When   int64
+	RoleID discord.RoleID
+	Added  bool
+}
+
+// roleHistory is the roles someone had last time they were seen, to tell what changed, and the changes so far.
+type roleHistory struct {
+	Roles   []discord.RoleID
+	Changes []RoleChange
+}
+
+// GetRoleChanges gets the roles the user has gotten and lost, oldest first.
+func GetRoleChanges(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID) ([]RoleChange, error) {
+	history := roleHistory{}
+	_, err := kvs.Get(guildID, "rolehistory", userID, &history)
+	return history.Changes, err
+}
+
+// RecordRoles compares the roles with the ones the user had last time, and adds what changed to their history.
+// The first time, there's nothing to compare with, so the roles are just noted for next time.
+func RecordRoles(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID, roles []discord.RoleID) ([]RoleChange, error) {
+	unlock, err := LockRecord(kvs, guildID, "rolehistory", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	history := roleHistory{}
+	exist, err := kvs.Get(guildID, "rolehistory", userID, &history)
+	if err != nil {
+		return nil, err
+	}
+	changes := []RoleChange{}
+	if exist {
+		now := time.Now().Unix()
+		had := map[discord.RoleID]bool{}
+		for _, roleID := range history.Roles {
+			had[roleID] = true
+		}
+		for _, roleID := range roles {
+			if !had[roleID] {
+				changes = append(changes, RoleChange{When: now, RoleID: roleID, Added: true})
+			}
+			delete(had, roleID)
+		}
+		for _, roleID := range history.Roles {
+			if had[roleID] {
+				changes = append(changes, RoleChange{When: now, RoleID: roleID})
+			}
+		}
+		if len(changes) == 0 {
+			return changes, nil
+		}
+	}
+	history.Roles = roles
+	history.Changes = append(history.Changes, changes...)
+	if len(history.Changes) > roleHistoryLimit {
+		history.Changes = history.Changes[len(history.Changes)-roleHistoryLimit:]
+	}
+	return changes, kvs.Set(guildID, "rolehistory", userID, history)
+}
+
+// NoteRoles notes the roles the user has right now, to compare with next time, without counting it as a change.
+// For when they join, as whatever they had when they left wasn't taken away just now.
+func NoteRoles(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID, roles []discord.RoleID) error {
+	unlock, err := LockRecord(kvs, guildID, "rolehistory", userID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	history := roleHistory{}
+	if _, err := kvs.Get(guildID, "rolehistory", userID, &history); err != nil {
+		return err
+	}
+	history.Roles = roles
+	return kvs.Set(guildID, "rolehistory", userID, history)
+}
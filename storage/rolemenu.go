@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// RoleMenuRole is a role in a role menu group, with a bit of text to say what it's for.
+type RoleMenuRole struct {
+	RoleID      discord.RoleID
+	Description string
+}
+
+// RoleMenuGroup is a set of roles people pick from, like colors or pronouns.
+type RoleMenuGroup struct {
+	Name      string
+	Exclusive bool // Only one of the roles at a time.
+	Roles     []RoleMenuRole
+}
+
+// Has checks if the role is in the group.
+func (g RoleMenuGroup) Has(roleID discord.RoleID) bool {
+	for _, role := range g.Roles {
+		if role.RoleID == roleID {
+			return true
+		}
+	}
+	return false
+}
+
+// Store saves the group, replacing any with the same name.
+func (g RoleMenuGroup) Store(kvs KeyValueStore, guildID discord.GuildID) error {
+	return kvs.Set(guildID, "rolemenugroups", g.Name, g)
+}
+
+// GetRoleMenuGroup gets the role menu group with the given name.
+func GetRoleMenuGroup(kvs KeyValueStore, guildID discord.GuildID, name string) (exist bool, group RoleMenuGroup, err error) {
+	exist, err = kvs.Get(guildID, "rolemenugroups", name, &group)
+	return
+}
+
+// DeleteRoleMenuGroup forgets the role menu group with the given name.
+func DeleteRoleMenuGroup(kvs KeyValueStore, guildID discord.GuildID, name string) error {
+	return kvs.Delete(guildID, "rolemenugroups", name)
+}
+
+// GetRoleMenuGroups gets all the role menu groups of the guild, sorted by name.
+func GetRoleMenuGroups(kvs KeyValueStore, guildID discord.GuildID) ([]RoleMenuGroup, error) {
+	keys, err := kvs.Keys(guildID, "rolemenugroups")
+	if err != nil {
+		return nil, fmt.Errorf("role menu groups could not get keys: %w", err)
+	}
+	sort.Strings(keys)
+	groups := make([]RoleMenuGroup, 0, len(keys))
+	for _, key := range keys {
+		exist, group, err := GetRoleMenuGroup(kvs, guildID, key)
+		if err != nil {
+			return nil, fmt.Errorf("role menu groups could not get %s: %w", key, err)
+		}
+		if exist {
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// GetSayRoles gets the roles that can make the bot say things, besides the administrators.
+func GetSayRoles(kvs KeyValueStore, guildID discord.GuildID) ([]discord.RoleID, error) {
+	roles := []discord.RoleID{}
+	_, err := kvs.Get(guildID, "say", "roles", &roles)
+	return roles, err
+}
+
+// SetSayRoles stores the roles that can make the bot say things.
+func SetSayRoles(kvs KeyValueStore, guildID discord.GuildID, roles []discord.RoleID) error {
+	if len(roles) == 0 {
+		return kvs.Delete(guildID, "say", "roles")
+	}
+	return kvs.Set(guildID, "say", "roles", roles)
+}
@@ -1,7 +1,9 @@
 package storage
 
 import (
+	"context"
 	"fmt"
+	"komainu/membercache"
 	"komainu/utility"
 	"log"
 	"time"
@@ -24,6 +26,141 @@ func LastSeen(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID)
 
 }
 
+// SeeOnline saves the given user as being online in the given guild, for guilds that have opted in to that.
+func SeeOnline(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID) error {
+	return kvs.Set(guildID, "online", userID, time.Now().Unix())
+}
+
+// LastOnline checks to see when the given user was last online in the given guild, as opposed to saying something.
+func LastOnline(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID) (bool, int64, error) {
+	var onlineTimestamp int64
+	exist, err := kvs.Get(guildID, "online", userID, &onlineTimestamp)
+	return exist, onlineTimestamp, err
+}
+
+// GetPresenceTracking checks if the guild has opted in to noting when people are online.
+func GetPresenceTracking(kvs KeyValueStore, guildID discord.GuildID) (bool, error) {
+	enabled := false
+	_, err := kvs.Get(guildID, "presence", "enabled", &enabled)
+	return enabled, err
+}
+
+// SetPresenceTracking opts the guild in or out of noting when people are online.
+// Opting out also forgets when everyone was last online, as that's the point of opting out.
+func SetPresenceTracking(kvs KeyValueStore, guildID discord.GuildID, enabled bool) error {
+	if enabled {
+		return kvs.Set(guildID, "presence", "enabled", true)
+	}
+	if err := kvs.Delete(guildID, "presence", "enabled"); err != nil {
+		return err
+	}
+	keys, err := kvs.Keys(guildID, "online")
+	if err != nil {
+		return fmt.Errorf("failed to list who was online: %w", err)
+	}
+	for _, key := range keys {
+		if err := kvs.Delete(guildID, "online", key); err != nil {
+			return fmt.Errorf("failed to forget when %s was online: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// LastSeenMany checks when all the given users were seen in the given guild, in one go.
+// Anyone that was never seen is left out.
+func LastSeenMany(kvs KeyValueStore, guildID discord.GuildID, userIDs []discord.UserID) (map[discord.UserID]int64, error) {
+	keys := make([]any, len(userIDs))
+	for i, userID := range userIDs {
+		keys[i] = userID
+	}
+	timestamps := make([]int64, len(userIDs))
+	found, err := kvs.GetMany(guildID, "seen", keys, func(i int) any { return &timestamps[i] })
+	if err != nil {
+		return nil, err
+	}
+	seen := map[discord.UserID]int64{}
+	for i, userID := range userIDs {
+		if found[i] {
+			seen[userID] = timestamps[i]
+		}
+	}
+	return seen, nil
+}
+
+// Activity is how someone has been around in a guild over time, beyond just when they were last seen.
+type Activity struct {
+	Joined     int64 // When they first joined, as far as the bot noticed. Zero if they were here before it was looking.
+	FirstDay   int64 // The first day they were seen saying anything, in days since the epoch.
+	LastDay    int64 // The last day they were seen saying anything, in days since the epoch.
+	ActiveDays int   // How many different days they've been seen saying something.
+}
+
+// Regular tells if they used to be around a fair bit, on at least one day in a week on average while they were.
+// It takes a few days of activity to tell, so nobody is a regular after a single chatty afternoon.
+func (activity Activity) Regular() bool {
+	if activity.ActiveDays < 3 {
+		return false
+	}
+	span := activity.LastDay - activity.FirstDay + 1
+	return float64(activity.ActiveDays)/float64(span) >= 1.0/7
+}
+
+func epochDay(when time.Time) int64 {
+	return when.Unix() / (24 * 3600)
+}
+
+// RecordActivity counts the day the user was seen saying something in the guild, unless that's already counted.
+func RecordActivity(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID, when time.Time) error {
+	activity := Activity{}
+	if _, err := kvs.Get(guildID, "activity", userID, &activity); err != nil {
+		return err
+	}
+	day := epochDay(when)
+	if activity.ActiveDays > 0 && activity.LastDay == day {
+		return nil // Already counted, and no need to write it again.
+	}
+	if activity.ActiveDays == 0 {
+		activity.FirstDay = day
+	}
+	activity.LastDay = day
+	activity.ActiveDays++
+	return kvs.Set(guildID, "activity", userID, activity)
+}
+
+// RecordJoin notes when the user joined the guild, unless they've been here before.
+func RecordJoin(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID, joined time.Time) error {
+	activity := Activity{}
+	if _, err := kvs.Get(guildID, "activity", userID, &activity); err != nil {
+		return err
+	}
+	if activity.Joined != 0 {
+		return nil
+	}
+	activity.Joined = joined.Unix()
+	return kvs.Set(guildID, "activity", userID, activity)
+}
+
+// ActivityMany gets the activity of all the given users in the guild, in one go.
+// Anyone the bot knows nothing about is left out.
+func ActivityMany(kvs KeyValueStore, guildID discord.GuildID, userIDs []discord.UserID) (map[discord.UserID]Activity, error) {
+	keys := make([]any, len(userIDs))
+	for i, userID := range userIDs {
+		keys[i] = userID
+	}
+	activities := make([]Activity, len(userIDs))
+	found, err := kvs.GetMany(guildID, "activity", keys, func(i int) any { return &activities[i] })
+	if err != nil {
+		return nil, err
+	}
+	known := map[discord.UserID]Activity{}
+	for i, userID := range userIDs {
+		if found[i] {
+			known[userID] = activities[i]
+		}
+	}
+	return known, nil
+}
+
 func MaybeGiveActiveRole(kvs KeyValueStore, state *state.State, guildID discord.GuildID, member *discord.Member) (err error) {
 
 	if member == nil {
@@ -76,45 +213,54 @@ func RevokeActiveRoles(state *state.State, kvs KeyValueStore) error {
 	now := time.Now().Unix()
 	secondsInDay := float64(24 * 60 * 60)
 	for _, guild := range guilds {
-		role := discord.NullRoleID
-		exist, err := kvs.Get(guild.ID, "activerole", "role", &role)
-		if err != nil {
-			log.Printf("[%s] Failed to fetch the active role object: %s\n", guild.ID, err)
-		}
-		if !exist {
-			continue // Because if the role isn't set, this guild has no "active role"
-		}
+		err := GuildJob(kvs, guild.ID, "activerole", 10*time.Minute, func() error {
+			role := discord.NullRoleID
+			exist, err := kvs.Get(guild.ID, "activerole", "role", &role)
+			if err != nil {
+				log.Printf("[%s] Failed to fetch the active role object: %s\n", guild.ID, err)
+			}
+			if !exist {
+				return nil // Because if the role isn't set, this guild has no "active role"
+			}
 
-		var days float64
-		exist, err = kvs.Get(guild.ID, "activerole", "days", &days)
-		if err != nil {
-			log.Printf("[%s] Failed to fetch the active role time: %s\n", guild.ID, err)
-		}
-		if !exist {
-			continue
-		}
-		inactiveIfSeenBefore := now - int64(days*secondsInDay)
+			var days float64
+			exist, err = kvs.Get(guild.ID, "activerole", "days", &days)
+			if err != nil {
+				log.Printf("[%s] Failed to fetch the active role time: %s\n", guild.ID, err)
+			}
+			if !exist {
+				return nil
+			}
+			inactiveIfSeenBefore := now - int64(days*secondsInDay)
 
-		members, err := state.Session.Members(guild.ID, 0)
-		if err != nil {
-			log.Printf("[%s] Failed to fetch the member list: %s\n", guild.ID, err)
-			continue
-		}
-		for _, member := range members {
-			wasSeen, when, err := LastSeen(kvs, guild.ID, member.User.ID)
+			ctx, cancel := context.WithTimeout(context.Background(), membercache.RequestTimeout)
+			members, err := membercache.Get(ctx, state, guild.ID)
+			cancel()
 			if err != nil {
-				log.Printf("[%s] Failed to fetch seen data for member: %s", guild.ID, err)
+				log.Printf("[%s] Failed to fetch the member list: %s\n", guild.ID, err)
+				return nil
 			}
-			if !wasSeen || when < inactiveIfSeenBefore {
-				if utility.ContainsRole(member.RoleIDs, role) {
-					err := state.RemoveRole(guild.ID, member.User.ID, role, api.AuditLogReason("Role automatically revoked for chat inactivity."))
-					if err != nil {
-						log.Printf("[%s] Failed to remove role from inactive user: %s", guild.ID, err)
+			lastSeen, err := LastSeenMany(kvs, guild.ID, membercache.UserIDs(members))
+			if err != nil {
+				log.Printf("[%s] Failed to fetch seen data for the members: %s", guild.ID, err)
+				return nil
+			}
+			for _, member := range members {
+				when, wasSeen := lastSeen[member.User.ID]
+				if !wasSeen || when < inactiveIfSeenBefore {
+					if utility.ContainsRole(member.RoleIDs, role) {
+						err := state.RemoveRole(guild.ID, member.User.ID, role, api.AuditLogReason("Role automatically revoked for chat inactivity."))
+						if err != nil {
+							log.Printf("[%s] Failed to remove role from inactive user: %s", guild.ID, err)
+						}
 					}
 				}
 			}
+			return nil
+		})
+		if err != nil {
+			return err
 		}
-
 	}
 	return nil
 }
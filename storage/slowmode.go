@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+const (
+	SlowmodeDaily     = "daily"
+	SlowmodeSpike     = "spike"
+	SlowmodeRuleLimit = 25 // How many slowmode rules a single guild can have.
+)
+
+// SlowmodeRule changes the slowmode of a channel by itself, either at a time of day or when the channel gets busy.
+type SlowmodeRule struct {
+	ID        int64
+	GuildID   discord.GuildID
+	ChannelID discord.ChannelID
+	Kind      string // SlowmodeDaily or SlowmodeSpike.
+	Seconds   int64  // The slowmode to set. Zero turns it off.
+	Minute    int    // Daily only: when to set it, in minutes after midnight in the guild's time zone.
+	Messages  int    // Spike only: this many messages in a minute...
+	Duration  int64  // Spike only: ...sets the slowmode for this many seconds.
+	TimerID   string // Daily only: the timer that sets it next time.
+	AddedBy   discord.UserID
+}
+
+// NextDaily returns when a daily rule should next set the slowmode, after the given time.
+func (rule *SlowmodeRule) NextDaily(after time.Time, location *time.Location) time.Time {
+	local := after.In(location)
+	next := time.Date(local.Year(), local.Month(), local.Day(), rule.Minute/60, rule.Minute%60, 0, 0, location)
+	for !next.After(after) {
+		local = local.AddDate(0, 0, 1)
+		next = time.Date(local.Year(), local.Month(), local.Day(), rule.Minute/60, rule.Minute%60, 0, 0, location)
+	}
+	return next
+}
+
+// Store stores the rule.
+func (rule *SlowmodeRule) Store(kvs KeyValueStore) error {
+	return kvs.Set(rule.GuildID, "slowmode", rule.ID, rule)
+}
+
+// Delete forgets the rule, along with the timer of a daily rule.
+func (rule *SlowmodeRule) Delete(kvs KeyValueStore) error {
+	if rule.TimerID != "" {
+		if err := CancelTimer(kvs, rule.GuildID, rule.TimerID); err != nil {
+			return fmt.Errorf("cancelling the timer of slowmode rule %d: %w", rule.ID, err)
+		}
+	}
+	return kvs.Delete(rule.GuildID, "slowmode", rule.ID)
+}
+
+// GetSlowmodeRule gets a single slowmode rule of the guild.
+func GetSlowmodeRule(kvs KeyValueStore, guildID discord.GuildID, id int64) (exist bool, rule SlowmodeRule, err error) {
+	exist, err = kvs.Get(guildID, "slowmode", id, &rule)
+	return exist, rule, err
+}
+
+// GetSlowmodeRules gets all the slowmode rules of the guild, in the order they were added.
+func GetSlowmodeRules(kvs KeyValueStore, guildID discord.GuildID) ([]SlowmodeRule, error) {
+	keys, err := kvs.Keys(guildID, "slowmode")
+	if err != nil {
+		return nil, fmt.Errorf("getting slowmode rules could not get keys: %w", err)
+	}
+	rules := []SlowmodeRule{}
+	for _, key := range keys {
+		rule := SlowmodeRule{}
+		exist, err := kvs.Get(guildID, "slowmode", key, &rule)
+		if err != nil {
+			return nil, fmt.Errorf("getting slowmode rules could not get %s: %w", key, err)
+		}
+		if exist {
+			rules = append(rules, rule)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+	return rules, nil
+}
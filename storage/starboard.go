@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// StarboardConfig is how the starboard is set up in a guild.
+type StarboardConfig struct {
+	ChannelID discord.ChannelID
+	Emoji     discord.APIEmoji
+	Threshold int
+}
+
+// StarboardPost connects a starred message to where it was reposted.
+type StarboardPost struct {
+	ChannelID discord.ChannelID // Where the original message is.
+	PostID    discord.MessageID // The repost in the starboard channel.
+	Count     int
+}
+
+// GetStarboardConfig gets the starboard setup for the guild, if there is one.
+func GetStarboardConfig(kvs KeyValueStore, guildID discord.GuildID) (exist bool, config StarboardConfig, err error) {
+	exist, err = kvs.Get(guildID, "starboard", "config", &config)
+	return
+}
+
+// SetStarboardConfig stores the starboard setup for the guild.
+func SetStarboardConfig(kvs KeyValueStore, guildID discord.GuildID, config StarboardConfig) error {
+	return kvs.Set(guildID, "starboard", "config", config)
+}
+
+// DisableStarboard forgets the starboard setup for the guild. Posts already made are left alone.
+func DisableStarboard(kvs KeyValueStore, guildID discord.GuildID) error {
+	return kvs.Delete(guildID, "starboard", "config")
+}
+
+// GetStarboardPost looks up the repost of the given message, if there is one.
+func GetStarboardPost(kvs KeyValueStore, guildID discord.GuildID, messageID discord.MessageID) (exist bool, post StarboardPost, err error) {
+	exist, err = kvs.Get(guildID, "starboardposts", messageID, &post)
+	return
+}
+
+// SetStarboardPost remembers the repost of the given message.
+func SetStarboardPost(kvs KeyValueStore, guildID discord.GuildID, messageID discord.MessageID, post StarboardPost) error {
+	return kvs.Set(guildID, "starboardposts", messageID, post)
+}
+
+// ForgetStarboardPost forgets the repost of the given message.
+func ForgetStarboardPost(kvs KeyValueStore, guildID discord.GuildID, messageID discord.MessageID) error {
+	return kvs.Delete(guildID, "starboardposts", messageID)
+}
+
+// ParseEmoji turns an emoji as typed in the client, like "⭐" or "<:star:123>", into the format the API uses.
+func ParseEmoji(input string) (discord.APIEmoji, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", fmt.Errorf("no emoji given")
+	}
+	if !strings.HasPrefix(input, "<") {
+		if strings.ContainsAny(input, " :<>") {
+			return "", fmt.Errorf("%q does not look like an emoji", input)
+		}
+		return discord.APIEmoji(input), nil
+	}
+	parts := strings.Split(strings.Trim(input, "<>"), ":")
+	if len(parts) != 3 || (parts[0] != "" && parts[0] != "a") {
+		return "", fmt.Errorf("%q does not look like an emoji", input)
+	}
+	id, err := discord.ParseSnowflake(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("%q has a weird ID: %w", input, err)
+	}
+	return discord.NewAPIEmoji(discord.EmojiID(id), parts[1]), nil
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// StatusOverride is a status the owners set with /debug status, shown instead of the usual rotation.
+type StatusOverride struct {
+	Text  string
+	Until int64 // When it goes back to the rotation, in Unix time. Zero means not until it's cleared.
+	SetBy discord.UserID
+}
+
+// GetStatusOverride gets the status set instead of the rotation, if there is one. It may have run out.
+func GetStatusOverride(kvs KeyValueStore) (exist bool, override StatusOverride, err error) {
+	exist, err = kvs.Get(GlobalScope, "status", "override", &override)
+	return
+}
+
+// SetStatusOverride shows the given status instead of the rotation, on every shard.
+func SetStatusOverride(kvs KeyValueStore, override StatusOverride) error {
+	return kvs.Set(GlobalScope, "status", "override", override)
+}
+
+// ClearStatusOverride goes back to the rotation.
+func ClearStatusOverride(kvs KeyValueStore) error {
+	return kvs.Delete(GlobalScope, "status", "override")
+}
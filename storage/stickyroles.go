@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// StickyRolesConfig is how roles are given back to people who leave and come back.
+type StickyRolesConfig struct {
+	Enabled bool
+	Exclude map[discord.RoleID]bool // Roles that are never given back.
+}
+
+// GetStickyRolesConfig gets the sticky roles setup for the guild, or an empty one.
+func GetStickyRolesConfig(kvs KeyValueStore, guildID discord.GuildID) (config StickyRolesConfig, err error) {
+	_, err = kvs.Get(guildID, "stickyroles", "config", &config)
+	if config.Exclude == nil {
+		config.Exclude = map[discord.RoleID]bool{} // gob doesn't bother with empty maps.
+	}
+	return
+}
+
+// SetStickyRolesConfig stores the sticky roles setup for the guild.
+func SetStickyRolesConfig(kvs KeyValueStore, guildID discord.GuildID, config StickyRolesConfig) error {
+	return kvs.Set(guildID, "stickyroles", "config", config)
+}
+
+// RememberRoles stores the roles the user has right now.
+func RememberRoles(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID, roles []discord.RoleID) error {
+	if len(roles) == 0 {
+		return kvs.Delete(guildID, "rememberedroles", userID)
+	}
+	return kvs.Set(guildID, "rememberedroles", userID, roles)
+}
+
+// RememberedRoles gets the roles the user had last time they were looked at.
+func RememberedRoles(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID) ([]discord.RoleID, error) {
+	roles := []discord.RoleID{}
+	_, err := kvs.Get(guildID, "rememberedroles", userID, &roles)
+	return roles, err
+}
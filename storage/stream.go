@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"fmt"
+	"komainu/utility"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+const (
+	StreamTwitch       = "twitch"
+	StreamYouTube      = "youtube"
+	StreamLimit        = 25 // How many channels a single guild can watch.
+	streamPollInterval = 3 * time.Minute
+)
+
+// StreamPlaceholders are the extra placeholders a stream announcement can have, on top of the usual ones.
+var StreamPlaceholders = []string{"name", "title", "url", "game"}
+
+// StreamDefaultMessage is what's announced if no message is given, per platform.
+var StreamDefaultMessage = map[string]string{
+	StreamTwitch:  "**{name}** is live: {title}\n{url}",
+	StreamYouTube: "**{name}** uploaded a new video: {title}\n{url}",
+}
+
+// StreamWatch is a Twitch channel or YouTube channel the guild announces, when it goes live or uploads.
+type StreamWatch struct {
+	ID        int64
+	GuildID   discord.GuildID
+	ChannelID discord.ChannelID // Where the announcements go.
+	Platform  string            // StreamTwitch or StreamYouTube.
+	Account   string            // The Twitch login, or the YouTube channel ID.
+	Name      string            // What the account is called, as far as we know.
+	Message   string            // The template announcements are made from.
+	RoleID    discord.RoleID    // Pinged with every announcement. Optional.
+	AddedBy   discord.UserID
+	Live      bool     // Twitch only: if it was live the last time it was checked.
+	Seen      []string // YouTube only: the videos already announced, or already there when it was added.
+	LastError string   // What went wrong the last time it was checked, if anything did.
+}
+
+// YouTubeFeedURL returns the address of the uploads feed of the YouTube channel.
+func YouTubeFeedURL(channelID string) string {
+	return "https://www.youtube.com/feeds/videos.xml?channel_id=" + channelID
+}
+
+// Store stores the watch.
+func (watch *StreamWatch) Store(kvs KeyValueStore) error {
+	return kvs.Set(watch.GuildID, "streams", watch.ID, watch)
+}
+
+// Delete forgets the watch.
+func (watch *StreamWatch) Delete(kvs KeyValueStore) error {
+	return kvs.Delete(watch.GuildID, "streams", watch.ID)
+}
+
+// MarkSeen remembers the videos as seen.
+func (watch *StreamWatch) MarkSeen(items ...utility.FeedItem) {
+	watch.Seen = markSeen(watch.Seen, items...)
+}
+
+// GetStreamWatch gets a single watch of the guild.
+func GetStreamWatch(kvs KeyValueStore, guildID discord.GuildID, id int64) (exist bool, watch StreamWatch, err error) {
+	exist, err = kvs.Get(guildID, "streams", id, &watch)
+	return exist, watch, err
+}
+
+// GetStreamWatches gets all the watches of the guild, in the order they were added.
+func GetStreamWatches(kvs KeyValueStore, guildID discord.GuildID) ([]StreamWatch, error) {
+	keys, err := kvs.Keys(guildID, "streams")
+	if err != nil {
+		return nil, fmt.Errorf("getting stream watches could not get keys: %w", err)
+	}
+	watches := []StreamWatch{}
+	for _, key := range keys {
+		watch := StreamWatch{}
+		exist, err := kvs.Get(guildID, "streams", key, &watch)
+		if err != nil {
+			return nil, fmt.Errorf("getting stream watches could not get %s: %w", key, err)
+		}
+		if exist {
+			watches = append(watches, watch)
+		}
+	}
+	sort.Slice(watches, func(i, j int) bool {
+		return watches[i].ID < watches[j].ID
+	})
+	return watches, nil
+}
+
+// announce posts the announcement, filled in with what the stream or video is about.
+func (watch *StreamWatch) announce(state *state.State, values map[string]string) error {
+	serverName := watch.GuildID.String()
+	if guild, err := state.Guild(watch.GuildID); err == nil {
+		serverName = guild.Name
+	}
+	message := watch.Message
+	if message == "" {
+		message = StreamDefaultMessage[watch.Platform]
+	}
+	content := utility.RenderTemplate(message, utility.TemplateValues{
+		Channel: watch.ChannelID.Mention(),
+		Server:  serverName,
+		Extra:   values,
+	})
+	mentions := &api.AllowedMentions{Parse: []api.AllowedMentionType{}}
+	if watch.RoleID.IsValid() {
+		content = watch.RoleID.Mention() + " " + content
+		mentions.Roles = []discord.RoleID{watch.RoleID}
+	}
+	_, err := state.SendMessageComplex(watch.ChannelID, api.SendMessageData{
+		Content:         utility.Substring(content, 0, 2000),
+		AllowedMentions: mentions,
+	})
+	return err
+}
+
+// checkYouTube announces the videos uploaded since the last time.
+func (watch *StreamWatch) checkYouTube(state *state.State) {
+	fetched, err := FetchFeed(YouTubeFeedURL(watch.Account))
+	if err != nil {
+		watch.LastError = err.Error()
+		return
+	}
+	watch.LastError = ""
+	if fetched.Title != "" {
+		watch.Name = fetched.Title
+	}
+	fresh := unseen(watch.Seen, fetched.Items)
+	if len(fresh) > feedPostLimit {
+		watch.MarkSeen(fresh[:len(fresh)-feedPostLimit]...)
+		fresh = fresh[len(fresh)-feedPostLimit:]
+	}
+	for _, item := range fresh {
+		err := watch.announce(state, map[string]string{
+			"name":  watch.Name,
+			"title": item.Title,
+			"url":   item.Link,
+			"game":  "",
+		})
+		if err != nil {
+			watch.LastError = "could not post in the channel: " + err.Error()
+			return
+		}
+		watch.MarkSeen(item)
+	}
+}
+
+// checkTwitch announces the stream if it went live since the last time.
+func (watch *StreamWatch) checkTwitch(state *state.State, live map[string]TwitchStream) {
+	stream, isLive := live[strings.ToLower(watch.Account)]
+	if isLive && !watch.Live {
+		watch.Name = stream.UserName
+		err := watch.announce(state, map[string]string{
+			"name":  stream.UserName,
+			"title": stream.Title,
+			"url":   "https://twitch.tv/" + stream.UserLogin,
+			"game":  stream.GameName,
+		})
+		if err != nil {
+			watch.LastError = "could not post in the channel: " + err.Error()
+			return // Not marked as live, so it's tried again next time.
+		}
+	}
+	watch.LastError = ""
+	watch.Live = isLive
+}
+
+// CheckStreams checks all the watched channels of all the guilds, announcing the ones that went live or uploaded.
+func CheckStreams(state *state.State, kvs KeyValueStore, cfg Configuration) error {
+	guilds, err := state.Guilds()
+	if err != nil {
+		return fmt.Errorf("checking streams could not fetch current guilds: %w", err)
+	}
+	for _, guild := range guilds {
+		err := GuildJob(kvs, guild.ID, "streams", streamPollInterval, func() error {
+			watches, err := GetStreamWatches(kvs, guild.ID)
+			if err != nil {
+				return err
+			}
+			logins := []string{}
+			for _, watch := range watches {
+				if watch.Platform == StreamTwitch {
+					logins = append(logins, watch.Account)
+				}
+			}
+			var live map[string]TwitchStream
+			var twitchErr error
+			if len(logins) > 0 {
+				live, twitchErr = TwitchLive(cfg, logins)
+			}
+			for _, watch := range watches {
+				switch watch.Platform {
+				case StreamTwitch:
+					if twitchErr != nil {
+						watch.LastError = twitchErr.Error()
+					} else {
+						watch.checkTwitch(state, live)
+					}
+				case StreamYouTube:
+					watch.checkYouTube(state)
+				}
+				// It could have been removed while it was being checked, and shouldn't come back from that.
+				if exist, _, err := GetStreamWatch(kvs, guild.ID, watch.ID); err != nil || !exist {
+					continue
+				}
+				if err := watch.Store(kvs); err != nil {
+					log.Printf("[%s] Could not store stream watch %d after checking it: %s", guild.ID, watch.ID, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartCheckingStreams starts a ticker and, every streamPollInterval, calls CheckStreams with the configuration as it is then.
+// Intended to be called as a goroutine.
+func StartCheckingStreams(state *state.State, kvs KeyValueStore, cfg *Configuration) {
+	ticker := time.NewTicker(streamPollInterval)
+	for {
+		<-ticker.C
+		if err := CheckStreams(state, kvs, cfg.Snapshot()); err != nil {
+			log.Printf("Error encountered checking streams: %s", err)
+		}
+	}
+}
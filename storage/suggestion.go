@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+const (
+	SuggestionPending     = "pending"
+	SuggestionApproved    = "approved"
+	SuggestionDenied      = "denied"
+	SuggestionImplemented = "implemented"
+)
+
+// Suggestion is something a member would like to see happen, posted for everyone to vote on.
+type Suggestion struct {
+	ID        int64
+	GuildID   discord.GuildID
+	ChannelID discord.ChannelID
+	MessageID discord.MessageID
+	Author    discord.UserID
+	Text      string
+	Created   int64
+	Status    string
+	Reason    string                 // Why staff decided what they did. Optional.
+	Votes     map[discord.UserID]int // +1 or -1
+}
+
+// Store saves the suggestion struct to kvs
+func (suggestion *Suggestion) Store(kvs KeyValueStore) error {
+	return kvs.Set(suggestion.GuildID, "suggestions", suggestion.ID, suggestion)
+}
+
+// Tally counts the up and down votes.
+func (suggestion *Suggestion) Tally() (up int, down int) {
+	for _, vote := range suggestion.Votes {
+		if vote > 0 {
+			up++
+		} else if vote < 0 {
+			down++
+		}
+	}
+	return
+}
+
+// Open checks if the suggestion can still be voted on.
+func (suggestion *Suggestion) Open() bool {
+	return suggestion.Status == SuggestionPending || suggestion.Status == SuggestionApproved
+}
+
+// Embed returns the suggestion formatted as a Discord embed.
+func (suggestion *Suggestion) Embed() discord.Embed {
+	up, down := suggestion.Tally()
+	status := map[string]string{
+		SuggestionPending:     "Pending",
+		SuggestionApproved:    "Approved",
+		SuggestionDenied:      "Denied",
+		SuggestionImplemented: "Implemented",
+	}[suggestion.Status]
+	if suggestion.Reason != "" {
+		status += ": " + suggestion.Reason
+	}
+	color := map[string]discord.Color{
+		SuggestionPending:     0x5865F2,
+		SuggestionApproved:    0x57F287,
+		SuggestionDenied:      0xED4245,
+		SuggestionImplemented: 0xEB459E,
+	}[suggestion.Status]
+	return discord.Embed{
+		Type:        discord.NormalEmbed,
+		Title:       fmt.Sprintf("Suggestion #%d", suggestion.ID),
+		Description: suggestion.Text,
+		Color:       color,
+		Fields: []discord.EmbedField{
+			{Name: "Suggested by", Value: suggestion.Author.Mention(), Inline: true},
+			{Name: "Votes", Value: fmt.Sprintf("👍 %d 👎 %d", up, down), Inline: true},
+			{Name: "Status", Value: status},
+		},
+	}
+}
+
+// NewSuggestion creates a suggestion with the next free number in the guild, and stores it.
+func NewSuggestion(kvs KeyValueStore, guildID discord.GuildID, author discord.UserID, text string, created int64) (*Suggestion, error) {
+	id, err := NextNumber(kvs, guildID, "suggestions")
+	if err != nil {
+		return nil, fmt.Errorf("new suggestion could not get a number: %w", err)
+	}
+	suggestion := &Suggestion{
+		ID:      id,
+		GuildID: guildID,
+		Author:  author,
+		Text:    text,
+		Created: created,
+		Status:  SuggestionPending,
+		Votes:   map[discord.UserID]int{},
+	}
+	return suggestion, suggestion.Store(kvs)
+}
+
+// LockSuggestion locks the suggestion, so votes on it at the same time can't lose each other. Call unlock when done.
+func LockSuggestion(kvs KeyValueStore, guildID discord.GuildID, id int64) (unlock func(), err error) {
+	return LockRecord(kvs, guildID, "suggestions", id)
+}
+
+// GetSuggestion gets the suggestion with the given number, if it exists.
+func GetSuggestion(kvs KeyValueStore, guildID discord.GuildID, id int64) (exist bool, suggestion *Suggestion, err error) {
+	exist, err = kvs.Get(guildID, "suggestions", id, &suggestion)
+	if exist && suggestion.Votes == nil {
+		suggestion.Votes = map[discord.UserID]int{} // gob doesn't bother with empty maps.
+	}
+	return exist, suggestion, err
+}
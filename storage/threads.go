@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"log"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+	"github.com/diamondburned/arikawa/v3/utils/json/option"
+)
+
+// ThreadConfig is how threads are managed in a guild.
+type ThreadConfig struct {
+	AutoThread map[discord.ChannelID]bool // Channels where every message gets a thread.
+	StaleHours int64                      // Archive threads with no messages for this long. Zero to leave them alone.
+	AutoJoin   bool                       // Join every new thread, so message handlers see what's said in them.
+}
+
+// GetThreadConfig gets the thread setup for the guild, or an empty one.
+func GetThreadConfig(kvs KeyValueStore, guildID discord.GuildID) (config ThreadConfig, err error) {
+	_, err = kvs.Get(guildID, "threads", "config", &config)
+	if config.AutoThread == nil {
+		config.AutoThread = map[discord.ChannelID]bool{} // gob doesn't bother with empty maps.
+	}
+	return
+}
+
+// SetThreadConfig stores the thread setup for the guild.
+func SetThreadConfig(kvs KeyValueStore, guildID discord.GuildID, config ThreadConfig) error {
+	return kvs.Set(guildID, "threads", "config", config)
+}
+
+// threadLastActive is when the last message in the thread was sent, or when it was created if there are none.
+func threadLastActive(thread discord.Channel) time.Time {
+	if thread.LastMessageID.IsValid() {
+		return thread.LastMessageID.Time()
+	}
+	return thread.ID.Time()
+}
+
+// ArchiveStaleThreads archives the active threads in the guild that haven't seen a message in the configured number of hours.
+func ArchiveStaleThreads(state *state.State, kvs KeyValueStore, guildID discord.GuildID) {
+	config, err := GetThreadConfig(kvs, guildID)
+	if err != nil {
+		log.Printf("[%s] Archiving stale threads failed to get the config: %s", guildID, err)
+		return
+	}
+	if config.StaleHours <= 0 {
+		return
+	}
+	active, err := state.ActiveThreads(guildID)
+	if err != nil {
+		log.Printf("[%s] Archiving stale threads failed to get the active threads: %s", guildID, err)
+		return
+	}
+	staleBefore := time.Now().Add(-time.Duration(config.StaleHours) * time.Hour)
+	for _, thread := range active.Threads {
+		if thread.ThreadMetadata != nil && thread.ThreadMetadata.Archived {
+			continue
+		}
+		if threadLastActive(thread).After(staleBefore) {
+			continue
+		}
+		err := state.ModifyChannel(thread.ID, api.ModifyChannelData{
+			Archived:       option.True,
+			AuditLogReason: api.AuditLogReason("Stale thread"),
+		})
+		if err != nil {
+			log.Printf("[%s] Failed to archive stale thread <#%s>: %s", guildID, thread.ID, err)
+			continue
+		}
+		log.Printf("[%s] Archived stale thread <#%s>", guildID, thread.ID)
+	}
+}
+
+// StartArchivingStaleThreads checks for stale threads in every guild every 15 minutes.
+// Intended to be called as a goroutine.
+func StartArchivingStaleThreads(state *state.State, kvs KeyValueStore) {
+	ticker := time.NewTicker(15 * time.Minute)
+	for {
+		<-ticker.C
+		guilds, err := state.Guilds()
+		if err != nil {
+			log.Printf("Archiving stale threads could not fetch current guilds: %s", err)
+			continue
+		}
+		for _, guild := range guilds {
+			err := GuildJob(kvs, guild.ID, "stalethreads", 15*time.Minute, func() error {
+				ArchiveStaleThreads(state, kvs, guild.ID)
+				return nil
+			})
+			if err != nil {
+				log.Printf("[%s] Error encountered archiving stale threads: %s", guild.ID, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// TicketConfig is how tickets are set up in a guild.
+type TicketConfig struct {
+	LogChannelID discord.ChannelID // Where transcripts go when a ticket is closed.
+	StaffRoleID  discord.RoleID    // Who gets pulled into new tickets.
+}
+
+// Ticket is a private thread between someone and the staff.
+type Ticket struct {
+	ThreadID discord.ChannelID
+	GuildID  discord.GuildID
+	Opener   discord.UserID
+	Opened   int64
+}
+
+// GetTicketConfig gets the ticket setup for the guild, if there is one.
+func GetTicketConfig(kvs KeyValueStore, guildID discord.GuildID) (exist bool, config TicketConfig, err error) {
+	exist, err = kvs.Get(guildID, "ticketconfig", "config", &config)
+	return
+}
+
+// SetTicketConfig stores the ticket setup for the guild.
+func SetTicketConfig(kvs KeyValueStore, guildID discord.GuildID, config TicketConfig) error {
+	return kvs.Set(guildID, "ticketconfig", "config", config)
+}
+
+// Store saves the ticket to kvs
+func (ticket *Ticket) Store(kvs KeyValueStore) error {
+	return kvs.Set(ticket.GuildID, "tickets", ticket.ThreadID, ticket)
+}
+
+// Delete forgets about the ticket.
+func (ticket *Ticket) Delete(kvs KeyValueStore) error {
+	return kvs.Delete(ticket.GuildID, "tickets", ticket.ThreadID)
+}
+
+// GetTicket gets the ticket in the given thread, if there is one.
+func GetTicket(kvs KeyValueStore, guildID discord.GuildID, threadID discord.ChannelID) (exist bool, ticket Ticket, err error) {
+	exist, err = kvs.Get(guildID, "tickets", threadID, &ticket)
+	return
+}
+
+// LockTicketOpener locks opening tickets for the user, so clicking the button twice can't open two. Call unlock when done.
+func LockTicketOpener(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID) (unlock func(), err error) {
+	return LockRecord(kvs, guildID, "ticketopeners", userID)
+}
+
+// OpenTicketFor finds the ticket the given user already has open, if any.
+func OpenTicketFor(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID) (exist bool, ticket Ticket, err error) {
+	keys, err := kvs.Keys(guildID, "tickets")
+	if err != nil {
+		return false, ticket, fmt.Errorf("looking for open ticket could not get keys: %w", err)
+	}
+	for _, key := range keys {
+		candidate := Ticket{}
+		if _, err := kvs.Get(guildID, "tickets", key, &candidate); err != nil {
+			return false, ticket, fmt.Errorf("looking for open ticket could not get %s: %w", key, err)
+		}
+		if candidate.Opener == userID {
+			return true, candidate, nil
+		}
+	}
+	return false, ticket, nil
+}
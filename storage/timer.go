@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/google/uuid"
+)
+
+// Timer is something that should happen at a specific time, even if the bot restarts in the meantime.
+type Timer struct {
+	ID      string
+	GuildID discord.GuildID
+	Kind    string // What registered timer handler deals with this
+	When    int64
+	Data    map[string]string
+}
+
+// NewTimer creates a timer with a fresh ID. It is not stored until you call Store on it.
+func NewTimer(guildID discord.GuildID, kind string, when time.Time, data map[string]string) Timer {
+	return Timer{
+		ID:      uuid.New().String(),
+		GuildID: guildID,
+		Kind:    kind,
+		When:    when.Unix(),
+		Data:    data,
+	}
+}
+
+// Store saves the timer to kvs
+func (timer *Timer) Store(kvs KeyValueStore) error {
+	return kvs.Set(timer.GuildID, "timers", timer.ID, timer)
+}
+
+// Due checks if it's time for the timer to fire.
+func (timer *Timer) Due(now time.Time) bool {
+	return timer.When <= now.Unix()
+}
+
+// GetTimers returns all the timers pending for the given guild.
+func GetTimers(kvs KeyValueStore, guildID discord.GuildID) ([]Timer, error) {
+	keys, err := kvs.Keys(guildID, "timers")
+	if err != nil {
+		return nil, err
+	}
+	timers := []Timer{}
+	for _, key := range keys {
+		timer := Timer{}
+		exist, err := kvs.Get(guildID, "timers", key, &timer)
+		if err != nil {
+			return nil, err
+		}
+		if exist {
+			timers = append(timers, timer)
+		}
+	}
+	return timers, nil
+}
+
+// CancelTimer removes a timer so it never fires.
+func CancelTimer(kvs KeyValueStore, guildID discord.GuildID, id string) error {
+	return kvs.Delete(guildID, "timers", id)
+}
@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+	_ "time/tzdata" // So time zones work even where the system has no zoneinfo.
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// GetTimezone gets the time zone the guild has set, or UTC if it has none.
+func GetTimezone(kvs KeyValueStore, guildID discord.GuildID) (*time.Location, error) {
+	name := ""
+	exist, err := kvs.Get(guildID, "timezone", "name", &name)
+	if err != nil {
+		return time.UTC, fmt.Errorf("getting time zone: %w", err)
+	}
+	if !exist || name == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC, fmt.Errorf("loading stored time zone %q: %w", name, err)
+	}
+	return location, nil
+}
+
+// SetTimezone stores the time zone of the guild, by IANA name like "Europe/Oslo". Blank goes back to UTC.
+func SetTimezone(kvs KeyValueStore, guildID discord.GuildID, name string) error {
+	if name == "" {
+		return kvs.Delete(guildID, "timezone", "name")
+	}
+	if name == "Local" {
+		return fmt.Errorf("the bot's own time zone can't be used")
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("unknown time zone %q: %w", name, err)
+	}
+	return kvs.Set(guildID, "timezone", "name", name)
+}
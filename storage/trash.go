@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// TrashRetention is how long deleted things stay in the trash before they are gone for good.
+const TrashRetention = 7 * 24 * time.Hour
+
+// trashCollection is where deleted things go, keyed by collection and key.
+const trashCollection = "trash"
+
+// trashedCollections are the collections where Delete puts things in the trash instead of just deleting them.
+// Things people have made by hand go here, but not bookkeeping like timers and sessions, that nobody would want back.
+var trashedCollections = map[string]bool{
+	"faq":            true,
+	"votes":          true,
+	"votetemplates":  true,
+	"customcommands": true,
+	"macros":         true,
+	"notes":          true,
+	"presets":        true,
+	"events":         true,
+	"rolemenugroups": true,
+}
+
+// ErrTrashOccupied is returned by Restore when something else has been stored under the same key since.
+var ErrTrashOccupied = errors.New("there is something else there now")
+
+// TrashEntry is something that was deleted, as it was when it was deleted.
+type TrashEntry struct {
+	Collection string
+	Key        string
+	Deleted    int64
+	Value      []byte // Exactly as it was stored, so it can be put back without knowing what it is.
+}
+
+// Expires is when the entry is gone for good.
+func (entry TrashEntry) Expires() time.Time {
+	return time.Unix(entry.Deleted, 0).Add(TrashRetention)
+}
+
+// encodeTrashEntry makes a TrashEntry for the value, deleted just now, encoded the way the store encodes everything.
+func encodeTrashEntry(collection string, key string, value []byte) ([]byte, error) {
+	entry := TrashEntry{
+		Collection: collection,
+		Key:        key,
+		Deleted:    time.Now().Unix(),
+		Value:      append([]byte{}, value...),
+	}
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(entry); err != nil {
+		return nil, fmt.Errorf("unable to encode trash entry: %w", err)
+	}
+	return buffer.Bytes(), nil
+}
+
+func decodeTrashEntry(raw []byte) (entry TrashEntry, err error) {
+	if err = gob.NewDecoder(bytes.NewReader(raw)).Decode(&entry); err != nil {
+		err = fmt.Errorf("unable to decode trash entry: %w", err)
+	}
+	return
+}
+
+// TrashedCollections lists the collections that deleted things can be restored to, sorted.
+func TrashedCollections() []string {
+	collections := make([]string, 0, len(trashedCollections))
+	for collection := range trashedCollections {
+		collections = append(collections, collection)
+	}
+	sort.Strings(collections)
+	return collections
+}
+
+func trashKey(collection string, key string) string {
+	return collection + "/" + key
+}
+
+// GetTrash gets everything in the trash for the guild, newest first. If collection isn't blank, only what was deleted
+// from that collection.
+func GetTrash(kvs KeyValueStore, guildID discord.GuildID, collection string) ([]TrashEntry, error) {
+	keys, err := kvs.Keys(guildID, trashCollection)
+	if err != nil {
+		return nil, fmt.Errorf("could not get trash keys: %w", err)
+	}
+	entries := []TrashEntry{}
+	for _, key := range keys {
+		if collection != "" && !strings.HasPrefix(key, collection+"/") {
+			continue
+		}
+		entry := TrashEntry{}
+		exist, err := kvs.Get(guildID, trashCollection, key, &entry)
+		if err != nil {
+			return nil, fmt.Errorf("could not get trash entry %q: %w", key, err)
+		}
+		if exist {
+			entries = append(entries, entry)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Deleted > entries[j].Deleted
+	})
+	return entries, nil
+}
+
+// PruneTrash deletes everything in the guild's trash that is older than TrashRetention.
+func PruneTrash(kvs KeyValueStore, guildID discord.GuildID) error {
+	entries, err := GetTrash(kvs, guildID, "")
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.Expires().After(now) {
+			continue
+		}
+		if err := kvs.Delete(guildID, trashCollection, trashKey(entry.Collection, entry.Key)); err != nil {
+			return fmt.Errorf("could not delete trash entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// PruneAllTrash calls PruneTrash for every guild.
+func PruneAllTrash(state *state.State, kvs KeyValueStore) error {
+	guilds, err := state.Guilds()
+	if err != nil {
+		return fmt.Errorf("pruning trash could not fetch current guilds: %w", err)
+	}
+	for _, guild := range guilds {
+		err := GuildJob(kvs, guild.ID, "trash", time.Hour, func() error { return PruneTrash(kvs, guild.ID) })
+		if err != nil {
+			return fmt.Errorf("pruning trash for %s: %w", guild.ID, err)
+		}
+	}
+	return nil
+}
+
+// StartPruningTrash starts a ticker and, once an hour, calls PruneAllTrash.
+// Intended to be called as a goroutine.
+func StartPruningTrash(state *state.State, kvs KeyValueStore) {
+	ticker := time.NewTicker(1 * time.Hour)
+	for {
+		<-ticker.C
+		if err := PruneAllTrash(state, kvs); err != nil {
+			log.Printf("Error encountered pruning trash: %s", err)
+		}
+	}
+}
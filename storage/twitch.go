@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// twitchBatch is how many channels Twitch lets us ask about at once.
+const twitchBatch = 100
+
+// TwitchStream is a live stream, as Twitch describes it.
+type TwitchStream struct {
+	UserLogin string    `json:"user_login"`
+	UserName  string    `json:"user_name"`
+	GameName  string    `json:"game_name"`
+	Title     string    `json:"title"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// ErrTwitchNotConfigured is returned when the bot has no Twitch credentials in its configuration.
+var ErrTwitchNotConfigured = errors.New("twitch is not set up for this bot")
+
+// twitchToken is the app access token, shared by everything asking Twitch about streams.
+var twitchToken struct {
+	sync.Mutex
+	clientID string
+	token    string
+	expires  time.Time
+}
+
+var twitchClient = &http.Client{Timeout: 15 * time.Second}
+
+// twitchAccessToken gets an app access token from Twitch, or reuses the one we have if it's still good.
+func twitchAccessToken(clientID, secret string) (string, error) {
+	twitchToken.Lock()
+	defer twitchToken.Unlock()
+	if twitchToken.token != "" && twitchToken.clientID == clientID && time.Now().Before(twitchToken.expires) {
+		return twitchToken.token, nil
+	}
+	form := url.Values{
+		"client_id":     {clientID},
+		"client_secret": {secret},
+		"grant_type":    {"client_credentials"},
+	}
+	resp, err := twitchClient.PostForm("https://id.twitch.tv/oauth2/token", form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("twitch turned down the credentials: %s", resp.Status)
+	}
+	body := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	twitchToken.clientID = clientID
+	twitchToken.token = body.AccessToken
+	// A minute early, so it doesn't run out halfway through a round of checks.
+	twitchToken.expires = time.Now().Add(time.Duration(body.ExpiresIn)*time.Second - time.Minute)
+	return twitchToken.token, nil
+}
+
+// forgetTwitchToken drops the app access token, so a fresh one is fetched next time.
+func forgetTwitchToken() {
+	twitchToken.Lock()
+	twitchToken.token = ""
+	twitchToken.Unlock()
+}
+
+// TwitchLive asks Twitch which of the given channels are live, and returns their streams keyed by lowercase login.
+func TwitchLive(cfg Configuration, logins []string) (map[string]TwitchStream, error) {
+	if cfg.TwitchClientID == "" || cfg.TwitchClientSecret == "" {
+		return nil, ErrTwitchNotConfigured
+	}
+	live := map[string]TwitchStream{}
+	for start := 0; start < len(logins); start += twitchBatch {
+		end := start + twitchBatch
+		if end > len(logins) {
+			end = len(logins)
+		}
+		if err := twitchLiveBatch(cfg, logins[start:end], live); err != nil {
+			return nil, err
+		}
+	}
+	return live, nil
+}
+
+func twitchLiveBatch(cfg Configuration, logins []string, live map[string]TwitchStream) error {
+	token, err := twitchAccessToken(cfg.TwitchClientID, cfg.TwitchClientSecret)
+	if err != nil {
+		return err
+	}
+	query := url.Values{"first": {fmt.Sprint(twitchBatch)}}
+	for _, login := range logins {
+		query.Add("user_login", login)
+	}
+	request, err := http.NewRequest(http.MethodGet, "https://api.twitch.tv/helix/streams?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Client-Id", cfg.TwitchClientID)
+	request.Header.Set("Authorization", "Bearer "+token)
+	resp, err := twitchClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		forgetTwitchToken()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("twitch answered %s", resp.Status)
+	}
+	body := struct {
+		Data []TwitchStream `json:"data"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return err
+	}
+	for _, stream := range body.Data {
+		live[strings.ToLower(stream.UserLogin)] = stream
+	}
+	return nil
+}
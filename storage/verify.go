@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// VerifyConfig is how new members prove they are people before they get in.
+type VerifyConfig struct {
+	RoleID    discord.RoleID    // Given once verified.
+	ChannelID discord.ChannelID // Where the Verify button is.
+	MessageID discord.MessageID // The message with the Verify button.
+	Question  string            // Blank means a random sum is asked instead.
+	Answer    string
+}
+
+// GetVerifyConfig gets the verification setup for the guild.
+func GetVerifyConfig(kvs KeyValueStore, guildID discord.GuildID) (exist bool, config VerifyConfig, err error) {
+	exist, err = kvs.Get(guildID, "verify", "config", &config)
+	return
+}
+
+// SetVerifyConfig stores the verification setup for the guild.
+func SetVerifyConfig(kvs KeyValueStore, guildID discord.GuildID, config VerifyConfig) error {
+	return kvs.Set(guildID, "verify", "config", config)
+}
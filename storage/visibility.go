@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// The visibilities a guild can pick for the responses to a command, instead of whatever the command does by default.
+const (
+	VisibilityPublic    = "public"
+	VisibilityEphemeral = "ephemeral"
+)
+
+// SetVisibility stores who should see the responses to the named command. Blank goes back to the default.
+func SetVisibility(kvs KeyValueStore, guildID discord.GuildID, name string, visibility string) error {
+	switch visibility {
+	case "":
+		return kvs.Delete(guildID, "visibility", name)
+	case VisibilityPublic, VisibilityEphemeral:
+		return kvs.Set(guildID, "visibility", name, visibility)
+	}
+	return fmt.Errorf("unknown visibility %q", visibility)
+}
+
+// GetVisibility gets who should see the responses to the named command, or blank if the guild hasn't said.
+func GetVisibility(kvs KeyValueStore, guildID discord.GuildID, name string) (string, error) {
+	visibility := ""
+	if _, err := kvs.Get(guildID, "visibility", name, &visibility); err != nil {
+		return "", fmt.Errorf("getting visibility for %s: %w", name, err)
+	}
+	return visibility, nil
+}
+
+// AllVisibilities gets every visibility the guild has picked, keyed by command name.
+func AllVisibilities(kvs KeyValueStore, guildID discord.GuildID) (map[string]string, error) {
+	keys, err := kvs.Keys(guildID, "visibility")
+	if err != nil {
+		return nil, fmt.Errorf("getting visibility keys: %w", err)
+	}
+	visibilities := map[string]string{}
+	for _, key := range keys {
+		visibility, err := GetVisibility(kvs, guildID, key)
+		if err != nil {
+			return nil, err
+		}
+		visibilities[key] = visibility
+	}
+	return visibilities, nil
+}
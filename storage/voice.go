@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// voiceCheckpointInterval is how often the time of everyone in voice is added up, so a crash loses at most this much.
+const voiceCheckpointInterval = 5 * time.Minute
+
+// VoiceSession is someone being in voice right now, as far as we know.
+type VoiceSession struct {
+	ChannelID discord.ChannelID
+	Since     int64 // When their time was last added up.
+}
+
+// VoiceTime is how much time someone has spent in voice in the guild.
+type VoiceTime struct {
+	UserID   discord.UserID
+	Seconds  int64
+	Sessions int   // How many times they've joined.
+	Last     int64 // When they were last in voice.
+}
+
+// voiceLock keeps joins, leaves and checkpoints from adding up the same time twice.
+var voiceLock sync.Mutex
+
+// GetVoiceTime gets the voice time of the user. Someone who has never been in voice has none.
+func GetVoiceTime(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID) (VoiceTime, error) {
+	voiceTime := VoiceTime{UserID: userID}
+	_, err := kvs.Get(guildID, "voicetime", userID, &voiceTime)
+	return voiceTime, err
+}
+
+// VoiceLeaderboard gets everyone who has been in voice in the guild, most time first.
+func VoiceLeaderboard(kvs KeyValueStore, guildID discord.GuildID) ([]VoiceTime, error) {
+	keys, err := kvs.Keys(guildID, "voicetime")
+	if err != nil {
+		return nil, fmt.Errorf("voice leaderboard could not get keys: %w", err)
+	}
+	board := make([]VoiceTime, 0, len(keys))
+	for _, key := range keys {
+		voiceTime := VoiceTime{}
+		exist, err := kvs.Get(guildID, "voicetime", key, &voiceTime)
+		if err != nil {
+			return nil, fmt.Errorf("voice leaderboard could not get %s: %w", key, err)
+		}
+		if exist {
+			board = append(board, voiceTime)
+		}
+	}
+	sort.SliceStable(board, func(i, j int) bool {
+		return board[i].Seconds > board[j].Seconds
+	})
+	return board, nil
+}
+
+// addVoiceTime adds the time since the session was last added up to the user's voice time.
+// A session that hasn't been added up for a good while is from before the bot went down, and that time isn't counted,
+// as there's no telling when they left.
+func addVoiceTime(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID, session VoiceSession, now int64, joined bool) error {
+	voiceTime, err := GetVoiceTime(kvs, guildID, userID)
+	if err != nil {
+		return err
+	}
+	if elapsed := now - session.Since; elapsed > 0 && elapsed <= int64(2*voiceCheckpointInterval/time.Second) {
+		voiceTime.Seconds += elapsed
+	}
+	if joined {
+		voiceTime.Sessions++
+	}
+	voiceTime.Last = now
+	return kvs.Set(guildID, "voicetime", userID, voiceTime)
+}
+
+// JoinVoice starts counting voice time for the user, or carries on counting if they just moved to another channel.
+func JoinVoice(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID, channelID discord.ChannelID) error {
+	voiceLock.Lock()
+	defer voiceLock.Unlock()
+	now := time.Now().Unix()
+	session := VoiceSession{ChannelID: channelID, Since: now}
+	exist, err := kvs.Get(guildID, "voicesessions", userID, &session)
+	if err != nil {
+		return err
+	}
+	if err := addVoiceTime(kvs, guildID, userID, session, now, !exist); err != nil {
+		return err
+	}
+	session.ChannelID = channelID
+	session.Since = now
+	return kvs.Set(guildID, "voicesessions", userID, session)
+}
+
+// LeaveVoice stops counting voice time for the user, adding up what they have so far.
+func LeaveVoice(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID) error {
+	voiceLock.Lock()
+	defer voiceLock.Unlock()
+	session := VoiceSession{}
+	exist, err := kvs.Get(guildID, "voicesessions", userID, &session)
+	if err != nil || !exist {
+		return err
+	}
+	if err := addVoiceTime(kvs, guildID, userID, session, time.Now().Unix(), false); err != nil {
+		return err
+	}
+	return kvs.Delete(guildID, "voicesessions", userID)
+}
+
+// CountsAsVoice checks if being in the voice channel counts, which it does anywhere but the AFK channel.
+func CountsAsVoice(guild discord.Guild, voiceState discord.VoiceState) bool {
+	if !voiceState.ChannelID.IsValid() || voiceState.ChannelID == guild.AFKChannelID {
+		return false
+	}
+	return voiceState.Member == nil || !voiceState.Member.User.Bot
+}
+
+// CheckpointVoice adds up the voice time of everyone in voice in all the guilds, and marks them as seen.
+// It also catches up on whatever was missed while the bot was down: those who left stop being counted,
+// and those who joined start.
+func CheckpointVoice(state *state.State, kvs KeyValueStore) error {
+	guilds, err := state.Guilds()
+	if err != nil {
+		return fmt.Errorf("checkpointing voice could not fetch current guilds: %w", err)
+	}
+	for _, guild := range guilds {
+		err := GuildJob(kvs, guild.ID, "voice", voiceCheckpointInterval, func() error {
+			voiceStates, err := state.VoiceStates(guild.ID)
+			if err != nil {
+				log.Printf("[%s] Checkpointing voice could not get the voice states: %s", guild.ID, err)
+				return nil
+			}
+			inVoice := map[discord.UserID]discord.ChannelID{}
+			for _, voiceState := range voiceStates {
+				if CountsAsVoice(guild, voiceState) {
+					inVoice[voiceState.UserID] = voiceState.ChannelID
+				}
+			}
+			keys, err := kvs.Keys(guild.ID, "voicesessions")
+			if err != nil {
+				log.Printf("[%s] Checkpointing voice could not get the sessions: %s", guild.ID, err)
+				return nil
+			}
+			for _, key := range keys {
+				snowflake, err := discord.ParseSnowflake(key)
+				if err != nil {
+					continue
+				}
+				userID := discord.UserID(snowflake)
+				if _, ok := inVoice[userID]; ok {
+					continue
+				}
+				if err := LeaveVoice(kvs, guild.ID, userID); err != nil {
+					log.Printf("[%s] Checkpointing voice could not end the session of <@%s>: %s", guild.ID, userID, err)
+				}
+			}
+			for userID, channelID := range inVoice {
+				if err := JoinVoice(kvs, guild.ID, userID, channelID); err != nil {
+					log.Printf("[%s] Checkpointing voice could not add up the time of <@%s>: %s", guild.ID, userID, err)
+					continue
+				}
+				if err := See(kvs, guild.ID, userID); err != nil {
+					log.Printf("[%s] Checkpointing voice could not see <@%s>: %s", guild.ID, userID, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartCheckpointingVoice starts a ticker and, every voiceCheckpointInterval, calls CheckpointVoice.
+// Intended to be called as a goroutine.
+func StartCheckpointingVoice(state *state.State, kvs KeyValueStore) {
+	ticker := time.NewTicker(voiceCheckpointInterval)
+	for {
+		<-ticker.C
+		if err := CheckpointVoice(state, kvs); err != nil {
+			log.Printf("Error encountered checkpointing voice: %s", err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/api"
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// voiceLobbyGrace is how long a new lobby is left alone, even if it's empty, so the owner has time to be moved in.
+const voiceLobbyGrace = 30 * time.Second
+
+// VoiceLobbyPlaceholders are the extra placeholders a lobby name can have, on top of the usual ones.
+var VoiceLobbyPlaceholders = []string{"number"}
+
+// VoiceLobbyConfig is how voice lobbies are set up in a guild.
+type VoiceLobbyConfig struct {
+	ChannelID  discord.ChannelID // Joining this voice channel makes a lobby.
+	CategoryID discord.ChannelID // Where the lobbies go.
+	Name       string            // The template lobby names are made from.
+	UserLimit  int               // How many people fit in a lobby. Zero is no limit.
+}
+
+// VoiceLobby is a temporary voice channel someone made by joining the lobby channel.
+type VoiceLobby struct {
+	ChannelID discord.ChannelID
+	OwnerID   discord.UserID
+	Created   int64
+}
+
+// GetVoiceLobbyConfig gets the voice lobby setup for the guild, if there is one.
+func GetVoiceLobbyConfig(kvs KeyValueStore, guildID discord.GuildID) (exist bool, config VoiceLobbyConfig, err error) {
+	exist, err = kvs.Get(guildID, "voicelobby", "config", &config)
+	return
+}
+
+// SetVoiceLobbyConfig stores the voice lobby setup for the guild.
+func SetVoiceLobbyConfig(kvs KeyValueStore, guildID discord.GuildID, config VoiceLobbyConfig) error {
+	return kvs.Set(guildID, "voicelobby", "config", config)
+}
+
+// DisableVoiceLobbies forgets the voice lobby setup for the guild. Lobbies already made are still cleaned up when they empty.
+func DisableVoiceLobbies(kvs KeyValueStore, guildID discord.GuildID) error {
+	return kvs.Delete(guildID, "voicelobby", "config")
+}
+
+// AddVoiceLobby remembers the lobby, so it can be deleted when it empties.
+func AddVoiceLobby(kvs KeyValueStore, guildID discord.GuildID, lobby VoiceLobby) error {
+	return kvs.Set(guildID, "voicelobbies", lobby.ChannelID, lobby)
+}
+
+// ForgetVoiceLobby forgets the lobby.
+func ForgetVoiceLobby(kvs KeyValueStore, guildID discord.GuildID, channelID discord.ChannelID) error {
+	return kvs.Delete(guildID, "voicelobbies", channelID)
+}
+
+// GetVoiceLobbies gets all the lobbies of the guild.
+func GetVoiceLobbies(kvs KeyValueStore, guildID discord.GuildID) ([]VoiceLobby, error) {
+	keys, err := kvs.Keys(guildID, "voicelobbies")
+	if err != nil {
+		return nil, fmt.Errorf("getting voice lobbies could not get keys: %w", err)
+	}
+	lobbies := []VoiceLobby{}
+	for _, key := range keys {
+		lobby := VoiceLobby{}
+		exist, err := kvs.Get(guildID, "voicelobbies", key, &lobby)
+		if err != nil {
+			return nil, fmt.Errorf("getting voice lobbies could not get %s: %w", key, err)
+		}
+		if exist {
+			lobbies = append(lobbies, lobby)
+		}
+	}
+	return lobbies, nil
+}
+
+// CleanVoiceLobbies deletes the lobbies of the guild that nobody is in anymore, and forgets the ones already gone.
+func CleanVoiceLobbies(state *state.State, kvs KeyValueStore, guildID discord.GuildID) error {
+	lobbies, err := GetVoiceLobbies(kvs, guildID)
+	if err != nil || len(lobbies) == 0 {
+		return err
+	}
+	voiceStates, err := state.VoiceStates(guildID)
+	if err != nil {
+		return fmt.Errorf("cleaning voice lobbies could not get the voice states: %w", err)
+	}
+	occupied := map[discord.ChannelID]bool{}
+	for _, voiceState := range voiceStates {
+		occupied[voiceState.ChannelID] = true
+	}
+	now := time.Now()
+	for _, lobby := range lobbies {
+		if occupied[lobby.ChannelID] || now.Sub(time.Unix(lobby.Created, 0)) < voiceLobbyGrace {
+			continue
+		}
+		if _, err := state.Channel(lobby.ChannelID); err == nil {
+			if err := state.DeleteChannel(lobby.ChannelID, api.AuditLogReason("Voice lobby is empty")); err != nil {
+				log.Printf("[%s] Could not delete empty voice lobby <#%s>: %s", guildID, lobby.ChannelID, err)
+				continue
+			}
+		}
+		if err := ForgetVoiceLobby(kvs, guildID, lobby.ChannelID); err != nil {
+			log.Printf("[%s] Could not forget voice lobby <#%s>: %s", guildID, lobby.ChannelID, err)
+		}
+	}
+	return nil
+}
+
+// StartCleaningVoiceLobbies starts a ticker and, every ten minutes, cleans up the voice lobbies of all the guilds.
+// Lobbies are mostly cleaned up as people leave them, so this is for the ones emptied while the bot was down.
+// Intended to be called as a goroutine.
+func StartCleaningVoiceLobbies(state *state.State, kvs KeyValueStore) {
+	ticker := time.NewTicker(10 * time.Minute)
+	for {
+		<-ticker.C
+		guilds, err := state.Guilds()
+		if err != nil {
+			log.Printf("Cleaning voice lobbies could not fetch current guilds: %s", err)
+			continue
+		}
+		for _, guild := range guilds {
+			err := GuildJob(kvs, guild.ID, "voicelobbies", 10*time.Minute, func() error { return CleanVoiceLobbies(state, kvs, guild.ID) })
+			if err != nil {
+				log.Printf("[%s] Error encountered cleaning voice lobbies: %s", guild.ID, err)
+			}
+		}
+	}
+}
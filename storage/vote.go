@@ -1,9 +1,14 @@
 package storage
 
 import (
+	"bytes"
+	"encoding/csv"
 	"fmt"
+	"komainu/utility"
 	"log"
+	"math"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -24,6 +29,95 @@ type Vote struct {
 	Order     []string
 	Options   map[string]string
 	Votes     map[discord.UserID]string
+	Outcome   *VoteOutcome // Something that happens automatically if the vote passes. Optional.
+	Closed    bool         // Closed votes are kept around for a while, until ReapClosedVotes gets to them.
+	Creator   discord.UserID
+	Cancelled bool // Cancelled votes are closed early, without results.
+
+	QuorumCount   int     // How many votes it takes for the vote to count. Zero means no minimum.
+	QuorumPercent float64 // How many of the Eligible it takes for the vote to count, in percent. Zero means no minimum.
+	Eligible      int     // How many members could vote when it started. Only counted if QuorumPercent is set.
+
+	Abstain  bool                      // Voters can abstain, which counts towards quorum but not for any option.
+	Comments map[discord.UserID]string // Only ever shown to the creator, and never with who wrote them.
+	Buttons  bool                      // Voted on with a button per option, rather than picked from a menu.
+}
+
+// VoteAbstain is what's stored in Votes for someone that abstained.
+const VoteAbstain = "abstain"
+
+// VoteMaxButtons is how many options a vote voted on with buttons can have, as that's how many fit in a row.
+const VoteMaxButtons = 5
+
+// VoteMaxButtonLabel is how long, in characters, an option of a vote voted on with buttons can be, as that's as long as
+// Discord lets the label of a button be. Options in the menu can be longer.
+const VoteMaxButtonLabel = 80
+
+// Label returns the label of the given option key, if it is something that can be voted for.
+func (vote *Vote) Label(key string) (label string, ok bool) {
+	if key == VoteAbstain {
+		return "Abstain", vote.Abstain
+	}
+	label, ok = vote.Options[key]
+	return label, ok
+}
+
+// QuorumNeeded returns how many votes it takes for the vote to count. If both a count and a percentage are set, the larger wins.
+func (vote *Vote) QuorumNeeded() int {
+	needed := vote.QuorumCount
+	if byPercent := int(math.Ceil(vote.QuorumPercent * float64(vote.Eligible) / 100)); byPercent > needed {
+		needed = byPercent
+	}
+	return needed
+}
+
+// QuorumMet checks if enough votes have been cast for the vote to count.
+func (vote *Vote) QuorumMet() bool {
+	return len(vote.Votes) >= vote.QuorumNeeded()
+}
+
+// describeQuorum explains the minimum number of votes, if there is one.
+func (vote *Vote) describeQuorum(closed bool) string {
+	needed := vote.QuorumNeeded()
+	if needed == 0 {
+		return ""
+	}
+	how := fmt.Sprintf("%d votes", needed)
+	if vote.QuorumPercent > 0 && needed > vote.QuorumCount {
+		how = fmt.Sprintf("%d votes, %g%% of %d members", needed, vote.QuorumPercent, vote.Eligible)
+	}
+	if !closed {
+		return fmt.Sprintf("It takes %s for this vote to count.", how)
+	}
+	if vote.QuorumMet() {
+		return fmt.Sprintf("Quorum met: %d votes were cast, and it took %s.", len(vote.Votes), how)
+	}
+	return fmt.Sprintf("**This vote is invalid.** Only %d votes were cast, but it took %s.", len(vote.Votes), how)
+}
+
+const (
+	OutcomeUnlock = "unlock"
+	OutcomeRevert = "revert"
+)
+
+// VoteOutcome describes something the bot does when a vote passes.
+type VoteOutcome struct {
+	Action    string // OutcomeUnlock or OutcomeRevert
+	Pass      string // The option key that has to win for the vote to pass.
+	ChannelID discord.ChannelID
+	RoleID    discord.RoleID
+	UnlockID  discord.MessageID // What unlock to revert, for OutcomeRevert
+}
+
+// Describe returns a human readable description of what the outcome will be, suitable for a Discord message.
+func (outcome *VoteOutcome) Describe(vote *Vote) string {
+	switch outcome.Action {
+	case OutcomeUnlock:
+		return fmt.Sprintf("If **%s** wins, %s will be opened for %s.", vote.Options[outcome.Pass], outcome.ChannelID.Mention(), outcome.RoleID.Mention())
+	case OutcomeRevert:
+		return fmt.Sprintf("If **%s** wins, the channel unlock %s will be reverted.", vote.Options[outcome.Pass], outcome.UnlockID)
+	}
+	return ""
 }
 
 // Store saves the vote struct to kvs
@@ -31,6 +125,18 @@ func (vote *Vote) Store(kvs KeyValueStore) error {
 	return kvs.Set(vote.GuildID, "votes", vote.MessageID, vote)
 }
 
+// SetComment sets the comment of the user, or removes it if it's blank.
+func (vote *Vote) SetComment(userID discord.UserID, comment string) {
+	if comment == "" {
+		delete(vote.Comments, userID)
+		return
+	}
+	if vote.Comments == nil {
+		vote.Comments = map[discord.UserID]string{}
+	}
+	vote.Comments[userID] = comment
+}
+
 // Tally returns the current vote tally along with a slice containing the different vote options for easy sorting.
 func (vote *Vote) Tally() (tally map[string]int, keys []string) {
 	tally = map[string]int{}
@@ -39,9 +145,13 @@ func (vote *Vote) Tally() (tally map[string]int, keys []string) {
 		tally[label] = 0
 		keys = append(keys, label)
 	}
+	if label, ok := vote.Label(VoteAbstain); ok {
+		tally[label] = 0
+		keys = append(keys, label)
+	}
 	for _, opt := range vote.Votes {
 		optionLabel := "!!UNKNOWN OPTION!!"
-		if label, ok := vote.Options[opt]; ok {
+		if label, ok := vote.Label(opt); ok {
 			optionLabel = label
 		}
 		if _, ok := tally[optionLabel]; ok {
@@ -53,20 +163,48 @@ func (vote *Vote) Tally() (tally map[string]int, keys []string) {
 	return tally, keys
 }
 
+// Winner returns the key of the option with the most votes, if there is a single one that has more votes than all the others.
+// Abstaining doesn't count for anything. An invalid vote, one that didn't meet quorum, has no winner.
+func (vote *Vote) Winner() (key string, ok bool) {
+	if !vote.QuorumMet() {
+		return "", false
+	}
+	counts := map[string]int{}
+	for _, opt := range vote.Votes {
+		counts[opt]++
+	}
+	best := 0
+	for _, candidate := range vote.Order {
+		count := counts[candidate]
+		if count > best {
+			best = count
+			key = candidate
+			ok = true
+		} else if count == best {
+			ok = false // A tie. Nobody wins.
+		}
+	}
+	return key, ok && best > 0
+}
+
 // String returns the vote as a string, which means formatting it as suitable as a Discord message.
 func (vote *Vote) String() (voteText string) {
+	if vote.Cancelled {
+		return fmt.Sprintf("%s\n\nThis vote was cancelled %s.\n", vote.Question, utility.Timestamp(vote.EndTime, utility.Relative))
+	}
 	var sb strings.Builder
 	now := time.Now().Unix()
 	tally, keys := vote.Tally()
 
-	if vote.EndTime <= now {
-		fmt.Fprintf(&sb, "%s\n\nVoting closed <t:%d:R>.\n\n", vote.Question, vote.EndTime)
+	closed := vote.EndTime <= now
+	if closed {
+		fmt.Fprintf(&sb, "%s\n\nVoting closed %s.\n\n", vote.Question, utility.Timestamp(vote.EndTime, utility.Relative))
 		// If voting has ended, we rank them by score.
 		sort.SliceStable(keys, func(i int, j int) bool {
 			return tally[keys[i]] > tally[keys[j]]
 		})
 	} else {
-		fmt.Fprintf(&sb, "%s\n\nCloses <t:%d:R>.\n\n", vote.Question, vote.EndTime)
+		fmt.Fprintf(&sb, "%s\n\nCloses %s.\n\n", vote.Question, utility.Timestamp(vote.EndTime, utility.Relative))
 	}
 
 	for _, opt := range keys {
@@ -77,15 +215,135 @@ func (vote *Vote) String() (voteText string) {
 		}
 		fmt.Fprintf(&sb, "**%s** (%d vote%s)\n", opt, count, plural)
 	}
+	if quorum := vote.describeQuorum(closed); quorum != "" {
+		fmt.Fprintf(&sb, "\n%s\n", quorum)
+	}
+	if vote.Outcome != nil {
+		fmt.Fprintf(&sb, "\n%s\n", vote.Outcome.Describe(vote))
+	}
 	return sb.String()
 }
 
+// VoteExportID is the CustomID of the button closed votes get, so the one that started it can export the results.
+const VoteExportID = "voteexport"
+
+// CSV returns the results of the vote as CSV, with a header row and then a row per option.
+func (vote *Vote) CSV() ([]byte, error) {
+	tally, keys := vote.Tally()
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write([]string{"option", "votes"}); err != nil {
+		return nil, fmt.Errorf("writing CSV header: %w", err)
+	}
+	for _, key := range keys {
+		if err := w.Write([]string{key, strconv.Itoa(tally[key])}); err != nil {
+			return nil, fmt.Errorf("writing CSV row: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("flushing CSV: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
 // GetVote gets a specific vote for the given guild and message. Returns a boolean to let you know if the vote exists, that Vote object if it does and any error that occured fetching it.
 func GetVote(kvs KeyValueStore, guildID discord.GuildID, messageID discord.MessageID) (exist bool, vote *Vote, err error) {
 	exist, err = kvs.Get(guildID, "votes", messageID, &vote)
 	return exist, vote, err
 }
 
+// LockVote locks the vote, so votes cast on it at the same time can't lose each other. Call unlock when done.
+func LockVote(kvs KeyValueStore, guildID discord.GuildID, messageID discord.MessageID) (unlock func(), err error) {
+	return LockRecord(kvs, guildID, "votes", messageID)
+}
+
+// GetOpenVotes gets all the votes in the guild that are still open, the ones closing first at the top.
+func GetOpenVotes(kvs KeyValueStore, guildID discord.GuildID) ([]Vote, error) {
+	keys, err := kvs.Keys(guildID, "votes")
+	if err != nil {
+		return nil, fmt.Errorf("open votes could not get keys: %w", err)
+	}
+	votes := []Vote{}
+	for _, key := range keys {
+		vote := Vote{}
+		exist, err := kvs.Get(guildID, "votes", key, &vote)
+		if err != nil {
+			return nil, fmt.Errorf("open votes could not get %s: %w", key, err)
+		}
+		if exist && !vote.Closed {
+			votes = append(votes, vote)
+		}
+	}
+	sort.Slice(votes, func(i, j int) bool {
+		return votes[i].EndTime < votes[j].EndTime
+	})
+	return votes, nil
+}
+
+// Cancel closes the vote right away, without results or outcome, and updates the vote message to say so.
+func (vote *Vote) Cancel(state *state.State, kvs KeyValueStore) error {
+	vote.EndTime = time.Now().Unix()
+	vote.Cancelled = true
+	vote.Closed = true
+	_, err := state.EditMessageComplex(vote.ChannelID, vote.MessageID, api.EditMessageData{
+		Content:    option.NewNullableString(vote.String()),
+		Components: &discord.ContainerComponents{},
+	})
+	if err != nil {
+		return fmt.Errorf("cancelling vote could not update vote message: %w", err)
+	}
+	if err := vote.Store(kvs); err != nil {
+		return fmt.Errorf("cancelling vote could not store it: %w", err)
+	}
+	vote.publishClosed()
+	return nil
+}
+
+// applyOutcome does whatever the outcome says, if the vote passed, and tells the channel about it.
+func (vote *Vote) applyOutcome(state *state.State, kvs KeyValueStore) {
+	winner, ok := vote.Winner()
+	if !ok || winner != vote.Outcome.Pass {
+		return
+	}
+	var result string
+	switch vote.Outcome.Action {
+	case OutcomeUnlock:
+		if err := UnlockChannel(state, kvs, vote.GuildID, vote.MessageID, vote.Outcome.ChannelID, vote.Outcome.RoleID); err != nil {
+			log.Printf("[%s] Vote passed, but unlocking the channel failed: %s", vote.GuildID, err)
+			result = "The vote passed, but I couldn't unlock the channel. The error has been logged."
+			break
+		}
+		result = fmt.Sprintf(
+			"The vote passed, so %s is now open for %s. To undo this, use `/vote revert unlock:%s` or start a vote with `revert_unlock:%s`",
+			vote.Outcome.ChannelID.Mention(), vote.Outcome.RoleID.Mention(), vote.MessageID, vote.MessageID,
+		)
+	case OutcomeRevert:
+		exist, unlock, err := RevertChannelUnlock(state, kvs, vote.GuildID, vote.Outcome.UnlockID)
+		if err != nil {
+			log.Printf("[%s] Vote passed, but reverting the channel unlock failed: %s", vote.GuildID, err)
+			result = "The vote passed, but I couldn't revert the channel unlock. The error has been logged."
+		} else if !exist {
+			result = "The vote passed, but that channel unlock was already reverted."
+		} else {
+			result = fmt.Sprintf("The vote passed, so %s is back the way it was for %s.", unlock.ChannelID.Mention(), unlock.RoleID.Mention())
+		}
+	default:
+		log.Printf("[%s] Vote has an unknown outcome action %q", vote.GuildID, vote.Outcome.Action)
+		return
+	}
+	_, err := state.SendMessageComplex(vote.ChannelID, api.SendMessageData{
+		Content:   result,
+		Reference: &discord.MessageReference{MessageID: vote.MessageID},
+		AllowedMentions: &api.AllowedMentions{
+			Parse: []api.AllowedMentionType{},
+		},
+	})
+	if err != nil {
+		log.Printf("[%s] Failed to announce vote outcome: %s", vote.GuildID, err)
+	}
+}
+
 // CloseExpiredVotes iterates over all the known votes in the connected guilds, and closes the ended ones.
 func CloseExpiredVotes(state *state.State, kvs KeyValueStore) error {
 	guilds, err := state.Guilds()
@@ -94,41 +352,74 @@ func CloseExpiredVotes(state *state.State, kvs KeyValueStore) error {
 	}
 	now := time.Now().Unix()
 	for _, guild := range guilds {
-		keys, err := kvs.Keys(guild.ID, "votes")
-		if err != nil {
-			return fmt.Errorf("closing expired votes could not get keys for guild: %w", err)
-		}
-		for _, key := range keys {
-			vote := Vote{}
-			exist, err := kvs.Get(guild.ID, "votes", key, &vote)
+		err := GuildJob(kvs, guild.ID, "closevotes", time.Minute, func() error {
+			keys, err := kvs.Keys(guild.ID, "votes")
 			if err != nil {
-				return fmt.Errorf("closing expired votes could not obtain vote object: %w", err)
+				return fmt.Errorf("closing expired votes could not get keys for guild: %w", err)
 			}
-			if exist {
-				if vote.ChannelID == discord.NullChannelID || vote.ChannelID == 0 {
-					log.Printf("[%s] Closing expired votes encountered vote with no channel ID -- PURGING", guild.ID)
-					err := kvs.Delete(guild.ID, "votes", key)
-					if err != nil {
-						log.Printf("[%s] Error purging invalid vote: %s\n", guild.ID, err)
-					}
-					continue
-				}
-				if vote.EndTime <= now {
-					_, err := state.EditMessageComplex(vote.ChannelID, vote.MessageID, api.EditMessageData{
-						Content:    option.NewNullableString(vote.String()),
-						Components: &discord.ContainerComponents{},
-					})
-					if err != nil {
-						return fmt.Errorf("closing expired votes could not update vote message: %w", err)
-					}
-					err = kvs.Delete(guild.ID, "votes", key)
-					if err != nil {
-						return fmt.Errorf("encoutered an error removing expired vote: %w", err)
-					}
+			for _, key := range keys {
+				if err := closeExpiredVote(state, kvs, guild.ID, key, now); err != nil {
+					return err
 				}
 			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeExpiredVote closes the vote with the key if it has ended, with the vote locked so no vote cast on it is lost.
+func closeExpiredVote(state *state.State, kvs KeyValueStore, guildID discord.GuildID, key string, now int64) error {
+	unlock, err := LockRecord(kvs, guildID, "votes", key)
+	if err != nil {
+		log.Printf("[%s] Closing expired votes skipped vote %s for now, as it's busy: %s", guildID, key, err)
+		return nil
+	}
+	defer unlock()
+	vote := Vote{}
+	exist, err := kvs.Get(guildID, "votes", key, &vote)
+	if err != nil {
+		return fmt.Errorf("closing expired votes could not obtain vote object: %w", err)
+	}
+	if !exist {
+		return nil
+	}
+	if vote.ChannelID == discord.NullChannelID || vote.ChannelID == 0 {
+		log.Printf("[%s] Closing expired votes encountered vote with no channel ID -- PURGING", guildID)
+		if err := kvs.Delete(guildID, "votes", key); err != nil {
+			log.Printf("[%s] Error purging invalid vote: %s\n", guildID, err)
 		}
+		return nil
+	}
+	if vote.Closed || vote.EndTime > now {
+		return nil
+	}
+	_, err = state.EditMessageComplex(vote.ChannelID, vote.MessageID, api.EditMessageData{
+		Content: option.NewNullableString(vote.String()),
+		Components: &discord.ContainerComponents{
+			&discord.ActionRowComponent{
+				&discord.ButtonComponent{
+					Style:    discord.SecondaryButtonStyle(),
+					CustomID: VoteExportID,
+					Label:    "Export results",
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("closing expired votes could not update vote message: %w", err)
+	}
+	if vote.Outcome != nil {
+		vote.applyOutcome(state, kvs)
+	}
+	vote.Closed = true
+	if err := vote.Store(kvs); err != nil {
+		return fmt.Errorf("encoutered an error storing closed vote: %w", err)
 	}
+	vote.publishClosed()
 	return nil
 }
 
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// archiveVote appends the vote to the guild's vote archive, as a single line of JSON.
+func archiveVote(archivePath string, vote *Vote) error {
+	dir := filepath.Join(archivePath, vote.GuildID.String())
+	if err := os.MkdirAll(dir, 0770); err != nil {
+		return err
+	}
+	fileHandle, err := os.OpenFile(filepath.Join(dir, "votes.jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	defer fileHandle.Close()
+	line, err := json.Marshal(vote)
+	if err != nil {
+		return err
+	}
+	_, err = fileHandle.Write(append(line, '\n'))
+	return err
+}
+
+// ReapClosedVotes archives and/or deletes the closed votes that are older than the configured retention.
+func ReapClosedVotes(state *state.State, kvs KeyValueStore, configuration *Configuration) error {
+	cfg := configuration.Snapshot()
+	policy := cfg.VoteRetentionPolicy()
+	if policy == RetentionKeep {
+		return nil
+	}
+	guilds, err := state.Guilds()
+	if err != nil {
+		return fmt.Errorf("reaping closed votes could not fetch current guilds: %w", err)
+	}
+	cutoff := time.Now().Add(-cfg.VoteRetention()).Unix()
+	for _, guild := range guilds {
+		err := GuildJob(kvs, guild.ID, "reapvotes", time.Hour, func() error {
+			keys, err := kvs.Keys(guild.ID, "votes")
+			if err != nil {
+				return fmt.Errorf("reaping closed votes could not get keys for guild: %w", err)
+			}
+			reaped := 0
+			for _, key := range keys {
+				vote := Vote{}
+				exist, err := kvs.Get(guild.ID, "votes", key, &vote)
+				if err != nil {
+					return fmt.Errorf("reaping closed votes could not obtain vote object: %w", err)
+				}
+				if !exist || !vote.Closed || vote.EndTime > cutoff {
+					continue
+				}
+				if policy == RetentionArchive {
+					if err := archiveVote(cfg.Archive(), &vote); err != nil {
+						// Not deleting what we couldn't archive. It'll be retried next time.
+						log.Printf("[%s] Failed to archive vote %s: %s", guild.ID, vote.MessageID, err)
+						continue
+					}
+				}
+				if err := kvs.Delete(guild.ID, "votes", key); err != nil {
+					return fmt.Errorf("reaping closed votes could not delete vote: %w", err)
+				}
+				reaped++
+			}
+			if reaped > 0 {
+				log.Printf("[%s] Reaped %d closed votes (%s)", guild.ID, reaped, policy)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartReapingClosedVotes starts a ticker and, once an hour, calls ReapClosedVotes.
+// Intended to be called as a goroutine.
+func StartReapingClosedVotes(state *state.State, kvs KeyValueStore, cfg *Configuration) {
+	ticker := time.NewTicker(1 * time.Hour)
+	for {
+		<-ticker.C
+		if err := ReapClosedVotes(state, kvs, cfg); err != nil {
+			log.Printf("Error encountered reaping closed votes: %s", err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// VoteTemplate is a vote saved for running again later, like a weekly poll.
+type VoteTemplate struct {
+	Name     string
+	Question string
+	Order    []string
+	Options  map[string]string
+	Length   int64        // How long the vote runs, in seconds.
+	Outcome  *VoteOutcome // Optional, like for Vote.
+	Creator  discord.UserID
+
+	QuorumCount   int
+	QuorumPercent float64
+	Abstain       bool
+	Buttons       bool
+}
+
+// Vote makes a fresh vote from the template, starting now.
+func (t *VoteTemplate) Vote(guildID discord.GuildID) Vote {
+	now := time.Now().Unix()
+	vote := Vote{
+		StartTime: now,
+		EndTime:   now + t.Length,
+		GuildID:   guildID,
+		Question:  t.Question,
+		Order:     append([]string{}, t.Order...),
+		Options:   map[string]string{},
+		Votes:     map[discord.UserID]string{},
+
+		QuorumCount:   t.QuorumCount,
+		QuorumPercent: t.QuorumPercent,
+		Abstain:       t.Abstain,
+		Buttons:       t.Buttons,
+	}
+	for key, label := range t.Options {
+		vote.Options[key] = label
+	}
+	if t.Outcome != nil {
+		outcome := *t.Outcome
+		vote.Outcome = &outcome
+	}
+	return vote
+}
+
+// Store saves the template, replacing any with the same name.
+func (t *VoteTemplate) Store(kvs KeyValueStore, guildID discord.GuildID) error {
+	return kvs.Set(guildID, "votetemplates", t.Name, t)
+}
+
+// GetVoteTemplate gets the vote template with the given name.
+func GetVoteTemplate(kvs KeyValueStore, guildID discord.GuildID, name string) (exist bool, template VoteTemplate, err error) {
+	exist, err = kvs.Get(guildID, "votetemplates", name, &template)
+	return
+}
+
+// DeleteVoteTemplate forgets the vote template with the given name.
+func DeleteVoteTemplate(kvs KeyValueStore, guildID discord.GuildID, name string) error {
+	return kvs.Delete(guildID, "votetemplates", name)
+}
+
+// GetVoteTemplates gets all the vote templates of the guild, sorted by name.
+func GetVoteTemplates(kvs KeyValueStore, guildID discord.GuildID) ([]VoteTemplate, error) {
+	keys, err := kvs.Keys(guildID, "votetemplates")
+	if err != nil {
+		return nil, fmt.Errorf("vote templates could not get keys: %w", err)
+	}
+	sort.Strings(keys)
+	templates := make([]VoteTemplate, 0, len(keys))
+	for _, key := range keys {
+		exist, template, err := GetVoteTemplate(kvs, guildID, key)
+		if err != nil {
+			return nil, fmt.Errorf("vote templates could not get %s: %w", key, err)
+		}
+		if exist {
+			templates = append(templates, template)
+		}
+	}
+	return templates, nil
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// Warning is a mark on someone's record, given by the staff or by automod.
+type Warning struct {
+	ID        int64
+	GuildID   discord.GuildID
+	UserID    discord.UserID
+	Moderator discord.UserID // The bot itself, for automatic warnings.
+	Reason    string
+	Created   int64
+}
+
+// AddWarning gives the user a warning, and stores it.
+func AddWarning(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID, moderator discord.UserID, reason string) (Warning, error) {
+	id, err := NextNumber(kvs, guildID, "warnings")
+	if err != nil {
+		return Warning{}, fmt.Errorf("adding warning could not get a number: %w", err)
+	}
+	warning := Warning{
+		ID:        id,
+		GuildID:   guildID,
+		UserID:    userID,
+		Moderator: moderator,
+		Reason:    reason,
+		Created:   time.Now().Unix(),
+	}
+	if err := kvs.Set(guildID, "warnings", id, warning); err != nil {
+		return warning, err
+	}
+	publishWarningIssued(warning)
+	return warning, nil
+}
+
+// GetWarnings gets all the warnings the user has been given, oldest first.
+func GetWarnings(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID) ([]Warning, error) {
+	keys, err := kvs.Keys(guildID, "warnings")
+	if err != nil {
+		return nil, fmt.Errorf("getting warnings could not get keys: %w", err)
+	}
+	warnings := []Warning{}
+	for _, key := range keys {
+		warning := Warning{}
+		exist, err := kvs.Get(guildID, "warnings", key, &warning)
+		if err != nil {
+			return nil, fmt.Errorf("getting warnings could not get %s: %w", key, err)
+		}
+		if exist && warning.UserID == userID {
+			warnings = append(warnings, warning)
+		}
+	}
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].Created < warnings[j].Created
+	})
+	return warnings, nil
+}
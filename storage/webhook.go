@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"komainu/bus"
+	"log"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+const (
+	WebhookWarningLimit    = "member.warnings"
+	webhookDefaultLimit    = 3
+	webhookAttempts        = 5
+	webhookFirstRetryDelay = 10 * time.Second
+)
+
+// WebhookConfig is where a guild wants to be told about things happening, outside of Discord.
+type WebhookConfig struct {
+	URL          string
+	Secret       string // Used to sign every payload, so the receiver can tell it came from the bot.
+	WarningLimit int    // How many warnings someone has when the receiver is told. Zero means webhookDefaultLimit.
+}
+
+// Limit returns how many warnings someone has when the receiver is told.
+func (config WebhookConfig) Limit() int {
+	if config.WarningLimit <= 0 {
+		return webhookDefaultLimit
+	}
+	return config.WarningLimit
+}
+
+// WebhookPayload is what is sent to the receiver, as JSON.
+type WebhookPayload struct {
+	Event   string          `json:"event"`
+	GuildID discord.GuildID `json:"guild_id"`
+	Time    int64           `json:"time"`
+	Data    any             `json:"data"`
+}
+
+// ErrWebhookAddress is when a webhook would go somewhere inside the network the bot runs in, rather than out on the internet.
+var ErrWebhookAddress = errors.New("webhooks can only be sent to public addresses")
+
+// webhookDialer refuses to connect anywhere but public addresses. The address was checked when it was set, but the name
+// can resolve to something else by the time a webhook is sent, so every connection is checked as it's made.
+var webhookDialer = &net.Dialer{
+	Timeout: 10 * time.Second,
+	Control: func(network string, address string, c syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); ip == nil || !publicAddress(ip) {
+			return fmt.Errorf("%w, not %s", ErrWebhookAddress, host)
+		}
+		return nil
+	},
+}
+
+// webhookClient is used for all webhook deliveries, so a slow receiver can't hold a delivery up forever.
+var webhookClient = &http.Client{
+	Timeout:   10 * time.Second,
+	Transport: &http.Transport{DialContext: webhookDialer.DialContext},
+}
+
+// publicAddress says if the address is out on the internet, rather than the machine itself or a network it's on.
+// That includes link-local addresses, like the 169.254.169.254 cloud providers answer secrets on.
+func publicAddress(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// CheckWebhookHost looks the host up, and returns ErrWebhookAddress if any of its addresses isn't public.
+func CheckWebhookHost(host string) error {
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return err
+	}
+	for _, ip := range ips {
+		if !publicAddress(ip) {
+			return fmt.Errorf("%w, not %s", ErrWebhookAddress, ip)
+		}
+	}
+	return nil
+}
+
+// GetWebhookConfig gets the webhook configuration of the guild, if it has one.
+func GetWebhookConfig(kvs KeyValueStore, guildID discord.GuildID) (exist bool, config WebhookConfig, err error) {
+	exist, err = kvs.Get(guildID, "webhook", "config", &config)
+	return exist, config, err
+}
+
+// SetWebhookConfig sets where the guild wants webhooks sent, with a fresh secret, and returns the secret.
+func SetWebhookConfig(kvs KeyValueStore, guildID discord.GuildID, url string, warningLimit int) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	config := WebhookConfig{
+		URL:          url,
+		Secret:       hex.EncodeToString(raw),
+		WarningLimit: warningLimit,
+	}
+	return config.Secret, kvs.Set(guildID, "webhook", "config", config)
+}
+
+// ClearWebhookConfig stops sending webhooks for the guild.
+func ClearWebhookConfig(kvs KeyValueStore, guildID discord.GuildID) error {
+	return kvs.Delete(guildID, "webhook", "config")
+}
+
+// SignWebhook returns the signature of the body, as it is sent in the X-Komainu-Signature header.
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NotifyWebhook sends the event to the guild's webhook, if it has one. Delivery happens in the background,
+// with retries, so this returns right away.
+func NotifyWebhook(kvs KeyValueStore, guildID discord.GuildID, event string, data any) {
+	exist, config, err := GetWebhookConfig(kvs, guildID)
+	if err != nil {
+		log.Printf("[%s] Could not get the webhook config to send %s: %s", guildID, event, err)
+		return
+	}
+	if !exist {
+		return
+	}
+	body, err := json.Marshal(WebhookPayload{Event: event, GuildID: guildID, Time: time.Now().Unix(), Data: data})
+	if err != nil {
+		log.Printf("[%s] Could not encode the %s webhook: %s", guildID, event, err)
+		return
+	}
+	go deliverWebhook(guildID, config, event, body)
+}
+
+// deliverWebhook posts the body to the webhook, trying again a few times, waiting longer each time, if it fails.
+func deliverWebhook(guildID discord.GuildID, config WebhookConfig, event string, body []byte) {
+	delay := webhookFirstRetryDelay
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		retry, err := postWebhook(config, event, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt == webhookAttempts {
+			log.Printf("[%s] Giving up on the %s webhook after %d attempt(s): %s", guildID, event, attempt, err)
+			return
+		}
+		log.Printf("[%s] The %s webhook failed, trying again in %s: %s", guildID, event, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// postWebhook makes a single attempt at delivering the webhook, and says if it is worth trying again if it fails.
+func postWebhook(config WebhookConfig, event string, body []byte) (retry bool, err error) {
+	request, err := http.NewRequest(http.MethodPost, config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "Komainu")
+	request.Header.Set("X-Komainu-Event", event)
+	request.Header.Set("X-Komainu-Signature", SignWebhook(config.Secret, body))
+	resp, err := webhookClient.Do(request)
+	if err != nil {
+		return !errors.Is(err, ErrWebhookAddress), err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	// The receiver turning it down won't change by asking again, unless it asked us to slow down.
+	retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("the receiver answered %s", resp.Status)
+}
+
+// SubscribeWebhooks sends events on to the guild's webhook, if it has one. Votes closing, tickets opening and members
+// being verified are sent as they are, and warnings only once someone reaches the limit.
+func SubscribeWebhooks(kvs KeyValueStore) {
+	bus.Subscribe(bus.Everything, func(event bus.Event) {
+		switch event.Name {
+		case bus.VoteClosed, bus.TicketOpened, bus.MemberVerified:
+			NotifyWebhook(kvs, event.GuildID, event.Name, event.Data)
+		case bus.WarningIssued:
+			if warning, ok := event.Data.(Warning); ok {
+				notifyWarningLimit(kvs, warning)
+			}
+		}
+	})
+}
+
+// notifyWarningLimit tells the webhook, if any, when someone reaches the warning limit with their latest warning.
+func notifyWarningLimit(kvs KeyValueStore, warning Warning) {
+	exist, config, err := GetWebhookConfig(kvs, warning.GuildID)
+	if err != nil || !exist {
+		return
+	}
+	warnings, err := GetWarnings(kvs, warning.GuildID, warning.UserID)
+	if err != nil {
+		log.Printf("[%s] Could not count the warnings of <@%s> for the webhook: %s", warning.GuildID, warning.UserID, err)
+		return
+	}
+	if len(warnings) != config.Limit() {
+		return
+	}
+	NotifyWebhook(kvs, warning.GuildID, WebhookWarningLimit, struct {
+		UserID   discord.UserID `json:"user_id"`
+		Warnings int            `json:"warnings"`
+		Reason   string         `json:"reason"`
+	}{warning.UserID, len(warnings), warning.Reason})
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookHost(t *testing.T) {
+	for _, host := range []string{"127.0.0.1", "::1", "10.1.2.3", "192.168.0.1", "172.16.0.1", "169.254.169.254", "fe80::1", "0.0.0.0", "localhost"} {
+		if err := CheckWebhookHost(host); !errors.Is(err, ErrWebhookAddress) {
+			t.Errorf("Expected %s to be refused, Got %v", host, err)
+		}
+	}
+	for _, host := range []string{"1.1.1.1", "2606:4700:4700::1111"} {
+		if err := CheckWebhookHost(host); err != nil {
+			t.Errorf("Expected %s to be allowed, Got %v", host, err)
+		}
+	}
+}
+
+func TestWebhookDial(t *testing.T) {
+	received := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+	}))
+	t.Cleanup(server.Close)
+
+	// As if the name resolved to somewhere public when it was set, and to the machine itself by the time it's sent.
+	retry, err := postWebhook(WebhookConfig{URL: server.URL, Secret: "secret"}, "test", []byte("{}"))
+	if !errors.Is(err, ErrWebhookAddress) || retry {
+		t.Errorf("Expected the connection to be refused, and not tried again, Got %v (retry: %t)", err, retry)
+	}
+	if received {
+		t.Error("The webhook was delivered to a loopback address")
+	}
+}
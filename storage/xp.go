@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// XPConfig is how XP is handed out in a guild.
+type XPConfig struct {
+	Enabled         bool
+	CooldownSeconds int                    // Messages closer together than this don't count. Zero means 60.
+	Announce        discord.ChannelID      // Where level-ups are announced. Null means where the message was sent.
+	Rewards         map[int]discord.RoleID // Role given on reaching the level.
+}
+
+// Cooldown returns how long someone has to wait between messages that give XP.
+func (c XPConfig) Cooldown() time.Duration {
+	if c.CooldownSeconds <= 0 {
+		return 60 * time.Second
+	}
+	return time.Duration(c.CooldownSeconds) * time.Second
+}
+
+// RewardsUpTo lists the reward roles for every level up to and including the given one.
+func (c XPConfig) RewardsUpTo(level int) []discord.RoleID {
+	roles := []discord.RoleID{}
+	for rewardLevel, roleID := range c.Rewards {
+		if rewardLevel <= level {
+			roles = append(roles, roleID)
+		}
+	}
+	return roles
+}
+
+// XP is how far along someone is.
+type XP struct {
+	UserID discord.UserID
+	Points int
+	Last   int64 // When they were last given XP.
+}
+
+// Level is the level the points add up to.
+func (x XP) Level() int {
+	return XPLevel(x.Points)
+}
+
+// XPLevel is the level a number of points add up to. Every level takes a bit more than the last.
+func XPLevel(points int) int {
+	return int(math.Sqrt(float64(points) / 100))
+}
+
+// XPForLevel is how many points it takes to reach the level.
+func XPForLevel(level int) int {
+	return 100 * level * level
+}
+
+// xpLock keeps two messages from the same person from both reading the old points.
+var xpLock sync.Mutex
+
+// GetXPConfig gets the XP setup for the guild, or an empty one.
+func GetXPConfig(kvs KeyValueStore, guildID discord.GuildID) (config XPConfig, err error) {
+	_, err = kvs.Get(guildID, "xpconfig", "config", &config)
+	if config.Rewards == nil {
+		config.Rewards = map[int]discord.RoleID{} // gob doesn't bother with empty maps.
+	}
+	return
+}
+
+// SetXPConfig stores the XP setup for the guild.
+func SetXPConfig(kvs KeyValueStore, guildID discord.GuildID, config XPConfig) error {
+	return kvs.Set(guildID, "xpconfig", "config", config)
+}
+
+// GetXP gets the XP of the user. Someone who has never been given any has zero.
+func GetXP(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID) (XP, error) {
+	xp := XP{UserID: userID}
+	_, err := kvs.Get(guildID, "xp", userID, &xp)
+	return xp, err
+}
+
+// AwardXP gives the user between 15 and 25 points, unless they got some too recently.
+// Returns the new XP, and the level they reached if the points took them to a new one, otherwise zero.
+func AwardXP(kvs KeyValueStore, guildID discord.GuildID, userID discord.UserID, cooldown time.Duration) (xp XP, levelUp int, err error) {
+	xpLock.Lock()
+	defer xpLock.Unlock()
+	xp, err = GetXP(kvs, guildID, userID)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	if now.Sub(time.Unix(xp.Last, 0)) < cooldown {
+		return
+	}
+	before := xp.Level()
+	xp.Points += 15 + rand.Intn(11)
+	xp.Last = now.Unix()
+	if err = kvs.Set(guildID, "xp", userID, xp); err != nil {
+		return
+	}
+	if xp.Level() > before {
+		levelUp = xp.Level()
+	}
+	return
+}
+
+// XPLeaderboard gets everyone with XP in the guild, most points first.
+func XPLeaderboard(kvs KeyValueStore, guildID discord.GuildID) ([]XP, error) {
+	keys, err := kvs.Keys(guildID, "xp")
+	if err != nil {
+		return nil, fmt.Errorf("XP leaderboard could not get keys: %w", err)
+	}
+	board := make([]XP, 0, len(keys))
+	for _, key := range keys {
+		xp := XP{}
+		exist, err := kvs.Get(guildID, "xp", key, &xp)
+		if err != nil {
+			return nil, fmt.Errorf("XP leaderboard could not get %s: %w", key, err)
+		}
+		if exist {
+			board = append(board, xp)
+		}
+	}
+	sort.SliceStable(board, func(i, j int) bool {
+		return board[i].Points > board[j].Points
+	})
+	return board, nil
+}
@@ -0,0 +1,94 @@
+package utility
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationPart = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)\s*(w|d|h|m|s)`)
+
+var durationUnits = map[string]time.Duration{
+	"w": 7 * 24 * time.Hour,
+	"d": 24 * time.Hour,
+	"h": time.Hour,
+	"m": time.Minute,
+	"s": time.Second,
+}
+
+// ParseDuration works like time.ParseDuration, except it also understands days and weeks, like "3d12h" or "1w".
+// It does not understand anything smaller than a second, because nobody needs that in a chat.
+func ParseDuration(input string) (time.Duration, error) {
+	remaining := strings.ToLower(strings.TrimSpace(input))
+	if remaining == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+	var total time.Duration
+	for remaining != "" {
+		match := durationPart.FindStringSubmatch(remaining)
+		if match == nil {
+			return 0, fmt.Errorf("invalid duration %q", input)
+		}
+		amount, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number in duration %q: %w", input, err)
+		}
+		total += time.Duration(amount * float64(durationUnits[match[2]]))
+		remaining = strings.TrimSpace(remaining[len(match[0]):])
+	}
+	return total, nil
+}
+
+// durationNames are the units FormatDuration spells things out in, largest first.
+var durationNames = []struct {
+	name string
+	unit time.Duration
+}{
+	{"week", 7 * 24 * time.Hour},
+	{"day", 24 * time.Hour},
+	{"hour", time.Hour},
+	{"minute", time.Minute},
+}
+
+// FormatDuration spells out how long something is, in the two largest units it has, like "3 days 4 hours" or
+// "1 week". Anything under a minute is "less than a minute", and it is rounded down, so it never says more than it is.
+func FormatDuration(duration time.Duration) string {
+	if duration < 0 {
+		duration = -duration
+	}
+	parts := []string{}
+	for _, unit := range durationNames {
+		if len(parts) == 2 {
+			break
+		}
+		count := int64(duration / unit.unit)
+		duration -= time.Duration(count) * unit.unit
+		if count == 0 {
+			if len(parts) > 0 {
+				break // "1 week 3 hours" would be misleading with the days left out.
+			}
+			continue
+		}
+		if count == 1 {
+			parts = append(parts, "1 "+unit.name)
+		} else {
+			parts = append(parts, fmt.Sprintf("%d %ss", count, unit.name))
+		}
+	}
+	if len(parts) == 0 {
+		return "less than a minute"
+	}
+	return strings.Join(parts, " ")
+}
+
+// FormatIn is FormatDuration for something that's still to come, like "in 3 days 4 hours".
+func FormatIn(duration time.Duration) string {
+	return "in " + FormatDuration(duration)
+}
+
+// FormatAgo is FormatDuration for something that has passed, like "3 days 4 hours ago".
+func FormatAgo(duration time.Duration) string {
+	return FormatDuration(duration) + " ago"
+}
@@ -0,0 +1,26 @@
+package utility
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatDuration(t *testing.T) {
+	day := 24 * time.Hour
+	cases := map[time.Duration]string{
+		0:                                 "less than a minute",
+		30 * time.Second:                  "less than a minute",
+		time.Minute:                       "1 minute",
+		90 * time.Minute:                  "1 hour 30 minutes",
+		3*day + 4*time.Hour + time.Minute: "3 days 4 hours",
+		7 * day:                           "1 week",
+		8*day + 3*time.Hour:               "1 week 1 day",
+		7*day + 3*time.Hour:               "1 week",
+		-2 * time.Hour:                    "2 hours",
+	}
+	for duration, expected := range cases {
+		if got := FormatDuration(duration); got != expected {
+			t.Errorf("Expected %s to be %q, Got %q", duration, expected, got)
+		}
+	}
+}
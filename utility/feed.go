@@ -0,0 +1,168 @@
+package utility
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// FeedItem is a single entry in an RSS or Atom feed.
+type FeedItem struct {
+	ID        string // Whatever the feed uses to tell entries apart, or the link if it doesn't.
+	Title     string
+	Link      string
+	Summary   string // The description or summary, as plain text.
+	Published time.Time
+}
+
+// Feed is an RSS or Atom feed, with the entries in the order the feed lists them.
+type Feed struct {
+	Title string
+	Items []FeedItem
+}
+
+// xmlFeed covers RSS 2.0 (<rss><channel><item>), RSS 1.0 (<rdf:RDF><item>) and Atom (<feed><entry>) all at once.
+type xmlFeed struct {
+	Title   string `xml:"title"`
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []xmlItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []xmlItem  `xml:"item"`
+	Entries []xmlEntry `xml:"entry"`
+}
+
+type xmlItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"date"` // Dublin Core, as RSS 1.0 uses.
+}
+
+type xmlEntry struct {
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	ID        string `xml:"id"`
+	Summary   string `xml:"summary"`
+	Content   string `xml:"content"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+}
+
+// feedTimeLayouts are the date formats seen in the wild, RSS ones first and then Atom.
+var feedTimeLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC3339,
+}
+
+// ParseFeed reads an RSS or Atom feed.
+func ParseFeed(r io.Reader) (Feed, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = feedCharsetReader
+	decoder.Strict = false
+	raw := xmlFeed{}
+	if err := decoder.Decode(&raw); err != nil {
+		return Feed{}, fmt.Errorf("not a feed I can read: %w", err)
+	}
+
+	feed := Feed{Title: strings.TrimSpace(raw.Title)}
+	if raw.Channel.Title != "" {
+		feed.Title = strings.TrimSpace(raw.Channel.Title)
+	}
+	for _, item := range append(raw.Channel.Items, raw.Items...) {
+		published := item.PubDate
+		if published == "" {
+			published = item.Date
+		}
+		feed.Items = append(feed.Items, newFeedItem(item.GUID, item.Title, item.Link, item.Description, published))
+	}
+	for _, entry := range raw.Entries {
+		link := ""
+		for _, l := range entry.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				link = l.Href
+				break
+			}
+		}
+		summary := entry.Summary
+		if summary == "" {
+			summary = entry.Content
+		}
+		published := entry.Published
+		if published == "" {
+			published = entry.Updated
+		}
+		feed.Items = append(feed.Items, newFeedItem(entry.ID, entry.Title, link, summary, published))
+	}
+	if len(feed.Items) == 0 && feed.Title == "" {
+		return Feed{}, fmt.Errorf("not a feed I can read: no title and no entries")
+	}
+	return feed, nil
+}
+
+func newFeedItem(id, title, link, summary, published string) FeedItem {
+	item := FeedItem{
+		ID:      strings.TrimSpace(id),
+		Title:   strings.TrimSpace(html.UnescapeString(title)),
+		Link:    strings.TrimSpace(link),
+		Summary: PlainText(summary),
+	}
+	if item.ID == "" {
+		item.ID = item.Link
+	}
+	if item.ID == "" {
+		item.ID = item.Title
+	}
+	published = strings.TrimSpace(published)
+	for _, layout := range feedTimeLayouts {
+		if t, err := time.Parse(layout, published); err == nil {
+			item.Published = t
+			break
+		}
+	}
+	return item
+}
+
+var (
+	htmlTag    = regexp.MustCompile(`<[^>]*>`)
+	whitespace = regexp.MustCompile(`\s+`)
+)
+
+// PlainText makes a rough plain text version of a bit of HTML, dropping the tags and squashing the whitespace.
+func PlainText(text string) string {
+	text = htmlTag.ReplaceAllString(text, " ")
+	text = html.UnescapeString(text)
+	return strings.TrimSpace(whitespace.ReplaceAllString(text, " "))
+}
+
+// feedCharsetReader reads the few character sets feeds come in other than UTF-8.
+func feedCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "latin1", "latin-1", "windows-1252":
+		raw, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		runes := make([]rune, len(raw))
+		for i, b := range raw {
+			runes[i] = rune(b)
+		}
+		return strings.NewReader(string(runes)), nil
+	}
+	return nil, fmt.Errorf("unsupported character set %q", charset)
+}
@@ -0,0 +1,69 @@
+package utility
+
+import (
+	"sort"
+	"strings"
+)
+
+// EditDistance is how many single character insertions, deletions and substitutions it takes to turn one string into
+// the other, also known as the Levenshtein distance.
+func EditDistance(one string, other string) int {
+	a, b := []rune(one), []rune(other)
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// ClosestMatches finds the candidates that look like what was typed, best first, and at most limit of them.
+// Anything containing what was typed counts as close, as does anything within a typo or two, with a bit more leeway for
+// longer words. Case is ignored.
+func ClosestMatches(typed string, candidates []string, limit int) []string {
+	typed = strings.ToLower(strings.TrimSpace(typed))
+	if typed == "" {
+		return []string{}
+	}
+	leeway := max(2, len([]rune(typed))/3)
+	type match struct {
+		candidate string
+		distance  int
+	}
+	matches := []match{}
+	for _, candidate := range candidates {
+		lower := strings.ToLower(candidate)
+		distance := EditDistance(typed, lower)
+		if strings.Contains(lower, typed) || strings.Contains(typed, lower) {
+			distance = min(distance, 1)
+		}
+		if distance <= leeway {
+			matches = append(matches, match{candidate, distance})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].candidate < matches[j].candidate
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	found := make([]string, len(matches))
+	for i, m := range matches {
+		found[i] = m.candidate
+	}
+	return found
+}
@@ -0,0 +1,120 @@
+package utility
+
+import (
+	"errors"
+	"math"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+	"github.com/diamondburned/arikawa/v3/state"
+)
+
+// HierarchyError is why a role or member is out of the bot's reach, or whoever asked. It's put so it can be shown
+// to them as it is, rather than whatever Discord would have said when it refused.
+type HierarchyError string
+
+func (e HierarchyError) Error() string {
+	return string(e)
+}
+
+// OutOfReach tells if the error is a HierarchyError, rather than a failure to look things up.
+func OutOfReach(err error) bool {
+	var hierarchyErr HierarchyError
+	return errors.As(err, &hierarchyErr)
+}
+
+// hierarchy is what it takes to tell who is above what in a guild.
+type hierarchy struct {
+	ownerID discord.UserID
+	roles   map[discord.RoleID]discord.Role
+	me      int // The position of the bot's highest role.
+}
+
+func newHierarchy(state *state.State, guildID discord.GuildID) (*hierarchy, error) {
+	guild, err := state.Guild(guildID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := state.Roles(guildID)
+	if err != nil {
+		return nil, err
+	}
+	me, err := state.Me()
+	if err != nil {
+		return nil, err
+	}
+	h := &hierarchy{ownerID: guild.OwnerID, roles: make(map[discord.RoleID]discord.Role, len(roles))}
+	for _, role := range roles {
+		h.roles[role.ID] = role
+	}
+	myMember, err := state.Member(guildID, me.ID)
+	if err != nil {
+		return nil, err
+	}
+	h.me = h.position(myMember)
+	return h, nil
+}
+
+// position is that of the member's highest role. The owner is above everything, and no roles is the same as @everyone.
+func (h *hierarchy) position(member *discord.Member) int {
+	if member.User.ID == h.ownerID {
+		return math.MaxInt
+	}
+	highest := 0
+	for _, roleID := range member.RoleIDs {
+		if role, ok := h.roles[roleID]; ok && role.Position > highest {
+			highest = role.Position
+		}
+	}
+	return highest
+}
+
+// CanManageRole checks that the role can be given or taken away, both by the bot and by actor.
+// The actor is whoever asked for it, like an admin using a command. It's nil when the bot does it by itself, or
+// for members picking their own roles, as then only the bot has to be above the role.
+// Anything in the way is a HierarchyError. Other errors are from looking it all up.
+func CanManageRole(state *state.State, guildID discord.GuildID, actor *discord.Member, roleID discord.RoleID) error {
+	if discord.GuildID(roleID) == guildID {
+		return HierarchyError("Everyone has @everyone, and nobody can give it or take it away.")
+	}
+	h, err := newHierarchy(state, guildID)
+	if err != nil {
+		return err
+	}
+	role, ok := h.roles[roleID]
+	if !ok {
+		return HierarchyError("That role doesn't exist anymore.")
+	}
+	if role.Managed {
+		return HierarchyError("That role belongs to an integration or a bot, so only Discord can give it or take it away.")
+	}
+	if h.me <= role.Position {
+		return HierarchyError("My role is below that role, so I can't give it or take it away. Move my role above it in the server settings.")
+	}
+	if actor != nil && h.position(actor) <= role.Position {
+		return HierarchyError("Your highest role isn't above that role, so you can't have me give it or take it away either.")
+	}
+	return nil
+}
+
+// CanModerate checks that the target can be timed out, kicked or banned, both by the bot and by actor.
+// Just like with CanManageRole, the actor is nil when the bot does it by itself.
+func CanModerate(state *state.State, guildID discord.GuildID, actor *discord.Member, targetID discord.UserID) error {
+	h, err := newHierarchy(state, guildID)
+	if err != nil {
+		return err
+	}
+	if targetID == h.ownerID {
+		return HierarchyError("That's the owner of the server. Nobody can do that to them.")
+	}
+	target, err := state.Member(guildID, targetID)
+	if err != nil {
+		return err
+	}
+	if h.me <= h.position(target) {
+		return HierarchyError("My role is below theirs, so I can't do that to them. Move my role above theirs in the server settings.")
+	}
+	if actor != nil && h.position(actor) <= h.position(target) {
+		return HierarchyError("Their highest role isn't below yours, so you can't have me do that to them.")
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package utility
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TemplateMaxArgs is the highest {argN} a template can use.
+const TemplateMaxArgs = 5
+
+// templatePlaceholder matches things like {user}, {arg2} and {random:a|b|c}. Anything else in braces is left alone.
+var templatePlaceholder = regexp.MustCompile(`\{([a-z]+[0-9]*)(?::([^{}]*))?\}`)
+
+// TemplateValues are what the placeholders in a template are filled in with.
+type TemplateValues struct {
+	User    string
+	Channel string
+	Server  string
+	Args    []string
+	Extra   map[string]string // Placeholders only some templates have, like {title}, by name.
+}
+
+// templateArg returns N if the placeholder name is argN, otherwise zero.
+func templateArg(name string) int {
+	if !strings.HasPrefix(name, "arg") {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(name, "arg"))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// ValidateTemplate checks that every placeholder in the text is one RenderTemplate knows about, along with any extra
+// ones the template gets filled in with. For checking when a template is saved, so mistakes are caught by whoever made them,
+// not whoever uses it.
+func ValidateTemplate(text string, extra ...string) error {
+	for _, match := range templatePlaceholder.FindAllStringSubmatch(text, -1) {
+		name, param := match[1], match[2]
+		switch {
+		case name == "user" || name == "channel" || name == "server" || ContainsString(extra, name):
+			if param != "" {
+				return fmt.Errorf("%s doesn't take anything after a colon", match[0])
+			}
+		case name == "random":
+			if strings.Trim(param, "|") == "" {
+				return fmt.Errorf("%s needs some choices, like {random:this|that}", match[0])
+			}
+		case templateArg(name) > 0:
+			if n := templateArg(name); n > TemplateMaxArgs {
+				return fmt.Errorf("%s is too many, only up to {arg%d} is allowed", match[0], TemplateMaxArgs)
+			}
+		default:
+			return fmt.Errorf("%s is not something I know how to fill in", match[0])
+		}
+	}
+	return nil
+}
+
+// TemplateArgs returns the highest {argN} used in the text, or zero if there are none.
+func TemplateArgs(text string) int {
+	highest := 0
+	for _, match := range templatePlaceholder.FindAllStringSubmatch(text, -1) {
+		if n := templateArg(match[1]); n > highest && n <= TemplateMaxArgs {
+			highest = n
+		}
+	}
+	return highest
+}
+
+// RenderTemplate fills in the placeholders in the text. Unknown ones are left as they are, and whatever is filled in is never
+// looked at again, so an argument that happens to say {user} stays that way.
+func RenderTemplate(text string, values TemplateValues) string {
+	return templatePlaceholder.ReplaceAllStringFunc(text, func(placeholder string) string {
+		match := templatePlaceholder.FindStringSubmatch(placeholder)
+		name, param := match[1], match[2]
+		switch name {
+		case "user":
+			return values.User
+		case "channel":
+			return values.Channel
+		case "server":
+			return values.Server
+		case "random":
+			choices := []string{}
+			for _, choice := range strings.Split(param, "|") {
+				if choice != "" {
+					choices = append(choices, choice)
+				}
+			}
+			if len(choices) == 0 {
+				return placeholder
+			}
+			return choices[rand.Intn(len(choices))]
+		}
+		if value, ok := values.Extra[name]; ok {
+			return value
+		}
+		if n := templateArg(name); n > 0 && n <= TemplateMaxArgs {
+			if n <= len(values.Args) {
+				return values.Args[n-1]
+			}
+			return ""
+		}
+		return placeholder
+	})
+}
@@ -0,0 +1,41 @@
+package utility
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimestampStyle is how Discord shows a timestamp. Discord shows it in the time zone and language of whoever is
+// looking at it, which is why it's preferred over formatting the time ourselves.
+type TimestampStyle string
+
+// The styles Discord has, with how they look in English.
+const (
+	ShortTime     TimestampStyle = "t" // 16:20
+	LongTime      TimestampStyle = "T" // 16:20:30
+	ShortDate     TimestampStyle = "d" // 20/04/2021
+	LongDate      TimestampStyle = "D" // 20 April 2021
+	ShortDateTime TimestampStyle = "f" // 20 April 2021 16:20
+	LongDateTime  TimestampStyle = "F" // Tuesday, 20 April 2021 16:20
+	Relative      TimestampStyle = "R" // in 2 months, or 3 days ago
+)
+
+// Timestamp makes a Discord timestamp of the unix time, in the style.
+func Timestamp(unix int64, style TimestampStyle) string {
+	return fmt.Sprintf("<t:%d:%s>", unix, style)
+}
+
+// TimestampAndRelative makes a Discord timestamp of the unix time in the style, followed by how long until or since
+// it, like "Tuesday, 20 April 2021 16:20 (in 3 days)".
+func TimestampAndRelative(unix int64, style TimestampStyle) string {
+	return fmt.Sprintf("%s (%s)", Timestamp(unix, style), Timestamp(unix, Relative))
+}
+
+// FormatLocal shows the time as it is in the location, like "2021-04-20 16:20 CEST". For where a Discord timestamp
+// won't do, like files, logs and modals, or where it has to be the time in the guild's time zone.
+func FormatLocal(t time.Time, location *time.Location) string {
+	if location == nil {
+		location = time.UTC
+	}
+	return t.In(location).Format("2006-01-02 15:04 MST")
+}
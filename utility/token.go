@@ -1,6 +1,7 @@
 package utility
 
 import (
+	"sync"
 	"time"
 
 	"github.com/diamondburned/arikawa/v3/discord"
@@ -10,10 +11,13 @@ type Token struct {
 	Interval int
 	count    int
 	ticker   *time.Ticker
+	lock     sync.Mutex
 }
 
 // Increment increments the count of the token, and starts a ticker to count it back down, as needed.
 func (token *Token) Increment() {
+	token.lock.Lock()
+	defer token.lock.Unlock()
 	token.count++
 	if token.ticker == nil {
 		interval := 10
@@ -21,22 +25,31 @@ func (token *Token) Increment() {
 			interval = token.Interval
 		}
 		token.ticker = time.NewTicker(time.Duration(interval) * time.Second)
-		go tick(token)
+		go tick(token, token.ticker)
 	}
 }
 
 // tick does the ticker waiting, decrementing and cleanup.
-func tick(token *Token) {
-	for token.count > 0 {
-		<-token.ticker.C // Just wait for it to tick, we don't care what it returns.
+func tick(token *Token, ticker *time.Ticker) {
+	for range ticker.C { // Just wait for it to tick, we don't care what it returns.
+		token.lock.Lock()
 		token.count--
+		done := token.count <= 0
+		if done {
+			ticker.Stop()
+			token.ticker = nil
+		}
+		token.lock.Unlock()
+		if done {
+			return
+		}
 	}
-	token.ticker.Stop()
-	token.ticker = nil
 }
 
 // GetCount returns the current count on the token.
 func (token *Token) GetCount() int {
+	token.lock.Lock()
+	defer token.lock.Unlock()
 	return token.count
 }
 
@@ -44,6 +57,7 @@ type TokenBin struct {
 	Max      int
 	Interval int
 	tokens   map[discord.Snowflake]map[discord.Snowflake]*Token
+	lock     sync.Mutex
 }
 
 // ensureTokenExists makes sure the pile and key exists, and has a Token on it with the correct Interval
@@ -61,6 +75,8 @@ func (tb *TokenBin) ensureTokenExists(pile discord.Snowflake, key discord.Snowfl
 
 // Allocate will return a true value if the token has not reached it's maximum value, false if it has.
 func (tb *TokenBin) Allocate(pile discord.Snowflake, key discord.Snowflake) bool {
+	tb.lock.Lock()
+	defer tb.lock.Unlock()
 	tb.ensureTokenExists(pile, key)
 	if token, ok := tb.tokens[pile][key]; ok {
 		if token.GetCount() < tb.Max {
@@ -0,0 +1,257 @@
+package webapi
+
+import (
+	"fmt"
+	"komainu/locale"
+	"komainu/storage"
+	"komainu/utility"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// handleFaq lists the FAQ, or gets, sets or removes a single topic.
+func handleFaq(r request) (any, error) {
+	if r.key == "" {
+		if r.Method != http.MethodGet {
+			return nil, errMethodNotAllowed
+		}
+		topics, err := r.kvs.Keys(r.guildID, "faq")
+		if err != nil {
+			return nil, err
+		}
+		faq := map[string]string{}
+		for _, topic := range topics {
+			text := ""
+			if _, err := r.kvs.Get(r.guildID, "faq", topic, &text); err != nil {
+				return nil, err
+			}
+			faq[topic] = text
+		}
+		return faq, nil
+	}
+
+	topic := strings.ToLower(r.key)
+	switch r.Method {
+	case http.MethodGet:
+		text := ""
+		exist, err := r.kvs.Get(r.guildID, "faq", topic, &text)
+		if err != nil {
+			return nil, err
+		}
+		if !exist {
+			return nil, errNotFound
+		}
+		return map[string]string{"topic": topic, "text": text}, nil
+	case http.MethodPut:
+		if err := r.writable(); err != nil {
+			return nil, err
+		}
+		body := struct{ Text string }{}
+		if err := r.decode(&body); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(body.Text) == "" {
+			return nil, apiError{http.StatusBadRequest, "the text can't be blank"}
+		}
+		if err := utility.ValidateTemplate(body.Text); err != nil {
+			return nil, apiError{http.StatusBadRequest, err.Error()}
+		}
+		if err := storage.SetFAQTopic(r.kvs, r.guildID, topic, body.Text, discord.NullUserID); err != nil {
+			return nil, err
+		}
+		return map[string]string{"topic": topic, "text": body.Text}, nil
+	case http.MethodDelete:
+		if err := r.writable(); err != nil {
+			return nil, err
+		}
+		return nil, storage.RemoveFAQTopic(r.kvs, r.guildID, topic, discord.NullUserID)
+	}
+	return nil, errMethodNotAllowed
+}
+
+// handleSeen lists when everyone was last seen, or when a single user was, as Unix timestamps.
+func handleSeen(r request) (any, error) {
+	if r.Method != http.MethodGet {
+		return nil, errMethodNotAllowed
+	}
+	if r.key != "" {
+		snowflake, err := discord.ParseSnowflake(r.key)
+		if err != nil {
+			return nil, errNotFound
+		}
+		exist, seen, err := storage.LastSeen(r.kvs, r.guildID, discord.UserID(snowflake))
+		if err != nil {
+			return nil, err
+		}
+		if !exist {
+			return nil, errNotFound
+		}
+		return map[string]int64{"seen": seen}, nil
+	}
+	users, err := r.kvs.Keys(r.guildID, "seen")
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]int64{}
+	for _, user := range users {
+		var timestamp int64
+		if _, err := r.kvs.Get(r.guildID, "seen", user, &timestamp); err != nil {
+			return nil, err
+		}
+		seen[user] = timestamp
+	}
+	return seen, nil
+}
+
+// apiVote is a vote, as the API shows it. Who voted for what, and who wrote which comment, is never shown, so it's
+// only ever the tally.
+type apiVote struct {
+	MessageID    discord.MessageID `json:"message_id"`
+	ChannelID    discord.ChannelID `json:"channel_id"`
+	Question     string            `json:"question"`
+	Options      []string          `json:"options"`
+	Tally        map[string]int    `json:"tally"`
+	EndTime      int64             `json:"end_time"`
+	Closed       bool              `json:"closed"`
+	Cancelled    bool              `json:"cancelled"`
+	QuorumNeeded int               `json:"quorum_needed"`
+	QuorumMet    bool              `json:"quorum_met"`
+}
+
+// newAPIVote makes what the API shows of the vote.
+func newAPIVote(vote *storage.Vote) apiVote {
+	tally, options := vote.Tally()
+	return apiVote{
+		MessageID:    vote.MessageID,
+		ChannelID:    vote.ChannelID,
+		Question:     vote.Question,
+		Options:      options,
+		Tally:        tally,
+		EndTime:      vote.EndTime,
+		Closed:       vote.Closed,
+		Cancelled:    vote.Cancelled,
+		QuorumNeeded: vote.QuorumNeeded(),
+		QuorumMet:    vote.QuorumMet(),
+	}
+}
+
+// handleVotes lists the votes, or gets a single one by its message ID.
+func handleVotes(r request) (any, error) {
+	if r.Method != http.MethodGet {
+		return nil, errMethodNotAllowed
+	}
+	if r.key != "" {
+		snowflake, err := discord.ParseSnowflake(r.key)
+		if err != nil {
+			return nil, errNotFound
+		}
+		exist, vote, err := storage.GetVote(r.kvs, r.guildID, discord.MessageID(snowflake))
+		if err != nil {
+			return nil, err
+		}
+		if !exist {
+			return nil, errNotFound
+		}
+		return newAPIVote(vote), nil
+	}
+	keys, err := r.kvs.Keys(r.guildID, "votes")
+	if err != nil {
+		return nil, err
+	}
+	votes := []apiVote{}
+	for _, key := range keys {
+		vote := storage.Vote{}
+		exist, err := r.kvs.Get(r.guildID, "votes", key, &vote)
+		if err != nil {
+			return nil, err
+		}
+		if exist {
+			votes = append(votes, newAPIVote(&vote))
+		}
+	}
+	return votes, nil
+}
+
+// guildConfig is the part of the guild configuration the API shows and changes.
+type guildConfig struct {
+	Timezone string            `json:"timezone"`
+	Locale   string            `json:"locale"`
+	Presets  map[string]string `json:"presets"`
+}
+
+// handleConfig shows the guild configuration, or changes the time zone and locale.
+func handleConfig(r request) (any, error) {
+	if r.key != "" {
+		return nil, errNotFound
+	}
+	switch r.Method {
+	case http.MethodGet:
+		return getConfig(r)
+	case http.MethodPatch:
+		if err := r.writable(); err != nil {
+			return nil, err
+		}
+		body := struct {
+			Timezone *string `json:"timezone"`
+			Locale   *string `json:"locale"`
+		}{}
+		if err := r.decode(&body); err != nil {
+			return nil, err
+		}
+		if body.Timezone != nil {
+			name := strings.TrimSpace(*body.Timezone)
+			if _, err := time.LoadLocation(name); err != nil || name == "Local" {
+				return nil, apiError{http.StatusBadRequest, fmt.Sprintf("unknown time zone %q", name)}
+			}
+			if err := storage.SetTimezone(r.kvs, r.guildID, name); err != nil {
+				return nil, err
+			}
+		}
+		if body.Locale != nil {
+			language, ok := knownLanguage(*body.Locale)
+			if !ok {
+				return nil, apiError{http.StatusBadRequest, fmt.Sprintf("unknown locale %q", *body.Locale)}
+			}
+			if err := storage.SetLocale(r.kvs, r.guildID, language); err != nil {
+				return nil, err
+			}
+		}
+		return getConfig(r)
+	}
+	return nil, errMethodNotAllowed
+}
+
+func getConfig(r request) (any, error) {
+	location, err := storage.GetTimezone(r.kvs, r.guildID)
+	if err != nil {
+		return nil, err
+	}
+	language, err := storage.GetLocale(r.kvs, r.guildID)
+	if err != nil {
+		return nil, err
+	}
+	if language == "" {
+		language = string(locale.English)
+	}
+	presets, err := storage.AllPresets(r.kvs, r.guildID)
+	if err != nil {
+		return nil, err
+	}
+	return guildConfig{Timezone: location.String(), Locale: language, Presets: presets}, nil
+}
+
+// knownLanguage checks that there is a catalog for the language, returning what to store for it.
+func knownLanguage(language string) (string, bool) {
+	if discord.Language(language) == locale.English {
+		return "", true
+	}
+	for _, known := range locale.Languages() {
+		if string(known) == language {
+			return language, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,165 @@
+// Package webapi serves guild data as JSON over HTTP, so external tools and guild websites can use it.
+//
+// Every guild has its own token, made with /config api token, and every request needs it as a bearer token:
+//
+//	Authorization: Bearer <token>
+//
+// Everything lives under /api/guilds/<guild ID>/, like /api/guilds/1234/faq/rules.
+// Tokens can read everything, and tokens made with write access can also change FAQ entries and the configuration:
+//
+//	GET /faq, GET, PUT {"text": "..."} and DELETE /faq/<topic>
+//	GET /seen, GET /seen/<user ID>
+//	GET /votes, GET /votes/<message ID>
+//	GET /config, PATCH {"timezone": "...", "locale": "..."} /config
+package webapi
+
+import (
+	"encoding/json"
+	"errors"
+	"komainu/storage"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/diamondburned/arikawa/v3/discord"
+)
+
+// maxBody is the largest request body accepted, as nothing the API takes is anywhere near this big.
+const maxBody = 64 * 1024
+
+// request is what a handler gets to work with, after the token has been checked.
+type request struct {
+	*http.Request
+	kvs     storage.KeyValueStore
+	guildID discord.GuildID
+	key     string // Whatever comes after the resource in the path, like the topic in /faq/<topic>.
+	write   bool   // If the token can write.
+}
+
+// handler handles a single resource, returning what to respond with, or an error.
+type handler func(r request) (any, error)
+
+// resources are the handlers for everything under /api/guilds/<guild ID>/, by the first part of the path after that.
+var resources = map[string]handler{
+	"faq":    handleFaq,
+	"seen":   handleSeen,
+	"votes":  handleVotes,
+	"config": handleConfig,
+}
+
+// apiError is an error with a HTTP status, for the ones that are the fault of whoever made the request.
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e apiError) Error() string {
+	return e.message
+}
+
+var (
+	errNotFound         = apiError{http.StatusNotFound, "not found"}
+	errMethodNotAllowed = apiError{http.StatusMethodNotAllowed, "method not allowed"}
+	errReadOnly         = apiError{http.StatusForbidden, "this token can only read"}
+)
+
+// Serve serves the API on the given address, like ":8081".
+// Intended to be called as a goroutine.
+func Serve(kvs storage.KeyValueStore, address string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/guilds/", func(w http.ResponseWriter, r *http.Request) {
+		serveGuild(kvs, w, r)
+	})
+	log.Printf("Serving the REST API on %s", address)
+	if err := http.ListenAndServe(address, mux); err != nil {
+		log.Printf("REST API server stopped: %s", err)
+	}
+}
+
+// serveGuild checks the token, and hands the request to the handler for the resource.
+func serveGuild(kvs storage.KeyValueStore, w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/api/guilds/"), "/", 3)
+	if len(parts) < 2 {
+		writeError(w, errNotFound)
+		return
+	}
+	snowflake, err := discord.ParseSnowflake(parts[0])
+	if err != nil {
+		writeError(w, errNotFound)
+		return
+	}
+	guildID := discord.GuildID(snowflake)
+
+	token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	valid, write, err := storage.CheckAPIToken(kvs, guildID, token)
+	if err != nil {
+		log.Printf("[%s] REST API could not check the token: %s", guildID, err)
+		writeError(w, err)
+		return
+	}
+	if !valid {
+		writeError(w, apiError{http.StatusUnauthorized, "missing or invalid token"})
+		return
+	}
+
+	handle, ok := resources[parts[1]]
+	if !ok {
+		writeError(w, errNotFound)
+		return
+	}
+	key := ""
+	if len(parts) == 3 {
+		key = parts[2]
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+	result, err := handle(request{Request: r, kvs: kvs, guildID: guildID, key: key, write: write})
+	if err != nil {
+		var apiErr apiError
+		if !errors.As(err, &apiErr) {
+			log.Printf("[%s] REST API %s %s failed: %s", guildID, r.Method, r.URL.Path, err)
+		}
+		writeError(w, err)
+		return
+	}
+	if r.Method != http.MethodGet {
+		log.Printf("[%s] REST API %s %s", guildID, r.Method, r.URL.Path)
+	}
+	if result == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// decode reads the JSON request body into out.
+func (r request) decode(out any) error {
+	if err := json.NewDecoder(r.Body).Decode(out); err != nil {
+		return apiError{http.StatusBadRequest, "could not read the request body: " + err.Error()}
+	}
+	return nil
+}
+
+// writable returns errReadOnly if the token can't write.
+func (r request) writable() error {
+	if !r.write {
+		return errReadOnly
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("REST API could not write the response: %s", err)
+	}
+}
+
+// writeError responds with the error as JSON, hiding the details of anything that isn't an apiError.
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr apiError
+	if !errors.As(err, &apiErr) {
+		apiErr = apiError{http.StatusInternalServerError, "an error occured, and has been logged"}
+	}
+	writeJSON(w, apiErr.status, map[string]string{"error": apiErr.message})
+}